@@ -0,0 +1,198 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auditcmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/trustbloc/edv/pkg/audit"
+	"github.com/trustbloc/edv/pkg/edvprovider/couchdbedvprovider"
+	cmdutils "github.com/trustbloc/edv/pkg/utils/cmd"
+)
+
+const (
+	auditLogTypeFlagName  = "audit-log-type"
+	auditLogTypeEnvKey    = "EDV_AUDIT_LOG_TYPE"
+	auditLogTypeFlagUsage = "Where the audit log is kept. Supported options: " + auditLogTypeFileOption + ", " +
+		auditLogTypeDatabaseOption + ". " + auditLogTypeDatabaseOption + " reads the audit log from CouchDB." +
+		" Defaults to " + auditLogTypeFileOption + "." +
+		" Alternatively, this can be set with the following environment variable: " + auditLogTypeEnvKey
+
+	auditLogTypeFileOption     = "file"
+	auditLogTypeDatabaseOption = "database"
+
+	auditLogPathFlagName  = "audit-log-path"
+	auditLogPathEnvKey    = "EDV_AUDIT_LOG_PATH"
+	auditLogPathFlagUsage = "Path to the audit log file. Required if " + auditLogTypeFlagName + " is " +
+		auditLogTypeFileOption + "." +
+		" Alternatively, this can be set with the following environment variable: " + auditLogPathEnvKey
+
+	databaseURLFlagName  = "database-url"
+	databaseURLEnvKey    = "EDV_DATABASE_URL"
+	databaseURLFlagUsage = "The URL of the CouchDB database holding the audit log. Required if " +
+		auditLogTypeFlagName + " is " + auditLogTypeDatabaseOption + "." +
+		" Alternatively, this can be set with the following environment variable: " + databaseURLEnvKey
+
+	databasePrefixFlagName  = "database-prefix"
+	databasePrefixEnvKey    = "EDV_DATABASE_PREFIX"
+	databasePrefixFlagUsage = "The database prefix the EDV was started with, if any." +
+		" Alternatively, this can be set with the following environment variable: " + databasePrefixEnvKey
+)
+
+var errInvalidAuditLogType = fmt.Errorf("audit log type not set to a valid type." +
+	" run audit --help to see the available options")
+var errMissingAuditLogPath = fmt.Errorf(auditLogPathFlagName + " must be set when " + auditLogTypeFlagName +
+	" is " + auditLogTypeFileOption)
+var errMissingDatabaseURL = fmt.Errorf(databaseURLFlagName + " must be set when " + auditLogTypeFlagName +
+	" is " + auditLogTypeDatabaseOption)
+
+// GetAuditCmd returns the Cobra audit command, which has subcommands for exporting and verifying audit logs.
+func GetAuditCmd() *cobra.Command {
+	auditCmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Export or verify the audit log",
+		Long:  "Export or verify the tamper-evident audit log of vault operations",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.HelpFunc()(cmd, args)
+		},
+	}
+
+	auditCmd.AddCommand(createExportCmd(), createVerifyCmd())
+
+	return auditCmd
+}
+
+func createExportCmd() *cobra.Command {
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the audit log",
+		Long:  "Write every record in the audit log to standard output as a JSON object per line",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			records, err := readRecords(cmd)
+			if err != nil {
+				return err
+			}
+
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+
+			for i := range records {
+				err = encoder.Encode(records[i])
+				if err != nil {
+					return fmt.Errorf("failed to write audit record %d: %w", records[i].Sequence, err)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	createFlags(exportCmd)
+
+	return exportCmd
+}
+
+func createVerifyCmd() *cobra.Command {
+	verifyCmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Verify the audit log",
+		Long:  "Check that no record in the audit log has been modified, removed, reordered or inserted",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			records, err := readRecords(cmd)
+			if err != nil {
+				return err
+			}
+
+			err = audit.Verify(records)
+			if err != nil {
+				return err
+			}
+
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "Audit log verified: %d records\n", len(records))
+
+			return err
+		},
+	}
+
+	createFlags(verifyCmd)
+
+	return verifyCmd
+}
+
+func createFlags(cmd *cobra.Command) {
+	cmd.Flags().String(auditLogTypeFlagName, "", auditLogTypeFlagUsage)
+	cmd.Flags().String(auditLogPathFlagName, "", auditLogPathFlagUsage)
+	cmd.Flags().String(databaseURLFlagName, "", databaseURLFlagUsage)
+	cmd.Flags().String(databasePrefixFlagName, "", databasePrefixFlagUsage)
+}
+
+func readRecords(cmd *cobra.Command) ([]audit.Record, error) {
+	auditLogType, err := cmdutils.GetUserSetVar(cmd, auditLogTypeFlagName, auditLogTypeEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case auditLogType == "" || strings.EqualFold(auditLogType, auditLogTypeFileOption):
+		return readRecordsFromFile(cmd)
+	case strings.EqualFold(auditLogType, auditLogTypeDatabaseOption):
+		return readRecordsFromDatabase(cmd)
+	default:
+		return nil, errInvalidAuditLogType
+	}
+}
+
+func readRecordsFromFile(cmd *cobra.Command) ([]audit.Record, error) {
+	auditLogPath, err := cmdutils.GetUserSetVar(cmd, auditLogPathFlagName, auditLogPathEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if auditLogPath == "" {
+		return nil, errMissingAuditLogPath
+	}
+
+	return audit.ReadRecordsFromFile(auditLogPath)
+}
+
+func readRecordsFromDatabase(cmd *cobra.Command) ([]audit.Record, error) {
+	databaseURL, err := cmdutils.GetUserSetVar(cmd, databaseURLFlagName, databaseURLEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if databaseURL == "" {
+		return nil, errMissingDatabaseURL
+	}
+
+	databasePrefix, err := cmdutils.GetUserSetVar(cmd, databasePrefixFlagName, databasePrefixEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := couchdbedvprovider.NewProvider(databaseURL, databasePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if closeErr := provider.Close(); closeErr != nil {
+			log.Errorf("Failed to close database provider: %s", closeErr.Error())
+		}
+	}()
+
+	sink, err := audit.NewStoreSink(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	return sink.Records()
+}
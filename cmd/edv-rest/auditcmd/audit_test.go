@@ -0,0 +1,130 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auditcmd
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edv/pkg/audit"
+)
+
+func TestGetAuditCmd(t *testing.T) {
+	auditCmd := GetAuditCmd()
+
+	require.Equal(t, "audit", auditCmd.Use)
+	require.Len(t, auditCmd.Commands(), 2)
+
+	auditCmd.SetArgs([]string{})
+	require.NoError(t, auditCmd.Execute())
+}
+
+func TestExportAndVerify(t *testing.T) {
+	dir, tempDirErr := ioutil.TempDir("", "edv-auditcmd")
+	require.NoError(t, tempDirErr)
+
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	auditLogPath := writeTestAuditLog(t, dir)
+
+	t.Run("Export", func(t *testing.T) {
+		output, err := execute("export", "--"+auditLogPathFlagName, auditLogPath)
+		require.NoError(t, err)
+
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		require.Len(t, lines, 2)
+		require.Contains(t, lines[0], `"action":"createVault"`)
+		require.Contains(t, lines[1], `"action":"createDocument"`)
+	})
+	t.Run("Verify", func(t *testing.T) {
+		output, err := execute("verify", "--"+auditLogTypeFlagName, auditLogTypeFileOption,
+			"--"+auditLogPathFlagName, auditLogPath)
+		require.NoError(t, err)
+		require.Equal(t, "Audit log verified: 2 records\n", output)
+	})
+	t.Run("Verify tampered audit log", func(t *testing.T) {
+		auditLogBytes, err := ioutil.ReadFile(auditLogPath) //nolint: gosec
+		require.NoError(t, err)
+
+		tamperedAuditLogPath := filepath.Join(dir, "tampered.log")
+		err = ioutil.WriteFile(tamperedAuditLogPath,
+			bytes.Replace(auditLogBytes, []byte("createDocument"), []byte("readDocument"), 1), 0600)
+		require.NoError(t, err)
+
+		_, err = execute("verify", "--"+auditLogPathFlagName, tamperedAuditLogPath)
+		require.True(t, errors.Is(err, audit.ErrChainBroken))
+	})
+	t.Run("Audit log file doesn't exist", func(t *testing.T) {
+		_, err := execute("export", "--"+auditLogPathFlagName, filepath.Join(dir, "missing.log"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to open audit log file")
+	})
+}
+
+func TestReadRecords_InvalidParameters(t *testing.T) {
+	t.Run("Missing audit log path", func(t *testing.T) {
+		_, err := execute("verify")
+		require.Equal(t, errMissingAuditLogPath, err)
+	})
+	t.Run("Invalid audit log type", func(t *testing.T) {
+		_, err := execute("verify", "--"+auditLogTypeFlagName, "NotAValidType")
+		require.Equal(t, errInvalidAuditLogType, err)
+	})
+	t.Run("Missing database URL", func(t *testing.T) {
+		_, err := execute("export", "--"+auditLogTypeFlagName, auditLogTypeDatabaseOption)
+		require.Equal(t, errMissingDatabaseURL, err)
+	})
+	t.Run("Database is unreachable", func(t *testing.T) {
+		_, err := execute("export", "--"+auditLogTypeFlagName, auditLogTypeDatabaseOption,
+			"--"+databaseURLFlagName, "http://localhost:0", "--"+databasePrefixFlagName, "edv")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to create audit store")
+	})
+}
+
+func execute(args ...string) (string, error) {
+	auditCmd := GetAuditCmd()
+
+	var output bytes.Buffer
+
+	auditCmd.SetOut(&output)
+	auditCmd.SetArgs(args)
+
+	err := auditCmd.Execute()
+
+	return output.String(), err
+}
+
+func writeTestAuditLog(t *testing.T, dir string) string {
+	auditLogPath := filepath.Join(dir, "audit.log")
+
+	sink, err := audit.NewFileSink(auditLogPath)
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, sink.Close())
+	}()
+
+	auditLog, err := audit.New(sink)
+	require.NoError(t, err)
+
+	require.NoError(t, auditLog.Record(audit.Entry{Action: "createVault", VaultID: "vault",
+		Result: audit.ResultSuccess}))
+	require.NoError(t, auditLog.Record(audit.Entry{Action: "createDocument", VaultID: "vault",
+		Result: audit.ResultSuccess}))
+
+	return auditLogPath
+}
@@ -0,0 +1,280 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fsckcmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/trustbloc/edv/pkg/edvprovider"
+	"github.com/trustbloc/edv/pkg/edvprovider/couchdbedvprovider"
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+	"github.com/trustbloc/edv/pkg/restapi/edv/operation"
+	cmdutils "github.com/trustbloc/edv/pkg/utils/cmd"
+)
+
+const (
+	databaseTypeFlagName  = "database-type"
+	databaseTypeEnvKey    = "EDV_DATABASE_TYPE"
+	databaseTypeFlagUsage = "The type of database the EDV server uses. Supported options: mem, couchdb." +
+		" Alternatively, this can be set with the following environment variable: " + databaseTypeEnvKey
+
+	databaseURLFlagName  = "database-url"
+	databaseURLEnvKey    = "EDV_DATABASE_URL"
+	databaseURLFlagUsage = "The URL of the database. Not needed if using memstore." +
+		" For CouchDB, include the username:password@ text if required." +
+		" Alternatively, this can be set with the following environment variable: " + databaseURLEnvKey
+
+	databasePrefixFlagName  = "database-prefix"
+	databasePrefixEnvKey    = "EDV_DATABASE_PREFIX"
+	databasePrefixFlagUsage = "The database prefix the EDV server was started with." +
+		" Required with CouchDB unless a vault ID is given." +
+		" Alternatively, this can be set with the following environment variable: " + databasePrefixEnvKey
+
+	vaultIDFlagName  = "vault-id"
+	vaultIDEnvKey    = "EDV_FSCK_VAULT_ID"
+	vaultIDFlagUsage = "The ID of the vault to check. Defaults to every vault." +
+		" Alternatively, this can be set with the following environment variable: " + vaultIDEnvKey
+
+	repairFlagName  = "repair"
+	repairEnvKey    = "EDV_FSCK_REPAIR"
+	repairFlagUsage = "Delete orphaned mappings and create the mappings of unindexed documents." +
+		" Possible values [true] [false]. Defaults to false." +
+		" Alternatively, this can be set with the following environment variable: " + repairEnvKey
+
+	databaseTypeMemOption     = "mem"
+	databaseTypeCouchDBOption = "couchdb"
+)
+
+var (
+	errInvalidDatabaseType = fmt.Errorf("database type not set to a valid type." +
+		" run fsck --help to see the available options")
+	errMissingDatabasePrefix = fmt.Errorf("database prefix not set. Checking every vault in CouchDB requires" +
+		" the database prefix the EDV server was started with, since every other database would be checked" +
+		" as a vault otherwise")
+)
+
+// GetFsckCmd returns the Cobra fsck command, which checks that vaults' documents and encrypted indexes agree.
+func GetFsckCmd() *cobra.Command {
+	fsckCmd := &cobra.Command{
+		Use:   "fsck",
+		Short: "Check vaults for inconsistencies",
+		Long: "Scan vaults for orphaned mappings, documents that are missing mappings or can't be parsed, and" +
+			" index names and values that are declared unique but shared by several documents, and optionally" +
+			" repair the mappings. Fails if any problems remain afterwards.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vaultID, err := cmdutils.GetUserSetVar(cmd, vaultIDFlagName, vaultIDEnvKey, true)
+			if err != nil {
+				return err
+			}
+
+			provider, err := getProvider(cmd, vaultID)
+			if err != nil {
+				return err
+			}
+
+			repair, err := getRepair(cmd)
+			if err != nil {
+				return err
+			}
+
+			return fsck(cmd, provider, vaultID, repair)
+		},
+	}
+
+	createFlags(fsckCmd)
+
+	return fsckCmd
+}
+
+func createFlags(cmd *cobra.Command) {
+	cmd.Flags().String(databaseTypeFlagName, "", databaseTypeFlagUsage)
+	cmd.Flags().String(databaseURLFlagName, "", databaseURLFlagUsage)
+	cmd.Flags().String(databasePrefixFlagName, "", databasePrefixFlagUsage)
+	cmd.Flags().String(vaultIDFlagName, "", vaultIDFlagUsage)
+	cmd.Flags().String(repairFlagName, "", repairFlagUsage)
+}
+
+// getProvider creates the provider of the EDV server's database. When every vault is checked, a database prefix is
+// required with CouchDB, since the vaults are listed from the server's databases.
+func getProvider(cmd *cobra.Command, vaultID string) (edvprovider.EDVProvider, error) {
+	databaseType, err := cmdutils.GetUserSetVar(cmd, databaseTypeFlagName, databaseTypeEnvKey, false)
+	if err != nil {
+		return nil, err
+	}
+
+	databaseURL, err := cmdutils.GetUserSetVar(cmd, databaseURLFlagName, databaseURLEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	databasePrefix, err := cmdutils.GetUserSetVar(cmd, databasePrefixFlagName, databasePrefixEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case strings.EqualFold(databaseType, databaseTypeMemOption):
+		return memedvprovider.NewProvider(), nil
+	case strings.EqualFold(databaseType, databaseTypeCouchDBOption):
+		if databasePrefix == "" && vaultID == "" {
+			return nil, errMissingDatabasePrefix
+		}
+
+		return couchdbedvprovider.NewProvider(databaseURL, databasePrefix)
+	default:
+		return nil, errInvalidDatabaseType
+	}
+}
+
+func getRepair(cmd *cobra.Command) (bool, error) {
+	repairString, err := cmdutils.GetUserSetVar(cmd, repairFlagName, repairEnvKey, true)
+	if err != nil {
+		return false, err
+	}
+
+	if repairString == "" {
+		return false, nil
+	}
+
+	repair, err := strconv.ParseBool(repairString)
+	if err != nil {
+		return false, fmt.Errorf("invalid value for %s: %w", repairFlagName, err)
+	}
+
+	return repair, nil
+}
+
+// fsck checks the given vault, or every vault if none is given, writing what was found in each one to standard output.
+func fsck(cmd *cobra.Command, provider edvprovider.EDVProvider, vaultID string, repair bool) error {
+	defer func() {
+		if err := provider.Close(); err != nil {
+			log.Errorf("Failed to close EDV provider: %s", err.Error())
+		}
+	}()
+
+	vaultIDs := []string{vaultID}
+
+	if vaultID == "" {
+		var err error
+
+		vaultIDs, err = listVaults(provider)
+		if err != nil {
+			return err
+		}
+	}
+
+	inconsistentVaults := 0
+
+	for _, id := range vaultIDs {
+		report, err := checkVault(provider, id, repair)
+		if err != nil {
+			return fmt.Errorf("failed to check vault %s: %w", id, err)
+		}
+
+		err = writeReport(cmd.OutOrStdout(), id, report)
+		if err != nil {
+			return err
+		}
+
+		if !isConsistent(report) {
+			inconsistentVaults++
+		}
+	}
+
+	if inconsistentVaults > 0 {
+		return fmt.Errorf("%d of %d vaults have problems", inconsistentVaults, len(vaultIDs))
+	}
+
+	return nil
+}
+
+// listVaults returns the IDs of every vault, leaving out the EDV server's own stores.
+func listVaults(provider edvprovider.EDVProvider) ([]string, error) {
+	storeNames, err := provider.StoreNames()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vaults: %w", err)
+	}
+
+	var vaultIDs []string
+
+	for _, storeName := range storeNames {
+		if !operation.IsInternalStoreName(storeName) {
+			vaultIDs = append(vaultIDs, storeName)
+		}
+	}
+
+	return vaultIDs, nil
+}
+
+func checkVault(provider edvprovider.EDVProvider, vaultID string, repair bool) (*models.ConsistencyReport, error) {
+	store, err := provider.OpenStore(vaultID)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.Check(repair)
+}
+
+// isConsistent returns whether the vault has no problems left. Orphaned mappings and unindexed documents
+// are fixed by repairing, but unparsable documents and uniqueness violations never are.
+func isConsistent(report *models.ConsistencyReport) bool {
+	if len(report.UnparsableDocuments) > 0 || len(report.UniquenessViolations) > 0 {
+		return false
+	}
+
+	return report.Repaired || (len(report.OrphanedMappings) == 0 && len(report.UnindexedDocuments) == 0)
+}
+
+func writeReport(out io.Writer, vaultID string, report *models.ConsistencyReport) error {
+	_, err := fmt.Fprintf(out, "Vault %s: %d documents, %d orphaned mappings, %d unindexed documents,"+
+		" %d unparsable documents, %d uniqueness violations\n", vaultID, report.Documents,
+		len(report.OrphanedMappings), len(report.UnindexedDocuments), len(report.UnparsableDocuments),
+		len(report.UniquenessViolations))
+	if err != nil {
+		return err
+	}
+
+	fixed := ""
+	if report.Repaired {
+		fixed = " (repaired)"
+	}
+
+	var lines []string
+
+	for _, mappingID := range report.OrphanedMappings {
+		lines = append(lines, fmt.Sprintf("Orphaned mapping %s%s", mappingID, fixed))
+	}
+
+	for _, docID := range report.UnindexedDocuments {
+		lines = append(lines, fmt.Sprintf("Unindexed document %s%s", docID, fixed))
+	}
+
+	for _, docID := range report.UnparsableDocuments {
+		lines = append(lines, fmt.Sprintf("Unparsable document %s", docID))
+	}
+
+	for _, violation := range report.UniquenessViolations {
+		lines = append(lines, fmt.Sprintf("Unique index %s with value %s is shared by documents %s",
+			violation.Name, violation.Value, strings.Join(violation.DocumentIDs, ", ")))
+	}
+
+	for _, line := range lines {
+		_, err = fmt.Fprintln(out, line)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
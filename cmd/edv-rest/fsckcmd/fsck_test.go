@@ -0,0 +1,216 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fsckcmd
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edv/pkg/edvprovider"
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+	"github.com/trustbloc/edv/pkg/restapi/edv/operation"
+)
+
+const testVaultID = "testvault"
+
+func TestGetFsckCmd(t *testing.T) {
+	fsckCmd := GetFsckCmd()
+
+	require.Equal(t, "fsck", fsckCmd.Use)
+
+	output, err := execute("--"+databaseTypeFlagName, "MEM", "--"+repairFlagName, "true")
+	require.NoError(t, err)
+	require.Empty(t, output)
+}
+
+func TestInvalidParameters(t *testing.T) {
+	for _, test := range []struct {
+		args []string
+		err  string
+	}{
+		{args: []string{}, err: databaseTypeFlagName},
+		{args: []string{"--" + databaseTypeFlagName, "mysql"}, err: errInvalidDatabaseType.Error()},
+		{args: []string{"--" + databaseTypeFlagName, "couchdb", "--" + databasePrefixFlagName, "edv"},
+			err: "couchDB database URL not set"},
+		{args: []string{"--" + databaseTypeFlagName, "couchdb", "--" + vaultIDFlagName, testVaultID},
+			err: "couchDB database URL not set"},
+		{args: []string{"--" + databaseTypeFlagName, "couchdb", "--" + databaseURLFlagName, "localhost:5984"},
+			err: errMissingDatabasePrefix.Error()},
+		{args: []string{"--" + databaseTypeFlagName, "mem", "--" + repairFlagName, "maybe"},
+			err: "invalid value for " + repairFlagName},
+	} {
+		_, err := execute(test.args...)
+		require.Error(t, err, test.args)
+		require.Contains(t, err.Error(), test.err, test.args)
+	}
+}
+
+func TestFsck(t *testing.T) {
+	inconsistentReport := models.ConsistencyReport{
+		Documents:          2,
+		OrphanedMappings:   []string{"doc1_mapping_a"},
+		UnindexedDocuments: []string{"doc2"},
+	}
+
+	t.Run("Consistent vaults", func(t *testing.T) {
+		provider := newMockProvider(t, &models.ConsistencyReport{Documents: 2}, testVaultID, "othervault",
+			operation.VaultConfigurationStoreName)
+
+		cmd, output := newTestCmd()
+
+		err := fsck(cmd, provider, "", false)
+		require.NoError(t, err)
+		require.Equal(t, "Vault othervault: 2 documents, 0 orphaned mappings, 0 unindexed documents,"+
+			" 0 unparsable documents, 0 uniqueness violations\n"+
+			"Vault testvault: 2 documents, 0 orphaned mappings, 0 unindexed documents,"+
+			" 0 unparsable documents, 0 uniqueness violations\n", output.String())
+	})
+	t.Run("Inconsistent vault", func(t *testing.T) {
+		provider := newMockProvider(t, &inconsistentReport, testVaultID)
+
+		cmd, output := newTestCmd()
+
+		err := fsck(cmd, provider, testVaultID, false)
+		require.EqualError(t, err, "1 of 1 vaults have problems")
+		require.Equal(t, "Vault testvault: 2 documents, 1 orphaned mappings, 1 unindexed documents,"+
+			" 0 unparsable documents, 0 uniqueness violations\n"+
+			"Orphaned mapping doc1_mapping_a\n"+
+			"Unindexed document doc2\n", output.String())
+		require.False(t, provider.repaired)
+	})
+	t.Run("Repaired vault", func(t *testing.T) {
+		provider := newMockProvider(t, &inconsistentReport, testVaultID)
+
+		cmd, output := newTestCmd()
+
+		err := fsck(cmd, provider, testVaultID, true)
+		require.NoError(t, err)
+		require.Contains(t, output.String(), "Orphaned mapping doc1_mapping_a (repaired)\n")
+		require.Contains(t, output.String(), "Unindexed document doc2 (repaired)\n")
+		require.True(t, provider.repaired)
+	})
+	t.Run("Problems that can't be repaired", func(t *testing.T) {
+		provider := newMockProvider(t, &models.ConsistencyReport{
+			Documents:           3,
+			UnparsableDocuments: []string{"brokenDoc"},
+			UniquenessViolations: []models.UniquenessViolation{
+				{Name: "name", Value: "value", DocumentIDs: []string{"doc1", "doc2"}},
+			},
+		}, testVaultID)
+
+		cmd, output := newTestCmd()
+
+		err := fsck(cmd, provider, testVaultID, true)
+		require.EqualError(t, err, "1 of 1 vaults have problems")
+		require.Contains(t, output.String(), "Unparsable document brokenDoc\n"+
+			"Unique index name with value value is shared by documents doc1, doc2\n")
+	})
+	t.Run("Provider doesn't support indexing", func(t *testing.T) {
+		provider := memedvprovider.NewProvider()
+		require.NoError(t, provider.CreateStore(testVaultID))
+
+		cmd, _ := newTestCmd()
+
+		err := fsck(cmd, provider, "", false)
+		require.EqualError(t, err, "failed to check vault testvault: "+edvprovider.ErrIndexingNotSupported.Error())
+	})
+	t.Run("Vault not found", func(t *testing.T) {
+		cmd, _ := newTestCmd()
+
+		err := fsck(cmd, memedvprovider.NewProvider(), testVaultID, false)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to check vault testvault")
+	})
+	t.Run("Failed to list vaults", func(t *testing.T) {
+		provider := newMockProvider(t, nil)
+		provider.errStoreNames = errors.New("store names error")
+
+		cmd, _ := newTestCmd()
+
+		err := fsck(cmd, provider, "", false)
+		require.EqualError(t, err, "failed to list vaults: store names error")
+	})
+}
+
+type mockProvider struct {
+	edvprovider.EDVProvider
+	report        *models.ConsistencyReport
+	errStoreNames error
+	repaired      bool
+}
+
+func newMockProvider(t *testing.T, report *models.ConsistencyReport, storeNames ...string) *mockProvider {
+	provider := memedvprovider.NewProvider()
+
+	for _, storeName := range storeNames {
+		require.NoError(t, provider.CreateStore(storeName))
+	}
+
+	return &mockProvider{EDVProvider: provider, report: report}
+}
+
+func (m *mockProvider) StoreNames() ([]string, error) {
+	if m.errStoreNames != nil {
+		return nil, m.errStoreNames
+	}
+
+	return m.EDVProvider.StoreNames()
+}
+
+func (m *mockProvider) OpenStore(name string) (edvprovider.EDVStore, error) {
+	store, err := m.EDVProvider.OpenStore(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mockStore{EDVStore: store, provider: m}, nil
+}
+
+type mockStore struct {
+	edvprovider.EDVStore
+	provider *mockProvider
+}
+
+func (m *mockStore) Check(repair bool) (*models.ConsistencyReport, error) {
+	m.provider.repaired = m.provider.repaired || repair
+
+	report := *m.provider.report
+	report.Repaired = repair
+
+	return &report, nil
+}
+
+func newTestCmd() (*cobra.Command, *bytes.Buffer) {
+	cmd := &cobra.Command{}
+
+	var output bytes.Buffer
+
+	cmd.SetOut(&output)
+	cmd.SetErr(ioutil.Discard)
+
+	return cmd, &output
+}
+
+func execute(args ...string) (string, error) {
+	fsckCmd := GetFsckCmd()
+
+	var output bytes.Buffer
+
+	fsckCmd.SetOut(&output)
+	fsckCmd.SetErr(ioutil.Discard)
+	fsckCmd.SetArgs(args)
+
+	err := fsckCmd.Execute()
+
+	return output.String(), err
+}
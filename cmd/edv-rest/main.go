@@ -11,7 +11,11 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/trustbloc/edv/cmd/edv-rest/auditcmd"
+	"github.com/trustbloc/edv/cmd/edv-rest/fsckcmd"
+	"github.com/trustbloc/edv/cmd/edv-rest/migratecmd"
 	"github.com/trustbloc/edv/cmd/edv-rest/startcmd"
+	"github.com/trustbloc/edv/cmd/edv-rest/vaultcmd"
 )
 
 func main() {
@@ -23,6 +27,10 @@ func main() {
 	}
 
 	rootCmd.AddCommand(startcmd.GetStartCmd(&startcmd.HTTPServer{}))
+	rootCmd.AddCommand(auditcmd.GetAuditCmd())
+	rootCmd.AddCommand(vaultcmd.GetVaultCmd())
+	rootCmd.AddCommand(migratecmd.GetMigrateCmd())
+	rootCmd.AddCommand(fsckcmd.GetFsckCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		log.Fatalf("Failed to run edv: %s", err.Error())
@@ -0,0 +1,303 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package migratecmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/trustbloc/edge-core/pkg/storage"
+	couchdbstore "github.com/trustbloc/edge-core/pkg/storage/couchdb"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+
+	"github.com/trustbloc/edv/pkg/edvprovider"
+	"github.com/trustbloc/edv/pkg/edvprovider/couchdbedvprovider"
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/migration"
+	cmdutils "github.com/trustbloc/edv/pkg/utils/cmd"
+)
+
+const (
+	fromTypeFlagName  = "from-type"
+	fromTypeEnvKey    = "EDV_MIGRATE_FROM_TYPE"
+	fromTypeFlagUsage = "The type of database to migrate from. Supported options: mem, couchdb." +
+		" Alternatively, this can be set with the following environment variable: " + fromTypeEnvKey
+
+	fromURLFlagName  = "from-url"
+	fromURLEnvKey    = "EDV_MIGRATE_FROM_URL"
+	fromURLFlagUsage = "The URL of the database to migrate from. Not needed if using memstore." +
+		" Alternatively, this can be set with the following environment variable: " + fromURLEnvKey
+
+	fromPrefixFlagName  = "from-prefix"
+	fromPrefixEnvKey    = "EDV_MIGRATE_FROM_PREFIX"
+	fromPrefixFlagUsage = "The database prefix the source EDV was started with. Required with CouchDB." +
+		" Alternatively, this can be set with the following environment variable: " + fromPrefixEnvKey
+
+	toTypeFlagName  = "to-type"
+	toTypeEnvKey    = "EDV_MIGRATE_TO_TYPE"
+	toTypeFlagUsage = "The type of database to migrate to. Supported options: mem, couchdb." +
+		" Alternatively, this can be set with the following environment variable: " + toTypeEnvKey
+
+	toURLFlagName  = "to-url"
+	toURLEnvKey    = "EDV_MIGRATE_TO_URL"
+	toURLFlagUsage = "The URL of the database to migrate to. Not needed if using memstore." +
+		" Alternatively, this can be set with the following environment variable: " + toURLEnvKey
+
+	toPrefixFlagName  = "to-prefix"
+	toPrefixEnvKey    = "EDV_MIGRATE_TO_PREFIX"
+	toPrefixFlagUsage = "The database prefix the destination EDV will be started with, if any." +
+		" Alternatively, this can be set with the following environment variable: " + toPrefixEnvKey
+
+	dryRunFlagName  = "dry-run"
+	dryRunEnvKey    = "EDV_MIGRATE_DRY_RUN"
+	dryRunFlagUsage = "Read and check every vault in the source without writing anything to the destination." +
+		" Possible values [true] [false]. Defaults to false." +
+		" Alternatively, this can be set with the following environment variable: " + dryRunEnvKey
+
+	batchSizeFlagName  = "batch-size"
+	batchSizeEnvKey    = "EDV_MIGRATE_BATCH_SIZE"
+	batchSizeFlagUsage = "The number of documents to read and write at a time. Defaults to 100." +
+		" Alternatively, this can be set with the following environment variable: " + batchSizeEnvKey
+
+	databaseTypeMemOption     = "mem"
+	databaseTypeCouchDBOption = "couchdb"
+)
+
+var (
+	errInvalidDatabaseType = fmt.Errorf("database type not set to a valid type." +
+		" run migrate --help to see the available options")
+	errMissingDatabasePrefix = fmt.Errorf("database prefix not set. Migrating from CouchDB requires the database" +
+		" prefix the source EDV server was started with, since every other database would be migrated as a vault" +
+		" otherwise")
+)
+
+// GetMigrateCmd returns the Cobra migrate command, which copies every vault from one database to another.
+func GetMigrateCmd() *cobra.Command {
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Migrate vaults to another database",
+		Long: "Copy every vault, with its configuration and documents, from the database of one EDV server to" +
+			" another's, then check that each copied vault has the same number of documents and that every" +
+			" document's hash matches. The EDV servers should be stopped while this runs. Documents that were" +
+			" already copied are skipped, so an interrupted migration can be resumed by running it again.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			source, err := getEndpoint(cmd, fromTypeFlagName, fromTypeEnvKey, fromURLFlagName, fromURLEnvKey,
+				fromPrefixFlagName, fromPrefixEnvKey, true)
+			if err != nil {
+				return err
+			}
+
+			destination, err := getEndpoint(cmd, toTypeFlagName, toTypeEnvKey, toURLFlagName, toURLEnvKey,
+				toPrefixFlagName, toPrefixEnvKey, false)
+			if err != nil {
+				return err
+			}
+
+			opts, err := getOptions(cmd)
+			if err != nil {
+				return err
+			}
+
+			return migrate(cmd, source, destination, opts...)
+		},
+	}
+
+	createFlags(migrateCmd)
+
+	return migrateCmd
+}
+
+func createFlags(cmd *cobra.Command) {
+	cmd.Flags().String(fromTypeFlagName, "", fromTypeFlagUsage)
+	cmd.Flags().String(fromURLFlagName, "", fromURLFlagUsage)
+	cmd.Flags().String(fromPrefixFlagName, "", fromPrefixFlagUsage)
+	cmd.Flags().String(toTypeFlagName, "", toTypeFlagUsage)
+	cmd.Flags().String(toURLFlagName, "", toURLFlagUsage)
+	cmd.Flags().String(toPrefixFlagName, "", toPrefixFlagUsage)
+	cmd.Flags().String(dryRunFlagName, "", dryRunFlagUsage)
+	cmd.Flags().String(batchSizeFlagName, "", batchSizeFlagUsage)
+}
+
+// getEndpoint creates the providers for one side of the migration the same way the EDV server does,
+// so that the vaults and their configurations are found where the server keeps them. The source's vaults are listed
+// from its databases, so a database prefix is required for a CouchDB source.
+func getEndpoint(cmd *cobra.Command, typeFlagName, typeEnvKey, urlFlagName, urlEnvKey, prefixFlagName,
+	prefixEnvKey string, isSource bool) (migration.Endpoint, error) {
+	databaseType, err := cmdutils.GetUserSetVar(cmd, typeFlagName, typeEnvKey, false)
+	if err != nil {
+		return migration.Endpoint{}, err
+	}
+
+	databaseURL, err := cmdutils.GetUserSetVar(cmd, urlFlagName, urlEnvKey, true)
+	if err != nil {
+		return migration.Endpoint{}, err
+	}
+
+	databasePrefix, err := cmdutils.GetUserSetVar(cmd, prefixFlagName, prefixEnvKey, true)
+	if err != nil {
+		return migration.Endpoint{}, err
+	}
+
+	var edvProvider edvprovider.EDVProvider
+
+	var storageProvider storage.Provider
+
+	switch {
+	case strings.EqualFold(databaseType, databaseTypeMemOption):
+		edvProvider = memedvprovider.NewProvider()
+		storageProvider = memstore.NewProvider()
+	case strings.EqualFold(databaseType, databaseTypeCouchDBOption):
+		if isSource && databasePrefix == "" {
+			return migration.Endpoint{}, fmt.Errorf("%s: %w", prefixFlagName, errMissingDatabasePrefix)
+		}
+
+		edvProvider, err = couchdbedvprovider.NewProvider(databaseURL, databasePrefix)
+		if err != nil {
+			return migration.Endpoint{}, err
+		}
+
+		storageProvider, err = couchdbstore.NewProvider(databaseURL, couchdbstore.WithDBPrefix(databasePrefix))
+		if err != nil {
+			return migration.Endpoint{}, err
+		}
+	default:
+		return migration.Endpoint{}, fmt.Errorf("%s: %w", typeFlagName, errInvalidDatabaseType)
+	}
+
+	return migration.Endpoint{EDVProvider: edvProvider, StorageProvider: storageProvider}, nil
+}
+
+func getOptions(cmd *cobra.Command) ([]migration.Option, error) {
+	dryRunString, err := cmdutils.GetUserSetVar(cmd, dryRunFlagName, dryRunEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	dryRun := false
+
+	if dryRunString != "" {
+		dryRun, err = strconv.ParseBool(dryRunString)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", dryRunFlagName, err)
+		}
+	}
+
+	batchSizeString, err := cmdutils.GetUserSetVar(cmd, batchSizeFlagName, batchSizeEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	batchSize := migration.DefaultBatchSize
+
+	if batchSizeString != "" {
+		batchSize, err = strconv.Atoi(batchSizeString)
+		if err != nil || batchSize < 1 {
+			return nil, fmt.Errorf("invalid value for %s: must be an integer greater than 0", batchSizeFlagName)
+		}
+	}
+
+	return []migration.Option{migration.WithDryRun(dryRun), migration.WithBatchSize(batchSize),
+		migration.WithProgress(cmd.ErrOrStderr())}, nil
+}
+
+// migrate runs the migration, reporting its progress on standard error and each vault's outcome on standard output.
+func migrate(cmd *cobra.Command, source, destination migration.Endpoint, opts ...migration.Option) error {
+	defer closeEndpoint(source, "source")
+	defer closeEndpoint(destination, "destination")
+
+	report, migrateErr := migration.New(source, destination, opts...).Migrate()
+
+	// The vaults that were migrated before an error are still reported.
+	if report != nil {
+		err := writeReport(cmd, report)
+		if err != nil {
+			return err
+		}
+	}
+
+	if migrateErr != nil {
+		return migrateErr
+	}
+
+	if !report.Verified() {
+		return fmt.Errorf("%d of %d vaults couldn't be verified", countUnverified(report), len(report.Vaults))
+	}
+
+	return nil
+}
+
+func writeReport(cmd *cobra.Command, report *migration.Report) error {
+	for i := range report.Vaults {
+		err := writeVaultReport(cmd.OutOrStdout(), &report.Vaults[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeVaultReport(out io.Writer, vault *migration.VaultReport) error {
+	if vault.Verification == nil {
+		_, err := fmt.Fprintf(out, "Vault %s: %d documents to copy\n", vault.ID, vault.Documents)
+		if err != nil {
+			return err
+		}
+	} else {
+		_, err := fmt.Fprintf(out, "Vault %s: %d documents, %d copied, %d already copied, %d in the destination\n",
+			vault.ID, vault.Documents, vault.Copied, vault.AlreadyCopied, vault.Verification.Documents)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, failure := range vault.Failed {
+		_, err := fmt.Fprintf(out, "Failed to copy document %s: %s\n", failure.ID, failure.Err)
+		if err != nil {
+			return err
+		}
+	}
+
+	if vault.Verification == nil {
+		return nil
+	}
+
+	for _, docID := range vault.Verification.Mismatched {
+		_, err := fmt.Fprintf(out, "Document %s is missing or different in the destination\n", docID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func countUnverified(report *migration.Report) int {
+	count := 0
+
+	for i := range report.Vaults {
+		if !report.Vaults[i].Verified() {
+			count++
+		}
+	}
+
+	return count
+}
+
+func closeEndpoint(endpoint migration.Endpoint, name string) {
+	if err := endpoint.EDVProvider.Close(); err != nil {
+		log.Errorf("Failed to close %s EDV provider: %s", name, err.Error())
+	}
+
+	if err := endpoint.StorageProvider.Close(); err != nil {
+		log.Errorf("Failed to close %s storage provider: %s", name, err.Error())
+	}
+}
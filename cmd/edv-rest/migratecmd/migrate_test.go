@@ -0,0 +1,143 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package migratecmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/migration"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+const testVaultID = "testvault"
+
+func TestGetMigrateCmd(t *testing.T) {
+	migrateCmd := GetMigrateCmd()
+
+	require.Equal(t, "migrate", migrateCmd.Use)
+
+	output, err := execute("--"+fromTypeFlagName, "mem", "--"+toTypeFlagName, "MEM", "--"+dryRunFlagName, "true",
+		"--"+batchSizeFlagName, "10")
+	require.NoError(t, err)
+	require.Empty(t, output)
+}
+
+func TestInvalidParameters(t *testing.T) {
+	for _, test := range []struct {
+		args []string
+		err  string
+	}{
+		{args: []string{"--" + toTypeFlagName, "mem"}, err: fromTypeFlagName},
+		{args: []string{"--" + fromTypeFlagName, "mem"}, err: toTypeFlagName},
+		{args: []string{"--" + fromTypeFlagName, "mysql", "--" + toTypeFlagName, "mem"},
+			err: fromTypeFlagName + ": " + errInvalidDatabaseType.Error()},
+		{args: []string{"--" + fromTypeFlagName, "couchdb", "--" + fromPrefixFlagName, "edv", "--" + toTypeFlagName,
+			"mem"}, err: "couchDB database URL not set"},
+		{args: []string{"--" + fromTypeFlagName, "couchdb", "--" + fromURLFlagName, "localhost:5984",
+			"--" + toTypeFlagName, "mem"}, err: fromPrefixFlagName + ": " + errMissingDatabasePrefix.Error()},
+		{args: []string{"--" + fromTypeFlagName, "mem", "--" + toTypeFlagName, "couchdb"},
+			err: "couchDB database URL not set"},
+		{args: []string{"--" + fromTypeFlagName, "mem", "--" + toTypeFlagName, "mem", "--" + dryRunFlagName, "maybe"},
+			err: "invalid value for " + dryRunFlagName},
+		{args: []string{"--" + fromTypeFlagName, "mem", "--" + toTypeFlagName, "mem", "--" + batchSizeFlagName, "0"},
+			err: "invalid value for " + batchSizeFlagName + ": must be an integer greater than 0"},
+	} {
+		_, err := execute(test.args...)
+		require.Error(t, err, test.args)
+		require.Contains(t, err.Error(), test.err, test.args)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		source := createTestSource(t)
+
+		cmd, output := newTestCmd()
+
+		err := migrate(cmd, source, newTestEndpoint())
+		require.NoError(t, err)
+		require.Equal(t, "Vault testvault: 1 documents, 1 copied, 0 already copied, 1 in the destination\n",
+			output.String())
+	})
+	t.Run("Dry run", func(t *testing.T) {
+		source := createTestSource(t)
+
+		cmd, output := newTestCmd()
+
+		err := migrate(cmd, source, newTestEndpoint(), migration.WithDryRun(true))
+		require.NoError(t, err)
+		require.Equal(t, "Vault testvault: 1 documents to copy\n", output.String())
+	})
+	t.Run("Vault that doesn't match after copying", func(t *testing.T) {
+		source := createTestSource(t)
+		destination := newTestEndpoint()
+
+		require.NoError(t, destination.EDVProvider.CreateStore(testVaultID))
+
+		store, err := destination.EDVProvider.OpenStore(testVaultID)
+		require.NoError(t, err)
+
+		require.NoError(t, store.Put(models.EncryptedDocument{ID: "doc1", Sequence: 1}))
+
+		cmd, output := newTestCmd()
+
+		err = migrate(cmd, source, destination)
+		require.EqualError(t, err, "1 of 1 vaults couldn't be verified")
+		require.Equal(t, "Vault testvault: 1 documents, 0 copied, 0 already copied, 1 in the destination\n"+
+			"Failed to copy document doc1: a different document with the same ID is already in the destination vault\n"+
+			"Document doc1 is missing or different in the destination\n", output.String())
+	})
+}
+
+func createTestSource(t *testing.T) migration.Endpoint {
+	source := newTestEndpoint()
+
+	require.NoError(t, source.EDVProvider.CreateStore(testVaultID))
+
+	store, err := source.EDVProvider.OpenStore(testVaultID)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Put(models.EncryptedDocument{ID: "doc1"}))
+
+	return source
+}
+
+func newTestEndpoint() migration.Endpoint {
+	return migration.Endpoint{EDVProvider: memedvprovider.NewProvider(), StorageProvider: memstore.NewProvider()}
+}
+
+func newTestCmd() (*cobra.Command, *bytes.Buffer) {
+	cmd := &cobra.Command{}
+
+	var output bytes.Buffer
+
+	cmd.SetOut(&output)
+	cmd.SetErr(ioutil.Discard)
+
+	return cmd, &output
+}
+
+func execute(args ...string) (string, error) {
+	migrateCmd := GetMigrateCmd()
+
+	var output bytes.Buffer
+
+	migrateCmd.SetOut(&output)
+	migrateCmd.SetErr(ioutil.Discard)
+	migrateCmd.SetArgs(args)
+
+	err := migrateCmd.Execute()
+
+	return output.String(), err
+}
@@ -7,19 +7,46 @@ SPDX-License-Identifier: Apache-2.0
 package startcmd
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/trustbloc/edge-core/pkg/storage"
+	couchdbstore "github.com/trustbloc/edge-core/pkg/storage/couchdb"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
 
+	"github.com/trustbloc/edv/pkg/audit"
+	edvclient "github.com/trustbloc/edv/pkg/client/edv"
 	"github.com/trustbloc/edv/pkg/edvprovider"
 	"github.com/trustbloc/edv/pkg/edvprovider/couchdbedvprovider"
 	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/edvprovider/metricsedvprovider"
+	"github.com/trustbloc/edv/pkg/jwe"
+	"github.com/trustbloc/edv/pkg/metrics"
+	"github.com/trustbloc/edv/pkg/principal"
+	"github.com/trustbloc/edv/pkg/ratelimit"
+	"github.com/trustbloc/edv/pkg/replication"
+	"github.com/trustbloc/edv/pkg/requestlog"
 	"github.com/trustbloc/edv/pkg/restapi/edv"
+	"github.com/trustbloc/edv/pkg/restapi/edv/operation"
 	cmdutils "github.com/trustbloc/edv/pkg/utils/cmd"
+	"github.com/trustbloc/edv/pkg/webhook"
 )
 
 const (
@@ -53,30 +80,320 @@ const (
 		" This followed by an underscore will be prepended to any incoming vault IDs received in REST calls before" +
 		" creating or accessing underlying databases." +
 		" Alternatively, this can be set with the following environment variable: " + databasePrefixEnvKey
+
+	tlsCertFileFlagName  = "tls-cert"
+	tlsCertFileEnvKey    = "EDV_TLS_CERT"
+	tlsCertFileFlagUsage = "Path to a PEM-encoded TLS certificate. If set along with " + tlsKeyFileFlagName +
+		", the EDV will serve HTTPS instead of HTTP." +
+		" Alternatively, this can be set with the following environment variable: " + tlsCertFileEnvKey
+
+	tlsKeyFileFlagName  = "tls-key"
+	tlsKeyFileEnvKey    = "EDV_TLS_KEY"
+	tlsKeyFileFlagUsage = "Path to the PEM-encoded private key for the TLS certificate." +
+		" Alternatively, this can be set with the following environment variable: " + tlsKeyFileEnvKey
+
+	tlsClientCAFileFlagName  = "tls-client-ca"
+	tlsClientCAFileEnvKey    = "EDV_TLS_CLIENT_CA"
+	tlsClientCAFileFlagUsage = "Optional path to a PEM-encoded CA certificate bundle. If set, clients must present" +
+		" a certificate signed by one of these CAs (mutual TLS). Requires " + tlsCertFileFlagName + " and " +
+		tlsKeyFileFlagName + " to be set." +
+		" Alternatively, this can be set with the following environment variable: " + tlsClientCAFileEnvKey
+
+	shutdownTimeoutFlagName  = "shutdown-timeout"
+	shutdownTimeoutEnvKey    = "EDV_SHUTDOWN_TIMEOUT"
+	shutdownTimeoutFlagUsage = "How long to wait for in-flight requests to complete when shutting down after" +
+		" receiving a SIGINT or SIGTERM signal, as a Go duration (e.g. 30s). Defaults to " +
+		defaultShutdownTimeout + "." +
+		" Alternatively, this can be set with the following environment variable: " + shutdownTimeoutEnvKey
+
+	defaultShutdownTimeout = "30s"
+
+	logLevelFlagName  = "log-level"
+	logLevelEnvKey    = "EDV_LOG_LEVEL"
+	logLevelFlagUsage = "Logging level. Supported options: panic, fatal, error, warn, info, debug, trace." +
+		" Defaults to info." +
+		" Alternatively, this can be set with the following environment variable: " + logLevelEnvKey
+
+	logFormatFlagName  = "log-format"
+	logFormatEnvKey    = "EDV_LOG_FORMAT"
+	logFormatFlagUsage = "Logging format. Supported options: " + requestlog.TextFormat + ", " +
+		requestlog.JSONFormat + ". Defaults to " + requestlog.TextFormat + "." +
+		" Alternatively, this can be set with the following environment variable: " + logFormatEnvKey
+
+	auditLogTypeFlagName  = "audit-log-type"
+	auditLogTypeEnvKey    = "EDV_AUDIT_LOG_TYPE"
+	auditLogTypeFlagUsage = "Where to keep a tamper-evident audit log of vault operations. Supported options: " +
+		auditLogTypeNoneOption + ", " + auditLogTypeFileOption + ", " + auditLogTypeDatabaseOption + ". " +
+		auditLogTypeFileOption + " appends to the file set with " + auditLogPathFlagName + ", while " +
+		auditLogTypeDatabaseOption + " stores the audit log in the database the EDV is configured to use." +
+		" Defaults to " + auditLogTypeNoneOption + "." +
+		" Alternatively, this can be set with the following environment variable: " + auditLogTypeEnvKey
+
+	auditLogTypeNoneOption     = "none"
+	auditLogTypeFileOption     = "file"
+	auditLogTypeDatabaseOption = "database"
+
+	auditLogPathFlagName  = "audit-log-path"
+	auditLogPathEnvKey    = "EDV_AUDIT_LOG_PATH"
+	auditLogPathFlagUsage = "Path to the audit log file. Required if " + auditLogTypeFlagName + " is set to " +
+		auditLogTypeFileOption + "." +
+		" Alternatively, this can be set with the following environment variable: " + auditLogPathEnvKey
+
+	writeRateLimitFlagName  = "write-rate-limit"
+	writeRateLimitEnvKey    = "EDV_WRITE_RATE_LIMIT"
+	writeRateLimitFlagUsage = "The maximum sustained number of vault and document creation requests per second" +
+		" allowed from each client and for each vault. Clients are identified by their TLS client certificate if" +
+		" mutual TLS is used, or by IP address otherwise. Requests over the limit are rejected with a 429 status code." +
+		" Defaults to no limit." +
+		" Alternatively, this can be set with the following environment variable: " + writeRateLimitEnvKey
+
+	writeRateBurstFlagName  = "write-rate-burst"
+	writeRateBurstEnvKey    = "EDV_WRITE_RATE_BURST"
+	writeRateBurstFlagUsage = "The number of vault and document creation requests that can be made in a burst above " +
+		writeRateLimitFlagName + ". Defaults to " + writeRateLimitFlagName + " rounded up." +
+		" Alternatively, this can be set with the following environment variable: " + writeRateBurstEnvKey
+
+	queryRateLimitFlagName  = "query-rate-limit"
+	queryRateLimitEnvKey    = "EDV_QUERY_RATE_LIMIT"
+	queryRateLimitFlagUsage = "The maximum sustained number of vault queries per second allowed from each client" +
+		" and for each vault. Requests over the limit are rejected with a 429 status code. Defaults to no limit." +
+		" Alternatively, this can be set with the following environment variable: " + queryRateLimitEnvKey
+
+	queryRateBurstFlagName  = "query-rate-burst"
+	queryRateBurstEnvKey    = "EDV_QUERY_RATE_BURST"
+	queryRateBurstFlagUsage = "The number of vault queries that can be made in a burst above " +
+		queryRateLimitFlagName + ". Defaults to " + queryRateLimitFlagName + " rounded up." +
+		" Alternatively, this can be set with the following environment variable: " + queryRateBurstEnvKey
+
+	maxVaultConfigurationSizeFlagName  = "max-vault-configuration-size"
+	maxVaultConfigurationSizeEnvKey    = "EDV_MAX_VAULT_CONFIGURATION_SIZE"
+	maxVaultConfigurationSizeFlagUsage = "The maximum size, in bytes, of the request body when creating a vault." +
+		" Larger requests are rejected with a 413 status code. Defaults to 65536." +
+		" Alternatively, this can be set with the following environment variable: " + maxVaultConfigurationSizeEnvKey
+
+	maxQuerySizeFlagName  = "max-query-size"
+	maxQuerySizeEnvKey    = "EDV_MAX_QUERY_SIZE"
+	maxQuerySizeFlagUsage = "The maximum size, in bytes, of the request body when querying a vault." +
+		" Larger requests are rejected with a 413 status code. Defaults to 65536." +
+		" Alternatively, this can be set with the following environment variable: " + maxQuerySizeEnvKey
+
+	maxDocumentSizeFlagName  = "max-document-size"
+	maxDocumentSizeEnvKey    = "EDV_MAX_DOCUMENT_SIZE"
+	maxDocumentSizeFlagUsage = "The maximum size, in bytes, of the request body when creating a document." +
+		" Larger requests are rejected with a 413 status code. Defaults to 16777216." +
+		" Alternatively, this can be set with the following environment variable: " + maxDocumentSizeEnvKey
+
+	maxBatchSizeFlagName  = "max-batch-size"
+	maxBatchSizeEnvKey    = "EDV_MAX_BATCH_SIZE"
+	maxBatchSizeFlagUsage = "The maximum size, in bytes, of the request body when applying a batch of document" +
+		" operations. Larger requests are rejected with a 413 status code. Defaults to 67108864." +
+		" Alternatively, this can be set with the following environment variable: " + maxBatchSizeEnvKey
+
+	maxImportSizeFlagName  = "max-import-size"
+	maxImportSizeEnvKey    = "EDV_MAX_IMPORT_SIZE"
+	maxImportSizeFlagUsage = "The maximum size, in bytes, of the request body when importing a vault export." +
+		" Larger requests are rejected with a 413 status code. Defaults to 1073741824." +
+		" Alternatively, this can be set with the following environment variable: " + maxImportSizeEnvKey
+
+	strictJSONFlagName  = "strict-json"
+	strictJSONEnvKey    = "EDV_STRICT_JSON"
+	strictJSONFlagUsage = "Set to true to reject request bodies that have unknown fields or data after the JSON value." +
+		" Defaults to false." +
+		" Alternatively, this can be set with the following environment variable: " + strictJSONEnvKey
+
+	eventPollIntervalFlagName  = "event-poll-interval"
+	eventPollIntervalEnvKey    = "EDV_EVENT_POLL_INTERVAL"
+	eventPollIntervalFlagUsage = "How often vault event streams and webhooks check for documents written through other" +
+		" EDV server instances, and how often failed webhook deliveries are checked for retries, as a Go duration" +
+		" (e.g. 2s). Documents written through the same instance are sent right away." +
+		" Defaults to " + defaultEventPollInterval + "." +
+		" Alternatively, this can be set with the following environment variable: " + eventPollIntervalEnvKey
+
+	defaultEventPollInterval = "2s"
+
+	webhookMaxAttemptsFlagName  = "webhook-max-attempts"
+	webhookMaxAttemptsEnvKey    = "EDV_WEBHOOK_MAX_ATTEMPTS"
+	webhookMaxAttemptsFlagUsage = "How many times an event is sent to a webhook before it's given up on." +
+		" The wait between attempts starts at 1s and doubles after every failed attempt, up to 1h." +
+		" Defaults to 10." +
+		" Alternatively, this can be set with the following environment variable: " + webhookMaxAttemptsEnvKey
+
+	webhookAllowedHostsFlagName  = "webhook-allowed-hosts"
+	webhookAllowedHostsEnvKey    = "EDV_WEBHOOK_ALLOWED_HOSTS"
+	webhookAllowedHostsFlagUsage = "Comma-separated list of the only hosts that webhook URLs may have." +
+		" These hosts are trusted, so they may be on the EDV server's own network." +
+		" By default, any host is allowed except ones that resolve to addresses that aren't globally reachable," +
+		" such as loopback, private, link-local or carrier-grade NAT addresses." +
+		" Alternatively, this can be set with the following environment variable: " + webhookAllowedHostsEnvKey
+
+	replicationSourceURLFlagName  = "replication-source-url"
+	replicationSourceURLEnvKey    = "EDV_REPLICATION_SOURCE_URL"
+	replicationSourceURLFlagUsage = "The URL of the encrypted-data-vaults endpoint of another EDV server to replicate" +
+		" vaults from (e.g. https://edv.example.com/encrypted-data-vaults). If set, then " +
+		replicationVaultIDsFlagName + " must be set too. Replication is disabled by default." +
+		" Alternatively, this can be set with the following environment variable: " + replicationSourceURLEnvKey
+
+	replicationVaultIDsFlagName  = "replication-vault-ids"
+	replicationVaultIDsEnvKey    = "EDV_REPLICATION_VAULT_IDS"
+	replicationVaultIDsFlagUsage = "Comma-separated list of the IDs of the vaults to replicate from " +
+		replicationSourceURLFlagName + ". Each vault must already exist on this server." +
+		" Alternatively, this can be set with the following environment variable: " + replicationVaultIDsEnvKey
+
+	replicationModeFlagName  = "replication-mode"
+	replicationModeEnvKey    = "EDV_REPLICATION_MODE"
+	replicationModeFlagUsage = "How vaults are replicated. Supported options: " + replicationModeContinuousOption +
+		", " + replicationModeOnceOption + ". " + replicationModeContinuousOption + " keeps checking for changes every " +
+		replicationIntervalFlagName + ". " + replicationModeOnceOption + " replicates each vault once when the server" +
+		" starts. Defaults to " + replicationModeContinuousOption + "." +
+		" Alternatively, this can be set with the following environment variable: " + replicationModeEnvKey
+
+	replicationModeContinuousOption = "continuous"
+	replicationModeOnceOption       = "once"
+
+	replicationIntervalFlagName  = "replication-interval"
+	replicationIntervalEnvKey    = "EDV_REPLICATION_INTERVAL"
+	replicationIntervalFlagUsage = "How often " + replicationSourceURLFlagName + " is checked for changes in " +
+		replicationModeContinuousOption + " mode, as a Go duration (e.g. 10s). Defaults to " +
+		defaultReplicationInterval + "." +
+		" Alternatively, this can be set with the following environment variable: " + replicationIntervalEnvKey
+
+	defaultReplicationInterval = "10s"
+
+	replicationTLSCAFileFlagName  = "replication-tls-ca"
+	replicationTLSCAFileEnvKey    = "EDV_REPLICATION_TLS_CA"
+	replicationTLSCAFileFlagUsage = "Optional path to a PEM-encoded CA certificate bundle used to verify the" +
+		" certificate of " + replicationSourceURLFlagName + " instead of the system's. If " + tlsCertFileFlagName +
+		" and " + tlsKeyFileFlagName + " are set, then they're also presented as the client certificate." +
+		" Alternatively, this can be set with the following environment variable: " + replicationTLSCAFileEnvKey
+
+	jweAllowedAlgorithmsFlagName  = "jwe-allowed-algorithms"
+	jweAllowedAlgorithmsEnvKey    = "EDV_JWE_ALLOWED_ALGORITHMS"
+	jweAllowedAlgorithmsFlagUsage = "Comma-separated list of the key management algorithms (JWE alg header values)" +
+		" that the JWEs of incoming documents may use. Defaults to the algorithms used by the EDV specification" +
+		" and the Aries JWE packers." +
+		" Alternatively, this can be set with the following environment variable: " + jweAllowedAlgorithmsEnvKey
+
+	jweAllowedEncryptionsFlagName  = "jwe-allowed-encryptions"
+	jweAllowedEncryptionsEnvKey    = "EDV_JWE_ALLOWED_ENCRYPTIONS"
+	jweAllowedEncryptionsFlagUsage = "Comma-separated list of the content encryption algorithms (JWE enc header" +
+		" values) that the JWEs of incoming documents may use. Defaults to the algorithms used by the EDV" +
+		" specification and the Aries JWE packers." +
+		" Alternatively, this can be set with the following environment variable: " + jweAllowedEncryptionsEnvKey
+
+	metricsEndpoint = "/metrics"
 )
 
 var errMissingHostURL = fmt.Errorf("host URL not provided")
 var errInvalidDatabaseType = fmt.Errorf("database type not set to a valid type." +
 	" run start --help to see the available options")
+var errIncompleteTLSParameters = fmt.Errorf("both " + tlsCertFileFlagName + " and " + tlsKeyFileFlagName +
+	" must be set in order to use TLS")
+var errClientCAWithoutTLS = fmt.Errorf(tlsClientCAFileFlagName + " can only be used if " + tlsCertFileFlagName +
+	" and " + tlsKeyFileFlagName + " are set")
+var errNoClientCACertificates = fmt.Errorf("no PEM-encoded certificates found in the client CA file")
+var errInvalidAuditLogType = fmt.Errorf("audit log type not set to a valid type." +
+	" run start --help to see the available options")
+var errMissingReplicationVaultIDs = fmt.Errorf(replicationVaultIDsFlagName + " must be set when " +
+	replicationSourceURLFlagName + " is set")
+var errReplicationWithoutSource = fmt.Errorf(replicationVaultIDsFlagName + " can only be used if " +
+	replicationSourceURLFlagName + " is set")
+var errInvalidReplicationMode = fmt.Errorf("replication mode not set to a valid mode." +
+	" run start --help to see the available options")
+var errNoReplicationCACertificates = fmt.Errorf("no PEM-encoded certificates found in the replication CA file")
+var errMissingAuditLogPath = fmt.Errorf(auditLogPathFlagName + " must be set when " + auditLogTypeFlagName +
+	" is " + auditLogTypeFileOption)
 
 type edvParameters struct {
-	srv            server
-	hostURL        string
-	databaseType   string
-	databaseURL    string
-	databasePrefix string
+	srv                 server
+	hostURL             string
+	databaseType        string
+	databaseURL         string
+	databasePrefix      string
+	tlsCertFile         string
+	tlsKeyFile          string
+	tlsClientCAFile     string
+	shutdownTimeout     time.Duration
+	auditLogType        string
+	auditLogPath        string
+	writeRateLimit      rateLimitParameters
+	queryRateLimit      rateLimitParameters
+	bodySizeLimits      operation.BodySizeLimits
+	strictJSON          bool
+	jwePolicy           jwe.Policy
+	eventPollInterval   time.Duration
+	webhookMaxAttempts  int64
+	webhookAllowedHosts []string
+	replication         replicationParameters
+}
+
+// replicationParameters holds the settings for replicating vaults from another EDV server.
+// A blank sourceURL means that replication is disabled.
+type replicationParameters struct {
+	sourceURL string
+	vaultIDs  []string
+	once      bool
+	interval  time.Duration
+	tlsCAFile string
+}
+
+// rateLimitParameters holds the token bucket settings for a class of requests.
+// A requestsPerSecond value of 0 means that the requests aren't rate limited.
+type rateLimitParameters struct {
+	requestsPerSecond float64
+	burst             int
 }
 
 type server interface {
 	ListenAndServe(host string, router http.Handler) error
+	ListenAndServeTLS(host, certFile, keyFile string, tlsConfig *tls.Config, router http.Handler) error
+	Shutdown(ctx context.Context) error
 }
 
 // HTTPServer represents an actual HTTP server implementation.
-type HTTPServer struct{}
+type HTTPServer struct {
+	srv *http.Server
+	mux sync.Mutex
+}
 
 // ListenAndServe starts the server using the standard Go HTTP server implementation.
 func (s *HTTPServer) ListenAndServe(host string, router http.Handler) error {
-	return http.ListenAndServe(host, router)
+	return s.newServer(host, router, nil).ListenAndServe()
+}
+
+// ListenAndServeTLS starts the server using the standard Go HTTPS server implementation.
+// The given TLS config may be used to require and verify client certificates.
+func (s *HTTPServer) ListenAndServeTLS(host, certFile, keyFile string, tlsConfig *tls.Config,
+	router http.Handler) error {
+	return s.newServer(host, router, tlsConfig).ListenAndServeTLS(certFile, keyFile)
+}
+
+// Shutdown stops the server from accepting new connections and waits for in-flight requests to complete
+// until the given context expires.
+func (s *HTTPServer) Shutdown(ctx context.Context) error {
+	s.mux.Lock()
+	srv := s.srv
+	s.mux.Unlock()
+
+	if srv == nil {
+		return nil
+	}
+
+	return srv.Shutdown(ctx)
+}
+
+func (s *HTTPServer) newServer(host string, router http.Handler, tlsConfig *tls.Config) *http.Server {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	// Long-lived requests such as event streams only end when their context is done, so the context of every request
+	// is cancelled when the server starts shutting down. Otherwise, shutting down would always take the full timeout.
+	baseContext, cancel := context.WithCancel(context.Background())
+
+	s.srv = &http.Server{Addr: host, Handler: router, TLSConfig: tlsConfig,
+		BaseContext: func(net.Listener) context.Context { return baseContext }}
+	s.srv.RegisterOnShutdown(cancel)
+
+	return s.srv
 }
 
 // GetStartCmd returns the Cobra start command.
@@ -94,43 +411,456 @@ func createStartCmd(srv server) *cobra.Command {
 		Short: "Start EDV",
 		Long:  "Start EDV",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			hostURL, err := cmdutils.GetUserSetVar(cmd, hostURLFlagName, hostURLEnvKey, false)
-			if err != nil {
-				return err
-			}
-
-			databaseType, err := cmdutils.GetUserSetVar(cmd, databaseTypeFlagName, databaseTypeEnvKey, false)
+			err := setUpLogging(cmd)
 			if err != nil {
 				return err
 			}
 
-			databaseURL, err := cmdutils.GetUserSetVar(cmd, databaseURLFlagName, databaseURLEnvKey, true)
+			parameters, err := getEDVParameters(cmd, srv)
 			if err != nil {
 				return err
 			}
 
-			databasePrefix, err := cmdutils.GetUserSetVar(cmd, databasePrefixFlagName, databasePrefixEnvKey, true)
-			if err != nil {
-				return err
-			}
-
-			parameters := &edvParameters{
-				srv:            srv,
-				hostURL:        hostURL,
-				databaseType:   databaseType,
-				databaseURL:    databaseURL,
-				databasePrefix: databasePrefix,
-			}
 			return startEDV(parameters)
 		},
 	}
 }
 
+func getEDVParameters(cmd *cobra.Command, srv server) (*edvParameters, error) {
+	hostURL, err := cmdutils.GetUserSetVar(cmd, hostURLFlagName, hostURLEnvKey, false)
+	if err != nil {
+		return nil, err
+	}
+
+	databaseType, databaseURL, databasePrefix, err := getDatabaseParameters(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsCertFile, tlsKeyFile, tlsClientCAFile, err := getTLSParameters(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	shutdownTimeout, err := getShutdownTimeout(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	parameters := &edvParameters{
+		srv:             srv,
+		hostURL:         hostURL,
+		databaseType:    databaseType,
+		databaseURL:     databaseURL,
+		databasePrefix:  databasePrefix,
+		tlsCertFile:     tlsCertFile,
+		tlsKeyFile:      tlsKeyFile,
+		tlsClientCAFile: tlsClientCAFile,
+		shutdownTimeout: shutdownTimeout,
+	}
+
+	err = getRequestHandlingParameters(cmd, parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	parameters.replication, err = getReplicationParameters(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return parameters, nil
+}
+
+// getRequestHandlingParameters sets the parameters that control how the EDV handles individual requests.
+func getRequestHandlingParameters(cmd *cobra.Command, parameters *edvParameters) error {
+	var err error
+
+	parameters.auditLogType, parameters.auditLogPath, err = getAuditLogParameters(cmd)
+	if err != nil {
+		return err
+	}
+
+	parameters.writeRateLimit, parameters.queryRateLimit, err = getRateLimitParameters(cmd)
+	if err != nil {
+		return err
+	}
+
+	parameters.bodySizeLimits, err = getBodySizeLimits(cmd)
+	if err != nil {
+		return err
+	}
+
+	parameters.strictJSON, err = getBool(cmd, strictJSONFlagName, strictJSONEnvKey)
+	if err != nil {
+		return err
+	}
+
+	parameters.jwePolicy, err = getJWEPolicy(cmd)
+	if err != nil {
+		return err
+	}
+
+	parameters.eventPollInterval, err = getDuration(cmd, eventPollIntervalFlagName, eventPollIntervalEnvKey,
+		defaultEventPollInterval)
+	if err != nil {
+		return err
+	}
+
+	parameters.webhookMaxAttempts, err = getPositiveInt(cmd, webhookMaxAttemptsFlagName, webhookMaxAttemptsEnvKey)
+	if err != nil {
+		return err
+	}
+
+	parameters.webhookAllowedHosts, err = getList(cmd, webhookAllowedHostsFlagName, webhookAllowedHostsEnvKey)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func createFlags(startCmd *cobra.Command) {
 	startCmd.Flags().StringP(hostURLFlagName, hostURLFlagShorthand, "", hostURLFlagUsage)
 	startCmd.Flags().StringP(databaseTypeFlagName, databaseTypeFlagShorthand, "", databaseTypeFlagUsage)
 	startCmd.Flags().StringP(databaseURLFlagName, databaseURLFlagShorthand, "", databaseURLFlagUsage)
 	startCmd.Flags().StringP(databasePrefixFlagName, databasePrefixFlagShorthand, "", databasePrefixFlagUsage)
+	startCmd.Flags().String(tlsCertFileFlagName, "", tlsCertFileFlagUsage)
+	startCmd.Flags().String(tlsKeyFileFlagName, "", tlsKeyFileFlagUsage)
+	startCmd.Flags().String(tlsClientCAFileFlagName, "", tlsClientCAFileFlagUsage)
+	startCmd.Flags().String(shutdownTimeoutFlagName, "", shutdownTimeoutFlagUsage)
+	startCmd.Flags().String(logLevelFlagName, "", logLevelFlagUsage)
+	startCmd.Flags().String(logFormatFlagName, "", logFormatFlagUsage)
+	startCmd.Flags().String(auditLogTypeFlagName, "", auditLogTypeFlagUsage)
+	startCmd.Flags().String(auditLogPathFlagName, "", auditLogPathFlagUsage)
+	startCmd.Flags().String(writeRateLimitFlagName, "", writeRateLimitFlagUsage)
+	startCmd.Flags().String(writeRateBurstFlagName, "", writeRateBurstFlagUsage)
+	startCmd.Flags().String(queryRateLimitFlagName, "", queryRateLimitFlagUsage)
+	startCmd.Flags().String(queryRateBurstFlagName, "", queryRateBurstFlagUsage)
+	startCmd.Flags().String(maxVaultConfigurationSizeFlagName, "", maxVaultConfigurationSizeFlagUsage)
+	startCmd.Flags().String(maxQuerySizeFlagName, "", maxQuerySizeFlagUsage)
+	startCmd.Flags().String(maxDocumentSizeFlagName, "", maxDocumentSizeFlagUsage)
+	startCmd.Flags().String(maxBatchSizeFlagName, "", maxBatchSizeFlagUsage)
+	startCmd.Flags().String(maxImportSizeFlagName, "", maxImportSizeFlagUsage)
+	startCmd.Flags().String(strictJSONFlagName, "", strictJSONFlagUsage)
+	startCmd.Flags().String(jweAllowedAlgorithmsFlagName, "", jweAllowedAlgorithmsFlagUsage)
+	startCmd.Flags().String(jweAllowedEncryptionsFlagName, "", jweAllowedEncryptionsFlagUsage)
+	startCmd.Flags().String(eventPollIntervalFlagName, "", eventPollIntervalFlagUsage)
+	startCmd.Flags().String(webhookMaxAttemptsFlagName, "", webhookMaxAttemptsFlagUsage)
+	startCmd.Flags().String(webhookAllowedHostsFlagName, "", webhookAllowedHostsFlagUsage)
+	startCmd.Flags().String(replicationSourceURLFlagName, "", replicationSourceURLFlagUsage)
+	startCmd.Flags().String(replicationVaultIDsFlagName, "", replicationVaultIDsFlagUsage)
+	startCmd.Flags().String(replicationModeFlagName, "", replicationModeFlagUsage)
+	startCmd.Flags().String(replicationIntervalFlagName, "", replicationIntervalFlagUsage)
+	startCmd.Flags().String(replicationTLSCAFileFlagName, "", replicationTLSCAFileFlagUsage)
+}
+
+func setUpLogging(cmd *cobra.Command) error {
+	logLevel, err := cmdutils.GetUserSetVar(cmd, logLevelFlagName, logLevelEnvKey, true)
+	if err != nil {
+		return err
+	}
+
+	if logLevel != "" {
+		err = requestlog.SetLevel(logLevel)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %w", logLevelFlagName, err)
+		}
+	}
+
+	logFormat, err := cmdutils.GetUserSetVar(cmd, logFormatFlagName, logFormatEnvKey, true)
+	if err != nil {
+		return err
+	}
+
+	if logFormat != "" {
+		err = requestlog.SetFormat(logFormat)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %w", logFormatFlagName, err)
+		}
+	}
+
+	return nil
+}
+
+func getShutdownTimeout(cmd *cobra.Command) (time.Duration, error) {
+	return getDuration(cmd, shutdownTimeoutFlagName, shutdownTimeoutEnvKey, defaultShutdownTimeout)
+}
+
+// getDuration parses a duration flag, which uses the Go duration format. If the flag isn't set,
+// then the default value is used.
+func getDuration(cmd *cobra.Command, flagName, envKey, defaultValue string) (time.Duration, error) {
+	durationString, err := cmdutils.GetUserSetVar(cmd, flagName, envKey, true)
+	if err != nil {
+		return 0, err
+	}
+
+	if durationString == "" {
+		durationString = defaultValue
+	}
+
+	duration, err := time.ParseDuration(durationString)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for %s: %w", flagName, err)
+	}
+
+	return duration, nil
+}
+
+func getDatabaseParameters(cmd *cobra.Command) (databaseType, databaseURL, databasePrefix string, err error) {
+	databaseType, err = cmdutils.GetUserSetVar(cmd, databaseTypeFlagName, databaseTypeEnvKey, false)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	databaseURL, err = cmdutils.GetUserSetVar(cmd, databaseURLFlagName, databaseURLEnvKey, true)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	databasePrefix, err = cmdutils.GetUserSetVar(cmd, databasePrefixFlagName, databasePrefixEnvKey, true)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	return databaseType, databaseURL, databasePrefix, nil
+}
+
+func getAuditLogParameters(cmd *cobra.Command) (auditLogType, auditLogPath string, err error) {
+	auditLogType, err = cmdutils.GetUserSetVar(cmd, auditLogTypeFlagName, auditLogTypeEnvKey, true)
+	if err != nil {
+		return "", "", err
+	}
+
+	auditLogPath, err = cmdutils.GetUserSetVar(cmd, auditLogPathFlagName, auditLogPathEnvKey, true)
+	if err != nil {
+		return "", "", err
+	}
+
+	return auditLogType, auditLogPath, nil
+}
+
+func getRateLimitParameters(cmd *cobra.Command) (writeRateLimit, queryRateLimit rateLimitParameters, err error) {
+	writeRateLimit, err = getRateLimit(cmd, writeRateLimitFlagName, writeRateLimitEnvKey,
+		writeRateBurstFlagName, writeRateBurstEnvKey)
+	if err != nil {
+		return rateLimitParameters{}, rateLimitParameters{}, err
+	}
+
+	queryRateLimit, err = getRateLimit(cmd, queryRateLimitFlagName, queryRateLimitEnvKey,
+		queryRateBurstFlagName, queryRateBurstEnvKey)
+	if err != nil {
+		return rateLimitParameters{}, rateLimitParameters{}, err
+	}
+
+	return writeRateLimit, queryRateLimit, nil
+}
+
+func getRateLimit(cmd *cobra.Command, limitFlagName, limitEnvKey, burstFlagName,
+	burstEnvKey string) (rateLimitParameters, error) {
+	limitString, err := cmdutils.GetUserSetVar(cmd, limitFlagName, limitEnvKey, true)
+	if err != nil {
+		return rateLimitParameters{}, err
+	}
+
+	burstString, err := cmdutils.GetUserSetVar(cmd, burstFlagName, burstEnvKey, true)
+	if err != nil {
+		return rateLimitParameters{}, err
+	}
+
+	if limitString == "" {
+		return rateLimitParameters{}, nil
+	}
+
+	requestsPerSecond, err := strconv.ParseFloat(limitString, 64)
+	if err != nil || requestsPerSecond <= 0 {
+		return rateLimitParameters{}, fmt.Errorf("invalid value for %s: must be a number greater than 0",
+			limitFlagName)
+	}
+
+	burst := int(math.Ceil(requestsPerSecond))
+
+	if burstString != "" {
+		burst, err = strconv.Atoi(burstString)
+		if err != nil || burst < 1 {
+			return rateLimitParameters{}, fmt.Errorf("invalid value for %s: must be an integer greater than 0",
+				burstFlagName)
+		}
+	}
+
+	return rateLimitParameters{requestsPerSecond: requestsPerSecond, burst: burst}, nil
+}
+
+func getBodySizeLimits(cmd *cobra.Command) (operation.BodySizeLimits, error) {
+	vaultConfigurationSize, err := getPositiveInt(cmd, maxVaultConfigurationSizeFlagName, maxVaultConfigurationSizeEnvKey)
+	if err != nil {
+		return operation.BodySizeLimits{}, err
+	}
+
+	querySize, err := getPositiveInt(cmd, maxQuerySizeFlagName, maxQuerySizeEnvKey)
+	if err != nil {
+		return operation.BodySizeLimits{}, err
+	}
+
+	documentSize, err := getPositiveInt(cmd, maxDocumentSizeFlagName, maxDocumentSizeEnvKey)
+	if err != nil {
+		return operation.BodySizeLimits{}, err
+	}
+
+	batchSize, err := getPositiveInt(cmd, maxBatchSizeFlagName, maxBatchSizeEnvKey)
+	if err != nil {
+		return operation.BodySizeLimits{}, err
+	}
+
+	importSize, err := getPositiveInt(cmd, maxImportSizeFlagName, maxImportSizeEnvKey)
+	if err != nil {
+		return operation.BodySizeLimits{}, err
+	}
+
+	return operation.BodySizeLimits{
+		VaultConfiguration: vaultConfigurationSize,
+		Query:              querySize,
+		Document:           documentSize,
+		Batch:              batchSize,
+		Import:             importSize,
+	}, nil
+}
+
+// getPositiveInt returns the positive integer (e.g. a size in bytes) set by the given flag or environment variable,
+// or 0 if neither is set.
+func getPositiveInt(cmd *cobra.Command, flagName, envKey string) (int64, error) {
+	valueString, err := cmdutils.GetUserSetVar(cmd, flagName, envKey, true)
+	if err != nil {
+		return 0, err
+	}
+
+	if valueString == "" {
+		return 0, nil
+	}
+
+	value, err := strconv.ParseInt(valueString, 10, 64)
+	if err != nil || value < 1 {
+		return 0, fmt.Errorf("invalid value for %s: must be an integer greater than 0", flagName)
+	}
+
+	return value, nil
+}
+
+func getBool(cmd *cobra.Command, flagName, envKey string) (bool, error) {
+	boolString, err := cmdutils.GetUserSetVar(cmd, flagName, envKey, true)
+	if err != nil {
+		return false, err
+	}
+
+	if boolString == "" {
+		return false, nil
+	}
+
+	value, err := strconv.ParseBool(boolString)
+	if err != nil {
+		return false, fmt.Errorf("invalid value for %s: %w", flagName, err)
+	}
+
+	return value, nil
+}
+
+func getJWEPolicy(cmd *cobra.Command) (jwe.Policy, error) {
+	allowedAlgorithms, err := getList(cmd, jweAllowedAlgorithmsFlagName, jweAllowedAlgorithmsEnvKey)
+	if err != nil {
+		return jwe.Policy{}, err
+	}
+
+	allowedEncryptions, err := getList(cmd, jweAllowedEncryptionsFlagName, jweAllowedEncryptionsEnvKey)
+	if err != nil {
+		return jwe.Policy{}, err
+	}
+
+	return jwe.Policy{AllowedAlgorithms: allowedAlgorithms, AllowedEncryptions: allowedEncryptions}, nil
+}
+
+// getList returns the non-blank values of the comma-separated list set by the given flag or environment variable.
+func getList(cmd *cobra.Command, flagName, envKey string) ([]string, error) {
+	listString, err := cmdutils.GetUserSetVar(cmd, flagName, envKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+
+	for _, value := range strings.Split(listString, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values, nil
+}
+
+func getReplicationParameters(cmd *cobra.Command) (replicationParameters, error) {
+	sourceURL, err := cmdutils.GetUserSetVar(cmd, replicationSourceURLFlagName, replicationSourceURLEnvKey, true)
+	if err != nil {
+		return replicationParameters{}, err
+	}
+
+	vaultIDs, err := getList(cmd, replicationVaultIDsFlagName, replicationVaultIDsEnvKey)
+	if err != nil {
+		return replicationParameters{}, err
+	}
+
+	switch {
+	case sourceURL == "" && len(vaultIDs) > 0:
+		return replicationParameters{}, errReplicationWithoutSource
+	case sourceURL != "" && len(vaultIDs) == 0:
+		return replicationParameters{}, errMissingReplicationVaultIDs
+	}
+
+	mode, err := cmdutils.GetUserSetVar(cmd, replicationModeFlagName, replicationModeEnvKey, true)
+	if err != nil {
+		return replicationParameters{}, err
+	}
+
+	if mode != "" && !strings.EqualFold(mode, replicationModeContinuousOption) &&
+		!strings.EqualFold(mode, replicationModeOnceOption) {
+		return replicationParameters{}, errInvalidReplicationMode
+	}
+
+	interval, err := getDuration(cmd, replicationIntervalFlagName, replicationIntervalEnvKey,
+		defaultReplicationInterval)
+	if err != nil {
+		return replicationParameters{}, err
+	}
+
+	tlsCAFile, err := cmdutils.GetUserSetVar(cmd, replicationTLSCAFileFlagName, replicationTLSCAFileEnvKey, true)
+	if err != nil {
+		return replicationParameters{}, err
+	}
+
+	return replicationParameters{
+		sourceURL: sourceURL,
+		vaultIDs:  vaultIDs,
+		once:      strings.EqualFold(mode, replicationModeOnceOption),
+		interval:  interval,
+		tlsCAFile: tlsCAFile,
+	}, nil
+}
+
+func getTLSParameters(cmd *cobra.Command) (certFile, keyFile, clientCAFile string, err error) {
+	certFile, err = cmdutils.GetUserSetVar(cmd, tlsCertFileFlagName, tlsCertFileEnvKey, true)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	keyFile, err = cmdutils.GetUserSetVar(cmd, tlsKeyFileFlagName, tlsKeyFileEnvKey, true)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	clientCAFile, err = cmdutils.GetUserSetVar(cmd, tlsClientCAFileFlagName, tlsClientCAFileEnvKey, true)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	return certFile, keyFile, clientCAFile, nil
 }
 
 func startEDV(parameters *edvParameters) error {
@@ -138,30 +868,393 @@ func startEDV(parameters *edvParameters) error {
 		return errMissingHostURL
 	}
 
+	tlsConfig, err := createTLSConfig(parameters)
+	if err != nil {
+		return err
+	}
+
 	provider, err := createEDVProvider(parameters)
 	if err != nil {
 		return err
 	}
 
-	edvService, err := edv.New(provider)
+	edvMetrics := metrics.New()
+	provider = metricsedvprovider.NewProvider(provider, edvMetrics)
+
+	defer closeResource(provider, "EDV provider")
+
+	storageProvider, err := createStorageProvider(parameters)
+	if err != nil {
+		return err
+	}
+
+	defer closeResource(storageProvider, "storage provider")
+
+	auditLog, auditLogFile, err := createAuditLog(parameters, provider)
+	if err != nil {
+		return err
+	}
+
+	background, err := createBackgroundWork(parameters, provider, storageProvider)
+	if err != nil {
+		return err
+	}
+
+	opts := append(requestHandlingOptions(parameters), operation.WithStorageProvider(storageProvider))
+	opts = append(opts, background.options()...)
+
+	if auditLog != nil {
+		opts = append(opts, operation.WithAuditRecorder(auditLog))
+	}
+
+	if auditLogFile != nil {
+		defer closeResource(auditLogFile, "audit log file")
+	}
+
+	edvService, err := edv.New(provider, opts...)
 	if err != nil {
 		return err
 	}
 
-	handlers := edvService.GetOperations()
+	router := createRouter(edvService, edvMetrics)
+
+	// The background work uses the EDV provider and the storage provider, so it's stopped before they're closed.
+	stopBackgroundWork := background.start()
+	defer stopBackgroundWork()
+
+	return serve(parameters, requestlog.Handler(router), tlsConfig)
+}
+
+// backgroundWork is the work that the EDV server does in the background: delivering webhooks and, if enabled,
+// replicating vaults from another EDV server.
+type backgroundWork struct {
+	dispatcher    *webhook.Dispatcher
+	replicator    *replication.Replicator
+	replicateOnce bool
+}
+
+// createBackgroundWork creates the webhook dispatcher and, if replication is enabled, the replicator.
+// Neither is started yet.
+func createBackgroundWork(parameters *edvParameters, provider edvprovider.EDVProvider,
+	storageProvider storage.Provider) (*backgroundWork, error) {
+	dispatcher, err := webhook.New(provider, storageProvider,
+		webhook.WithMaxAttempts(int(parameters.webhookMaxAttempts)),
+		webhook.WithPollInterval(parameters.eventPollInterval),
+		webhook.WithAllowedHosts(parameters.webhookAllowedHosts))
+	if err != nil {
+		return nil, err
+	}
+
+	replicator, err := createReplicator(parameters, provider, storageProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	return &backgroundWork{
+		dispatcher:    dispatcher,
+		replicator:    replicator,
+		replicateOnce: parameters.replication.once,
+	}, nil
+}
+
+// createReplicator creates the replicator that mirrors vaults from another EDV server,
+// or returns nil if replication isn't enabled.
+func createReplicator(parameters *edvParameters, provider edvprovider.EDVProvider,
+	storageProvider storage.Provider) (*replication.Replicator, error) {
+	if parameters.replication.sourceURL == "" {
+		return nil, nil
+	}
+
+	tlsConfig, err := createReplicationTLSConfig(parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	source := edvclient.New(parameters.replication.sourceURL, edvclient.WithTLSConfig(tlsConfig))
+
+	return replication.New(source, provider, storageProvider, parameters.replication.vaultIDs,
+		replication.WithPollInterval(parameters.replication.interval))
+}
+
+// options returns the options that give the EDV operations access to the background work.
+func (w *backgroundWork) options() []operation.Option {
+	return []operation.Option{operation.WithWebhookDispatcher(w.dispatcher), operation.WithReplicator(w.replicator)}
+}
+
+// start starts the background work. It must only be called once the EDV operations have been created with the
+// background work's options, since the replicator writes the documents it replicates through them.
+// The returned function stops the background work and waits for it to finish. The background work uses
+// the EDV provider, so it must be stopped before the provider is closed.
+func (w *backgroundWork) start() func() {
+	stopDispatcher := runInBackground(w.dispatcher.Run)
+
+	if w.replicator == nil {
+		return stopDispatcher
+	}
+
+	replicate := w.replicator.Run
+	if w.replicateOnce {
+		replicate = w.replicator.ReplicateAll
+	}
+
+	stopReplicator := runInBackground(replicate)
+
+	return func() {
+		stopReplicator()
+		stopDispatcher()
+	}
+}
+
+// runInBackground calls the given function in a goroutine with a context that's done once the returned function
+// is called. The returned function waits for the given function to return.
+func runInBackground(run func(ctx context.Context)) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		run(ctx)
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// createReplicationTLSConfig creates the TLS config used to connect to the EDV server that vaults are replicated from.
+// The server's own certificate, if any, is presented as the client certificate.
+func createReplicationTLSConfig(parameters *edvParameters) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if parameters.replication.tlsCAFile != "" {
+		caBytes, err := ioutil.ReadFile(parameters.replication.tlsCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read replication CA file: %w", err)
+		}
+
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(caBytes) {
+			return nil, errNoReplicationCACertificates
+		}
+
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	if parameters.tlsCertFile != "" && parameters.tlsKeyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(parameters.tlsCertFile, parameters.tlsKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the client certificate for replication: %w", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+// createRouter creates a router for all of the EDV's REST operations plus the metrics endpoint.
+// Every route registered here must be described in the OpenAPI document.
+func createRouter(edvService *edv.Controller, edvMetrics *metrics.Metrics) *mux.Router {
 	router := mux.NewRouter()
 	router.UseEncodedPath()
 
-	for _, handler := range handlers {
-		router.HandleFunc(handler.Path(), handler.Handle()).Methods(handler.Method())
+	for _, handler := range edvService.GetOperations() {
+		router.HandleFunc(handler.Path(),
+			edvMetrics.InstrumentHandler(handler.Path(), handler.Handle())).Methods(handler.Method())
 	}
 
-	log.Infof("Starting edv rest server on host %s", parameters.hostURL)
-	err = parameters.srv.ListenAndServe(parameters.hostURL, router)
+	router.Handle(metricsEndpoint, edvMetrics.Handler()).Methods(http.MethodGet)
+
+	return router
+}
+
+// serve runs the server until it stops by itself or a SIGINT or SIGTERM signal is received.
+// Upon receiving a signal, the server stops accepting new requests and waits up to the shutdown timeout for
+// in-flight requests to complete.
+func serve(parameters *edvParameters, router http.Handler, tlsConfig *tls.Config) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	defer signal.Stop(signals)
+
+	serveErrs := make(chan error, 1)
+
+	go func() {
+		serveErrs <- listenAndServe(parameters, router, tlsConfig)
+	}()
+
+	var err error
+
+	select {
+	case err = <-serveErrs:
+	case sig := <-signals:
+		log.Infof("Received %s signal. Draining in-flight requests for up to %s before shutting down",
+			sig, parameters.shutdownTimeout)
+
+		err = shutdown(parameters)
+
+		if serveErr := <-serveErrs; err == nil {
+			err = serveErr
+		}
+	}
+
+	if err == http.ErrServerClosed {
+		return nil
+	}
 
 	return err
 }
 
+func listenAndServe(parameters *edvParameters, router http.Handler, tlsConfig *tls.Config) error {
+	if tlsConfig == nil {
+		log.Infof("Starting edv rest server on host %s", parameters.hostURL)
+
+		return parameters.srv.ListenAndServe(parameters.hostURL, router)
+	}
+
+	log.Infof("Starting edv rest server with TLS on host %s", parameters.hostURL)
+
+	return parameters.srv.ListenAndServeTLS(parameters.hostURL, parameters.tlsCertFile, parameters.tlsKeyFile,
+		tlsConfig, principal.TLSClientIdentityHandler(router))
+}
+
+func shutdown(parameters *edvParameters) error {
+	ctx, cancel := context.WithTimeout(context.Background(), parameters.shutdownTimeout)
+	defer cancel()
+
+	err := parameters.srv.Shutdown(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to gracefully shut down edv rest server: %w", err)
+	}
+
+	log.Info("edv rest server shut down gracefully")
+
+	return nil
+}
+
+// createTLSConfig creates the TLS config used by the server, or returns nil if TLS isn't being used.
+// If a client CA file was provided, then clients are required to present a certificate signed by one of those CAs.
+func createTLSConfig(parameters *edvParameters) (*tls.Config, error) {
+	if parameters.tlsCertFile == "" && parameters.tlsKeyFile == "" {
+		if parameters.tlsClientCAFile != "" {
+			return nil, errClientCAWithoutTLS
+		}
+
+		return nil, nil
+	}
+
+	if parameters.tlsCertFile == "" || parameters.tlsKeyFile == "" {
+		return nil, errIncompleteTLSParameters
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if parameters.tlsClientCAFile == "" {
+		return tlsConfig, nil
+	}
+
+	clientCABytes, err := ioutil.ReadFile(parameters.tlsClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(clientCABytes) {
+		return nil, errNoClientCACertificates
+	}
+
+	tlsConfig.ClientCAs = clientCAs
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+
+	return tlsConfig, nil
+}
+
+func requestHandlingOptions(parameters *edvParameters) []operation.Option {
+	opts := []operation.Option{
+		operation.WithBodySizeLimits(parameters.bodySizeLimits),
+		operation.WithJWEPolicy(parameters.jwePolicy),
+		operation.WithEventPollInterval(parameters.eventPollInterval),
+	}
+
+	if parameters.strictJSON {
+		opts = append(opts, operation.WithStrictJSON())
+	}
+
+	if parameters.writeRateLimit.requestsPerSecond > 0 {
+		opts = append(opts, operation.WithWriteRateLimiter(
+			ratelimit.New(parameters.writeRateLimit.requestsPerSecond, parameters.writeRateLimit.burst)))
+	}
+
+	if parameters.queryRateLimit.requestsPerSecond > 0 {
+		opts = append(opts, operation.WithQueryRateLimiter(
+			ratelimit.New(parameters.queryRateLimit.requestsPerSecond, parameters.queryRateLimit.burst)))
+	}
+
+	return opts
+}
+
+// createAuditLog creates the audit log configured by the given parameters. If the audit log is kept in a file, then
+// the file sink is also returned so that it can be closed once the server stops.
+// If audit logging is disabled, then a nil audit log is returned.
+func createAuditLog(parameters *edvParameters,
+	provider edvprovider.EDVProvider) (*audit.Log, *audit.FileSink, error) {
+	switch {
+	case parameters.auditLogType == "" || strings.EqualFold(parameters.auditLogType, auditLogTypeNoneOption):
+		return nil, nil, nil
+	case strings.EqualFold(parameters.auditLogType, auditLogTypeFileOption):
+		if parameters.auditLogPath == "" {
+			return nil, nil, errMissingAuditLogPath
+		}
+
+		fileSink, err := audit.NewFileSink(parameters.auditLogPath)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		auditLog, err := audit.New(fileSink)
+		if err != nil {
+			closeResource(fileSink, "audit log file")
+
+			return nil, nil, err
+		}
+
+		return auditLog, fileSink, nil
+	case strings.EqualFold(parameters.auditLogType, auditLogTypeDatabaseOption):
+		storeSink, err := audit.NewStoreSink(provider)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		auditLog, err := audit.New(storeSink)
+
+		return auditLog, nil, err
+	default:
+		return nil, nil, errInvalidAuditLogType
+	}
+}
+
+// createStorageProvider creates the storage provider used for vault configurations and other server data
+// that isn't part of a vault. It uses the same database as the EDV provider.
+func createStorageProvider(parameters *edvParameters) (storage.Provider, error) {
+	switch {
+	case strings.EqualFold(parameters.databaseType, databaseTypeMemOption):
+		return memstore.NewProvider(), nil
+	case strings.EqualFold(parameters.databaseType, databaseTypeCouchDBOption):
+		return couchdbstore.NewProvider(parameters.databaseURL, couchdbstore.WithDBPrefix(parameters.databasePrefix))
+	default:
+		return nil, errInvalidDatabaseType
+	}
+}
+
+func closeResource(closer io.Closer, name string) {
+	if err := closer.Close(); err != nil {
+		log.Errorf("Failed to close %s: %s", name, err.Error())
+	}
+}
+
 func createEDVProvider(parameters *edvParameters) (edvprovider.EDVProvider, error) {
 	var edvProv edvprovider.EDVProvider
 
@@ -6,20 +6,70 @@ SPDX-License-Identifier: Apache-2.0
 package startcmd
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/trustbloc/edv/pkg/audit"
 	"github.com/trustbloc/edv/pkg/edvprovider/couchdbedvprovider"
 	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
-
+	"github.com/trustbloc/edv/pkg/jwe"
+	"github.com/trustbloc/edv/pkg/metrics"
+	"github.com/trustbloc/edv/pkg/replication"
+	"github.com/trustbloc/edv/pkg/requestlog"
+	"github.com/trustbloc/edv/pkg/restapi/edv"
+	"github.com/trustbloc/edv/pkg/restapi/edv/openapi"
+	"github.com/trustbloc/edv/pkg/restapi/edv/operation"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/require"
 )
 
-type mockServer struct{}
+type mockServer struct {
+	tlsConfig   *tls.Config
+	handler     http.Handler
+	errShutdown error
+	// serve, if set, is called with the handler while the server is "running", i.e. before the EDV shuts down.
+	serve func(handler http.Handler)
+}
 
 func (s *mockServer) ListenAndServe(host string, handler http.Handler) error {
+	s.handler = handler
+
+	if s.serve != nil {
+		s.serve(handler)
+	}
+
+	return nil
+}
+
+func (s *mockServer) Shutdown(ctx context.Context) error {
+	return s.errShutdown
+}
+
+func (s *mockServer) ListenAndServeTLS(host, certFile, keyFile string, tlsConfig *tls.Config,
+	router http.Handler) error {
+	s.tlsConfig = tlsConfig
+
 	return nil
 }
 
@@ -93,6 +143,677 @@ func TestStartCmdValidArgsEnvVar(t *testing.T) {
 	require.Nil(t, err)
 }
 
+func TestStartCmdWithTLS(t *testing.T) {
+	dir, tempDirErr := ioutil.TempDir("", "edv-startcmd")
+	require.NoError(t, tempDirErr)
+
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	t.Run("TLS without client CA", func(t *testing.T) {
+		srv := &mockServer{}
+		startCmd := GetStartCmd(srv)
+
+		args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + databaseTypeFlagName, "mem",
+			"--" + tlsCertFileFlagName, "cert.pem", "--" + tlsKeyFileFlagName, "key.pem"}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.NoError(t, err)
+		require.NotNil(t, srv.tlsConfig)
+		require.Equal(t, tls.NoClientCert, srv.tlsConfig.ClientAuth)
+	})
+	t.Run("Mutual TLS", func(t *testing.T) {
+		srv := &mockServer{}
+		startCmd := GetStartCmd(srv)
+
+		args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + databaseTypeFlagName, "mem",
+			"--" + tlsCertFileFlagName, "cert.pem", "--" + tlsKeyFileFlagName, "key.pem",
+			"--" + tlsClientCAFileFlagName, writeTestCACertificate(t, dir)}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.NoError(t, err)
+		require.NotNil(t, srv.tlsConfig)
+		require.Equal(t, tls.RequireAndVerifyClientCert, srv.tlsConfig.ClientAuth)
+		require.NotNil(t, srv.tlsConfig.ClientCAs)
+	})
+	t.Run("Error - key file missing", func(t *testing.T) {
+		parameters := &edvParameters{srv: &mockServer{}, hostURL: "localhost:8080",
+			databaseType: databaseTypeMemOption, tlsCertFile: "cert.pem"}
+
+		err := startEDV(parameters)
+		require.Equal(t, errIncompleteTLSParameters, err)
+	})
+	t.Run("Error - client CA without TLS", func(t *testing.T) {
+		parameters := &edvParameters{srv: &mockServer{}, hostURL: "localhost:8080",
+			databaseType: databaseTypeMemOption, tlsClientCAFile: "ca.pem"}
+
+		err := startEDV(parameters)
+		require.Equal(t, errClientCAWithoutTLS, err)
+	})
+	t.Run("Error - client CA file doesn't exist", func(t *testing.T) {
+		parameters := &edvParameters{srv: &mockServer{}, hostURL: "localhost:8080",
+			databaseType: databaseTypeMemOption, tlsCertFile: "cert.pem", tlsKeyFile: "key.pem",
+			tlsClientCAFile: "NonExistentFile"}
+
+		err := startEDV(parameters)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to read client CA file")
+	})
+	t.Run("Error - client CA file has no certificates", func(t *testing.T) {
+		caFile := filepath.Join(dir, "invalid-ca.pem")
+		require.NoError(t, ioutil.WriteFile(caFile, []byte("not a certificate"), 0600))
+
+		parameters := &edvParameters{srv: &mockServer{}, hostURL: "localhost:8080",
+			databaseType: databaseTypeMemOption, tlsCertFile: "cert.pem", tlsKeyFile: "key.pem",
+			tlsClientCAFile: caFile}
+
+		err := startEDV(parameters)
+		require.Equal(t, errNoClientCACertificates, err)
+	})
+}
+
+// signallingServer sends a SIGTERM to the current process once it starts serving,
+// and then blocks until it's shut down.
+type signallingServer struct {
+	mockServer
+	stopped       chan struct{}
+	shutdownCalls int
+}
+
+func (s *signallingServer) ListenAndServe(host string, handler http.Handler) error {
+	err := syscall.Kill(os.Getpid(), syscall.SIGTERM)
+	if err != nil {
+		return err
+	}
+
+	<-s.stopped
+
+	return http.ErrServerClosed
+}
+
+func (s *signallingServer) Shutdown(ctx context.Context) error {
+	s.shutdownCalls++
+
+	close(s.stopped)
+
+	return s.errShutdown
+}
+
+func TestStartEDV_GracefulShutdown(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		srv := &signallingServer{stopped: make(chan struct{})}
+
+		parameters := &edvParameters{srv: srv, hostURL: "localhost:8080", databaseType: databaseTypeMemOption,
+			shutdownTimeout: time.Second}
+
+		err := startEDV(parameters)
+		require.NoError(t, err)
+		require.Equal(t, 1, srv.shutdownCalls)
+	})
+	t.Run("Error - in-flight requests not drained in time", func(t *testing.T) {
+		srv := &signallingServer{stopped: make(chan struct{}),
+			mockServer: mockServer{errShutdown: context.DeadlineExceeded}}
+
+		parameters := &edvParameters{srv: srv, hostURL: "localhost:8080", databaseType: databaseTypeMemOption,
+			shutdownTimeout: time.Second}
+
+		err := startEDV(parameters)
+		require.EqualError(t, err, "failed to gracefully shut down edv rest server: context deadline exceeded")
+	})
+}
+
+func TestStartCmdWithShutdownTimeout(t *testing.T) {
+	t.Run("Valid timeout", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + databaseTypeFlagName, "mem",
+			"--" + shutdownTimeoutFlagName, "5s"}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.NoError(t, err)
+	})
+	t.Run("Invalid timeout", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + databaseTypeFlagName, "mem",
+			"--" + shutdownTimeoutFlagName, "soon"}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for "+shutdownTimeoutFlagName)
+	})
+}
+
+func TestStartCmdWithLoggingFlags(t *testing.T) {
+	defer func() {
+		log.SetLevel(log.InfoLevel)
+		log.SetFormatter(&log.TextFormatter{})
+	}()
+
+	t.Run("Valid log level and format", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + databaseTypeFlagName, "mem",
+			"--" + logLevelFlagName, "debug", "--" + logFormatFlagName, "json"}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.NoError(t, err)
+		require.Equal(t, log.DebugLevel, log.GetLevel())
+		require.IsType(t, &log.JSONFormatter{}, log.StandardLogger().Formatter)
+	})
+	t.Run("Invalid log level", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + databaseTypeFlagName, "mem",
+			"--" + logLevelFlagName, "loud"}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for "+logLevelFlagName)
+	})
+	t.Run("Invalid log format", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + databaseTypeFlagName, "mem",
+			"--" + logFormatFlagName, "xml"}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for "+logFormatFlagName)
+	})
+}
+
+func TestHTTPServer_Shutdown(t *testing.T) {
+	srv := &HTTPServer{}
+
+	err := srv.Shutdown(context.Background())
+	require.NoError(t, err)
+
+	serveErrs := make(chan error)
+
+	go func() {
+		serveErrs <- srv.ListenAndServe("localhost:0", http.NewServeMux())
+	}()
+
+	require.Eventually(t, func() bool {
+		srv.mux.Lock()
+		defer srv.mux.Unlock()
+
+		return srv.srv != nil
+	}, time.Second, 10*time.Millisecond)
+
+	err = srv.Shutdown(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, http.ErrServerClosed, <-serveErrs)
+}
+
+func TestHTTPServer_ShutdownCancelsRequestContexts(t *testing.T) {
+	srv := &HTTPServer{}
+
+	requestContext := srv.newServer("localhost:0", http.NewServeMux(), nil).BaseContext(nil)
+	require.NoError(t, requestContext.Err())
+
+	err := srv.Shutdown(context.Background())
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return requestContext.Err() != nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRunInBackground_StopWaitsForWorkToFinish(t *testing.T) {
+	finished := false
+
+	stop := runInBackground(func(ctx context.Context) {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+
+		finished = true
+	})
+
+	stop()
+	require.True(t, finished)
+}
+
+func TestStartEDV_MetricsEndpoint(t *testing.T) {
+	srv := &mockServer{}
+
+	parameters := &edvParameters{srv: srv, hostURL: "localhost:8080", databaseType: databaseTypeMemOption}
+
+	err := startEDV(parameters)
+	require.NoError(t, err)
+	require.NotNil(t, srv.handler)
+
+	rr := httptest.NewRecorder()
+	srv.handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/healthcheck", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	srv.handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, metricsEndpoint, nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.NotEmpty(t, rr.Header().Get(requestlog.RequestIDHeader))
+	require.Contains(t, rr.Body.String(), `edv_http_requests_total{code="200",method="get",route="/healthcheck"} 1`)
+}
+
+func TestStartCmdWithAuditLog(t *testing.T) {
+	dir, tempDirErr := ioutil.TempDir("", "edv-startcmd")
+	require.NoError(t, tempDirErr)
+
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	t.Run("File audit log", func(t *testing.T) {
+		auditLogPath := filepath.Join(dir, "audit.log")
+
+		srv := &mockServer{serve: func(handler http.Handler) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/encrypted-data-vaults",
+				strings.NewReader(`{"referenceId":"testvault"}`)))
+			require.Equal(t, http.StatusCreated, rr.Code)
+		}}
+
+		startCmd := GetStartCmd(srv)
+		startCmd.SetArgs([]string{"--" + hostURLFlagName, "localhost:8080", "--" + databaseTypeFlagName, "mem",
+			"--" + auditLogTypeFlagName, auditLogTypeFileOption, "--" + auditLogPathFlagName, auditLogPath})
+
+		err := startCmd.Execute()
+		require.NoError(t, err)
+
+		records, err := audit.ReadRecordsFromFile(auditLogPath)
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		require.Equal(t, "createVault", records[0].Action)
+		require.Equal(t, "testvault", records[0].VaultID)
+		require.NoError(t, audit.Verify(records))
+	})
+	t.Run("Database audit log", func(t *testing.T) {
+		parameters := &edvParameters{srv: &mockServer{}, hostURL: "localhost:8080", databaseType: databaseTypeMemOption,
+			auditLogType: auditLogTypeDatabaseOption}
+
+		err := startEDV(parameters)
+		require.NoError(t, err)
+	})
+	t.Run("Missing audit log path", func(t *testing.T) {
+		parameters := &edvParameters{srv: &mockServer{}, hostURL: "localhost:8080", databaseType: databaseTypeMemOption,
+			auditLogType: auditLogTypeFileOption}
+
+		err := startEDV(parameters)
+		require.Equal(t, errMissingAuditLogPath, err)
+	})
+	t.Run("Invalid audit log type", func(t *testing.T) {
+		parameters := &edvParameters{srv: &mockServer{}, hostURL: "localhost:8080", databaseType: databaseTypeMemOption,
+			auditLogType: "NotAValidType"}
+
+		err := startEDV(parameters)
+		require.Equal(t, errInvalidAuditLogType, err)
+	})
+	t.Run("Fail to open audit log file", func(t *testing.T) {
+		parameters := &edvParameters{srv: &mockServer{}, hostURL: "localhost:8080", databaseType: databaseTypeMemOption,
+			auditLogType: auditLogTypeFileOption, auditLogPath: filepath.Join(dir, "missing", "audit.log")}
+
+		err := startEDV(parameters)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to open audit log file")
+	})
+	t.Run("Existing audit log file has been tampered with", func(t *testing.T) {
+		auditLogPath := filepath.Join(dir, "tampered.log")
+		require.NoError(t, ioutil.WriteFile(auditLogPath, []byte(`{"sequence":1,"hash":"tampered"}`), 0600))
+
+		parameters := &edvParameters{srv: &mockServer{}, hostURL: "localhost:8080", databaseType: databaseTypeMemOption,
+			auditLogType: auditLogTypeFileOption, auditLogPath: auditLogPath}
+
+		err := startEDV(parameters)
+		require.True(t, errors.Is(err, audit.ErrChainBroken))
+	})
+}
+
+func TestCreateStorageProvider(t *testing.T) {
+	provider, err := createStorageProvider(&edvParameters{databaseType: databaseTypeCouchDBOption,
+		databaseURL: "localhost:5984"})
+	require.NoError(t, err)
+	require.NotNil(t, provider)
+
+	provider, err = createStorageProvider(&edvParameters{databaseType: "NotAValidType"})
+	require.Equal(t, errInvalidDatabaseType, err)
+	require.Nil(t, provider)
+}
+
+func TestStartCmdWithRateLimits(t *testing.T) {
+	t.Run("Write and query limits", func(t *testing.T) {
+		srv := &mockServer{serve: func(handler http.Handler) {
+			createVault := func(referenceID string) int {
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/encrypted-data-vaults",
+					strings.NewReader(`{"referenceId":"`+referenceID+`"}`)))
+
+				return rr.Code
+			}
+
+			require.Equal(t, http.StatusCreated, createVault("vault1"))
+			require.Equal(t, http.StatusCreated, createVault("vault2"))
+			require.Equal(t, http.StatusTooManyRequests, createVault("vault3"))
+		}}
+
+		startCmd := GetStartCmd(srv)
+		startCmd.SetArgs([]string{"--" + hostURLFlagName, "localhost:8080", "--" + databaseTypeFlagName, "mem",
+			"--" + writeRateLimitFlagName, "0.001", "--" + writeRateBurstFlagName, "2",
+			"--" + queryRateLimitFlagName, "5"})
+
+		err := startCmd.Execute()
+		require.NoError(t, err)
+	})
+	t.Run("Default burst", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		require.NoError(t, startCmd.ParseFlags([]string{"--" + queryRateLimitFlagName, "2.5"}))
+
+		writeRateLimit, queryRateLimit, err := getRateLimitParameters(startCmd)
+		require.NoError(t, err)
+		require.Equal(t, rateLimitParameters{}, writeRateLimit)
+		require.Equal(t, rateLimitParameters{requestsPerSecond: 2.5, burst: 3}, queryRateLimit)
+	})
+	t.Run("Invalid values", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"--" + writeRateLimitFlagName, "not a number"},
+			{"--" + writeRateLimitFlagName, "0"},
+			{"--" + queryRateLimitFlagName, "1", "--" + queryRateBurstFlagName, "0"},
+			{"--" + queryRateLimitFlagName, "1", "--" + queryRateBurstFlagName, "1.5"},
+		} {
+			startCmd := GetStartCmd(&mockServer{})
+			startCmd.SetArgs(append([]string{"--" + hostURLFlagName, "localhost:8080",
+				"--" + databaseTypeFlagName, "mem"}, args...))
+
+			err := startCmd.Execute()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "invalid value for")
+		}
+	})
+}
+
+func TestStartCmdWithRequestBodyLimits(t *testing.T) {
+	t.Run("Size limits and strict JSON", func(t *testing.T) {
+		srv := &mockServer{serve: func(handler http.Handler) {
+			createVault := func(body string) int {
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/encrypted-data-vaults",
+					strings.NewReader(body)))
+
+				return rr.Code
+			}
+
+			require.Equal(t, http.StatusCreated, createVault(`{"referenceId":"vault1"}`))
+			require.Equal(t, http.StatusBadRequest, createVault(`{"referenceId":"vault2","unknown":true}`))
+			require.Equal(t, http.StatusRequestEntityTooLarge,
+				createVault(`{"referenceId":"vault3","controller":"did:example:123456789"}`))
+		}}
+
+		startCmd := GetStartCmd(srv)
+		startCmd.SetArgs([]string{"--" + hostURLFlagName, "localhost:8080", "--" + databaseTypeFlagName, "mem",
+			"--" + maxVaultConfigurationSizeFlagName, "40", "--" + strictJSONFlagName, "true"})
+
+		err := startCmd.Execute()
+		require.NoError(t, err)
+	})
+	t.Run("Values from environment variables", func(t *testing.T) {
+		require.NoError(t, os.Setenv(maxQuerySizeEnvKey, "100"))
+		require.NoError(t, os.Setenv(maxDocumentSizeEnvKey, "200"))
+		require.NoError(t, os.Setenv(maxBatchSizeEnvKey, "300"))
+
+		defer func() {
+			require.NoError(t, os.Unsetenv(maxQuerySizeEnvKey))
+			require.NoError(t, os.Unsetenv(maxDocumentSizeEnvKey))
+			require.NoError(t, os.Unsetenv(maxBatchSizeEnvKey))
+		}()
+
+		startCmd := GetStartCmd(&mockServer{})
+
+		limits, err := getBodySizeLimits(startCmd)
+		require.NoError(t, err)
+		require.Equal(t, operation.BodySizeLimits{Query: 100, Document: 200, Batch: 300}, limits)
+	})
+	t.Run("Invalid values", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"--" + maxVaultConfigurationSizeFlagName, "0"},
+			{"--" + maxQuerySizeFlagName, "-1"},
+			{"--" + maxDocumentSizeFlagName, "1MB"},
+			{"--" + maxBatchSizeFlagName, "0"},
+			{"--" + maxImportSizeFlagName, "1GB"},
+			{"--" + strictJSONFlagName, "maybe"},
+		} {
+			startCmd := GetStartCmd(&mockServer{})
+			startCmd.SetArgs(append([]string{"--" + hostURLFlagName, "localhost:8080",
+				"--" + databaseTypeFlagName, "mem"}, args...))
+
+			err := startCmd.Execute()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "invalid value for")
+		}
+	})
+}
+
+func TestStartCmdWithJWEPolicy(t *testing.T) {
+	startCmd := GetStartCmd(&mockServer{})
+	require.NoError(t, startCmd.ParseFlags([]string{"--" + jweAllowedAlgorithmsFlagName, "ECDH-ES+A256KW, A256KW,",
+		"--" + jweAllowedEncryptionsFlagName, "XC20P"}))
+
+	policy, err := getJWEPolicy(startCmd)
+	require.NoError(t, err)
+	require.Equal(t, jwe.Policy{
+		AllowedAlgorithms:  []string{"ECDH-ES+A256KW", "A256KW"},
+		AllowedEncryptions: []string{"XC20P"},
+	}, policy)
+
+	startCmd = GetStartCmd(&mockServer{})
+
+	policy, err = getJWEPolicy(startCmd)
+	require.NoError(t, err)
+	require.Equal(t, jwe.Policy{}, policy)
+}
+
+func TestStartCmdWithEventPollInterval(t *testing.T) {
+	startCmd := GetStartCmd(&mockServer{})
+	require.NoError(t, startCmd.ParseFlags([]string{"--" + eventPollIntervalFlagName, "500ms"}))
+
+	parameters := &edvParameters{}
+
+	require.NoError(t, getRequestHandlingParameters(startCmd, parameters))
+	require.Equal(t, 500*time.Millisecond, parameters.eventPollInterval)
+
+	startCmd = GetStartCmd(&mockServer{})
+
+	require.NoError(t, getRequestHandlingParameters(startCmd, parameters))
+	require.Equal(t, operation.DefaultEventPollInterval, parameters.eventPollInterval)
+
+	startCmd = GetStartCmd(&mockServer{})
+	require.NoError(t, startCmd.ParseFlags([]string{"--" + eventPollIntervalFlagName, "often"}))
+
+	err := getRequestHandlingParameters(startCmd, parameters)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid value for "+eventPollIntervalFlagName)
+}
+
+func TestStartCmdWithWebhookMaxAttempts(t *testing.T) {
+	startCmd := GetStartCmd(&mockServer{})
+	require.NoError(t, startCmd.ParseFlags([]string{"--" + webhookMaxAttemptsFlagName, "3"}))
+
+	parameters := &edvParameters{}
+
+	require.NoError(t, getRequestHandlingParameters(startCmd, parameters))
+	require.Equal(t, int64(3), parameters.webhookMaxAttempts)
+
+	startCmd = GetStartCmd(&mockServer{})
+	require.NoError(t, startCmd.ParseFlags([]string{"--" + webhookMaxAttemptsFlagName, "0"}))
+
+	err := getRequestHandlingParameters(startCmd, parameters)
+	require.EqualError(t, err, "invalid value for "+webhookMaxAttemptsFlagName+": must be an integer greater than 0")
+}
+
+func TestStartCmdWithWebhookAllowedHosts(t *testing.T) {
+	startCmd := GetStartCmd(&mockServer{})
+	require.NoError(t, startCmd.ParseFlags([]string{"--" + webhookAllowedHostsFlagName, "hooks.internal, 10.0.0.5"}))
+
+	parameters := &edvParameters{}
+
+	require.NoError(t, getRequestHandlingParameters(startCmd, parameters))
+	require.Equal(t, []string{"hooks.internal", "10.0.0.5"}, parameters.webhookAllowedHosts)
+}
+
+func TestStartCmdWithReplication(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		require.NoError(t, startCmd.ParseFlags([]string{
+			"--" + replicationSourceURLFlagName, "https://edv.example.com/encrypted-data-vaults",
+			"--" + replicationVaultIDsFlagName, "vault1, vault2",
+			"--" + replicationModeFlagName, "once",
+			"--" + replicationIntervalFlagName, "1m",
+		}))
+
+		parameters, err := getReplicationParameters(startCmd)
+		require.NoError(t, err)
+		require.Equal(t, replicationParameters{
+			sourceURL: "https://edv.example.com/encrypted-data-vaults",
+			vaultIDs:  []string{"vault1", "vault2"},
+			once:      true,
+			interval:  time.Minute,
+		}, parameters)
+	})
+	t.Run("Disabled by default", func(t *testing.T) {
+		parameters, err := getReplicationParameters(GetStartCmd(&mockServer{}))
+		require.NoError(t, err)
+		require.Empty(t, parameters.sourceURL)
+		require.False(t, parameters.once)
+		require.Equal(t, replication.DefaultPollInterval, parameters.interval)
+	})
+	t.Run("Invalid parameters", func(t *testing.T) {
+		for _, test := range []struct {
+			args []string
+			err  string
+		}{
+			{
+				args: []string{"--" + replicationSourceURLFlagName, "https://edv.example.com/encrypted-data-vaults"},
+				err:  errMissingReplicationVaultIDs.Error(),
+			},
+			{
+				args: []string{"--" + replicationVaultIDsFlagName, "vault1"},
+				err:  errReplicationWithoutSource.Error(),
+			},
+			{
+				args: []string{"--" + replicationModeFlagName, "sometimes"},
+				err:  errInvalidReplicationMode.Error(),
+			},
+			{
+				args: []string{"--" + replicationIntervalFlagName, "often"},
+				err:  "invalid value for " + replicationIntervalFlagName,
+			},
+		} {
+			startCmd := GetStartCmd(&mockServer{})
+			require.NoError(t, startCmd.ParseFlags(test.args))
+
+			_, err := getReplicationParameters(startCmd)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), test.err)
+		}
+	})
+	t.Run("Start the server with replication", func(t *testing.T) {
+		for _, mode := range []string{replicationModeContinuousOption, replicationModeOnceOption} {
+			startCmd := GetStartCmd(&mockServer{})
+			startCmd.SetArgs([]string{"--" + hostURLFlagName, "localhost:8080", "--" + databaseTypeFlagName, "mem",
+				"--" + replicationSourceURLFlagName, "https://localhost:1/encrypted-data-vaults",
+				"--" + replicationVaultIDsFlagName, "vault1", "--" + replicationModeFlagName, mode})
+
+			require.NoError(t, startCmd.Execute())
+		}
+	})
+	t.Run("Replication TLS config", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "edv-startcmd")
+		require.NoError(t, err)
+
+		defer func() {
+			require.NoError(t, os.RemoveAll(dir))
+		}()
+
+		tlsConfig, err := createReplicationTLSConfig(&edvParameters{
+			replication: replicationParameters{tlsCAFile: writeTestCACertificate(t, dir)},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, tlsConfig.RootCAs)
+		require.Empty(t, tlsConfig.Certificates)
+
+		_, err = createReplicationTLSConfig(&edvParameters{
+			replication: replicationParameters{tlsCAFile: "NonExistentFile"},
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to read replication CA file")
+
+		invalidCAFile := filepath.Join(dir, "invalid-ca.pem")
+		require.NoError(t, ioutil.WriteFile(invalidCAFile, []byte("not a certificate"), 0600))
+
+		_, err = createReplicationTLSConfig(&edvParameters{
+			replication: replicationParameters{tlsCAFile: invalidCAFile},
+		})
+		require.Equal(t, errNoReplicationCACertificates, err)
+
+		parameters := &edvParameters{srv: &mockServer{}, hostURL: "localhost:8080",
+			databaseType: databaseTypeMemOption, tlsCertFile: "cert.pem", tlsKeyFile: "key.pem",
+			replication: replicationParameters{sourceURL: "https://localhost:1/encrypted-data-vaults",
+				vaultIDs: []string{"vault1"}}}
+
+		err = startEDV(parameters)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to load the client certificate for replication")
+	})
+}
+
+// Fails if the routes served by the EDV and the paths described in the OpenAPI document drift apart.
+func TestCreateRouter_MatchesOpenAPISpec(t *testing.T) {
+	edvService, err := edv.New(memedvprovider.NewProvider())
+	require.NoError(t, err)
+
+	router := createRouter(edvService, metrics.New())
+
+	routes := make(map[string]bool)
+
+	err = router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		pathTemplate, pathErr := route.GetPathTemplate()
+		require.NoError(t, pathErr)
+
+		methods, methodsErr := route.GetMethods()
+		require.NoError(t, methodsErr)
+
+		for _, method := range methods {
+			routes[method+" "+pathTemplate] = true
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	var spec struct {
+		Paths map[string]map[string]interface{} `json:"paths"`
+	}
+
+	err = json.Unmarshal([]byte(openapi.Spec), &spec)
+	require.NoError(t, err)
+
+	specRoutes := make(map[string]bool)
+
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			specRoutes[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	require.Equal(t, specRoutes, routes)
+}
+
 func TestCreateProvider(t *testing.T) {
 	t.Run("Successfully create memory storage provider", func(t *testing.T) {
 		parameters := edvParameters{databaseType: databaseTypeMemOption}
@@ -143,3 +864,27 @@ func checkFlagPropertiesCorrect(t *testing.T, cmd *cobra.Command, flagName, flag
 	flagAnnotations := flag.Annotations
 	require.Nil(t, flagAnnotations)
 }
+
+func writeTestCACertificate(t *testing.T, dir string) string {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	require.NoError(t, err)
+
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, ioutil.WriteFile(caFile,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certBytes}), 0600))
+
+	return caFile
+}
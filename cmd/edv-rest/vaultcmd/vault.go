@@ -0,0 +1,290 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vaultcmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/trustbloc/edv/pkg/client/edv"
+	cmdutils "github.com/trustbloc/edv/pkg/utils/cmd"
+	"github.com/trustbloc/edv/pkg/vaultexport"
+)
+
+const (
+	edvURLFlagName  = "edv-url"
+	edvURLEnvKey    = "EDV_URL"
+	edvURLFlagUsage = "The URL of the EDV server (e.g. http://localhost:8071)." +
+		" Alternatively, this can be set with the following environment variable: " + edvURLEnvKey
+
+	vaultIDFlagName  = "vault-id"
+	vaultIDEnvKey    = "EDV_VAULT_ID"
+	vaultIDFlagUsage = "The ID of the vault." +
+		" Alternatively, this can be set with the following environment variable: " + vaultIDEnvKey
+
+	outputFlagName  = "output"
+	outputEnvKey    = "EDV_EXPORT_OUTPUT"
+	outputFlagUsage = "Path of the file to write the export to. Defaults to standard output." +
+		" Alternatively, this can be set with the following environment variable: " + outputEnvKey
+
+	inputFlagName  = "input"
+	inputEnvKey    = "EDV_IMPORT_INPUT"
+	inputFlagUsage = "Path of the export file to import." +
+		" Alternatively, this can be set with the following environment variable: " + inputEnvKey
+
+	vaultsPath = "/encrypted-data-vaults"
+)
+
+// GetVaultCmd returns the Cobra vault command, which has subcommands for exporting, importing and reindexing vaults.
+func GetVaultCmd() *cobra.Command {
+	vaultCmd := &cobra.Command{
+		Use:   "vault",
+		Short: "Export, import or reindex a vault",
+		Long: "Back up a vault or move it between EDV servers as newline-delimited JSON," +
+			" or rebuild its encrypted indexes",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.HelpFunc()(cmd, args)
+		},
+	}
+
+	vaultCmd.AddCommand(createExportCmd(), createImportCmd(), createReindexCmd())
+
+	return vaultCmd
+}
+
+func createExportCmd() *cobra.Command {
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export a vault",
+		Long: "Write a vault's configuration and documents, with their checksums, as newline-delimited JSON." +
+			" The export is verified as it's written.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, vaultID, err := getClientAndVaultID(cmd)
+			if err != nil {
+				return err
+			}
+
+			outputPath, err := cmdutils.GetUserSetVar(cmd, outputFlagName, outputEnvKey, true)
+			if err != nil {
+				return err
+			}
+
+			return exportVault(cmd, client, vaultID, outputPath)
+		},
+	}
+
+	createFlags(exportCmd)
+	exportCmd.Flags().String(outputFlagName, "", outputFlagUsage)
+
+	return exportCmd
+}
+
+func createImportCmd() *cobra.Command {
+	importCmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import a vault",
+		Long: "Verify a vault export and import it into a vault, which is created if it doesn't exist." +
+			" Documents that were already imported are skipped, so an interrupted import can be resumed" +
+			" by running it again.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, vaultID, err := getClientAndVaultID(cmd)
+			if err != nil {
+				return err
+			}
+
+			inputPath, err := cmdutils.GetUserSetVar(cmd, inputFlagName, inputEnvKey, false)
+			if err != nil {
+				return err
+			}
+
+			return importVault(cmd, client, vaultID, inputPath)
+		},
+	}
+
+	createFlags(importCmd)
+	importCmd.Flags().String(inputFlagName, "", inputFlagUsage)
+
+	return importCmd
+}
+
+func createReindexCmd() *cobra.Command {
+	reindexCmd := &cobra.Command{
+		Use:   "reindex",
+		Short: "Rebuild a vault's encrypted indexes",
+		Long: "Rebuild a vault's encrypted indexes from its documents, removing index entries that are out of date" +
+			" and adding the missing ones. Indexed attributes that are declared unique but shared by several" +
+			" documents are reported, and the command fails if there are any.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, vaultID, err := getClientAndVaultID(cmd)
+			if err != nil {
+				return err
+			}
+
+			return reindexVault(cmd, client, vaultID)
+		},
+	}
+
+	createFlags(reindexCmd)
+
+	return reindexCmd
+}
+
+func createFlags(cmd *cobra.Command) {
+	cmd.Flags().String(edvURLFlagName, "", edvURLFlagUsage)
+	cmd.Flags().String(vaultIDFlagName, "", vaultIDFlagUsage)
+}
+
+func getClientAndVaultID(cmd *cobra.Command) (*edv.Client, string, error) {
+	edvURL, err := cmdutils.GetUserSetVar(cmd, edvURLFlagName, edvURLEnvKey, false)
+	if err != nil {
+		return nil, "", err
+	}
+
+	vaultID, err := cmdutils.GetUserSetVar(cmd, vaultIDFlagName, vaultIDEnvKey, false)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return edv.New(strings.TrimSuffix(edvURL, "/") + vaultsPath), vaultID, nil
+}
+
+// exportVault writes the export of the given vault to the output file, or to standard output if there's no output
+// file, checking it as it goes. If the export can't be verified, then it's incomplete or was corrupted on the way.
+func exportVault(cmd *cobra.Command, client *edv.Client, vaultID, outputPath string) error {
+	export, err := client.ExportVault(vaultID)
+	if err != nil {
+		return err
+	}
+
+	defer closeAndLog(export, "export response")
+
+	output := cmd.OutOrStdout()
+
+	if outputPath != "" {
+		outputFile, createErr := os.Create(outputPath) //nolint: gosec
+		if createErr != nil {
+			return fmt.Errorf("failed to create output file: %w", createErr)
+		}
+
+		defer closeAndLog(outputFile, "output file")
+
+		output = outputFile
+	}
+
+	count, err := vaultexport.Verify(io.TeeReader(export, output))
+	if err != nil {
+		return fmt.Errorf("failed to export vault %s: %w", vaultID, err)
+	}
+
+	_, err = fmt.Fprintf(cmd.ErrOrStderr(), "Exported %d documents from vault %s\n", count, vaultID)
+
+	return err
+}
+
+// importVault checks the whole export before sending it to the EDV server, so that nothing is imported from
+// a corrupted or incomplete export.
+func importVault(cmd *cobra.Command, client *edv.Client, vaultID, inputPath string) error {
+	count, err := verifyExportFile(inputPath)
+	if err != nil {
+		return err
+	}
+
+	inputFile, err := os.Open(inputPath) //nolint: gosec
+	if err != nil {
+		return fmt.Errorf("failed to open input file: %w", err)
+	}
+
+	defer closeAndLog(inputFile, "input file")
+
+	result, err := client.ImportVault(vaultID, inputFile)
+	if err != nil {
+		return fmt.Errorf("failed to import vault %s: %w", vaultID, err)
+	}
+
+	_, err = fmt.Fprintf(cmd.OutOrStdout(), "Imported %d of %d documents into vault %s (%d already imported)\n",
+		result.Imported, count, vaultID, result.Skipped)
+	if err != nil {
+		return err
+	}
+
+	for _, failure := range result.Failed {
+		_, err = fmt.Fprintf(cmd.OutOrStdout(), "Failed to import document %s: %s\n", failure.ID, failure.Error)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(result.Failed) > 0 {
+		return fmt.Errorf("%d documents couldn't be imported into vault %s", len(result.Failed), vaultID)
+	}
+
+	return nil
+}
+
+func reindexVault(cmd *cobra.Command, client *edv.Client, vaultID string) error {
+	result, err := client.ReindexVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("failed to reindex vault %s: %w", vaultID, err)
+	}
+
+	_, err = fmt.Fprintf(cmd.OutOrStdout(),
+		"Reindexed %d documents in vault %s (%d index entries deleted, %d created)\n",
+		result.Documents, vaultID, result.MappingsDeleted, result.MappingsCreated)
+	if err != nil {
+		return err
+	}
+
+	for _, docID := range result.UnparsableDocuments {
+		_, err = fmt.Fprintf(cmd.OutOrStdout(), "Document %s couldn't be parsed, so its index entries were kept\n",
+			docID)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, violation := range result.UniquenessViolations {
+		_, err = fmt.Fprintf(cmd.OutOrStdout(), "Unique index %s with value %s is shared by documents %s\n",
+			violation.Name, violation.Value, strings.Join(violation.DocumentIDs, ", "))
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(result.UniquenessViolations) > 0 {
+		return fmt.Errorf("%d unique index values are shared by several documents in vault %s",
+			len(result.UniquenessViolations), vaultID)
+	}
+
+	return nil
+}
+
+func verifyExportFile(inputPath string) (int, error) {
+	inputFile, err := os.Open(inputPath) //nolint: gosec
+	if err != nil {
+		return 0, fmt.Errorf("failed to open input file: %w", err)
+	}
+
+	defer closeAndLog(inputFile, "input file")
+
+	count, err := vaultexport.Verify(inputFile)
+	if err != nil {
+		return 0, fmt.Errorf("failed to verify %s: %w", inputPath, err)
+	}
+
+	return count, nil
+}
+
+func closeAndLog(closer io.Closer, name string) {
+	if err := closer.Close(); err != nil {
+		log.Errorf("Failed to close %s: %s", name, err.Error())
+	}
+}
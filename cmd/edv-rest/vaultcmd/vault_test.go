@@ -0,0 +1,250 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vaultcmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edv/pkg/client/edv"
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	edvservice "github.com/trustbloc/edv/pkg/restapi/edv"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+const (
+	testVaultID    = "testvault"
+	testDocumentID = "VJYHHJx4C8J9Fsgz7rZqSp"
+	testJWE        = `{"protected":"eyJlbmMiOiJDMjBQIn0","recipients":[{"header":{"alg":"A256KW",` +
+		`"kid":"https://example.com/kms/z7BgF536GaR"},"encrypted_key":` +
+		`"OR1vdCNvf_B68mfUxFQVT-vyXVrBembuiM40mAAjDC1-Qu5iArDbug"}],"iv":"i8Nins2vTI3PlrYW",` +
+		`"ciphertext":"Cb-963UCXblINT8F6MDHzMJN9EAhK3I","tag":"pfZO0JulJcrc3trOZy8rjA"}`
+)
+
+func TestGetVaultCmd(t *testing.T) {
+	vaultCmd := GetVaultCmd()
+
+	require.Equal(t, "vault", vaultCmd.Use)
+	require.Len(t, vaultCmd.Commands(), 3)
+
+	vaultCmd.SetArgs([]string{})
+	require.NoError(t, vaultCmd.Execute())
+}
+
+func TestExportAndImport(t *testing.T) {
+	dir, tempDirErr := ioutil.TempDir("", "edv-vaultcmd")
+	require.NoError(t, tempDirErr)
+
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	source := startTestEDVServer(t)
+	defer source.Close()
+
+	client := edv.New(source.URL + vaultsPath)
+
+	_, err := client.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: testVaultID})
+	require.NoError(t, err)
+
+	_, err = client.CreateDocument(testVaultID, &models.EncryptedDocument{ID: testDocumentID, JWE: []byte(testJWE)})
+	require.NoError(t, err)
+
+	exportPath := filepath.Join(dir, "export.ndjson")
+
+	t.Run("Export to a file", func(t *testing.T) {
+		_, err = execute("export", "--"+edvURLFlagName, source.URL+"/", "--"+vaultIDFlagName, testVaultID,
+			"--"+outputFlagName, exportPath)
+		require.NoError(t, err)
+
+		exportBytes, err := ioutil.ReadFile(exportPath) //nolint: gosec
+		require.NoError(t, err)
+		require.Len(t, strings.Split(strings.TrimSpace(string(exportBytes)), "\n"), 3)
+	})
+	t.Run("Export to standard output", func(t *testing.T) {
+		output, err := execute("export", "--"+edvURLFlagName, source.URL, "--"+vaultIDFlagName, testVaultID)
+		require.NoError(t, err)
+		require.Contains(t, output, testDocumentID)
+	})
+	t.Run("Import, then resume", func(t *testing.T) {
+		destination := startTestEDVServer(t)
+		defer destination.Close()
+
+		output, err := execute("import", "--"+edvURLFlagName, destination.URL, "--"+vaultIDFlagName, testVaultID,
+			"--"+inputFlagName, exportPath)
+		require.NoError(t, err)
+		require.Equal(t, "Imported 1 of 1 documents into vault testvault (0 already imported)\n", output)
+
+		output, err = execute("import", "--"+edvURLFlagName, destination.URL, "--"+vaultIDFlagName, testVaultID,
+			"--"+inputFlagName, exportPath)
+		require.NoError(t, err)
+		require.Equal(t, "Imported 0 of 1 documents into vault testvault (1 already imported)\n", output)
+
+		_, err = edv.New(destination.URL+vaultsPath).ReadDocument(testVaultID, testDocumentID)
+		require.NoError(t, err)
+	})
+	t.Run("Documents that can't be imported", func(t *testing.T) {
+		destination := startTestEDVServer(t)
+		defer destination.Close()
+
+		destinationClient := edv.New(destination.URL + vaultsPath)
+
+		_, err = destinationClient.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: testVaultID})
+		require.NoError(t, err)
+
+		_, err = destinationClient.CreateDocument(testVaultID,
+			&models.EncryptedDocument{ID: testDocumentID, Sequence: 1, JWE: []byte(testJWE)})
+		require.NoError(t, err)
+
+		output, err := execute("import", "--"+edvURLFlagName, destination.URL, "--"+vaultIDFlagName, testVaultID,
+			"--"+inputFlagName, exportPath)
+		require.EqualError(t, err, "1 documents couldn't be imported into vault testvault")
+		require.Contains(t, output, "Failed to import document "+testDocumentID)
+	})
+	t.Run("Corrupted export", func(t *testing.T) {
+		exportBytes, err := ioutil.ReadFile(exportPath) //nolint: gosec
+		require.NoError(t, err)
+
+		corruptedExportPath := filepath.Join(dir, "corrupted.ndjson")
+		require.NoError(t, ioutil.WriteFile(corruptedExportPath,
+			bytes.Replace(exportBytes, []byte("Cb-963"), []byte("Db-963"), 1), 0600))
+
+		_, err = execute("import", "--"+edvURLFlagName, source.URL, "--"+vaultIDFlagName, "othervault",
+			"--"+inputFlagName, corruptedExportPath)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "doesn't match its checksum")
+
+		_, err = client.ReadDocument("othervault", testDocumentID)
+		require.Error(t, err)
+	})
+}
+
+func TestReindex(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		server := startMockReindexServer(t, `{"documents":2,"mappingsDeleted":1,"mappingsCreated":3,`+
+			`"unparsableDocuments":["brokenDoc"]}`)
+		defer server.Close()
+
+		output, err := execute("reindex", "--"+edvURLFlagName, server.URL, "--"+vaultIDFlagName, testVaultID)
+		require.NoError(t, err)
+		require.Equal(t, "Reindexed 2 documents in vault testvault (1 index entries deleted, 3 created)\n"+
+			"Document brokenDoc couldn't be parsed, so its index entries were kept\n", output)
+	})
+	t.Run("Uniqueness violations", func(t *testing.T) {
+		server := startMockReindexServer(t, `{"documents":2,"uniquenessViolations":`+
+			`[{"name":"name","value":"value","documentIds":["docID1","docID2"]}]}`)
+		defer server.Close()
+
+		output, err := execute("reindex", "--"+edvURLFlagName, server.URL, "--"+vaultIDFlagName, testVaultID)
+		require.EqualError(t, err, "1 unique index values are shared by several documents in vault testvault")
+		require.Contains(t, output, "Unique index name with value value is shared by documents docID1, docID2\n")
+	})
+	t.Run("Provider doesn't support indexing", func(t *testing.T) {
+		server := startTestEDVServer(t)
+		defer server.Close()
+
+		_, err := edv.New(server.URL + vaultsPath).CreateDataVault(&models.DataVaultConfiguration{
+			ReferenceID: testVaultID})
+		require.NoError(t, err)
+
+		_, err = execute("reindex", "--"+edvURLFlagName, server.URL, "--"+vaultIDFlagName, testVaultID)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "status code 501")
+	})
+}
+
+func TestInvalidParameters(t *testing.T) {
+	t.Run("Missing EDV URL", func(t *testing.T) {
+		_, err := execute("export", "--"+vaultIDFlagName, testVaultID)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), edvURLFlagName)
+	})
+	t.Run("Missing vault ID", func(t *testing.T) {
+		_, err := execute("import", "--"+edvURLFlagName, "http://localhost:0")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), vaultIDFlagName)
+	})
+	t.Run("Missing input", func(t *testing.T) {
+		_, err := execute("import", "--"+edvURLFlagName, "http://localhost:0", "--"+vaultIDFlagName, testVaultID)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), inputFlagName)
+	})
+	t.Run("Input file doesn't exist", func(t *testing.T) {
+		_, err := execute("import", "--"+edvURLFlagName, "http://localhost:0", "--"+vaultIDFlagName, testVaultID,
+			"--"+inputFlagName, filepath.Join(os.TempDir(), "missing.ndjson"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to open input file")
+	})
+	t.Run("Vault not found", func(t *testing.T) {
+		server := startTestEDVServer(t)
+		defer server.Close()
+
+		_, err := execute("export", "--"+edvURLFlagName, server.URL, "--"+vaultIDFlagName, testVaultID)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "status code 404")
+	})
+	t.Run("Output file can't be created", func(t *testing.T) {
+		server := startTestEDVServer(t)
+		defer server.Close()
+
+		_, err := edv.New(server.URL + vaultsPath).CreateDataVault(&models.DataVaultConfiguration{
+			ReferenceID: testVaultID})
+		require.NoError(t, err)
+
+		_, err = execute("export", "--"+edvURLFlagName, server.URL, "--"+vaultIDFlagName, testVaultID,
+			"--"+outputFlagName, filepath.Join(os.TempDir(), "missing", "export.ndjson"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to create output file")
+	})
+}
+
+func startTestEDVServer(t *testing.T) *httptest.Server {
+	edvService, err := edvservice.New(memedvprovider.NewProvider())
+	require.NoError(t, err)
+
+	router := mux.NewRouter()
+	router.UseEncodedPath()
+
+	for _, handler := range edvService.GetOperations() {
+		router.HandleFunc(handler.Path(), handler.Handle()).Methods(handler.Method())
+	}
+
+	return httptest.NewServer(router)
+}
+
+func startMockReindexServer(t *testing.T, response string) *httptest.Server {
+	router := mux.NewRouter()
+	router.HandleFunc(vaultsPath+"/{vaultID}/reindex", func(rw http.ResponseWriter, req *http.Request) {
+		_, err := rw.Write([]byte(response))
+		require.NoError(t, err)
+	}).Methods(http.MethodPost)
+
+	return httptest.NewServer(router)
+}
+
+func execute(args ...string) (string, error) {
+	vaultCmd := GetVaultCmd()
+
+	var output bytes.Buffer
+
+	vaultCmd.SetOut(&output)
+	vaultCmd.SetErr(ioutil.Discard)
+	vaultCmd.SetArgs(args)
+
+	err := vaultCmd.Execute()
+
+	return output.String(), err
+}
@@ -0,0 +1,262 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// ResultSuccess is the result recorded for operations that succeeded.
+	ResultSuccess = "success"
+	// ResultFailure is the result recorded for operations that failed.
+	ResultFailure = "failure"
+	// ResultSkipped is the result recorded for documents that an operation left as they were,
+	// such as documents that were already imported.
+	ResultSkipped = "skipped"
+
+	// maxAppendAttempts is how many times a record is appended to a shared sink before giving up, in case other
+	// writers keep taking its sequence number first.
+	maxAppendAttempts = 10
+)
+
+var (
+	// ErrChainBroken is returned when verification of an audit log fails because a record was modified,
+	// removed, reordered or inserted.
+	ErrChainBroken = errors.New("audit log hash chain is broken")
+	// ErrSequenceTaken is returned by a SharedSink when another writer already appended a record with the same
+	// sequence number.
+	ErrSequenceTaken = errors.New("an audit record with the same sequence number already exists")
+)
+
+// Entry represents the details of a single vault operation to be recorded in the audit log.
+type Entry struct {
+	Principal  string `json:"principal,omitempty"`
+	RemoteAddr string `json:"remoteAddr,omitempty"`
+	Action     string `json:"action"`
+	VaultID    string `json:"vaultId,omitempty"`
+	DocumentID string `json:"documentId,omitempty"`
+	Result     string `json:"result"`
+	StatusCode int    `json:"statusCode"`
+	// Documents holds the outcome for each document of operations that affect several documents at once.
+	Documents []DocumentEntry `json:"documents,omitempty"`
+}
+
+// DocumentEntry represents the outcome for a single document of an operation that affects several documents.
+// StatusCode is the HTTP status code that the equivalent single-document request would have returned.
+type DocumentEntry struct {
+	ID         string `json:"id"`
+	Operation  string `json:"operation"`
+	Result     string `json:"result"`
+	StatusCode int    `json:"statusCode"`
+}
+
+// Record represents an entry in the audit log. Each record includes the hash of the record before it,
+// so any modification to the log can be detected by Verify.
+type Record struct {
+	Entry
+	Sequence     uint64    `json:"sequence"`
+	Timestamp    time.Time `json:"timestamp"`
+	PreviousHash string    `json:"previousHash"`
+	Hash         string    `json:"hash"`
+}
+
+// Sink represents append-only storage for audit records.
+type Sink interface {
+	// Append stores the given record after all previously stored records.
+	Append(record *Record) error
+
+	// Records returns all stored records in the order they were appended.
+	Records() ([]Record, error)
+}
+
+// SharedSink represents a sink that several writers, such as EDV server instances that share a database,
+// append to at once. Append must fail with ErrSequenceTaken instead of replacing an existing record,
+// so that the writers can't fork or overwrite the chain.
+type SharedSink interface {
+	Sink
+
+	// LastRecord returns the last stored record, or nil if there are none, without reading every record.
+	LastRecord() (*Record, error)
+}
+
+// Log is a tamper-evident, hash-chained audit log.
+type Log struct {
+	sink         Sink
+	lastSequence uint64
+	lastHash     string
+	mux          sync.Mutex
+}
+
+// New returns a new audit log that stores its records in the given sink.
+// If the sink already contains records, then the existing chain is verified and new records are appended to it.
+// For a SharedSink, only the last record is checked, since the chain could be long. It can still be verified in full
+// with Verify.
+func New(sink Sink) (*Log, error) {
+	if sharedSink, ok := sink.(SharedSink); ok {
+		l := &Log{sink: sink}
+
+		err := l.loadLastRecord(sharedSink)
+		if err != nil {
+			return nil, err
+		}
+
+		return l, nil
+	}
+
+	records, err := sink.Records()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read existing audit records: %w", err)
+	}
+
+	err = Verify(records)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Log{sink: sink}
+
+	if len(records) > 0 {
+		lastRecord := records[len(records)-1]
+		l.lastSequence = lastRecord.Sequence
+		l.lastHash = lastRecord.Hash
+	}
+
+	return l, nil
+}
+
+// Record appends a new record for the given entry to the audit log.
+// If another writer appended a record to a shared sink first, then the log catches up with it and tries again.
+func (l *Log) Record(entry Entry) error {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	for attempt := 1; ; attempt++ {
+		err := l.append(entry)
+
+		sharedSink, isShared := l.sink.(SharedSink)
+		if !errors.Is(err, ErrSequenceTaken) || !isShared || attempt == maxAppendAttempts {
+			return err
+		}
+
+		err = l.loadLastRecord(sharedSink)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// append appends a record for the given entry after the last record the log knows of.
+// The caller must hold the lock.
+func (l *Log) append(entry Entry) error {
+	record := &Record{
+		Entry:        entry,
+		Sequence:     l.lastSequence + 1,
+		Timestamp:    time.Now().UTC(),
+		PreviousHash: l.lastHash,
+	}
+
+	hash, err := computeHash(record)
+	if err != nil {
+		return err
+	}
+
+	record.Hash = hash
+
+	err = l.sink.Append(record)
+	if err != nil {
+		return fmt.Errorf("failed to append audit record: %w", err)
+	}
+
+	l.lastSequence = record.Sequence
+	l.lastHash = record.Hash
+
+	return nil
+}
+
+// loadLastRecord makes the log carry on from the last record in the given sink, after checking that the record
+// hasn't been modified.
+func (l *Log) loadLastRecord(sink SharedSink) error {
+	record, err := sink.LastRecord()
+	if err != nil {
+		return fmt.Errorf("failed to read the last audit record: %w", err)
+	}
+
+	if record == nil {
+		l.lastSequence = 0
+		l.lastHash = ""
+
+		return nil
+	}
+
+	expectedHash, err := computeHash(record)
+	if err != nil {
+		return err
+	}
+
+	if record.Hash != expectedHash {
+		return fmt.Errorf("%w: record %d has been modified", ErrChainBroken, record.Sequence)
+	}
+
+	l.lastSequence = record.Sequence
+	l.lastHash = record.Hash
+
+	return nil
+}
+
+// Verify checks that the given records form an unbroken hash chain starting from the first record.
+func Verify(records []Record) error {
+	previousHash := ""
+
+	for i := range records {
+		record := records[i]
+
+		if record.Sequence != uint64(i+1) {
+			return fmt.Errorf("%w: expected sequence %d but found %d", ErrChainBroken, i+1, record.Sequence)
+		}
+
+		if record.PreviousHash != previousHash {
+			return fmt.Errorf("%w: record %d doesn't reference the hash of the previous record",
+				ErrChainBroken, record.Sequence)
+		}
+
+		expectedHash, err := computeHash(&record)
+		if err != nil {
+			return err
+		}
+
+		if record.Hash != expectedHash {
+			return fmt.Errorf("%w: record %d has been modified", ErrChainBroken, record.Sequence)
+		}
+
+		previousHash = record.Hash
+	}
+
+	return nil
+}
+
+// computeHash returns the hex-encoded SHA-256 hash of the given record with its hash field left blank.
+// Since the record includes the hash of the previous record, this chains all records together.
+func computeHash(record *Record) (string, error) {
+	recordToHash := *record
+	recordToHash.Hash = ""
+
+	recordBytes, err := json.Marshal(recordToHash)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+
+	hash := sha256.Sum256(recordBytes)
+
+	return hex.EncodeToString(hash[:]), nil
+}
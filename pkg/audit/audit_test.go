@@ -0,0 +1,172 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package audit
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type mockSink struct {
+	records    []Record
+	errAppend  error
+	errRecords error
+}
+
+func (m *mockSink) Append(record *Record) error {
+	if m.errAppend != nil {
+		return m.errAppend
+	}
+
+	m.records = append(m.records, *record)
+
+	return nil
+}
+
+func (m *mockSink) Records() ([]Record, error) {
+	return m.records, m.errRecords
+}
+
+func TestLog_Record(t *testing.T) {
+	t.Run("Records are chained together", func(t *testing.T) {
+		sink := &mockSink{}
+
+		auditLog, err := New(sink)
+		require.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			err = auditLog.Record(Entry{Action: "createDocument", VaultID: "vault", Result: ResultSuccess})
+			require.NoError(t, err)
+		}
+
+		require.Len(t, sink.records, 3)
+		require.Equal(t, uint64(1), sink.records[0].Sequence)
+		require.Empty(t, sink.records[0].PreviousHash)
+		require.Equal(t, sink.records[0].Hash, sink.records[1].PreviousHash)
+		require.Equal(t, sink.records[1].Hash, sink.records[2].PreviousHash)
+		require.NoError(t, Verify(sink.records))
+	})
+	t.Run("Documents affected by an operation are part of its record", func(t *testing.T) {
+		sink := &mockSink{}
+
+		auditLog, err := New(sink)
+		require.NoError(t, err)
+
+		err = auditLog.Record(Entry{Action: "batchDocuments", VaultID: "vault", Result: ResultSuccess,
+			Documents: []DocumentEntry{{ID: "doc", Operation: "create", Result: ResultSuccess, StatusCode: 201}}})
+		require.NoError(t, err)
+		require.NoError(t, Verify(sink.records))
+
+		sink.records[0].Documents[0].ID = "otherDoc"
+		require.True(t, errors.Is(Verify(sink.records), ErrChainBroken))
+	})
+	t.Run("Existing chain is resumed", func(t *testing.T) {
+		sink := &mockSink{}
+
+		auditLog, err := New(sink)
+		require.NoError(t, err)
+
+		require.NoError(t, auditLog.Record(Entry{Action: "createVault", Result: ResultSuccess}))
+
+		auditLog, err = New(sink)
+		require.NoError(t, err)
+
+		require.NoError(t, auditLog.Record(Entry{Action: "createVault", Result: ResultFailure}))
+
+		require.Len(t, sink.records, 2)
+		require.Equal(t, uint64(2), sink.records[1].Sequence)
+		require.NoError(t, Verify(sink.records))
+	})
+	t.Run("Failure to append", func(t *testing.T) {
+		sink := &mockSink{}
+
+		auditLog, err := New(sink)
+		require.NoError(t, err)
+
+		sink.errAppend = errors.New("append failure")
+
+		err = auditLog.Record(Entry{Action: "createVault", Result: ResultSuccess})
+		require.EqualError(t, err, "failed to append audit record: append failure")
+
+		sink.errAppend = nil
+
+		require.NoError(t, auditLog.Record(Entry{Action: "createVault", Result: ResultSuccess}))
+		require.Equal(t, uint64(1), sink.records[0].Sequence)
+		require.NoError(t, Verify(sink.records))
+	})
+}
+
+func TestNew(t *testing.T) {
+	t.Run("Failure to read existing records", func(t *testing.T) {
+		auditLog, err := New(&mockSink{errRecords: errors.New("read failure")})
+		require.EqualError(t, err, "failed to read existing audit records: read failure")
+		require.Nil(t, auditLog)
+	})
+	t.Run("Existing chain is broken", func(t *testing.T) {
+		sink := &mockSink{}
+
+		auditLog, err := New(sink)
+		require.NoError(t, err)
+
+		require.NoError(t, auditLog.Record(Entry{Action: "createVault", Result: ResultSuccess}))
+
+		sink.records[0].VaultID = "tampered"
+
+		auditLog, err = New(sink)
+		require.True(t, errors.Is(err, ErrChainBroken))
+		require.Nil(t, auditLog)
+	})
+}
+
+func TestVerify(t *testing.T) {
+	newRecords := func(t *testing.T) []Record {
+		sink := &mockSink{}
+
+		auditLog, err := New(sink)
+		require.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			require.NoError(t, auditLog.Record(Entry{Action: "readDocument", Result: ResultSuccess}))
+		}
+
+		return sink.records
+	}
+
+	t.Run("Empty log", func(t *testing.T) {
+		require.NoError(t, Verify(nil))
+	})
+	t.Run("Modified record", func(t *testing.T) {
+		records := newRecords(t)
+		records[1].Result = ResultFailure
+
+		err := Verify(records)
+		require.True(t, errors.Is(err, ErrChainBroken))
+		require.Contains(t, err.Error(), "record 2 has been modified")
+	})
+	t.Run("Removed record", func(t *testing.T) {
+		records := newRecords(t)
+
+		err := Verify(append(records[:1], records[2:]...))
+		require.True(t, errors.Is(err, ErrChainBroken))
+		require.Contains(t, err.Error(), "expected sequence 2 but found 3")
+	})
+	t.Run("Rehashed record after removal", func(t *testing.T) {
+		records := newRecords(t)
+
+		records = append(records[:1], records[2:]...)
+		records[1].Sequence = 2
+		hash, err := computeHash(&records[1])
+		require.NoError(t, err)
+		records[1].Hash = hash
+
+		err = Verify(records)
+		require.True(t, errors.Is(err, ErrChainBroken))
+		require.Contains(t, err.Error(), "record 2 doesn't reference the hash of the previous record")
+	})
+}
@@ -0,0 +1,110 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	auditFilePermissions = 0600
+	maxRecordSize        = 1024 * 1024
+)
+
+// FileSink stores audit records in an append-only file, one JSON record per line.
+type FileSink struct {
+	path string
+	file *os.File
+	mux  sync.Mutex
+}
+
+// NewFileSink returns a new FileSink that appends records to the file at the given path,
+// creating the file if it doesn't exist.
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, auditFilePermissions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log file: %w", err)
+	}
+
+	return &FileSink{path: path, file: file}, nil
+}
+
+// Append writes the given record to the end of the file and syncs it to disk.
+func (f *FileSink) Append(record *Record) error {
+	recordBytes, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	_, err = f.file.Write(append(recordBytes, '\n'))
+	if err != nil {
+		return err
+	}
+
+	return f.file.Sync()
+}
+
+// Records reads all records from the file.
+func (f *FileSink) Records() ([]Record, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	return ReadRecordsFromFile(f.path)
+}
+
+// Close closes the underlying file.
+func (f *FileSink) Close() error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	return f.file.Close()
+}
+
+// ReadRecordsFromFile reads all audit records from the file at the given path.
+func ReadRecordsFromFile(path string) ([]Record, error) {
+	file, err := os.Open(path) //nolint: gosec
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log file: %w", err)
+	}
+
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			log.Errorf("Failed to close audit log file: %s", closeErr.Error())
+		}
+	}()
+
+	var records []Record
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, bufio.MaxScanTokenSize), maxRecordSize)
+
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record Record
+
+		err = json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse audit record %d: %w", len(records)+1, err)
+		}
+
+		records = append(records, record)
+	}
+
+	return records, scanner.Err()
+}
@@ -0,0 +1,91 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package audit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSink(t *testing.T) {
+	dir, tempDirErr := ioutil.TempDir("", "audit")
+	require.NoError(t, tempDirErr)
+
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	t.Run("Records are appended and read back", func(t *testing.T) {
+		path := filepath.Join(dir, "audit.log")
+
+		sink, err := NewFileSink(path)
+		require.NoError(t, err)
+
+		auditLog, err := New(sink)
+		require.NoError(t, err)
+
+		require.NoError(t, auditLog.Record(Entry{Action: "createVault", VaultID: "vault", Result: ResultSuccess}))
+		require.NoError(t, sink.Close())
+
+		sink, err = NewFileSink(path)
+		require.NoError(t, err)
+
+		auditLog, err = New(sink)
+		require.NoError(t, err)
+
+		require.NoError(t, auditLog.Record(Entry{Action: "createDocument", VaultID: "vault", Result: ResultSuccess}))
+		require.NoError(t, sink.Close())
+
+		records, err := ReadRecordsFromFile(path)
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		require.Equal(t, "createVault", records[0].Action)
+		require.Equal(t, "createDocument", records[1].Action)
+		require.NoError(t, Verify(records))
+	})
+	t.Run("Fail to open file", func(t *testing.T) {
+		sink, err := NewFileSink(filepath.Join(dir, "missing", "audit.log"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to open audit log file")
+		require.Nil(t, sink)
+	})
+	t.Run("Fail to append to closed file", func(t *testing.T) {
+		sink, err := NewFileSink(filepath.Join(dir, "closed.log"))
+		require.NoError(t, err)
+		require.NoError(t, sink.Close())
+
+		require.Error(t, sink.Append(&Record{}))
+	})
+}
+
+func TestReadRecordsFromFile(t *testing.T) {
+	dir, tempDirErr := ioutil.TempDir("", "audit")
+	require.NoError(t, tempDirErr)
+
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	t.Run("File doesn't exist", func(t *testing.T) {
+		records, err := ReadRecordsFromFile(filepath.Join(dir, "missing.log"))
+		require.Error(t, err)
+		require.Nil(t, records)
+	})
+	t.Run("Unparsable record", func(t *testing.T) {
+		path := filepath.Join(dir, "invalid.log")
+		require.NoError(t, ioutil.WriteFile(path, []byte("{}\n\nnot json\n"), auditFilePermissions))
+
+		records, err := ReadRecordsFromFile(path)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse audit record 2")
+		require.Nil(t, records)
+	})
+}
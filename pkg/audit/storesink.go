@@ -0,0 +1,200 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edv/pkg/edvprovider"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+const (
+	// StoreName is the name of the store used by StoreSink.
+	StoreName = "auditlog"
+
+	// headKey is the key of the document that holds the chain head, which is the sequence number of the last record.
+	headKey = "head"
+)
+
+// StoreSink stores audit records in a store from an EDV provider, so that EDV server instances that share
+// a database share the audit log too. Each record is kept in the JWE field of a document whose ID is the record's
+// sequence number. Records are written with a create-only write, so if another instance already appended a record
+// with the same sequence number, Append fails with ErrSequenceTaken instead of replacing it.
+// The chain head is stored as well, so that the last record can be found without reading every record.
+type StoreSink struct {
+	store edvprovider.EDVStore
+}
+
+// chainHead is what's kept in the head document.
+type chainHead struct {
+	Sequence uint64 `json:"sequence"`
+}
+
+// NewStoreSink returns a new StoreSink that stores records in the given provider, creating the audit store if needed.
+func NewStoreSink(provider edvprovider.EDVProvider) (*StoreSink, error) {
+	err := provider.CreateStore(StoreName)
+	if err != nil && err != storage.ErrDuplicateStore {
+		return nil, fmt.Errorf("failed to create audit store: %w", err)
+	}
+
+	store, err := provider.OpenStore(StoreName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit store: %w", err)
+	}
+
+	return &StoreSink{store: store}, nil
+}
+
+// Append stores the given record, unless a record with the same sequence number is already stored,
+// and then moves the chain head to it.
+func (s *StoreSink) Append(record *Record) error {
+	recordBytes, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.Create(models.EncryptedDocument{ID: recordKey(record.Sequence), JWE: recordBytes})
+	if errors.Is(err, edverrors.ErrDuplicateDocument) {
+		return ErrSequenceTaken
+	}
+
+	if err != nil {
+		return err
+	}
+
+	headBytes, err := json.Marshal(chainHead{Sequence: record.Sequence})
+	if err != nil {
+		return err
+	}
+
+	// The record is stored by now, and LastRecord looks past the chain head anyway, so failing to move it
+	// is logged rather than returned.
+	err = s.store.Put(models.EncryptedDocument{ID: headKey, JWE: headBytes})
+	if err != nil {
+		log.Warnf("Failed to update the audit log's chain head to record %d: %s", record.Sequence, err.Error())
+	}
+
+	return nil
+}
+
+// LastRecord returns the last stored record, or nil if there are none.
+// The chain head isn't moved in the same write as the record is stored, and concurrent writers can move it
+// in either order, so any records after the one it points to are looked for as well.
+func (s *StoreSink) LastRecord() (*Record, error) {
+	sequence, err := s.headSequence()
+	if err != nil {
+		return nil, err
+	}
+
+	var lastRecord *Record
+
+	if sequence > 0 {
+		lastRecord, err = s.getRecord(sequence)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		record, err := s.getRecord(sequence + 1)
+		if errors.Is(err, storage.ErrValueNotFound) {
+			return lastRecord, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		lastRecord = record
+		sequence++
+	}
+}
+
+// Records reads all records from the store, starting from the first sequence number until there are no more.
+func (s *StoreSink) Records() ([]Record, error) {
+	var records []Record
+
+	for sequence := uint64(1); ; sequence++ {
+		record, err := s.getRecord(sequence)
+		if errors.Is(err, storage.ErrValueNotFound) {
+			return records, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, *record)
+	}
+}
+
+// headSequence returns the sequence number that the chain head points to, or 0 if there's no chain head yet.
+func (s *StoreSink) headSequence() (uint64, error) {
+	headBytes, err := s.getDocumentContents(headKey)
+	if errors.Is(err, storage.ErrValueNotFound) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	head := chainHead{}
+
+	err = json.Unmarshal(headBytes, &head)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse audit chain head: %w", err)
+	}
+
+	return head.Sequence, nil
+}
+
+// getRecord returns the record with the given sequence number, or storage.ErrValueNotFound if there isn't one.
+func (s *StoreSink) getRecord(sequence uint64) (*Record, error) {
+	recordBytes, err := s.getDocumentContents(recordKey(sequence))
+	if err != nil {
+		return nil, err
+	}
+
+	var record Record
+
+	err = json.Unmarshal(recordBytes, &record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse audit record %d: %w", sequence, err)
+	}
+
+	return &record, nil
+}
+
+// getDocumentContents returns what's kept in the JWE field of the document with the given ID.
+func (s *StoreSink) getDocumentContents(id string) ([]byte, error) {
+	documentBytes, err := s.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	document := models.EncryptedDocument{}
+
+	err = json.Unmarshal(documentBytes, &document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse audit document %s: %w", id, err)
+	}
+
+	return document.JWE, nil
+}
+
+func recordKey(sequence uint64) string {
+	return "record" + strconv.FormatUint(sequence, 10)
+}
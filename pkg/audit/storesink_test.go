@@ -0,0 +1,315 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package audit
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edv/pkg/edvprovider"
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+type mockProvider struct {
+	edvprovider.EDVProvider
+	errCreateStore error
+	errOpenStore   error
+	store          *mockStore
+}
+
+func (m *mockProvider) CreateStore(name string) error {
+	if m.errCreateStore != nil {
+		return m.errCreateStore
+	}
+
+	return m.EDVProvider.CreateStore(name)
+}
+
+func (m *mockProvider) OpenStore(name string) (edvprovider.EDVStore, error) {
+	if m.errOpenStore != nil {
+		return nil, m.errOpenStore
+	}
+
+	store, err := m.EDVProvider.OpenStore(name)
+	if err != nil {
+		return nil, err
+	}
+
+	m.store.EDVStore = store
+
+	return m.store, nil
+}
+
+type mockStore struct {
+	edvprovider.EDVStore
+	errCreate error
+	errPut    error
+	errGet    error
+}
+
+func (m *mockStore) Create(document models.EncryptedDocument) error {
+	if m.errCreate != nil {
+		return m.errCreate
+	}
+
+	return m.EDVStore.Create(document)
+}
+
+func (m *mockStore) Put(document models.EncryptedDocument) error {
+	if m.errPut != nil {
+		return m.errPut
+	}
+
+	return m.EDVStore.Put(document)
+}
+
+func (m *mockStore) Get(k string) ([]byte, error) {
+	if m.errGet != nil {
+		return nil, m.errGet
+	}
+
+	return m.EDVStore.Get(k)
+}
+
+func newMockProvider() *mockProvider {
+	return &mockProvider{EDVProvider: memedvprovider.NewProvider(), store: &mockStore{}}
+}
+
+func TestStoreSink(t *testing.T) {
+	t.Run("Records are appended and read back", func(t *testing.T) {
+		provider := memedvprovider.NewProvider()
+
+		sink, err := NewStoreSink(provider)
+		require.NoError(t, err)
+
+		auditLog, err := New(sink)
+		require.NoError(t, err)
+
+		require.NoError(t, auditLog.Record(Entry{Action: "createVault", VaultID: "vault", Result: ResultSuccess}))
+
+		sink, err = NewStoreSink(provider)
+		require.NoError(t, err)
+
+		auditLog, err = New(sink)
+		require.NoError(t, err)
+
+		require.NoError(t, auditLog.Record(Entry{Action: "queryVault", VaultID: "vault", Result: ResultFailure}))
+
+		records, err := sink.Records()
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		require.Equal(t, "queryVault", records[1].Action)
+		require.NoError(t, Verify(records))
+	})
+	t.Run("Writers sharing the store don't overwrite each other's records", func(t *testing.T) {
+		provider := memedvprovider.NewProvider()
+
+		sink1, err := NewStoreSink(provider)
+		require.NoError(t, err)
+
+		auditLog1, err := New(sink1)
+		require.NoError(t, err)
+
+		sink2, err := NewStoreSink(provider)
+		require.NoError(t, err)
+
+		auditLog2, err := New(sink2)
+		require.NoError(t, err)
+
+		require.NoError(t, auditLog1.Record(Entry{Action: "createVault", Result: ResultSuccess}))
+		require.NoError(t, auditLog1.Record(Entry{Action: "createDocument", Result: ResultSuccess}))
+
+		// The second log still thinks the store is empty, so it has to catch up first.
+		require.NoError(t, auditLog2.Record(Entry{Action: "readDocument", Result: ResultSuccess}))
+		require.NoError(t, auditLog1.Record(Entry{Action: "queryVault", Result: ResultSuccess}))
+
+		records, err := sink1.Records()
+		require.NoError(t, err)
+		require.NoError(t, Verify(records))
+		require.Len(t, records, 4)
+		require.Equal(t, "createVault", records[0].Action)
+		require.Equal(t, "createDocument", records[1].Action)
+		require.Equal(t, "readDocument", records[2].Action)
+		require.Equal(t, "queryVault", records[3].Action)
+	})
+	t.Run("Only the last record is read when the log is opened", func(t *testing.T) {
+		provider := memedvprovider.NewProvider()
+
+		sink, err := NewStoreSink(provider)
+		require.NoError(t, err)
+
+		auditLog, err := New(sink)
+		require.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			require.NoError(t, auditLog.Record(Entry{Action: "createDocument", Result: ResultSuccess}))
+		}
+
+		store, err := provider.OpenStore(StoreName)
+		require.NoError(t, err)
+		require.NoError(t, store.Put(models.EncryptedDocument{ID: recordKey(1), JWE: []byte(`"tampered"`)}))
+
+		auditLog, err = New(sink)
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), auditLog.lastSequence)
+
+		// Tampering with earlier records is still caught by reading and verifying the whole log.
+		_, err = sink.Records()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse audit record 1")
+	})
+	t.Run("Records after a stale chain head are found", func(t *testing.T) {
+		provider := memedvprovider.NewProvider()
+
+		sink, err := NewStoreSink(provider)
+		require.NoError(t, err)
+
+		auditLog, err := New(sink)
+		require.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			require.NoError(t, auditLog.Record(Entry{Action: "createDocument", Result: ResultSuccess}))
+		}
+
+		store, err := provider.OpenStore(StoreName)
+		require.NoError(t, err)
+		require.NoError(t, store.Put(models.EncryptedDocument{ID: headKey, JWE: []byte(`{"sequence":1}`)}))
+
+		lastRecord, err := sink.LastRecord()
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), lastRecord.Sequence)
+	})
+	t.Run("Modified last record", func(t *testing.T) {
+		provider := memedvprovider.NewProvider()
+
+		sink, err := NewStoreSink(provider)
+		require.NoError(t, err)
+
+		auditLog, err := New(sink)
+		require.NoError(t, err)
+
+		require.NoError(t, auditLog.Record(Entry{Action: "createVault", Result: ResultSuccess}))
+
+		store, err := provider.OpenStore(StoreName)
+		require.NoError(t, err)
+		require.NoError(t, store.Put(models.EncryptedDocument{ID: recordKey(1),
+			JWE: []byte(`{"action":"createVault","result":"failure","statusCode":0,"sequence":1}`)}))
+
+		auditLog, err = New(sink)
+		require.True(t, errors.Is(err, ErrChainBroken))
+		require.Nil(t, auditLog)
+	})
+	t.Run("Give up after the sequence number is taken too many times", func(t *testing.T) {
+		provider := newMockProvider()
+		provider.store.errCreate = edverrors.ErrDuplicateDocument
+
+		sink, err := NewStoreSink(provider)
+		require.NoError(t, err)
+
+		auditLog, err := New(sink)
+		require.NoError(t, err)
+
+		err = auditLog.Record(Entry{Action: "createVault", Result: ResultSuccess})
+		require.True(t, errors.Is(err, ErrSequenceTaken))
+	})
+	t.Run("Fail to store record", func(t *testing.T) {
+		provider := newMockProvider()
+		provider.store.errCreate = errors.New("create failure")
+
+		sink, err := NewStoreSink(provider)
+		require.NoError(t, err)
+
+		auditLog, err := New(sink)
+		require.NoError(t, err)
+
+		err = auditLog.Record(Entry{Action: "createVault", Result: ResultSuccess})
+		require.EqualError(t, err, "failed to append audit record: create failure")
+	})
+	t.Run("Failure to move the chain head doesn't fail the append", func(t *testing.T) {
+		provider := newMockProvider()
+		provider.store.errPut = errors.New("put failure")
+
+		sink, err := NewStoreSink(provider)
+		require.NoError(t, err)
+
+		auditLog, err := New(sink)
+		require.NoError(t, err)
+
+		require.NoError(t, auditLog.Record(Entry{Action: "createVault", Result: ResultSuccess}))
+		require.NoError(t, auditLog.Record(Entry{Action: "queryVault", Result: ResultSuccess}))
+
+		lastRecord, err := sink.LastRecord()
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), lastRecord.Sequence)
+	})
+	t.Run("Fail to create store", func(t *testing.T) {
+		provider := newMockProvider()
+		provider.errCreateStore = errors.New("create failure")
+
+		sink, err := NewStoreSink(provider)
+		require.EqualError(t, err, "failed to create audit store: create failure")
+		require.Nil(t, sink)
+	})
+	t.Run("Fail to open store", func(t *testing.T) {
+		provider := newMockProvider()
+		provider.errOpenStore = errors.New("open failure")
+
+		sink, err := NewStoreSink(provider)
+		require.EqualError(t, err, "failed to open audit store: open failure")
+		require.Nil(t, sink)
+	})
+	t.Run("Fail to read records", func(t *testing.T) {
+		provider := newMockProvider()
+		provider.store.errGet = errors.New("get failure")
+
+		sink, err := NewStoreSink(provider)
+		require.NoError(t, err)
+
+		records, err := sink.Records()
+		require.EqualError(t, err, "get failure")
+		require.Nil(t, records)
+
+		auditLog, err := New(sink)
+		require.EqualError(t, err, "failed to read the last audit record: get failure")
+		require.Nil(t, auditLog)
+	})
+	t.Run("Unparsable documents", func(t *testing.T) {
+		provider := memedvprovider.NewProvider()
+
+		sink, err := NewStoreSink(provider)
+		require.NoError(t, err)
+
+		store, err := provider.OpenStore(StoreName)
+		require.NoError(t, err)
+		require.NoError(t, store.Put(models.EncryptedDocument{ID: recordKey(1), JWE: []byte(`"not a record"`)}))
+
+		records, err := sink.Records()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse audit record 1")
+		require.Nil(t, records)
+
+		require.NoError(t, store.Put(models.EncryptedDocument{ID: headKey, JWE: []byte(`"not a chain head"`)}))
+
+		_, err = sink.LastRecord()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse audit chain head")
+	})
+	t.Run("Existing store is reused", func(t *testing.T) {
+		provider := newMockProvider()
+		provider.errCreateStore = storage.ErrDuplicateStore
+
+		require.NoError(t, provider.EDVProvider.CreateStore(StoreName))
+
+		_, err := NewStoreSink(provider)
+		require.NoError(t, err)
+	})
+}
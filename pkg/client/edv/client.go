@@ -15,6 +15,8 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
 	"github.com/trustbloc/edv/pkg/restapi/edv/models"
@@ -26,9 +28,10 @@ type marshalFunc func(interface{}) ([]byte, error)
 
 // Client is used to interact with an EDV server.
 type Client struct {
-	edvServerURL string
-	httpClient   *http.Client
-	marshal      marshalFunc
+	edvServerURL        string
+	httpClient          *http.Client
+	marshal             marshalFunc
+	eventReconnectDelay time.Duration
 }
 
 // Option configures the edv client
@@ -43,7 +46,8 @@ func WithTLSConfig(tlsConfig *tls.Config) Option {
 
 // New returns a new instance of an EDV client.
 func New(edvServerURL string, opts ...Option) *Client {
-	c := &Client{edvServerURL: edvServerURL, httpClient: &http.Client{}, marshal: json.Marshal}
+	c := &Client{edvServerURL: edvServerURL, httpClient: &http.Client{}, marshal: json.Marshal,
+		eventReconnectDelay: DefaultEventReconnectDelay}
 
 	for _, opt := range opts {
 		opt(c)
@@ -140,6 +144,215 @@ func (c *Client) QueryVault(vaultID string, query *models.Query) ([]string, erro
 	}
 }
 
+// ReadChanges returns up to limit changes made to the documents in the given vault after the given cursor,
+// along with the cursor to pass in the next call. A blank cursor reads from the beginning of the vault's change feed,
+// and a limit of 0 uses the EDV server's default.
+func (c *Client) ReadChanges(vaultID, since string, limit int) (*models.ChangeFeed, error) {
+	query := url.Values{}
+
+	if since != "" {
+		query.Set("since", since)
+	}
+
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	// The linter falsely claims that the body is not being closed
+	// https://github.com/golangci/golangci-lint/issues/637
+	resp, err := c.httpClient.Get(fmt.Sprintf("%s/%s/changes?%s", //nolint: bodyclose
+		c.edvServerURL, url.PathEscape(vaultID), query.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to send GET message: %w", err)
+	}
+
+	defer closeReadCloser(resp.Body)
+
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response message while reading changes: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("the EDV server returned status code %d along with the following message: %s",
+			resp.StatusCode, respBytes)
+	}
+
+	feed := models.ChangeFeed{}
+
+	err = json.Unmarshal(respBytes, &feed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal change feed: %w", err)
+	}
+
+	return &feed, nil
+}
+
+// Batch sends the EDV server a list of document operations to apply to the given vault in a single request.
+// The outcome of each operation is returned, in the same order as the operations. An error is only returned if the
+// batch as a whole couldn't be applied; operations that fail on their own are reported in their results.
+func (c *Client) Batch(vaultID string, operations []models.BatchOperation) ([]models.BatchResult, error) {
+	jsonToSend, err := c.marshal(models.BatchRequest{Operations: operations})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal batch: %w", err)
+	}
+
+	// The linter falsely claims that the body is not being closed
+	// https://github.com/golangci/golangci-lint/issues/637
+	resp, err := c.httpClient.Post(fmt.Sprintf("%s/%s/batch", //nolint: bodyclose
+		c.edvServerURL, url.PathEscape(vaultID)), "application/json", bytes.NewBuffer(jsonToSend))
+	if err != nil {
+		return nil, fmt.Errorf("failed to send POST message: %w", err)
+	}
+
+	defer closeReadCloser(resp.Body)
+
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response message while applying batch: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("the EDV server returned status code %d along with the following message: %s",
+			resp.StatusCode, respBytes)
+	}
+
+	response := models.BatchResponse{}
+
+	err = json.Unmarshal(respBytes, &response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal batch response: %w", err)
+	}
+
+	return response.Results, nil
+}
+
+// ExportVault sends the EDV server a request to export the given vault. The export is returned as a stream of
+// newline-delimited JSON records, which the caller must close. It can be checked with the vaultexport package.
+func (c *Client) ExportVault(vaultID string) (io.ReadCloser, error) {
+	// The response body is closed by the caller, or below if the export failed.
+	resp, err := c.httpClient.Get(fmt.Sprintf("%s/%s/export", //nolint: bodyclose
+		c.edvServerURL, url.PathEscape(vaultID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to send GET message: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer closeReadCloser(resp.Body)
+
+		respBytes, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response message while exporting vault: %w", err)
+		}
+
+		return nil, fmt.Errorf("the EDV server returned status code %d along with the following message: %s",
+			resp.StatusCode, respBytes)
+	}
+
+	return resp.Body, nil
+}
+
+// ImportVault sends the EDV server a vault export to import into the given vault, which is created if it doesn't
+// exist. Documents that are already in the vault with the same contents are skipped, so an interrupted import can be
+// resumed by importing the same export again.
+func (c *Client) ImportVault(vaultID string, export io.Reader) (*models.ImportResult, error) {
+	// The linter falsely claims that the body is not being closed
+	// https://github.com/golangci/golangci-lint/issues/637
+	resp, err := c.httpClient.Post(fmt.Sprintf("%s/%s/import", //nolint: bodyclose
+		c.edvServerURL, url.PathEscape(vaultID)), "application/x-ndjson", export)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send POST message: %w", err)
+	}
+
+	defer closeReadCloser(resp.Body)
+
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response message while importing vault: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("the EDV server returned status code %d along with the following message: %s",
+			resp.StatusCode, respBytes)
+	}
+
+	result := models.ImportResult{}
+
+	err = json.Unmarshal(respBytes, &result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal import result: %w", err)
+	}
+
+	return &result, nil
+}
+
+// ReindexVault sends the EDV server a request to rebuild the given vault's encrypted indexes from its documents.
+// Uniqueness violations found along the way are reported in the result, but aren't fixed.
+func (c *Client) ReindexVault(vaultID string) (*models.ReindexResult, error) {
+	// The linter falsely claims that the body is not being closed
+	// https://github.com/golangci/golangci-lint/issues/637
+	resp, err := c.httpClient.Post(fmt.Sprintf("%s/%s/reindex", //nolint: bodyclose
+		c.edvServerURL, url.PathEscape(vaultID)), "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send POST message: %w", err)
+	}
+
+	defer closeReadCloser(resp.Body)
+
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response message while reindexing vault: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("the EDV server returned status code %d along with the following message: %s",
+			resp.StatusCode, respBytes)
+	}
+
+	result := models.ReindexResult{}
+
+	err = json.Unmarshal(respBytes, &result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal reindex result: %w", err)
+	}
+
+	return &result, nil
+}
+
+// ListVaults returns the IDs of the vaults that the given controller controls.
+// The EDV server only allows this for the controller itself, so the client must be configured to authenticate with
+// a TLS client certificate whose identity is the controller.
+func (c *Client) ListVaults(controller string) ([]string, error) {
+	// The linter falsely claims that the body is not being closed
+	// https://github.com/golangci/golangci-lint/issues/637
+	resp, err := c.httpClient.Get(fmt.Sprintf("%s?%s", //nolint: bodyclose
+		c.edvServerURL, url.Values{"controller": {controller}}.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to send GET message: %w", err)
+	}
+
+	defer closeReadCloser(resp.Body)
+
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response message while listing vaults: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("the EDV server returned status code %d along with the following message: %s",
+			resp.StatusCode, respBytes)
+	}
+
+	list := models.VaultList{}
+
+	err = json.Unmarshal(respBytes, &list)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal vault list: %w", err)
+	}
+
+	return list.VaultIDs, nil
+}
+
 func (c *Client) sendCreateRequest(objectToMarshal interface{},
 	endpoint, statusConflictErrText string) (string, error) {
 	jsonToSend, err := c.marshal(objectToMarshal)
@@ -189,14 +402,15 @@ func getError(resp *http.Response) error {
 	return fmt.Errorf("the EDV server returned the following error: %s", string(respMsg))
 }
 
+// getStatusNotFoundErr returns the error for a not found response to a read document request.
+// The EDV errors are wrapped so that callers can tell a missing vault or document apart from other failures.
 func getStatusNotFoundErr(respBytes []byte) error {
-	respString := string(respBytes)
-
-	serverEndpointReached :=
-		respString == edverrors.ErrVaultNotFound.Error() || respString == edverrors.ErrDocumentNotFound.Error()
-	if serverEndpointReached {
-		return fmt.Errorf(fmt.Sprintf("failed to retrieve document: %s", respBytes))
+	switch string(respBytes) {
+	case edverrors.ErrVaultNotFound.Error():
+		return fmt.Errorf("failed to retrieve document: %w", edverrors.ErrVaultNotFound)
+	case edverrors.ErrDocumentNotFound.Error():
+		return fmt.Errorf("failed to retrieve document: %w", edverrors.ErrDocumentNotFound)
+	default:
+		return fmt.Errorf("unable to reach the EDV server Read Credential endpoint")
 	}
-
-	return fmt.Errorf("unable to reach the EDV server Read Credential endpoint")
 }
@@ -12,6 +12,7 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"strconv"
@@ -29,6 +30,7 @@ import (
 	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
 	"github.com/trustbloc/edv/pkg/restapi/edv/models"
 	"github.com/trustbloc/edv/pkg/restapi/edv/operation"
+	"github.com/trustbloc/edv/pkg/vaultexport"
 )
 
 const (
@@ -351,6 +353,7 @@ func TestClient_ReadDocument_NotFound(t *testing.T) {
 	document, err := client.ReadDocument(testVaultID, testDocumentID)
 	require.Nil(t, document)
 	require.Equal(t, fmt.Sprintf("failed to retrieve document: %s", edverrors.ErrDocumentNotFound.Error()), err.Error())
+	require.True(t, errors.Is(err, edverrors.ErrDocumentNotFound))
 
 	err = srv.Shutdown(context.Background())
 	require.NoError(t, err)
@@ -465,6 +468,414 @@ func TestClient_QueryVault(t *testing.T) {
 	})
 }
 
+func TestClient_ReadChanges(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		srvAddr := randomURL()
+
+		srv := startEDVServer(t, srvAddr)
+
+		waitForServerToStart(t, srvAddr)
+
+		client := New("http://" + srvAddr + "/encrypted-data-vaults")
+
+		_, err := client.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: testVaultIDWithSlashes})
+		require.NoError(t, err)
+
+		feed, err := client.ReadChanges(testVaultIDWithSlashes, "", 0)
+		require.NoError(t, err)
+		require.Empty(t, feed.Changes)
+
+		_, err = client.CreateDocument(testVaultIDWithSlashes,
+			&models.EncryptedDocument{ID: testDocumentID, JWE: []byte(testEncryptedDocJWE)})
+		require.NoError(t, err)
+
+		feed, err = client.ReadChanges(testVaultIDWithSlashes, feed.Cursor, 1)
+		require.NoError(t, err)
+		require.Equal(t, []models.DocumentChange{
+			{ID: testDocumentID, Sequence: "1", Type: models.ChangeTypeCreated},
+		}, feed.Changes)
+		require.False(t, feed.HasMore)
+
+		err = srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	})
+	t.Run("Failure: vault doesn't exist", func(t *testing.T) {
+		srvAddr := randomURL()
+
+		srv := startEDVServer(t, srvAddr)
+
+		waitForServerToStart(t, srvAddr)
+
+		client := New("http://" + srvAddr + "/encrypted-data-vaults")
+
+		feed, err := client.ReadChanges(testVaultID, "", 0)
+		require.EqualError(t, err, "the EDV server returned status code "+strconv.Itoa(http.StatusNotFound)+
+			" along with the following message: Failed to read changes: "+edverrors.ErrVaultNotFound.Error())
+		require.Nil(t, feed)
+
+		err = srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	})
+	t.Run("Failure: unable to unmarshal response", func(t *testing.T) {
+		srvAddr := randomURL()
+
+		srv := startMockEDVServer(srvAddr, support.NewHTTPHandler("/encrypted-data-vaults/{vaultID}/changes",
+			http.MethodGet, mockFailQueryVaultHandler))
+
+		waitForServerToStart(t, srvAddr)
+
+		client := New("http://" + srvAddr + "/encrypted-data-vaults")
+
+		feed, err := client.ReadChanges(testVaultID, "", 0)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal change feed")
+		require.Nil(t, feed)
+
+		err = srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	})
+	t.Run("Failure: server unreachable", func(t *testing.T) {
+		client := New("http://" + randomURL() + "/encrypted-data-vaults")
+
+		feed, err := client.ReadChanges(testVaultID, "", 0)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to send GET message")
+		require.Nil(t, feed)
+	})
+}
+
+func TestClient_Batch(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		srvAddr := randomURL()
+
+		srv := startEDVServer(t, srvAddr)
+
+		waitForServerToStart(t, srvAddr)
+
+		client := New("http://" + srvAddr + "/encrypted-data-vaults")
+
+		_, err := client.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: testVaultIDWithSlashes})
+		require.NoError(t, err)
+
+		document := &models.EncryptedDocument{ID: testDocumentID, JWE: []byte(testEncryptedDocJWE)}
+
+		results, err := client.Batch(testVaultIDWithSlashes, []models.BatchOperation{
+			{Type: models.BatchOperationCreate, Document: document},
+			{Type: models.BatchOperationCreate, Document: &models.EncryptedDocument{ID: "notBase58!"}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []models.BatchResult{
+			{ID: testDocumentID, Status: http.StatusCreated},
+			{ID: "notBase58!", Status: http.StatusBadRequest, Error: edverrors.ErrNotBase58Encoded.Error()},
+		}, results)
+
+		results, err = client.Batch(testVaultIDWithSlashes, []models.BatchOperation{
+			{Type: models.BatchOperationDelete, ID: testDocumentID},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []models.BatchResult{{ID: testDocumentID, Status: http.StatusNoContent}}, results)
+
+		_, err = client.ReadDocument(testVaultIDWithSlashes, testDocumentID)
+		require.True(t, errors.Is(err, edverrors.ErrDocumentNotFound))
+
+		err = srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	})
+	t.Run("Failure: vault doesn't exist", func(t *testing.T) {
+		srvAddr := randomURL()
+
+		srv := startEDVServer(t, srvAddr)
+
+		waitForServerToStart(t, srvAddr)
+
+		client := New("http://" + srvAddr + "/encrypted-data-vaults")
+
+		results, err := client.Batch(testVaultID, []models.BatchOperation{
+			{Type: models.BatchOperationDelete, ID: testDocumentID},
+		})
+		require.EqualError(t, err, "the EDV server returned status code "+strconv.Itoa(http.StatusNotFound)+
+			" along with the following message: Failed to apply batch: "+edverrors.ErrVaultNotFound.Error())
+		require.Nil(t, results)
+
+		err = srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	})
+	t.Run("Failure: unable to unmarshal response", func(t *testing.T) {
+		srvAddr := randomURL()
+
+		srv := startMockEDVServer(srvAddr, support.NewHTTPHandler("/encrypted-data-vaults/{vaultID}/batch",
+			http.MethodPost, mockFailQueryVaultHandler))
+
+		waitForServerToStart(t, srvAddr)
+
+		client := New("http://" + srvAddr + "/encrypted-data-vaults")
+
+		results, err := client.Batch(testVaultID, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal batch response")
+		require.Nil(t, results)
+
+		err = srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	})
+	t.Run("Failure: server unreachable", func(t *testing.T) {
+		client := New("http://" + randomURL() + "/encrypted-data-vaults")
+
+		results, err := client.Batch(testVaultID, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to send POST message")
+		require.Nil(t, results)
+	})
+	t.Run("Failure: unable to marshal batch", func(t *testing.T) {
+		client := New("http://" + randomURL() + "/encrypted-data-vaults")
+		client.marshal = failingMarshal
+
+		results, err := client.Batch(testVaultID, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to marshal batch")
+		require.Nil(t, results)
+	})
+}
+
+func TestClient_ExportAndImportVault(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		srvAddr := randomURL()
+
+		srv := startEDVServer(t, srvAddr)
+
+		waitForServerToStart(t, srvAddr)
+
+		client := New("http://" + srvAddr + "/encrypted-data-vaults")
+
+		_, err := client.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: testVaultIDWithSlashes})
+		require.NoError(t, err)
+
+		_, err = client.CreateDocument(testVaultIDWithSlashes,
+			&models.EncryptedDocument{ID: testDocumentID, JWE: []byte(testEncryptedDocJWE)})
+		require.NoError(t, err)
+
+		export, err := client.ExportVault(testVaultIDWithSlashes)
+		require.NoError(t, err)
+
+		exportBytes, err := ioutil.ReadAll(export)
+		require.NoError(t, err)
+		require.NoError(t, export.Close())
+
+		count, err := vaultexport.Verify(bytes.NewReader(exportBytes))
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+
+		result, err := client.ImportVault(testVaultID, bytes.NewReader(exportBytes))
+		require.NoError(t, err)
+		require.Equal(t, &models.ImportResult{VaultCreated: true, Imported: 1}, result)
+
+		result, err = client.ImportVault(testVaultID, bytes.NewReader(exportBytes))
+		require.NoError(t, err)
+		require.Equal(t, &models.ImportResult{Skipped: 1}, result)
+
+		_, err = client.ReadDocument(testVaultID, testDocumentID)
+		require.NoError(t, err)
+
+		err = srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	})
+	t.Run("Failure: server errors", func(t *testing.T) {
+		srvAddr := randomURL()
+
+		srv := startEDVServer(t, srvAddr)
+
+		waitForServerToStart(t, srvAddr)
+
+		client := New("http://" + srvAddr + "/encrypted-data-vaults")
+
+		export, err := client.ExportVault(testVaultID)
+		require.EqualError(t, err, "the EDV server returned status code "+strconv.Itoa(http.StatusNotFound)+
+			" along with the following message: Failed to export vault: "+edverrors.ErrVaultNotFound.Error())
+		require.Nil(t, export)
+
+		result, err := client.ImportVault(testVaultID, strings.NewReader("{"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "the EDV server returned status code "+strconv.Itoa(http.StatusBadRequest))
+		require.Nil(t, result)
+
+		err = srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	})
+	t.Run("Failure: unable to unmarshal import result", func(t *testing.T) {
+		srvAddr := randomURL()
+
+		srv := startMockEDVServer(srvAddr, support.NewHTTPHandler("/encrypted-data-vaults/{vaultID}/import",
+			http.MethodPost, mockFailQueryVaultHandler))
+
+		waitForServerToStart(t, srvAddr)
+
+		client := New("http://" + srvAddr + "/encrypted-data-vaults")
+
+		result, err := client.ImportVault(testVaultID, strings.NewReader(""))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal import result")
+		require.Nil(t, result)
+
+		err = srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	})
+	t.Run("Failure: server unreachable", func(t *testing.T) {
+		client := New("http://" + randomURL() + "/encrypted-data-vaults")
+
+		export, err := client.ExportVault(testVaultID)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to send GET message")
+		require.Nil(t, export)
+
+		result, err := client.ImportVault(testVaultID, strings.NewReader(""))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to send POST message")
+		require.Nil(t, result)
+	})
+}
+
+func TestClient_ReindexVault(t *testing.T) {
+	t.Run("Failure: server errors", func(t *testing.T) {
+		srvAddr := randomURL()
+
+		srv := startEDVServer(t, srvAddr)
+
+		waitForServerToStart(t, srvAddr)
+
+		client := New("http://" + srvAddr + "/encrypted-data-vaults")
+
+		result, err := client.ReindexVault(testVaultID)
+		require.EqualError(t, err, "the EDV server returned status code "+strconv.Itoa(http.StatusNotFound)+
+			" along with the following message: Failed to reindex vault: "+edverrors.ErrVaultNotFound.Error())
+		require.Nil(t, result)
+
+		_, err = client.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: testVaultID})
+		require.NoError(t, err)
+
+		result, err = client.ReindexVault(testVaultID)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "the EDV server returned status code "+strconv.Itoa(http.StatusNotImplemented))
+		require.Nil(t, result)
+
+		err = srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		srvAddr := randomURL()
+
+		srv := startMockEDVServer(srvAddr, support.NewHTTPHandler("/encrypted-data-vaults/{vaultID}/reindex",
+			http.MethodPost, func(rw http.ResponseWriter, req *http.Request) {
+				_, err := rw.Write([]byte(`{"documents":2,"mappingsDeleted":1,"mappingsCreated":3}`))
+				require.NoError(t, err)
+			}))
+
+		waitForServerToStart(t, srvAddr)
+
+		client := New("http://" + srvAddr + "/encrypted-data-vaults")
+
+		result, err := client.ReindexVault(testVaultID)
+		require.NoError(t, err)
+		require.Equal(t, &models.ReindexResult{Documents: 2, MappingsDeleted: 1, MappingsCreated: 3}, result)
+
+		err = srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	})
+	t.Run("Failure: unable to unmarshal reindex result", func(t *testing.T) {
+		srvAddr := randomURL()
+
+		srv := startMockEDVServer(srvAddr, support.NewHTTPHandler("/encrypted-data-vaults/{vaultID}/reindex",
+			http.MethodPost, mockFailQueryVaultHandler))
+
+		waitForServerToStart(t, srvAddr)
+
+		client := New("http://" + srvAddr + "/encrypted-data-vaults")
+
+		result, err := client.ReindexVault(testVaultID)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal reindex result")
+		require.Nil(t, result)
+
+		err = srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	})
+	t.Run("Failure: server unreachable", func(t *testing.T) {
+		client := New("http://" + randomURL() + "/encrypted-data-vaults")
+
+		result, err := client.ReindexVault(testVaultID)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to send POST message")
+		require.Nil(t, result)
+	})
+}
+
+func TestClient_ListVaults(t *testing.T) {
+	t.Run("Failure: client isn't authenticated", func(t *testing.T) {
+		srvAddr := randomURL()
+
+		srv := startEDVServer(t, srvAddr)
+
+		waitForServerToStart(t, srvAddr)
+
+		client := New("http://" + srvAddr + "/encrypted-data-vaults")
+
+		vaultIDs, err := client.ListVaults("did:example:123456789")
+		require.EqualError(t, err, "the EDV server returned status code "+strconv.Itoa(http.StatusUnauthorized)+
+			" along with the following message: Failed to list vaults: "+
+			edverrors.ErrUnauthenticatedController.Error())
+		require.Nil(t, vaultIDs)
+
+		err = srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		srvAddr := randomURL()
+
+		srv := startMockEDVServer(srvAddr, support.NewHTTPHandler("/encrypted-data-vaults",
+			http.MethodGet, func(rw http.ResponseWriter, req *http.Request) {
+				require.Equal(t, "did:example:123456789", req.URL.Query().Get("controller"))
+
+				_, err := rw.Write([]byte(`{"controller":"did:example:123456789","vaultIds":["vault1","vault2"]}`))
+				require.NoError(t, err)
+			}))
+
+		waitForServerToStart(t, srvAddr)
+
+		client := New("http://" + srvAddr + "/encrypted-data-vaults")
+
+		vaultIDs, err := client.ListVaults("did:example:123456789")
+		require.NoError(t, err)
+		require.Equal(t, []string{"vault1", "vault2"}, vaultIDs)
+
+		err = srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	})
+	t.Run("Failure: unable to unmarshal vault list", func(t *testing.T) {
+		srvAddr := randomURL()
+
+		srv := startMockEDVServer(srvAddr, support.NewHTTPHandler("/encrypted-data-vaults",
+			http.MethodGet, mockFailQueryVaultHandler))
+
+		waitForServerToStart(t, srvAddr)
+
+		client := New("http://" + srvAddr + "/encrypted-data-vaults")
+
+		vaultIDs, err := client.ListVaults("did:example:123456789")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal vault list")
+		require.Nil(t, vaultIDs)
+
+		err = srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	})
+	t.Run("Failure: server unreachable", func(t *testing.T) {
+		client := New("http://" + randomURL() + "/encrypted-data-vaults")
+
+		vaultIDs, err := client.ListVaults("did:example:123456789")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to send GET message")
+		require.Nil(t, vaultIDs)
+	})
+}
 func TestGetErrorReadFail(t *testing.T) {
 	badResp := http.Response{
 		Body: failingReadCloser{},
@@ -0,0 +1,34 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package edv
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcutil/base58"
+)
+
+const documentIDLengthInBytes = 16
+
+// GenerateDocumentID returns a new random document ID in the format required by the EDV specification:
+// a base58-encoded 128-bit value.
+func GenerateDocumentID() (string, error) {
+	return generateDocumentID(rand.Reader)
+}
+
+func generateDocumentID(randomSource io.Reader) (string, error) {
+	randomBytes := make([]byte, documentIDLengthInBytes)
+
+	_, err := io.ReadFull(randomSource, randomBytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate random document ID: %w", err)
+	}
+
+	return base58.Encode(randomBytes), nil
+}
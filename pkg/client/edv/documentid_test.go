@@ -0,0 +1,60 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package edv
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateDocumentID(t *testing.T) {
+	t.Run("Generated IDs are accepted by the EDV server", func(t *testing.T) {
+		srvAddr := randomURL()
+
+		srv := startEDVServer(t, srvAddr)
+
+		waitForServerToStart(t, srvAddr)
+
+		client := New("http://" + srvAddr + "/encrypted-data-vaults")
+
+		validConfig := getTestValidDataVaultConfiguration(false)
+
+		_, err := client.CreateDataVault(&validConfig)
+		require.NoError(t, err)
+
+		for i := 0; i < 10; i++ {
+			documentID, err := GenerateDocumentID()
+			require.NoError(t, err)
+			require.Len(t, base58.Decode(documentID), documentIDLengthInBytes)
+
+			document := getTestValidEncryptedDocument()
+			document.ID = documentID
+
+			_, err = client.CreateDocument(testVaultID, document)
+			require.NoError(t, err)
+		}
+
+		err = srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	})
+	t.Run("Leading zero bytes", func(t *testing.T) {
+		documentID, err := generateDocumentID(bytes.NewReader(make([]byte, documentIDLengthInBytes)))
+		require.NoError(t, err)
+		require.Equal(t, strings.Repeat("1", documentIDLengthInBytes), documentID)
+	})
+	t.Run("Random source fails", func(t *testing.T) {
+		documentID, err := generateDocumentID(bytes.NewReader(nil))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to generate random document ID")
+		require.Empty(t, documentID)
+	})
+}
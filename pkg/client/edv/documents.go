@@ -0,0 +1,135 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package edv
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+// DocumentIterator iterates over the documents in a vault, reading them from the EDV server a page at a time.
+// Call Next before reading each document, and check Err once Next returns false.
+type DocumentIterator struct {
+	client           *Client
+	vaultID          string
+	includeDocuments bool
+	pageSize         int
+	page             *models.DocumentList
+	position         int
+	err              error
+}
+
+// ListDocuments returns an iterator over the documents in the given vault, sorted by ID. If includeDocuments is true,
+// then the full documents are read along with their IDs. A page size of 0 uses the EDV server's default.
+// Documents created or deleted while iterating may or may not be seen.
+func (c *Client) ListDocuments(vaultID string, includeDocuments bool, pageSize int) *DocumentIterator {
+	return &DocumentIterator{client: c, vaultID: vaultID, includeDocuments: includeDocuments, pageSize: pageSize}
+}
+
+// Next moves to the next document, reading the next page from the EDV server if needed. It returns false once
+// there are no more documents or if reading a page failed.
+func (i *DocumentIterator) Next() bool {
+	if i.err != nil {
+		return false
+	}
+
+	if i.page != nil {
+		i.position++
+	}
+
+	// Pages can be empty even if there are more documents after them, so keep reading until a document is found.
+	for i.page == nil || i.position >= len(i.page.DocumentIDs) {
+		if i.page != nil && !i.page.HasMore {
+			return false
+		}
+
+		cursor := ""
+		if i.page != nil {
+			cursor = i.page.Cursor
+		}
+
+		i.page, i.err = i.client.readDocumentList(i.vaultID, cursor, i.pageSize, i.includeDocuments)
+		if i.err != nil {
+			return false
+		}
+
+		i.position = 0
+	}
+
+	return true
+}
+
+// ID returns the ID of the current document.
+func (i *DocumentIterator) ID() string {
+	return i.page.DocumentIDs[i.position]
+}
+
+// Document returns the current document, or nil if the iterator wasn't asked to include documents.
+func (i *DocumentIterator) Document() *models.EncryptedDocument {
+	if i.position >= len(i.page.Documents) {
+		return nil
+	}
+
+	return &i.page.Documents[i.position]
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (i *DocumentIterator) Err() error {
+	return i.err
+}
+
+func (c *Client) readDocumentList(vaultID, cursor string, limit int,
+	includeDocuments bool) (*models.DocumentList, error) {
+	query := url.Values{}
+
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	if includeDocuments {
+		query.Set("includeDocuments", "true")
+	}
+
+	// The linter falsely claims that the body is not being closed
+	// https://github.com/golangci/golangci-lint/issues/637
+	resp, err := c.httpClient.Get(fmt.Sprintf("%s/%s/documents?%s", //nolint: bodyclose
+		c.edvServerURL, url.PathEscape(vaultID), query.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to send GET message: %w", err)
+	}
+
+	defer closeReadCloser(resp.Body)
+
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response message while listing documents: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("the EDV server returned status code %d along with the following message: %s",
+			resp.StatusCode, respBytes)
+	}
+
+	list := models.DocumentList{}
+
+	err = json.Unmarshal(respBytes, &list)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal document list: %w", err)
+	}
+
+	return &list, nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package edv
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+func TestClient_ListDocuments(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		srvAddr := randomURL()
+
+		srv := startEDVServer(t, srvAddr)
+
+		waitForServerToStart(t, srvAddr)
+
+		client := New("http://" + srvAddr + "/encrypted-data-vaults")
+
+		_, err := client.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: testVaultIDWithSlashes})
+		require.NoError(t, err)
+
+		documentIDs := []string{testDocumentID, "WJYHHJx4C8J9Fsgz7rZqSp", "XJYHHJx4C8J9Fsgz7rZqSp"}
+
+		for _, docID := range documentIDs {
+			_, err = client.CreateDocument(testVaultIDWithSlashes,
+				&models.EncryptedDocument{ID: docID, JWE: []byte(testEncryptedDocJWE)})
+			require.NoError(t, err)
+		}
+
+		var listedIDs []string
+
+		documents := client.ListDocuments(testVaultIDWithSlashes, false, 2)
+		for documents.Next() {
+			listedIDs = append(listedIDs, documents.ID())
+			require.Nil(t, documents.Document())
+		}
+
+		require.NoError(t, documents.Err())
+		require.Equal(t, documentIDs, listedIDs)
+
+		documents = client.ListDocuments(testVaultIDWithSlashes, true, 0)
+		require.True(t, documents.Next())
+		require.Equal(t, testDocumentID, documents.ID())
+		require.Equal(t, testDocumentID, documents.Document().ID)
+
+		err = srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	})
+	t.Run("Success: empty pages are skipped", func(t *testing.T) {
+		pages := []string{
+			`{"documentIds":["docID1"],"cursor":"docID1","hasMore":true}`,
+			`{"documentIds":[],"cursor":"docID1_mapping_a","hasMore":true}`,
+			`{"documentIds":["docID2"],"cursor":"docID2","hasMore":false}`,
+		}
+
+		var cursors []string
+
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			require.Equal(t, "5", req.URL.Query().Get("limit"))
+
+			cursors = append(cursors, req.URL.Query().Get("cursor"))
+
+			_, err := rw.Write([]byte(pages[len(cursors)-1]))
+			require.NoError(t, err)
+		}))
+		defer srv.Close()
+
+		documents := New(srv.URL).ListDocuments(testVaultID, false, 5)
+
+		var listedIDs []string
+
+		for documents.Next() {
+			listedIDs = append(listedIDs, documents.ID())
+		}
+
+		require.NoError(t, documents.Err())
+		require.Equal(t, []string{"docID1", "docID2"}, listedIDs)
+		require.Equal(t, []string{"", "docID1", "docID1_mapping_a"}, cursors)
+		require.False(t, documents.Next())
+	})
+	t.Run("Failure: vault not found", func(t *testing.T) {
+		srvAddr := randomURL()
+
+		srv := startEDVServer(t, srvAddr)
+
+		waitForServerToStart(t, srvAddr)
+
+		documents := New("http://"+srvAddr+"/encrypted-data-vaults").ListDocuments(testVaultID, false, 0)
+		require.False(t, documents.Next())
+		require.EqualError(t, documents.Err(), "the EDV server returned status code "+
+			strconv.Itoa(http.StatusNotFound)+" along with the following message: Failed to list documents: "+
+			edverrors.ErrVaultNotFound.Error())
+		require.False(t, documents.Next())
+
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	})
+	t.Run("Failure: unable to unmarshal document list", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(mockFailQueryVaultHandler))
+		defer srv.Close()
+
+		documents := New(srv.URL).ListDocuments(testVaultID, true, 0)
+		require.False(t, documents.Next())
+		require.Error(t, documents.Err())
+		require.Contains(t, documents.Err().Error(), "failed to unmarshal document list")
+	})
+	t.Run("Failure: server unreachable", func(t *testing.T) {
+		documents := New("http://"+randomURL()+"/encrypted-data-vaults").ListDocuments(testVaultID, false, 0)
+		require.False(t, documents.Next())
+		require.Error(t, documents.Err())
+		require.Contains(t, documents.Err().Error(), "failed to send GET message")
+	})
+}
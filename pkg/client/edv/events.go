@@ -0,0 +1,194 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package edv
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+// DefaultEventReconnectDelay is how long the client waits before reconnecting to an event stream that ended,
+// if WithEventReconnectDelay isn't used.
+const DefaultEventReconnectDelay = time.Second
+
+// WithEventReconnectDelay sets how long the client waits before reconnecting to an event stream that ended.
+func WithEventReconnectDelay(delay time.Duration) Option {
+	return func(opts *Client) {
+		opts.eventReconnectDelay = delay
+	}
+}
+
+// eventStreamStatusError is returned when the EDV server responds to an event stream request with an error.
+type eventStreamStatusError struct {
+	statusCode int
+	message    []byte
+}
+
+func (e *eventStreamStatusError) Error() string {
+	return fmt.Sprintf("the EDV server returned status code %d along with the following message: %s",
+		e.statusCode, e.message)
+}
+
+// SubscribeEvents streams the changes made to the documents in the given vault. If lastEventID is blank, then only
+// changes made from now on are sent. Otherwise, it's the sequence of the last change received, and the changes made
+// after it are sent. If the stream ends, then the client reconnects and resumes from the last change received.
+// The returned channel is closed once the context is done, or if the EDV server rejects a reconnection.
+func (c *Client) SubscribeEvents(ctx context.Context, vaultID, lastEventID string) (<-chan models.DocumentChange,
+	error) {
+	resp, err := c.openEventStream(ctx, vaultID, lastEventID)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan models.DocumentChange)
+
+	go c.receiveEvents(ctx, vaultID, lastEventID, resp, events)
+
+	return events, nil
+}
+
+func (c *Client) openEventStream(ctx context.Context, vaultID, lastEventID string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("%s/%s/events", c.edvServerURL, url.PathEscape(vaultID)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event stream request: %w", err)
+	}
+
+	req.Header.Set("Accept", "text/event-stream")
+
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send GET message: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer closeReadCloser(resp.Body)
+
+		respBytes, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response message while opening event stream: %w", err)
+		}
+
+		return nil, &eventStreamStatusError{statusCode: resp.StatusCode, message: respBytes}
+	}
+
+	return resp, nil
+}
+
+// receiveEvents sends the events from the given stream to the events channel, reconnecting whenever the stream ends.
+func (c *Client) receiveEvents(ctx context.Context, vaultID, lastEventID string, resp *http.Response,
+	events chan<- models.DocumentChange) {
+	defer close(events)
+
+	for {
+		lastEventID = readEvents(ctx, resp.Body, lastEventID, events)
+
+		closeReadCloser(resp.Body)
+
+		resp = c.reconnectToEventStream(ctx, vaultID, lastEventID)
+		if resp == nil {
+			return
+		}
+	}
+}
+
+// reconnectToEventStream keeps trying to reconnect to an event stream until it succeeds, the context is done or the
+// EDV server rejects the request. It returns nil if it gives up.
+func (c *Client) reconnectToEventStream(ctx context.Context, vaultID, lastEventID string) *http.Response {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(c.eventReconnectDelay):
+		}
+
+		resp, err := c.openEventStream(ctx, vaultID, lastEventID)
+		if err == nil {
+			return resp
+		}
+
+		if statusErr, ok := err.(*eventStreamStatusError); ok && statusErr.statusCode < http.StatusInternalServerError {
+			log.Errorf("Event stream for vault %s was rejected: %s", vaultID, err.Error())
+
+			return nil
+		}
+
+		log.Warnf("Failed to reconnect to event stream for vault %s: %s", vaultID, err.Error())
+	}
+}
+
+// readEvents parses Server-Sent Events from the given stream until it ends and sends them to the events channel.
+// It returns the ID of the last event sent.
+func readEvents(ctx context.Context, stream io.Reader, lastEventID string,
+	events chan<- models.DocumentChange) string {
+	scanner := bufio.NewScanner(stream)
+
+	var eventID, data string
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if line != "" {
+			field, value := parseEventLine(line)
+
+			switch field {
+			case "id":
+				eventID = value
+			case "data":
+				data += value
+			}
+
+			continue
+		}
+
+		if data != "" {
+			change := models.DocumentChange{}
+
+			if err := json.Unmarshal([]byte(data), &change); err != nil {
+				log.Errorf("Failed to parse event: %s", err.Error())
+			} else {
+				select {
+				case events <- change:
+					lastEventID = eventID
+				case <-ctx.Done():
+					return lastEventID
+				}
+			}
+		}
+
+		eventID, data = "", ""
+	}
+
+	return lastEventID
+}
+
+// parseEventLine splits a line of an event stream into its field name and value.
+// Comment lines, which start with a colon, have a blank field name.
+func parseEventLine(line string) (string, string) {
+	parts := strings.SplitN(line, ":", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+
+	return parts[0], strings.TrimPrefix(parts[1], " ")
+}
@@ -0,0 +1,216 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package edv
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+func TestClient_SubscribeEvents(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		srvAddr := randomURL()
+
+		srv := startEDVServer(t, srvAddr)
+
+		waitForServerToStart(t, srvAddr)
+
+		client := New("http://" + srvAddr + "/encrypted-data-vaults")
+
+		_, err := client.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: testVaultIDWithSlashes})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		events, err := client.SubscribeEvents(ctx, testVaultIDWithSlashes, "")
+		require.NoError(t, err)
+
+		_, err = client.CreateDocument(testVaultIDWithSlashes,
+			&models.EncryptedDocument{ID: testDocumentID, JWE: []byte(testEncryptedDocJWE)})
+		require.NoError(t, err)
+
+		require.Equal(t, models.DocumentChange{ID: testDocumentID, Sequence: "1", Type: models.ChangeTypeCreated},
+			receiveEvent(t, events))
+
+		cancel()
+
+		requireEventsClosed(t, events)
+
+		err = srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	})
+	t.Run("Success: resume from the last event ID", func(t *testing.T) {
+		srvAddr := randomURL()
+
+		srv := startEDVServer(t, srvAddr)
+
+		waitForServerToStart(t, srvAddr)
+
+		client := New("http://" + srvAddr + "/encrypted-data-vaults")
+
+		_, err := client.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: testVaultID})
+		require.NoError(t, err)
+
+		secondDocumentID, err := GenerateDocumentID()
+		require.NoError(t, err)
+
+		for _, documentID := range []string{testDocumentID, secondDocumentID} {
+			_, err = client.CreateDocument(testVaultID,
+				&models.EncryptedDocument{ID: documentID, JWE: []byte(testEncryptedDocJWE)})
+			require.NoError(t, err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		events, err := client.SubscribeEvents(ctx, testVaultID, "1")
+		require.NoError(t, err)
+
+		require.Equal(t, secondDocumentID, receiveEvent(t, events).ID)
+
+		cancel()
+
+		requireEventsClosed(t, events)
+
+		err = srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	})
+	t.Run("Success: reconnect after the stream ends", func(t *testing.T) {
+		eventStreams := &mockEventStreams{}
+
+		srv := httptest.NewServer(eventStreams)
+		defer srv.Close()
+
+		client := New(srv.URL+"/encrypted-data-vaults", WithEventReconnectDelay(time.Millisecond))
+
+		events, err := client.SubscribeEvents(context.Background(), testVaultID, "")
+		require.NoError(t, err)
+
+		require.Equal(t, "1", receiveEvent(t, events).Sequence)
+		require.Equal(t, "2", receiveEvent(t, events).Sequence)
+
+		// The third connection is rejected, which closes the channel.
+		requireEventsClosed(t, events)
+
+		require.Equal(t, []string{"", "1", "2"}, eventStreams.lastEventIDs())
+	})
+	t.Run("Failure: vault doesn't exist", func(t *testing.T) {
+		srvAddr := randomURL()
+
+		srv := startEDVServer(t, srvAddr)
+
+		waitForServerToStart(t, srvAddr)
+
+		client := New("http://" + srvAddr + "/encrypted-data-vaults")
+
+		events, err := client.SubscribeEvents(context.Background(), testVaultID, "")
+		require.EqualError(t, err, "the EDV server returned status code "+strconv.Itoa(http.StatusNotFound)+
+			" along with the following message: Failed to stream events: "+edverrors.ErrVaultNotFound.Error())
+		require.Nil(t, events)
+
+		err = srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	})
+	t.Run("Failure: server unreachable", func(t *testing.T) {
+		client := New("http://" + randomURL() + "/encrypted-data-vaults")
+
+		events, err := client.SubscribeEvents(context.Background(), testVaultID, "")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to send GET message")
+		require.Nil(t, events)
+	})
+}
+
+func TestReadEvents(t *testing.T) {
+	stream := strings.NewReader(": heartbeat\n\n" +
+		"id: 1\nevent: created\ndata: {\"id\":\"doc1\",\"sequence\":\"1\",\"type\":\"created\"}\n\n" +
+		"id: 2\ndata: not JSON\n\n" +
+		"id: 3\nevent: updated\ndata:{\"id\":\"doc1\",\"sequence\":\"3\",\"type\":\"updated\"}\n\n")
+
+	events := make(chan models.DocumentChange, 3)
+
+	lastEventID := readEvents(context.Background(), stream, "0", events)
+	require.Equal(t, "3", lastEventID)
+
+	close(events)
+
+	var changes []models.DocumentChange
+
+	for change := range events {
+		changes = append(changes, change)
+	}
+
+	require.Equal(t, []models.DocumentChange{
+		{ID: "doc1", Sequence: "1", Type: models.ChangeTypeCreated},
+		{ID: "doc1", Sequence: "3", Type: models.ChangeTypeUpdated},
+	}, changes)
+}
+
+// mockEventStreams sends one event per connection, then rejects the third connection.
+type mockEventStreams struct {
+	mux     sync.Mutex
+	headers []string
+}
+
+func (m *mockEventStreams) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	m.mux.Lock()
+	m.headers = append(m.headers, req.Header.Get("Last-Event-ID"))
+	connection := len(m.headers)
+	m.mux.Unlock()
+
+	if connection > 2 { //nolint: gomnd
+		rw.WriteHeader(http.StatusNotFound)
+
+		return
+	}
+
+	_, err := fmt.Fprintf(rw, "id: %d\nevent: created\ndata: {\"id\":\"doc%d\",\"sequence\":\"%d\"}\n\n",
+		connection, connection, connection)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (m *mockEventStreams) lastEventIDs() []string {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	return m.headers
+}
+
+func receiveEvent(t *testing.T, events <-chan models.DocumentChange) models.DocumentChange {
+	select {
+	case change, ok := <-events:
+		require.True(t, ok, "events channel was closed")
+
+		return change
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timed out waiting for an event")
+	}
+
+	return models.DocumentChange{}
+}
+
+func requireEventsClosed(t *testing.T, events <-chan models.DocumentChange) {
+	select {
+	case _, ok := <-events:
+		require.False(t, ok, "received an unexpected event")
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "events channel wasn't closed")
+	}
+}
@@ -7,9 +7,17 @@ SPDX-License-Identifier: Apache-2.0
 package couchdbedvprovider
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	_ "github.com/go-kivik/couchdb" // The CouchDB driver
+	"github.com/go-kivik/kivik"
 	"github.com/google/uuid"
 	"github.com/trustbloc/edge-core/pkg/storage"
 	couchdbstore "github.com/trustbloc/edge-core/pkg/storage/couchdb"
@@ -19,11 +27,209 @@ import (
 	"github.com/trustbloc/edv/pkg/restapi/edv/models"
 )
 
-const mapDocumentIndexedField = "IndexName"
+const (
+	mapDocumentIndexedField = "IndexName"
+	mappingDocumentIDMarker = "_mapping_"
+
+	pingTimeout = 5 * time.Second
+)
 
 // ErrMissingDatabaseURL is returned when an attempt is made to instantiate a new CouchDBEDVProvider with a blank URL.
 var ErrMissingDatabaseURL = errors.New("couchDB database URL not set")
 
+// ErrCouchDBNotUp is returned by Ping when the CouchDB server responds, but reports that it isn't ready.
+var ErrCouchDBNotUp = errors.New("couchDB server is not up")
+
+// errNoDatabaseClient is returned by operations that need to talk to CouchDB directly when the store
+// wasn't opened with a CouchDB client.
+var errNoDatabaseClient = errors.New("store has no CouchDB database client")
+
+// errMissingBulkResult is returned for a write in a bulk write that CouchDB didn't report a result for.
+var errMissingBulkResult = errors.New("no result was returned for the document in the bulk write")
+
+// couchDBDatabase represents the operations on a CouchDB database that the edge-core CouchDB store doesn't expose.
+type couchDBDatabase interface {
+	Put(ctx context.Context, docID string, doc interface{}, options ...kivik.Options) (rev string, err error)
+	Changes(ctx context.Context, options ...kivik.Options) (couchDBChanges, error)
+	Revisions(ctx context.Context, docIDs []string) (map[string]string, error)
+	BulkDocs(ctx context.Context, docs []interface{}, options ...kivik.Options) (couchDBBulkResults, error)
+	AllDocs(ctx context.Context) ([]couchDBStoredDocument, error)
+	DocumentIDs(ctx context.Context, startKey string, limit int) ([]string, error)
+}
+
+// couchDBChanges is an iterator over a CouchDB changes feed.
+type couchDBChanges interface {
+	Next() bool
+	ID() string
+	Seq() string
+	Deleted() bool
+	Changes() []string
+	Pending() int64
+	LastSeq() string
+	Err() error
+	Close() error
+}
+
+// couchDBBulkResults is an iterator over the results of a CouchDB bulk write,
+// in the same order as the written documents.
+type couchDBBulkResults interface {
+	Next() bool
+	ID() string
+	UpdateErr() error
+	Err() error
+	Close() error
+}
+
+// kivikDatabase adapts a kivik database to the couchDBDatabase interface.
+type kivikDatabase struct {
+	*kivik.DB
+}
+
+// Changes returns an iterator over the database changes feed.
+func (k kivikDatabase) Changes(ctx context.Context, options ...kivik.Options) (couchDBChanges, error) {
+	changes, err := k.DB.Changes(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// Revisions returns the current revisions of the given documents, in a single request.
+// Documents that don't exist or were deleted are left out.
+func (k kivikDatabase) Revisions(ctx context.Context, docIDs []string) (map[string]string, error) {
+	rows, err := k.DB.AllDocs(ctx, kivik.Options{"keys": docIDs})
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make(map[string]string)
+
+	for rows.Next() {
+		// Missing documents have a row with an error and no ID.
+		if rows.ID() == "" {
+			continue
+		}
+
+		value := struct {
+			Rev     string `json:"rev"`
+			Deleted bool   `json:"deleted"`
+		}{}
+
+		err = rows.ScanValue(&value)
+		if err != nil {
+			return nil, err
+		}
+
+		if !value.Deleted {
+			revisions[rows.ID()] = value.Rev
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, rows.Close()
+}
+
+// BulkDocs writes the given documents with a single request to the _bulk_docs endpoint.
+func (k kivikDatabase) BulkDocs(ctx context.Context, docs []interface{},
+	options ...kivik.Options) (couchDBBulkResults, error) {
+	results, err := k.DB.BulkDocs(ctx, docs, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// AllDocs returns every document in the database, along with its current revision, in a single request.
+// Deleted documents are left out.
+func (k kivikDatabase) AllDocs(ctx context.Context) ([]couchDBStoredDocument, error) {
+	rows, err := k.DB.AllDocs(ctx, kivik.Options{"include_docs": true})
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []couchDBStoredDocument
+
+	for rows.Next() {
+		value := struct {
+			Rev string `json:"rev"`
+		}{}
+
+		err = rows.ScanValue(&value)
+		if err != nil {
+			return nil, err
+		}
+
+		doc := couchDBStoredDocument{ID: rows.ID(), Rev: value.Rev}
+
+		err = rows.ScanDoc(&doc.Doc)
+		if err != nil {
+			return nil, err
+		}
+
+		docs = append(docs, doc)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return docs, rows.Close()
+}
+
+// DocumentIDs returns up to limit IDs of the documents in the database, in the order CouchDB sorts them,
+// starting from startKey. Deleted documents are left out.
+func (k kivikDatabase) DocumentIDs(ctx context.Context, startKey string, limit int) ([]string, error) {
+	options := kivik.Options{"limit": limit}
+
+	if startKey != "" {
+		options["startkey"] = startKey
+	}
+
+	rows, err := k.DB.AllDocs(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+
+	for rows.Next() {
+		ids = append(ids, rows.ID())
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, rows.Close()
+}
+
+// couchDBStoredDocument is a document as stored in a CouchDB database, along with its current revision.
+type couchDBStoredDocument struct {
+	ID  string
+	Rev string
+	Doc json.RawMessage
+}
+
+// couchDBBulkDocument is an encrypted document as sent in a bulk write, which needs its CouchDB ID set,
+// along with the revision of the CouchDB document it replaces, if any.
+type couchDBBulkDocument struct {
+	models.EncryptedDocument
+	CouchDBID string `json:"_id"`
+	Rev       string `json:"_rev,omitempty"`
+}
+
+// couchDBDeletedDocument marks a CouchDB document as deleted.
+type couchDBDeletedDocument struct {
+	ID      string `json:"_id"`
+	Rev     string `json:"_rev"`
+	Deleted bool   `json:"_deleted"`
+}
+
 type couchDBIndexMappingDocument struct {
 	IndexName              string `json:"IndexName"`
 	MatchingEncryptedDocID string `json:"MatchingEncryptedDocID"`
@@ -32,7 +238,9 @@ type couchDBIndexMappingDocument struct {
 // CouchDBEDVProvider represents a CouchDB provider with functionality needed for EDV data storage.
 // It wraps an edge-core CouchDB provider with additional functionality that's needed for EDV operations.
 type CouchDBEDVProvider struct {
-	coreProvider storage.Provider
+	coreProvider  storage.Provider
+	couchDBClient *kivik.Client
+	dbPrefix      string
 }
 
 // NewProvider instantiates Provider
@@ -46,7 +254,14 @@ func NewProvider(databaseURL, dbPrefix string) (*CouchDBEDVProvider, error) {
 		return nil, err
 	}
 
-	return &CouchDBEDVProvider{coreProvider: couchDBProvider}, nil
+	// The edge-core provider doesn't expose everything the EDV needs (e.g. health checks),
+	// so a separate client is kept for talking to the CouchDB server directly.
+	couchDBClient, err := kivik.New("couch", databaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CouchDBEDVProvider{coreProvider: couchDBProvider, couchDBClient: couchDBClient, dbPrefix: dbPrefix}, nil
 }
 
 // CreateStore creates a new store with the given name.
@@ -61,19 +276,116 @@ func (c *CouchDBEDVProvider) OpenStore(name string) (edvprovider.EDVStore, error
 		return nil, err
 	}
 
-	return &CouchDBEDVStore{coreStore: coreStore}, nil
+	store := &CouchDBEDVStore{coreStore: coreStore}
+
+	if c.couchDBClient != nil {
+		store.db = kivikDatabase{DB: c.couchDBClient.DB(context.Background(), c.databaseName(name))}
+	}
+
+	return store, nil
+}
+
+// DeleteStore deletes the store with the given name by deleting its database on the CouchDB server.
+func (c *CouchDBEDVProvider) DeleteStore(name string) error {
+	// The edge-core provider caches the stores it has opened, so the store is closed to drop it from the cache.
+	err := c.coreProvider.CloseStore(name)
+	if err != nil && err != storage.ErrStoreNotFound {
+		return err
+	}
+
+	err = c.couchDBClient.DestroyDB(context.Background(), c.databaseName(name))
+	if kivik.StatusCode(err) == http.StatusNotFound {
+		return storage.ErrStoreNotFound
+	}
+
+	return err
+}
+
+// StoreNames returns the names of the stores whose databases are on the CouchDB server, sorted.
+// Only databases with the provider's prefix are included, and CouchDB's own databases are left out.
+func (c *CouchDBEDVProvider) StoreNames() ([]string, error) {
+	databases, err := c.couchDBClient.AllDBs(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list databases: %w", err)
+	}
+
+	var names []string
+
+	for _, database := range databases {
+		name := database
+
+		if c.dbPrefix != "" {
+			if !strings.HasPrefix(database, c.dbPrefix+"_") {
+				continue
+			}
+
+			name = strings.TrimPrefix(database, c.dbPrefix+"_")
+		}
+
+		if name == "" || strings.HasPrefix(database, "_") {
+			continue
+		}
+
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// databaseName returns the name of the CouchDB database that backs the store with the given name.
+// This matches the naming used by the edge-core CouchDB provider.
+func (c *CouchDBEDVProvider) databaseName(storeName string) string {
+	if c.dbPrefix == "" {
+		return storeName
+	}
+
+	return c.dbPrefix + "_" + storeName
+}
+
+// Ping checks whether the CouchDB server is reachable and up.
+func (c *CouchDBEDVProvider) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+
+	up, err := c.couchDBClient.Ping(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !up {
+		return ErrCouchDBNotUp
+	}
+
+	return nil
+}
+
+// Close closes the provider and all of its stores.
+func (c *CouchDBEDVProvider) Close() error {
+	err := c.coreProvider.Close()
+	if err != nil {
+		return err
+	}
+
+	if c.couchDBClient == nil {
+		return nil
+	}
+
+	return c.couchDBClient.Close(context.Background())
 }
 
 // CouchDBEDVStore represents a CouchDB store with functionality needed for EDV data storage.
 // It wraps an edge-core CouchDB store with additional functionality that's needed for EDV operations.
 type CouchDBEDVStore struct {
 	coreStore storage.Store
+	db        couchDBDatabase
 }
 
 // Put stores the given document.
 // A mapping document is also created and stored in order to allow for encrypted indices to work.
 func (c *CouchDBEDVStore) Put(document models.EncryptedDocument) error {
-	err := c.validateNewDoc(document)
+	err := c.validateNewDoc(document, "")
 	if err != nil {
 		return err
 	}
@@ -87,18 +399,170 @@ func (c *CouchDBEDVStore) Put(document models.EncryptedDocument) error {
 	// If either of these requests fails, then the database will be left in a weird state.
 	// https://github.com/trustbloc/edge-core/issues/27 and https://github.com/trustbloc/edv/issues/49
 
-	for _, indexedAttributeCollection := range document.IndexedAttributeCollections {
-		for _, indexedAttribute := range indexedAttributeCollection.IndexedAttributes {
-			err := c.createMappingDocument(indexedAttribute.Name, document.ID)
-			if err != nil {
-				return err
-			}
-		}
+	err = c.createMappingDocuments(document)
+	if err != nil {
+		return err
 	}
 
 	return c.coreStore.Put(document.ID, documentBytes)
 }
 
+// Create stores the given document only if there isn't already a document with the same ID.
+// The document is written without a revision, so CouchDB rejects it with a conflict if the ID is already in use.
+// Mapping documents for encrypted indices are only created once the document itself has been stored.
+func (c *CouchDBEDVStore) Create(document models.EncryptedDocument) error {
+	if c.db == nil {
+		return errNoDatabaseClient
+	}
+
+	err := c.validateNewDoc(document, "")
+	if err != nil {
+		return err
+	}
+
+	documentBytes, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.db.Put(context.Background(), document.ID, json.RawMessage(documentBytes))
+	if err != nil {
+		if kivik.StatusCode(err) == http.StatusConflict {
+			return edverrors.ErrDuplicateDocument
+		}
+
+		return err
+	}
+
+	return c.createMappingDocuments(document)
+}
+
+// BulkWrite applies the given writes with a single request to the CouchDB _bulk_docs endpoint, after looking up
+// the current revisions of the documents with a single request to _all_docs. Writes that can be rejected up front
+// (e.g. creating a document that already exists) aren't sent. Mapping documents for encrypted indices are created
+// once the documents themselves have been stored. The mapping documents of deleted documents are left in place
+// and skipped by queries.
+// A document should only be written once per bulk write: CouchDB rejects any further writes to it with a conflict.
+func (c *CouchDBEDVStore) BulkWrite(writes []edvprovider.DocumentWrite) ([]error, error) {
+	if c.db == nil {
+		return nil, errNoDatabaseClient
+	}
+
+	docIDs := make([]string, len(writes))
+
+	for i, write := range writes {
+		docIDs[i] = write.Document.ID
+	}
+
+	revisions, err := c.db.Revisions(context.Background(), docIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	writeErrs := make([]error, len(writes))
+
+	var (
+		docs         []interface{}
+		writeIndexes []int
+	)
+
+	for i, write := range writes {
+		doc, prepareErr := c.prepareWrite(write, revisions)
+		if prepareErr != nil {
+			writeErrs[i] = prepareErr
+
+			continue
+		}
+
+		docs = append(docs, doc)
+		writeIndexes = append(writeIndexes, i)
+	}
+
+	if len(docs) == 0 {
+		return writeErrs, nil
+	}
+
+	results, err := c.db.BulkDocs(context.Background(), docs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.applyBulkResults(results, writes, writeIndexes, writeErrs)
+	if err != nil {
+		return nil, err
+	}
+
+	return writeErrs, nil
+}
+
+// prepareWrite returns the CouchDB document to send for the given write, given the current document revisions.
+func (c *CouchDBEDVStore) prepareWrite(write edvprovider.DocumentWrite, revisions map[string]string) (interface{},
+	error) {
+	rev, exists := revisions[write.Document.ID]
+
+	switch write.Type {
+	case edvprovider.WriteCreate:
+		if exists {
+			return nil, edverrors.ErrDuplicateDocument
+		}
+
+		err := c.validateNewDoc(write.Document, "")
+		if err != nil {
+			return nil, err
+		}
+
+		return couchDBBulkDocument{EncryptedDocument: write.Document, CouchDBID: write.Document.ID}, nil
+	case edvprovider.WriteUpdate:
+		if !exists {
+			return nil, edverrors.ErrDocumentNotFound
+		}
+
+		err := c.validateNewDoc(write.Document, write.Document.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		return couchDBBulkDocument{EncryptedDocument: write.Document, CouchDBID: write.Document.ID, Rev: rev}, nil
+	case edvprovider.WriteDelete:
+		if !exists {
+			return nil, edverrors.ErrDocumentNotFound
+		}
+
+		return couchDBDeletedDocument{ID: write.Document.ID, Rev: rev, Deleted: true}, nil
+	default:
+		return nil, fmt.Errorf("unsupported document write type: %s", write.Type)
+	}
+}
+
+// applyBulkResults records the outcome of each write that was sent, and creates the mapping documents
+// of the documents that were stored.
+func (c *CouchDBEDVStore) applyBulkResults(results couchDBBulkResults, writes []edvprovider.DocumentWrite,
+	writeIndexes []int, writeErrs []error) error {
+	for _, i := range writeIndexes {
+		if !results.Next() {
+			writeErrs[i] = errMissingBulkResult
+
+			continue
+		}
+
+		if updateErr := results.UpdateErr(); updateErr != nil {
+			writeErrs[i] = bulkWriteError(writes[i].Type, updateErr)
+
+			continue
+		}
+
+		if writes[i].Type != edvprovider.WriteDelete {
+			writeErrs[i] = c.createMappingDocuments(writes[i].Document)
+		}
+	}
+
+	if err := results.Err(); err != nil {
+		return err
+	}
+
+	return results.Close()
+}
+
 // Get fetches the document associated with the given key.
 func (c *CouchDBEDVStore) Get(k string) ([]byte, error) {
 	return c.coreStore.Get(k)
@@ -127,11 +591,66 @@ func (c *CouchDBEDVStore) Query(query *models.Query) ([]string, error) {
 	return c.filterDocsByQuery(idsOfDocsWithMatchingQueryIndexName, query)
 }
 
+// Changes returns up to limit changes made to the store's documents after the given cursor, oldest first.
+// The changes are read from the CouchDB changes feed, so cursors are CouchDB update sequences.
+// Mapping documents and design documents are left out, but the cursor still moves past them.
+func (c *CouchDBEDVStore) Changes(since string, limit int) (*models.ChangeFeed, error) {
+	if c.db == nil {
+		return nil, errNoDatabaseClient
+	}
+
+	options := kivik.Options{"limit": limit}
+
+	if since != "" {
+		options["since"] = since
+	}
+
+	changes, err := c.db.Changes(context.Background(), options)
+	if err != nil {
+		return nil, changesError(err)
+	}
+
+	feed := &models.ChangeFeed{Changes: []models.DocumentChange{}, Cursor: since}
+
+	for changes.Next() {
+		feed.Cursor = changes.Seq()
+
+		if !isEncryptedDocumentID(changes.ID()) {
+			continue
+		}
+
+		feed.Changes = append(feed.Changes, models.DocumentChange{
+			ID:       changes.ID(),
+			Sequence: changes.Seq(),
+			Type:     changeType(changes),
+		})
+	}
+
+	if err = changes.Err(); err != nil {
+		return nil, changesError(err)
+	}
+
+	if lastSeq := changes.LastSeq(); lastSeq != "" {
+		feed.Cursor = lastSeq
+	}
+
+	feed.HasMore = changes.Pending() > 0
+
+	err = changes.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return feed, nil
+}
+
 // validateNewDoc tries to ensure that index name+pairs declared unique are maintained as such. Note that
 // this cannot be guaranteed due to the nature of concurrent requests and CouchDB's eventual consistency model.
-func (c *CouchDBEDVStore) validateNewDoc(newDoc models.EncryptedDocument) error {
+// If the new document replaces an existing one, then replacedDocID is the ID of the document being replaced,
+// which isn't checked against.
+func (c *CouchDBEDVStore) validateNewDoc(newDoc models.EncryptedDocument, replacedDocID string) error {
 	for _, newAttributeCollection := range newDoc.IndexedAttributeCollections {
-		err := c.validateNewAttributeCollection(newAttributeCollection)
+		err := c.validateNewAttributeCollection(newAttributeCollection, replacedDocID)
 		if err != nil {
 			return err
 		}
@@ -141,9 +660,9 @@ func (c *CouchDBEDVStore) validateNewDoc(newDoc models.EncryptedDocument) error
 }
 
 func (c *CouchDBEDVStore) validateNewAttributeCollection(
-	newAttributeCollection models.IndexedAttributeCollection) error {
+	newAttributeCollection models.IndexedAttributeCollection, replacedDocID string) error {
 	for _, newAttribute := range newAttributeCollection.IndexedAttributes {
-		err := c.validateNewAttribute(newAttribute)
+		err := c.validateNewAttribute(newAttribute, replacedDocID)
 		if err != nil {
 			return err
 		}
@@ -152,7 +671,7 @@ func (c *CouchDBEDVStore) validateNewAttributeCollection(
 	return nil
 }
 
-func (c *CouchDBEDVStore) validateNewAttribute(newAttribute models.IndexedAttribute) error {
+func (c *CouchDBEDVStore) validateNewAttribute(newAttribute models.IndexedAttribute, replacedDocID string) error {
 	query := models.Query{
 		Name:  newAttribute.Name,
 		Value: newAttribute.Value,
@@ -163,7 +682,7 @@ func (c *CouchDBEDVStore) validateNewAttribute(newAttribute models.IndexedAttrib
 		return err
 	}
 
-	err = c.validateNewAttributeAgainstDocs(existingDocIDs, newAttribute)
+	err = c.validateNewAttributeAgainstDocs(existingDocIDs, newAttribute, replacedDocID)
 	if err != nil {
 		return err
 	}
@@ -171,8 +690,13 @@ func (c *CouchDBEDVStore) validateNewAttribute(newAttribute models.IndexedAttrib
 	return nil
 }
 
-func (c *CouchDBEDVStore) validateNewAttributeAgainstDocs(docIDs []string, newAttribute models.IndexedAttribute) error {
+func (c *CouchDBEDVStore) validateNewAttributeAgainstDocs(docIDs []string, newAttribute models.IndexedAttribute,
+	replacedDocID string) error {
 	for _, docID := range docIDs {
+		if docID == replacedDocID {
+			continue
+		}
+
 		err := c.validateNewAttributeAgainstDoc(newAttribute, docID)
 		if err != nil {
 			return err
@@ -245,6 +769,20 @@ func validateNewAttributeAgainstAttribute(newAttribute, attribute models.Indexed
 	return nil
 }
 
+// createMappingDocuments creates a mapping document for each of the given document's indexed attributes.
+func (c *CouchDBEDVStore) createMappingDocuments(document models.EncryptedDocument) error {
+	for _, indexedAttributeCollection := range document.IndexedAttributeCollections {
+		for _, indexedAttribute := range indexedAttributeCollection.IndexedAttributes {
+			err := c.createMappingDocument(indexedAttribute.Name, document.ID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 // createMappingDocument creates a document with a mapping of the encrypted index to the document that has it.
 func (c *CouchDBEDVStore) createMappingDocument(indexedAttributeName, encryptedDocID string) error {
 	mapDocument := couchDBIndexMappingDocument{
@@ -257,7 +795,7 @@ func (c *CouchDBEDVStore) createMappingDocument(indexedAttributeName, encryptedD
 		return err
 	}
 
-	return c.coreStore.Put(encryptedDocID+"_mapping_"+uuid.New().String(), documentBytes)
+	return c.coreStore.Put(encryptedDocID+mappingDocumentIDMarker+uuid.New().String(), documentBytes)
 }
 
 func (c *CouchDBEDVStore) findDocsMatchingQueryIndexName(queryIndexName string) (map[string]struct{}, error) {
@@ -308,16 +846,17 @@ func (c *CouchDBEDVStore) findDocsMatchingQueryIndexName(queryIndexName string)
 }
 
 // Given a set of documents, returns the document IDs that satisfy the query.
+// Documents that no longer exist, since they were deleted after their mapping documents were created, are skipped.
 func (c *CouchDBEDVStore) filterDocsByQuery(docIDs map[string]struct{}, query *models.Query) ([]string, error) {
 	matchingDocIDs := make([]string, 0)
 
 	for docID := range docIDs {
 		documentBytes, err := c.coreStore.Get(docID)
-		if err != nil {
-			if err == storage.ErrValueNotFound {
-				return nil, edverrors.ErrDocumentNotFound
-			}
+		if err == storage.ErrValueNotFound {
+			continue
+		}
 
+		if err != nil {
 			return nil, err
 		}
 
@@ -357,3 +896,88 @@ func attributeCollectionSatisfiesQuery(attrCollection models.IndexedAttributeCol
 
 	return false
 }
+
+// ListDocuments returns up to limit IDs of the store's documents, sorted, starting after the given cursor.
+// Mapping documents and design documents are left out, so a page can have fewer than limit IDs even if there are
+// more documents after it. The cursor is the ID of the last document CouchDB returned, which may be a mapping
+// document's.
+func (c *CouchDBEDVStore) ListDocuments(cursor string, limit int) (*models.DocumentList, error) {
+	if c.db == nil {
+		return nil, errNoDatabaseClient
+	}
+
+	// One more ID than needed is read to tell whether there are more, along with the cursor itself,
+	// which comes first unless it was deleted since.
+	readLimit := limit + 1
+	if cursor != "" {
+		readLimit++
+	}
+
+	ids, err := c.db.DocumentIDs(context.Background(), cursor, readLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+
+	if len(ids) > 0 && cursor != "" && ids[0] == cursor {
+		ids = ids[1:]
+	}
+
+	list := &models.DocumentList{DocumentIDs: []string{}, Cursor: cursor}
+
+	for i, id := range ids {
+		if i == limit {
+			list.HasMore = true
+
+			break
+		}
+
+		list.Cursor = id
+
+		if isEncryptedDocumentID(id) {
+			list.DocumentIDs = append(list.DocumentIDs, id)
+		}
+	}
+
+	return list, nil
+}
+
+// isEncryptedDocumentID returns whether the given ID belongs to an encrypted document,
+// as opposed to a mapping document or a design document.
+func isEncryptedDocumentID(id string) bool {
+	return !strings.HasPrefix(id, "_") && !strings.Contains(id, mappingDocumentIDMarker)
+}
+
+// changeType returns the type of the current change in the given changes feed.
+// A document's first revision always starts with "1-", so any other revision means the document was updated.
+func changeType(changes couchDBChanges) string {
+	if changes.Deleted() {
+		return models.ChangeTypeDeleted
+	}
+
+	revisions := changes.Changes()
+	if len(revisions) > 0 && strings.HasPrefix(revisions[0], "1-") {
+		return models.ChangeTypeCreated
+	}
+
+	return models.ChangeTypeUpdated
+}
+
+// bulkWriteError converts the error CouchDB gave for a document in a bulk write. A conflict when creating a document
+// means that it already exists.
+func bulkWriteError(writeType string, err error) error {
+	if writeType == edvprovider.WriteCreate && kivik.StatusCode(err) == http.StatusConflict {
+		return edverrors.ErrDuplicateDocument
+	}
+
+	return err
+}
+
+// changesError converts an error from the CouchDB changes feed. CouchDB rejects a malformed since parameter
+// with a bad request status.
+func changesError(err error) error {
+	if kivik.StatusCode(err) == http.StatusBadRequest {
+		return edverrors.ErrInvalidChangeCursor
+	}
+
+	return err
+}
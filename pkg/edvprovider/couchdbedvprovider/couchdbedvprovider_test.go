@@ -7,15 +7,21 @@ SPDX-License-Identifier: Apache-2.0
 package couchdbedvprovider
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/go-kivik/kivik"
 	"github.com/stretchr/testify/require"
 	"github.com/trustbloc/edge-core/pkg/storage"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
 	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
 
 	"github.com/trustbloc/edv/pkg/edvprovider"
+	"github.com/trustbloc/edv/pkg/replication"
 	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
 	"github.com/trustbloc/edv/pkg/restapi/edv/models"
 )
@@ -161,6 +167,133 @@ func TestCouchDBEDVProvider_OpenStore(t *testing.T) {
 	})
 }
 
+func TestCouchDBEDVProvider_StoreNames(t *testing.T) {
+	couchDBServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		require.Equal(t, "/_all_dbs", req.URL.Path)
+
+		_, err := rw.Write([]byte(`["_replicator","_users","edv_vault2","edv_vault1","edv_","other_vault3"]`))
+		require.NoError(t, err)
+	}))
+	defer couchDBServer.Close()
+
+	t.Run("With a prefix", func(t *testing.T) {
+		prov, err := NewProvider(couchDBServer.URL, "edv")
+		require.NoError(t, err)
+
+		names, err := prov.StoreNames()
+		require.NoError(t, err)
+		require.Equal(t, []string{"vault1", "vault2"}, names)
+	})
+	t.Run("Without a prefix", func(t *testing.T) {
+		prov, err := NewProvider(couchDBServer.URL, "")
+		require.NoError(t, err)
+
+		names, err := prov.StoreNames()
+		require.NoError(t, err)
+		require.Equal(t, []string{"edv_", "edv_vault1", "edv_vault2", "other_vault3"}, names)
+	})
+	t.Run("Failure: CouchDB server unreachable", func(t *testing.T) {
+		unreachableServer := httptest.NewServer(http.NotFoundHandler())
+		unreachableServer.Close()
+
+		prov, err := NewProvider(unreachableServer.URL, "")
+		require.NoError(t, err)
+
+		names, err := prov.StoreNames()
+		require.Nil(t, names)
+		require.Contains(t, err.Error(), "failed to list databases")
+	})
+}
+
+func TestCouchDBEDVProvider_DeleteStore(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		couchDBServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			require.Equal(t, http.MethodDelete, req.Method)
+			require.Equal(t, "/edv_vault1", req.URL.Path)
+
+			_, err := rw.Write([]byte(`{"ok":true}`))
+			require.NoError(t, err)
+		}))
+		defer couchDBServer.Close()
+
+		prov, err := NewProvider(couchDBServer.URL, "edv")
+		require.NoError(t, err)
+
+		require.NoError(t, prov.DeleteStore("vault1"))
+	})
+	t.Run("Failure: store not found", func(t *testing.T) {
+		couchDBServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusNotFound)
+
+			_, err := rw.Write([]byte(`{"error":"not_found","reason":"Database does not exist."}`))
+			require.NoError(t, err)
+		}))
+		defer couchDBServer.Close()
+
+		prov, err := NewProvider(couchDBServer.URL, "edv")
+		require.NoError(t, err)
+
+		require.Equal(t, storage.ErrStoreNotFound, prov.DeleteStore("vault1"))
+	})
+	t.Run("Failure: CouchDB server unreachable", func(t *testing.T) {
+		unreachableServer := httptest.NewServer(http.NotFoundHandler())
+		unreachableServer.Close()
+
+		prov, err := NewProvider(unreachableServer.URL, "")
+		require.NoError(t, err)
+
+		err = prov.DeleteStore("vault1")
+		require.Error(t, err)
+		require.NotEqual(t, storage.ErrStoreNotFound, err)
+	})
+}
+
+func TestCouchDBEDVProvider_Ping(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		couchDBServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			require.Equal(t, "/_up", req.URL.Path)
+			rw.WriteHeader(http.StatusOK)
+		}))
+		defer couchDBServer.Close()
+
+		prov, err := NewProvider(couchDBServer.URL, "")
+		require.NoError(t, err)
+
+		err = prov.Ping()
+		require.NoError(t, err)
+	})
+	t.Run("Failure: CouchDB server not up", func(t *testing.T) {
+		couchDBServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusNotFound)
+		}))
+		defer couchDBServer.Close()
+
+		prov, err := NewProvider(couchDBServer.URL, "")
+		require.NoError(t, err)
+
+		err = prov.Ping()
+		require.Equal(t, ErrCouchDBNotUp, err)
+	})
+	t.Run("Failure: CouchDB server unreachable", func(t *testing.T) {
+		couchDBServer := httptest.NewServer(http.NotFoundHandler())
+		couchDBServer.Close()
+
+		prov, err := NewProvider(couchDBServer.URL, "")
+		require.NoError(t, err)
+
+		err = prov.Ping()
+		require.Error(t, err)
+		require.NotEqual(t, ErrCouchDBNotUp, err)
+	})
+}
+
+func TestCouchDBEDVProvider_Close(t *testing.T) {
+	prov := CouchDBEDVProvider{coreProvider: mockstore.NewMockStoreProvider()}
+
+	err := prov.Close()
+	require.NoError(t, err)
+}
+
 func TestCouchDBEDVStore_Put(t *testing.T) {
 	t.Run("Success - no new encrypted indices", func(t *testing.T) {
 		mockCoreStore := mockstore.MockStore{Store: make(map[string][]byte)}
@@ -261,6 +394,594 @@ func TestCouchDBEDVStore_createMappingDocument(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestCouchDBEDVStore_Create(t *testing.T) {
+	var testDoc models.EncryptedDocument
+
+	require.NoError(t, json.Unmarshal([]byte(testEncryptedDoc), &testDoc))
+
+	t.Run("Success - mapping documents are created", func(t *testing.T) {
+		mockCoreStore := mockstore.MockStore{Store: make(map[string][]byte), ResultsIteratorToReturn: &mockIterator{}}
+		db := &mockCouchDBDatabase{}
+		store := CouchDBEDVStore{coreStore: &mockCoreStore, db: db}
+
+		err := store.Create(testDoc)
+		require.NoError(t, err)
+		require.Equal(t, []string{testDocID1}, db.putDocIDs)
+		require.Len(t, mockCoreStore.Store, 2)
+	})
+	t.Run("Failure - document already exists", func(t *testing.T) {
+		mockCoreStore := mockstore.MockStore{Store: make(map[string][]byte), ResultsIteratorToReturn: &mockIterator{}}
+		store := CouchDBEDVStore{coreStore: &mockCoreStore,
+			db: &mockCouchDBDatabase{errPut: &mockStatusCodeError{statusCode: http.StatusConflict}}}
+
+		err := store.Create(testDoc)
+		require.Equal(t, edverrors.ErrDuplicateDocument, err)
+		require.Empty(t, mockCoreStore.Store)
+	})
+	t.Run("Failure - other database error", func(t *testing.T) {
+		store := CouchDBEDVStore{
+			coreStore: &mockstore.MockStore{Store: make(map[string][]byte), ResultsIteratorToReturn: &mockIterator{}},
+			db:        &mockCouchDBDatabase{errPut: errors.New("put error")}}
+
+		err := store.Create(testDoc)
+		require.EqualError(t, err, "put error")
+	})
+	t.Run("Failure - error while validating encrypted indices", func(t *testing.T) {
+		db := &mockCouchDBDatabase{}
+		store := CouchDBEDVStore{
+			coreStore: &mockstore.MockStore{Store: make(map[string][]byte), ErrQuery: errors.New("query error")}, db: db}
+
+		err := store.Create(testDoc)
+		require.EqualError(t, err, "query error")
+		require.Empty(t, db.putDocIDs)
+	})
+	t.Run("Failure - no database client", func(t *testing.T) {
+		store := CouchDBEDVStore{coreStore: &mockstore.MockStore{Store: make(map[string][]byte)}}
+
+		err := store.Create(testDoc)
+		require.Equal(t, errNoDatabaseClient, err)
+	})
+}
+
+func TestCouchDBEDVStore_BulkWrite(t *testing.T) {
+	var testDoc, testDoc2 models.EncryptedDocument
+
+	require.NoError(t, json.Unmarshal([]byte(testEncryptedDoc), &testDoc))
+	require.NoError(t, json.Unmarshal([]byte(testEncryptedDoc2), &testDoc2))
+
+	t.Run("Success - writes are sent in one request", func(t *testing.T) {
+		mockCoreStore := mockstore.MockStore{Store: make(map[string][]byte), ResultsIteratorToReturn: &mockIterator{}}
+		db := &mockCouchDBDatabase{revisions: map[string]string{testDocID2: "1-a", "deletedDoc": "2-b"}}
+		store := CouchDBEDVStore{coreStore: &mockCoreStore, db: db}
+
+		writeErrs, err := store.BulkWrite([]edvprovider.DocumentWrite{
+			{Type: edvprovider.WriteCreate, Document: testDoc},
+			{Type: edvprovider.WriteUpdate, Document: testDoc2},
+			{Type: edvprovider.WriteDelete, Document: models.EncryptedDocument{ID: "deletedDoc"}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []error{nil, nil, nil}, writeErrs)
+
+		docsBytes, err := json.Marshal(db.bulkDocs)
+		require.NoError(t, err)
+
+		var docs []map[string]interface{}
+
+		require.NoError(t, json.Unmarshal(docsBytes, &docs))
+		require.Len(t, docs, 3)
+		require.Equal(t, testDocID1, docs[0]["_id"])
+		require.Equal(t, testDocID1, docs[0]["id"])
+		require.NotContains(t, docs[0], "_rev")
+		require.Equal(t, testDocID2, docs[1]["_id"])
+		require.Equal(t, "1-a", docs[1]["_rev"])
+		require.Equal(t, map[string]interface{}{"_id": "deletedDoc", "_rev": "2-b", "_deleted": true}, docs[2])
+
+		// One mapping document per indexed attribute of the created and updated documents.
+		require.Len(t, mockCoreStore.Store, 5)
+	})
+	t.Run("Writes that can't be applied are rejected individually", func(t *testing.T) {
+		mockCoreStore := mockstore.MockStore{Store: make(map[string][]byte), ResultsIteratorToReturn: &mockIterator{}}
+		db := &mockCouchDBDatabase{
+			revisions: map[string]string{testDocID1: "1-a"},
+			bulkResults: &mockBulkResults{updateErrs: []error{
+				&mockStatusCodeError{statusCode: http.StatusConflict},
+				&mockStatusCodeError{statusCode: http.StatusConflict},
+			}},
+		}
+		store := CouchDBEDVStore{coreStore: &mockCoreStore, db: db}
+
+		writeErrs, err := store.BulkWrite([]edvprovider.DocumentWrite{
+			{Type: edvprovider.WriteCreate, Document: testDoc},
+			{Type: edvprovider.WriteUpdate, Document: testDoc2},
+			{Type: edvprovider.WriteDelete, Document: testDoc2},
+			{Type: "replace", Document: testDoc},
+			{Type: edvprovider.WriteCreate, Document: testDoc2},
+			{Type: edvprovider.WriteUpdate, Document: testDoc},
+			{Type: edvprovider.WriteCreate, Document: models.EncryptedDocument{ID: "notReported"}},
+		})
+		require.NoError(t, err)
+		require.Len(t, writeErrs, 7)
+		require.Equal(t, edverrors.ErrDuplicateDocument, writeErrs[0])
+		require.Equal(t, edverrors.ErrDocumentNotFound, writeErrs[1])
+		require.Equal(t, edverrors.ErrDocumentNotFound, writeErrs[2])
+		require.EqualError(t, writeErrs[3], "unsupported document write type: replace")
+		require.Equal(t, edverrors.ErrDuplicateDocument, writeErrs[4])
+		require.EqualError(t, writeErrs[5], http.StatusText(http.StatusConflict))
+		require.Equal(t, errMissingBulkResult, writeErrs[6])
+		require.Len(t, db.bulkDocs, 3)
+	})
+	t.Run("Nothing to send", func(t *testing.T) {
+		db := &mockCouchDBDatabase{revisions: map[string]string{}}
+		store := CouchDBEDVStore{coreStore: &mockstore.MockStore{Store: make(map[string][]byte)}, db: db}
+
+		writeErrs, err := store.BulkWrite([]edvprovider.DocumentWrite{
+			{Type: edvprovider.WriteDelete, Document: testDoc},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []error{edverrors.ErrDocumentNotFound}, writeErrs)
+		require.Nil(t, db.bulkDocs)
+	})
+	t.Run("Updates aren't checked against the document they replace", func(t *testing.T) {
+		mappingDocBytes, err := json.Marshal(couchDBIndexMappingDocument{
+			IndexName:              "CUQaxPtSLtd8L3WBAIkJ4DiVJeqoF6bdnhR7lSaPloZ",
+			MatchingEncryptedDocID: testDocID1,
+		})
+		require.NoError(t, err)
+
+		mockCoreStore := mockstore.MockStore{Store: make(map[string][]byte),
+			ResultsIteratorToReturn: &mockIterator{maxTimesNextCanBeCalled: 1, valueReturn: mappingDocBytes}}
+		require.NoError(t, mockCoreStore.Put(testDocID1, []byte(testEncryptedDoc)))
+
+		db := &mockCouchDBDatabase{revisions: map[string]string{testDocID1: "1-a"}}
+		store := CouchDBEDVStore{coreStore: &mockCoreStore, db: db}
+
+		writeErrs, err := store.BulkWrite([]edvprovider.DocumentWrite{{Type: edvprovider.WriteUpdate, Document: testDoc}})
+		require.NoError(t, err)
+		require.Equal(t, []error{nil}, writeErrs)
+	})
+	t.Run("Failure - validating encrypted indices", func(t *testing.T) {
+		db := &mockCouchDBDatabase{revisions: map[string]string{testDocID2: "1-a"}}
+		store := CouchDBEDVStore{
+			coreStore: &mockstore.MockStore{Store: make(map[string][]byte), ErrQuery: errors.New("query error")}, db: db}
+
+		writeErrs, err := store.BulkWrite([]edvprovider.DocumentWrite{
+			{Type: edvprovider.WriteCreate, Document: testDoc},
+			{Type: edvprovider.WriteUpdate, Document: testDoc2},
+		})
+		require.NoError(t, err)
+		require.Len(t, writeErrs, 2)
+		require.EqualError(t, writeErrs[0], "query error")
+		require.EqualError(t, writeErrs[1], "query error")
+	})
+	t.Run("Failure - creating mapping documents", func(t *testing.T) {
+		db := &mockCouchDBDatabase{revisions: map[string]string{}}
+		store := CouchDBEDVStore{coreStore: &mockstore.MockStore{Store: make(map[string][]byte),
+			ResultsIteratorToReturn: &mockIterator{}, ErrPut: errors.New("put error")}, db: db}
+
+		writeErrs, err := store.BulkWrite([]edvprovider.DocumentWrite{{Type: edvprovider.WriteCreate, Document: testDoc}})
+		require.NoError(t, err)
+		require.Len(t, writeErrs, 1)
+		require.EqualError(t, writeErrs[0], "put error")
+	})
+	t.Run("Failure - database errors", func(t *testing.T) {
+		writes := []edvprovider.DocumentWrite{{Type: edvprovider.WriteCreate, Document: testDoc2}}
+		coreStore := &mockstore.MockStore{Store: make(map[string][]byte), ResultsIteratorToReturn: &mockIterator{}}
+
+		store := CouchDBEDVStore{coreStore: coreStore, db: &mockCouchDBDatabase{errRevisions: errors.New("all docs error")}}
+		_, err := store.BulkWrite(writes)
+		require.EqualError(t, err, "all docs error")
+
+		store = CouchDBEDVStore{coreStore: coreStore, db: &mockCouchDBDatabase{errBulkDocs: errors.New("bulk error")}}
+		_, err = store.BulkWrite(writes)
+		require.EqualError(t, err, "bulk error")
+
+		store = CouchDBEDVStore{coreStore: coreStore, db: &mockCouchDBDatabase{
+			bulkResults: &mockBulkResults{updateErrs: []error{nil}, errIter: errors.New("iterator error")}}}
+		_, err = store.BulkWrite(writes)
+		require.EqualError(t, err, "iterator error")
+
+		store = CouchDBEDVStore{coreStore: coreStore, db: &mockCouchDBDatabase{
+			bulkResults: &mockBulkResults{updateErrs: []error{nil}, errClose: errors.New("close error")}}}
+		_, err = store.BulkWrite(writes)
+		require.EqualError(t, err, "close error")
+	})
+	t.Run("Failure - no database client", func(t *testing.T) {
+		store := CouchDBEDVStore{coreStore: &mockstore.MockStore{Store: make(map[string][]byte)}}
+
+		_, err := store.BulkWrite(nil)
+		require.Equal(t, errNoDatabaseClient, err)
+	})
+}
+
+func TestCouchDBEDVStore_ReplicateUpdate(t *testing.T) {
+	var document models.EncryptedDocument
+
+	require.NoError(t, json.Unmarshal([]byte(testEncryptedDoc), &document))
+
+	mockCoreStore := mockstore.MockStore{Store: make(map[string][]byte), ResultsIteratorToReturn: &mockIterator{}}
+	db := &mockCouchDBDatabase{revisions: map[string]string{}}
+	store := &CouchDBEDVStore{coreStore: &mockCoreStore, db: db}
+	source := &mockReplicationSource{document: document, sequence: "1"}
+
+	replicator, err := replication.New(source, &mockReplicationProvider{store: store}, memstore.NewProvider(),
+		[]string{"testvault"})
+	require.NoError(t, err)
+
+	result, err := replicator.Replicate(context.Background(), "testvault")
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Applied)
+	require.Len(t, db.bulkDocs, 1)
+
+	// The document is now stored locally, and its unique indexed attribute is mapped to it.
+	documentBytes, err := json.Marshal(document)
+	require.NoError(t, err)
+	require.NoError(t, mockCoreStore.Put(testDocID1, documentBytes))
+
+	mappingDocBytes, err := json.Marshal(couchDBIndexMappingDocument{
+		IndexName:              "CUQaxPtSLtd8L3WBAIkJ4DiVJeqoF6bdnhR7lSaPloZ",
+		MatchingEncryptedDocID: testDocID1,
+	})
+	require.NoError(t, err)
+
+	mockCoreStore.ResultsIteratorToReturn = &mockIterator{maxTimesNextCanBeCalled: 1, valueReturn: mappingDocBytes}
+	db.revisions = map[string]string{testDocID1: "1-a"}
+	db.bulkResults = nil
+
+	// The updated document keeps its unique indexed attribute.
+	source.document.JWE = []byte(`{"ciphertext":"updated"}`)
+	source.sequence = "2"
+
+	result, err = replicator.Replicate(context.Background(), "testvault")
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Applied)
+
+	docsBytes, err := json.Marshal(db.bulkDocs)
+	require.NoError(t, err)
+
+	var docs []map[string]interface{}
+
+	require.NoError(t, json.Unmarshal(docsBytes, &docs))
+	require.Len(t, docs, 1)
+	require.Equal(t, testDocID1, docs[0]["_id"])
+	require.Equal(t, "1-a", docs[0]["_rev"])
+	require.Equal(t, map[string]interface{}{"ciphertext": "updated"}, docs[0]["jwe"])
+}
+
+// mockReplicationSource serves a single document, standing in for a remote EDV server.
+// The document is reported as changed whenever the sequence is past the cursor it's asked for changes since.
+type mockReplicationSource struct {
+	document models.EncryptedDocument
+	sequence string
+}
+
+func (m *mockReplicationSource) ReadChanges(_, since string, _ int) (*models.ChangeFeed, error) {
+	feed := &models.ChangeFeed{Cursor: m.sequence}
+
+	if since != m.sequence {
+		feed.Changes = []models.DocumentChange{
+			{ID: m.document.ID, Sequence: m.sequence, Type: models.ChangeTypeUpdated},
+		}
+	}
+
+	return feed, nil
+}
+
+func (m *mockReplicationSource) ReadDocument(_, _ string) (*models.EncryptedDocument, error) {
+	document := m.document
+
+	return &document, nil
+}
+
+// mockReplicationProvider opens the same store for every vault.
+type mockReplicationProvider struct {
+	edvprovider.EDVProvider
+	store edvprovider.EDVStore
+}
+
+func (m *mockReplicationProvider) OpenStore(string) (edvprovider.EDVStore, error) {
+	return m.store, nil
+}
+
+type mockCouchDBDatabase struct {
+	errPut         error
+	putDocIDs      []string
+	changes        *mockChanges
+	errChanges     error
+	changesOptions kivik.Options
+	revisions      map[string]string
+	errRevisions   error
+	bulkDocs       []interface{}
+	bulkDocsCalls  [][]interface{}
+	bulkResults    *mockBulkResults
+	errBulkDocs    error
+	allDocs        []couchDBStoredDocument
+	errAllDocs     error
+	documentIDs    []string
+	errDocumentIDs error
+	idsLimit       int
+}
+
+func (m *mockCouchDBDatabase) Put(_ context.Context, docID string, _ interface{}, _ ...kivik.Options) (string, error) {
+	if m.errPut != nil {
+		return "", m.errPut
+	}
+
+	m.putDocIDs = append(m.putDocIDs, docID)
+
+	return "1-rev", nil
+}
+
+func (m *mockCouchDBDatabase) Changes(_ context.Context, options ...kivik.Options) (couchDBChanges, error) {
+	if m.errChanges != nil {
+		return nil, m.errChanges
+	}
+
+	m.changesOptions = options[0]
+
+	return m.changes, nil
+}
+
+func (m *mockCouchDBDatabase) Revisions(_ context.Context, _ []string) (map[string]string, error) {
+	return m.revisions, m.errRevisions
+}
+
+func (m *mockCouchDBDatabase) BulkDocs(_ context.Context, docs []interface{},
+	_ ...kivik.Options) (couchDBBulkResults, error) {
+	if m.errBulkDocs != nil {
+		return nil, m.errBulkDocs
+	}
+
+	m.bulkDocs = docs
+	m.bulkDocsCalls = append(m.bulkDocsCalls, docs)
+
+	if m.bulkResults == nil {
+		m.bulkResults = &mockBulkResults{updateErrs: make([]error, len(docs))}
+	}
+
+	return m.bulkResults, nil
+}
+
+func (m *mockCouchDBDatabase) AllDocs(context.Context) ([]couchDBStoredDocument, error) {
+	return m.allDocs, m.errAllDocs
+}
+
+func (m *mockCouchDBDatabase) DocumentIDs(_ context.Context, startKey string, limit int) ([]string, error) {
+	if m.errDocumentIDs != nil {
+		return nil, m.errDocumentIDs
+	}
+
+	m.idsLimit = limit
+
+	var ids []string
+
+	for _, id := range m.documentIDs {
+		if id >= startKey && len(ids) < limit {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+type mockBulkResults struct {
+	updateErrs []error
+	current    int
+	errIter    error
+	errClose   error
+}
+
+func (m *mockBulkResults) Next() bool {
+	m.current++
+
+	return m.current <= len(m.updateErrs)
+}
+
+func (m *mockBulkResults) ID() string {
+	return ""
+}
+
+func (m *mockBulkResults) UpdateErr() error {
+	return m.updateErrs[m.current-1]
+}
+
+func (m *mockBulkResults) Err() error {
+	return m.errIter
+}
+
+func (m *mockBulkResults) Close() error {
+	return m.errClose
+}
+
+type mockChange struct {
+	id      string
+	seq     string
+	deleted bool
+	rev     string
+}
+
+type mockChanges struct {
+	changes  []mockChange
+	current  int
+	pending  int64
+	lastSeq  string
+	errIter  error
+	errClose error
+}
+
+func (m *mockChanges) Next() bool {
+	m.current++
+
+	return m.current <= len(m.changes)
+}
+
+func (m *mockChanges) ID() string {
+	return m.changes[m.current-1].id
+}
+
+func (m *mockChanges) Seq() string {
+	return m.changes[m.current-1].seq
+}
+
+func (m *mockChanges) Deleted() bool {
+	return m.changes[m.current-1].deleted
+}
+
+func (m *mockChanges) Changes() []string {
+	return []string{m.changes[m.current-1].rev}
+}
+
+func (m *mockChanges) Pending() int64 {
+	return m.pending
+}
+
+func (m *mockChanges) LastSeq() string {
+	return m.lastSeq
+}
+
+func (m *mockChanges) Err() error {
+	return m.errIter
+}
+
+func (m *mockChanges) Close() error {
+	return m.errClose
+}
+
+type mockStatusCodeError struct {
+	statusCode int
+}
+
+func (e *mockStatusCodeError) Error() string {
+	return http.StatusText(e.statusCode)
+}
+
+func (e *mockStatusCodeError) StatusCode() int {
+	return e.statusCode
+}
+
+func TestCouchDBEDVStore_Changes(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		db := &mockCouchDBDatabase{changes: &mockChanges{
+			changes: []mockChange{
+				{id: "_design/EDV_EncryptedIndexesDesignDoc", seq: "1-a", rev: "1-x"},
+				{id: testDocID1, seq: "2-a", rev: "1-x"},
+				{id: testDocID1 + "_mapping_fb2a3ba0-3ec2-4a41-a6d2-8f8b3c1d1b87", seq: "3-a", rev: "1-x"},
+				{id: testDocID1, seq: "4-a", rev: "2-x"},
+				{id: "docID2", seq: "5-a", rev: "3-x", deleted: true},
+				{id: "docID2_mapping_2d1e8a3b-1c4e-4b7e-9f0e-5e8f5a7c6d21", seq: "6-a", rev: "1-x"},
+			},
+			pending: 3,
+		}}
+		store := CouchDBEDVStore{db: db}
+
+		feed, err := store.Changes("", 10)
+		require.NoError(t, err)
+		require.Equal(t, &models.ChangeFeed{
+			Changes: []models.DocumentChange{
+				{ID: testDocID1, Sequence: "2-a", Type: models.ChangeTypeCreated},
+				{ID: testDocID1, Sequence: "4-a", Type: models.ChangeTypeUpdated},
+				{ID: "docID2", Sequence: "5-a", Type: models.ChangeTypeDeleted},
+			},
+			Cursor:  "6-a",
+			HasMore: true,
+		}, feed)
+		require.Equal(t, kivik.Options{"limit": 10}, db.changesOptions)
+	})
+	t.Run("Success - resume from cursor", func(t *testing.T) {
+		db := &mockCouchDBDatabase{changes: &mockChanges{lastSeq: "7-a"}}
+		store := CouchDBEDVStore{db: db}
+
+		feed, err := store.Changes("6-a", 10)
+		require.NoError(t, err)
+		require.Empty(t, feed.Changes)
+		require.Equal(t, "7-a", feed.Cursor)
+		require.False(t, feed.HasMore)
+		require.Equal(t, kivik.Options{"limit": 10, "since": "6-a"}, db.changesOptions)
+	})
+	t.Run("Failure - invalid cursor", func(t *testing.T) {
+		store := CouchDBEDVStore{
+			db: &mockCouchDBDatabase{errChanges: &mockStatusCodeError{statusCode: http.StatusBadRequest}}}
+
+		_, err := store.Changes("notACursor", 10)
+		require.Equal(t, edverrors.ErrInvalidChangeCursor, err)
+	})
+	t.Run("Failure - error while reading changes", func(t *testing.T) {
+		store := CouchDBEDVStore{db: &mockCouchDBDatabase{errChanges: errors.New("changes error")}}
+
+		_, err := store.Changes("", 10)
+		require.EqualError(t, err, "changes error")
+
+		store = CouchDBEDVStore{db: &mockCouchDBDatabase{changes: &mockChanges{errIter: errors.New("iterator error")}}}
+
+		_, err = store.Changes("", 10)
+		require.EqualError(t, err, "iterator error")
+
+		store = CouchDBEDVStore{db: &mockCouchDBDatabase{changes: &mockChanges{errClose: errors.New("close error")}}}
+
+		_, err = store.Changes("", 10)
+		require.EqualError(t, err, "close error")
+	})
+	t.Run("Failure - no database client", func(t *testing.T) {
+		store := CouchDBEDVStore{}
+
+		_, err := store.Changes("", 10)
+		require.Equal(t, errNoDatabaseClient, err)
+	})
+}
+
+func TestCouchDBEDVStore_ListDocuments(t *testing.T) {
+	db := &mockCouchDBDatabase{documentIDs: []string{
+		"_design/EDV_EncryptedIndexesDesignDoc",
+		"docID1",
+		"docID1_mapping_fb2a3ba0-3ec2-4a41-a6d2-8f8b3c1d1b87",
+		"docID2",
+		"docID3",
+		"docID3_mapping_2d1e8a3b-1c4e-4b7e-9f0e-5e8f5a7c6d21",
+	}}
+	store := CouchDBEDVStore{db: db}
+
+	t.Run("Success - pages", func(t *testing.T) {
+		list, err := store.ListDocuments("", 3)
+		require.NoError(t, err)
+		require.Equal(t, &models.DocumentList{DocumentIDs: []string{"docID1"},
+			Cursor: "docID1_mapping_fb2a3ba0-3ec2-4a41-a6d2-8f8b3c1d1b87", HasMore: true}, list)
+		require.Equal(t, 4, db.idsLimit)
+
+		list, err = store.ListDocuments(list.Cursor, 2)
+		require.NoError(t, err)
+		require.Equal(t, &models.DocumentList{DocumentIDs: []string{"docID2", "docID3"}, Cursor: "docID3",
+			HasMore: true}, list)
+		require.Equal(t, 4, db.idsLimit)
+
+		list, err = store.ListDocuments(list.Cursor, 2)
+		require.NoError(t, err)
+		require.Equal(t, &models.DocumentList{DocumentIDs: []string{},
+			Cursor: "docID3_mapping_2d1e8a3b-1c4e-4b7e-9f0e-5e8f5a7c6d21"}, list)
+
+		list, err = store.ListDocuments(list.Cursor, 2)
+		require.NoError(t, err)
+		require.Equal(t, &models.DocumentList{DocumentIDs: []string{},
+			Cursor: "docID3_mapping_2d1e8a3b-1c4e-4b7e-9f0e-5e8f5a7c6d21"}, list)
+	})
+	t.Run("Success - cursor was deleted", func(t *testing.T) {
+		list, err := store.ListDocuments("docID1_mapping_0", 10)
+		require.NoError(t, err)
+		require.Equal(t, []string{"docID2", "docID3"}, list.DocumentIDs)
+		require.False(t, list.HasMore)
+	})
+	t.Run("Failure - error while listing documents", func(t *testing.T) {
+		store := CouchDBEDVStore{db: &mockCouchDBDatabase{errDocumentIDs: errors.New("all docs error")}}
+
+		_, err := store.ListDocuments("", 10)
+		require.EqualError(t, err, "failed to list documents: all docs error")
+	})
+	t.Run("Failure - no database client", func(t *testing.T) {
+		store := CouchDBEDVStore{}
+
+		_, err := store.ListDocuments("", 10)
+		require.Equal(t, errNoDatabaseClient, err)
+	})
+}
+
 func TestCouchDBEDVStore_Get(t *testing.T) {
 	mockCoreStore := mockstore.MockStore{Store: make(map[string][]byte)}
 	store := CouchDBEDVStore{coreStore: &mockCoreStore}
@@ -448,7 +1169,7 @@ func TestCouchDBEDVStore_Query(t *testing.T) {
 		require.EqualError(t, err, "unexpected end of JSON input")
 		require.Empty(t, docIDs)
 	})
-	t.Run("Failure: document not found in coreStore while filtering docs by query", func(t *testing.T) {
+	t.Run("Success: documents deleted since their mapping documents were created are skipped", func(t *testing.T) {
 		mockCoreStore := mockstore.MockStore{Store: make(map[string][]byte),
 			ResultsIteratorToReturn: &mockIterator{maxTimesNextCanBeCalled: 1,
 				valueReturn: []byte(testQuery)}}
@@ -460,7 +1181,7 @@ func TestCouchDBEDVStore_Query(t *testing.T) {
 		}
 
 		docIDs, err := store.Query(&query)
-		require.Equal(t, edverrors.ErrDocumentNotFound, err)
+		require.NoError(t, err)
 		require.Empty(t, docIDs)
 	})
 	t.Run("Failure: other error in coreStore while filtering docs by query", func(t *testing.T) {
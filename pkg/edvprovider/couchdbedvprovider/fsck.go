@@ -0,0 +1,57 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package couchdbedvprovider
+
+import (
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+// Check reads every document in the store and reports the mapping documents that are orphaned (because their
+// encrypted document was deleted or no longer has the indexed attribute, they can't be parsed, or they duplicate
+// another mapping document), the encrypted documents that are missing mapping documents or can't be parsed,
+// and the unique index names and values that more than one document has. Since Put isn't atomic, a write that was
+// interrupted can leave any of these behind. If repair is true, then the orphaned mapping documents are deleted and
+// the missing ones are created. Unparsable documents and uniqueness violations can't be repaired, since only the
+// client that encrypted the documents can fix them.
+func (c *CouchDBEDVStore) Check(repair bool) (*models.ConsistencyReport, error) {
+	if c.db == nil {
+		return nil, errNoDatabaseClient
+	}
+
+	scan, err := c.scanIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	if repair {
+		err = c.repairIndex(scan)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	report := &models.ConsistencyReport{
+		Documents:            scan.documents,
+		UnparsableDocuments:  scan.unparsableDocuments,
+		UniquenessViolations: scan.uniquenessViolations,
+		Repaired:             repair,
+	}
+
+	for _, mapping := range scan.staleMappings {
+		report.OrphanedMappings = append(report.OrphanedMappings, mapping.ID)
+	}
+
+	// Missing mappings are sorted by document ID, so each document's mappings are next to each other.
+	for _, mapping := range scan.missingMappings {
+		unindexed := len(report.UnindexedDocuments)
+		if unindexed == 0 || report.UnindexedDocuments[unindexed-1] != mapping.MatchingEncryptedDocID {
+			report.UnindexedDocuments = append(report.UnindexedDocuments, mapping.MatchingEncryptedDocID)
+		}
+	}
+
+	return report, nil
+}
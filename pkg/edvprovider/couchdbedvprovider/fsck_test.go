@@ -0,0 +1,89 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package couchdbedvprovider
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+func TestCouchDBEDVStore_Check(t *testing.T) {
+	expectedReport := models.ConsistencyReport{
+		Documents: 3,
+		OrphanedMappings: []string{
+			testDocID1 + "_mapping_duplicate",
+			testDocID1 + "_mapping_old",
+			"deletedDoc_mapping_d",
+			testDocID1 + "_mapping_unparsable",
+		},
+		UnindexedDocuments:  []string{testDocID2, testDocID1},
+		UnparsableDocuments: []string{"brokenDoc"},
+		UniquenessViolations: []models.UniquenessViolation{{
+			Name:        testIndexName1,
+			Value:       "RV58Va4904K-18_L5g_vfARXRWEB00knFSGPpukUBro",
+			DocumentIDs: []string{testDocID2, testDocID1},
+		}},
+	}
+
+	t.Run("Success - report only", func(t *testing.T) {
+		db := &mockCouchDBDatabase{allDocs: testStoredDocuments(t)}
+		store := CouchDBEDVStore{coreStore: &mockstore.MockStore{Store: make(map[string][]byte)}, db: db}
+
+		report, err := store.Check(false)
+		require.NoError(t, err)
+		require.Equal(t, &expectedReport, report)
+		require.Empty(t, db.bulkDocsCalls)
+	})
+	t.Run("Success - repair", func(t *testing.T) {
+		db := &mockCouchDBDatabase{allDocs: testStoredDocuments(t)}
+		store := CouchDBEDVStore{coreStore: &mockstore.MockStore{Store: make(map[string][]byte)}, db: db}
+
+		report, err := store.Check(true)
+		require.NoError(t, err)
+
+		expectedRepairedReport := expectedReport
+		expectedRepairedReport.Repaired = true
+
+		require.Equal(t, &expectedRepairedReport, report)
+		require.Len(t, db.bulkDocsCalls, 2)
+		require.Len(t, db.bulkDocsCalls[0], 4)
+		require.Len(t, db.bulkDocsCalls[1], 3)
+	})
+	t.Run("Success - consistent store", func(t *testing.T) {
+		store := CouchDBEDVStore{coreStore: &mockstore.MockStore{Store: make(map[string][]byte)},
+			db: &mockCouchDBDatabase{}}
+
+		report, err := store.Check(true)
+		require.NoError(t, err)
+		require.Equal(t, &models.ConsistencyReport{Repaired: true}, report)
+	})
+	t.Run("Failure - no database client", func(t *testing.T) {
+		store := CouchDBEDVStore{coreStore: &mockstore.MockStore{Store: make(map[string][]byte)}}
+
+		_, err := store.Check(false)
+		require.Equal(t, errNoDatabaseClient, err)
+	})
+	t.Run("Failure - read documents", func(t *testing.T) {
+		store := CouchDBEDVStore{coreStore: &mockstore.MockStore{Store: make(map[string][]byte)},
+			db: &mockCouchDBDatabase{errAllDocs: errors.New("all docs error")}}
+
+		_, err := store.Check(false)
+		require.EqualError(t, err, "failed to read documents: all docs error")
+	})
+	t.Run("Failure - repair", func(t *testing.T) {
+		store := CouchDBEDVStore{coreStore: &mockstore.MockStore{Store: make(map[string][]byte)},
+			db: &mockCouchDBDatabase{allDocs: testStoredDocuments(t), errBulkDocs: errors.New("bulk docs error")}}
+
+		_, err := store.Check(true)
+		require.EqualError(t, err, "failed to delete mapping documents: bulk docs error")
+	})
+}
@@ -0,0 +1,306 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package couchdbedvprovider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+// reindexBatchSize is the number of mapping documents that are deleted or created with each bulk write
+// when reindexing.
+const reindexBatchSize = 1000
+
+// couchDBBulkMappingDocument is a mapping document as sent in a bulk write, which needs its CouchDB ID set.
+type couchDBBulkMappingDocument struct {
+	couchDBIndexMappingDocument
+	CouchDBID string `json:"_id"`
+}
+
+// indexScan is what was found by reading every document in a store: how its mapping documents differ from the
+// ones its encrypted documents need, along with the encrypted documents that couldn't be parsed and the unique
+// index names and values that more than one document has.
+type indexScan struct {
+	documents            int
+	unparsableDocuments  []string
+	staleMappings        []couchDBDeletedDocument
+	missingMappings      []couchDBIndexMappingDocument
+	uniquenessViolations []models.UniquenessViolation
+}
+
+// indexedValue is an indexed attribute name and value, along with the documents that have it and whether any of them
+// declares it unique.
+type indexedValue struct {
+	name        string
+	value       string
+	unique      bool
+	documentIDs []string
+}
+
+// Reindex recreates the index of mapping documents, then deletes the mapping documents that are out of date
+// (because their encrypted document was deleted or no longer has the indexed attribute, they can't be parsed, or they
+// duplicate another mapping document) and creates the ones that are missing. Encrypted documents that can't be parsed
+// keep their mapping documents as they are.
+func (c *CouchDBEDVStore) Reindex() (*models.ReindexResult, error) {
+	if c.db == nil {
+		return nil, errNoDatabaseClient
+	}
+
+	err := c.CreateEDVIndex()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create index: %w", err)
+	}
+
+	scan, err := c.scanIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.repairIndex(scan)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ReindexResult{
+		Documents:            scan.documents,
+		MappingsDeleted:      len(scan.staleMappings),
+		MappingsCreated:      len(scan.missingMappings),
+		UnparsableDocuments:  scan.unparsableDocuments,
+		UniquenessViolations: scan.uniquenessViolations,
+	}, nil
+}
+
+// scanIndex reads every document in the store with a single request and works out which mapping documents
+// are out of date and which are missing.
+func (c *CouchDBEDVStore) scanIndex() (*indexScan, error) {
+	storedDocs, err := c.db.AllDocs(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read documents: %w", err)
+	}
+
+	scan := &indexScan{}
+
+	// The names of the indexed attributes of each encrypted document that could be parsed, which need mappings.
+	indexedNames := make(map[string]map[string]struct{})
+	// Encrypted documents that couldn't be parsed, whose mappings are left as they are.
+	unparsable := make(map[string]struct{})
+	indexedValues := make(map[string]*indexedValue)
+
+	var mappingDocs []couchDBStoredDocument
+
+	for _, storedDoc := range storedDocs {
+		switch {
+		case strings.HasPrefix(storedDoc.ID, "_"):
+			continue
+		case strings.Contains(storedDoc.ID, mappingDocumentIDMarker):
+			mappingDocs = append(mappingDocs, storedDoc)
+
+			continue
+		}
+
+		scan.documents++
+
+		document := models.EncryptedDocument{}
+
+		err = json.Unmarshal(storedDoc.Doc, &document)
+		if err != nil {
+			scan.unparsableDocuments = append(scan.unparsableDocuments, storedDoc.ID)
+			unparsable[storedDoc.ID] = struct{}{}
+
+			continue
+		}
+
+		indexedNames[storedDoc.ID] = addIndexedValues(indexedValues, storedDoc.ID, document)
+	}
+
+	scan.staleMappings = findStaleMappings(mappingDocs, indexedNames, unparsable)
+	scan.missingMappings = findMissingMappings(indexedNames)
+	scan.uniquenessViolations = findUniquenessViolations(indexedValues)
+
+	return scan, nil
+}
+
+// addIndexedValues adds the given document's indexed attributes to indexedValues,
+// and returns the names of its indexed attributes.
+func addIndexedValues(indexedValues map[string]*indexedValue, docID string,
+	document models.EncryptedDocument) map[string]struct{} {
+	names := make(map[string]struct{})
+
+	for _, attributeCollection := range document.IndexedAttributeCollections {
+		for _, attribute := range attributeCollection.IndexedAttributes {
+			names[attribute.Name] = struct{}{}
+
+			key := attribute.Name + "\x00" + attribute.Value
+
+			value, exists := indexedValues[key]
+			if !exists {
+				value = &indexedValue{name: attribute.Name, value: attribute.Value}
+				indexedValues[key] = value
+			}
+
+			value.unique = value.unique || attribute.Unique
+
+			if len(value.documentIDs) == 0 || value.documentIDs[len(value.documentIDs)-1] != docID {
+				value.documentIDs = append(value.documentIDs, docID)
+			}
+		}
+	}
+
+	return names
+}
+
+// findStaleMappings returns the mapping documents to delete, and removes the ones that are kept from indexedNames,
+// leaving only the mappings that are missing.
+func findStaleMappings(mappingDocs []couchDBStoredDocument, indexedNames map[string]map[string]struct{},
+	unparsable map[string]struct{}) []couchDBDeletedDocument {
+	var staleMappings []couchDBDeletedDocument
+
+	for _, mappingDoc := range mappingDocs {
+		mapping := couchDBIndexMappingDocument{}
+
+		err := json.Unmarshal(mappingDoc.Doc, &mapping)
+		if err == nil {
+			if _, exists := unparsable[mapping.MatchingEncryptedDocID]; exists {
+				continue
+			}
+
+			if _, needed := indexedNames[mapping.MatchingEncryptedDocID][mapping.IndexName]; needed {
+				delete(indexedNames[mapping.MatchingEncryptedDocID], mapping.IndexName)
+
+				continue
+			}
+		}
+
+		staleMappings = append(staleMappings,
+			couchDBDeletedDocument{ID: mappingDoc.ID, Rev: mappingDoc.Rev, Deleted: true})
+	}
+
+	return staleMappings
+}
+
+// findMissingMappings returns the mapping documents that are still needed once the existing ones were accounted for,
+// sorted by document ID and index name.
+func findMissingMappings(indexedNames map[string]map[string]struct{}) []couchDBIndexMappingDocument {
+	var missingMappings []couchDBIndexMappingDocument
+
+	for docID, names := range indexedNames {
+		for name := range names {
+			missingMappings = append(missingMappings,
+				couchDBIndexMappingDocument{IndexName: name, MatchingEncryptedDocID: docID})
+		}
+	}
+
+	sort.Slice(missingMappings, func(i, j int) bool {
+		if missingMappings[i].MatchingEncryptedDocID != missingMappings[j].MatchingEncryptedDocID {
+			return missingMappings[i].MatchingEncryptedDocID < missingMappings[j].MatchingEncryptedDocID
+		}
+
+		return missingMappings[i].IndexName < missingMappings[j].IndexName
+	})
+
+	return missingMappings
+}
+
+// findUniquenessViolations returns the index names and values that are declared unique but that more than
+// one document has, sorted by name and value.
+func findUniquenessViolations(indexedValues map[string]*indexedValue) []models.UniquenessViolation {
+	var violations []models.UniquenessViolation
+
+	for _, value := range indexedValues {
+		if !value.unique || len(value.documentIDs) < 2 {
+			continue
+		}
+
+		documentIDs := append([]string(nil), value.documentIDs...)
+		sort.Strings(documentIDs)
+
+		violations = append(violations,
+			models.UniquenessViolation{Name: value.name, Value: value.value, DocumentIDs: documentIDs})
+	}
+
+	sort.Slice(violations, func(i, j int) bool {
+		if violations[i].Name != violations[j].Name {
+			return violations[i].Name < violations[j].Name
+		}
+
+		return violations[i].Value < violations[j].Value
+	})
+
+	return violations
+}
+
+// repairIndex deletes the mapping documents that the scan found to be out of date, then creates the missing ones.
+func (c *CouchDBEDVStore) repairIndex(scan *indexScan) error {
+	err := c.deleteMappingDocuments(scan.staleMappings)
+	if err != nil {
+		return err
+	}
+
+	return c.createMissingMappingDocuments(scan.missingMappings)
+}
+
+func (c *CouchDBEDVStore) deleteMappingDocuments(mappings []couchDBDeletedDocument) error {
+	docs := make([]interface{}, len(mappings))
+
+	for i, mapping := range mappings {
+		docs[i] = mapping
+	}
+
+	return c.bulkWriteMappingDocuments(docs, "delete")
+}
+
+func (c *CouchDBEDVStore) createMissingMappingDocuments(mappings []couchDBIndexMappingDocument) error {
+	docs := make([]interface{}, len(mappings))
+
+	for i, mapping := range mappings {
+		docs[i] = couchDBBulkMappingDocument{
+			couchDBIndexMappingDocument: mapping,
+			CouchDBID:                   mapping.MatchingEncryptedDocID + mappingDocumentIDMarker + uuid.New().String(),
+		}
+	}
+
+	return c.bulkWriteMappingDocuments(docs, "create")
+}
+
+// bulkWriteMappingDocuments writes the given mapping documents in batches, stopping at the first one that fails.
+func (c *CouchDBEDVStore) bulkWriteMappingDocuments(docs []interface{}, action string) error {
+	for start := 0; start < len(docs); start += reindexBatchSize {
+		end := start + reindexBatchSize
+		if end > len(docs) {
+			end = len(docs)
+		}
+
+		results, err := c.db.BulkDocs(context.Background(), docs[start:end])
+		if err != nil {
+			return fmt.Errorf("failed to %s mapping documents: %w", action, err)
+		}
+
+		for results.Next() {
+			if updateErr := results.UpdateErr(); updateErr != nil {
+				return fmt.Errorf("failed to %s mapping document %s: %w", action, results.ID(), updateErr)
+			}
+		}
+
+		if err = results.Err(); err != nil {
+			return fmt.Errorf("failed to %s mapping documents: %w", action, err)
+		}
+
+		err = results.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package couchdbedvprovider
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+const (
+	testIndexName1 = "CUQaxPtSLtd8L3WBAIkJ4DiVJeqoF6bdnhR7lSaPloZ"
+	testIndexName2 = "DUQaxPtSLtd8L3WBAIkJ4DiVJeqoF6bdnhR7lSaPloZ"
+)
+
+func TestCouchDBEDVStore_Reindex(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		db := &mockCouchDBDatabase{allDocs: testStoredDocuments(t)}
+		store := CouchDBEDVStore{coreStore: &mockstore.MockStore{Store: make(map[string][]byte)}, db: db}
+
+		result, err := store.Reindex()
+		require.NoError(t, err)
+		require.Equal(t, &models.ReindexResult{
+			Documents:           3,
+			MappingsDeleted:     4,
+			MappingsCreated:     3,
+			UnparsableDocuments: []string{"brokenDoc"},
+			UniquenessViolations: []models.UniquenessViolation{{
+				Name:        testIndexName1,
+				Value:       "RV58Va4904K-18_L5g_vfARXRWEB00knFSGPpukUBro",
+				DocumentIDs: []string{testDocID2, testDocID1},
+			}},
+		}, result)
+
+		require.Len(t, db.bulkDocsCalls, 2)
+		require.Equal(t, []interface{}{
+			couchDBDeletedDocument{ID: testDocID1 + "_mapping_duplicate", Rev: "1-b", Deleted: true},
+			couchDBDeletedDocument{ID: testDocID1 + "_mapping_old", Rev: "1-c", Deleted: true},
+			couchDBDeletedDocument{ID: "deletedDoc_mapping_d", Rev: "1-d", Deleted: true},
+			couchDBDeletedDocument{ID: testDocID1 + "_mapping_unparsable", Rev: "1-e", Deleted: true},
+		}, db.bulkDocsCalls[0])
+
+		var createdMappings []couchDBIndexMappingDocument
+
+		for _, doc := range db.bulkDocsCalls[1] {
+			mappingDoc, ok := doc.(couchDBBulkMappingDocument)
+			require.True(t, ok)
+			require.True(t, strings.HasPrefix(mappingDoc.CouchDBID,
+				mappingDoc.MatchingEncryptedDocID+mappingDocumentIDMarker))
+
+			createdMappings = append(createdMappings, mappingDoc.couchDBIndexMappingDocument)
+		}
+
+		require.Equal(t, []couchDBIndexMappingDocument{
+			{IndexName: testIndexName1, MatchingEncryptedDocID: testDocID2},
+			{IndexName: "some other index", MatchingEncryptedDocID: testDocID2},
+			{IndexName: testIndexName2, MatchingEncryptedDocID: testDocID1},
+		}, createdMappings)
+	})
+	t.Run("Nothing to do", func(t *testing.T) {
+		db := &mockCouchDBDatabase{}
+		store := CouchDBEDVStore{coreStore: &mockstore.MockStore{Store: make(map[string][]byte)}, db: db}
+
+		result, err := store.Reindex()
+		require.NoError(t, err)
+		require.Equal(t, &models.ReindexResult{}, result)
+		require.Empty(t, db.bulkDocsCalls)
+	})
+	t.Run("Failure - no database client", func(t *testing.T) {
+		store := CouchDBEDVStore{coreStore: &mockstore.MockStore{Store: make(map[string][]byte)}}
+
+		_, err := store.Reindex()
+		require.Equal(t, errNoDatabaseClient, err)
+	})
+	t.Run("Failure - create index", func(t *testing.T) {
+		store := CouchDBEDVStore{
+			coreStore: &mockstore.MockStore{ErrCreateIndex: errors.New("create index error")},
+			db:        &mockCouchDBDatabase{},
+		}
+
+		_, err := store.Reindex()
+		require.EqualError(t, err, "failed to create index: create index error")
+	})
+
+	for _, test := range []struct {
+		name string
+		db   *mockCouchDBDatabase
+		err  string
+	}{
+		{
+			name: "Failure - read documents",
+			db:   &mockCouchDBDatabase{errAllDocs: errors.New("all docs error")},
+			err:  "failed to read documents: all docs error",
+		},
+		{
+			name: "Failure - bulk write",
+			db:   &mockCouchDBDatabase{errBulkDocs: errors.New("bulk docs error")},
+			err:  "failed to delete mapping documents: bulk docs error",
+		},
+		{
+			name: "Failure - mapping document write",
+			db: &mockCouchDBDatabase{bulkResults: &mockBulkResults{
+				updateErrs: []error{errors.New("conflict")}}},
+			err: "failed to delete mapping document : conflict",
+		},
+		{
+			name: "Failure - bulk write results",
+			db:   &mockCouchDBDatabase{bulkResults: &mockBulkResults{errIter: errors.New("iteration error")}},
+			err:  "failed to delete mapping documents: iteration error",
+		},
+		{
+			name: "Failure - close bulk write results",
+			db:   &mockCouchDBDatabase{bulkResults: &mockBulkResults{errClose: errors.New("close error")}},
+			err:  "close error",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if test.db.allDocs == nil && test.db.errAllDocs == nil {
+				test.db.allDocs = testStoredDocuments(t)
+			}
+
+			store := CouchDBEDVStore{coreStore: &mockstore.MockStore{Store: make(map[string][]byte)}, db: test.db}
+
+			_, err := store.Reindex()
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
+
+// testStoredDocuments returns two encrypted documents that both have an attribute declared unique, one that can't
+// be parsed and a design document, along with mapping documents that are in use, duplicated, out of date,
+// for a deleted document, can't be parsed, or belong to the document that can't be parsed.
+func testStoredDocuments(t *testing.T) []couchDBStoredDocument {
+	mapping := func(indexName, docID string) json.RawMessage {
+		mappingBytes, err := json.Marshal(couchDBIndexMappingDocument{IndexName: indexName,
+			MatchingEncryptedDocID: docID})
+		require.NoError(t, err)
+
+		return mappingBytes
+	}
+
+	return []couchDBStoredDocument{
+		{ID: "_design/EDV_EncryptedIndexesDesignDoc", Rev: "1-x", Doc: json.RawMessage(`{}`)},
+		{ID: testDocID1, Rev: "1-1", Doc: json.RawMessage(testEncryptedDoc)},
+		{ID: testDocID2, Rev: "1-2", Doc: json.RawMessage(testEncryptedDoc2)},
+		{ID: "brokenDoc", Rev: "1-3", Doc: json.RawMessage(`"not an object"`)},
+		{ID: testDocID1 + "_mapping_a", Rev: "1-a", Doc: mapping(testIndexName1, testDocID1)},
+		{ID: testDocID1 + "_mapping_duplicate", Rev: "1-b", Doc: mapping(testIndexName1, testDocID1)},
+		{ID: testDocID1 + "_mapping_old", Rev: "1-c", Doc: mapping("old index", testDocID1)},
+		{ID: "deletedDoc_mapping_d", Rev: "1-d", Doc: mapping(testIndexName1, "deletedDoc")},
+		{ID: testDocID1 + "_mapping_unparsable", Rev: "1-e", Doc: json.RawMessage(`[]`)},
+		{ID: "brokenDoc_mapping_f", Rev: "1-f", Doc: mapping(testIndexName1, "brokenDoc")},
+	}
+}
@@ -8,6 +8,7 @@ package edvprovider
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/trustbloc/edv/pkg/restapi/edv/models"
 )
@@ -29,6 +30,32 @@ var ErrIndexNameAndValueCannotBeUnique = errors.New("unable to store document si
 	"index name and value that are declared as unique, but another document already has an " +
 	"identical index name + value pair")
 
+// ChangesNow is a change feed cursor that skips all of the changes made so far, so that only changes made after
+// the call are read from then on.
+const ChangesNow = "now"
+
+// documentIDsPageSize is the number of changes DocumentIDs reads at a time.
+const documentIDsPageSize = 1000
+
+// Document write types used in bulk writes.
+const (
+	// WriteCreate stores a new document. If there's already a document with the same ID,
+	// then the write fails with edverrors.ErrDuplicateDocument.
+	WriteCreate = "create"
+	// WriteUpdate replaces an existing document. If there's no document with the same ID,
+	// then the write fails with edverrors.ErrDocumentNotFound.
+	WriteUpdate = "update"
+	// WriteDelete removes an existing document. Only the document's ID is used.
+	// If there's no document with that ID, then the write fails with edverrors.ErrDocumentNotFound.
+	WriteDelete = "delete"
+)
+
+// DocumentWrite is a single write in a bulk write.
+type DocumentWrite struct {
+	Type     string
+	Document models.EncryptedDocument
+}
+
 // EDVProvider represents a provider with functionality needed for EDV data storage.
 type EDVProvider interface {
 	// CreateStore creates a new store with the given name.
@@ -36,13 +63,32 @@ type EDVProvider interface {
 
 	// OpenStore opens an existing store and returns it.
 	OpenStore(name string) (EDVStore, error)
+
+	// DeleteStore deletes the store with the given name, along with its documents.
+	// If there's no such store, then storage.ErrStoreNotFound is returned.
+	DeleteStore(name string) error
+
+	// StoreNames returns the names of the provider's stores, sorted. Stores that share the provider's underlying
+	// storage without being created through it, such as the EDV server's own stores, may be included.
+	StoreNames() ([]string, error)
+
+	// Ping checks whether the underlying storage is reachable and usable.
+	Ping() error
+
+	// Close closes the provider and all of its stores.
+	Close() error
 }
 
 // EDVStore represents a store with functionality needed for EDV data storage.
 type EDVStore interface {
-	// Put stores the given document.
+	// Put stores the given document, replacing any existing document with the same ID.
 	Put(document models.EncryptedDocument) error
 
+	// Create stores the given document only if there isn't already a document with the same ID.
+	// If there is, then edverrors.ErrDuplicateDocument is returned and the existing document is left as is.
+	// The check and the write happen atomically, so only one of several concurrent creates with the same ID succeeds.
+	Create(document models.EncryptedDocument) error
+
 	// Get fetches the document associated with the given key.
 	Get(k string) ([]byte, error)
 
@@ -51,4 +97,58 @@ type EDVStore interface {
 
 	// Query does an EDV encrypted index query.
 	Query(query *models.Query) ([]string, error)
+
+	// Changes returns up to limit changes made to the store's documents after the given cursor, oldest first.
+	// A blank cursor reads from the beginning and ChangesNow skips to the end. If the cursor wasn't issued by this
+	// store, then edverrors.ErrInvalidChangeCursor is returned.
+	Changes(since string, limit int) (*models.ChangeFeed, error)
+
+	// ListDocuments returns up to limit IDs of the store's documents, sorted, starting after the given cursor.
+	// A blank cursor starts from the beginning. Pages may have fewer than limit IDs even if HasMore is true.
+	ListDocuments(cursor string, limit int) (*models.DocumentList, error)
+
+	// Reindex rebuilds the store's encrypted indexes from its documents, removing index entries that are out of date
+	// and adding any that are missing. Providers that don't support indexing return ErrIndexingNotSupported.
+	Reindex() (*models.ReindexResult, error)
+
+	// Check scans the store for mapping documents that don't match a document's indexed attributes, documents that
+	// are missing mappings or can't be parsed, and unique index names and values that several documents have.
+	// If repair is true, then the mappings are fixed the same way Reindex does. Providers that don't support indexing
+	// return ErrIndexingNotSupported.
+	Check(repair bool) (*models.ConsistencyReport, error)
+
+	// BulkWrite applies the given writes, using as few requests to the underlying storage as it can.
+	// Each write succeeds or fails on its own: the returned slice holds the outcome of each write, in order,
+	// with nil for the ones that succeeded. An error is only returned if none of the writes could be attempted.
+	BulkWrite(writes []DocumentWrite) ([]error, error)
+}
+
+// DocumentIDs returns the IDs of the documents written to the given store, in the order they were first written,
+// by reading its change feed from the beginning. Documents that were deleted since are included, so some of the
+// returned IDs may not be found.
+func DocumentIDs(store EDVStore) ([]string, error) {
+	var documentIDs []string
+
+	seenDocumentIDs := make(map[string]struct{})
+	cursor := ""
+
+	for {
+		feed, err := store.Changes(cursor, documentIDsPageSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read changes: %w", err)
+		}
+
+		for _, change := range feed.Changes {
+			if _, seen := seenDocumentIDs[change.ID]; !seen {
+				seenDocumentIDs[change.ID] = struct{}{}
+				documentIDs = append(documentIDs, change.ID)
+			}
+		}
+
+		if !feed.HasMore {
+			return documentIDs, nil
+		}
+
+		cursor = feed.Cursor
+	}
 }
@@ -0,0 +1,66 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package edvprovider_test
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edv/pkg/edvprovider"
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+func TestDocumentIDs(t *testing.T) {
+	t.Run("Documents are listed once, in the order they were first written", func(t *testing.T) {
+		prov := memedvprovider.NewProvider()
+		require.NoError(t, prov.CreateStore("testStore"))
+
+		store, err := prov.OpenStore("testStore")
+		require.NoError(t, err)
+
+		var expectedIDs []string
+
+		// More changes than are read at a time, so that the change feed is read in several pages.
+		for i := 0; i < 1500; i++ {
+			docID := "doc" + strconv.Itoa(i)
+
+			require.NoError(t, store.Put(models.EncryptedDocument{ID: docID}))
+
+			expectedIDs = append(expectedIDs, docID)
+		}
+
+		require.NoError(t, store.Put(models.EncryptedDocument{ID: "doc0", Sequence: 1}))
+
+		writeErrs, err := store.BulkWrite([]edvprovider.DocumentWrite{
+			{Type: edvprovider.WriteDelete, Document: models.EncryptedDocument{ID: "doc1"}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []error{nil}, writeErrs)
+
+		documentIDs, err := edvprovider.DocumentIDs(store)
+		require.NoError(t, err)
+		require.Equal(t, expectedIDs, documentIDs)
+	})
+	t.Run("Change feed error", func(t *testing.T) {
+		documentIDs, err := edvprovider.DocumentIDs(&failingChangesStore{err: errors.New("changes error")})
+		require.Nil(t, documentIDs)
+		require.EqualError(t, err, "failed to read changes: changes error")
+	})
+}
+
+type failingChangesStore struct {
+	edvprovider.EDVStore
+	err error
+}
+
+func (f *failingChangesStore) Changes(string, int) (*models.ChangeFeed, error) {
+	return nil, f.err
+}
@@ -9,11 +9,16 @@ package memedvprovider
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
 
 	"github.com/trustbloc/edge-core/pkg/storage"
 	"github.com/trustbloc/edge-core/pkg/storage/memstore"
 
 	"github.com/trustbloc/edv/pkg/edvprovider"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
 	"github.com/trustbloc/edv/pkg/restapi/edv/models"
 )
 
@@ -23,18 +28,45 @@ var ErrQueryingNotSupported = errors.New("querying is not supported by memstore"
 // MemEDVProvider represents an in-memory provider with functionality needed for EDV data storage.
 // It wraps an edge-core memstore provider with additional functionality that's needed for EDV operations,
 // however this additional functionality is not supported in memstore.
+// Each store keeps a log of the changes made to it, which lives as long as the provider does.
 type MemEDVProvider struct {
 	coreProvider storage.Provider
+	writeMux     *sync.Mutex
+	changeLogs   map[string]*changeLog
+	storeNames   map[string]struct{}
+}
+
+// changeLog is the change feed of a store. The sequence of a change is its position in the log, starting from 1.
+// Since memstore can't remove values, the IDs of documents whose latest change is a deletion are kept as well,
+// so that deleted documents can be treated as missing.
+type changeLog struct {
+	changes []models.DocumentChange
+	deleted map[string]struct{}
 }
 
 // NewProvider instantiates Provider
 func NewProvider() *MemEDVProvider {
-	return &MemEDVProvider{coreProvider: memstore.NewProvider()}
+	return &MemEDVProvider{
+		coreProvider: memstore.NewProvider(),
+		writeMux:     &sync.Mutex{},
+		changeLogs:   make(map[string]*changeLog),
+		storeNames:   make(map[string]struct{}),
+	}
 }
 
 // CreateStore creates a new store with the given name.
 func (m MemEDVProvider) CreateStore(name string) error {
-	return m.coreProvider.CreateStore(name)
+	m.writeMux.Lock()
+	defer m.writeMux.Unlock()
+
+	err := m.coreProvider.CreateStore(name)
+	if err != nil {
+		return err
+	}
+
+	m.storeNames[name] = struct{}{}
+
+	return nil
 }
 
 // OpenStore opens an existing store and returns it.
@@ -44,36 +76,322 @@ func (m MemEDVProvider) OpenStore(name string) (edvprovider.EDVStore, error) {
 		return nil, err
 	}
 
-	return &MemEDVStore{coreStore: coreStore}, nil
+	m.writeMux.Lock()
+	defer m.writeMux.Unlock()
+
+	storeChangeLog, exists := m.changeLogs[name]
+	if !exists {
+		storeChangeLog = &changeLog{deleted: make(map[string]struct{})}
+		m.changeLogs[name] = storeChangeLog
+	}
+
+	return &MemEDVStore{coreStore: coreStore, writeMux: m.writeMux, changeLog: storeChangeLog}, nil
+}
+
+// DeleteStore deletes the store with the given name, along with its documents and change log.
+func (m MemEDVProvider) DeleteStore(name string) error {
+	m.writeMux.Lock()
+	defer m.writeMux.Unlock()
+
+	err := m.coreProvider.CloseStore(name)
+	if err != nil {
+		return err
+	}
+
+	delete(m.storeNames, name)
+	delete(m.changeLogs, name)
+
+	return nil
+}
+
+// StoreNames returns the names of the stores created through this provider, sorted.
+func (m MemEDVProvider) StoreNames() ([]string, error) {
+	m.writeMux.Lock()
+	defer m.writeMux.Unlock()
+
+	names := make([]string, 0, len(m.storeNames))
+
+	for name := range m.storeNames {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Ping always succeeds since the memstore lives in the same process as the EDV.
+func (m MemEDVProvider) Ping() error {
+	return nil
+}
+
+// Close closes the provider and all of its stores.
+func (m MemEDVProvider) Close() error {
+	m.writeMux.Lock()
+	defer m.writeMux.Unlock()
+
+	for name := range m.storeNames {
+		delete(m.storeNames, name)
+	}
+
+	return m.coreProvider.Close()
 }
 
 // MemEDVStore represents an in-memory store with functionality needed for EDV data storage.
 // It wraps an edge-core in-memory store with additional functionality that's needed for EDV operations.
+// Writes to all of the provider's stores are serialized so that Create can check for an existing document
+// and store the new one atomically, and so that changes are logged in the order they're made.
 type MemEDVStore struct {
 	coreStore storage.Store
+	writeMux  *sync.Mutex
+	changeLog *changeLog
 }
 
 // Put stores the given document.
 func (m MemEDVStore) Put(document models.EncryptedDocument) error {
+	m.writeMux.Lock()
+	defer m.writeMux.Unlock()
+
+	exists, err := m.exists(document.ID)
+	if err != nil {
+		return err
+	}
+
+	changeType := models.ChangeTypeCreated
+
+	if exists {
+		changeType = models.ChangeTypeUpdated
+	}
+
+	return m.put(document, changeType)
+}
+
+// Create stores the given document only if there isn't already a document with the same ID.
+func (m MemEDVStore) Create(document models.EncryptedDocument) error {
+	m.writeMux.Lock()
+	defer m.writeMux.Unlock()
+
+	return m.create(document)
+}
+
+// BulkWrite applies the given writes one after the other. No other writes to the provider's stores
+// can happen in between.
+func (m MemEDVStore) BulkWrite(writes []edvprovider.DocumentWrite) ([]error, error) {
+	m.writeMux.Lock()
+	defer m.writeMux.Unlock()
+
+	writeErrs := make([]error, len(writes))
+
+	for i, write := range writes {
+		switch write.Type {
+		case edvprovider.WriteCreate:
+			writeErrs[i] = m.create(write.Document)
+		case edvprovider.WriteUpdate:
+			writeErrs[i] = m.update(write.Document)
+		case edvprovider.WriteDelete:
+			writeErrs[i] = m.remove(write.Document.ID)
+		default:
+			writeErrs[i] = fmt.Errorf("unsupported document write type: %s", write.Type)
+		}
+	}
+
+	return writeErrs, nil
+}
+
+func (m MemEDVStore) create(document models.EncryptedDocument) error {
+	exists, err := m.exists(document.ID)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return edverrors.ErrDuplicateDocument
+	}
+
+	return m.put(document, models.ChangeTypeCreated)
+}
+
+func (m MemEDVStore) update(document models.EncryptedDocument) error {
+	exists, err := m.exists(document.ID)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return edverrors.ErrDocumentNotFound
+	}
+
+	return m.put(document, models.ChangeTypeUpdated)
+}
+
+func (m MemEDVStore) remove(docID string) error {
+	exists, err := m.exists(docID)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return edverrors.ErrDocumentNotFound
+	}
+
+	m.changeLog.deleted[docID] = struct{}{}
+	m.logChange(docID, models.ChangeTypeDeleted)
+
+	return nil
+}
+
+// exists returns whether there's a document with the given ID that hasn't been deleted.
+func (m MemEDVStore) exists(docID string) (bool, error) {
+	if _, deleted := m.changeLog.deleted[docID]; deleted {
+		return false, nil
+	}
+
+	_, err := m.coreStore.Get(docID)
+	if err == storage.ErrValueNotFound {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (m MemEDVStore) put(document models.EncryptedDocument, changeType string) error {
 	documentBytes, err := json.Marshal(document)
 	if err != nil {
 		return err
 	}
 
-	return m.coreStore.Put(document.ID, documentBytes)
+	err = m.coreStore.Put(document.ID, documentBytes)
+	if err != nil {
+		return err
+	}
+
+	delete(m.changeLog.deleted, document.ID)
+	m.logChange(document.ID, changeType)
+
+	return nil
+}
+
+func (m MemEDVStore) logChange(docID, changeType string) {
+	m.changeLog.changes = append(m.changeLog.changes, models.DocumentChange{
+		ID:       docID,
+		Sequence: strconv.Itoa(len(m.changeLog.changes) + 1),
+		Type:     changeType,
+	})
 }
 
 // Get fetches the document associated with the given key.
+// Deleted documents aren't found.
 func (m MemEDVStore) Get(k string) ([]byte, error) {
+	m.writeMux.Lock()
+	_, deleted := m.changeLog.deleted[k]
+	m.writeMux.Unlock()
+
+	if deleted {
+		return nil, storage.ErrValueNotFound
+	}
+
 	return m.coreStore.Get(k)
 }
 
+// ListDocuments returns up to limit IDs of the store's documents, sorted, starting after the given cursor.
+// Cursors are document IDs. The IDs are found in the change log, since memstore can't list its keys.
+func (m MemEDVStore) ListDocuments(cursor string, limit int) (*models.DocumentList, error) {
+	m.writeMux.Lock()
+	defer m.writeMux.Unlock()
+
+	seen := make(map[string]struct{})
+
+	var ids []string
+
+	for _, change := range m.changeLog.changes {
+		if _, exists := seen[change.ID]; exists {
+			continue
+		}
+
+		seen[change.ID] = struct{}{}
+
+		if _, deleted := m.changeLog.deleted[change.ID]; !deleted && change.ID > cursor {
+			ids = append(ids, change.ID)
+		}
+	}
+
+	sort.Strings(ids)
+
+	list := &models.DocumentList{DocumentIDs: []string{}, Cursor: cursor}
+
+	if len(ids) > limit {
+		ids = ids[:limit]
+		list.HasMore = true
+	}
+
+	if len(ids) > 0 {
+		list.DocumentIDs = ids
+		list.Cursor = ids[len(ids)-1]
+	}
+
+	return list, nil
+}
+
 // CreateEDVIndex is not supported in memstore, and calling it will always return an error.
 func (m MemEDVStore) CreateEDVIndex() error {
 	return edvprovider.ErrIndexingNotSupported
 }
 
+// Reindex is not supported in memstore, since it has no indexes, and calling it will always return an error.
+func (m MemEDVStore) Reindex() (*models.ReindexResult, error) {
+	return nil, edvprovider.ErrIndexingNotSupported
+}
+
+// Check is not supported in memstore, since it has no indexes, and calling it will always return an error.
+func (m MemEDVStore) Check(bool) (*models.ConsistencyReport, error) {
+	return nil, edvprovider.ErrIndexingNotSupported
+}
+
 // Query is not supported in memstore, and calling it will always return an error.
 func (m MemEDVStore) Query(query *models.Query) ([]string, error) {
 	return nil, ErrQueryingNotSupported
 }
+
+// Changes returns up to limit changes made to the store's documents after the given cursor, oldest first.
+// Cursors are change sequences, so the change log can be resumed from any change.
+func (m MemEDVStore) Changes(since string, limit int) (*models.ChangeFeed, error) {
+	m.writeMux.Lock()
+	defer m.writeMux.Unlock()
+
+	sinceSequence := 0
+
+	switch since {
+	case "":
+	case edvprovider.ChangesNow:
+		sinceSequence = len(m.changeLog.changes)
+	default:
+		var err error
+
+		sinceSequence, err = strconv.Atoi(since)
+		if err != nil || sinceSequence < 0 {
+			return nil, edverrors.ErrInvalidChangeCursor
+		}
+	}
+
+	if sinceSequence > len(m.changeLog.changes) {
+		return nil, edverrors.ErrInvalidChangeCursor
+	}
+
+	end := len(m.changeLog.changes)
+	if end-sinceSequence > limit {
+		end = sinceSequence + limit
+	}
+
+	changes := make([]models.DocumentChange, end-sinceSequence)
+	copy(changes, m.changeLog.changes[sinceSequence:end])
+
+	return &models.ChangeFeed{
+		Changes: changes,
+		Cursor:  strconv.Itoa(end),
+		HasMore: end < len(m.changeLog.changes),
+	}, nil
+}
@@ -7,12 +7,270 @@ SPDX-License-Identifier: Apache-2.0
 package memedvprovider
 
 import (
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edv/pkg/edvprovider"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
 )
 
 func TestNewProvider(t *testing.T) {
 	prov := NewProvider()
 	require.NotNil(t, prov)
 }
+
+func TestMemEDVProvider_Close(t *testing.T) {
+	prov := NewProvider()
+
+	err := prov.CreateStore("testStore")
+	require.NoError(t, err)
+
+	err = prov.Close()
+	require.NoError(t, err)
+
+	_, err = prov.OpenStore("testStore")
+	require.Error(t, err)
+}
+
+func TestMemEDVProvider_StoreNames(t *testing.T) {
+	prov := NewProvider()
+
+	names, err := prov.StoreNames()
+	require.NoError(t, err)
+	require.Empty(t, names)
+
+	require.NoError(t, prov.CreateStore("store2"))
+	require.NoError(t, prov.CreateStore("store1"))
+	require.Equal(t, storage.ErrDuplicateStore, prov.CreateStore("store1"))
+
+	names, err = prov.StoreNames()
+	require.NoError(t, err)
+	require.Equal(t, []string{"store1", "store2"}, names)
+
+	require.NoError(t, prov.Close())
+
+	names, err = prov.StoreNames()
+	require.NoError(t, err)
+	require.Empty(t, names)
+}
+
+func TestMemEDVProvider_DeleteStore(t *testing.T) {
+	prov := NewProvider()
+
+	require.NoError(t, prov.CreateStore("store1"))
+
+	store, err := prov.OpenStore("store1")
+	require.NoError(t, err)
+	require.NoError(t, store.Create(models.EncryptedDocument{ID: "docID1"}))
+
+	require.NoError(t, prov.DeleteStore("store1"))
+	require.Equal(t, storage.ErrStoreNotFound, prov.DeleteStore("store1"))
+
+	_, err = prov.OpenStore("store1")
+	require.Equal(t, storage.ErrStoreNotFound, err)
+
+	names, err := prov.StoreNames()
+	require.NoError(t, err)
+	require.Empty(t, names)
+
+	require.NoError(t, prov.CreateStore("store1"))
+
+	store, err = prov.OpenStore("store1")
+	require.NoError(t, err)
+
+	_, err = store.Get("docID1")
+	require.Equal(t, storage.ErrValueNotFound, err)
+
+	feed, err := store.Changes("", 10)
+	require.NoError(t, err)
+	require.Empty(t, feed.Changes)
+}
+
+func TestMemEDVStore_Create(t *testing.T) {
+	prov := NewProvider()
+
+	err := prov.CreateStore("testStore")
+	require.NoError(t, err)
+
+	store, err := prov.OpenStore("testStore")
+	require.NoError(t, err)
+
+	t.Run("Success", func(t *testing.T) {
+		err = store.Create(models.EncryptedDocument{ID: "docID1"})
+		require.NoError(t, err)
+
+		_, err = store.Get("docID1")
+		require.NoError(t, err)
+	})
+	t.Run("Failure - document already exists", func(t *testing.T) {
+		err = store.Create(models.EncryptedDocument{ID: "docID1", Sequence: 1})
+		require.Equal(t, edverrors.ErrDuplicateDocument, err)
+	})
+	t.Run("Concurrent creates with the same ID", func(t *testing.T) {
+		const numCreates = 20
+
+		var (
+			wg        sync.WaitGroup
+			successes int32
+		)
+
+		for i := 0; i < numCreates; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				if store.Create(models.EncryptedDocument{ID: "docID2"}) == nil {
+					atomic.AddInt32(&successes, 1)
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		require.Equal(t, int32(1), successes)
+	})
+}
+
+func TestMemEDVStore_Changes(t *testing.T) {
+	prov := NewProvider()
+
+	err := prov.CreateStore("testStore")
+	require.NoError(t, err)
+
+	store, err := prov.OpenStore("testStore")
+	require.NoError(t, err)
+
+	feed, err := store.Changes("", 10)
+	require.NoError(t, err)
+	require.Equal(t, &models.ChangeFeed{Changes: []models.DocumentChange{}, Cursor: "0"}, feed)
+
+	require.NoError(t, store.Create(models.EncryptedDocument{ID: "docID1"}))
+	require.NoError(t, store.Put(models.EncryptedDocument{ID: "docID2"}))
+	require.NoError(t, store.Put(models.EncryptedDocument{ID: "docID1", Sequence: 1}))
+
+	// The change log is shared by every opened instance of the same store.
+	store, err = prov.OpenStore("testStore")
+	require.NoError(t, err)
+
+	feed, err = store.Changes("", 2)
+	require.NoError(t, err)
+	require.Equal(t, &models.ChangeFeed{
+		Changes: []models.DocumentChange{
+			{ID: "docID1", Sequence: "1", Type: models.ChangeTypeCreated},
+			{ID: "docID2", Sequence: "2", Type: models.ChangeTypeCreated},
+		},
+		Cursor:  "2",
+		HasMore: true,
+	}, feed)
+
+	feed, err = store.Changes(feed.Cursor, 2)
+	require.NoError(t, err)
+	require.Equal(t, &models.ChangeFeed{
+		Changes: []models.DocumentChange{{ID: "docID1", Sequence: "3", Type: models.ChangeTypeUpdated}},
+		Cursor:  "3",
+	}, feed)
+
+	feed, err = store.Changes(edvprovider.ChangesNow, 2)
+	require.NoError(t, err)
+	require.Equal(t, &models.ChangeFeed{Changes: []models.DocumentChange{}, Cursor: "3"}, feed)
+
+	for _, invalidCursor := range []string{"notACursor", "-1", "4"} {
+		_, err = store.Changes(invalidCursor, 2)
+		require.Equal(t, edverrors.ErrInvalidChangeCursor, err)
+	}
+}
+
+func TestMemEDVStore_ListDocuments(t *testing.T) {
+	prov := NewProvider()
+
+	err := prov.CreateStore("testStore")
+	require.NoError(t, err)
+
+	store, err := prov.OpenStore("testStore")
+	require.NoError(t, err)
+
+	list, err := store.ListDocuments("", 10)
+	require.NoError(t, err)
+	require.Equal(t, &models.DocumentList{DocumentIDs: []string{}}, list)
+
+	require.NoError(t, store.Create(models.EncryptedDocument{ID: "docID3"}))
+	require.NoError(t, store.Create(models.EncryptedDocument{ID: "docID1"}))
+	require.NoError(t, store.Put(models.EncryptedDocument{ID: "docID1", Sequence: 1}))
+	require.NoError(t, store.Create(models.EncryptedDocument{ID: "docID2"}))
+
+	writeErrs, err := store.BulkWrite([]edvprovider.DocumentWrite{
+		{Type: edvprovider.WriteDelete, Document: models.EncryptedDocument{ID: "docID2"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []error{nil}, writeErrs)
+
+	list, err = store.ListDocuments("", 1)
+	require.NoError(t, err)
+	require.Equal(t, &models.DocumentList{DocumentIDs: []string{"docID1"}, Cursor: "docID1", HasMore: true}, list)
+
+	list, err = store.ListDocuments(list.Cursor, 1)
+	require.NoError(t, err)
+	require.Equal(t, &models.DocumentList{DocumentIDs: []string{"docID3"}, Cursor: "docID3"}, list)
+
+	list, err = store.ListDocuments(list.Cursor, 1)
+	require.NoError(t, err)
+	require.Equal(t, &models.DocumentList{DocumentIDs: []string{}, Cursor: "docID3"}, list)
+}
+
+func TestMemEDVStore_BulkWrite(t *testing.T) {
+	prov := NewProvider()
+
+	err := prov.CreateStore("testStore")
+	require.NoError(t, err)
+
+	store, err := prov.OpenStore("testStore")
+	require.NoError(t, err)
+
+	require.NoError(t, store.Create(models.EncryptedDocument{ID: "docID1"}))
+
+	writeErrs, err := store.BulkWrite([]edvprovider.DocumentWrite{
+		{Type: edvprovider.WriteCreate, Document: models.EncryptedDocument{ID: "docID2"}},
+		{Type: edvprovider.WriteCreate, Document: models.EncryptedDocument{ID: "docID1"}},
+		{Type: edvprovider.WriteUpdate, Document: models.EncryptedDocument{ID: "docID1", Sequence: 1}},
+		{Type: edvprovider.WriteUpdate, Document: models.EncryptedDocument{ID: "docID3"}},
+		{Type: edvprovider.WriteDelete, Document: models.EncryptedDocument{ID: "docID2"}},
+		{Type: edvprovider.WriteDelete, Document: models.EncryptedDocument{ID: "docID2"}},
+		{Type: "replace", Document: models.EncryptedDocument{ID: "docID1"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, writeErrs, 7)
+	require.NoError(t, writeErrs[0])
+	require.Equal(t, edverrors.ErrDuplicateDocument, writeErrs[1])
+	require.NoError(t, writeErrs[2])
+	require.Equal(t, edverrors.ErrDocumentNotFound, writeErrs[3])
+	require.NoError(t, writeErrs[4])
+	require.Equal(t, edverrors.ErrDocumentNotFound, writeErrs[5])
+	require.EqualError(t, writeErrs[6], "unsupported document write type: replace")
+
+	documentBytes, err := store.Get("docID1")
+	require.NoError(t, err)
+	require.Contains(t, string(documentBytes), `"sequence":1`)
+
+	_, err = store.Get("docID2")
+	require.Equal(t, storage.ErrValueNotFound, err)
+
+	feed, err := store.Changes("1", 10)
+	require.NoError(t, err)
+	require.Equal(t, []models.DocumentChange{
+		{ID: "docID2", Sequence: "2", Type: models.ChangeTypeCreated},
+		{ID: "docID1", Sequence: "3", Type: models.ChangeTypeUpdated},
+		{ID: "docID2", Sequence: "4", Type: models.ChangeTypeDeleted},
+	}, feed.Changes)
+
+	// A deleted document can be created again.
+	require.NoError(t, store.Create(models.EncryptedDocument{ID: "docID2"}))
+
+	_, err = store.Get("docID2")
+	require.NoError(t, err)
+}
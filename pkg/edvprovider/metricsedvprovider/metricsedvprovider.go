@@ -0,0 +1,243 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package metricsedvprovider
+
+import (
+	"time"
+
+	"github.com/trustbloc/edv/pkg/edvprovider"
+	"github.com/trustbloc/edv/pkg/metrics"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+const (
+	createStoreOperation    = "create_store"
+	openStoreOperation      = "open_store"
+	deleteStoreOperation    = "delete_store"
+	storeNamesOperation     = "store_names"
+	putOperation            = "put"
+	createOperation         = "create"
+	getOperation            = "get"
+	createEDVIndexOperation = "create_edv_index"
+	queryOperation          = "query"
+	changesOperation        = "changes"
+	bulkWriteOperation      = "bulk_write"
+	reindexOperation        = "reindex"
+	checkOperation          = "check"
+	listDocumentsOperation  = "list_documents"
+)
+
+// MetricsEDVProvider represents an EDV provider that records Prometheus metrics for every call
+// made to the EDV provider it wraps.
+type MetricsEDVProvider struct {
+	provider edvprovider.EDVProvider
+	metrics  *metrics.Metrics
+}
+
+// NewProvider returns a new MetricsEDVProvider that wraps the given provider.
+func NewProvider(provider edvprovider.EDVProvider, m *metrics.Metrics) *MetricsEDVProvider {
+	return &MetricsEDVProvider{provider: provider, metrics: m}
+}
+
+// CreateStore creates a new store with the given name.
+func (p *MetricsEDVProvider) CreateStore(name string) error {
+	start := time.Now()
+
+	err := p.provider.CreateStore(name)
+
+	p.metrics.ObserveProviderCall(createStoreOperation, start, err)
+
+	return err
+}
+
+// OpenStore opens an existing store and returns it.
+func (p *MetricsEDVProvider) OpenStore(name string) (edvprovider.EDVStore, error) {
+	start := time.Now()
+
+	store, err := p.provider.OpenStore(name)
+
+	p.metrics.ObserveProviderCall(openStoreOperation, start, err)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &MetricsEDVStore{store: store, metrics: p.metrics}, nil
+}
+
+// DeleteStore deletes the store with the given name.
+func (p *MetricsEDVProvider) DeleteStore(name string) error {
+	start := time.Now()
+
+	err := p.provider.DeleteStore(name)
+
+	p.metrics.ObserveProviderCall(deleteStoreOperation, start, err)
+
+	return err
+}
+
+// StoreNames returns the names of the provider's stores.
+func (p *MetricsEDVProvider) StoreNames() ([]string, error) {
+	start := time.Now()
+
+	names, err := p.provider.StoreNames()
+
+	p.metrics.ObserveProviderCall(storeNamesOperation, start, err)
+
+	return names, err
+}
+
+// Ping checks whether the underlying storage is reachable and usable.
+func (p *MetricsEDVProvider) Ping() error {
+	return p.provider.Ping()
+}
+
+// Close closes the provider and all of its stores.
+func (p *MetricsEDVProvider) Close() error {
+	return p.provider.Close()
+}
+
+// MetricsEDVStore represents an EDV store that records Prometheus metrics for every call
+// made to the EDV store it wraps.
+type MetricsEDVStore struct {
+	store   edvprovider.EDVStore
+	metrics *metrics.Metrics
+}
+
+// Put stores the given document.
+// Documents rejected due to unique index name+value constraints are counted separately.
+func (s *MetricsEDVStore) Put(document models.EncryptedDocument) error {
+	start := time.Now()
+
+	err := s.store.Put(document)
+
+	s.metrics.ObserveProviderCall(putOperation, start, err)
+
+	if err == edvprovider.ErrIndexNameAndValueAlreadyDeclaredUnique ||
+		err == edvprovider.ErrIndexNameAndValueCannotBeUnique {
+		s.metrics.IncUniqueConstraintRejections()
+	}
+
+	return err
+}
+
+// Create stores the given document only if there isn't already a document with the same ID.
+// Documents rejected due to unique index name+value constraints are counted separately.
+func (s *MetricsEDVStore) Create(document models.EncryptedDocument) error {
+	start := time.Now()
+
+	err := s.store.Create(document)
+
+	s.metrics.ObserveProviderCall(createOperation, start, err)
+
+	if err == edvprovider.ErrIndexNameAndValueAlreadyDeclaredUnique ||
+		err == edvprovider.ErrIndexNameAndValueCannotBeUnique {
+		s.metrics.IncUniqueConstraintRejections()
+	}
+
+	return err
+}
+
+// Get fetches the document associated with the given key.
+func (s *MetricsEDVStore) Get(k string) ([]byte, error) {
+	start := time.Now()
+
+	documentBytes, err := s.store.Get(k)
+
+	s.metrics.ObserveProviderCall(getOperation, start, err)
+
+	return documentBytes, err
+}
+
+// CreateEDVIndex creates the index which will allow for encrypted indices to work.
+func (s *MetricsEDVStore) CreateEDVIndex() error {
+	start := time.Now()
+
+	err := s.store.CreateEDVIndex()
+
+	s.metrics.ObserveProviderCall(createEDVIndexOperation, start, err)
+
+	return err
+}
+
+// ListDocuments returns a page of the IDs of the store's documents.
+func (s *MetricsEDVStore) ListDocuments(cursor string, limit int) (*models.DocumentList, error) {
+	start := time.Now()
+
+	list, err := s.store.ListDocuments(cursor, limit)
+
+	s.metrics.ObserveProviderCall(listDocumentsOperation, start, err)
+
+	return list, err
+}
+
+// Reindex rebuilds the store's encrypted indexes from its documents.
+func (s *MetricsEDVStore) Reindex() (*models.ReindexResult, error) {
+	start := time.Now()
+
+	result, err := s.store.Reindex()
+
+	s.metrics.ObserveProviderCall(reindexOperation, start, err)
+
+	return result, err
+}
+
+// Check scans the store for inconsistencies between its documents and its encrypted indexes.
+func (s *MetricsEDVStore) Check(repair bool) (*models.ConsistencyReport, error) {
+	start := time.Now()
+
+	report, err := s.store.Check(repair)
+
+	s.metrics.ObserveProviderCall(checkOperation, start, err)
+
+	return report, err
+}
+
+// Query does an EDV encrypted index query.
+func (s *MetricsEDVStore) Query(query *models.Query) ([]string, error) {
+	start := time.Now()
+
+	docIDs, err := s.store.Query(query)
+
+	s.metrics.ObserveProviderCall(queryOperation, start, err)
+
+	if err == nil {
+		s.metrics.ObserveQueryResultSize(len(docIDs))
+	}
+
+	return docIDs, err
+}
+
+// Changes returns up to limit changes made to the store's documents after the given cursor.
+func (s *MetricsEDVStore) Changes(since string, limit int) (*models.ChangeFeed, error) {
+	start := time.Now()
+
+	feed, err := s.store.Changes(since, limit)
+
+	s.metrics.ObserveProviderCall(changesOperation, start, err)
+
+	return feed, err
+}
+
+// BulkWrite applies the given writes. The bulk write is observed as a single call, but documents rejected due to
+// unique index name+value constraints are counted individually.
+func (s *MetricsEDVStore) BulkWrite(writes []edvprovider.DocumentWrite) ([]error, error) {
+	start := time.Now()
+
+	writeErrs, err := s.store.BulkWrite(writes)
+
+	s.metrics.ObserveProviderCall(bulkWriteOperation, start, err)
+
+	for _, writeErr := range writeErrs {
+		if writeErr == edvprovider.ErrIndexNameAndValueAlreadyDeclaredUnique ||
+			writeErr == edvprovider.ErrIndexNameAndValueCannotBeUnique {
+			s.metrics.IncUniqueConstraintRejections()
+		}
+	}
+
+	return writeErrs, err
+}
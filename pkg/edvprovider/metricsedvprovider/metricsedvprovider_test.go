@@ -0,0 +1,222 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package metricsedvprovider
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edv/pkg/edvprovider"
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/metrics"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+const testStoreName = "testStore"
+
+func TestMetricsEDVProvider(t *testing.T) {
+	m := metrics.New()
+	prov := NewProvider(memedvprovider.NewProvider(), m)
+
+	err := prov.CreateStore(testStoreName)
+	require.NoError(t, err)
+
+	store, err := prov.OpenStore(testStoreName)
+	require.NoError(t, err)
+
+	err = store.Put(models.EncryptedDocument{ID: "docID"})
+	require.NoError(t, err)
+
+	err = store.Create(models.EncryptedDocument{ID: "docID2"})
+	require.NoError(t, err)
+
+	err = store.Create(models.EncryptedDocument{ID: "docID2"})
+	require.Equal(t, edverrors.ErrDuplicateDocument, err)
+
+	_, err = store.Get("docID")
+	require.NoError(t, err)
+
+	_, err = store.Get("nonExistentDocID")
+	require.Equal(t, storage.ErrValueNotFound, err)
+
+	err = store.CreateEDVIndex()
+	require.Equal(t, edvprovider.ErrIndexingNotSupported, err)
+
+	_, err = store.Query(&models.Query{})
+	require.Equal(t, memedvprovider.ErrQueryingNotSupported, err)
+
+	_, err = store.Reindex()
+	require.Equal(t, edvprovider.ErrIndexingNotSupported, err)
+
+	_, err = store.Check(false)
+	require.Equal(t, edvprovider.ErrIndexingNotSupported, err)
+
+	list, err := store.ListDocuments("", 10)
+	require.NoError(t, err)
+	require.Len(t, list.DocumentIDs, 2)
+
+	feed, err := store.Changes("", 10)
+	require.NoError(t, err)
+	require.Len(t, feed.Changes, 2)
+
+	writeErrs, err := store.BulkWrite([]edvprovider.DocumentWrite{
+		{Type: edvprovider.WriteDelete, Document: models.EncryptedDocument{ID: "docID"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []error{nil}, writeErrs)
+
+	require.NoError(t, prov.Ping())
+
+	names, err := prov.StoreNames()
+	require.NoError(t, err)
+	require.Equal(t, []string{testStoreName}, names)
+
+	_, err = prov.OpenStore("nonExistentStore")
+	require.Equal(t, storage.ErrStoreNotFound, err)
+
+	require.Equal(t, storage.ErrStoreNotFound, prov.DeleteStore("nonExistentStore"))
+
+	body := scrapeMetrics(t, m)
+	require.Contains(t, body, `edv_provider_call_duration_seconds_count{operation="create_store",result="success"} 1`)
+	require.Contains(t, body, `edv_provider_call_duration_seconds_count{operation="open_store",result="success"} 1`)
+	require.Contains(t, body, `edv_provider_call_duration_seconds_count{operation="open_store",result="error"} 1`)
+	require.Contains(t, body, `edv_provider_call_duration_seconds_count{operation="store_names",result="success"} 1`)
+	require.Contains(t, body, `edv_provider_call_duration_seconds_count{operation="delete_store",result="error"} 1`)
+	require.Contains(t, body, `edv_provider_call_duration_seconds_count{operation="put",result="success"} 1`)
+	require.Contains(t, body, `edv_provider_call_duration_seconds_count{operation="create",result="success"} 1`)
+	require.Contains(t, body, `edv_provider_call_duration_seconds_count{operation="create",result="duplicate"} 1`)
+	require.Contains(t, body, `edv_provider_call_duration_seconds_count{operation="get",result="success"} 1`)
+	require.Contains(t, body, `edv_provider_call_duration_seconds_count{operation="get",result="not_found"} 1`)
+	require.Contains(t, body,
+		`edv_provider_call_duration_seconds_count{operation="create_edv_index",result="error"} 1`)
+	require.Contains(t, body, `edv_provider_call_duration_seconds_count{operation="query",result="error"} 1`)
+	require.Contains(t, body, `edv_provider_call_duration_seconds_count{operation="reindex",result="error"} 1`)
+	require.Contains(t, body, `edv_provider_call_duration_seconds_count{operation="check",result="error"} 1`)
+	require.Contains(t, body,
+		`edv_provider_call_duration_seconds_count{operation="list_documents",result="success"} 1`)
+	require.Contains(t, body, `edv_provider_call_duration_seconds_count{operation="changes",result="success"} 1`)
+	require.Contains(t, body, `edv_provider_call_duration_seconds_count{operation="bulk_write",result="success"} 1`)
+
+	require.NoError(t, prov.Close())
+}
+
+func TestMetricsEDVStore_Put_UniqueConstraintRejection(t *testing.T) {
+	m := metrics.New()
+	store := MetricsEDVStore{store: &mockEDVStore{errPut: edvprovider.ErrIndexNameAndValueCannotBeUnique}, metrics: m}
+
+	err := store.Put(models.EncryptedDocument{})
+	require.Equal(t, edvprovider.ErrIndexNameAndValueCannotBeUnique, err)
+
+	store.store = &mockEDVStore{errPut: errors.New("other error")}
+
+	err = store.Put(models.EncryptedDocument{})
+	require.EqualError(t, err, "other error")
+
+	require.Contains(t, scrapeMetrics(t, m), "edv_provider_unique_constraint_rejections_total 1")
+}
+
+func TestMetricsEDVStore_Create_UniqueConstraintRejection(t *testing.T) {
+	m := metrics.New()
+	store := MetricsEDVStore{
+		store: &mockEDVStore{errPut: edvprovider.ErrIndexNameAndValueAlreadyDeclaredUnique}, metrics: m}
+
+	err := store.Create(models.EncryptedDocument{})
+	require.Equal(t, edvprovider.ErrIndexNameAndValueAlreadyDeclaredUnique, err)
+
+	require.Contains(t, scrapeMetrics(t, m), "edv_provider_unique_constraint_rejections_total 1")
+}
+
+func TestMetricsEDVStore_BulkWrite_UniqueConstraintRejection(t *testing.T) {
+	m := metrics.New()
+	store := MetricsEDVStore{
+		store: &mockEDVStore{errPut: edvprovider.ErrIndexNameAndValueAlreadyDeclaredUnique}, metrics: m}
+
+	writeErrs, err := store.BulkWrite(make([]edvprovider.DocumentWrite, 2))
+	require.NoError(t, err)
+	require.Len(t, writeErrs, 2)
+
+	require.Contains(t, scrapeMetrics(t, m), "edv_provider_unique_constraint_rejections_total 2")
+}
+
+func TestMetricsEDVStore_Query_ResultSize(t *testing.T) {
+	m := metrics.New()
+	store := MetricsEDVStore{store: &mockEDVStore{queryResult: []string{"docID1", "docID2"}}, metrics: m}
+
+	docIDs, err := store.Query(&models.Query{})
+	require.NoError(t, err)
+	require.Len(t, docIDs, 2)
+
+	body := scrapeMetrics(t, m)
+	require.Contains(t, body, "edv_provider_query_result_size_sum 2")
+	require.Contains(t, body, "edv_provider_query_result_size_count 1")
+}
+
+func scrapeMetrics(t *testing.T, m *metrics.Metrics) string {
+	rr := httptest.NewRecorder()
+
+	m.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	return rr.Body.String()
+}
+
+type mockEDVStore struct {
+	errPut      error
+	queryResult []string
+}
+
+func (m *mockEDVStore) Put(models.EncryptedDocument) error {
+	return m.errPut
+}
+
+func (m *mockEDVStore) Create(models.EncryptedDocument) error {
+	return m.errPut
+}
+
+func (m *mockEDVStore) Get(string) ([]byte, error) {
+	return nil, nil
+}
+
+func (m *mockEDVStore) CreateEDVIndex() error {
+	return nil
+}
+
+func (m *mockEDVStore) Query(*models.Query) ([]string, error) {
+	return m.queryResult, nil
+}
+
+func (m *mockEDVStore) Reindex() (*models.ReindexResult, error) {
+	return &models.ReindexResult{}, nil
+}
+
+func (m *mockEDVStore) ListDocuments(string, int) (*models.DocumentList, error) {
+	return &models.DocumentList{}, nil
+}
+
+func (m *mockEDVStore) Check(bool) (*models.ConsistencyReport, error) {
+	return &models.ConsistencyReport{}, nil
+}
+
+func (m *mockEDVStore) Changes(string, int) (*models.ChangeFeed, error) {
+	return &models.ChangeFeed{}, nil
+}
+
+func (m *mockEDVStore) BulkWrite(writes []edvprovider.DocumentWrite) ([]error, error) {
+	writeErrs := make([]error, len(writes))
+
+	for i := range writeErrs {
+		writeErrs[i] = m.errPut
+	}
+
+	return writeErrs, nil
+}
@@ -37,3 +37,34 @@ func (h *HTTPHandler) Method() string {
 func (h *HTTPHandler) Handle() http.HandlerFunc {
 	return h.handle
 }
+
+// NewStatusRecorder returns a StatusRecorder that wraps the given response writer.
+func NewStatusRecorder(rw http.ResponseWriter) *StatusRecorder {
+	return &StatusRecorder{ResponseWriter: rw, status: http.StatusOK}
+}
+
+// StatusRecorder wraps an http.ResponseWriter and records the status code written to it,
+// so middleware can find out how a request was handled.
+type StatusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// Status returns the status code written to the response, which defaults to 200 if none was written explicitly.
+func (s *StatusRecorder) Status() int {
+	return s.status
+}
+
+// WriteHeader records the given status code and writes it to the underlying response writer.
+func (s *StatusRecorder) WriteHeader(statusCode int) {
+	s.status = statusCode
+	s.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Flush flushes the underlying response writer if it supports flushing, which allows streaming handlers
+// to keep working when wrapped.
+func (s *StatusRecorder) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,356 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package jwe
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const compactSerializationParts = 5
+
+// ErrInvalidJWE is returned when a JWE isn't well-formed or isn't allowed by the policy.
+// Errors returned by Validate wrap it with a description of the problem.
+var ErrInvalidJWE = errors.New("invalid jwe")
+
+// DefaultAllowedAlgorithms are the key management algorithms allowed by a policy that doesn't list any.
+// They're the ones used by the EDV specification's examples and by the Aries JWE packers.
+var DefaultAllowedAlgorithms = []string{ //nolint: gochecknoglobals
+	"A256KW", "ECDH-ES+A256KW", "ECDH-1PU+A256KW", "ECDH-ES+XC20PKW", "ECDH-1PU+XC20PKW",
+	// Used by the legacy Aries packers.
+	"Authcrypt", "Anoncrypt",
+}
+
+// DefaultAllowedEncryptions are the content encryption algorithms allowed by a policy that doesn't list any.
+// They're the ones used by the EDV specification's examples and by the Aries JWE packers.
+var DefaultAllowedEncryptions = []string{ //nolint: gochecknoglobals
+	"A256GCM", "C20P", "XC20P", "A128CBC-HS256", "A192CBC-HS384", "A256CBC-HS512",
+	// Used by the legacy Aries packers.
+	"chacha20poly1305_ietf", "xchacha20poly1305_ietf",
+}
+
+// Policy restricts the algorithms that can be used in a JWE.
+// An empty list allows the default algorithms.
+type Policy struct {
+	// AllowedAlgorithms is the list of allowed key management algorithms ("alg" header values).
+	AllowedAlgorithms []string
+	// AllowedEncryptions is the list of allowed content encryption algorithms ("enc" header values).
+	AllowedEncryptions []string
+}
+
+// jsonJWE represents a JWE in the general or flattened JSON serialization.
+type jsonJWE struct {
+	Protected    *string         `json:"protected"`
+	Unprotected  json.RawMessage `json:"unprotected"`
+	Recipients   json.RawMessage `json:"recipients"`
+	Header       json.RawMessage `json:"header"`
+	EncryptedKey *string         `json:"encrypted_key"`
+	AAD          *string         `json:"aad"`
+	IV           *string         `json:"iv"`
+	Ciphertext   *string         `json:"ciphertext"`
+	Tag          *string         `json:"tag"`
+}
+
+type recipient struct {
+	Header       json.RawMessage `json:"header"`
+	EncryptedKey *string         `json:"encrypted_key"`
+}
+
+type header map[string]interface{}
+
+// parsedHeaders holds the header parameters of a JWE. The shared header combines the protected and unprotected
+// headers, while each recipient header also includes the parameters that apply only to that recipient.
+type parsedHeaders struct {
+	shared     header
+	recipients []header
+}
+
+// Validate checks that the given JWE is well-formed and uses algorithms allowed by the policy.
+// The JWE can be either a JSON serialization (general or flattened) or a compact serialization in a JSON string.
+func Validate(rawJWE json.RawMessage, policy Policy) error {
+	headers, err := parse(rawJWE)
+	if err != nil {
+		return err
+	}
+
+	if err := checkEncryption(headers.shared, policy); err != nil {
+		return err
+	}
+
+	for _, recipientHeader := range headers.recipients {
+		if err := checkAlgorithm(recipientHeader, policy); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RecipientKeyIDs returns the "kid" header parameter that applies to each recipient of the given JWE.
+// The key ID is blank for recipients that don't have one.
+func RecipientKeyIDs(rawJWE json.RawMessage) ([]string, error) {
+	headers, err := parse(rawJWE)
+	if err != nil {
+		return nil, err
+	}
+
+	keyIDs := make([]string, len(headers.recipients))
+
+	for i, recipientHeader := range headers.recipients {
+		keyIDs[i], _ = recipientHeader["kid"].(string) //nolint: errcheck
+	}
+
+	return keyIDs, nil
+}
+
+func parse(rawJWE json.RawMessage) (*parsedHeaders, error) {
+	trimmedJWE := bytes.TrimSpace(rawJWE)
+
+	if len(trimmedJWE) == 0 {
+		return nil, fmt.Errorf("%w: jwe is empty", ErrInvalidJWE)
+	}
+
+	switch trimmedJWE[0] {
+	case '{':
+		return parseJSONSerialization(trimmedJWE)
+	case '"':
+		var compactJWE string
+
+		if err := json.Unmarshal(trimmedJWE, &compactJWE); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidJWE, err.Error())
+		}
+
+		return parseCompactSerialization(compactJWE)
+	default:
+		return nil, fmt.Errorf("%w: must be a JSON object or a compact serialization string", ErrInvalidJWE)
+	}
+}
+
+func parseCompactSerialization(compactJWE string) (*parsedHeaders, error) {
+	parts := strings.Split(compactJWE, ".")
+	if len(parts) != compactSerializationParts {
+		return nil, fmt.Errorf("%w: compact serialization must have %d parts but has %d",
+			ErrInvalidJWE, compactSerializationParts, len(parts))
+	}
+
+	protectedHeader, err := decodeProtectedHeader(parts[0])
+	if err != nil {
+		return nil, err
+	}
+
+	fieldNames := []string{"encrypted key", "iv", "ciphertext", "tag"}
+
+	for i, fieldName := range fieldNames {
+		if _, decodeErr := decodeBase64URL(parts[i+1]); decodeErr != nil {
+			return nil, fmt.Errorf("%w: %s isn't valid base64url", ErrInvalidJWE, fieldName)
+		}
+	}
+
+	if parts[3] == "" {
+		return nil, fmt.Errorf("%w: ciphertext is required", ErrInvalidJWE)
+	}
+
+	return &parsedHeaders{shared: protectedHeader, recipients: []header{protectedHeader}}, nil
+}
+
+func parseJSONSerialization(rawJWE []byte) (*parsedHeaders, error) {
+	var parsedJWE jsonJWE
+
+	if err := json.Unmarshal(rawJWE, &parsedJWE); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidJWE, err.Error())
+	}
+
+	if err := checkEncodedFields(&parsedJWE); err != nil {
+		return nil, err
+	}
+
+	sharedHeader := header{}
+
+	if parsedJWE.Protected != nil {
+		protectedHeader, err := decodeProtectedHeader(*parsedJWE.Protected)
+		if err != nil {
+			return nil, err
+		}
+
+		sharedHeader = protectedHeader
+	}
+
+	if err := mergeHeader(sharedHeader, parsedJWE.Unprotected, "unprotected header"); err != nil {
+		return nil, err
+	}
+
+	recipients, err := getRecipients(&parsedJWE)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := &parsedHeaders{shared: sharedHeader, recipients: make([]header, len(recipients))}
+
+	for i, r := range recipients {
+		headers.recipients[i], err = parseRecipient(i, r, sharedHeader)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return headers, nil
+}
+
+// parseRecipient returns the header parameters that apply to the given recipient.
+func parseRecipient(index int, r recipient, sharedHeader header) (header, error) {
+	recipientHeader := header{}
+
+	for name, value := range sharedHeader {
+		recipientHeader[name] = value
+	}
+
+	if err := mergeHeader(recipientHeader, r.Header, fmt.Sprintf("recipient %d header", index)); err != nil {
+		return nil, err
+	}
+
+	if r.EncryptedKey != nil {
+		if _, err := decodeBase64URL(*r.EncryptedKey); err != nil {
+			return nil, fmt.Errorf("%w: recipient %d encrypted_key isn't valid base64url", ErrInvalidJWE, index)
+		}
+	}
+
+	return recipientHeader, nil
+}
+
+// checkEncodedFields checks that the ciphertext is present and that all of the base64url-encoded fields
+// shared by the recipients can be decoded.
+func checkEncodedFields(parsedJWE *jsonJWE) error {
+	if parsedJWE.Ciphertext == nil || *parsedJWE.Ciphertext == "" {
+		return fmt.Errorf("%w: ciphertext is required", ErrInvalidJWE)
+	}
+
+	encodedFields := []struct {
+		name  string
+		value *string
+	}{
+		{"aad", parsedJWE.AAD},
+		{"iv", parsedJWE.IV},
+		{"ciphertext", parsedJWE.Ciphertext},
+		{"tag", parsedJWE.Tag},
+	}
+
+	for _, field := range encodedFields {
+		if field.value == nil {
+			continue
+		}
+
+		if _, err := decodeBase64URL(*field.value); err != nil {
+			return fmt.Errorf("%w: %s isn't valid base64url", ErrInvalidJWE, field.name)
+		}
+	}
+
+	return nil
+}
+
+// getRecipients returns the recipients of a general JSON serialization, or the single recipient of a flattened one.
+func getRecipients(parsedJWE *jsonJWE) ([]recipient, error) {
+	if parsedJWE.Recipients == nil {
+		return []recipient{{Header: parsedJWE.Header, EncryptedKey: parsedJWE.EncryptedKey}}, nil
+	}
+
+	if parsedJWE.Header != nil || parsedJWE.EncryptedKey != nil {
+		return nil, fmt.Errorf("%w: recipients can't be used together with header or encrypted_key", ErrInvalidJWE)
+	}
+
+	var recipients []recipient
+
+	if err := json.Unmarshal(parsedJWE.Recipients, &recipients); err != nil {
+		return nil, fmt.Errorf("%w: recipients must be an array of objects", ErrInvalidJWE)
+	}
+
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("%w: recipients can't be empty", ErrInvalidJWE)
+	}
+
+	return recipients, nil
+}
+
+func decodeProtectedHeader(encodedHeader string) (header, error) {
+	headerBytes, err := decodeBase64URL(encodedHeader)
+	if err != nil {
+		return nil, fmt.Errorf("%w: protected header isn't valid base64url", ErrInvalidJWE)
+	}
+
+	protectedHeader := header{}
+
+	if err := json.Unmarshal(headerBytes, &protectedHeader); err != nil || protectedHeader == nil {
+		return nil, fmt.Errorf("%w: protected header isn't a JSON object", ErrInvalidJWE)
+	}
+
+	return protectedHeader, nil
+}
+
+// mergeHeader adds the parameters of the given raw header to the target header.
+// A header parameter can't appear in more than one of the headers that apply to a recipient.
+func mergeHeader(target header, rawHeader json.RawMessage, name string) error {
+	if rawHeader == nil {
+		return nil
+	}
+
+	parsedHeader := header{}
+
+	if err := json.Unmarshal(rawHeader, &parsedHeader); err != nil || parsedHeader == nil {
+		return fmt.Errorf("%w: %s isn't a JSON object", ErrInvalidJWE, name)
+	}
+
+	for parameterName, value := range parsedHeader {
+		if _, exists := target[parameterName]; exists {
+			return fmt.Errorf("%w: %s parameter %q is already set in another header", ErrInvalidJWE, name, parameterName)
+		}
+
+		target[parameterName] = value
+	}
+
+	return nil
+}
+
+func checkAlgorithm(h header, policy Policy) error {
+	allowedAlgorithms := policy.AllowedAlgorithms
+	if len(allowedAlgorithms) == 0 {
+		allowedAlgorithms = DefaultAllowedAlgorithms
+	}
+
+	return checkHeaderValue(h, "alg", allowedAlgorithms)
+}
+
+func checkEncryption(h header, policy Policy) error {
+	allowedEncryptions := policy.AllowedEncryptions
+	if len(allowedEncryptions) == 0 {
+		allowedEncryptions = DefaultAllowedEncryptions
+	}
+
+	return checkHeaderValue(h, "enc", allowedEncryptions)
+}
+
+func checkHeaderValue(h header, parameterName string, allowedValues []string) error {
+	value, ok := h[parameterName].(string)
+	if !ok || value == "" {
+		return fmt.Errorf("%w: %q header parameter is required", ErrInvalidJWE, parameterName)
+	}
+
+	for _, allowedValue := range allowedValues {
+		if value == allowedValue {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %q header parameter value %q isn't allowed", ErrInvalidJWE, parameterName, value)
+}
+
+// decodeBase64URL decodes base64url data, with or without padding.
+func decodeBase64URL(data string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(data, "="))
+}
@@ -0,0 +1,155 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package jwe
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// Protected header: {"enc":"C20P"}
+	testGeneralJWE = `{"protected":"eyJlbmMiOiJDMjBQIn0",` +
+		`"recipients":[{"header":{"alg":"A256KW","kid":"https://example.com/kms/z7BgF536GaR"},"encrypted_key"` +
+		`:"OR1vdCNvf_B68mfUxFQVT-vyXVrBembuiM40mAAjDC1-Qu5iArDbug"}],"iv":"i8Nins2vTI3PlrYW","ciphertext"` +
+		`:"Cb-963UCXblINT8F6MDHzMJN9EAhK3I","tag":"pfZO0JulJcrc3trOZy8rjA"}`
+
+	testFlattenedJWE = `{"protected":"eyJlbmMiOiJDMjBQIn0","header":{"alg":"A256KW"},` +
+		`"encrypted_key":"OR1vdCNvf_B68mfUxFQVT-vyXVrBembuiM40mAAjDC1-Qu5iArDbug","iv":"i8Nins2vTI3PlrYW",` +
+		`"ciphertext":"Cb-963UCXblINT8F6MDHzMJN9EAhK3I","tag":"pfZO0JulJcrc3trOZy8rjA"}`
+
+	// Protected header: {"alg":"RSA-OAEP","enc":"A256GCM"}
+	testCompactJWE = `"eyJhbGciOiJSU0EtT0FFUCIsImVuYyI6IkEyNTZHQ00ifQ.OKOawDo13gRp2ojaHV7LFpZcgV7T6DVZKTyKOMTYUmKoTCVJRg` +
+		`ckCL9kiMT03JGeipsEdY3mx_etLbbWSrFr05kLzcSr4qKAq7YN7e9jwQRb23nfa6c9d-StnImGyFDbSv04uVuxIp5Zms1gNxKKK2Da14B8S4r` +
+		`zVRltdYwam_lDp5XnZAYpQdb76FdIKLaVmqgfwX7XWRxv2322i-vDxRfqNzo_tETKzpVLzfiwQyeyPGLBIO56YJ7eObdv0je81860ppamavo` +
+		`35UgoRdbYaBcoh9QcfylQr66oc6vFWXRcZ_ZT2LawVCWTIy3brGPi6UklfCpIMfIjf7iGdXKHzg.48V1_ALb6US04U3b.5eym8TW_c8SuK0l` +
+		`tJ3rpYIzOeDQz7TALvtu6UG9oMo4vpzs9tX_EFShS8iB7j6jiSdiwkIr3ajwQzaBtQD_A.XFBoMYUZodetZdvTiFvSkQ"`
+)
+
+func TestValidate(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		for _, validJWE := range []string{testGeneralJWE, testFlattenedJWE} {
+			require.NoError(t, Validate([]byte(validJWE), Policy{}))
+		}
+	})
+	t.Run("Algorithms not allowed by default", func(t *testing.T) {
+		protectedHeader := func(header string) string {
+			return base64.RawURLEncoding.EncodeToString([]byte(header))
+		}
+
+		err := Validate([]byte(testCompactJWE), Policy{})
+		requireInvalidJWE(t, err, `"alg" header parameter value "RSA-OAEP" isn't allowed`)
+
+		err = Validate([]byte(`"`+protectedHeader(`{"alg":"none","enc":"A256GCM"}`)+`..AA.AA.AA"`), Policy{})
+		requireInvalidJWE(t, err, `"alg" header parameter value "none" isn't allowed`)
+
+		err = Validate([]byte(`"`+protectedHeader(`{"alg":"ECDH-ES+A256KW","enc":"unknown"}`)+`..AA.AA.AA"`),
+			Policy{})
+		requireInvalidJWE(t, err, `"enc" header parameter value "unknown" isn't allowed`)
+
+		// Not a registered enc value, unlike A192CBC-HS384 and A256CBC-HS512.
+		err = Validate([]byte(`"`+protectedHeader(`{"alg":"ECDH-ES+A256KW","enc":"A256CBC-HS384"}`)+`..AA.AA.AA"`),
+			Policy{})
+		requireInvalidJWE(t, err, `"enc" header parameter value "A256CBC-HS384" isn't allowed`)
+	})
+	t.Run("Algorithms allowed by policy", func(t *testing.T) {
+		policy := Policy{AllowedAlgorithms: []string{"A256KW", "RSA-OAEP"}, AllowedEncryptions: []string{"C20P", "A256GCM"}}
+
+		for _, validJWE := range []string{testGeneralJWE, testFlattenedJWE, testCompactJWE} {
+			require.NoError(t, Validate([]byte(validJWE), policy))
+		}
+	})
+	t.Run("Algorithm not allowed by policy", func(t *testing.T) {
+		err := Validate([]byte(testGeneralJWE), Policy{AllowedAlgorithms: []string{"ECDH-ES+A256KW"}})
+		requireInvalidJWE(t, err, `"alg" header parameter value "A256KW" isn't allowed`)
+
+		err = Validate([]byte(testCompactJWE), Policy{AllowedEncryptions: []string{"XC20P"}})
+		requireInvalidJWE(t, err, `"enc" header parameter value "A256GCM" isn't allowed`)
+	})
+	t.Run("Malformed JWEs", func(t *testing.T) {
+		protectedHeader := func(header string) string {
+			return base64.RawURLEncoding.EncodeToString([]byte(header))
+		}
+
+		testCases := []struct {
+			jwe         string
+			errContains string
+		}{
+			{``, "jwe is empty"},
+			{`null`, "must be a JSON object or a compact serialization string"},
+			{`[]`, "must be a JSON object or a compact serialization string"},
+			{`{"protected":1}`, "cannot unmarshal"},
+			{`{"protected":"eyJlbmMiOiJDMjBQIn0","header":{"alg":"A256KW"}}`, "ciphertext is required"},
+			{`{"protected":"not base64!","header":{"alg":"A256KW"},"ciphertext":"AA"}`,
+				"protected header isn't valid base64url"},
+			{`{"protected":"` + protectedHeader(`garbage`) + `","ciphertext":"AA"}`,
+				"protected header isn't a JSON object"},
+			{`{"protected":"eyJlbmMiOiJDMjBQIn0","header":{"alg":"A256KW"},"ciphertext":"AA","iv":"%%"}`,
+				"iv isn't valid base64url"},
+			{`{"protected":"` + protectedHeader(`{"alg":"A256KW"}`) + `","ciphertext":"AA"}`,
+				`"enc" header parameter is required`},
+			{`{"protected":"eyJlbmMiOiJDMjBQIn0","ciphertext":"AA"}`, `"alg" header parameter is required`},
+			{`{"protected":"eyJlbmMiOiJDMjBQIn0","unprotected":[],"ciphertext":"AA"}`,
+				"unprotected header isn't a JSON object"},
+			{`{"protected":"eyJlbmMiOiJDMjBQIn0","unprotected":{"enc":"A256GCM"},"ciphertext":"AA"}`,
+				`parameter "enc" is already set in another header`},
+			{`{"protected":"eyJlbmMiOiJDMjBQIn0","recipients":[],"ciphertext":"AA"}`, "recipients can't be empty"},
+			{`{"protected":"eyJlbmMiOiJDMjBQIn0","recipients":{},"ciphertext":"AA"}`,
+				"recipients must be an array of objects"},
+			{`{"protected":"eyJlbmMiOiJDMjBQIn0","recipients":[{"header":{"alg":"A256KW"}}],` +
+				`"encrypted_key":"AA","ciphertext":"AA"}`, "recipients can't be used together with header"},
+			{`{"protected":"eyJlbmMiOiJDMjBQIn0","recipients":[{"header":"A256KW"}],"ciphertext":"AA"}`,
+				"recipient 0 header isn't a JSON object"},
+			{`{"protected":"eyJlbmMiOiJDMjBQIn0","recipients":[{"header":{"alg":"A256KW"},` +
+				`"encrypted_key":"%%"}],"ciphertext":"AA"}`, "recipient 0 encrypted_key isn't valid base64url"},
+			{`{"protected":"eyJlbmMiOiJDMjBQIn0","recipients":[{"header":{"alg":"A256KW"}},{"header":{}}],` +
+				`"ciphertext":"AA"}`, `"alg" header parameter is required`},
+			{`"a.b.c"`, "compact serialization must have 5 parts but has 3"},
+			{`"` + protectedHeader(`{"alg":"dir","enc":"A256GCM"}`) + `..AA..AA"`, "ciphertext is required"},
+			{`"` + protectedHeader(`{"alg":"dir","enc":"A256GCM"}`) + `..%%.AA.AA"`, "iv isn't valid base64url"},
+			{`"` + protectedHeader(`{"enc":"A256GCM"}`) + `..AA.AA.AA"`, `"alg" header parameter is required`},
+			{`"%%.AA.AA.AA.AA"`, "protected header isn't valid base64url"},
+			{`"\q"`, "in string"},
+		}
+
+		for _, tc := range testCases {
+			err := Validate([]byte(tc.jwe), Policy{})
+			requireInvalidJWE(t, err, tc.errContains)
+		}
+	})
+}
+
+func TestRecipientKeyIDs(t *testing.T) {
+	keyIDs, err := RecipientKeyIDs([]byte(testGeneralJWE))
+	require.NoError(t, err)
+	require.Equal(t, []string{"https://example.com/kms/z7BgF536GaR"}, keyIDs)
+
+	keyIDs, err = RecipientKeyIDs([]byte(testFlattenedJWE))
+	require.NoError(t, err)
+	require.Equal(t, []string{""}, keyIDs)
+
+	// Protected header: {"enc":"C20P","kid":"key1"}
+	keyIDs, err = RecipientKeyIDs([]byte(`{"protected":"eyJlbmMiOiJDMjBQIiwia2lkIjoia2V5MSJ9",` +
+		`"recipients":[{"header":{"alg":"A256KW"}},{"header":{"alg":"A256KW"}}],"ciphertext":"AA"}`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"key1", "key1"}, keyIDs)
+
+	keyIDs, err = RecipientKeyIDs([]byte(`{}`))
+	require.Error(t, err)
+	require.Nil(t, keyIDs)
+}
+
+func requireInvalidJWE(t *testing.T, err error, errContains string) {
+	t.Helper()
+
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ErrInvalidJWE))
+	require.Contains(t, err.Error(), errContains)
+}
@@ -0,0 +1,133 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+)
+
+const (
+	namespace = "edv"
+
+	routeLabel     = "route"
+	operationLabel = "operation"
+	resultLabel    = "result"
+
+	resultSuccess   = "success"
+	resultNotFound  = "not_found"
+	resultDuplicate = "duplicate"
+	resultError     = "error"
+)
+
+// Metrics holds the Prometheus collectors for the EDV server's REST operations and provider calls.
+// Each instance uses its own registry, so multiple instances can coexist (e.g. in tests).
+type Metrics struct {
+	registry                   *prometheus.Registry
+	requests                   *prometheus.CounterVec
+	requestDuration            *prometheus.HistogramVec
+	providerCallDuration       *prometheus.HistogramVec
+	queryResultSize            prometheus.Histogram
+	uniqueConstraintRejections prometheus.Counter
+}
+
+// New returns a new Metrics instance with all of its collectors registered.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Number of HTTP requests handled, partitioned by route, method and status code.",
+		}, []string{routeLabel, "method", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of HTTP requests, partitioned by route, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{routeLabel, "method", "code"}),
+		providerCallDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "provider",
+			Name:      "call_duration_seconds",
+			Help:      "Latency of EDV provider calls, partitioned by operation and result.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{operationLabel, resultLabel}),
+		queryResultSize: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "provider",
+			Name:      "query_result_size",
+			Help:      "Number of document IDs returned by encrypted index queries.",
+			Buckets:   prometheus.ExponentialBuckets(1, 4, 8),
+		}),
+		uniqueConstraintRejections: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "provider",
+			Name:      "unique_constraint_rejections_total",
+			Help:      "Number of documents rejected because they violated a unique index name+value constraint.",
+		}),
+	}
+
+	m.registry.MustRegister(m.requests, m.requestDuration, m.providerCallDuration, m.queryResultSize,
+		m.uniqueConstraintRejections, prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+
+	return m
+}
+
+// Handler returns an HTTP handler that exposes the collected metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// InstrumentHandler wraps the given handler so that a request counter and a latency histogram
+// are recorded for it. The route should be the path template (not the actual request path) to keep the
+// number of distinct label values bounded.
+func (m *Metrics) InstrumentHandler(route string, handler http.HandlerFunc) http.HandlerFunc {
+	routeLabels := prometheus.Labels{routeLabel: route}
+
+	return promhttp.InstrumentHandlerCounter(m.requests.MustCurryWith(routeLabels),
+		promhttp.InstrumentHandlerDuration(m.requestDuration.MustCurryWith(routeLabels), handler))
+}
+
+// ObserveProviderCall records the duration and result of an EDV provider call that started at the given time.
+// Lookups of values that don't exist and attempts to create documents that already exist are recorded separately
+// from other errors since they're expected outcomes.
+func (m *Metrics) ObserveProviderCall(operation string, start time.Time, err error) {
+	var result string
+
+	switch err {
+	case nil:
+		result = resultSuccess
+	case storage.ErrValueNotFound:
+		result = resultNotFound
+	case edverrors.ErrDuplicateDocument:
+		result = resultDuplicate
+	default:
+		result = resultError
+	}
+
+	m.providerCallDuration.WithLabelValues(operation, result).Observe(time.Since(start).Seconds())
+}
+
+// ObserveQueryResultSize records the number of document IDs returned by an encrypted index query.
+func (m *Metrics) ObserveQueryResultSize(size int) {
+	m.queryResultSize.Observe(float64(size))
+}
+
+// IncUniqueConstraintRejections increments the count of documents rejected due to unique index constraints.
+func (m *Metrics) IncUniqueConstraintRejections() {
+	m.uniqueConstraintRejections.Inc()
+}
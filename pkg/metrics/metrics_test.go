@@ -0,0 +1,72 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage"
+)
+
+func TestMetrics_InstrumentHandler(t *testing.T) {
+	m := New()
+
+	handler := m.InstrumentHandler("/encrypted-data-vaults/{vaultID}/documents",
+		func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusCreated)
+		})
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost,
+		"/encrypted-data-vaults/vault1/documents", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost,
+		"/encrypted-data-vaults/vault2/documents", nil))
+
+	require.Equal(t, float64(2), testutil.ToFloat64(
+		m.requests.WithLabelValues("/encrypted-data-vaults/{vaultID}/documents", "post", "201")))
+	require.Equal(t, 1, testutil.CollectAndCount(m.requestDuration))
+}
+
+func TestMetrics_ObserveProviderCall(t *testing.T) {
+	m := New()
+
+	m.ObserveProviderCall("get", time.Now(), nil)
+	m.ObserveProviderCall("get", time.Now(), storage.ErrValueNotFound)
+	m.ObserveProviderCall("put", time.Now(), errors.New("put failure"))
+
+	require.Equal(t, 3, testutil.CollectAndCount(m.providerCallDuration))
+}
+
+func TestMetrics_ProviderCounters(t *testing.T) {
+	m := New()
+
+	m.ObserveQueryResultSize(3)
+	m.IncUniqueConstraintRejections()
+	m.IncUniqueConstraintRejections()
+
+	require.Equal(t, 1, testutil.CollectAndCount(m.queryResultSize))
+	require.Equal(t, float64(2), testutil.ToFloat64(m.uniqueConstraintRejections))
+}
+
+func TestMetrics_Handler(t *testing.T) {
+	m := New()
+
+	m.IncUniqueConstraintRejections()
+
+	rr := httptest.NewRecorder()
+
+	m.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Contains(t, rr.Body.String(), "edv_provider_unique_constraint_rejections_total 1")
+	require.Contains(t, rr.Body.String(), "go_goroutines")
+}
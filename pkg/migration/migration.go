@@ -0,0 +1,463 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package migration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edv/pkg/edvprovider"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+	"github.com/trustbloc/edv/pkg/restapi/edv/operation"
+	"github.com/trustbloc/edv/pkg/vaultexport"
+)
+
+// DefaultBatchSize is the number of documents that are read and written at a time, unless set with WithBatchSize.
+const DefaultBatchSize = 100
+
+// errDifferentDocument is the failure recorded for a document that's already in the destination vault
+// with other contents.
+var errDifferentDocument = errors.New("a different document with the same ID is already in the destination vault")
+
+// Endpoint is the source or destination of a migration: the EDV provider that holds the vaults' documents and
+// the storage provider that holds the EDV server's vault configurations and usage.
+type Endpoint struct {
+	EDVProvider     edvprovider.EDVProvider
+	StorageProvider storage.Provider
+}
+
+// Migrator copies every vault, along with its configuration and documents, from one EDV server's database
+// to another's. Documents that are already in the destination with the same contents are skipped, so a migration
+// that was interrupted can be resumed by running it again.
+type Migrator struct {
+	source      Endpoint
+	destination Endpoint
+	dryRun      bool
+	batchSize   int
+	progress    io.Writer
+}
+
+// Option configures a Migrator.
+type Option func(m *Migrator)
+
+// WithDryRun makes the Migrator read and check every vault in the source without writing anything
+// to the destination.
+func WithDryRun(dryRun bool) Option {
+	return func(m *Migrator) {
+		m.dryRun = dryRun
+	}
+}
+
+// WithBatchSize sets the number of documents that are read and written at a time.
+func WithBatchSize(batchSize int) Option {
+	return func(m *Migrator) {
+		m.batchSize = batchSize
+	}
+}
+
+// WithProgress makes the Migrator write a line to the given writer after each batch of documents.
+func WithProgress(progress io.Writer) Option {
+	return func(m *Migrator) {
+		m.progress = progress
+	}
+}
+
+// New returns a new Migrator that copies vaults from the source to the destination.
+func New(source, destination Endpoint, opts ...Option) *Migrator {
+	m := &Migrator{
+		source:      source,
+		destination: destination,
+		batchSize:   DefaultBatchSize,
+		progress:    ioutil.Discard,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// Report is the outcome of a migration.
+type Report struct {
+	Vaults []VaultReport
+}
+
+// Verified returns whether every vault was copied in full and matches its source.
+func (r *Report) Verified() bool {
+	for i := range r.Vaults {
+		if !r.Vaults[i].Verified() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// VaultReport is the outcome of migrating a single vault.
+type VaultReport struct {
+	ID string
+	// Documents is the number of documents in the source vault.
+	Documents     int
+	Copied        int
+	AlreadyCopied int
+	Failed        []DocumentFailure
+	// Verification is nil for dry runs, since nothing is written to the destination.
+	Verification *Verification
+}
+
+// Verified returns whether every document in the vault was copied and matches its source.
+// Dry runs are verified as long as every document in the source could be read.
+func (v *VaultReport) Verified() bool {
+	if len(v.Failed) > 0 {
+		return false
+	}
+
+	if v.Verification == nil {
+		return true
+	}
+
+	return v.Verification.Documents == v.Documents && len(v.Verification.Mismatched) == 0
+}
+
+// DocumentFailure is a document that couldn't be read from the source or written to the destination.
+type DocumentFailure struct {
+	ID  string
+	Err error
+}
+
+// Verification is what was found in a destination vault after its documents were copied.
+type Verification struct {
+	// Documents is the number of documents in the destination vault.
+	Documents int
+	// Mismatched has the IDs of the source vault's documents that are missing from the destination vault
+	// or whose hashes differ there, sorted.
+	Mismatched []string
+}
+
+// Migrate copies every vault in the source to the destination, then checks that each destination vault has the same
+// number of documents as its source and that every document's hash matches. The EDV server's own stores, such as
+// the audit log and webhook subscriptions, aren't vaults and aren't copied.
+func (m *Migrator) Migrate() (*Report, error) {
+	storeNames, err := m.source.EDVProvider.StoreNames()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the source vaults: %w", err)
+	}
+
+	report := &Report{}
+
+	for _, storeName := range storeNames {
+		if operation.IsInternalStoreName(storeName) {
+			continue
+		}
+
+		vaultReport, migrateErr := m.migrateVault(storeName)
+		if migrateErr != nil {
+			return report, fmt.Errorf("failed to migrate vault %s: %w", storeName, migrateErr)
+		}
+
+		report.Vaults = append(report.Vaults, *vaultReport)
+	}
+
+	return report, nil
+}
+
+func (m *Migrator) migrateVault(vaultID string) (*VaultReport, error) {
+	sourceStore, err := m.source.EDVProvider.OpenStore(vaultID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the source vault: %w", err)
+	}
+
+	documentIDs, err := edvprovider.DocumentIDs(sourceStore)
+	if err != nil {
+		return nil, err
+	}
+
+	var destinationStore edvprovider.EDVStore
+
+	if !m.dryRun {
+		destinationStore, err = m.createDestinationVault(vaultID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	report := &VaultReport{ID: vaultID}
+	checksums := make(map[string]string)
+
+	for start := 0; start < len(documentIDs); start += m.batchSize {
+		end := start + m.batchSize
+		if end > len(documentIDs) {
+			end = len(documentIDs)
+		}
+
+		err = m.migrateBatch(sourceStore, destinationStore, documentIDs[start:end], checksums, report)
+		if err != nil {
+			return nil, err
+		}
+
+		m.reportProgress(report, end, len(documentIDs))
+	}
+
+	if m.dryRun {
+		return report, nil
+	}
+
+	err = m.copyVaultRecord(vaultID)
+	if err != nil {
+		return nil, err
+	}
+
+	report.Verification, err = verify(destinationStore, checksums)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify the destination vault: %w", err)
+	}
+
+	return report, nil
+}
+
+// createDestinationVault creates the destination vault the same way the EDV server does, unless it already exists.
+func (m *Migrator) createDestinationVault(vaultID string) (edvprovider.EDVStore, error) {
+	err := m.destination.EDVProvider.CreateStore(vaultID)
+	if err != nil && err != storage.ErrDuplicateStore {
+		return nil, fmt.Errorf("failed to create the destination vault: %w", err)
+	}
+
+	store, err := m.destination.EDVProvider.OpenStore(vaultID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the destination vault: %w", err)
+	}
+
+	err = store.CreateEDVIndex()
+	if err != nil && err != edvprovider.ErrIndexingNotSupported {
+		return nil, fmt.Errorf("failed to create the destination vault's index: %w", err)
+	}
+
+	return store, nil
+}
+
+func (m *Migrator) migrateBatch(sourceStore, destinationStore edvprovider.EDVStore, documentIDs []string,
+	checksums map[string]string, report *VaultReport) error {
+	documents, err := readDocuments(sourceStore, documentIDs, report)
+	if err != nil {
+		return err
+	}
+
+	for i := range documents {
+		checksum, checksumErr := vaultexport.DocumentChecksum(&documents[i])
+		if checksumErr != nil {
+			return checksumErr
+		}
+
+		checksums[documents[i].ID] = checksum
+	}
+
+	if m.dryRun || len(documents) == 0 {
+		return nil
+	}
+
+	return copyDocuments(destinationStore, documents, checksums, report)
+}
+
+// readDocuments reads the given documents from the source vault. Documents that were deleted are left out,
+// and ones that can't be parsed are recorded as failures.
+func readDocuments(store edvprovider.EDVStore, documentIDs []string,
+	report *VaultReport) ([]models.EncryptedDocument, error) {
+	var documents []models.EncryptedDocument
+
+	for _, docID := range documentIDs {
+		documentBytes, err := store.Get(docID)
+		if err == storage.ErrValueNotFound {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read document %s: %w", docID, err)
+		}
+
+		report.Documents++
+
+		document := models.EncryptedDocument{}
+
+		err = json.Unmarshal(documentBytes, &document)
+		if err != nil {
+			report.Failed = append(report.Failed,
+				DocumentFailure{ID: docID, Err: fmt.Errorf("failed to parse document: %w", err)})
+
+			continue
+		}
+
+		documents = append(documents, document)
+	}
+
+	return documents, nil
+}
+
+// copyDocuments creates the given documents in the destination vault. Documents that are already there with
+// the same contents were copied by an earlier run.
+func copyDocuments(store edvprovider.EDVStore, documents []models.EncryptedDocument, checksums map[string]string,
+	report *VaultReport) error {
+	writes := make([]edvprovider.DocumentWrite, len(documents))
+
+	for i := range documents {
+		writes[i] = edvprovider.DocumentWrite{Type: edvprovider.WriteCreate, Document: documents[i]}
+	}
+
+	writeErrs, err := store.BulkWrite(writes)
+	if err != nil {
+		return fmt.Errorf("failed to write documents: %w", err)
+	}
+
+	for i, writeErr := range writeErrs {
+		docID := documents[i].ID
+
+		switch {
+		case writeErr == nil:
+			report.Copied++
+		case errors.Is(writeErr, edverrors.ErrDuplicateDocument):
+			checksum, readErr := readChecksum(store, docID)
+
+			switch {
+			case readErr != nil:
+				report.Failed = append(report.Failed, DocumentFailure{ID: docID, Err: readErr})
+			case checksum != checksums[docID]:
+				report.Failed = append(report.Failed, DocumentFailure{ID: docID, Err: errDifferentDocument})
+			default:
+				report.AlreadyCopied++
+			}
+		default:
+			report.Failed = append(report.Failed, DocumentFailure{ID: docID, Err: writeErr})
+		}
+	}
+
+	return nil
+}
+
+// copyVaultRecord copies the vault's configuration and usage as is. Vaults created before configurations were
+// stored may not have one.
+func (m *Migrator) copyVaultRecord(vaultID string) error {
+	sourceStore, err := m.source.StorageProvider.OpenStore(operation.VaultConfigurationStoreName)
+	if err == storage.ErrStoreNotFound {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to open the source vault configuration store: %w", err)
+	}
+
+	recordBytes, err := sourceStore.Get(vaultID)
+	if err == storage.ErrValueNotFound {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to read the vault configuration: %w", err)
+	}
+
+	err = m.destination.StorageProvider.CreateStore(operation.VaultConfigurationStoreName)
+	if err != nil && err != storage.ErrDuplicateStore {
+		return fmt.Errorf("failed to create the destination vault configuration store: %w", err)
+	}
+
+	destinationStore, err := m.destination.StorageProvider.OpenStore(operation.VaultConfigurationStoreName)
+	if err != nil {
+		return fmt.Errorf("failed to open the destination vault configuration store: %w", err)
+	}
+
+	err = destinationStore.Put(vaultID, recordBytes)
+	if err != nil {
+		return fmt.Errorf("failed to write the vault configuration: %w", err)
+	}
+
+	return nil
+}
+
+// verify counts the documents in the destination vault and compares their hashes with the source's.
+func verify(store edvprovider.EDVStore, checksums map[string]string) (*Verification, error) {
+	documentIDs, err := edvprovider.DocumentIDs(store)
+	if err != nil {
+		return nil, err
+	}
+
+	verification := &Verification{}
+	matched := make(map[string]struct{})
+
+	for _, docID := range documentIDs {
+		checksum, readErr := readChecksum(store, docID)
+		if readErr == storage.ErrValueNotFound {
+			continue
+		}
+
+		if readErr != nil {
+			return nil, readErr
+		}
+
+		verification.Documents++
+
+		if expectedChecksum, exists := checksums[docID]; exists && checksum == expectedChecksum {
+			matched[docID] = struct{}{}
+		}
+	}
+
+	for docID := range checksums {
+		if _, exists := matched[docID]; !exists {
+			verification.Mismatched = append(verification.Mismatched, docID)
+		}
+	}
+
+	sort.Strings(verification.Mismatched)
+
+	return verification, nil
+}
+
+// readChecksum returns the checksum of a document in the destination vault. If the document doesn't exist,
+// storage.ErrValueNotFound is returned.
+func readChecksum(store edvprovider.EDVStore, docID string) (string, error) {
+	documentBytes, err := store.Get(docID)
+	if err == storage.ErrValueNotFound {
+		return "", err
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to read document %s: %w", docID, err)
+	}
+
+	document := models.EncryptedDocument{}
+
+	err = json.Unmarshal(documentBytes, &document)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse document %s: %w", docID, err)
+	}
+
+	return vaultexport.DocumentChecksum(&document)
+}
+
+func (m *Migrator) reportProgress(report *VaultReport, processed, total int) {
+	var err error
+
+	if m.dryRun {
+		_, err = fmt.Fprintf(m.progress, "Vault %s: checked %d of %d document IDs, found %d documents\n",
+			report.ID, processed, total, report.Documents)
+	} else {
+		_, err = fmt.Fprintf(m.progress,
+			"Vault %s: processed %d of %d document IDs, copied %d, already copied %d, failed %d\n",
+			report.ID, processed, total, report.Copied, report.AlreadyCopied, len(report.Failed))
+	}
+
+	// Progress is informational only, so failing to report it doesn't stop the migration.
+	if err != nil {
+		log.Warnf("Failed to report migration progress: %s", err.Error())
+	}
+}
@@ -0,0 +1,419 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package migration
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+
+	"github.com/trustbloc/edv/pkg/edvprovider"
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+	"github.com/trustbloc/edv/pkg/restapi/edv/operation"
+	"github.com/trustbloc/edv/pkg/webhook"
+)
+
+const (
+	testVaultID1 = "vault1"
+	testVaultID2 = "vault2"
+	testRecord   = `{"configuration":{"referenceId":"vault1"},"documentCount":3,"totalBytes":30}`
+)
+
+func TestMigrator_Migrate(t *testing.T) {
+	t.Run("Every vault is copied and verified", func(t *testing.T) {
+		source := createTestSource(t)
+		destination := newTestEndpoint()
+
+		var progress bytes.Buffer
+
+		report, err := New(source, destination, WithBatchSize(2), WithProgress(&progress)).Migrate()
+		require.NoError(t, err)
+		require.True(t, report.Verified())
+		require.Equal(t, []VaultReport{
+			{ID: testVaultID1, Documents: 3, Copied: 3, Verification: &Verification{Documents: 3}},
+			{ID: testVaultID2, Documents: 0, Verification: &Verification{}},
+		}, report.Vaults)
+		require.Equal(t,
+			"Vault vault1: processed 2 of 4 document IDs, copied 1, already copied 0, failed 0\n"+
+				"Vault vault1: processed 4 of 4 document IDs, copied 3, already copied 0, failed 0\n",
+			progress.String())
+
+		storeNames, err := destination.EDVProvider.StoreNames()
+		require.NoError(t, err)
+		require.Equal(t, []string{testVaultID1, testVaultID2}, storeNames)
+
+		configStore, err := destination.StorageProvider.OpenStore(operation.VaultConfigurationStoreName)
+		require.NoError(t, err)
+
+		record, err := configStore.Get(testVaultID1)
+		require.NoError(t, err)
+		require.Equal(t, testRecord, string(record))
+
+		_, err = configStore.Get(testVaultID2)
+		require.Equal(t, storage.ErrValueNotFound, err)
+
+		store, err := destination.EDVProvider.OpenStore(testVaultID1)
+		require.NoError(t, err)
+
+		_, err = store.Get("doc2")
+		require.Equal(t, storage.ErrValueNotFound, err)
+	})
+	t.Run("Running the migration again skips the documents that were already copied", func(t *testing.T) {
+		source := createTestSource(t)
+		destination := newTestEndpoint()
+
+		_, err := New(source, destination).Migrate()
+		require.NoError(t, err)
+
+		report, err := New(source, destination).Migrate()
+		require.NoError(t, err)
+		require.True(t, report.Verified())
+		require.Equal(t, VaultReport{ID: testVaultID1, Documents: 3, AlreadyCopied: 3,
+			Verification: &Verification{Documents: 3}}, report.Vaults[0])
+	})
+	t.Run("Dry run doesn't write anything", func(t *testing.T) {
+		source := createTestSource(t)
+		destination := newTestEndpoint()
+
+		var progress bytes.Buffer
+
+		report, err := New(source, destination, WithDryRun(true), WithProgress(&progress)).Migrate()
+		require.NoError(t, err)
+		require.True(t, report.Verified())
+		require.Equal(t, []VaultReport{{ID: testVaultID1, Documents: 3}, {ID: testVaultID2}}, report.Vaults)
+		require.Equal(t, "Vault vault1: checked 4 of 4 document IDs, found 3 documents\n", progress.String())
+
+		storeNames, err := destination.EDVProvider.StoreNames()
+		require.NoError(t, err)
+		require.Empty(t, storeNames)
+	})
+	t.Run("Documents that differ in the destination fail verification", func(t *testing.T) {
+		source := createTestSource(t)
+		destination := newTestEndpoint()
+
+		require.NoError(t, destination.EDVProvider.CreateStore(testVaultID1))
+
+		store, err := destination.EDVProvider.OpenStore(testVaultID1)
+		require.NoError(t, err)
+
+		require.NoError(t, store.Put(models.EncryptedDocument{ID: "doc1", Sequence: 1}))
+
+		report, err := New(source, destination).Migrate()
+		require.NoError(t, err)
+		require.False(t, report.Verified())
+		require.Equal(t, VaultReport{ID: testVaultID1, Documents: 3, Copied: 2,
+			Failed:       []DocumentFailure{{ID: "doc1", Err: errDifferentDocument}},
+			Verification: &Verification{Documents: 3, Mismatched: []string{"doc1"}}}, report.Vaults[0])
+	})
+	t.Run("Extra documents in the destination fail verification", func(t *testing.T) {
+		source := createTestSource(t)
+		destination := newTestEndpoint()
+
+		require.NoError(t, destination.EDVProvider.CreateStore(testVaultID1))
+
+		store, err := destination.EDVProvider.OpenStore(testVaultID1)
+		require.NoError(t, err)
+
+		require.NoError(t, store.Put(models.EncryptedDocument{ID: "doc5"}))
+
+		report, err := New(source, destination).Migrate()
+		require.NoError(t, err)
+		require.False(t, report.Verified())
+		require.Equal(t, &Verification{Documents: 4}, report.Vaults[0].Verification)
+	})
+	t.Run("Unparsable source documents fail", func(t *testing.T) {
+		source := createTestSource(t)
+		source.EDVProvider = &testProvider{EDVProvider: source.EDVProvider, getValue: []byte("not json")}
+
+		report, err := New(source, newTestEndpoint()).Migrate()
+		require.NoError(t, err)
+		require.False(t, report.Verified())
+		require.Len(t, report.Vaults[0].Failed, 3)
+		require.Contains(t, report.Vaults[0].Failed[0].Err.Error(), "failed to parse document")
+	})
+}
+
+func TestMigrator_Migrate_Failures(t *testing.T) {
+	testErr := errors.New("test error")
+
+	for _, test := range []struct {
+		name        string
+		source      func(provider edvprovider.EDVProvider) edvprovider.EDVProvider
+		destination func(provider edvprovider.EDVProvider) edvprovider.EDVProvider
+		err         string
+	}{
+		{
+			name: "Fail to list source vaults",
+			source: func(provider edvprovider.EDVProvider) edvprovider.EDVProvider {
+				return &testProvider{EDVProvider: provider, errStoreNames: testErr}
+			},
+			err: "failed to list the source vaults: test error",
+		},
+		{
+			name: "Fail to open source vault",
+			source: func(provider edvprovider.EDVProvider) edvprovider.EDVProvider {
+				return &testProvider{EDVProvider: provider, errOpenStore: testErr}
+			},
+			err: "failed to migrate vault vault1: failed to open the source vault: test error",
+		},
+		{
+			name: "Fail to read source changes",
+			source: func(provider edvprovider.EDVProvider) edvprovider.EDVProvider {
+				return &testProvider{EDVProvider: provider, errChanges: testErr}
+			},
+			err: "failed to migrate vault vault1: failed to read changes: test error",
+		},
+		{
+			name: "Fail to read source document",
+			source: func(provider edvprovider.EDVProvider) edvprovider.EDVProvider {
+				return &testProvider{EDVProvider: provider, errGet: testErr}
+			},
+			err: "failed to migrate vault vault1: failed to read document doc1: test error",
+		},
+		{
+			name: "Fail to create destination vault",
+			destination: func(provider edvprovider.EDVProvider) edvprovider.EDVProvider {
+				return &testProvider{EDVProvider: provider, errCreateStore: testErr}
+			},
+			err: "failed to migrate vault vault1: failed to create the destination vault: test error",
+		},
+		{
+			name: "Fail to open destination vault",
+			destination: func(provider edvprovider.EDVProvider) edvprovider.EDVProvider {
+				return &testProvider{EDVProvider: provider, errOpenStore: testErr}
+			},
+			err: "failed to migrate vault vault1: failed to open the destination vault: test error",
+		},
+		{
+			name: "Fail to create destination index",
+			destination: func(provider edvprovider.EDVProvider) edvprovider.EDVProvider {
+				return &testProvider{EDVProvider: provider, errCreateEDVIndex: testErr}
+			},
+			err: "failed to migrate vault vault1: failed to create the destination vault's index: test error",
+		},
+		{
+			name: "Fail to write documents",
+			destination: func(provider edvprovider.EDVProvider) edvprovider.EDVProvider {
+				return &testProvider{EDVProvider: provider, errBulkWrite: testErr}
+			},
+			err: "failed to migrate vault vault1: failed to write documents: test error",
+		},
+		{
+			name: "Fail to verify destination",
+			destination: func(provider edvprovider.EDVProvider) edvprovider.EDVProvider {
+				return &testProvider{EDVProvider: provider, errGet: testErr}
+			},
+			err: "failed to migrate vault vault1: failed to verify the destination vault: " +
+				"failed to read document doc1: test error",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			source := createTestSource(t)
+			destination := newTestEndpoint()
+
+			if test.source != nil {
+				source.EDVProvider = test.source(source.EDVProvider)
+			}
+
+			if test.destination != nil {
+				destination.EDVProvider = test.destination(destination.EDVProvider)
+			}
+
+			_, err := New(source, destination).Migrate()
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestMigrator_Migrate_VaultConfigurationFailures(t *testing.T) {
+	t.Run("Source configuration store doesn't exist", func(t *testing.T) {
+		source := createTestSource(t)
+		source.StorageProvider = memstore.NewProvider()
+
+		report, err := New(source, newTestEndpoint()).Migrate()
+		require.NoError(t, err)
+		require.True(t, report.Verified())
+	})
+
+	for _, test := range []struct {
+		name        string
+		source      *mockstore.Provider
+		destination *mockstore.Provider
+		err         string
+	}{
+		{
+			name:   "Fail to open source store",
+			source: &mockstore.Provider{FailNameSpace: operation.VaultConfigurationStoreName},
+			err:    "failed to open the source vault configuration store",
+		},
+		{
+			name: "Fail to read source record",
+			source: &mockstore.Provider{Store: &mockstore.MockStore{ErrGet: errors.New("get error"),
+				Store: map[string][]byte{testVaultID1: []byte(testRecord)}}},
+			err: "failed to read the vault configuration: get error",
+		},
+		{
+			name:        "Fail to create destination store",
+			destination: &mockstore.Provider{ErrCreateStore: errors.New("create error")},
+			err:         "failed to create the destination vault configuration store: create error",
+		},
+		{
+			name:        "Fail to open destination store",
+			destination: &mockstore.Provider{FailNameSpace: operation.VaultConfigurationStoreName},
+			err:         "failed to open the destination vault configuration store",
+		},
+		{
+			name: "Fail to write destination record",
+			destination: &mockstore.Provider{Store: &mockstore.MockStore{ErrPut: errors.New("put error"),
+				Store: make(map[string][]byte)}},
+			err: "failed to write the vault configuration: put error",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			source := createTestSource(t)
+			destination := newTestEndpoint()
+
+			if test.source != nil {
+				source.StorageProvider = test.source
+			}
+
+			if test.destination != nil {
+				destination.StorageProvider = test.destination
+			}
+
+			_, err := New(source, destination).Migrate()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), test.err)
+		})
+	}
+}
+
+// createTestSource creates two vaults: one with three documents and a fourth that was deleted, and one that's
+// empty and has no configuration. It also creates one of the EDV server's own stores, which isn't migrated.
+func createTestSource(t *testing.T) Endpoint {
+	source := newTestEndpoint()
+
+	for _, storeName := range []string{testVaultID1, testVaultID2, webhook.StoreName} {
+		require.NoError(t, source.EDVProvider.CreateStore(storeName))
+	}
+
+	store, err := source.EDVProvider.OpenStore(testVaultID1)
+	require.NoError(t, err)
+
+	for _, docID := range []string{"doc1", "doc2", "doc3", "doc4"} {
+		require.NoError(t, store.Put(models.EncryptedDocument{ID: docID, JWE: []byte(`{"id":"` + docID + `"}`)}))
+	}
+
+	writeErrs, err := store.BulkWrite([]edvprovider.DocumentWrite{
+		{Type: edvprovider.WriteDelete, Document: models.EncryptedDocument{ID: "doc2"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []error{nil}, writeErrs)
+
+	require.NoError(t, source.StorageProvider.CreateStore(operation.VaultConfigurationStoreName))
+
+	configStore, err := source.StorageProvider.OpenStore(operation.VaultConfigurationStoreName)
+	require.NoError(t, err)
+
+	require.NoError(t, configStore.Put(testVaultID1, []byte(testRecord)))
+
+	return source
+}
+
+func newTestEndpoint() Endpoint {
+	return Endpoint{EDVProvider: memedvprovider.NewProvider(), StorageProvider: memstore.NewProvider()}
+}
+
+// testProvider wraps a provider, failing the calls that have an error set.
+type testProvider struct {
+	edvprovider.EDVProvider
+	errStoreNames     error
+	errCreateStore    error
+	errOpenStore      error
+	errChanges        error
+	errGet            error
+	errCreateEDVIndex error
+	errBulkWrite      error
+	getValue          []byte
+}
+
+func (p *testProvider) StoreNames() ([]string, error) {
+	if p.errStoreNames != nil {
+		return nil, p.errStoreNames
+	}
+
+	return p.EDVProvider.StoreNames()
+}
+
+func (p *testProvider) CreateStore(name string) error {
+	if p.errCreateStore != nil {
+		return p.errCreateStore
+	}
+
+	return p.EDVProvider.CreateStore(name)
+}
+
+func (p *testProvider) OpenStore(name string) (edvprovider.EDVStore, error) {
+	if p.errOpenStore != nil {
+		return nil, p.errOpenStore
+	}
+
+	store, err := p.EDVProvider.OpenStore(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &testStore{EDVStore: store, provider: p}, nil
+}
+
+type testStore struct {
+	edvprovider.EDVStore
+	provider *testProvider
+}
+
+func (s *testStore) Changes(since string, limit int) (*models.ChangeFeed, error) {
+	if s.provider.errChanges != nil {
+		return nil, s.provider.errChanges
+	}
+
+	return s.EDVStore.Changes(since, limit)
+}
+
+func (s *testStore) Get(k string) ([]byte, error) {
+	if s.provider.errGet != nil {
+		return nil, s.provider.errGet
+	}
+
+	value, err := s.EDVStore.Get(k)
+	if err == nil && s.provider.getValue != nil {
+		return s.provider.getValue, nil
+	}
+
+	return value, err
+}
+
+func (s *testStore) CreateEDVIndex() error {
+	if s.provider.errCreateEDVIndex != nil {
+		return s.provider.errCreateEDVIndex
+	}
+
+	return s.EDVStore.CreateEDVIndex()
+}
+
+func (s *testStore) BulkWrite(writes []edvprovider.DocumentWrite) ([]error, error) {
+	if s.provider.errBulkWrite != nil {
+		return nil, s.provider.errBulkWrite
+	}
+
+	return s.EDVStore.BulkWrite(writes)
+}
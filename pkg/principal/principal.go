@@ -0,0 +1,62 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package principal
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+)
+
+type contextKey struct{}
+
+// NewContext returns a copy of the given context that carries the given client identity.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the client identity stored in the given context, if any.
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(contextKey{}).(string)
+
+	return id, ok && id != ""
+}
+
+// FromRequest returns the identity of the authenticated client that sent the given request, if any.
+func FromRequest(req *http.Request) (string, bool) {
+	return FromContext(req.Context())
+}
+
+// FromCertificate derives a client identity from the given certificate.
+// The first URI SAN (e.g. a DID) is preferred, followed by the first email SAN and finally the subject common name.
+func FromCertificate(cert *x509.Certificate) string {
+	if len(cert.URIs) > 0 {
+		return cert.URIs[0].String()
+	}
+
+	if len(cert.EmailAddresses) > 0 {
+		return cert.EmailAddresses[0]
+	}
+
+	return cert.Subject.CommonName
+}
+
+// TLSClientIdentityHandler wraps the given handler so that the identity of a client that presented a
+// verified TLS client certificate is made available to it through FromRequest.
+// Requests without a verified certificate chain are passed through unchanged.
+func TLSClientIdentityHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.TLS != nil && len(req.TLS.VerifiedChains) > 0 && len(req.TLS.VerifiedChains[0]) > 0 {
+			id := FromCertificate(req.TLS.VerifiedChains[0][0])
+			if id != "" {
+				req = req.WithContext(NewContext(req.Context(), id))
+			}
+		}
+
+		next.ServeHTTP(rw, req)
+	})
+}
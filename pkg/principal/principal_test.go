@@ -0,0 +1,86 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package principal
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContext(t *testing.T) {
+	t.Run("Identity set", func(t *testing.T) {
+		id, ok := FromContext(NewContext(context.Background(), "did:example:123"))
+		require.True(t, ok)
+		require.Equal(t, "did:example:123", id)
+	})
+	t.Run("Identity not set", func(t *testing.T) {
+		id, ok := FromContext(context.Background())
+		require.False(t, ok)
+		require.Empty(t, id)
+	})
+}
+
+func TestFromCertificate(t *testing.T) {
+	t.Run("URI SAN", func(t *testing.T) {
+		uri, err := url.Parse("did:example:123")
+		require.NoError(t, err)
+
+		cert := &x509.Certificate{URIs: []*url.URL{uri}, EmailAddresses: []string{"a@example.com"},
+			Subject: pkix.Name{CommonName: "client"}}
+		require.Equal(t, "did:example:123", FromCertificate(cert))
+	})
+	t.Run("Email SAN", func(t *testing.T) {
+		cert := &x509.Certificate{EmailAddresses: []string{"a@example.com"}, Subject: pkix.Name{CommonName: "client"}}
+		require.Equal(t, "a@example.com", FromCertificate(cert))
+	})
+	t.Run("Common name", func(t *testing.T) {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: "client"}}
+		require.Equal(t, "client", FromCertificate(cert))
+	})
+}
+
+func TestTLSClientIdentityHandler(t *testing.T) {
+	var receivedID string
+
+	var receivedOK bool
+
+	handler := TLSClientIdentityHandler(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		receivedID, receivedOK = FromRequest(req)
+	}))
+
+	t.Run("Verified client certificate", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.TLS = &tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "client"}}}},
+		}
+
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		require.True(t, receivedOK)
+		require.Equal(t, "client", receivedID)
+	})
+	t.Run("Unverified client certificate", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.TLS = &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "client"}}},
+		}
+
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		require.False(t, receivedOK)
+	})
+	t.Run("Plain HTTP", func(t *testing.T) {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		require.False(t, receivedOK)
+	})
+}
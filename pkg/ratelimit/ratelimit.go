@@ -0,0 +1,121 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	pruneInterval  = time.Minute
+	minIdleTimeout = time.Minute
+)
+
+// Limiter rate limits requests using a separate token bucket for each key (e.g. a client identity or a vault ID).
+// Every bucket holds up to burst tokens and is refilled at the configured rate.
+type Limiter struct {
+	limit       rate.Limit
+	burst       int
+	idleTimeout time.Duration
+	buckets     map[string]*bucket
+	lastPrune   time.Time
+	mux         sync.Mutex
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// New returns a new Limiter that allows requestsPerSecond requests per second for each key,
+// with bursts of up to burst requests. requestsPerSecond must be greater than 0.
+// If burst is less than 1, then a burst of 1 is used.
+func New(requestsPerSecond float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	// Buckets that haven't been used for long enough to refill completely are indistinguishable from new ones,
+	// so they can be discarded to stop the number of buckets from growing without bound.
+	idleTimeout := time.Duration(math.Ceil(float64(burst)/requestsPerSecond)) * time.Second
+	if idleTimeout < minIdleTimeout {
+		idleTimeout = minIdleTimeout
+	}
+
+	return &Limiter{
+		limit:       rate.Limit(requestsPerSecond),
+		burst:       burst,
+		idleTimeout: idleTimeout,
+		buckets:     make(map[string]*bucket),
+		lastPrune:   time.Now(),
+	}
+}
+
+// Allow takes a token from the bucket for each of the given keys. If every bucket has a token available,
+// then the tokens are consumed and true is returned. Otherwise no tokens are consumed, and the time to wait
+// before the request could be allowed is returned.
+func (l *Limiter) Allow(keys ...string) (bool, time.Duration) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	now := time.Now()
+
+	l.prune(now)
+
+	reservations := make([]*rate.Reservation, 0, len(keys))
+
+	var retryAfter time.Duration
+
+	for _, key := range keys {
+		reservation := l.bucket(key, now).ReserveN(now, 1)
+		reservations = append(reservations, reservation)
+
+		if delay := reservation.DelayFrom(now); delay > retryAfter {
+			retryAfter = delay
+		}
+	}
+
+	if retryAfter == 0 {
+		return true, 0
+	}
+
+	for _, reservation := range reservations {
+		reservation.CancelAt(now)
+	}
+
+	return false, retryAfter
+}
+
+func (l *Limiter) bucket(key string, now time.Time) *rate.Limiter {
+	b, found := l.buckets[key]
+	if !found {
+		b = &bucket{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.buckets[key] = b
+	}
+
+	b.lastSeen = now
+
+	return b.limiter
+}
+
+func (l *Limiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < pruneInterval {
+		return
+	}
+
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) > l.idleTimeout {
+			delete(l.buckets, key)
+		}
+	}
+
+	l.lastPrune = now
+}
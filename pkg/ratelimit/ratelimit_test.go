@@ -0,0 +1,71 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	t.Run("Burst is allowed, then requests are limited", func(t *testing.T) {
+		limiter := New(1, 2)
+
+		allowed, _ := limiter.Allow("client")
+		require.True(t, allowed)
+
+		allowed, _ = limiter.Allow("client")
+		require.True(t, allowed)
+
+		allowed, retryAfter := limiter.Allow("client")
+		require.False(t, allowed)
+		require.True(t, retryAfter > 0 && retryAfter <= time.Second, "unexpected retry after: %s", retryAfter)
+
+		allowed, _ = limiter.Allow("another client")
+		require.True(t, allowed)
+	})
+	t.Run("No tokens are taken unless all keys are allowed", func(t *testing.T) {
+		limiter := New(1, 1)
+
+		allowed, _ := limiter.Allow("client1", "vault")
+		require.True(t, allowed)
+
+		allowed, _ = limiter.Allow("client2", "vault")
+		require.False(t, allowed)
+
+		allowed, _ = limiter.Allow("client2")
+		require.True(t, allowed)
+	})
+	t.Run("Burst less than 1", func(t *testing.T) {
+		limiter := New(1, 0)
+
+		allowed, _ := limiter.Allow("client")
+		require.True(t, allowed)
+
+		allowed, _ = limiter.Allow("client")
+		require.False(t, allowed)
+	})
+}
+
+func TestLimiter_Prune(t *testing.T) {
+	limiter := New(10, 1)
+	require.Equal(t, minIdleTimeout, limiter.idleTimeout)
+
+	allowed, _ := limiter.Allow("idle")
+	require.True(t, allowed)
+
+	limiter.buckets["idle"].lastSeen = time.Now().Add(-2 * minIdleTimeout)
+	limiter.lastPrune = time.Now().Add(-2 * pruneInterval)
+
+	allowed, _ = limiter.Allow("active")
+	require.True(t, allowed)
+
+	require.Len(t, limiter.buckets, 1)
+	require.Contains(t, limiter.buckets, "active")
+}
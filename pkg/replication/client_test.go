@@ -0,0 +1,73 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package replication_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+
+	"github.com/trustbloc/edv/pkg/client/edv"
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/replication"
+	restapi "github.com/trustbloc/edv/pkg/restapi/edv"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+func TestReplicator_ThroughEDVClient(t *testing.T) {
+	const (
+		vaultID    = "testvault"
+		documentID = "VJYHHJx4C8J9Fsgz7rZqSp"
+	)
+
+	remoteProvider := memedvprovider.NewProvider()
+	require.NoError(t, remoteProvider.CreateStore(vaultID))
+
+	controller, err := restapi.New(remoteProvider)
+	require.NoError(t, err)
+
+	router := mux.NewRouter()
+	router.UseEncodedPath()
+
+	for _, handler := range controller.GetOperations() {
+		router.HandleFunc(handler.Path(), handler.Handle()).Methods(handler.Method())
+	}
+
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	remoteStore, err := remoteProvider.OpenStore(vaultID)
+	require.NoError(t, err)
+
+	document := models.EncryptedDocument{ID: documentID, Sequence: 1, JWE: []byte(`{"protected":"abc"}`)}
+	require.NoError(t, remoteStore.Put(document))
+
+	localProvider := memedvprovider.NewProvider()
+	require.NoError(t, localProvider.CreateStore(vaultID))
+
+	r, err := replication.New(edv.New(srv.URL+"/encrypted-data-vaults"), localProvider, memstore.NewProvider(),
+		[]string{vaultID})
+	require.NoError(t, err)
+
+	result, err := r.Replicate(context.Background(), vaultID)
+	require.NoError(t, err)
+	require.Equal(t, &replication.Result{Applied: 1}, result)
+
+	localStore, err := localProvider.OpenStore(vaultID)
+	require.NoError(t, err)
+
+	remoteBytes, err := remoteStore.Get(documentID)
+	require.NoError(t, err)
+
+	localBytes, err := localStore.Get(documentID)
+	require.NoError(t, err)
+	require.JSONEq(t, string(remoteBytes), string(localBytes))
+}
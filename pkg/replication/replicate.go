@@ -0,0 +1,331 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package replication
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edv/pkg/edvprovider"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+// Result summarizes a replication pass over a vault.
+type Result struct {
+	// Applied is the number of remote changes that were written to the local vault.
+	Applied int
+	// Unchanged is the number of remote changes whose document was already the same locally.
+	Unchanged int
+	// Conflicts is the number of remote changes to documents that were also changed locally.
+	Conflicts int
+	// Skipped is the number of remote deletions, which aren't replicated. This includes changes to documents
+	// that were deleted on the remote EDV server by the time they were read.
+	Skipped int
+}
+
+// Run replicates every vault continuously, checking the remote change feeds every poll interval,
+// until the given context is done. Failures are logged and retried at the next poll.
+func (r *Replicator) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		r.ReplicateAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ReplicateAll replicates every vault once, logging the outcome for each.
+func (r *Replicator) ReplicateAll(ctx context.Context) {
+	for _, vaultID := range r.vaultIDs {
+		logger := log.WithField("vaultID", vaultID)
+
+		result, err := r.Replicate(ctx, vaultID)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Errorf("Failed to replicate vault: %s", err.Error())
+			}
+
+			continue
+		}
+
+		if result.Applied > 0 || result.Conflicts > 0 {
+			logger.Infof("Replicated vault: %d changes applied, %d unchanged, %d conflicts, %d skipped",
+				result.Applied, result.Unchanged, result.Conflicts, result.Skipped)
+		}
+	}
+}
+
+// Replicate applies every change made to the given vault on the remote EDV server since the vault's checkpoint,
+// moving the checkpoint along after each batch. It returns once the vault has caught up with the remote change feed.
+func (r *Replicator) Replicate(ctx context.Context, vaultID string) (*Result, error) {
+	if err := r.checkVault(vaultID); err != nil {
+		return nil, err
+	}
+
+	store, err := r.provider.OpenStore(vaultID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	state, err := r.getVaultState(vaultID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+
+	for {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+
+		hasMore, err := r.replicateBatch(vaultID, store, state, result)
+		if err != nil {
+			return result, r.recordFailure(vaultID, state, err)
+		}
+
+		if !hasMore {
+			return result, nil
+		}
+	}
+}
+
+// replicateBatch applies the next batch of remote changes and saves the new checkpoint.
+// It returns whether the remote change feed has more changes.
+func (r *Replicator) replicateBatch(vaultID string, store edvprovider.EDVStore, state *vaultState,
+	result *Result) (bool, error) {
+	feed, err := r.source.ReadChanges(vaultID, state.Cursor, r.batchSize)
+	if err != nil {
+		return false, fmt.Errorf("failed to read the remote change feed: %w", err)
+	}
+
+	if feed.Cursor == state.Cursor && state.LastError == "" {
+		return false, nil
+	}
+
+	for _, change := range feed.Changes {
+		err = r.applyChange(vaultID, store, state, change, result)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	now := r.now()
+
+	state.Cursor = feed.Cursor
+	state.UpdatedAt = &now
+	state.LastError = ""
+
+	err = r.putJSON(vaultStateKey(vaultID), state)
+	if err != nil {
+		return false, err
+	}
+
+	return feed.HasMore, nil
+}
+
+// applyChange brings the local copy of the changed document up to date with the remote one,
+// unless it was changed locally since it was last replicated, in which case a conflict is recorded instead.
+func (r *Replicator) applyChange(vaultID string, store edvprovider.EDVStore, state *vaultState,
+	change models.DocumentChange, result *Result) error {
+	if change.Type == models.ChangeTypeDeleted {
+		result.Skipped++
+
+		return nil
+	}
+
+	err := r.applyDocumentChange(vaultID, store, state, change, result)
+	if errors.Is(err, edverrors.ErrDocumentNotFound) {
+		result.Skipped++
+
+		return nil
+	}
+
+	return err
+}
+
+func (r *Replicator) applyDocumentChange(vaultID string, store edvprovider.EDVStore, state *vaultState,
+	change models.DocumentChange, result *Result) error {
+	localHash, err := localDocumentHash(store, change.ID)
+	if err != nil {
+		return err
+	}
+
+	replicatedHash := ""
+
+	if _, err = r.getJSON(documentHashKey(vaultID, change.ID), &replicatedHash); err != nil {
+		return err
+	}
+
+	if localHash != "" && localHash != replicatedHash {
+		return r.applyChangeToChangedDocument(vaultID, state, change, localHash, result)
+	}
+
+	written, err := r.copyRemoteDocument(vaultID, store, change.ID)
+	if err != nil {
+		return err
+	}
+
+	if written {
+		result.Applied++
+	} else {
+		result.Unchanged++
+	}
+
+	return nil
+}
+
+// applyChangeToChangedDocument handles a remote change to a document that was changed locally since it was last
+// replicated. If both sides ended up the same, then there's nothing to do. Otherwise, it's a conflict.
+func (r *Replicator) applyChangeToChangedDocument(vaultID string, state *vaultState, change models.DocumentChange,
+	localHash string, result *Result) error {
+	remoteDocument, err := r.readRemoteDocument(vaultID, change.ID)
+	if err != nil {
+		return err
+	}
+
+	remoteHash, err := documentHash(remoteDocument)
+	if err != nil {
+		return err
+	}
+
+	removeConflict(state, change.ID)
+
+	if remoteHash == localHash {
+		result.Unchanged++
+
+		return r.putJSON(documentHashKey(vaultID, change.ID), remoteHash)
+	}
+
+	log.WithField("vaultID", vaultID).Warnf("Replication conflict: document %s was changed both locally and on "+
+		"the remote EDV server. The local version is kept until the conflict is resolved.", change.ID)
+
+	state.Conflicts = append(state.Conflicts, models.ReplicationConflict{
+		DocumentID:     change.ID,
+		RemoteSequence: change.Sequence,
+		DetectedAt:     r.now(),
+	})
+	result.Conflicts++
+
+	return nil
+}
+
+// copyRemoteDocument stores the remote version of the given document locally, unless the local version is
+// already the same, and records it as replicated. It returns whether the document was written.
+func (r *Replicator) copyRemoteDocument(vaultID string, store edvprovider.EDVStore, documentID string) (bool, error) {
+	remoteDocument, err := r.readRemoteDocument(vaultID, documentID)
+	if err != nil {
+		return false, err
+	}
+
+	remoteHash, err := documentHash(remoteDocument)
+	if err != nil {
+		return false, err
+	}
+
+	localHash, err := localDocumentHash(store, documentID)
+	if err != nil {
+		return false, err
+	}
+
+	written := localHash != remoteHash
+
+	if written {
+		err = r.writer.WriteReplicatedDocument(vaultID, *remoteDocument)
+		if err != nil {
+			return false, fmt.Errorf("failed to store document %s: %w", documentID, err)
+		}
+	}
+
+	return written, r.putJSON(documentHashKey(vaultID, documentID), remoteHash)
+}
+
+// acceptLocalDocument records the local version of the given document as replicated.
+func (r *Replicator) acceptLocalDocument(vaultID string, store edvprovider.EDVStore, documentID string) error {
+	localHash, err := localDocumentHash(store, documentID)
+	if err != nil {
+		return err
+	}
+
+	return r.putJSON(documentHashKey(vaultID, documentID), localHash)
+}
+
+func (r *Replicator) readRemoteDocument(vaultID, documentID string) (*models.EncryptedDocument, error) {
+	document, err := r.source.ReadDocument(vaultID, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document %s from the remote EDV server: %w", documentID, err)
+	}
+
+	return document, nil
+}
+
+// recordFailure saves the given replication failure in the vault's state so that it shows up in its status,
+// and returns it.
+func (r *Replicator) recordFailure(vaultID string, state *vaultState, replicationErr error) error {
+	if state.LastError == replicationErr.Error() {
+		return replicationErr
+	}
+
+	state.LastError = replicationErr.Error()
+
+	if err := r.putJSON(vaultStateKey(vaultID), state); err != nil {
+		log.WithField("vaultID", vaultID).Errorf("Failed to record replication failure: %s", err.Error())
+	}
+
+	return replicationErr
+}
+
+// localDocumentHash returns the hash of the local version of the given document, or a blank string if there's none.
+func localDocumentHash(store edvprovider.EDVStore, documentID string) (string, error) {
+	documentBytes, err := store.Get(documentID)
+	if err == storage.ErrValueNotFound {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to read local document %s: %w", documentID, err)
+	}
+
+	document := models.EncryptedDocument{}
+
+	err = json.Unmarshal(documentBytes, &document)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse local document %s: %w", documentID, err)
+	}
+
+	return documentHash(&document)
+}
+
+// documentHash hashes the document in a way that doesn't depend on how it was formatted when it was stored.
+func documentHash(document *models.EncryptedDocument) (string, error) {
+	documentBytes, err := json.Marshal(document)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal document %s: %w", document.ID, err)
+	}
+
+	hash := sha256.Sum256(documentBytes)
+
+	return hex.EncodeToString(hash[:]), nil
+}
@@ -0,0 +1,301 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package replication
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edv/pkg/edvprovider"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+func TestReplicator_Replicate(t *testing.T) {
+	t.Run("Documents are copied as is", func(t *testing.T) {
+		r, remote, local := newTestReplicator(t, WithBatchSize(1))
+
+		document := models.EncryptedDocument{
+			ID:       testDocumentID,
+			Sequence: 3,
+			IndexedAttributeCollections: []models.IndexedAttributeCollection{{
+				Sequence:          1,
+				HMAC:              models.IDTypePair{ID: "hmacKey", Type: "Sha256HmacKey2019"},
+				IndexedAttributes: []models.IndexedAttribute{{Name: "attr", Value: "value", Unique: true}},
+			}},
+			JWE: []byte(`{"protected":"abc"}`),
+		}
+		require.NoError(t, remote.Put(document))
+		putTestDocument(t, remote, "otherDocument", "other")
+
+		result, err := r.Replicate(context.Background(), testVaultID)
+		require.NoError(t, err)
+		require.Equal(t, &Result{Applied: 2}, result)
+
+		localHash, err := localDocumentHash(local, testDocumentID)
+		require.NoError(t, err)
+
+		remoteHash, err := documentHash(&document)
+		require.NoError(t, err)
+		require.Equal(t, remoteHash, localHash)
+
+		requireDocument(t, local, "otherDocument", "other")
+
+		// The checkpoint is where the remote change feed ends.
+		feed, err := remote.Changes("", 10)
+		require.NoError(t, err)
+
+		status, err := r.Status(testVaultID)
+		require.NoError(t, err)
+		require.Equal(t, feed.Cursor, status.Cursor)
+		require.NotNil(t, status.UpdatedAt)
+		require.Empty(t, status.LastError)
+
+		// Nothing is read again.
+		result, err = r.Replicate(context.Background(), testVaultID)
+		require.NoError(t, err)
+		require.Equal(t, &Result{}, result)
+	})
+	t.Run("Remote updates are applied", func(t *testing.T) {
+		r, remote, local := newTestReplicator(t)
+
+		putTestDocument(t, remote, testDocumentID, "original")
+
+		_, err := r.Replicate(context.Background(), testVaultID)
+		require.NoError(t, err)
+
+		putTestDocument(t, remote, testDocumentID, "remote edit")
+
+		result, err := r.Replicate(context.Background(), testVaultID)
+		require.NoError(t, err)
+		require.Equal(t, &Result{Applied: 1}, result)
+		requireDocument(t, local, testDocumentID, "remote edit")
+	})
+	t.Run("Documents that are already the same aren't written", func(t *testing.T) {
+		r, remote, local := newTestReplicator(t)
+
+		putTestDocument(t, remote, testDocumentID, "same")
+		putTestDocument(t, local, testDocumentID, "same")
+
+		localFeed, err := local.Changes("", 10)
+		require.NoError(t, err)
+
+		result, err := r.Replicate(context.Background(), testVaultID)
+		require.NoError(t, err)
+		require.Equal(t, &Result{Unchanged: 1}, result)
+
+		// Since nothing was written, replicating in the other direction doesn't bounce the document back.
+		localFeedAfter, err := local.Changes(localFeed.Cursor, 10)
+		require.NoError(t, err)
+		require.Empty(t, localFeedAfter.Changes)
+
+		// Local changes after that are conflicts.
+		putTestDocument(t, local, testDocumentID, "local edit")
+		putTestDocument(t, remote, testDocumentID, "remote edit")
+
+		result, err = r.Replicate(context.Background(), testVaultID)
+		require.NoError(t, err)
+		require.Equal(t, &Result{Conflicts: 1}, result)
+	})
+	t.Run("Conflicts keep the local version", func(t *testing.T) {
+		r, remote, local := newTestConflict(t)
+
+		requireDocument(t, local, testDocumentID, "local edit")
+
+		status, err := r.Status(testVaultID)
+		require.NoError(t, err)
+		require.Len(t, status.Conflicts, 1)
+		require.Equal(t, testDocumentID, status.Conflicts[0].DocumentID)
+		require.NotEmpty(t, status.Conflicts[0].RemoteSequence)
+		require.False(t, status.Conflicts[0].DetectedAt.IsZero())
+
+		// Another remote change replaces the conflict rather than adding one.
+		putTestDocument(t, remote, testDocumentID, "second remote edit")
+
+		result, err := r.Replicate(context.Background(), testVaultID)
+		require.NoError(t, err)
+		require.Equal(t, &Result{Conflicts: 1}, result)
+
+		status, err = r.Status(testVaultID)
+		require.NoError(t, err)
+		require.Len(t, status.Conflicts, 1)
+
+		// If both sides end up the same, the conflict goes away.
+		putTestDocument(t, local, testDocumentID, "converged")
+		putTestDocument(t, remote, testDocumentID, "converged")
+
+		result, err = r.Replicate(context.Background(), testVaultID)
+		require.NoError(t, err)
+		require.Equal(t, &Result{Unchanged: 1}, result)
+		requireNoConflicts(t, r)
+	})
+	t.Run("Deletions are skipped", func(t *testing.T) {
+		r, _, _ := newTestReplicator(t)
+
+		r.source = &feedSource{changes: []models.DocumentChange{
+			{ID: testDocumentID, Sequence: "1", Type: models.ChangeTypeDeleted},
+		}}
+
+		result, err := r.Replicate(context.Background(), testVaultID)
+		require.NoError(t, err)
+		require.Equal(t, &Result{Skipped: 1}, result)
+	})
+	t.Run("Documents deleted on the remote server before they're read are skipped", func(t *testing.T) {
+		r, remote, local := newTestReplicator(t)
+
+		putTestDocument(t, remote, testDocumentID, "remote content")
+
+		errs, err := remote.BulkWrite([]edvprovider.DocumentWrite{
+			{Type: edvprovider.WriteDelete, Document: models.EncryptedDocument{ID: testDocumentID}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []error{nil}, errs)
+
+		result, err := r.Replicate(context.Background(), testVaultID)
+		require.NoError(t, err)
+		require.Equal(t, &Result{Skipped: 2}, result)
+
+		_, err = local.Get(testDocumentID)
+		require.Error(t, err)
+	})
+	t.Run("Failures are recorded and retried", func(t *testing.T) {
+		r, remote, local := newTestReplicator(t)
+		source := r.source.(*testSource)
+
+		putTestDocument(t, remote, testDocumentID, "original")
+
+		source.errReadChanges = errors.New("connection refused")
+
+		_, err := r.Replicate(context.Background(), testVaultID)
+		require.EqualError(t, err, "failed to read the remote change feed: connection refused")
+
+		source.errReadChanges = nil
+		source.errReadDocument = errors.New("connection refused")
+
+		_, replicationErr := r.Replicate(context.Background(), testVaultID)
+		require.EqualError(t, replicationErr, "failed to read document "+testDocumentID+
+			" from the remote EDV server: connection refused")
+
+		status, err := r.Status(testVaultID)
+		require.NoError(t, err)
+		require.Equal(t, replicationErr.Error(), status.LastError)
+		require.Empty(t, status.Cursor)
+
+		source.errReadDocument = nil
+
+		result, err := r.Replicate(context.Background(), testVaultID)
+		require.NoError(t, err)
+		require.Equal(t, &Result{Applied: 1}, result)
+		requireDocument(t, local, testDocumentID, "original")
+
+		status, err = r.Status(testVaultID)
+		require.NoError(t, err)
+		require.Empty(t, status.LastError)
+	})
+	t.Run("Documents are written with the vault writer", func(t *testing.T) {
+		r, remote, local := newTestReplicator(t)
+
+		writer := &recordingWriter{}
+		r.SetVaultWriter(writer)
+
+		putTestDocument(t, remote, testDocumentID, "original")
+
+		result, err := r.Replicate(context.Background(), testVaultID)
+		require.NoError(t, err)
+		require.Equal(t, &Result{Applied: 1}, result)
+		require.Equal(t, []string{testVaultID + "/" + testDocumentID}, writer.writes)
+
+		_, err = local.Get(testDocumentID)
+		require.Error(t, err)
+	})
+	t.Run("Failure - writing document", func(t *testing.T) {
+		r, remote, _ := newTestReplicator(t)
+
+		r.SetVaultWriter(&recordingWriter{err: errors.New("vault quota exceeded")})
+
+		putTestDocument(t, remote, testDocumentID, "original")
+
+		_, err := r.Replicate(context.Background(), testVaultID)
+		require.EqualError(t, err, "failed to store document "+testDocumentID+": vault quota exceeded")
+
+		status, err := r.Status(testVaultID)
+		require.NoError(t, err)
+		require.Empty(t, status.Cursor)
+	})
+	t.Run("Vault not replicated", func(t *testing.T) {
+		r, _, _ := newTestReplicator(t)
+
+		result, err := r.Replicate(context.Background(), "othervault")
+		require.Error(t, err)
+		require.Nil(t, result)
+	})
+	t.Run("Context done", func(t *testing.T) {
+		r, _, _ := newTestReplicator(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := r.Replicate(ctx, testVaultID)
+		require.Equal(t, context.Canceled, err)
+	})
+}
+
+func TestReplicator_Run(t *testing.T) {
+	r, remote, local := newTestReplicator(t, WithPollInterval(10*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		r.Run(ctx)
+		close(done)
+	}()
+
+	putTestDocument(t, remote, testDocumentID, "original")
+
+	require.Eventually(t, func() bool {
+		_, err := local.Get(testDocumentID)
+
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+}
+
+// feedSource returns a fixed change feed.
+type feedSource struct {
+	testSource
+	changes []models.DocumentChange
+}
+
+func (s *feedSource) ReadChanges(vaultID, since string, limit int) (*models.ChangeFeed, error) {
+	if since != "" {
+		return &models.ChangeFeed{Changes: []models.DocumentChange{}, Cursor: since}, nil
+	}
+
+	return &models.ChangeFeed{Changes: s.changes, Cursor: "end"}, nil
+}
+
+// recordingWriter records the documents it's asked to write instead of writing them.
+type recordingWriter struct {
+	writes []string
+	err    error
+}
+
+func (w *recordingWriter) WriteReplicatedDocument(vaultID string, document models.EncryptedDocument) error {
+	if w.err != nil {
+		return w.err
+	}
+
+	w.writes = append(w.writes, vaultID+"/"+document.ID)
+
+	return nil
+}
@@ -0,0 +1,335 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package replication mirrors the documents of vaults from another EDV server into the local EDV provider.
+package replication
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edv/pkg/edvprovider"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+const (
+	// StoreName is the name of the store in the storage provider that holds replication checkpoints and conflicts.
+	StoreName = "replication"
+
+	// DefaultPollInterval is how often the remote change feeds are checked for new changes in continuous mode,
+	// if WithPollInterval isn't used.
+	DefaultPollInterval = 10 * time.Second
+	// DefaultBatchSize is the number of changes read from a remote change feed at a time,
+	// if WithBatchSize isn't used.
+	DefaultBatchSize = 100
+)
+
+// Source is where documents are replicated from. It's satisfied by the EDV client in pkg/client/edv.
+type Source interface {
+	// ReadChanges returns up to limit changes made to the documents in the given vault after the given cursor.
+	ReadChanges(vaultID, since string, limit int) (*models.ChangeFeed, error)
+
+	// ReadDocument returns the current version of the given document.
+	ReadDocument(vaultID, docID string) (*models.EncryptedDocument, error)
+}
+
+// VaultWriter writes replicated documents to local vaults. It's satisfied by the EDV operations' vault collection,
+// which writes them the same way as documents written through the REST API, so that the vaults' usage is tracked.
+type VaultWriter interface {
+	// WriteReplicatedDocument stores the given document in the given vault, replacing its current version if it has one.
+	WriteReplicatedDocument(vaultID string, document models.EncryptedDocument) error
+}
+
+// Option configures a Replicator.
+type Option func(opts *Replicator)
+
+// WithPollInterval sets how often the remote change feeds are checked for new changes in continuous mode.
+func WithPollInterval(interval time.Duration) Option {
+	return func(opts *Replicator) {
+		if interval > 0 {
+			opts.pollInterval = interval
+		}
+	}
+}
+
+// WithBatchSize sets the number of changes read from a remote change feed at a time.
+func WithBatchSize(batchSize int) Option {
+	return func(opts *Replicator) {
+		if batchSize > 0 {
+			opts.batchSize = batchSize
+		}
+	}
+}
+
+// Replicator pulls the documents of a set of vaults from another EDV server and stores them in the local EDV provider
+// as is, so their IDs, sequences and indexed attributes are preserved. Each vault must already exist locally.
+//
+// How far each remote change feed has been applied is checkpointed, so replication picks up where it left off.
+// A hash of every replicated document is kept as well. If a document changed locally since it was last replicated
+// and changes remotely too, then the local version is kept and a conflict is recorded until it's resolved.
+// Deletions aren't replicated.
+type Replicator struct {
+	source       Source
+	provider     edvprovider.EDVProvider
+	store        storage.Store
+	writer       VaultWriter
+	vaultIDs     []string
+	pollInterval time.Duration
+	batchSize    int
+	now          func() time.Time
+	// mux serializes replication. Note that this only works within a single EDV server instance.
+	mux sync.Mutex
+}
+
+// vaultState is what's kept in the replication store for each replicated vault.
+type vaultState struct {
+	Cursor    string                       `json:"cursor"`
+	UpdatedAt *time.Time                   `json:"updatedAt,omitempty"`
+	LastError string                       `json:"lastError,omitempty"`
+	Conflicts []models.ReplicationConflict `json:"conflicts,omitempty"`
+}
+
+// New returns a new Replicator that replicates the given vaults from the given source into the given EDV provider,
+// keeping its checkpoints and conflicts in the given storage provider. The replication store is created if needed.
+func New(source Source, provider edvprovider.EDVProvider, storageProvider storage.Provider, vaultIDs []string,
+	opts ...Option) (*Replicator, error) {
+	err := storageProvider.CreateStore(StoreName)
+	if err != nil && err != storage.ErrDuplicateStore {
+		return nil, fmt.Errorf("failed to create replication store: %w", err)
+	}
+
+	store, err := storageProvider.OpenStore(StoreName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open replication store: %w", err)
+	}
+
+	r := &Replicator{
+		source:       source,
+		provider:     provider,
+		store:        store,
+		writer:       providerWriter{provider: provider},
+		vaultIDs:     vaultIDs,
+		pollInterval: DefaultPollInterval,
+		batchSize:    DefaultBatchSize,
+		now:          time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r, nil
+}
+
+// SetVaultWriter sets what writes replicated documents to the local vaults. By default, they're written straight to
+// the EDV provider, without updating the vaults' usage. It should be set before replication starts.
+func (r *Replicator) SetVaultWriter(writer VaultWriter) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.writer = writer
+}
+
+// VaultIDs returns the IDs of the vaults being replicated.
+func (r *Replicator) VaultIDs() []string {
+	return r.vaultIDs
+}
+
+// Status returns how far the given vault has been replicated along with its unresolved conflicts.
+func (r *Replicator) Status(vaultID string) (*models.ReplicationStatus, error) {
+	if err := r.checkVault(vaultID); err != nil {
+		return nil, err
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	state, err := r.getVaultState(vaultID)
+	if err != nil {
+		return nil, err
+	}
+
+	conflicts := state.Conflicts
+	if conflicts == nil {
+		conflicts = []models.ReplicationConflict{}
+	}
+
+	return &models.ReplicationStatus{
+		VaultID:   vaultID,
+		Cursor:    state.Cursor,
+		UpdatedAt: state.UpdatedAt,
+		LastError: state.LastError,
+		Conflicts: conflicts,
+	}, nil
+}
+
+// ResolveConflict resolves the replication conflict of the given document by keeping either the local version
+// (models.ConflictKeepLocal) or the remote one (models.ConflictKeepRemote). Either way, the kept version counts as
+// replicated, so the next remote change to the document replaces it unless it's changed locally again.
+func (r *Replicator) ResolveConflict(vaultID, documentID, keep string) error {
+	if keep != models.ConflictKeepLocal && keep != models.ConflictKeepRemote {
+		return edverrors.ErrInvalidConflictResolution
+	}
+
+	if err := r.checkVault(vaultID); err != nil {
+		return err
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	state, err := r.getVaultState(vaultID)
+	if err != nil {
+		return err
+	}
+
+	if !removeConflict(state, documentID) {
+		return edverrors.ErrConflictNotFound
+	}
+
+	store, err := r.provider.OpenStore(vaultID)
+	if err != nil {
+		return err
+	}
+
+	if keep == models.ConflictKeepRemote {
+		_, err = r.copyRemoteDocument(vaultID, store, documentID)
+	} else {
+		err = r.acceptLocalDocument(vaultID, store, documentID)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return r.putJSON(vaultStateKey(vaultID), state)
+}
+
+// checkVault checks that the given vault is replicated and exists locally.
+func (r *Replicator) checkVault(vaultID string) error {
+	if !r.replicates(vaultID) {
+		return edverrors.ErrVaultNotReplicated
+	}
+
+	_, err := r.provider.OpenStore(vaultID)
+	if err == storage.ErrStoreNotFound {
+		return edverrors.ErrVaultNotFound
+	}
+
+	return err
+}
+
+func (r *Replicator) replicates(vaultID string) bool {
+	for _, replicatedVaultID := range r.vaultIDs {
+		if replicatedVaultID == vaultID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *Replicator) getVaultState(vaultID string) (*vaultState, error) {
+	state := vaultState{}
+
+	if _, err := r.getJSON(vaultStateKey(vaultID), &state); err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+// getJSON unmarshals the value stored under the given key into v. It returns false if there's no such value.
+func (r *Replicator) getJSON(key string, v interface{}) (bool, error) {
+	valueBytes, err := r.store.Get(key)
+	if err == storage.ErrValueNotFound {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("failed to get %s from replication store: %w", key, err)
+	}
+
+	err = json.Unmarshal(valueBytes, v)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse %s from replication store: %w", key, err)
+	}
+
+	return true, nil
+}
+
+func (r *Replicator) putJSON(key string, v interface{}) error {
+	valueBytes, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", key, err)
+	}
+
+	err = r.store.Put(key, valueBytes)
+	if err != nil {
+		return fmt.Errorf("failed to store %s in replication store: %w", key, err)
+	}
+
+	return nil
+}
+
+// providerWriter writes replicated documents straight to the EDV provider.
+type providerWriter struct {
+	provider edvprovider.EDVProvider
+}
+
+// WriteReplicatedDocument creates the given document or, if it exists already, updates it. Either way, it's written
+// the same way as other document writes, so that e.g. it isn't checked against its own unique indexed attributes.
+func (w providerWriter) WriteReplicatedDocument(vaultID string, document models.EncryptedDocument) error {
+	store, err := w.provider.OpenStore(vaultID)
+	if err != nil {
+		return err
+	}
+
+	write := edvprovider.DocumentWrite{Type: edvprovider.WriteUpdate, Document: document}
+
+	_, err = store.Get(document.ID)
+	if err == storage.ErrValueNotFound {
+		write.Type = edvprovider.WriteCreate
+	} else if err != nil {
+		return err
+	}
+
+	writeErrs, err := store.BulkWrite([]edvprovider.DocumentWrite{write})
+	if err != nil {
+		return err
+	}
+
+	return writeErrs[0]
+}
+
+// removeConflict removes the conflict of the given document from the vault state.
+// It returns false if the document has no conflict.
+func removeConflict(state *vaultState, documentID string) bool {
+	for i := range state.Conflicts {
+		if state.Conflicts[i].DocumentID == documentID {
+			state.Conflicts = append(state.Conflicts[:i], state.Conflicts[i+1:]...)
+
+			return true
+		}
+	}
+
+	return false
+}
+
+func vaultStateKey(vaultID string) string {
+	return "vault_" + vaultID
+}
+
+// documentHashKey prefixes the vault ID with its length, since both vault and document IDs can contain underscores.
+func documentHashKey(vaultID, documentID string) string {
+	return "document_" + strconv.Itoa(len(vaultID)) + "_" + vaultID + "_" + documentID
+}
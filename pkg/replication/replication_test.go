@@ -0,0 +1,303 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+
+	"github.com/trustbloc/edv/pkg/edvprovider"
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+const (
+	testVaultID    = "testvault"
+	testDocumentID = "VJYHHJx4C8J9Fsgz7rZqSp"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		storageProvider := memstore.NewProvider()
+
+		_, err := New(&testSource{}, memedvprovider.NewProvider(), storageProvider, []string{testVaultID})
+		require.NoError(t, err)
+
+		// The replication store already exists the second time.
+		r, err := New(&testSource{}, memedvprovider.NewProvider(), storageProvider, []string{testVaultID})
+		require.NoError(t, err)
+		require.Equal(t, []string{testVaultID}, r.VaultIDs())
+	})
+	t.Run("Failed to create store", func(t *testing.T) {
+		storageProvider := mockstore.NewMockStoreProvider()
+		storageProvider.ErrCreateStore = errors.New("create store error")
+
+		r, err := New(&testSource{}, memedvprovider.NewProvider(), storageProvider, []string{testVaultID})
+		require.EqualError(t, err, "failed to create replication store: create store error")
+		require.Nil(t, r)
+	})
+	t.Run("Failed to open store", func(t *testing.T) {
+		storageProvider := mockstore.NewMockStoreProvider()
+		storageProvider.ErrOpenStoreHandle = errors.New("open store error")
+
+		r, err := New(&testSource{}, memedvprovider.NewProvider(), storageProvider, []string{testVaultID})
+		require.EqualError(t, err, "failed to open replication store: open store error")
+		require.Nil(t, r)
+	})
+}
+
+func TestReplicator_Status(t *testing.T) {
+	t.Run("Not replicated yet", func(t *testing.T) {
+		r, _, _ := newTestReplicator(t)
+
+		status, err := r.Status(testVaultID)
+		require.NoError(t, err)
+		require.Equal(t, testVaultID, status.VaultID)
+		require.Empty(t, status.Cursor)
+		require.Nil(t, status.UpdatedAt)
+		require.NotNil(t, status.Conflicts)
+		require.Empty(t, status.Conflicts)
+	})
+	t.Run("Vault not replicated", func(t *testing.T) {
+		r, _, _ := newTestReplicator(t)
+
+		status, err := r.Status("othervault")
+		require.Equal(t, edverrors.ErrVaultNotReplicated, err)
+		require.Nil(t, status)
+	})
+	t.Run("Vault not found locally", func(t *testing.T) {
+		r, err := New(&testSource{}, memedvprovider.NewProvider(), memstore.NewProvider(), []string{testVaultID})
+		require.NoError(t, err)
+
+		status, err := r.Status(testVaultID)
+		require.Equal(t, edverrors.ErrVaultNotFound, err)
+		require.Nil(t, status)
+	})
+	t.Run("Storage error", func(t *testing.T) {
+		storageProvider := mockstore.NewMockStoreProvider()
+
+		r, err := New(&testSource{}, newTestEDVProvider(t), storageProvider, []string{testVaultID})
+		require.NoError(t, err)
+
+		storageProvider.Store.Store[vaultStateKey(testVaultID)] = []byte("{")
+
+		status, err := r.Status(testVaultID)
+		require.EqualError(t, err, "failed to parse vault_testvault from replication store: "+
+			"unexpected end of JSON input")
+		require.Nil(t, status)
+	})
+}
+
+func TestReplicator_ResolveConflict(t *testing.T) {
+	t.Run("Keep remote", func(t *testing.T) {
+		r, remote, local := newTestConflict(t)
+
+		require.NoError(t, r.ResolveConflict(testVaultID, testDocumentID, models.ConflictKeepRemote))
+
+		requireDocument(t, local, testDocumentID, "remote edit")
+		requireNoConflicts(t, r)
+
+		// The remote version is the replicated one now, so the next remote change is applied.
+		putTestDocument(t, remote, testDocumentID, "second remote edit")
+
+		result, err := r.Replicate(context.Background(), testVaultID)
+		require.NoError(t, err)
+		require.Equal(t, &Result{Applied: 1}, result)
+		requireDocument(t, local, testDocumentID, "second remote edit")
+	})
+	t.Run("Keep local", func(t *testing.T) {
+		r, remote, local := newTestConflict(t)
+
+		require.NoError(t, r.ResolveConflict(testVaultID, testDocumentID, models.ConflictKeepLocal))
+
+		requireDocument(t, local, testDocumentID, "local edit")
+		requireNoConflicts(t, r)
+
+		// The local version is the replicated one now, so the next remote change replaces it.
+		putTestDocument(t, remote, testDocumentID, "second remote edit")
+
+		result, err := r.Replicate(context.Background(), testVaultID)
+		require.NoError(t, err)
+		require.Equal(t, &Result{Applied: 1}, result)
+		requireDocument(t, local, testDocumentID, "second remote edit")
+	})
+	t.Run("Invalid resolution", func(t *testing.T) {
+		r, _, _ := newTestConflict(t)
+
+		require.Equal(t, edverrors.ErrInvalidConflictResolution, r.ResolveConflict(testVaultID, testDocumentID, ""))
+	})
+	t.Run("Conflict not found", func(t *testing.T) {
+		r, _, _ := newTestReplicator(t)
+
+		require.Equal(t, edverrors.ErrConflictNotFound,
+			r.ResolveConflict(testVaultID, testDocumentID, models.ConflictKeepLocal))
+	})
+	t.Run("Vault not replicated", func(t *testing.T) {
+		r, _, _ := newTestReplicator(t)
+
+		require.Equal(t, edverrors.ErrVaultNotReplicated,
+			r.ResolveConflict("othervault", testDocumentID, models.ConflictKeepLocal))
+	})
+	t.Run("Remote document can't be read", func(t *testing.T) {
+		r, _, _ := newTestConflict(t)
+
+		r.source.(*testSource).errReadDocument = errors.New("read document error")
+
+		err := r.ResolveConflict(testVaultID, testDocumentID, models.ConflictKeepRemote)
+		require.EqualError(t, err, "failed to read document "+testDocumentID+
+			" from the remote EDV server: read document error")
+
+		status, err := r.Status(testVaultID)
+		require.NoError(t, err)
+		require.Len(t, status.Conflicts, 1)
+	})
+}
+
+func TestProviderWriter(t *testing.T) {
+	provider := newTestEDVProvider(t)
+	writer := providerWriter{provider: provider}
+
+	document := models.EncryptedDocument{ID: testDocumentID, JWE: []byte(`"original"`)}
+	require.NoError(t, writer.WriteReplicatedDocument(testVaultID, document))
+
+	document.JWE = []byte(`"remote edit"`)
+	require.NoError(t, writer.WriteReplicatedDocument(testVaultID, document))
+
+	store := openTestStore(t, provider)
+	requireDocument(t, store, testDocumentID, "remote edit")
+
+	feed, err := store.Changes("", 10)
+	require.NoError(t, err)
+	require.Len(t, feed.Changes, 2)
+	require.Equal(t, models.ChangeTypeCreated, feed.Changes[0].Type)
+	require.Equal(t, models.ChangeTypeUpdated, feed.Changes[1].Type)
+
+	err = writer.WriteReplicatedDocument("othervault", document)
+	require.Equal(t, storage.ErrStoreNotFound, err)
+}
+
+// testSource serves the documents of a vault in an EDV provider, standing in for a remote EDV server.
+type testSource struct {
+	provider        edvprovider.EDVProvider
+	errReadChanges  error
+	errReadDocument error
+}
+
+func (s *testSource) ReadChanges(vaultID, since string, limit int) (*models.ChangeFeed, error) {
+	if s.errReadChanges != nil {
+		return nil, s.errReadChanges
+	}
+
+	store, err := s.provider.OpenStore(vaultID)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.Changes(since, limit)
+}
+
+func (s *testSource) ReadDocument(vaultID, docID string) (*models.EncryptedDocument, error) {
+	if s.errReadDocument != nil {
+		return nil, s.errReadDocument
+	}
+
+	store, err := s.provider.OpenStore(vaultID)
+	if err != nil {
+		return nil, err
+	}
+
+	documentBytes, err := store.Get(docID)
+	if errors.Is(err, storage.ErrValueNotFound) {
+		return nil, edverrors.ErrDocumentNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	document := models.EncryptedDocument{}
+
+	return &document, json.Unmarshal(documentBytes, &document)
+}
+
+// newTestReplicator returns a replicator of the test vault along with the remote and local test vault stores.
+func newTestReplicator(t *testing.T, opts ...Option) (*Replicator, edvprovider.EDVStore, edvprovider.EDVStore) {
+	remoteProvider := newTestEDVProvider(t)
+	localProvider := newTestEDVProvider(t)
+
+	r, err := New(&testSource{provider: remoteProvider}, localProvider, memstore.NewProvider(),
+		[]string{testVaultID}, opts...)
+	require.NoError(t, err)
+
+	return r, openTestStore(t, remoteProvider), openTestStore(t, localProvider)
+}
+
+// newTestConflict returns a replicator with a conflict on the test document,
+// which was edited both locally and remotely after being replicated.
+func newTestConflict(t *testing.T) (*Replicator, edvprovider.EDVStore, edvprovider.EDVStore) {
+	r, remote, local := newTestReplicator(t)
+
+	putTestDocument(t, remote, testDocumentID, "original")
+
+	_, err := r.Replicate(context.Background(), testVaultID)
+	require.NoError(t, err)
+
+	putTestDocument(t, local, testDocumentID, "local edit")
+	putTestDocument(t, remote, testDocumentID, "remote edit")
+
+	result, err := r.Replicate(context.Background(), testVaultID)
+	require.NoError(t, err)
+	require.Equal(t, &Result{Conflicts: 1}, result)
+
+	return r, remote, local
+}
+
+func newTestEDVProvider(t *testing.T) *memedvprovider.MemEDVProvider {
+	provider := memedvprovider.NewProvider()
+	require.NoError(t, provider.CreateStore(testVaultID))
+
+	return provider
+}
+
+func openTestStore(t *testing.T, provider edvprovider.EDVProvider) edvprovider.EDVStore {
+	store, err := provider.OpenStore(testVaultID)
+	require.NoError(t, err)
+
+	return store
+}
+
+// putTestDocument stores a document whose JWE is the given content, so that different versions can be told apart.
+func putTestDocument(t *testing.T, store edvprovider.EDVStore, documentID, content string) {
+	contentBytes, err := json.Marshal(content)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Put(models.EncryptedDocument{ID: documentID, JWE: contentBytes}))
+}
+
+func requireDocument(t *testing.T, store edvprovider.EDVStore, documentID, content string) {
+	documentBytes, err := store.Get(documentID)
+	require.NoError(t, err)
+
+	document := models.EncryptedDocument{}
+	require.NoError(t, json.Unmarshal(documentBytes, &document))
+
+	var storedContent string
+	require.NoError(t, json.Unmarshal(document.JWE, &storedContent))
+	require.Equal(t, content, storedContent)
+}
+
+func requireNoConflicts(t *testing.T, r *Replicator) {
+	status, err := r.Status(testVaultID)
+	require.NoError(t, err)
+	require.Empty(t, status.Conflicts)
+}
@@ -0,0 +1,134 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package requestlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/trustbloc/edv/pkg/internal/common/support"
+)
+
+const (
+	// RequestIDHeader is the HTTP header used to receive and propagate request IDs.
+	RequestIDHeader = "X-Request-ID"
+
+	// RequestIDField is the name of the log field that holds the request ID.
+	RequestIDField = "requestID"
+
+	// TextFormat is the log format option for human-readable logs.
+	TextFormat = "text"
+	// JSONFormat is the log format option for JSON logs.
+	JSONFormat = "json"
+
+	maxRequestIDLength = 128
+)
+
+// ErrInvalidLogFormat is returned when an unsupported log format is specified.
+var ErrInvalidLogFormat = errors.New("invalid log format. Supported options: " + TextFormat + ", " + JSONFormat)
+
+type contextKey struct{}
+
+// Handler wraps the given handler so that every request is assigned a request ID and logged once it completes.
+// If the request already has a valid X-Request-ID header, then that ID is reused. Otherwise a new one is generated.
+// The request ID is sent back in the response's X-Request-ID header and is made available to downstream handlers
+// through RequestIDFromContext and Logger.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+
+		requestID := req.Header.Get(RequestIDHeader)
+		if !isValidRequestID(requestID) {
+			requestID = uuid.New().String()
+		}
+
+		rw.Header().Set(RequestIDHeader, requestID)
+
+		req = req.WithContext(context.WithValue(req.Context(), contextKey{}, requestID))
+
+		recorder := support.NewStatusRecorder(rw)
+
+		next.ServeHTTP(recorder, req)
+
+		Logger(req).WithFields(log.Fields{
+			"method":  req.Method,
+			"path":    req.URL.EscapedPath(),
+			"status":  recorder.Status(),
+			"latency": time.Since(start).String(),
+		}).Info("Handled request")
+	})
+}
+
+// RequestIDFromContext returns the request ID stored in the given context, or a blank string if there isn't one.
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(contextKey{}).(string) //nolint: errcheck
+
+	return requestID
+}
+
+// Logger returns a log entry that includes the ID of the given request, if it has one.
+func Logger(req *http.Request) *log.Entry {
+	entry := log.NewEntry(log.StandardLogger())
+
+	if req == nil {
+		return entry
+	}
+
+	if requestID := RequestIDFromContext(req.Context()); requestID != "" {
+		return entry.WithField(RequestIDField, requestID)
+	}
+
+	return entry
+}
+
+// SetLevel sets the level of the standard logger. Valid levels are the ones supported by logrus
+// (e.g. debug, info, warn, error).
+func SetLevel(level string) error {
+	parsedLevel, err := log.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	log.SetLevel(parsedLevel)
+
+	return nil
+}
+
+// SetFormat sets the format of the standard logger. Valid formats are text and json.
+func SetFormat(format string) error {
+	switch strings.ToLower(format) {
+	case TextFormat:
+		log.SetFormatter(&log.TextFormatter{})
+	case JSONFormat:
+		log.SetFormatter(&log.JSONFormatter{})
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidLogFormat, format)
+	}
+
+	return nil
+}
+
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range requestID {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+
+	return true
+}
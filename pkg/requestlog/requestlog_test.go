@@ -0,0 +1,128 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package requestlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	var logContents bytes.Buffer
+
+	log.SetOutput(&logContents)
+
+	defer log.SetOutput(os.Stderr)
+
+	var receivedRequestID string
+
+	handler := Handler(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		receivedRequestID = RequestIDFromContext(req.Context())
+
+		rw.WriteHeader(http.StatusTeapot)
+	}))
+
+	t.Run("Request ID propagated", func(t *testing.T) {
+		logContents.Reset()
+
+		req := httptest.NewRequest(http.MethodGet, "/encrypted-data-vaults/vault1", nil)
+		req.Header.Set(RequestIDHeader, "testRequestID")
+
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		require.Equal(t, "testRequestID", receivedRequestID)
+		require.Equal(t, "testRequestID", rr.Header().Get(RequestIDHeader))
+		require.Contains(t, logContents.String(), "requestID=testRequestID")
+		require.Contains(t, logContents.String(), "method=GET")
+		require.Contains(t, logContents.String(), "path=/encrypted-data-vaults/vault1")
+		require.Contains(t, logContents.String(), "status=418")
+		require.Contains(t, logContents.String(), "latency=")
+	})
+	t.Run("Request ID generated", func(t *testing.T) {
+		for _, requestID := range []string{"", "contains spaces", strings.Repeat("a", maxRequestIDLength+1)} {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(RequestIDHeader, requestID)
+
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.NotEmpty(t, receivedRequestID)
+			require.NotEqual(t, requestID, receivedRequestID)
+			require.Equal(t, receivedRequestID, rr.Header().Get(RequestIDHeader))
+		}
+	})
+	t.Run("Flush passed through", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		Handler(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			flusher, ok := rw.(http.Flusher)
+			require.True(t, ok)
+
+			flusher.Flush()
+		})).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		require.True(t, rr.Flushed)
+	})
+}
+
+func TestLogger(t *testing.T) {
+	t.Run("Nil request", func(t *testing.T) {
+		require.Empty(t, Logger(nil).Data)
+	})
+	t.Run("Request without ID", func(t *testing.T) {
+		require.Empty(t, Logger(httptest.NewRequest(http.MethodGet, "/", nil)).Data)
+	})
+}
+
+func TestSetLevel(t *testing.T) {
+	defer log.SetLevel(log.InfoLevel)
+
+	err := SetLevel("debug")
+	require.NoError(t, err)
+	require.Equal(t, log.DebugLevel, log.GetLevel())
+
+	err = SetLevel("loud")
+	require.Error(t, err)
+}
+
+func TestSetFormat(t *testing.T) {
+	var logContents bytes.Buffer
+
+	log.SetOutput(&logContents)
+
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFormatter(&log.TextFormatter{})
+	}()
+
+	err := SetFormat("JSON")
+	require.NoError(t, err)
+
+	log.Info("test message")
+
+	entry := make(map[string]interface{})
+	require.NoError(t, json.Unmarshal(logContents.Bytes(), &entry))
+	require.Equal(t, "test message", entry["msg"])
+
+	err = SetFormat(TextFormat)
+	require.NoError(t, err)
+
+	err = SetFormat("xml")
+	require.True(t, errors.Is(err, ErrInvalidLogFormat))
+}
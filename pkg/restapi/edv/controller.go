@@ -12,10 +12,11 @@ import (
 )
 
 // New returns new controller instance.
-func New(provider edvprovider.EDVProvider) (*Controller, error) {
+// Options can be given to configure the EDV operations (e.g. to enable audit logging).
+func New(provider edvprovider.EDVProvider, opts ...operation.Option) (*Controller, error) {
 	var allHandlers []operation.Handler
 
-	edvService := operation.New(provider)
+	edvService := operation.New(provider, opts...)
 	allHandlers = append(allHandlers, edvService.GetRESTHandlers()...)
 
 	return &Controller{handlers: allHandlers}, nil
@@ -28,21 +28,97 @@ func TestController_GetOperations(t *testing.T) {
 
 	ops := controller.GetOperations()
 
-	require.Equal(t, 4, len(ops))
+	require.Equal(t, 23, len(ops))
 
 	require.Equal(t, "/encrypted-data-vaults", ops[0].Path())
 	require.Equal(t, http.MethodPost, ops[0].Method())
 	require.NotNil(t, ops[0].Handle())
 
-	require.Equal(t, "/encrypted-data-vaults/{vaultID}/queries", ops[1].Path())
-	require.Equal(t, http.MethodPost, ops[1].Method())
+	require.Equal(t, "/encrypted-data-vaults", ops[1].Path())
+	require.Equal(t, http.MethodGet, ops[1].Method())
 	require.NotNil(t, ops[1].Handle())
 
-	require.Equal(t, "/encrypted-data-vaults/{vaultID}/documents", ops[2].Path())
+	require.Equal(t, "/encrypted-data-vaults/{vaultID}/queries", ops[2].Path())
 	require.Equal(t, http.MethodPost, ops[2].Method())
 	require.NotNil(t, ops[2].Handle())
 
-	require.Equal(t, "/encrypted-data-vaults/{vaultID}/documents/{docID}", ops[3].Path())
-	require.Equal(t, http.MethodGet, ops[3].Method())
+	require.Equal(t, "/encrypted-data-vaults/{vaultID}/documents", ops[3].Path())
+	require.Equal(t, http.MethodPost, ops[3].Method())
 	require.NotNil(t, ops[3].Handle())
+
+	require.Equal(t, "/encrypted-data-vaults/{vaultID}/documents/{docID}", ops[4].Path())
+	require.Equal(t, http.MethodGet, ops[4].Method())
+	require.NotNil(t, ops[4].Handle())
+
+	require.Equal(t, "/encrypted-data-vaults/{vaultID}/documents", ops[5].Path())
+	require.Equal(t, http.MethodGet, ops[5].Method())
+	require.NotNil(t, ops[5].Handle())
+
+	require.Equal(t, "/encrypted-data-vaults/{vaultID}/batch", ops[6].Path())
+	require.Equal(t, http.MethodPost, ops[6].Method())
+	require.NotNil(t, ops[6].Handle())
+
+	require.Equal(t, "/encrypted-data-vaults/{vaultID}/export", ops[7].Path())
+	require.Equal(t, http.MethodGet, ops[7].Method())
+	require.NotNil(t, ops[7].Handle())
+
+	require.Equal(t, "/encrypted-data-vaults/{vaultID}/import", ops[8].Path())
+	require.Equal(t, http.MethodPost, ops[8].Method())
+	require.NotNil(t, ops[8].Handle())
+
+	require.Equal(t, "/encrypted-data-vaults/{vaultID}/reindex", ops[9].Path())
+	require.Equal(t, http.MethodPost, ops[9].Method())
+	require.NotNil(t, ops[9].Handle())
+
+	require.Equal(t, "/encrypted-data-vaults/{vaultID}/stats", ops[10].Path())
+	require.Equal(t, http.MethodGet, ops[10].Method())
+	require.NotNil(t, ops[10].Handle())
+
+	require.Equal(t, "/encrypted-data-vaults/{vaultID}/changes", ops[11].Path())
+	require.Equal(t, http.MethodGet, ops[11].Method())
+	require.NotNil(t, ops[11].Handle())
+
+	require.Equal(t, "/encrypted-data-vaults/{vaultID}/events", ops[12].Path())
+	require.Equal(t, http.MethodGet, ops[12].Method())
+	require.NotNil(t, ops[12].Handle())
+
+	require.Equal(t, "/encrypted-data-vaults/{vaultID}/webhooks", ops[13].Path())
+	require.Equal(t, http.MethodPost, ops[13].Method())
+	require.NotNil(t, ops[13].Handle())
+
+	require.Equal(t, "/encrypted-data-vaults/{vaultID}/webhooks", ops[14].Path())
+	require.Equal(t, http.MethodGet, ops[14].Method())
+	require.NotNil(t, ops[14].Handle())
+
+	require.Equal(t, "/encrypted-data-vaults/{vaultID}/webhooks/{webhookID}", ops[15].Path())
+	require.Equal(t, http.MethodGet, ops[15].Method())
+	require.NotNil(t, ops[15].Handle())
+
+	require.Equal(t, "/encrypted-data-vaults/{vaultID}/webhooks/{webhookID}", ops[16].Path())
+	require.Equal(t, http.MethodDelete, ops[16].Method())
+	require.NotNil(t, ops[16].Handle())
+
+	require.Equal(t, "/encrypted-data-vaults/{vaultID}/webhooks/{webhookID}/deliveries", ops[17].Path())
+	require.Equal(t, http.MethodGet, ops[17].Method())
+	require.NotNil(t, ops[17].Handle())
+
+	require.Equal(t, "/encrypted-data-vaults/{vaultID}/replication", ops[18].Path())
+	require.Equal(t, http.MethodGet, ops[18].Method())
+	require.NotNil(t, ops[18].Handle())
+
+	require.Equal(t, "/encrypted-data-vaults/{vaultID}/replication/conflicts/{docID}", ops[19].Path())
+	require.Equal(t, http.MethodPost, ops[19].Method())
+	require.NotNil(t, ops[19].Handle())
+
+	require.Equal(t, "/healthcheck", ops[20].Path())
+	require.Equal(t, http.MethodGet, ops[20].Method())
+	require.NotNil(t, ops[20].Handle())
+
+	require.Equal(t, "/readiness", ops[21].Path())
+	require.Equal(t, http.MethodGet, ops[21].Method())
+	require.NotNil(t, ops[21].Handle())
+
+	require.Equal(t, "/openapi.json", ops[22].Path())
+	require.Equal(t, http.MethodGet, ops[22].Method())
+	require.NotNil(t, ops[22].Handle())
 }
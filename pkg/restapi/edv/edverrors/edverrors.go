@@ -9,6 +9,9 @@ package edverrors
 const (
 	// ErrVaultNotFound is used when a vault could not be found in the provider.
 	ErrVaultNotFound = edvError("specified vault does not exist")
+	// ErrReservedVaultID is the error returned by the EDV server when an attempt is made to create a vault with
+	// the name of one of the stores the EDV server keeps its own data in.
+	ErrReservedVaultID = edvError("vault ID is reserved by the EDV server")
 	// ErrDocumentNotFound is used when a document could not be found in a vault.
 	ErrDocumentNotFound = edvError("specified document does not exist")
 	// ErrDuplicateVault is used when an attempt is made to create a vault under a name that is already being used.
@@ -22,6 +25,105 @@ const (
 	// to create a document with an ID that is base58-encoded, but the original value was not 128 bits long
 	// (which is required by the EDV spec).
 	ErrNot128BitValue = edvError("document ID is base58-encoded, but original value before encoding was not 128 bits long")
+	// ErrRateLimitExceeded is the error returned by the EDV server when a client or vault has made too many requests
+	// in too short a time.
+	ErrRateLimitExceeded = edvError("rate limit exceeded")
+	// ErrDocumentTooLarge is the error returned by the EDV server when an attempt is made to create a document
+	// that is larger than the maximum document size allowed by the vault's quota.
+	ErrDocumentTooLarge = edvError("document exceeds the maximum document size allowed in this vault")
+	// ErrVaultQuotaExceeded is the error returned by the EDV server when an attempt is made to create a document
+	// in a vault that has reached the maximum number of documents or bytes allowed by its quota.
+	ErrVaultQuotaExceeded = edvError("vault storage quota exceeded")
+	// ErrInvalidVaultQuota is the error returned by the EDV server when an attempt is made to create a vault
+	// with negative quota limits.
+	ErrInvalidVaultQuota = edvError("vault quota limits can't be negative")
+	// ErrRequestBodyTooLarge is the error returned by the EDV server when a request body is larger than the maximum
+	// size allowed for the endpoint.
+	ErrRequestBodyTooLarge = edvError("request body too large")
+	// ErrTrailingData is the error returned by the EDV server in strict JSON mode when a request body has data
+	// after its JSON value.
+	ErrTrailingData = edvError("request body has data after the JSON value")
+	// ErrMissingDocumentID is the error returned by the EDV server when an attempt is made to create a document
+	// without an ID.
+	ErrMissingDocumentID = edvError("document ID is required")
+	// ErrMissingJWE is the error returned by the EDV server when an attempt is made to create a document
+	// without a JWE.
+	ErrMissingJWE = edvError("document jwe is required")
+	// ErrInvalidVaultPolicy is the error returned by the EDV server when an attempt is made to create a vault with a
+	// policy that enforces key IDs the vault configuration doesn't set.
+	ErrInvalidVaultPolicy = edvError("vault policy enforces a key ID that isn't set in the vault configuration")
+	// ErrKEKMismatch is the error returned by the EDV server when the vault policy requires documents to be encrypted
+	// to the vault's KEK and a JWE recipient uses a different key.
+	ErrKEKMismatch = edvError("jwe recipient key ID doesn't match the vault's KEK")
+	// ErrHMACMismatch is the error returned by the EDV server when the vault policy requires indexed attributes to use
+	// the vault's HMAC key and an indexed attribute collection uses a different key.
+	ErrHMACMismatch = edvError("indexed attribute collection HMAC key ID doesn't match the vault's HMAC key")
+	// ErrInvalidDocumentIDFormat is the error returned by the EDV server when an attempt is made to create a vault
+	// with a policy that has an unsupported document ID format.
+	ErrInvalidDocumentIDFormat = edvError("unsupported document ID format. Supported formats: base58, uuid, any")
+	// ErrNotUUID is the error returned by the EDV server when an attempt is made to create a document in a vault
+	// that requires UUID document IDs with an ID that isn't a UUID in its canonical form.
+	ErrNotUUID = edvError("document ID must be a UUID in its canonical lowercase form")
+	// ErrInvalidDocumentID is the error returned by the EDV server when an attempt is made to create a document
+	// with an ID that conflicts with the IDs the EDV server uses internally.
+	ErrInvalidDocumentID = edvError("document ID can't be longer than 256 characters, start with an underscore " +
+		"or contain _mapping_")
+	// ErrInvalidChangeCursor is the error returned by the EDV server when the cursor for reading a vault's
+	// change feed isn't one that the vault issued.
+	ErrInvalidChangeCursor = edvError("invalid change feed cursor")
+	// ErrInvalidChangeLimit is the error returned by the EDV server when the maximum number of changes to read from a
+	// vault's change feed isn't a positive integer.
+	ErrInvalidChangeLimit = edvError("change feed limit must be a positive integer")
+	// ErrInvalidDocumentListLimit is the error returned by the EDV server when the maximum number of documents to list
+	// from a vault isn't a positive integer.
+	ErrInvalidDocumentListLimit = edvError("document list limit must be a positive integer")
+	// ErrInvalidIncludeDocuments is the error returned by the EDV server when the includeDocuments parameter
+	// of a document list request isn't a boolean.
+	ErrInvalidIncludeDocuments = edvError("includeDocuments must be true or false")
+	// ErrMissingController is the error returned by the EDV server when vaults are listed without saying
+	// which controller's vaults to list.
+	ErrMissingController = edvError("controller must be set")
+	// ErrUnauthenticatedController is the error returned by the EDV server when vaults are listed by a client
+	// that didn't authenticate itself.
+	ErrUnauthenticatedController = edvError("listing vaults requires an authenticated client")
+	// ErrControllerMismatch is the error returned by the EDV server when a client lists the vaults of a controller
+	// other than itself.
+	ErrControllerMismatch = edvError("vaults can only be listed by their controller")
+	// ErrWebhookNotFound is the error returned by the EDV server when a webhook could not be found in a vault.
+	ErrWebhookNotFound = edvError("specified webhook does not exist")
+	// ErrInvalidWebhookURL is the error returned by the EDV server when an attempt is made to create a webhook
+	// with a URL that isn't an absolute HTTPS URL.
+	ErrInvalidWebhookURL = edvError("webhook URL must be an absolute https URL")
+	// ErrWebhookURLNotAllowed is the error returned by the EDV server when an attempt is made to create a webhook
+	// with a URL whose host resolves to a loopback, private or link-local address, or isn't an allowed host.
+	ErrWebhookURLNotAllowed = edvError("webhook URL host is not allowed")
+	// ErrWebhooksDisabled is the error returned by the EDV server when webhooks are used but weren't enabled.
+	ErrWebhooksDisabled = edvError("webhooks are not enabled on this EDV server")
+	// ErrReplicationDisabled is the error returned by the EDV server when replication is used but wasn't enabled.
+	ErrReplicationDisabled = edvError("replication is not enabled on this EDV server")
+	// ErrVaultNotReplicated is the error returned by the EDV server when the replication status of a vault that isn't
+	// replicated from another EDV server is requested.
+	ErrVaultNotReplicated = edvError("specified vault is not replicated from another EDV server")
+	// ErrConflictNotFound is the error returned by the EDV server when an attempt is made to resolve a replication
+	// conflict that doesn't exist.
+	ErrConflictNotFound = edvError("specified document has no replication conflict")
+	// ErrInvalidConflictResolution is the error returned by the EDV server when a replication conflict resolution
+	// doesn't say which version of the document to keep.
+	ErrInvalidConflictResolution = edvError("conflict resolution must keep either the local or the remote version")
+	// ErrEmptyBatch is the error returned by the EDV server when a batch request has no operations.
+	ErrEmptyBatch = edvError("batch must contain at least one operation")
+	// ErrBatchTooLarge is the error returned by the EDV server when a batch request has more operations than the
+	// EDV server accepts in one request.
+	ErrBatchTooLarge = edvError("batch contains too many operations")
+	// ErrInvalidBatchOperation is the error returned by the EDV server for a batch operation whose type isn't
+	// create, update or delete.
+	ErrInvalidBatchOperation = edvError("batch operation type must be create, update or delete")
+	// ErrMissingBatchDocument is the error returned by the EDV server for a batch create or update operation
+	// without a document.
+	ErrMissingBatchDocument = edvError("batch create and update operations require a document")
+	// ErrRepeatedBatchDocument is the error returned by the EDV server for a batch operation on a document that
+	// an earlier operation in the same batch is already about.
+	ErrRepeatedBatchDocument = edvError("document is already the subject of another operation in the batch")
 	// QueryVaultFailureToWriteFailureResponseErrMsg is used when an unexpected failure happens while the response is
 	// being written after a failure occurs while querying a vault.
 	QueryVaultFailureToWriteFailureResponseErrMsg = "Failed to write response for vault query failure: %s"
@@ -6,17 +6,55 @@ SPDX-License-Identifier: Apache-2.0
 
 package models
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+)
 
 // DataVaultConfiguration represents a Data Vault Configuration.
 type DataVaultConfiguration struct {
-	Sequence    int        `json:"sequence"`
-	Controller  string     `json:"controller"`
-	Invoker     string     `json:"invoker"`
-	Delegator   string     `json:"delegator"`
-	ReferenceID string     `json:"referenceId"`
-	KEK         IDTypePair `json:"kek"`
-	HMAC        IDTypePair `json:"hmac"`
+	Sequence    int          `json:"sequence"`
+	Controller  string       `json:"controller"`
+	Invoker     string       `json:"invoker"`
+	Delegator   string       `json:"delegator"`
+	ReferenceID string       `json:"referenceId"`
+	KEK         IDTypePair   `json:"kek"`
+	HMAC        IDTypePair   `json:"hmac"`
+	Quota       *VaultQuota  `json:"quota,omitempty"`
+	Policy      *VaultPolicy `json:"policy,omitempty"`
+}
+
+// VaultQuota represents the storage limits of a data vault. Limits that are 0 aren't enforced.
+type VaultQuota struct {
+	MaxDocuments    int64 `json:"maxDocuments,omitempty"`
+	MaxBytes        int64 `json:"maxBytes,omitempty"`
+	MaxDocumentSize int64 `json:"maxDocumentSize,omitempty"`
+}
+
+// VaultPolicy represents the rules that documents must follow to be stored in a data vault.
+type VaultPolicy struct {
+	// EnforceKEK requires every JWE recipient's key ID to match the ID of the vault's KEK.
+	EnforceKEK bool `json:"enforceKek,omitempty"`
+	// EnforceHMAC requires every indexed attribute collection's HMAC key ID to match the ID of the vault's HMAC key.
+	EnforceHMAC bool `json:"enforceHmac,omitempty"`
+	// DocumentIDFormat is the format that document IDs must have. Defaults to DocumentIDFormatBase58.
+	DocumentIDFormat string `json:"documentIdFormat,omitempty"`
+}
+
+const (
+	// DocumentIDFormatBase58 requires document IDs to be base58-encoded 128-bit values, as the EDV specification does.
+	DocumentIDFormatBase58 = "base58"
+	// DocumentIDFormatUUID requires document IDs to be UUIDs in their canonical lowercase form.
+	DocumentIDFormatUUID = "uuid"
+	// DocumentIDFormatAny allows any document ID that doesn't conflict with the IDs the EDV server uses internally.
+	DocumentIDFormatAny = "any"
+)
+
+// VaultStats represents the current storage usage of a data vault, along with its limits if it has any.
+type VaultStats struct {
+	DocumentCount int64       `json:"documentCount"`
+	TotalBytes    int64       `json:"totalBytes"`
+	Quota         *VaultQuota `json:"quota,omitempty"`
 }
 
 // StructuredDocument represents a Structured Document.
@@ -60,3 +98,242 @@ type Query struct {
 	Name  string `json:"index"`
 	Value string `json:"equals"`
 }
+
+// ChangeFeed represents the changes made to the documents in a data vault since a cursor.
+// Cursor is where the next read of the change feed should resume from. Cursors are opaque and can only be used
+// with the vault they came from. HasMore is true if there are further changes after Cursor.
+type ChangeFeed struct {
+	Changes []DocumentChange `json:"changes"`
+	Cursor  string           `json:"cursor"`
+	HasMore bool             `json:"hasMore"`
+}
+
+// DocumentList represents a page of the documents in a data vault, sorted by ID. Cursor is where the next page
+// should start from. Cursors are opaque and can only be used with the vault they came from. HasMore is true if
+// there are further documents after Cursor. Documents is only set if the full documents were requested.
+type DocumentList struct {
+	DocumentIDs []string            `json:"documentIds"`
+	Documents   []EncryptedDocument `json:"documents,omitempty"`
+	Cursor      string              `json:"cursor"`
+	HasMore     bool                `json:"hasMore"`
+}
+
+// VaultList represents the IDs of the data vaults that a controller controls, sorted.
+type VaultList struct {
+	Controller string   `json:"controller"`
+	VaultIDs   []string `json:"vaultIds"`
+}
+
+// DocumentChange represents a single change to a document in a data vault.
+// Sequence identifies the change within the vault's change feed and can be used as a cursor.
+type DocumentChange struct {
+	ID       string `json:"id"`
+	Sequence string `json:"sequence"`
+	Type     string `json:"type"`
+}
+
+const (
+	// ChangeTypeCreated is the type of change made when a new document is stored.
+	ChangeTypeCreated = "created"
+	// ChangeTypeUpdated is the type of change made when an existing document is replaced.
+	ChangeTypeUpdated = "updated"
+	// ChangeTypeDeleted is the type of change made when a document is deleted.
+	ChangeTypeDeleted = "deleted"
+)
+
+// WebhookRequest represents a request to subscribe a callback URL to the events of a data vault.
+type WebhookRequest struct {
+	URL string `json:"url"`
+}
+
+// Webhook represents a callback URL subscribed to the events of a data vault.
+// Secret is the key that event payloads are signed with. It's only returned when the webhook is created.
+type Webhook struct {
+	ID        string    `json:"id"`
+	VaultID   string    `json:"vaultId"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// WebhookEvent represents the payload sent to a webhook when a document in its vault changes.
+// DeliveryID is the same for every attempt to deliver the event, so receivers can use it to ignore duplicates.
+type WebhookEvent struct {
+	DeliveryID string         `json:"deliveryId"`
+	WebhookID  string         `json:"webhookId"`
+	VaultID    string         `json:"vaultId"`
+	Change     DocumentChange `json:"change"`
+}
+
+// WebhookDelivery represents the status of the delivery of an event to a webhook.
+type WebhookDelivery struct {
+	ID             string         `json:"id"`
+	WebhookID      string         `json:"webhookId"`
+	Change         DocumentChange `json:"change"`
+	Status         string         `json:"status"`
+	Attempts       int            `json:"attempts"`
+	CreatedAt      time.Time      `json:"createdAt"`
+	LastAttemptAt  *time.Time     `json:"lastAttemptAt,omitempty"`
+	NextAttemptAt  *time.Time     `json:"nextAttemptAt,omitempty"`
+	LastStatusCode int            `json:"lastStatusCode,omitempty"`
+	LastError      string         `json:"lastError,omitempty"`
+}
+
+const (
+	// DeliveryStatusPending is the status of a webhook delivery that hasn't succeeded yet but will be retried.
+	DeliveryStatusPending = "pending"
+	// DeliveryStatusDelivered is the status of a webhook delivery that the receiver acknowledged.
+	DeliveryStatusDelivered = "delivered"
+	// DeliveryStatusFailed is the status of a webhook delivery that was given up on.
+	DeliveryStatusFailed = "failed"
+)
+
+// ReplicationStatus represents how far a vault has been replicated from another EDV server.
+// Cursor is the position in the other EDV server's change feed up to which changes have been applied.
+type ReplicationStatus struct {
+	VaultID   string                `json:"vaultId"`
+	Cursor    string                `json:"cursor"`
+	UpdatedAt *time.Time            `json:"updatedAt,omitempty"`
+	LastError string                `json:"lastError,omitempty"`
+	Conflicts []ReplicationConflict `json:"conflicts"`
+}
+
+// ReplicationConflict represents a document that was changed both locally and on the other EDV server since it was
+// last replicated. The local version is kept until the conflict is resolved.
+type ReplicationConflict struct {
+	DocumentID     string    `json:"documentId"`
+	RemoteSequence string    `json:"remoteSequence"`
+	DetectedAt     time.Time `json:"detectedAt"`
+}
+
+// ConflictResolution represents a request to resolve a replication conflict by keeping either the local or the
+// remote version of the document.
+type ConflictResolution struct {
+	Keep string `json:"keep"`
+}
+
+const (
+	// ConflictKeepLocal resolves a replication conflict by keeping the local version of the document.
+	ConflictKeepLocal = "local"
+	// ConflictKeepRemote resolves a replication conflict by replacing the local document with the remote version.
+	ConflictKeepRemote = "remote"
+)
+
+// BatchRequest represents a list of document operations to apply to a data vault in a single request.
+type BatchRequest struct {
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchOperation represents a single document operation in a batch request.
+// Create and update operations carry the whole document, while delete operations only carry the document's ID.
+type BatchOperation struct {
+	Type     string             `json:"type"`
+	Document *EncryptedDocument `json:"document,omitempty"`
+	ID       string             `json:"id,omitempty"`
+}
+
+const (
+	// BatchOperationCreate creates a new document. It fails if there's already a document with the same ID.
+	BatchOperationCreate = "create"
+	// BatchOperationUpdate replaces an existing document. It fails if there's no document with the same ID.
+	BatchOperationUpdate = "update"
+	// BatchOperationDelete deletes an existing document. It fails if there's no document with the given ID.
+	BatchOperationDelete = "delete"
+)
+
+// BatchResponse represents the outcome of each operation in a batch request, in the same order as the operations.
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+}
+
+// BatchResult represents the outcome of a single operation in a batch request.
+// Status is the HTTP status code that the equivalent single-document request would have returned.
+type BatchResult struct {
+	ID     string `json:"id"`
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ExportRecord represents a line of a vault export, which is newline-delimited JSON. An export starts with a vault
+// record holding the vault's configuration, followed by a document record for each document and an end record.
+// Exports without an end record are incomplete.
+type ExportRecord struct {
+	Type          string                  `json:"type"`
+	Configuration *DataVaultConfiguration `json:"configuration,omitempty"`
+	Document      *EncryptedDocument      `json:"document,omitempty"`
+	// Checksum is the hex-encoded SHA-256 hash of the document's JSON serialization in document records,
+	// and the hex-encoded SHA-256 hash of the checksums of all the documents, in order, in the end record.
+	Checksum string `json:"checksum,omitempty"`
+	// DocumentCount is the number of documents in the export. It's only set in the end record.
+	DocumentCount int `json:"documentCount,omitempty"`
+}
+
+const (
+	// ExportRecordVault is the type of the first record of a vault export, which holds the vault's configuration.
+	ExportRecordVault = "vault"
+	// ExportRecordDocument is the type of the records of a vault export that hold a document.
+	ExportRecordDocument = "document"
+	// ExportRecordEnd is the type of the last record of a vault export.
+	ExportRecordEnd = "end"
+)
+
+// ImportResult represents the outcome of a vault import. Documents that were already in the vault with the same
+// contents are skipped, so an import that was interrupted can be resumed by importing the same export again.
+type ImportResult struct {
+	VaultCreated bool `json:"vaultCreated"`
+	Imported     int  `json:"imported"`
+	Skipped      int  `json:"skipped"`
+	// Failed holds the outcome of each document that couldn't be imported.
+	Failed []BatchResult `json:"failed,omitempty"`
+}
+
+// ReindexResult represents the outcome of rebuilding a vault's encrypted indexes from its documents.
+// Uniqueness violations can't be fixed by reindexing, since they're in the documents themselves, so they're
+// only reported.
+type ReindexResult struct {
+	Documents            int                   `json:"documents"`
+	MappingsDeleted      int                   `json:"mappingsDeleted"`
+	MappingsCreated      int                   `json:"mappingsCreated"`
+	UnparsableDocuments  []string              `json:"unparsableDocuments,omitempty"`
+	UniquenessViolations []UniquenessViolation `json:"uniquenessViolations,omitempty"`
+}
+
+// ConsistencyReport represents what was found by checking that a vault's documents and its encrypted indexes agree.
+// Orphaned mappings and unindexed documents are fixed if the vault was repaired. Unparsable documents and
+// uniqueness violations are only reported.
+type ConsistencyReport struct {
+	Documents            int                   `json:"documents"`
+	OrphanedMappings     []string              `json:"orphanedMappings,omitempty"`
+	UnindexedDocuments   []string              `json:"unindexedDocuments,omitempty"`
+	UnparsableDocuments  []string              `json:"unparsableDocuments,omitempty"`
+	UniquenessViolations []UniquenessViolation `json:"uniquenessViolations,omitempty"`
+	Repaired             bool                  `json:"repaired"`
+}
+
+// UniquenessViolation represents an indexed attribute name and value that are declared unique,
+// but that more than one document has.
+type UniquenessViolation struct {
+	Name        string   `json:"name"`
+	Value       string   `json:"value"`
+	DocumentIDs []string `json:"documentIds"`
+}
+
+// HealthCheckResponse represents the response returned by the health check (liveness) endpoint.
+type HealthCheckResponse struct {
+	Status      string    `json:"status"`
+	CurrentTime time.Time `json:"currentTime"`
+	Version     string    `json:"version"`
+}
+
+// ReadinessResponse represents the response returned by the readiness endpoint.
+type ReadinessResponse struct {
+	Status       string                      `json:"status"`
+	Version      string                      `json:"version"`
+	Dependencies map[string]DependencyStatus `json:"dependencies"`
+}
+
+// DependencyStatus represents the status of a single dependency of the EDV server.
+type DependencyStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
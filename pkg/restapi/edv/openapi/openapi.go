@@ -0,0 +1,1093 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package openapi holds the OpenAPI 3 document describing the EDV REST API.
+// The document must be updated whenever an endpoint or model changes. Tests fail if it drifts from the
+// registered handlers or from the models package.
+package openapi
+
+// Spec is the OpenAPI 3 document describing every endpoint served by the EDV server.
+const Spec = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "TrustBloc EDV",
+    "description": "Encrypted Data Vault server implementing the Secure Data Store (SDS) EDV specification.",
+    "license": {
+      "name": "Apache-2.0",
+      "url": "https://www.apache.org/licenses/LICENSE-2.0"
+    },
+    "version": "0.1.3"
+  },
+  "paths": {
+    "/encrypted-data-vaults": {
+      "post": {
+        "summary": "Create a data vault",
+        "operationId": "createDataVault",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"$ref": "#/components/schemas/DataVaultConfiguration"}
+            }
+          }
+        },
+        "responses": {
+          "201": {
+            "description": "The data vault was created.",
+            "headers": {
+              "Location": {
+                "description": "The URL of the new data vault.",
+                "schema": {"type": "string"}
+              }
+            }
+          },
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "409": {"$ref": "#/components/responses/Conflict"},
+          "413": {"$ref": "#/components/responses/PayloadTooLarge"},
+          "429": {"$ref": "#/components/responses/TooManyRequests"}
+        }
+      },
+      "get": {
+        "summary": "List the data vaults a controller controls",
+        "description": "Only the controller itself can list its data vaults, so the client must authenticate with a TLS client certificate whose identity is the controller.",
+        "operationId": "listVaults",
+        "parameters": [
+          {
+            "name": "controller",
+            "in": "query",
+            "required": true,
+            "description": "The controller whose data vaults are listed, e.g. a DID.",
+            "schema": {"type": "string"}
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The IDs of the controller's data vaults.",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/VaultList"}
+              }
+            }
+          },
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {
+            "description": "The client didn't authenticate itself.",
+            "content": {"text/plain": {"schema": {"$ref": "#/components/schemas/Error"}}}
+          },
+          "403": {
+            "description": "The client isn't the controller.",
+            "content": {"text/plain": {"schema": {"$ref": "#/components/schemas/Error"}}}
+          },
+          "429": {"$ref": "#/components/responses/TooManyRequests"},
+          "500": {"$ref": "#/components/responses/InternalServerError"}
+        }
+      }
+    },
+    "/encrypted-data-vaults/{vaultID}/queries": {
+      "post": {
+        "summary": "Query a data vault's encrypted indices",
+        "operationId": "queryVault",
+        "parameters": [{"$ref": "#/components/parameters/VaultID"}],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"$ref": "#/components/schemas/Query"}
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The URLs of the matching documents, or a plain text message if there are none.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {"type": "string"}
+                }
+              },
+              "text/plain": {
+                "schema": {"$ref": "#/components/schemas/Error"}
+              }
+            }
+          },
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "413": {"$ref": "#/components/responses/PayloadTooLarge"},
+          "429": {"$ref": "#/components/responses/TooManyRequests"},
+          "500": {"$ref": "#/components/responses/InternalServerError"}
+        }
+      }
+    },
+    "/encrypted-data-vaults/{vaultID}/documents": {
+      "post": {
+        "summary": "Store an encrypted document in a data vault",
+        "operationId": "createDocument",
+        "parameters": [{"$ref": "#/components/parameters/VaultID"}],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"$ref": "#/components/schemas/EncryptedDocument"}
+            }
+          }
+        },
+        "responses": {
+          "201": {
+            "description": "The document was stored.",
+            "headers": {
+              "Location": {
+                "description": "The URL of the new document.",
+                "schema": {"type": "string"}
+              }
+            }
+          },
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "409": {"$ref": "#/components/responses/Conflict"},
+          "413": {
+            "description": "The request body or the document is larger than the server or the vault allows.",
+            "content": {"text/plain": {"schema": {"$ref": "#/components/schemas/Error"}}}
+          },
+          "429": {"$ref": "#/components/responses/TooManyRequests"},
+          "500": {"$ref": "#/components/responses/InternalServerError"},
+          "507": {
+            "description": "The vault has reached the maximum number of documents or bytes allowed by its quota.",
+            "content": {"text/plain": {"schema": {"$ref": "#/components/schemas/Error"}}}
+          }
+        }
+      },
+      "get": {
+        "summary": "List the documents in a data vault",
+        "description": "Returns the IDs of the vault's documents, sorted, a page at a time.",
+        "operationId": "listDocuments",
+        "parameters": [
+          {"$ref": "#/components/parameters/VaultID"},
+          {
+            "name": "cursor",
+            "in": "query",
+            "description": "A cursor from a previous response. Defaults to the start.",
+            "schema": {"type": "string"}
+          },
+          {
+            "name": "limit",
+            "in": "query",
+            "description": "The maximum number of documents to list. Defaults to 100 and can't be more than 1000.",
+            "schema": {"type": "integer", "minimum": 1}
+          },
+          {
+            "name": "includeDocuments",
+            "in": "query",
+            "description": "Whether to return the full documents along with their IDs. Defaults to false.",
+            "schema": {"type": "boolean"}
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "A page of the vault's documents.",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/DocumentList"}
+              }
+            }
+          },
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "429": {"$ref": "#/components/responses/TooManyRequests"},
+          "500": {"$ref": "#/components/responses/InternalServerError"}
+        }
+      }
+    },
+    "/encrypted-data-vaults/{vaultID}/documents/{docID}": {
+      "get": {
+        "summary": "Read an encrypted document from a data vault",
+        "operationId": "readDocument",
+        "parameters": [
+          {"$ref": "#/components/parameters/VaultID"},
+          {"$ref": "#/components/parameters/DocID"}
+        ],
+        "responses": {
+          "200": {
+            "description": "The encrypted document.",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/EncryptedDocument"}
+              }
+            }
+          },
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "500": {"$ref": "#/components/responses/InternalServerError"}
+        }
+      }
+    },
+    "/encrypted-data-vaults/{vaultID}/batch": {
+      "post": {
+        "summary": "Create, update and delete documents in a data vault in a single request",
+        "description": "Each operation is checked like a single document request and succeeds or fails on its own.",
+        "operationId": "batchDocuments",
+        "parameters": [{"$ref": "#/components/parameters/VaultID"}],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"$ref": "#/components/schemas/BatchRequest"}
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The outcome of each operation, in the same order as the operations.",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/BatchResponse"}
+              }
+            }
+          },
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "413": {"$ref": "#/components/responses/PayloadTooLarge"},
+          "429": {"$ref": "#/components/responses/TooManyRequests"},
+          "500": {"$ref": "#/components/responses/InternalServerError"}
+        }
+      }
+    },
+    "/encrypted-data-vaults/{vaultID}/export": {
+      "get": {
+        "summary": "Export a data vault's configuration and documents",
+        "description": "Documents are checksummed. Exports that end without an end record are incomplete.",
+        "operationId": "exportVault",
+        "parameters": [{"$ref": "#/components/parameters/VaultID"}],
+        "responses": {
+          "200": {
+            "description": "A stream of export records, one JSON object per line.",
+            "content": {
+              "application/x-ndjson": {
+                "schema": {"$ref": "#/components/schemas/ExportRecord"}
+              }
+            }
+          },
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "500": {"$ref": "#/components/responses/InternalServerError"}
+        }
+      }
+    },
+    "/encrypted-data-vaults/{vaultID}/import": {
+      "post": {
+        "summary": "Import a data vault export, creating the vault if it doesn't exist",
+        "description": "Documents that are already in the vault with the same contents are skipped.",
+        "operationId": "importVault",
+        "parameters": [{"$ref": "#/components/parameters/VaultID"}],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/x-ndjson": {
+              "schema": {"$ref": "#/components/schemas/ExportRecord"}
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The outcome of the import.",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/ImportResult"}
+              }
+            }
+          },
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "413": {"$ref": "#/components/responses/PayloadTooLarge"},
+          "429": {"$ref": "#/components/responses/TooManyRequests"},
+          "500": {"$ref": "#/components/responses/InternalServerError"}
+        }
+      }
+    },
+    "/encrypted-data-vaults/{vaultID}/reindex": {
+      "post": {
+        "summary": "Rebuild a data vault's encrypted indexes from its documents",
+        "description": "Out of date index entries are removed and missing ones are added. Uniqueness violations are only reported.",
+        "operationId": "reindexVault",
+        "parameters": [{"$ref": "#/components/parameters/VaultID"}],
+        "responses": {
+          "200": {
+            "description": "The outcome of the reindex.",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/ReindexResult"}
+              }
+            }
+          },
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "429": {"$ref": "#/components/responses/TooManyRequests"},
+          "500": {"$ref": "#/components/responses/InternalServerError"},
+          "501": {"$ref": "#/components/responses/NotImplemented"}
+        }
+      }
+    },
+    "/encrypted-data-vaults/{vaultID}/stats": {
+      "get": {
+        "summary": "Get the current storage usage of a data vault",
+        "operationId": "readVaultStats",
+        "parameters": [{"$ref": "#/components/parameters/VaultID"}],
+        "responses": {
+          "200": {
+            "description": "The vault's usage and quota.",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/VaultStats"}
+              }
+            }
+          },
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "500": {"$ref": "#/components/responses/InternalServerError"}
+        }
+      }
+    },
+    "/encrypted-data-vaults/{vaultID}/changes": {
+      "get": {
+        "summary": "Read the changes made to the documents in a data vault",
+        "description": "Returns the changes made after the given cursor, oldest first.",
+        "operationId": "readChanges",
+        "parameters": [
+          {"$ref": "#/components/parameters/VaultID"},
+          {
+            "name": "since",
+            "in": "query",
+            "description": "A cursor from a previous response, or now to skip existing changes. Defaults to the start.",
+            "schema": {"type": "string"}
+          },
+          {
+            "name": "limit",
+            "in": "query",
+            "description": "The maximum number of changes to read. Defaults to 100 and can't be more than 1000.",
+            "schema": {"type": "integer", "minimum": 1}
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The changes and the cursor to resume from.",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/ChangeFeed"}
+              }
+            }
+          },
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "500": {"$ref": "#/components/responses/InternalServerError"}
+        }
+      }
+    },
+    "/encrypted-data-vaults/{vaultID}/events": {
+      "get": {
+        "summary": "Stream the changes made to the documents in a data vault",
+        "description": "Sends each change as a Server-Sent Event named after its type, with a DocumentChange as data.",
+        "operationId": "streamEvents",
+        "parameters": [
+          {"$ref": "#/components/parameters/VaultID"},
+          {
+            "name": "Last-Event-ID",
+            "in": "header",
+            "description": "The ID of the last event received, to resume a stream after reconnecting.",
+            "schema": {"type": "string"}
+          },
+          {
+            "name": "since",
+            "in": "query",
+            "description": "A change feed cursor to start from if Last-Event-ID isn't set. Defaults to now.",
+            "schema": {"type": "string"}
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The event stream.",
+            "content": {"text/event-stream": {"schema": {"type": "string"}}}
+          },
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "500": {"$ref": "#/components/responses/InternalServerError"}
+        }
+      }
+    },
+    "/encrypted-data-vaults/{vaultID}/webhooks": {
+      "post": {
+        "summary": "Subscribe an HTTPS callback URL to the changes made to the documents in a data vault",
+        "description": "The response includes the secret that event payloads are signed with. It isn't returned again.",
+        "operationId": "createWebhook",
+        "parameters": [{"$ref": "#/components/parameters/VaultID"}],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"$ref": "#/components/schemas/WebhookRequest"}
+            }
+          }
+        },
+        "responses": {
+          "201": {
+            "description": "The webhook was created.",
+            "headers": {
+              "Location": {"description": "The URL of the new webhook.", "schema": {"type": "string"}}
+            },
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/Webhook"}
+              }
+            }
+          },
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "413": {"$ref": "#/components/responses/PayloadTooLarge"},
+          "429": {"$ref": "#/components/responses/TooManyRequests"},
+          "500": {"$ref": "#/components/responses/InternalServerError"},
+          "501": {"$ref": "#/components/responses/NotImplemented"}
+        }
+      },
+      "get": {
+        "summary": "List the webhooks of a data vault",
+        "operationId": "readWebhooks",
+        "parameters": [{"$ref": "#/components/parameters/VaultID"}],
+        "responses": {
+          "200": {
+            "description": "The vault's webhooks, without their secrets.",
+            "content": {
+              "application/json": {
+                "schema": {"type": "array", "items": {"$ref": "#/components/schemas/Webhook"}}
+              }
+            }
+          },
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "500": {"$ref": "#/components/responses/InternalServerError"},
+          "501": {"$ref": "#/components/responses/NotImplemented"}
+        }
+      }
+    },
+    "/encrypted-data-vaults/{vaultID}/webhooks/{webhookID}": {
+      "get": {
+        "summary": "Get a webhook of a data vault",
+        "operationId": "readWebhook",
+        "parameters": [
+          {"$ref": "#/components/parameters/VaultID"},
+          {"$ref": "#/components/parameters/WebhookID"}
+        ],
+        "responses": {
+          "200": {
+            "description": "The webhook, without its secret.",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/Webhook"}
+              }
+            }
+          },
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "500": {"$ref": "#/components/responses/InternalServerError"},
+          "501": {"$ref": "#/components/responses/NotImplemented"}
+        }
+      },
+      "delete": {
+        "summary": "Delete a webhook of a data vault",
+        "description": "Pending deliveries to the webhook are given up on.",
+        "operationId": "deleteWebhook",
+        "parameters": [
+          {"$ref": "#/components/parameters/VaultID"},
+          {"$ref": "#/components/parameters/WebhookID"}
+        ],
+        "responses": {
+          "204": {"description": "The webhook was deleted."},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "500": {"$ref": "#/components/responses/InternalServerError"},
+          "501": {"$ref": "#/components/responses/NotImplemented"}
+        }
+      }
+    },
+    "/encrypted-data-vaults/{vaultID}/webhooks/{webhookID}/deliveries": {
+      "get": {
+        "summary": "Get the status of the most recent deliveries to a webhook",
+        "operationId": "readWebhookDeliveries",
+        "parameters": [
+          {"$ref": "#/components/parameters/VaultID"},
+          {"$ref": "#/components/parameters/WebhookID"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Up to the last 100 deliveries, oldest first.",
+            "content": {
+              "application/json": {
+                "schema": {"type": "array", "items": {"$ref": "#/components/schemas/WebhookDelivery"}}
+              }
+            }
+          },
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "500": {"$ref": "#/components/responses/InternalServerError"},
+          "501": {"$ref": "#/components/responses/NotImplemented"}
+        }
+      }
+    },
+    "/encrypted-data-vaults/{vaultID}/replication": {
+      "get": {
+        "summary": "Get how far a data vault has been replicated from another EDV server",
+        "operationId": "readReplicationStatus",
+        "parameters": [{"$ref": "#/components/parameters/VaultID"}],
+        "responses": {
+          "200": {
+            "description": "The vault's replication checkpoint and unresolved conflicts.",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/ReplicationStatus"}
+              }
+            }
+          },
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "500": {"$ref": "#/components/responses/InternalServerError"},
+          "501": {"$ref": "#/components/responses/NotImplemented"}
+        }
+      }
+    },
+    "/encrypted-data-vaults/{vaultID}/replication/conflicts/{docID}": {
+      "post": {
+        "summary": "Resolve a replication conflict",
+        "description": "Keeps either the local or the remote version of a document that was changed on both servers.",
+        "operationId": "resolveReplicationConflict",
+        "parameters": [
+          {"$ref": "#/components/parameters/VaultID"},
+          {"$ref": "#/components/parameters/DocID"}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"$ref": "#/components/schemas/ConflictResolution"}
+            }
+          }
+        },
+        "responses": {
+          "204": {"description": "The conflict was resolved."},
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "413": {"$ref": "#/components/responses/PayloadTooLarge"},
+          "429": {"$ref": "#/components/responses/TooManyRequests"},
+          "500": {"$ref": "#/components/responses/InternalServerError"},
+          "501": {"$ref": "#/components/responses/NotImplemented"}
+        }
+      }
+    },
+    "/healthcheck": {
+      "get": {
+        "summary": "Check whether the server is alive",
+        "operationId": "healthCheck",
+        "responses": {
+          "200": {
+            "description": "The server is alive.",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/HealthCheckResponse"}
+              }
+            }
+          }
+        }
+      }
+    },
+    "/readiness": {
+      "get": {
+        "summary": "Check whether the server and its dependencies are ready to serve requests",
+        "operationId": "readiness",
+        "responses": {
+          "200": {
+            "description": "The server is ready.",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/ReadinessResponse"}
+              }
+            }
+          },
+          "503": {
+            "description": "A dependency of the server is unavailable.",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/ReadinessResponse"}
+              }
+            }
+          }
+        }
+      }
+    },
+    "/openapi.json": {
+      "get": {
+        "summary": "Get this OpenAPI document",
+        "operationId": "openAPISpec",
+        "responses": {
+          "200": {
+            "description": "The OpenAPI document describing the EDV REST API.",
+            "content": {
+              "application/json": {
+                "schema": {"type": "object"}
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metrics": {
+      "get": {
+        "summary": "Get Prometheus metrics",
+        "operationId": "metrics",
+        "responses": {
+          "200": {
+            "description": "Metrics in the Prometheus text exposition format.",
+            "content": {
+              "text/plain": {
+                "schema": {"type": "string"}
+              }
+            }
+          }
+        }
+      }
+    }
+  },
+  "components": {
+    "parameters": {
+      "VaultID": {
+        "name": "vaultID",
+        "in": "path",
+        "description": "The URL-encoded ID of the data vault. This is the referenceId from its configuration.",
+        "required": true,
+        "schema": {"type": "string"}
+      },
+      "DocID": {
+        "name": "docID",
+        "in": "path",
+        "description": "The URL-encoded ID of the document.",
+        "required": true,
+        "schema": {"type": "string"}
+      },
+      "WebhookID": {
+        "name": "webhookID",
+        "in": "path",
+        "description": "The ID of the webhook.",
+        "required": true,
+        "schema": {"type": "string"}
+      }
+    },
+    "responses": {
+      "BadRequest": {
+        "description": "The request was invalid or the operation failed.",
+        "content": {"text/plain": {"schema": {"$ref": "#/components/schemas/Error"}}}
+      },
+      "NotFound": {
+        "description": "The data vault, document, webhook or replication conflict doesn't exist.",
+        "content": {"text/plain": {"schema": {"$ref": "#/components/schemas/Error"}}}
+      },
+      "Conflict": {
+        "description": "A data vault or document with the same ID already exists.",
+        "content": {"text/plain": {"schema": {"$ref": "#/components/schemas/Error"}}}
+      },
+      "PayloadTooLarge": {
+        "description": "The request body is larger than the server allows for this endpoint.",
+        "content": {"text/plain": {"schema": {"$ref": "#/components/schemas/Error"}}}
+      },
+      "TooManyRequests": {
+        "description": "The client or the data vault has exceeded its rate limit.",
+        "headers": {
+          "Retry-After": {
+            "description": "The number of seconds to wait before retrying.",
+            "schema": {"type": "integer"}
+          }
+        },
+        "content": {"text/plain": {"schema": {"$ref": "#/components/schemas/Error"}}}
+      },
+      "InternalServerError": {
+        "description": "The server failed to process the request.",
+        "content": {"text/plain": {"schema": {"$ref": "#/components/schemas/Error"}}}
+      },
+      "NotImplemented": {
+        "description": "Webhooks or replication aren't enabled on this server.",
+        "content": {"text/plain": {"schema": {"$ref": "#/components/schemas/Error"}}}
+      }
+    },
+    "schemas": {
+      "Error": {
+        "type": "string",
+        "description": "A human-readable description of the error."
+      },
+      "DataVaultConfiguration": {
+        "type": "object",
+        "required": ["referenceId"],
+        "properties": {
+          "sequence": {"type": "integer"},
+          "controller": {"type": "string"},
+          "invoker": {"type": "string"},
+          "delegator": {"type": "string"},
+          "referenceId": {"type": "string"},
+          "kek": {"$ref": "#/components/schemas/IDTypePair"},
+          "hmac": {"$ref": "#/components/schemas/IDTypePair"},
+          "quota": {"$ref": "#/components/schemas/VaultQuota"},
+          "policy": {"$ref": "#/components/schemas/VaultPolicy"}
+        }
+      },
+      "VaultPolicy": {
+        "type": "object",
+        "description": "Rules that documents must follow to be stored in a data vault.",
+        "properties": {
+          "enforceKek": {
+            "type": "boolean",
+            "description": "Reject documents with a JWE recipient whose kid isn't the ID of the vault's KEK."
+          },
+          "enforceHmac": {
+            "type": "boolean",
+            "description": "Reject documents with an indexed attribute collection whose HMAC key isn't the vault's."
+          },
+          "documentIdFormat": {
+            "type": "string",
+            "enum": ["base58", "uuid", "any"],
+            "default": "base58",
+            "description": "The format that the IDs of documents in the vault must have."
+          }
+        }
+      },
+      "VaultQuota": {
+        "type": "object",
+        "description": "Storage limits of a data vault. Limits that are 0 or omitted aren't enforced.",
+        "properties": {
+          "maxDocuments": {"type": "integer", "format": "int64", "minimum": 0},
+          "maxBytes": {"type": "integer", "format": "int64", "minimum": 0},
+          "maxDocumentSize": {"type": "integer", "format": "int64", "minimum": 0}
+        }
+      },
+      "VaultStats": {
+        "type": "object",
+        "properties": {
+          "documentCount": {"type": "integer", "format": "int64"},
+          "totalBytes": {
+            "type": "integer",
+            "format": "int64",
+            "description": "The total size of the JSON serializations of the vault's documents."
+          },
+          "quota": {"$ref": "#/components/schemas/VaultQuota"}
+        }
+      },
+      "DocumentList": {
+        "type": "object",
+        "required": ["documentIds", "cursor", "hasMore"],
+        "properties": {
+          "documentIds": {
+            "type": "array",
+            "items": {"type": "string"}
+          },
+          "documents": {
+            "type": "array",
+            "description": "The full documents, if they were requested.",
+            "items": {"$ref": "#/components/schemas/EncryptedDocument"}
+          },
+          "cursor": {"type": "string", "description": "Where the next page starts from."},
+          "hasMore": {"type": "boolean"}
+        }
+      },
+      "VaultList": {
+        "type": "object",
+        "required": ["controller", "vaultIds"],
+        "properties": {
+          "controller": {"type": "string"},
+          "vaultIds": {
+            "type": "array",
+            "items": {"type": "string"}
+          }
+        }
+      },
+      "ChangeFeed": {
+        "type": "object",
+        "required": ["changes", "cursor", "hasMore"],
+        "properties": {
+          "changes": {
+            "type": "array",
+            "items": {"$ref": "#/components/schemas/DocumentChange"}
+          },
+          "cursor": {
+            "type": "string",
+            "description": "The opaque cursor to pass as since to read the changes after these ones."
+          },
+          "hasMore": {"type": "boolean", "description": "Whether there are more changes after the cursor."}
+        }
+      },
+      "DocumentChange": {
+        "type": "object",
+        "required": ["id", "sequence", "type"],
+        "properties": {
+          "id": {"type": "string"},
+          "sequence": {"type": "string", "description": "The opaque sequence of the change in the change feed."},
+          "type": {"type": "string", "enum": ["created", "updated", "deleted"]}
+        }
+      },
+      "WebhookRequest": {
+        "type": "object",
+        "required": ["url"],
+        "properties": {
+          "url": {"type": "string", "format": "uri", "description": "The HTTPS URL to send events to. Unless the EDV server allows it explicitly, its host mustn't resolve to a loopback, private or link-local address."}
+        }
+      },
+      "Webhook": {
+        "type": "object",
+        "required": ["id", "vaultId", "url", "createdAt"],
+        "properties": {
+          "id": {"type": "string"},
+          "vaultId": {"type": "string"},
+          "url": {"type": "string", "format": "uri"},
+          "secret": {
+            "type": "string",
+            "description": "The HMAC-SHA256 key that events are signed with. Only returned when the webhook is created."
+          },
+          "createdAt": {"type": "string", "format": "date-time"}
+        }
+      },
+      "WebhookEvent": {
+        "type": "object",
+        "description": "The payload POSTed to a webhook. Its signature is in the X-EDV-Signature header.",
+        "required": ["deliveryId", "webhookId", "vaultId", "change"],
+        "properties": {
+          "deliveryId": {"type": "string", "description": "The same for every attempt to deliver the event."},
+          "webhookId": {"type": "string"},
+          "vaultId": {"type": "string"},
+          "change": {"$ref": "#/components/schemas/DocumentChange"}
+        }
+      },
+      "WebhookDelivery": {
+        "type": "object",
+        "required": ["id", "webhookId", "change", "status", "attempts", "createdAt"],
+        "properties": {
+          "id": {"type": "string"},
+          "webhookId": {"type": "string"},
+          "change": {"$ref": "#/components/schemas/DocumentChange"},
+          "status": {"type": "string", "enum": ["pending", "delivered", "failed"]},
+          "attempts": {"type": "integer"},
+          "createdAt": {"type": "string", "format": "date-time"},
+          "lastAttemptAt": {"type": "string", "format": "date-time"},
+          "nextAttemptAt": {"type": "string", "format": "date-time"},
+          "lastStatusCode": {"type": "integer", "description": "The status code of the receiver's last response."},
+          "lastError": {"type": "string"}
+        }
+      },
+      "ReplicationStatus": {
+        "type": "object",
+        "required": ["vaultId", "cursor", "conflicts"],
+        "properties": {
+          "vaultId": {"type": "string"},
+          "cursor": {
+            "type": "string",
+            "description": "The position in the other server's change feed up to which changes have been applied."
+          },
+          "updatedAt": {"type": "string", "format": "date-time"},
+          "lastError": {"type": "string", "description": "Why the last replication attempt failed, if it did."},
+          "conflicts": {"type": "array", "items": {"$ref": "#/components/schemas/ReplicationConflict"}}
+        }
+      },
+      "ReplicationConflict": {
+        "type": "object",
+        "required": ["documentId", "remoteSequence", "detectedAt"],
+        "properties": {
+          "documentId": {"type": "string"},
+          "remoteSequence": {"type": "string", "description": "The sequence of the conflicting remote change."},
+          "detectedAt": {"type": "string", "format": "date-time"}
+        }
+      },
+      "ConflictResolution": {
+        "type": "object",
+        "required": ["keep"],
+        "properties": {
+          "keep": {"type": "string", "enum": ["local", "remote"]}
+        }
+      },
+      "BatchRequest": {
+        "type": "object",
+        "required": ["operations"],
+        "properties": {
+          "operations": {
+            "type": "array",
+            "minItems": 1,
+            "maxItems": 1000,
+            "items": {"$ref": "#/components/schemas/BatchOperation"}
+          }
+        }
+      },
+      "BatchOperation": {
+        "type": "object",
+        "required": ["type"],
+        "properties": {
+          "type": {"type": "string", "enum": ["create", "update", "delete"]},
+          "document": {
+            "allOf": [{"$ref": "#/components/schemas/EncryptedDocument"}],
+            "description": "The document to create or update."
+          },
+          "id": {"type": "string", "description": "The ID of the document to delete."}
+        }
+      },
+      "BatchResponse": {
+        "type": "object",
+        "properties": {
+          "results": {
+            "type": "array",
+            "items": {"$ref": "#/components/schemas/BatchResult"}
+          }
+        }
+      },
+      "BatchResult": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "string"},
+          "status": {
+            "type": "integer",
+            "description": "The status code that the equivalent single document request would have returned."
+          },
+          "error": {"type": "string"}
+        }
+      },
+      "ExportRecord": {
+        "type": "object",
+        "required": ["type"],
+        "properties": {
+          "type": {"type": "string", "enum": ["vault", "document", "end"]},
+          "configuration": {"$ref": "#/components/schemas/DataVaultConfiguration"},
+          "document": {"$ref": "#/components/schemas/EncryptedDocument"},
+          "checksum": {
+            "type": "string",
+            "description": "The SHA-256 hash of the document, or of all the document checksums in the end record."
+          },
+          "documentCount": {"type": "integer", "description": "The number of documents in the export."}
+        }
+      },
+      "ImportResult": {
+        "type": "object",
+        "properties": {
+          "vaultCreated": {"type": "boolean"},
+          "imported": {"type": "integer"},
+          "skipped": {"type": "integer", "description": "Documents that were already in the vault."},
+          "failed": {
+            "type": "array",
+            "items": {"$ref": "#/components/schemas/BatchResult"}
+          }
+        }
+      },
+      "ReindexResult": {
+        "type": "object",
+        "properties": {
+          "documents": {"type": "integer", "description": "The number of encrypted documents in the vault."},
+          "mappingsDeleted": {"type": "integer"},
+          "mappingsCreated": {"type": "integer"},
+          "unparsableDocuments": {
+            "type": "array",
+            "description": "IDs of the documents that couldn't be parsed, whose index entries were kept.",
+            "items": {"type": "string"}
+          },
+          "uniquenessViolations": {
+            "type": "array",
+            "items": {"$ref": "#/components/schemas/UniquenessViolation"}
+          }
+        }
+      },
+      "UniquenessViolation": {
+        "type": "object",
+        "description": "An indexed attribute name and value that are declared unique but that more than one document has.",
+        "required": ["name", "value", "documentIds"],
+        "properties": {
+          "name": {"type": "string"},
+          "value": {"type": "string"},
+          "documentIds": {
+            "type": "array",
+            "items": {"type": "string"}
+          }
+        }
+      },
+      "EncryptedDocument": {
+        "type": "object",
+        "required": ["id", "jwe"],
+        "properties": {
+          "id": {
+            "type": "string",
+            "description": "A base58-encoded 128-bit value, unless the vault's policy allows another format."
+          },
+          "sequence": {"type": "integer"},
+          "indexed": {
+            "type": "array",
+            "nullable": true,
+            "items": {"$ref": "#/components/schemas/IndexedAttributeCollection"}
+          },
+          "jwe": {
+            "oneOf": [
+              {"type": "object", "required": ["ciphertext"]},
+              {"type": "string"}
+            ],
+            "description": "The encrypted content of the document as a JWE in the JSON or compact serialization."
+          }
+        }
+      },
+      "StructuredDocument": {
+        "type": "object",
+        "description": "The plaintext form of a document. Clients encrypt it into the jwe of an EncryptedDocument.",
+        "properties": {
+          "id": {"type": "string"},
+          "meta": {"type": "object"},
+          "content": {"type": "object"}
+        }
+      },
+      "IndexedAttributeCollection": {
+        "type": "object",
+        "properties": {
+          "sequence": {"type": "integer"},
+          "hmac": {"$ref": "#/components/schemas/IDTypePair"},
+          "attributes": {
+            "type": "array",
+            "items": {"$ref": "#/components/schemas/IndexedAttribute"}
+          }
+        }
+      },
+      "IndexedAttribute": {
+        "type": "object",
+        "properties": {
+          "name": {"type": "string"},
+          "value": {"type": "string"},
+          "unique": {"type": "boolean"}
+        }
+      },
+      "IDTypePair": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "string"},
+          "type": {"type": "string"}
+        }
+      },
+      "Query": {
+        "type": "object",
+        "required": ["index", "equals"],
+        "properties": {
+          "index": {"type": "string"},
+          "equals": {"type": "string"}
+        }
+      },
+      "HealthCheckResponse": {
+        "type": "object",
+        "properties": {
+          "status": {"type": "string"},
+          "currentTime": {"type": "string", "format": "date-time"},
+          "version": {"type": "string"}
+        }
+      },
+      "ReadinessResponse": {
+        "type": "object",
+        "properties": {
+          "status": {"type": "string"},
+          "version": {"type": "string"},
+          "dependencies": {
+            "type": "object",
+            "additionalProperties": {"$ref": "#/components/schemas/DependencyStatus"}
+          }
+        }
+      },
+      "DependencyStatus": {
+        "type": "object",
+        "properties": {
+          "status": {"type": "string"},
+          "error": {"type": "string"}
+        }
+      }
+    }
+  }
+}`
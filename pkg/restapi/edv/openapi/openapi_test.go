@@ -0,0 +1,107 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+type schema struct {
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type document struct {
+	OpenAPI    string                 `json:"openapi"`
+	Paths      map[string]interface{} `json:"paths"`
+	Components struct {
+		Schemas map[string]schema `json:"schemas"`
+	} `json:"components"`
+}
+
+// Fails if a model's JSON fields and its schema in the OpenAPI document drift apart.
+// New models sent or received by the REST API must be added here along with their schema.
+func TestSpec_MatchesModels(t *testing.T) {
+	modelsBySchemaName := map[string]interface{}{
+		"DataVaultConfiguration":     models.DataVaultConfiguration{},
+		"VaultQuota":                 models.VaultQuota{},
+		"VaultPolicy":                models.VaultPolicy{},
+		"VaultStats":                 models.VaultStats{},
+		"DocumentList":               models.DocumentList{},
+		"VaultList":                  models.VaultList{},
+		"ChangeFeed":                 models.ChangeFeed{},
+		"DocumentChange":             models.DocumentChange{},
+		"WebhookRequest":             models.WebhookRequest{},
+		"Webhook":                    models.Webhook{},
+		"WebhookEvent":               models.WebhookEvent{},
+		"WebhookDelivery":            models.WebhookDelivery{},
+		"ReplicationStatus":          models.ReplicationStatus{},
+		"ReplicationConflict":        models.ReplicationConflict{},
+		"ConflictResolution":         models.ConflictResolution{},
+		"BatchRequest":               models.BatchRequest{},
+		"BatchOperation":             models.BatchOperation{},
+		"BatchResponse":              models.BatchResponse{},
+		"BatchResult":                models.BatchResult{},
+		"ExportRecord":               models.ExportRecord{},
+		"ImportResult":               models.ImportResult{},
+		"ReindexResult":              models.ReindexResult{},
+		"UniquenessViolation":        models.UniquenessViolation{},
+		"StructuredDocument":         models.StructuredDocument{},
+		"EncryptedDocument":          models.EncryptedDocument{},
+		"IndexedAttributeCollection": models.IndexedAttributeCollection{},
+		"IndexedAttribute":           models.IndexedAttribute{},
+		"IDTypePair":                 models.IDTypePair{},
+		"Query":                      models.Query{},
+		"HealthCheckResponse":        models.HealthCheckResponse{},
+		"ReadinessResponse":          models.ReadinessResponse{},
+		"DependencyStatus":           models.DependencyStatus{},
+	}
+
+	var doc document
+
+	err := json.Unmarshal([]byte(Spec), &doc)
+	require.NoError(t, err)
+	require.Equal(t, "3.0.3", doc.OpenAPI)
+	require.NotEmpty(t, doc.Paths)
+
+	for schemaName, model := range modelsBySchemaName {
+		specSchema, found := doc.Components.Schemas[schemaName]
+		require.True(t, found, "schema %s is missing from the OpenAPI document", schemaName)
+
+		var specProperties []string
+		for property := range specSchema.Properties {
+			specProperties = append(specProperties, property)
+		}
+
+		sort.Strings(specProperties)
+
+		require.Equal(t, jsonFieldNames(reflect.TypeOf(model)), specProperties,
+			"properties of schema %s don't match the fields of the model", schemaName)
+	}
+}
+
+func jsonFieldNames(modelType reflect.Type) []string {
+	var names []string
+
+	for i := 0; i < modelType.NumField(); i++ {
+		name := strings.Split(modelType.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,137 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+
+	"github.com/trustbloc/edv/pkg/audit"
+	"github.com/trustbloc/edv/pkg/internal/common/support"
+	"github.com/trustbloc/edv/pkg/principal"
+	"github.com/trustbloc/edv/pkg/requestlog"
+)
+
+const (
+	createVaultAction    = "createVault"
+	queryVaultAction     = "queryVault"
+	createDocumentAction = "createDocument"
+	readDocumentAction   = "readDocument"
+)
+
+// AuditRecorder records the outcome of vault operations. It's implemented by audit.Log.
+type AuditRecorder interface {
+	Record(entry audit.Entry) error
+}
+
+// WithAuditRecorder makes the EDV operations record the outcome of every vault operation with the given recorder.
+func WithAuditRecorder(recorder AuditRecorder) Option {
+	return func(opts *Operation) {
+		opts.auditRecorder = recorder
+	}
+}
+
+type auditDetailsKey struct{}
+
+// auditDetails holds IDs that only become known once a handler has decoded the request body,
+// such as the ID of a newly created vault or document, and the outcome for each document of requests
+// that affect several documents.
+type auditDetails struct {
+	vaultID    string
+	documentID string
+	documents  []audit.DocumentEntry
+}
+
+// audited wraps the given handler so that the outcome of every call to it is recorded with the audit recorder.
+// If no audit recorder has been set, then the handler is returned unchanged.
+func (c *Operation) audited(action string, handle http.HandlerFunc) http.HandlerFunc {
+	if c.auditRecorder == nil {
+		return handle
+	}
+
+	return func(rw http.ResponseWriter, req *http.Request) {
+		details := &auditDetails{}
+		recorder := support.NewStatusRecorder(rw)
+
+		handle(recorder, req.WithContext(context.WithValue(req.Context(), auditDetailsKey{}, details)))
+
+		c.recordAudit(req, &audit.Entry{
+			Action:     action,
+			VaultID:    auditPathVar(req, vaultIDPathVariable, details.vaultID),
+			DocumentID: auditPathVar(req, docIDPathVariable, details.documentID),
+			Result:     auditResult(recorder.Status()),
+			StatusCode: recorder.Status(),
+			Documents:  details.documents,
+		})
+	}
+}
+
+// recordAudit records the given entry for the given request with the audit recorder, if one has been set,
+// along with the client identity and remote address of the request.
+func (c *Operation) recordAudit(req *http.Request, entry *audit.Entry) {
+	if c.auditRecorder == nil {
+		return
+	}
+
+	entry.Principal, _ = principal.FromRequest(req)
+	entry.RemoteAddr = req.RemoteAddr
+
+	err := c.auditRecorder.Record(*entry)
+	if err != nil {
+		requestlog.Logger(req).Errorf("Failed to record %s operation in audit log: %s", entry.Action, err.Error())
+	}
+}
+
+// setAuditVaultID records the ID of the vault a request operates on, for requests that don't have it in their path.
+func setAuditVaultID(req *http.Request, vaultID string) {
+	if details, ok := req.Context().Value(auditDetailsKey{}).(*auditDetails); ok {
+		details.vaultID = vaultID
+	}
+}
+
+// setAuditDocumentID records the ID of the document a request operates on,
+// for requests that don't have it in their path.
+func setAuditDocumentID(req *http.Request, documentID string) {
+	if details, ok := req.Context().Value(auditDetailsKey{}).(*auditDetails); ok {
+		details.documentID = documentID
+	}
+}
+
+// addAuditDocument records the outcome for one of the documents that a request affects.
+func addAuditDocument(req *http.Request, document audit.DocumentEntry) {
+	if details, ok := req.Context().Value(auditDetailsKey{}).(*auditDetails); ok {
+		details.documents = append(details.documents, document)
+	}
+}
+
+// auditResult returns the result to record for an operation that got the given status code.
+func auditResult(statusCode int) string {
+	if statusCode >= http.StatusBadRequest {
+		return audit.ResultFailure
+	}
+
+	return audit.ResultSuccess
+}
+
+// auditPathVar returns the given ID if it was set by the handler, or the unescaped path variable otherwise.
+func auditPathVar(req *http.Request, pathVar, id string) string {
+	if id != "" {
+		return id
+	}
+
+	escapedPathVar := mux.Vars(req)[pathVar]
+
+	unescapedPathVar, err := url.PathUnescape(escapedPathVar)
+	if err != nil {
+		return escapedPathVar
+	}
+
+	return unescapedPathVar
+}
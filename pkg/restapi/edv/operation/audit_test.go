@@ -0,0 +1,245 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edv/pkg/audit"
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/principal"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+	"github.com/trustbloc/edv/pkg/vaultexport"
+)
+
+type mockAuditRecorder struct {
+	entries   []audit.Entry
+	errRecord error
+}
+
+func (m *mockAuditRecorder) Record(entry audit.Entry) error {
+	m.entries = append(m.entries, entry)
+
+	return m.errRecord
+}
+
+func TestAudited(t *testing.T) {
+	t.Run("Successful operations are recorded", func(t *testing.T) {
+		recorder := &mockAuditRecorder{}
+
+		op := New(memedvprovider.NewProvider(), WithAuditRecorder(recorder))
+
+		req := httptest.NewRequest(http.MethodPost, createVaultEndpoint,
+			bytes.NewBufferString(testDataVaultConfiguration))
+		req = req.WithContext(principal.NewContext(req.Context(), "did:example:123456789"))
+
+		rr := httptest.NewRecorder()
+		getHandler(t, op, createVaultEndpoint).Handle().ServeHTTP(rr, req)
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		req = httptest.NewRequest(http.MethodPost, createDocumentEndpoint,
+			bytes.NewBufferString(testEncryptedDocument))
+		req = mux.SetURLVars(req, map[string]string{vaultIDPathVariable: testVaultID})
+
+		rr = httptest.NewRecorder()
+		getHandler(t, op, createDocumentEndpoint).Handle().ServeHTTP(rr, req)
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		req = httptest.NewRequest(http.MethodGet, readDocumentEndpoint, nil)
+		req = mux.SetURLVars(req, map[string]string{vaultIDPathVariable: testVaultID, docIDPathVariable: testDocID})
+
+		rr = httptest.NewRecorder()
+		getHandler(t, op, readDocumentEndpoint).Handle().ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		require.Len(t, recorder.entries, 3)
+
+		require.Equal(t, audit.Entry{
+			Principal:  "did:example:123456789",
+			RemoteAddr: req.RemoteAddr,
+			Action:     createVaultAction,
+			VaultID:    testVaultID,
+			Result:     audit.ResultSuccess,
+			StatusCode: http.StatusCreated,
+		}, recorder.entries[0])
+		require.Equal(t, createDocumentAction, recorder.entries[1].Action)
+		require.Equal(t, testVaultID, recorder.entries[1].VaultID)
+		require.Equal(t, testDocID, recorder.entries[1].DocumentID)
+		require.Equal(t, readDocumentAction, recorder.entries[2].Action)
+		require.Equal(t, testDocID, recorder.entries[2].DocumentID)
+		require.Equal(t, audit.ResultSuccess, recorder.entries[2].Result)
+		require.Equal(t, http.StatusOK, recorder.entries[2].StatusCode)
+	})
+	t.Run("Failed operations are recorded", func(t *testing.T) {
+		recorder := &mockAuditRecorder{}
+
+		op := New(memedvprovider.NewProvider(), WithAuditRecorder(recorder))
+
+		req := httptest.NewRequest(http.MethodPost, queryVaultEndpoint, bytes.NewBufferString(testQuery))
+		req = mux.SetURLVars(req, map[string]string{vaultIDPathVariable: "vault%2Fwith%2Fslashes"})
+
+		rr := httptest.NewRecorder()
+		getHandler(t, op, queryVaultEndpoint).Handle().ServeHTTP(rr, req)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+
+		require.Len(t, recorder.entries, 1)
+		require.Equal(t, queryVaultAction, recorder.entries[0].Action)
+		require.Equal(t, "vault/with/slashes", recorder.entries[0].VaultID)
+		require.Equal(t, audit.ResultFailure, recorder.entries[0].Result)
+		require.Equal(t, http.StatusBadRequest, recorder.entries[0].StatusCode)
+	})
+	t.Run("Each document of a batch is recorded", func(t *testing.T) {
+		recorder := &mockAuditRecorder{}
+
+		op := New(memedvprovider.NewProvider(), WithAuditRecorder(recorder))
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+		require.Equal(t, http.StatusCreated, createTestDocument(t, op, testBatchDocID2))
+
+		applyTestBatch(t, op, []models.BatchOperation{
+			{Type: models.BatchOperationCreate, Document: testBatchDocument(t, testBatchDocID1)},
+			{Type: models.BatchOperationDelete, ID: testBatchDocID2},
+			{Type: models.BatchOperationUpdate, Document: testBatchDocument(t, testDocID)},
+		})
+
+		require.Len(t, recorder.entries, 3)
+		require.Equal(t, batchDocumentsAction, recorder.entries[2].Action)
+		require.Equal(t, testVaultID, recorder.entries[2].VaultID)
+		require.Equal(t, audit.ResultSuccess, recorder.entries[2].Result)
+		require.Equal(t, []audit.DocumentEntry{
+			{ID: testBatchDocID1, Operation: models.BatchOperationCreate, Result: audit.ResultSuccess,
+				StatusCode: http.StatusCreated},
+			{ID: testBatchDocID2, Operation: models.BatchOperationDelete, Result: audit.ResultSuccess,
+				StatusCode: http.StatusNoContent},
+			{ID: testDocID, Operation: models.BatchOperationUpdate, Result: audit.ResultFailure,
+				StatusCode: http.StatusNotFound},
+		}, recorder.entries[2].Documents)
+	})
+	t.Run("Each document of an import is recorded", func(t *testing.T) {
+		export := exportTestVault(t)
+		lines := strings.SplitAfter(export, "\n")
+
+		recorder := &mockAuditRecorder{}
+
+		op := New(memedvprovider.NewProvider(), WithAuditRecorder(recorder))
+
+		rr := serveImportRequest(t, op, testVaultID, lines[0]+lines[1])
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+
+		importTestVault(t, op, export)
+
+		// Each batch of imported documents is recorded on its own, before the import itself.
+		require.Len(t, recorder.entries, 4)
+		require.Equal(t, importDocumentsAction, recorder.entries[0].Action)
+		require.Equal(t, testVaultID, recorder.entries[0].VaultID)
+		require.Equal(t, []audit.DocumentEntry{
+			{ID: testDocID, Operation: importDocumentOperation, Result: audit.ResultSuccess,
+				StatusCode: http.StatusCreated},
+		}, recorder.entries[0].Documents)
+		require.Equal(t, importVaultAction, recorder.entries[1].Action)
+		require.Equal(t, audit.ResultFailure, recorder.entries[1].Result)
+		require.Empty(t, recorder.entries[1].Documents)
+		require.Equal(t, importDocumentsAction, recorder.entries[2].Action)
+		require.Equal(t, []audit.DocumentEntry{
+			{ID: testDocID, Operation: importDocumentOperation, Result: audit.ResultSkipped,
+				StatusCode: http.StatusConflict},
+			{ID: testBatchDocID1, Operation: importDocumentOperation, Result: audit.ResultSuccess,
+				StatusCode: http.StatusCreated},
+		}, recorder.entries[2].Documents)
+		require.Equal(t, importVaultAction, recorder.entries[3].Action)
+		require.Equal(t, audit.ResultSuccess, recorder.entries[3].Result)
+	})
+	t.Run("Large imports can be read back from the audit log", func(t *testing.T) {
+		// With IDs this long, the entries for all of the documents add up to more than the largest record that
+		// can be read back from an audit log file.
+		const documentCount = 4000
+
+		config := models.DataVaultConfiguration{}
+		require.NoError(t, json.Unmarshal([]byte(testDataVaultConfiguration), &config))
+		config.Policy = &models.VaultPolicy{DocumentIDFormat: models.DocumentIDFormatAny}
+
+		export := bytes.Buffer{}
+		writer := vaultexport.NewWriter(&export)
+		require.NoError(t, writer.WriteConfiguration(&config))
+
+		for i := 0; i < documentCount; i++ {
+			require.NoError(t, writer.WriteDocument(testBatchDocument(t, fmt.Sprintf("%0250d", i))))
+		}
+
+		require.NoError(t, writer.Finish())
+
+		path := filepath.Join(t.TempDir(), "audit.log")
+
+		sink, err := audit.NewFileSink(path)
+		require.NoError(t, err)
+
+		auditLog, err := audit.New(sink)
+		require.NoError(t, err)
+
+		op := New(memedvprovider.NewProvider(), WithAuditRecorder(auditLog))
+
+		require.Equal(t, documentCount, importTestVault(t, op, export.String()).Imported)
+		require.NoError(t, sink.Close())
+
+		records, err := audit.ReadRecordsFromFile(path)
+		require.NoError(t, err)
+		require.NoError(t, audit.Verify(records))
+
+		auditedDocuments := 0
+
+		for i := range records {
+			require.LessOrEqual(t, len(records[i].Documents), importBatchSize)
+
+			auditedDocuments += len(records[i].Documents)
+		}
+
+		require.Equal(t, documentCount, auditedDocuments)
+	})
+	t.Run("Failure to record doesn't affect the response", func(t *testing.T) {
+		recorder := &mockAuditRecorder{errRecord: errors.New("audit sink failure")}
+
+		op := New(memedvprovider.NewProvider(), WithAuditRecorder(recorder))
+
+		rr := httptest.NewRecorder()
+		getHandler(t, op, createVaultEndpoint).Handle().ServeHTTP(rr, httptest.NewRequest(http.MethodPost,
+			createVaultEndpoint, bytes.NewBufferString(testDataVaultConfiguration)))
+
+		require.Equal(t, http.StatusCreated, rr.Code)
+		require.Len(t, recorder.entries, 1)
+	})
+	t.Run("Health endpoints aren't recorded", func(t *testing.T) {
+		recorder := &mockAuditRecorder{}
+
+		op := New(memedvprovider.NewProvider(), WithAuditRecorder(recorder))
+
+		rr := httptest.NewRecorder()
+		getHandler(t, op, healthCheckEndpoint).Handle().ServeHTTP(rr,
+			httptest.NewRequest(http.MethodGet, healthCheckEndpoint, nil))
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Empty(t, recorder.entries)
+	})
+}
+
+func TestAuditPathVar(t *testing.T) {
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/", nil),
+		map[string]string{vaultIDPathVariable: "%zz"})
+
+	require.Equal(t, "%zz", auditPathVar(req, vaultIDPathVariable, ""))
+	require.Equal(t, "override", auditPathVar(req, vaultIDPathVariable, "override"))
+}
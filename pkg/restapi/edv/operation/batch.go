@@ -0,0 +1,403 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edv/pkg/audit"
+	"github.com/trustbloc/edv/pkg/edvprovider"
+	"github.com/trustbloc/edv/pkg/requestlog"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+const (
+	batchEndpoint = edvCommonEndpointPathRoot + "/{" + vaultIDPathVariable + "}/batch"
+
+	// MaxBatchOperations is the maximum number of operations in a batch request.
+	MaxBatchOperations = 1000
+
+	batchDocumentsAction = "batchDocuments"
+)
+
+// batchItem is an operation from a batch request along with the write it turned into, if it was valid,
+// and the change to the vault's usage that the write makes.
+type batchItem struct {
+	result         models.BatchResult
+	write          *edvprovider.DocumentWrite
+	addedDocuments int64
+	addedBytes     int64
+}
+
+func (c *Operation) batchHandler(rw http.ResponseWriter, req *http.Request) {
+	batch := models.BatchRequest{}
+
+	err := c.decodeRequestBody(rw, req, c.bodySizeLimits.Batch, &batch)
+	if err != nil {
+		rw.WriteHeader(decodeFailureStatusCode(err))
+
+		_, err = rw.Write([]byte(err.Error()))
+		if err != nil {
+			requestlog.Logger(req).Errorf("Failed to write response for batch failure: %s", err.Error())
+		}
+
+		return
+	}
+
+	vaultID, success := vaultIDFromPath(req, rw)
+	if !success {
+		return
+	}
+
+	results, err := c.vaultCollection.applyBatch(vaultID, batch.Operations)
+	if err != nil {
+		writeBatchFailure(rw, req, vaultID, err)
+
+		return
+	}
+
+	for i, result := range results {
+		addAuditDocument(req, audit.DocumentEntry{ID: result.ID, Operation: batch.Operations[i].Type,
+			Result: auditResult(result.Status), StatusCode: result.Status})
+
+		if result.Status == http.StatusInternalServerError {
+			requestlog.Logger(req).WithField("vaultID", vaultID).Errorf(
+				"Failed to apply batch operation to document %s: %s", result.ID, result.Error)
+		}
+	}
+
+	sendJSONResponse(rw, req, http.StatusOK, models.BatchResponse{Results: results})
+}
+
+// applyBatch applies the given operations to the vault with a single bulk write, and returns the outcome of each.
+// Operations are checked in order, and the usage of the earlier ones counts towards the vault's quota when checking
+// the later ones.
+func (vc *VaultCollection) applyBatch(vaultID string,
+	operations []models.BatchOperation) ([]models.BatchResult, error) {
+	if len(operations) == 0 {
+		return nil, edverrors.ErrEmptyBatch
+	}
+
+	if len(operations) > MaxBatchOperations {
+		return nil, edverrors.ErrBatchTooLarge
+	}
+
+	store, err := vc.provider.OpenStore(vaultID)
+	if err != nil {
+		if err == storage.ErrStoreNotFound {
+			return nil, edverrors.ErrVaultNotFound
+		}
+
+		return nil, err
+	}
+
+	// Like single documents, batches are applied one at a time per vault so that usage can be tracked accurately.
+	unlock := vc.lockVault(vaultID)
+	defer unlock()
+
+	record, err := vc.getVaultRecord(vaultID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := vc.prepareBatch(store, record, operations)
+
+	written, err := writeBatch(store, record, items)
+	if err != nil {
+		return nil, err
+	}
+
+	if written {
+		vc.recordUsage(vaultID, record)
+		vc.notifyChange(vaultID)
+	}
+
+	results := make([]models.BatchResult, len(items))
+
+	for i := range items {
+		results[i] = items[i].result
+	}
+
+	return results, nil
+}
+
+// prepareBatch checks each of the given operations and turns the valid ones into writes,
+// adding their changes to the vault's usage to the given record.
+func (vc *VaultCollection) prepareBatch(store edvprovider.EDVStore, record *vaultRecord,
+	operations []models.BatchOperation) []batchItem {
+	items := make([]batchItem, len(operations))
+	documentIDs := make(map[string]struct{}, len(operations))
+
+	for i, operation := range operations {
+		item := &items[i]
+		item.result.ID = batchOperationDocumentID(operation)
+
+		if _, repeated := documentIDs[item.result.ID]; repeated && item.result.ID != "" {
+			item.fail(edverrors.ErrRepeatedBatchDocument)
+
+			continue
+		}
+
+		documentIDs[item.result.ID] = struct{}{}
+
+		err := vc.prepareBatchOperation(store, record, operation, item)
+		if err != nil {
+			item.fail(err)
+
+			continue
+		}
+
+		record.DocumentCount += item.addedDocuments
+		record.TotalBytes += item.addedBytes
+	}
+
+	return items
+}
+
+func (vc *VaultCollection) prepareBatchOperation(store edvprovider.EDVStore, record *vaultRecord,
+	operation models.BatchOperation, item *batchItem) error {
+	switch operation.Type {
+	case models.BatchOperationCreate:
+		return vc.prepareBatchCreate(record, operation.Document, item)
+	case models.BatchOperationUpdate:
+		return vc.prepareBatchUpdate(store, record, operation.Document, item)
+	case models.BatchOperationDelete:
+		return prepareBatchDelete(store, record, operation.ID, item)
+	default:
+		return edverrors.ErrInvalidBatchOperation
+	}
+}
+
+// prepareBatchCreate checks the given document the same way as when creating a single document.
+// Whether there's already a document with the same ID is left to the store, which checks it atomically.
+func (vc *VaultCollection) prepareBatchCreate(record *vaultRecord, document *models.EncryptedDocument,
+	item *batchItem) error {
+	if document == nil {
+		return edverrors.ErrMissingBatchDocument
+	}
+
+	documentSize, err := vc.checkDocumentAllowedInVault(record, *document)
+	if err != nil {
+		return err
+	}
+
+	item.write = &edvprovider.DocumentWrite{Type: edvprovider.WriteCreate, Document: *document}
+	item.addedDocuments = 1
+	item.addedBytes = documentSize
+
+	return nil
+}
+
+// prepareBatchUpdate checks the given document the same way as when creating a single document,
+// except that only the difference in size with the document it replaces counts towards the vault's quota.
+func (vc *VaultCollection) prepareBatchUpdate(store edvprovider.EDVStore, record *vaultRecord,
+	document *models.EncryptedDocument, item *batchItem) error {
+	if document == nil {
+		return edverrors.ErrMissingBatchDocument
+	}
+
+	err := vc.checkDocumentValidInVault(record, document)
+	if err != nil {
+		return err
+	}
+
+	documentSize, err := encodedDocumentSize(*document)
+	if err != nil {
+		return err
+	}
+
+	replacedSize, err := storedDocumentSize(store, document.ID)
+	if err != nil {
+		return err
+	}
+
+	err = checkUsageFitsQuota(record.Configuration.Quota, documentSize,
+		record.DocumentCount, record.TotalBytes+documentSize-replacedSize)
+	if err != nil {
+		return err
+	}
+
+	item.write = &edvprovider.DocumentWrite{Type: edvprovider.WriteUpdate, Document: *document}
+	item.addedBytes = documentSize - replacedSize
+
+	return nil
+}
+
+// prepareBatchDelete checks that the given ID is one that a document in the vault could have, so that the documents
+// the EDV server uses internally can't be deleted.
+func prepareBatchDelete(store edvprovider.EDVStore, record *vaultRecord, docID string, item *batchItem) error {
+	if docID == "" {
+		return edverrors.ErrMissingDocumentID
+	}
+
+	err := checkDocumentID(docID, documentIDFormat(&record.Configuration))
+	if err != nil {
+		return err
+	}
+
+	documentSize, err := storedDocumentSize(store, docID)
+	if err != nil {
+		return err
+	}
+
+	item.write = &edvprovider.DocumentWrite{Type: edvprovider.WriteDelete,
+		Document: models.EncryptedDocument{ID: docID}}
+	item.addedDocuments = -1
+	item.addedBytes = -documentSize
+
+	return nil
+}
+
+// writeBatch sends the writes of the valid batch items to the store in one bulk write and records their outcome.
+// The usage changes of the writes that the store rejects are taken back out of the given record.
+// It returns whether any write succeeded.
+func writeBatch(store edvprovider.EDVStore, record *vaultRecord, items []batchItem) (bool, error) {
+	var (
+		writes       []edvprovider.DocumentWrite
+		writtenItems []*batchItem
+	)
+
+	for i := range items {
+		if items[i].write != nil {
+			writes = append(writes, *items[i].write)
+			writtenItems = append(writtenItems, &items[i])
+		}
+	}
+
+	if len(writes) == 0 {
+		return false, nil
+	}
+
+	writeErrs, err := store.BulkWrite(writes)
+	if err != nil {
+		return false, err
+	}
+
+	written := false
+
+	for i, item := range writtenItems {
+		if writeErrs[i] != nil {
+			item.fail(writeErrs[i])
+
+			record.DocumentCount -= item.addedDocuments
+			record.TotalBytes -= item.addedBytes
+
+			continue
+		}
+
+		item.result.Status = batchWriteStatusCode(item.write.Type)
+		written = true
+	}
+
+	return written, nil
+}
+
+// recordUsage stores the vault's usage after documents were written to it in bulk.
+// The documents have already been written by now, so failing to update the usage is logged rather than returned.
+func (vc *VaultCollection) recordUsage(vaultID string, record *vaultRecord) {
+	// Documents that were created before usage was tracked can take the usage below zero when they're deleted.
+	if record.DocumentCount < 0 {
+		record.DocumentCount = 0
+	}
+
+	if record.TotalBytes < 0 {
+		record.TotalBytes = 0
+	}
+
+	err := vc.storeVaultRecord(vaultID, record)
+	if err != nil {
+		log.WithField("vaultID", vaultID).Errorf("Failed to update vault usage: %s", err.Error())
+	}
+}
+
+func (i *batchItem) fail(err error) {
+	i.write = nil
+	i.result.Status = batchOperationFailureStatusCode(err)
+	i.result.Error = err.Error()
+}
+
+func batchOperationDocumentID(operation models.BatchOperation) string {
+	if operation.Type == models.BatchOperationDelete || operation.Document == nil {
+		return operation.ID
+	}
+
+	return operation.Document.ID
+}
+
+// storedDocumentSize returns the size of the stored document with the given ID, measured the same way as when
+// it was added to the vault's usage.
+func storedDocumentSize(store edvprovider.EDVStore, docID string) (int64, error) {
+	documentBytes, err := store.Get(docID)
+	if err == storage.ErrValueNotFound {
+		return 0, edverrors.ErrDocumentNotFound
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	document := models.EncryptedDocument{}
+
+	err = json.Unmarshal(documentBytes, &document)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse stored document: %w", err)
+	}
+
+	return encodedDocumentSize(document)
+}
+
+// batchWriteStatusCode returns the status code that a successful single-document request of the given type
+// would have returned.
+func batchWriteStatusCode(writeType string) int {
+	switch writeType {
+	case edvprovider.WriteCreate:
+		return http.StatusCreated
+	case edvprovider.WriteDelete:
+		return http.StatusNoContent
+	default:
+		return http.StatusOK
+	}
+}
+
+func batchOperationFailureStatusCode(err error) int {
+	switch {
+	case err == edverrors.ErrDocumentNotFound:
+		return http.StatusNotFound
+	case isClientError(err):
+		return createDocumentFailureStatusCode(err)
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeBatchFailure(rw http.ResponseWriter, req *http.Request, vaultID string, err error) {
+	logFailure(req, "apply batch", vaultID, err)
+
+	rw.WriteHeader(batchFailureStatusCode(err))
+
+	_, err = rw.Write([]byte(fmt.Sprintf("Failed to apply batch: %s", err)))
+	if err != nil {
+		requestlog.Logger(req).Errorf("Failed to write response for batch failure: %s", err.Error())
+	}
+}
+
+func batchFailureStatusCode(err error) int {
+	switch err {
+	case edverrors.ErrVaultNotFound:
+		return http.StatusNotFound
+	case edverrors.ErrEmptyBatch, edverrors.ErrBatchTooLarge:
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
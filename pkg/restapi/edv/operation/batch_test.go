@@ -0,0 +1,204 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+const (
+	testBatchDocID1 = "HvLY78UPL53tEHagb4D2Cs"
+	testBatchDocID2 = "BgspLw38whKt7HeFpNWLuv"
+)
+
+func TestBatchHandler(t *testing.T) {
+	t.Run("Each operation gets its own status", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+		require.Equal(t, http.StatusCreated, createTestDocument(t, op, testDocID))
+		require.Equal(t, http.StatusCreated, createTestDocument(t, op, testBatchDocID2))
+
+		updatedDocument := testBatchDocument(t, testDocID)
+		updatedDocument.Sequence = 1
+
+		results := applyTestBatch(t, op, []models.BatchOperation{
+			{Type: models.BatchOperationCreate, Document: testBatchDocument(t, testBatchDocID1)},
+			{Type: models.BatchOperationUpdate, Document: updatedDocument},
+			{Type: models.BatchOperationDelete, ID: testBatchDocID2},
+			{Type: models.BatchOperationCreate, Document: testBatchDocument(t, testBatchDocID1)},
+			{Type: models.BatchOperationCreate, Document: testBatchDocument(t, "0OIl")},
+			{Type: models.BatchOperationDelete, ID: "DUXDBhi4qGZij3VMjqFY2q"},
+			{Type: models.BatchOperationDelete, ID: "_design/EDV_EncryptedIndexesDesignDoc"},
+			{Type: models.BatchOperationUpdate},
+			{Type: "replace", Document: testBatchDocument(t, "p2Vpv92mjk6Dzd6hYGjwF")},
+		})
+		require.Equal(t, []models.BatchResult{
+			{ID: testBatchDocID1, Status: http.StatusCreated},
+			{ID: testDocID, Status: http.StatusOK},
+			{ID: testBatchDocID2, Status: http.StatusNoContent},
+			{ID: testBatchDocID1, Status: http.StatusBadRequest, Error: edverrors.ErrRepeatedBatchDocument.Error()},
+			{ID: "0OIl", Status: http.StatusBadRequest, Error: edverrors.ErrNotBase58Encoded.Error()},
+			{ID: "DUXDBhi4qGZij3VMjqFY2q", Status: http.StatusNotFound, Error: edverrors.ErrDocumentNotFound.Error()},
+			{ID: "_design/EDV_EncryptedIndexesDesignDoc", Status: http.StatusBadRequest,
+				Error: edverrors.ErrNotBase58Encoded.Error()},
+			{Status: http.StatusBadRequest, Error: edverrors.ErrMissingBatchDocument.Error()},
+			{ID: "p2Vpv92mjk6Dzd6hYGjwF", Status: http.StatusBadRequest,
+				Error: edverrors.ErrInvalidBatchOperation.Error()},
+		}, results)
+
+		_, err := op.vaultCollection.readDocument(testVaultID, testBatchDocID1)
+		require.NoError(t, err)
+
+		documentBytes, err := op.vaultCollection.readDocument(testVaultID, testDocID)
+		require.NoError(t, err)
+		require.Contains(t, string(documentBytes), `"sequence":1`)
+
+		_, err = op.vaultCollection.readDocument(testVaultID, testBatchDocID2)
+		require.Equal(t, edverrors.ErrDocumentNotFound, err)
+
+		stats, err := op.vaultCollection.getVaultStats(testVaultID)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), stats.DocumentCount)
+
+		feed, err := op.vaultCollection.readChanges(testVaultID, "2", 10)
+		require.NoError(t, err)
+		require.Len(t, feed.Changes, 3)
+		require.Equal(t, models.ChangeTypeDeleted, feed.Changes[2].Type)
+	})
+	t.Run("Earlier operations count towards the quota", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, `{"maxDocuments":1}`))
+
+		results := applyTestBatch(t, op, []models.BatchOperation{
+			{Type: models.BatchOperationCreate, Document: testBatchDocument(t, testBatchDocID1)},
+			{Type: models.BatchOperationCreate, Document: testBatchDocument(t, testBatchDocID2)},
+		})
+		require.Equal(t, http.StatusCreated, results[0].Status)
+		require.Equal(t, http.StatusInsufficientStorage, results[1].Status)
+		require.Equal(t, edverrors.ErrVaultQuotaExceeded.Error(), results[1].Error)
+
+		// Deleting a document makes room for another one in the same batch.
+		results = applyTestBatch(t, op, []models.BatchOperation{
+			{Type: models.BatchOperationDelete, ID: testBatchDocID1},
+			{Type: models.BatchOperationCreate, Document: testBatchDocument(t, testBatchDocID2)},
+		})
+		require.Equal(t, http.StatusNoContent, results[0].Status)
+		require.Equal(t, http.StatusCreated, results[1].Status)
+
+		stats, err := op.vaultCollection.getVaultStats(testVaultID)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), stats.DocumentCount)
+	})
+	t.Run("Writes rejected by the store don't count towards the usage", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+		require.Equal(t, http.StatusCreated, createTestDocument(t, op, testDocID))
+
+		results := applyTestBatch(t, op, []models.BatchOperation{
+			{Type: models.BatchOperationCreate, Document: testBatchDocument(t, testDocID)},
+		})
+		require.Equal(t, []models.BatchResult{
+			{ID: testDocID, Status: http.StatusConflict, Error: edverrors.ErrDuplicateDocument.Error()},
+		}, results)
+
+		stats, err := op.vaultCollection.getVaultStats(testVaultID)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), stats.DocumentCount)
+	})
+	t.Run("Invalid batches", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider(), WithBodySizeLimits(BodySizeLimits{Batch: 64 * 1024}))
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+
+		rr := serveBatchRequest(t, op, testVaultID, `{"operations":[]}`)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Equal(t, "Failed to apply batch: "+edverrors.ErrEmptyBatch.Error(), rr.Body.String())
+
+		tooManyOperations := `{"operations":[` +
+			strings.Repeat(`{"type":"delete","id":"x"},`, MaxBatchOperations) + `{"type":"delete","id":"x"}]}`
+
+		rr = serveBatchRequest(t, op, testVaultID, tooManyOperations)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), edverrors.ErrBatchTooLarge.Error())
+
+		rr = serveBatchRequest(t, op, testVaultID, `{`)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+
+		rr = serveBatchRequest(t, op, testVaultID, `{"operations":[`+strings.Repeat(" ", 64*1024)+`]}`)
+		require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	})
+	t.Run("Vault not found", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		rr := serveBatchRequest(t, op, testVaultID, `{"operations":[{"type":"delete","id":"`+testDocID+`"}]}`)
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Contains(t, rr.Body.String(), edverrors.ErrVaultNotFound.Error())
+	})
+	t.Run("Provider errors", func(t *testing.T) {
+		op := New(&mockEDVProvider{errOpenStore: errors.New("open store error")})
+
+		rr := serveBatchRequest(t, op, testVaultID, `{"operations":[{"type":"delete","id":"`+testDocID+`"}]}`)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Equal(t, "Failed to apply batch: open store error", rr.Body.String())
+
+		op = New(&mockEDVProvider{errStoreBulkWrite: errors.New("bulk write error"),
+			numTimesOpenStoreCalledBeforeErr: 1})
+
+		rr = serveBatchRequest(t, op, testVaultID, `{"operations":[{"type":"create","document":`+
+			testEncryptedDocument+`}]}`)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Equal(t, "Failed to apply batch: bulk write error", rr.Body.String())
+	})
+}
+
+func testBatchDocument(t *testing.T, docID string) *models.EncryptedDocument {
+	document := models.EncryptedDocument{}
+	require.NoError(t, json.Unmarshal([]byte(testEncryptedDocument), &document))
+
+	document.ID = docID
+
+	return &document
+}
+
+func applyTestBatch(t *testing.T, op *Operation, operations []models.BatchOperation) []models.BatchResult {
+	batchBytes, err := json.Marshal(models.BatchRequest{Operations: operations})
+	require.NoError(t, err)
+
+	rr := serveBatchRequest(t, op, testVaultID, string(batchBytes))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	response := models.BatchResponse{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+
+	return response.Results
+}
+
+func serveBatchRequest(t *testing.T, op *Operation, vaultID, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, batchEndpoint, bytes.NewBufferString(body))
+	req = mux.SetURLVars(req, map[string]string{vaultIDPathVariable: vaultID})
+
+	rr := httptest.NewRecorder()
+	getHandler(t, op, batchEndpoint).Handle().ServeHTTP(rr, req)
+
+	return rr
+}
@@ -0,0 +1,111 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edv/pkg/requestlog"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+const (
+	// DefaultChangeFeedLimit is the number of changes returned from a vault's change feed when the request
+	// doesn't set a limit.
+	DefaultChangeFeedLimit = 100
+	// MaxChangeFeedLimit is the largest number of changes returned from a vault's change feed in one response.
+	// Larger limits are lowered to this.
+	MaxChangeFeedLimit = 1000
+
+	changesEndpoint = edvCommonEndpointPathRoot + "/{" + vaultIDPathVariable + "}/changes"
+
+	changesSinceQueryParameter = "since"
+	changesLimitQueryParameter = "limit"
+
+	readChangesAction = "readChanges"
+)
+
+func (c *Operation) changesHandler(rw http.ResponseWriter, req *http.Request) {
+	vaultID, success := vaultIDFromPath(req, rw)
+	if !success {
+		return
+	}
+
+	limit, err := changeFeedLimit(req.URL.Query().Get(changesLimitQueryParameter))
+	if err != nil {
+		writeChangesFailure(rw, req, vaultID, err)
+
+		return
+	}
+
+	feed, err := c.vaultCollection.readChanges(vaultID, req.URL.Query().Get(changesSinceQueryParameter), limit)
+	if err != nil {
+		writeChangesFailure(rw, req, vaultID, err)
+
+		return
+	}
+
+	sendJSONResponse(rw, req, http.StatusOK, feed)
+}
+
+func (vc *VaultCollection) readChanges(vaultID, since string, limit int) (*models.ChangeFeed, error) {
+	store, err := vc.provider.OpenStore(vaultID)
+	if err != nil {
+		if err == storage.ErrStoreNotFound {
+			return nil, edverrors.ErrVaultNotFound
+		}
+
+		return nil, err
+	}
+
+	return store.Changes(since, limit)
+}
+
+// changeFeedLimit parses the limit query parameter of a change feed request.
+func changeFeedLimit(rawLimit string) (int, error) {
+	if rawLimit == "" {
+		return DefaultChangeFeedLimit, nil
+	}
+
+	limit, err := strconv.Atoi(rawLimit)
+	if err != nil || limit < 1 {
+		return 0, edverrors.ErrInvalidChangeLimit
+	}
+
+	if limit > MaxChangeFeedLimit {
+		return MaxChangeFeedLimit, nil
+	}
+
+	return limit, nil
+}
+
+func writeChangesFailure(rw http.ResponseWriter, req *http.Request, vaultID string, err error) {
+	logFailure(req, "read changes", vaultID, err)
+
+	rw.WriteHeader(changesFailureStatusCode(err))
+
+	_, err = rw.Write([]byte(fmt.Sprintf("Failed to read changes: %s", err)))
+	if err != nil {
+		requestlog.Logger(req).Errorf("Failed to write response for change feed failure: %s", err.Error())
+	}
+}
+
+func changesFailureStatusCode(err error) int {
+	switch err {
+	case edverrors.ErrVaultNotFound:
+		return http.StatusNotFound
+	case edverrors.ErrInvalidChangeCursor, edverrors.ErrInvalidChangeLimit:
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+func TestChangesHandler(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+
+		rr := serveChangesRequest(t, op, testVaultID, url.Values{})
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+		feed := parseChangeFeed(t, rr)
+		require.Empty(t, feed.Changes)
+		require.False(t, feed.HasMore)
+
+		require.Equal(t, http.StatusCreated, createTestDocument(t, op, testDocID))
+		require.Equal(t, http.StatusCreated, createTestDocument(t, op, testDocID2))
+		require.Equal(t, http.StatusCreated, createTestDocument(t, op, testDocID3))
+
+		rr = serveChangesRequest(t, op, testVaultID, url.Values{"since": {feed.Cursor}, "limit": {"2"}})
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		feed = parseChangeFeed(t, rr)
+		require.Equal(t, []models.DocumentChange{
+			{ID: testDocID, Sequence: "1", Type: models.ChangeTypeCreated},
+			{ID: testDocID2, Sequence: "2", Type: models.ChangeTypeCreated},
+		}, feed.Changes)
+		require.True(t, feed.HasMore)
+
+		rr = serveChangesRequest(t, op, testVaultID, url.Values{"since": {feed.Cursor}})
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		feed = parseChangeFeed(t, rr)
+		require.Equal(t, []models.DocumentChange{
+			{ID: testDocID3, Sequence: "3", Type: models.ChangeTypeCreated},
+		}, feed.Changes)
+		require.Equal(t, "3", feed.Cursor)
+		require.False(t, feed.HasMore)
+	})
+	t.Run("Vault not found", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		rr := serveChangesRequest(t, op, testVaultID, url.Values{})
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Contains(t, rr.Body.String(), edverrors.ErrVaultNotFound.Error())
+	})
+	t.Run("Invalid cursor", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+
+		rr := serveChangesRequest(t, op, testVaultID, url.Values{"since": {"notACursor"}})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), edverrors.ErrInvalidChangeCursor.Error())
+	})
+	t.Run("Invalid limit", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+
+		for _, limit := range []string{"0", "-1", "ten"} {
+			rr := serveChangesRequest(t, op, testVaultID, url.Values{"limit": {limit}})
+			require.Equal(t, http.StatusBadRequest, rr.Code)
+			require.Contains(t, rr.Body.String(), edverrors.ErrInvalidChangeLimit.Error())
+		}
+	})
+	t.Run("Provider error", func(t *testing.T) {
+		op := New(&mockEDVProvider{numTimesOpenStoreCalledBeforeErr: 1, errStoreChanges: errors.New("changes error")})
+
+		rr := serveChangesRequest(t, op, testVaultID, url.Values{})
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "changes error")
+
+		op = New(&mockEDVProvider{errOpenStore: errors.New("open store error")})
+
+		rr = serveChangesRequest(t, op, testVaultID, url.Values{})
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "open store error")
+	})
+}
+
+func TestChangeFeedLimit(t *testing.T) {
+	limit, err := changeFeedLimit("")
+	require.NoError(t, err)
+	require.Equal(t, DefaultChangeFeedLimit, limit)
+
+	limit, err = changeFeedLimit("5")
+	require.NoError(t, err)
+	require.Equal(t, 5, limit)
+
+	limit, err = changeFeedLimit("100000")
+	require.NoError(t, err)
+	require.Equal(t, MaxChangeFeedLimit, limit)
+}
+
+func serveChangesRequest(t *testing.T, op *Operation, vaultID string, query url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, changesEndpoint+"?"+query.Encode(), nil)
+	req = mux.SetURLVars(req, map[string]string{vaultIDPathVariable: vaultID})
+
+	rr := httptest.NewRecorder()
+	getHandler(t, op, changesEndpoint).Handle().ServeHTTP(rr, req)
+
+	return rr
+}
+
+func parseChangeFeed(t *testing.T, rr *httptest.ResponseRecorder) *models.ChangeFeed {
+	feed := models.ChangeFeed{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &feed))
+
+	return &feed
+}
@@ -0,0 +1,132 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+)
+
+const (
+	// DefaultMaxVaultConfigurationSize is the default maximum size, in bytes, of a data vault configuration
+	// in a create vault request.
+	DefaultMaxVaultConfigurationSize = 64 * 1024
+	// DefaultMaxQuerySize is the default maximum size, in bytes, of a vault query request.
+	DefaultMaxQuerySize = 64 * 1024
+	// DefaultMaxDocumentSize is the default maximum size, in bytes, of an encrypted document
+	// in a create document request.
+	DefaultMaxDocumentSize = 16 * 1024 * 1024
+	// DefaultMaxBatchSize is the default maximum size, in bytes, of a batch request.
+	DefaultMaxBatchSize = 64 * 1024 * 1024
+	// DefaultMaxImportSize is the default maximum size, in bytes, of a vault export in an import request.
+	DefaultMaxImportSize = 1024 * 1024 * 1024
+
+	// maxBytesErrorMessage is the message of the error that a body limited by http.MaxBytesReader fails with once
+	// it's read past its limit. The error's type isn't exported by every Go version this module can be built with.
+	maxBytesErrorMessage = "http: request body too large"
+)
+
+// BodySizeLimits holds the maximum request body sizes, in bytes, for each endpoint that accepts a request body.
+type BodySizeLimits struct {
+	VaultConfiguration int64
+	Query              int64
+	Document           int64
+	Batch              int64
+	Import             int64
+}
+
+// WithBodySizeLimits sets the maximum request body sizes. Requests with larger bodies are rejected with a 413 status
+// code. Limits that are 0 are left at their defaults.
+func WithBodySizeLimits(limits BodySizeLimits) Option {
+	return func(opts *Operation) {
+		if limits.VaultConfiguration > 0 {
+			opts.bodySizeLimits.VaultConfiguration = limits.VaultConfiguration
+		}
+
+		if limits.Query > 0 {
+			opts.bodySizeLimits.Query = limits.Query
+		}
+
+		if limits.Document > 0 {
+			opts.bodySizeLimits.Document = limits.Document
+		}
+
+		if limits.Batch > 0 {
+			opts.bodySizeLimits.Batch = limits.Batch
+		}
+
+		if limits.Import > 0 {
+			opts.bodySizeLimits.Import = limits.Import
+		}
+	}
+}
+
+// WithStrictJSON makes the EDV operations reject request bodies that have unknown fields or
+// data after the JSON value.
+func WithStrictJSON() Option {
+	return func(opts *Operation) {
+		opts.strictJSON = true
+	}
+}
+
+// decodeRequestBody decodes the JSON request body into v, reading no more than maxSize bytes.
+// If the body is larger than that, then edverrors.ErrRequestBodyTooLarge is returned and the connection is closed
+// once the response has been written.
+func (c *Operation) decodeRequestBody(rw http.ResponseWriter, req *http.Request, maxSize int64,
+	v interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(rw, req.Body, maxSize))
+
+	if c.strictJSON {
+		decoder.DisallowUnknownFields()
+	}
+
+	err := decoder.Decode(v)
+	if isRequestBodyTooLarge(err) {
+		return edverrors.ErrRequestBodyTooLarge
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if c.strictJSON {
+		if _, err = decoder.Token(); err != io.EOF {
+			if isRequestBodyTooLarge(err) {
+				return edverrors.ErrRequestBodyTooLarge
+			}
+
+			return edverrors.ErrTrailingData
+		}
+	}
+
+	return nil
+}
+
+// decodeFailureStatusCode returns the status code to respond with when a request body couldn't be decoded.
+func decodeFailureStatusCode(err error) int {
+	if err == edverrors.ErrRequestBodyTooLarge {
+		return http.StatusRequestEntityTooLarge
+	}
+
+	return http.StatusBadRequest
+}
+
+// isRequestBodyTooLarge returns whether the given error, or an error it wraps, is from reading a request body
+// limited by http.MaxBytesReader past its limit.
+func isRequestBodyTooLarge(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if err.Error() == maxBytesErrorMessage {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,157 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/jwe"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+func TestBodySizeLimits(t *testing.T) {
+	t.Run("Create vault", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider(),
+			WithBodySizeLimits(BodySizeLimits{VaultConfiguration: int64(len(testDataVaultConfiguration) - 1)}))
+
+		rr := serveTestRequest(t, op, createVaultEndpoint, testDataVaultConfiguration)
+		require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		require.Equal(t, edverrors.ErrRequestBodyTooLarge.Error(), rr.Body.String())
+
+		op = New(memedvprovider.NewProvider(),
+			WithBodySizeLimits(BodySizeLimits{VaultConfiguration: int64(len(testDataVaultConfiguration))}))
+
+		rr = serveTestRequest(t, op, createVaultEndpoint, testDataVaultConfiguration)
+		require.Equal(t, http.StatusCreated, rr.Code)
+	})
+	t.Run("Query", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider(), WithBodySizeLimits(BodySizeLimits{Query: 10}))
+
+		rr := serveTestRequest(t, op, queryVaultEndpoint, testQuery)
+		require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	})
+	t.Run("Create document", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider(), WithBodySizeLimits(BodySizeLimits{Document: 10}))
+
+		rr := serveTestRequest(t, op, createDocumentEndpoint, testEncryptedDocument)
+		require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	})
+	t.Run("Connection is closed after a body over the limit", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider(), WithBodySizeLimits(BodySizeLimits{VaultConfiguration: 10}))
+
+		server := httptest.NewServer(getHandler(t, op, createVaultEndpoint).Handle())
+		defer server.Close()
+
+		resp, err := http.Post(server.URL, "application/json", bytes.NewBufferString(testDataVaultConfiguration))
+		require.NoError(t, err)
+
+		defer func() {
+			require.NoError(t, resp.Body.Close())
+		}()
+
+		require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+		require.True(t, resp.Close)
+	})
+	t.Run("Defaults", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider(), WithBodySizeLimits(BodySizeLimits{}))
+
+		require.Equal(t, BodySizeLimits{
+			VaultConfiguration: DefaultMaxVaultConfigurationSize,
+			Query:              DefaultMaxQuerySize,
+			Document:           DefaultMaxDocumentSize,
+			Batch:              DefaultMaxBatchSize,
+			Import:             DefaultMaxImportSize,
+		}, op.bodySizeLimits)
+	})
+}
+
+func TestStrictJSON(t *testing.T) {
+	withUnknownField := strings.Replace(testDataVaultConfiguration, `"sequence": 0,`,
+		`"sequence": 0, "unknownField": true,`, 1)
+	withTrailingData := testDataVaultConfiguration + `{}`
+
+	t.Run("Lenient by default", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		rr := serveTestRequest(t, op, createVaultEndpoint, withUnknownField)
+		require.Equal(t, http.StatusCreated, rr.Code)
+	})
+	t.Run("Unknown fields are rejected", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider(), WithStrictJSON())
+
+		rr := serveTestRequest(t, op, createVaultEndpoint, withUnknownField)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "unknown field")
+	})
+	t.Run("Trailing data is rejected", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider(), WithStrictJSON())
+
+		rr := serveTestRequest(t, op, createVaultEndpoint, withTrailingData)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Equal(t, edverrors.ErrTrailingData.Error(), rr.Body.String())
+	})
+	t.Run("Trailing data over the size limit", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider(), WithStrictJSON(),
+			WithBodySizeLimits(BodySizeLimits{VaultConfiguration: int64(len(testDataVaultConfiguration) + 1)}))
+
+		rr := serveTestRequest(t, op, createVaultEndpoint, testDataVaultConfiguration+`  {"more": "data"}`)
+		require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	})
+	t.Run("Trailing whitespace is allowed", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider(), WithStrictJSON())
+
+		rr := serveTestRequest(t, op, createVaultEndpoint, testDataVaultConfiguration+"\n")
+		require.Equal(t, http.StatusCreated, rr.Code)
+	})
+}
+
+func TestValidateEncryptedDocument(t *testing.T) {
+	vc := &VaultCollection{}
+
+	var validDocument models.EncryptedDocument
+
+	require.NoError(t, json.Unmarshal([]byte(testEncryptedDocument), &validDocument))
+
+	require.Equal(t, edverrors.ErrMissingDocumentID, vc.validateEncryptedDocument(&models.EncryptedDocument{}, ""))
+	require.Equal(t, edverrors.ErrNotBase58Encoded,
+		vc.validateEncryptedDocument(&models.EncryptedDocument{ID: "0OIl"}, ""))
+	require.Equal(t, edverrors.ErrMissingJWE, vc.validateEncryptedDocument(&models.EncryptedDocument{ID: testDocID}, ""))
+	require.Equal(t, edverrors.ErrMissingJWE,
+		vc.validateEncryptedDocument(&models.EncryptedDocument{ID: testDocID, JWE: []byte("null")}, ""))
+	require.True(t, errors.Is(vc.validateEncryptedDocument(
+		&models.EncryptedDocument{ID: testDocID, JWE: []byte("{}")}, ""), jwe.ErrInvalidJWE))
+	require.NoError(t, vc.validateEncryptedDocument(&validDocument, ""))
+
+	op := New(memedvprovider.NewProvider())
+
+	createDataVaultExpectSuccess(t, op)
+
+	rr := serveTestRequest(t, op, createDocumentEndpoint, `{"id":"`+testDocID+`"}`)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Equal(t, edverrors.ErrMissingJWE.Error(), rr.Body.String())
+}
+
+func serveTestRequest(t *testing.T, op *Operation, endpoint, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, endpoint, bytes.NewBufferString(body))
+	req = mux.SetURLVars(req, map[string]string{vaultIDPathVariable: testVaultID})
+
+	rr := httptest.NewRecorder()
+	getHandler(t, op, endpoint).Handle().ServeHTTP(rr, req)
+
+	return rr
+}
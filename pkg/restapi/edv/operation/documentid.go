@@ -0,0 +1,73 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"strings"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/google/uuid"
+
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+const (
+	documentIDLengthInBytes = 16
+	maxDocumentIDLength     = 256
+
+	// Mapping documents used by the CouchDB EDV provider for encrypted indexing have IDs that contain this marker.
+	mappingDocumentIDMarker = "_mapping_"
+)
+
+// checkDocumentIDFormat checks that the given document ID format is supported.
+func checkDocumentIDFormat(format string) error {
+	switch format {
+	case "", models.DocumentIDFormatBase58, models.DocumentIDFormatUUID, models.DocumentIDFormatAny:
+		return nil
+	default:
+		return edverrors.ErrInvalidDocumentIDFormat
+	}
+}
+
+// checkDocumentID checks that the given document ID has the given format. A blank format means base58.
+func checkDocumentID(id, format string) error {
+	switch format {
+	case models.DocumentIDFormatUUID:
+		parsedUUID, err := uuid.Parse(id)
+		if err != nil || parsedUUID.String() != id {
+			return edverrors.ErrNotUUID
+		}
+
+		return nil
+	case models.DocumentIDFormatAny:
+		if len(id) > maxDocumentIDLength || strings.HasPrefix(id, "_") || strings.Contains(id, mappingDocumentIDMarker) {
+			return edverrors.ErrInvalidDocumentID
+		}
+
+		return nil
+	default:
+		return checkIfBase58Encoded128BitValue(id)
+	}
+}
+
+// checkIfBase58Encoded128BitValue checks that the given ID is the base58 encoding of exactly 128 bits.
+// Each leading zero byte of a value is encoded as a leading "1", so a 128-bit value that starts with zero bits
+// still decodes to the full 16 bytes. The decoded value must also encode back to the same ID, so that there's only
+// one valid ID for each 128-bit value.
+func checkIfBase58Encoded128BitValue(id string) error {
+	decodedBytes := base58.Decode(id)
+	if len(decodedBytes) == 0 || base58.Encode(decodedBytes) != id {
+		return edverrors.ErrNotBase58Encoded
+	}
+
+	if len(decodedBytes) != documentIDLengthInBytes {
+		return edverrors.ErrNot128BitValue
+	}
+
+	return nil
+}
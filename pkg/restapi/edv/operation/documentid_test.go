@@ -0,0 +1,107 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+func TestCheckIfBase58Encoded128BitValue(t *testing.T) {
+	valueWithLeadingZeroBytes := append([]byte{0, 0}, []byte("fourteen bytes")...)
+	fifteenByteValue := []byte("fifteen bytes!!")
+	seventeenByteValue := []byte("seventeen bytes!!")
+
+	testCases := []struct {
+		name        string
+		id          string
+		expectedErr error
+	}{
+		{"Valid ID", testDocID, nil},
+		{"Leading zero bytes", base58.Encode(valueWithLeadingZeroBytes), nil},
+		{"All zero bytes", strings.Repeat("1", 16), nil},
+		{"Empty ID", "", edverrors.ErrNotBase58Encoded},
+		{"Characters outside of the base58 alphabet", "0OIl", edverrors.ErrNotBase58Encoded},
+		{"Valid ID with an invalid character", testDocID + "0", edverrors.ErrNotBase58Encoded},
+		{"Too short", "2CHi6", edverrors.ErrNot128BitValue},
+		{"120 bits", base58.Encode(fifteenByteValue), edverrors.ErrNot128BitValue},
+		{"136 bits", base58.Encode(seventeenByteValue), edverrors.ErrNot128BitValue},
+		{"Extra leading zero byte", "1" + testDocID, edverrors.ErrNot128BitValue},
+		{"Missing leading zero byte", base58.Encode(valueWithLeadingZeroBytes)[1:], edverrors.ErrNot128BitValue},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.expectedErr, checkIfBase58Encoded128BitValue(tc.id), tc.name)
+	}
+}
+
+func TestCheckDocumentID(t *testing.T) {
+	require.NoError(t, checkDocumentID(testDocID, ""))
+	require.NoError(t, checkDocumentID(testDocID, models.DocumentIDFormatBase58))
+	require.Equal(t, edverrors.ErrNotBase58Encoded, checkDocumentID("my-document", models.DocumentIDFormatBase58))
+
+	require.NoError(t, checkDocumentID("3c5e7e9a-1f2d-4b8e-9a6c-0d1e2f3a4b5c", models.DocumentIDFormatUUID))
+	require.Equal(t, edverrors.ErrNotUUID,
+		checkDocumentID("3C5E7E9A-1F2D-4B8E-9A6C-0D1E2F3A4B5C", models.DocumentIDFormatUUID))
+	require.Equal(t, edverrors.ErrNotUUID,
+		checkDocumentID("urn:uuid:3c5e7e9a-1f2d-4b8e-9a6c-0d1e2f3a4b5c", models.DocumentIDFormatUUID))
+	require.Equal(t, edverrors.ErrNotUUID, checkDocumentID(testDocID, models.DocumentIDFormatUUID))
+
+	require.NoError(t, checkDocumentID("my-document", models.DocumentIDFormatAny))
+	require.Equal(t, edverrors.ErrInvalidDocumentID, checkDocumentID("_design", models.DocumentIDFormatAny))
+	require.Equal(t, edverrors.ErrInvalidDocumentID,
+		checkDocumentID(testDocID+mappingDocumentIDMarker+"1", models.DocumentIDFormatAny))
+	require.Equal(t, edverrors.ErrInvalidDocumentID,
+		checkDocumentID(strings.Repeat("a", maxDocumentIDLength+1), models.DocumentIDFormatAny))
+}
+
+func TestDocumentIDFormatPolicy(t *testing.T) {
+	withIDFormat := func(format string) string {
+		return strings.Replace(testDataVaultConfiguration, `"sequence": 0,`,
+			`"sequence": 0,"policy": {"documentIdFormat": "`+format+`"},`, 1)
+	}
+
+	t.Run("Unsupported format", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		rr := serveTestRequest(t, op, createVaultEndpoint, withIDFormat("base64"))
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), edverrors.ErrInvalidDocumentIDFormat.Error())
+	})
+	t.Run("UUID document IDs", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		rr := serveTestRequest(t, op, createVaultEndpoint, withIDFormat(models.DocumentIDFormatUUID))
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		rr = serveTestRequest(t, op, createDocumentEndpoint, testEncryptedDocument)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Equal(t, edverrors.ErrNotUUID.Error(), rr.Body.String())
+
+		rr = serveTestRequest(t, op, createDocumentEndpoint,
+			strings.Replace(testEncryptedDocument, testDocID, "3c5e7e9a-1f2d-4b8e-9a6c-0d1e2f3a4b5c", 1))
+		require.Equal(t, http.StatusCreated, rr.Code)
+	})
+	t.Run("Any document IDs", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		rr := serveTestRequest(t, op, createVaultEndpoint, withIDFormat(models.DocumentIDFormatAny))
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		rr = serveTestRequest(t, op, createDocumentEndpoint,
+			strings.Replace(testEncryptedDocument, testDocID, "invoices/2020-06.json", 1))
+		require.Equal(t, http.StatusCreated, rr.Code)
+	})
+}
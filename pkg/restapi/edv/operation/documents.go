@@ -0,0 +1,168 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edv/pkg/requestlog"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+const (
+	// DefaultDocumentListLimit is the number of document IDs returned when listing a vault's documents
+	// and the request doesn't set a limit.
+	DefaultDocumentListLimit = 100
+	// MaxDocumentListLimit is the largest number of document IDs returned when listing a vault's documents
+	// in one response. Larger limits are lowered to this.
+	MaxDocumentListLimit = 1000
+
+	listDocumentsEndpoint = edvCommonEndpointPathRoot + "/{" + vaultIDPathVariable + "}/documents"
+
+	listDocumentsCursorQueryParameter           = "cursor"
+	listDocumentsLimitQueryParameter            = "limit"
+	listDocumentsIncludeDocumentsQueryParameter = "includeDocuments"
+
+	listDocumentsAction = "listDocuments"
+)
+
+func (c *Operation) listDocumentsHandler(rw http.ResponseWriter, req *http.Request) {
+	vaultID, success := vaultIDFromPath(req, rw)
+	if !success {
+		return
+	}
+
+	limit, err := documentListLimit(req.URL.Query().Get(listDocumentsLimitQueryParameter))
+	if err != nil {
+		writeListDocumentsFailure(rw, req, vaultID, err)
+
+		return
+	}
+
+	includeDocuments, err := includeDocumentsParameter(
+		req.URL.Query().Get(listDocumentsIncludeDocumentsQueryParameter))
+	if err != nil {
+		writeListDocumentsFailure(rw, req, vaultID, err)
+
+		return
+	}
+
+	list, err := c.vaultCollection.listDocuments(vaultID,
+		req.URL.Query().Get(listDocumentsCursorQueryParameter), limit, includeDocuments)
+	if err != nil {
+		writeListDocumentsFailure(rw, req, vaultID, err)
+
+		return
+	}
+
+	sendJSONResponse(rw, req, http.StatusOK, list)
+}
+
+// listDocuments returns a page of the IDs of the vault's documents, along with the documents themselves if
+// includeDocuments is true. Documents that are deleted between being listed and being read are left out.
+func (vc *VaultCollection) listDocuments(vaultID, cursor string, limit int,
+	includeDocuments bool) (*models.DocumentList, error) {
+	store, err := vc.provider.OpenStore(vaultID)
+	if err != nil {
+		if err == storage.ErrStoreNotFound {
+			return nil, edverrors.ErrVaultNotFound
+		}
+
+		return nil, err
+	}
+
+	list, err := store.ListDocuments(cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if !includeDocuments {
+		return list, nil
+	}
+
+	documentIDs := list.DocumentIDs
+	list.DocumentIDs = []string{}
+	list.Documents = []models.EncryptedDocument{}
+
+	for _, docID := range documentIDs {
+		documentBytes, err := store.Get(docID)
+		if err == storage.ErrValueNotFound {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read document %s: %w", docID, err)
+		}
+
+		document := models.EncryptedDocument{}
+
+		err = json.Unmarshal(documentBytes, &document)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal document %s: %w", docID, err)
+		}
+
+		list.DocumentIDs = append(list.DocumentIDs, docID)
+		list.Documents = append(list.Documents, document)
+	}
+
+	return list, nil
+}
+
+// documentListLimit parses the limit query parameter of a document list request.
+func documentListLimit(rawLimit string) (int, error) {
+	if rawLimit == "" {
+		return DefaultDocumentListLimit, nil
+	}
+
+	limit, err := strconv.Atoi(rawLimit)
+	if err != nil || limit < 1 {
+		return 0, edverrors.ErrInvalidDocumentListLimit
+	}
+
+	if limit > MaxDocumentListLimit {
+		return MaxDocumentListLimit, nil
+	}
+
+	return limit, nil
+}
+
+func includeDocumentsParameter(rawIncludeDocuments string) (bool, error) {
+	if rawIncludeDocuments == "" {
+		return false, nil
+	}
+
+	includeDocuments, err := strconv.ParseBool(rawIncludeDocuments)
+	if err != nil {
+		return false, edverrors.ErrInvalidIncludeDocuments
+	}
+
+	return includeDocuments, nil
+}
+
+func writeListDocumentsFailure(rw http.ResponseWriter, req *http.Request, vaultID string, err error) {
+	logFailure(req, "list documents", vaultID, err)
+
+	switch err {
+	case edverrors.ErrVaultNotFound:
+		rw.WriteHeader(http.StatusNotFound)
+	case edverrors.ErrInvalidDocumentListLimit, edverrors.ErrInvalidIncludeDocuments:
+		rw.WriteHeader(http.StatusBadRequest)
+	default:
+		rw.WriteHeader(http.StatusInternalServerError)
+	}
+
+	_, err = rw.Write([]byte(fmt.Sprintf("Failed to list documents: %s", err)))
+	if err != nil {
+		requestlog.Logger(req).Errorf("Failed to write response for document list failure: %s", err.Error())
+	}
+}
@@ -0,0 +1,147 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+func TestListDocumentsHandler(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+
+		rr := serveListDocumentsRequest(t, op, testVaultID, url.Values{})
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+		require.Equal(t, &models.DocumentList{DocumentIDs: []string{}}, parseDocumentList(t, rr))
+
+		require.Equal(t, http.StatusCreated, createTestDocument(t, op, testDocID))
+		require.Equal(t, http.StatusCreated, createTestDocument(t, op, testDocID2))
+		require.Equal(t, http.StatusCreated, createTestDocument(t, op, testDocID3))
+
+		rr = serveListDocumentsRequest(t, op, testVaultID, url.Values{"limit": {"2"}})
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		list := parseDocumentList(t, rr)
+		require.Equal(t, &models.DocumentList{DocumentIDs: []string{testDocID3, testDocID2}, Cursor: testDocID2,
+			HasMore: true}, list)
+
+		rr = serveListDocumentsRequest(t, op, testVaultID,
+			url.Values{"cursor": {list.Cursor}, "includeDocuments": {"true"}})
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		list = parseDocumentList(t, rr)
+		require.Equal(t, []string{testDocID}, list.DocumentIDs)
+		require.Len(t, list.Documents, 1)
+		require.Equal(t, testDocID, list.Documents[0].ID)
+		require.False(t, list.HasMore)
+	})
+	t.Run("Document deleted while listing", func(t *testing.T) {
+		op := New(&mockEDVProvider{numTimesOpenStoreCalledBeforeErr: 1, errStoreGet: storage.ErrValueNotFound})
+
+		rr := serveListDocumentsRequest(t, op, testVaultID, url.Values{"includeDocuments": {"1"}})
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, &models.DocumentList{DocumentIDs: []string{}, Documents: nil, Cursor: "docID1"},
+			parseDocumentList(t, rr))
+	})
+	t.Run("Vault not found", func(t *testing.T) {
+		rr := serveListDocumentsRequest(t, New(memedvprovider.NewProvider()), testVaultID, url.Values{})
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Equal(t, "Failed to list documents: "+edverrors.ErrVaultNotFound.Error(), rr.Body.String())
+	})
+	t.Run("Invalid parameters", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		for _, limit := range []string{"0", "-1", "ten"} {
+			rr := serveListDocumentsRequest(t, op, testVaultID, url.Values{"limit": {limit}})
+			require.Equal(t, http.StatusBadRequest, rr.Code)
+			require.Contains(t, rr.Body.String(), edverrors.ErrInvalidDocumentListLimit.Error())
+		}
+
+		rr := serveListDocumentsRequest(t, op, testVaultID, url.Values{"includeDocuments": {"maybe"}})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), edverrors.ErrInvalidIncludeDocuments.Error())
+	})
+	t.Run("Provider errors", func(t *testing.T) {
+		rr := serveListDocumentsRequest(t, New(&mockEDVProvider{errOpenStore: errors.New("open store error")}),
+			testVaultID, url.Values{})
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Equal(t, "Failed to list documents: open store error", rr.Body.String())
+
+		rr = serveListDocumentsRequest(t, New(&mockEDVProvider{numTimesOpenStoreCalledBeforeErr: 1,
+			errStoreListDocuments: errors.New("list error")}), testVaultID, url.Values{})
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Equal(t, "Failed to list documents: list error", rr.Body.String())
+
+		rr = serveListDocumentsRequest(t, New(&mockEDVProvider{numTimesOpenStoreCalledBeforeErr: 1,
+			errStoreGet: errors.New("get error")}), testVaultID, url.Values{"includeDocuments": {"true"}})
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Equal(t, "Failed to list documents: failed to read document docID1: get error", rr.Body.String())
+
+		rr = serveListDocumentsRequest(t, New(&mockEDVProvider{numTimesOpenStoreCalledBeforeErr: 1}),
+			testVaultID, url.Values{"includeDocuments": {"true"}})
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "Failed to list documents: failed to unmarshal document docID1")
+	})
+}
+
+func TestDocumentListLimit(t *testing.T) {
+	limit, err := documentListLimit("")
+	require.NoError(t, err)
+	require.Equal(t, DefaultDocumentListLimit, limit)
+
+	limit, err = documentListLimit("5")
+	require.NoError(t, err)
+	require.Equal(t, 5, limit)
+
+	limit, err = documentListLimit("100000")
+	require.NoError(t, err)
+	require.Equal(t, MaxDocumentListLimit, limit)
+}
+
+func serveListDocumentsRequest(t *testing.T, op *Operation, vaultID string,
+	query url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, listDocumentsEndpoint+"?"+query.Encode(), nil)
+	req = mux.SetURLVars(req, map[string]string{vaultIDPathVariable: vaultID})
+
+	rr := httptest.NewRecorder()
+
+	// Listing and creating documents share a path, so the handler is looked up by method as well.
+	for _, handler := range op.GetRESTHandlers() {
+		if handler.Path() == listDocumentsEndpoint && handler.Method() == http.MethodGet {
+			handler.Handle().ServeHTTP(rr, req)
+
+			return rr
+		}
+	}
+
+	require.Fail(t, "unable to find handler")
+
+	return nil
+}
+
+func parseDocumentList(t *testing.T, rr *httptest.ResponseRecorder) *models.DocumentList {
+	list := models.DocumentList{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &list))
+
+	return &list
+}
@@ -0,0 +1,220 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/trustbloc/edv/pkg/edvprovider"
+	"github.com/trustbloc/edv/pkg/requestlog"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+const (
+	// DefaultEventPollInterval is how often event streams check the vault's change feed for changes made through
+	// other EDV server instances, if WithEventPollInterval isn't used.
+	DefaultEventPollInterval = 2 * time.Second
+
+	eventsEndpoint = edvCommonEndpointPathRoot + "/{" + vaultIDPathVariable + "}/events"
+
+	lastEventIDHeader = "Last-Event-ID"
+
+	// eventStreamHeartbeatInterval is how often a comment is sent on an idle event stream so that proxies
+	// don't close the connection.
+	eventStreamHeartbeatInterval = 15 * time.Second
+
+	streamEventsAction = "streamEvents"
+)
+
+var errStreamingNotSupported = errors.New("the response writer doesn't support streaming")
+
+// WithEventPollInterval sets how often event streams check the vault's change feed for changes.
+// Documents created through the same EDV server instance are pushed right away, so this only affects how quickly
+// changes made through other instances sharing the same database are noticed.
+func WithEventPollInterval(interval time.Duration) Option {
+	return func(opts *Operation) {
+		if interval > 0 {
+			opts.eventPollInterval = interval
+		}
+	}
+}
+
+// changeNotifier lets event streams wait for documents to be written to a vault through this EDV server instance.
+type changeNotifier struct {
+	mux     sync.Mutex
+	waiters map[string]chan struct{}
+}
+
+// wait returns a channel that's closed the next time the given vault changes.
+func (n *changeNotifier) wait(vaultID string) <-chan struct{} {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+
+	if n.waiters == nil {
+		n.waiters = make(map[string]chan struct{})
+	}
+
+	waiter, exists := n.waiters[vaultID]
+	if !exists {
+		waiter = make(chan struct{})
+		n.waiters[vaultID] = waiter
+	}
+
+	return waiter
+}
+
+// notify wakes up everything waiting for the given vault to change.
+func (n *changeNotifier) notify(vaultID string) {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+
+	if waiter, exists := n.waiters[vaultID]; exists {
+		close(waiter)
+		delete(n.waiters, vaultID)
+	}
+}
+
+// eventsHandler streams the changes made to the documents in a vault as Server-Sent Events.
+// Each event has the change sequence as its ID, the change type as its name and the change as its data.
+// A stream starts with the changes made after the Last-Event-ID header if it's set, then the since query parameter,
+// or otherwise from the time of the request.
+func (c *Operation) eventsHandler(rw http.ResponseWriter, req *http.Request) {
+	vaultID, success := vaultIDFromPath(req, rw)
+	if !success {
+		return
+	}
+
+	since := req.Header.Get(lastEventIDHeader)
+	if since == "" {
+		since = req.URL.Query().Get(changesSinceQueryParameter)
+	}
+
+	if since == "" {
+		since = edvprovider.ChangesNow
+	}
+
+	flusher, ok := rw.(http.Flusher)
+	if !ok {
+		writeEventsFailure(rw, req, vaultID, errStreamingNotSupported)
+
+		return
+	}
+
+	// The first read happens before the stream starts, so that a missing vault or an invalid cursor can still be
+	// reported with the right status code.
+	feed, err := c.vaultCollection.readChanges(vaultID, since, MaxChangeFeedLimit)
+	if err != nil {
+		writeEventsFailure(rw, req, vaultID, err)
+
+		return
+	}
+
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.Header().Set("Cache-Control", "no-cache")
+	rw.Header().Set("X-Accel-Buffering", "no")
+	rw.WriteHeader(http.StatusOK)
+
+	if err := writeEvents(rw, feed.Changes); err != nil {
+		requestlog.Logger(req).Debugf("Failed to write events for vault %s: %s", vaultID, err.Error())
+
+		return
+	}
+
+	flusher.Flush()
+
+	c.streamEvents(rw, flusher, req, vaultID, feed.Cursor)
+}
+
+// streamEvents keeps sending the changes made after the given cursor until the client disconnects.
+func (c *Operation) streamEvents(rw http.ResponseWriter, flusher http.Flusher, req *http.Request, vaultID,
+	cursor string) {
+	pollTicker := time.NewTicker(c.eventPollInterval)
+	defer pollTicker.Stop()
+
+	heartbeatTicker := time.NewTicker(c.eventHeartbeatInterval)
+	defer heartbeatTicker.Stop()
+
+	for {
+		// Start waiting before reading, so that changes made after the read aren't missed.
+		changed := c.vaultCollection.changeNotifier.wait(vaultID)
+
+		feed, err := c.vaultCollection.readChanges(vaultID, cursor, MaxChangeFeedLimit)
+		if err != nil {
+			requestlog.Logger(req).Errorf("Failed to read changes for vault %s event stream: %s", vaultID, err.Error())
+
+			return
+		}
+
+		if err := writeEvents(rw, feed.Changes); err != nil {
+			requestlog.Logger(req).Debugf("Failed to write events for vault %s: %s", vaultID, err.Error())
+
+			return
+		}
+
+		flusher.Flush()
+
+		cursor = feed.Cursor
+
+		if !feed.HasMore && !waitForChanges(rw, flusher, req, changed, pollTicker.C, heartbeatTicker.C) {
+			return
+		}
+	}
+}
+
+// waitForChanges blocks until the vault may have changed, sending heartbeats in the meantime.
+// It returns false if the stream should end.
+func waitForChanges(rw http.ResponseWriter, flusher http.Flusher, req *http.Request, changed <-chan struct{},
+	poll, heartbeat <-chan time.Time) bool {
+	for {
+		select {
+		case <-req.Context().Done():
+			return false
+		case <-changed:
+			return true
+		case <-poll:
+			return true
+		case <-heartbeat:
+			if _, err := rw.Write([]byte(":\n\n")); err != nil {
+				return false
+			}
+
+			flusher.Flush()
+		}
+	}
+}
+
+func writeEvents(rw http.ResponseWriter, changes []models.DocumentChange) error {
+	for _, change := range changes {
+		changeBytes, err := json.Marshal(change)
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(rw, "id: %s\nevent: %s\ndata: %s\n\n", change.Sequence, change.Type, changeBytes)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeEventsFailure(rw http.ResponseWriter, req *http.Request, vaultID string, err error) {
+	logFailure(req, "stream events", vaultID, err)
+
+	rw.WriteHeader(changesFailureStatusCode(err))
+
+	_, err = rw.Write([]byte(fmt.Sprintf("Failed to stream events: %s", err)))
+	if err != nil {
+		requestlog.Logger(req).Errorf("Failed to write response for event stream failure: %s", err.Error())
+	}
+}
@@ -0,0 +1,283 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+func TestEventsHandler(t *testing.T) {
+	t.Run("New documents are pushed right away", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider(), WithEventPollInterval(time.Hour))
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+		require.Equal(t, http.StatusCreated, createTestDocument(t, op, testDocID))
+
+		srv := startEventsServer(op)
+		defer srv.Close()
+
+		resp, events := openEventStream(t, srv, "", "")
+		defer closeEventStream(t, resp)
+
+		require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		require.Equal(t, "no-cache", resp.Header.Get("Cache-Control"))
+
+		// The document created before the stream started isn't sent.
+		require.Equal(t, http.StatusCreated, createTestDocument(t, op, testDocID2))
+
+		event := readEvent(t, events)
+		require.Equal(t, testEvent{id: "2", name: models.ChangeTypeCreated,
+			change: models.DocumentChange{ID: testDocID2, Sequence: "2", Type: models.ChangeTypeCreated}}, event)
+	})
+	t.Run("Resume from Last-Event-ID", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+		require.Equal(t, http.StatusCreated, createTestDocument(t, op, testDocID))
+		require.Equal(t, http.StatusCreated, createTestDocument(t, op, testDocID2))
+		require.Equal(t, http.StatusCreated, createTestDocument(t, op, testDocID3))
+
+		srv := startEventsServer(op)
+		defer srv.Close()
+
+		resp, events := openEventStream(t, srv, "1", "")
+		defer closeEventStream(t, resp)
+
+		require.Equal(t, testDocID2, readEvent(t, events).change.ID)
+		require.Equal(t, testDocID3, readEvent(t, events).change.ID)
+	})
+	t.Run("Start from the since query parameter", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+		require.Equal(t, http.StatusCreated, createTestDocument(t, op, testDocID))
+
+		srv := startEventsServer(op)
+		defer srv.Close()
+
+		resp, events := openEventStream(t, srv, "", "0")
+		defer closeEventStream(t, resp)
+
+		require.Equal(t, testDocID, readEvent(t, events).change.ID)
+	})
+	t.Run("Changes made through other instances are polled", func(t *testing.T) {
+		provider := memedvprovider.NewProvider()
+		op := New(provider, WithEventPollInterval(10*time.Millisecond))
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+
+		srv := startEventsServer(op)
+		defer srv.Close()
+
+		resp, events := openEventStream(t, srv, "", "")
+		defer closeEventStream(t, resp)
+
+		// Writing to the store directly doesn't notify the event stream.
+		store, err := provider.OpenStore(testVaultID)
+		require.NoError(t, err)
+		require.NoError(t, store.Put(models.EncryptedDocument{ID: testDocID}))
+
+		require.Equal(t, testDocID, readEvent(t, events).change.ID)
+	})
+	t.Run("Heartbeats are sent on idle streams", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider(), WithEventPollInterval(time.Hour))
+		op.eventHeartbeatInterval = 10 * time.Millisecond
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+
+		srv := startEventsServer(op)
+		defer srv.Close()
+
+		resp, events := openEventStream(t, srv, "", "")
+		defer closeEventStream(t, resp)
+
+		line, err := events.ReadString('\n')
+		require.NoError(t, err)
+		require.Equal(t, ":\n", line)
+	})
+	t.Run("Stream ends when the client disconnects", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		req := httptest.NewRequest(http.MethodGet, eventsEndpoint, nil).WithContext(ctx)
+		req = mux.SetURLVars(req, map[string]string{vaultIDPathVariable: testVaultID})
+
+		done := make(chan struct{})
+
+		go func() {
+			op.eventsHandler(httptest.NewRecorder(), req)
+			close(done)
+		}()
+
+		cancel()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			require.Fail(t, "event stream didn't end after the client disconnected")
+		}
+	})
+	t.Run("Vault not found", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		rr := httptest.NewRecorder()
+		serveEventsRequest(t, op, "", rr)
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Contains(t, rr.Body.String(), edverrors.ErrVaultNotFound.Error())
+	})
+	t.Run("Invalid Last-Event-ID", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+
+		rr := httptest.NewRecorder()
+		serveEventsRequest(t, op, "notACursor", rr)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), edverrors.ErrInvalidChangeCursor.Error())
+	})
+	t.Run("Response writer doesn't support streaming", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+
+		rr := httptest.NewRecorder()
+		serveEventsRequest(t, op, "", nonFlushingResponseWriter{rr})
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), errStreamingNotSupported.Error())
+	})
+	t.Run("Provider error while streaming", func(t *testing.T) {
+		op := New(&mockEDVProvider{errOpenStore: errors.New("open store error"), numTimesOpenStoreCalledBeforeErr: 1},
+			WithEventPollInterval(time.Millisecond))
+
+		rr := httptest.NewRecorder()
+		serveEventsRequest(t, op, "", rr)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Empty(t, rr.Body.String())
+	})
+}
+
+func TestChangeNotifier(t *testing.T) {
+	notifier := changeNotifier{}
+
+	notifier.notify("vault1")
+
+	vault1Changed := notifier.wait("vault1")
+	vault2Changed := notifier.wait("vault2")
+
+	require.Equal(t, vault1Changed, notifier.wait("vault1"))
+
+	notifier.notify("vault1")
+
+	select {
+	case <-vault1Changed:
+	default:
+		require.Fail(t, "waiter wasn't notified")
+	}
+
+	select {
+	case <-vault2Changed:
+		require.Fail(t, "waiter for another vault was notified")
+	default:
+	}
+
+	require.NotEqual(t, vault1Changed, notifier.wait("vault1"))
+}
+
+type testEvent struct {
+	id     string
+	name   string
+	change models.DocumentChange
+}
+
+type nonFlushingResponseWriter struct {
+	http.ResponseWriter
+}
+
+func startEventsServer(op *Operation) *httptest.Server {
+	router := mux.NewRouter()
+	router.UseEncodedPath()
+	router.HandleFunc(eventsEndpoint, op.eventsHandler).Methods(http.MethodGet)
+
+	return httptest.NewServer(router)
+}
+
+func openEventStream(t *testing.T, srv *httptest.Server, lastEventID, since string) (*http.Response, *bufio.Reader) {
+	streamURL := srv.URL + "/encrypted-data-vaults/" + url.PathEscape(testVaultID) + "/events"
+	if since != "" {
+		streamURL += "?since=" + url.QueryEscape(since)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, streamURL, nil)
+	require.NoError(t, err)
+
+	if lastEventID != "" {
+		req.Header.Set(lastEventIDHeader, lastEventID)
+	}
+
+	resp, err := http.DefaultClient.Do(req) //nolint: bodyclose
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	return resp, bufio.NewReader(resp.Body)
+}
+
+func closeEventStream(t *testing.T, resp *http.Response) {
+	require.NoError(t, resp.Body.Close())
+}
+
+// readEvent reads the next event from the stream, skipping comments.
+func readEvent(t *testing.T, events *bufio.Reader) testEvent {
+	event := testEvent{}
+
+	for {
+		line, err := events.ReadString('\n')
+		require.NoError(t, err)
+
+		line = strings.TrimSuffix(line, "\n")
+
+		switch {
+		case line == "" && event.id != "":
+			return event
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.change))
+		}
+	}
+}
+
+func serveEventsRequest(t *testing.T, op *Operation, lastEventID string, rw http.ResponseWriter) {
+	req := httptest.NewRequest(http.MethodGet, eventsEndpoint, nil)
+	req = mux.SetURLVars(req, map[string]string{vaultIDPathVariable: testVaultID})
+
+	if lastEventID != "" {
+		req.Header.Set(lastEventIDHeader, lastEventID)
+	}
+
+	getHandler(t, op, eventsEndpoint).Handle().ServeHTTP(rw, req)
+}
@@ -0,0 +1,322 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edv/pkg/audit"
+	"github.com/trustbloc/edv/pkg/edvprovider"
+	"github.com/trustbloc/edv/pkg/requestlog"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+	"github.com/trustbloc/edv/pkg/vaultexport"
+)
+
+const (
+	exportEndpoint = edvCommonEndpointPathRoot + "/{" + vaultIDPathVariable + "}/export"
+	importEndpoint = edvCommonEndpointPathRoot + "/{" + vaultIDPathVariable + "}/import"
+
+	// NDJSONContentType is the content type of vault exports.
+	NDJSONContentType = "application/x-ndjson"
+
+	// importBatchSize is the number of imported documents that are written to the vault at a time.
+	importBatchSize = 100
+
+	exportVaultAction     = "exportVault"
+	importVaultAction     = "importVault"
+	importDocumentsAction = "importDocuments"
+
+	// importDocumentOperation is the operation recorded in the audit log for each document of an import.
+	importDocumentOperation = "import"
+)
+
+// vaultExport is what's needed to export a vault: its store, its configuration and the IDs of its documents.
+type vaultExport struct {
+	store         edvprovider.EDVStore
+	configuration *models.DataVaultConfiguration
+	documentIDs   []string
+}
+
+func (c *Operation) exportVaultHandler(rw http.ResponseWriter, req *http.Request) {
+	vaultID, success := vaultIDFromPath(req, rw)
+	if !success {
+		return
+	}
+
+	export, err := c.vaultCollection.prepareExport(vaultID)
+	if err != nil {
+		logFailure(req, "export vault", vaultID, err)
+
+		if err == edverrors.ErrVaultNotFound {
+			rw.WriteHeader(http.StatusNotFound)
+		} else {
+			rw.WriteHeader(http.StatusInternalServerError)
+		}
+
+		_, err = rw.Write([]byte(fmt.Sprintf("Failed to export vault: %s", err)))
+		if err != nil {
+			requestlog.Logger(req).Errorf("Failed to write response for vault export failure: %s", err.Error())
+		}
+
+		return
+	}
+
+	rw.Header().Set("Content-Type", NDJSONContentType)
+	rw.WriteHeader(http.StatusOK)
+
+	err = writeExport(rw, export)
+	if err != nil {
+		// The export has already started by now, so it's cut short without its end record,
+		// which tells importers that it's incomplete.
+		requestlog.Logger(req).WithField("vaultID", vaultID).Errorf("Failed to export vault: %s", err.Error())
+	}
+}
+
+// prepareExport finds the documents in the given vault by reading its change feed from the beginning.
+func (vc *VaultCollection) prepareExport(vaultID string) (*vaultExport, error) {
+	store, err := vc.provider.OpenStore(vaultID)
+	if err != nil {
+		if err == storage.ErrStoreNotFound {
+			return nil, edverrors.ErrVaultNotFound
+		}
+
+		return nil, err
+	}
+
+	record, err := vc.getVaultRecord(vaultID)
+	if err != nil {
+		return nil, err
+	}
+
+	documentIDs, err := edvprovider.DocumentIDs(store)
+	if err != nil {
+		return nil, err
+	}
+
+	return &vaultExport{store: store, configuration: &record.Configuration, documentIDs: documentIDs}, nil
+}
+
+// writeExport writes the vault's configuration and documents to the given writer. Documents that were deleted
+// are left out.
+func writeExport(w io.Writer, export *vaultExport) error {
+	writer := vaultexport.NewWriter(w)
+
+	err := writer.WriteConfiguration(export.configuration)
+	if err != nil {
+		return err
+	}
+
+	for _, docID := range export.documentIDs {
+		var documentBytes []byte
+
+		documentBytes, err = export.store.Get(docID)
+		if err == storage.ErrValueNotFound {
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to read document %s: %w", docID, err)
+		}
+
+		document := models.EncryptedDocument{}
+
+		err = json.Unmarshal(documentBytes, &document)
+		if err != nil {
+			return fmt.Errorf("failed to parse document %s: %w", docID, err)
+		}
+
+		err = writer.WriteDocument(&document)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writer.Finish()
+}
+
+func (c *Operation) importVaultHandler(rw http.ResponseWriter, req *http.Request) {
+	vaultID, success := vaultIDFromPath(req, rw)
+	if !success {
+		return
+	}
+
+	body := http.MaxBytesReader(rw, req.Body, c.bodySizeLimits.Import)
+
+	// An export can hold any number of documents, so each batch of them gets its own audit record rather than
+	// adding them all to the import's record, which could then grow too large to be read back.
+	result, err := c.vaultCollection.importVault(vaultID, vaultexport.NewReader(body),
+		func(documents []audit.DocumentEntry) {
+			c.recordAudit(req, &audit.Entry{Action: importDocumentsAction, VaultID: vaultID,
+				Result: audit.ResultSuccess, StatusCode: http.StatusOK, Documents: documents})
+		})
+	if isRequestBodyTooLarge(err) {
+		err = edverrors.ErrRequestBodyTooLarge
+	}
+
+	if err != nil {
+		writeImportFailure(rw, req, vaultID, result, err)
+
+		return
+	}
+
+	for _, failure := range result.Failed {
+		if failure.Status == http.StatusInternalServerError {
+			requestlog.Logger(req).WithField("vaultID", vaultID).Errorf(
+				"Failed to import document %s: %s", failure.ID, failure.Error)
+		}
+	}
+
+	sendJSONResponse(rw, req, http.StatusOK, result)
+}
+
+// importVault creates the given vault from the configuration in the export, unless it already exists, and adds the
+// exported documents to it the same way as batches of document creations. Documents that are already in the vault
+// with the same contents are skipped, so an import that was interrupted can be resumed by importing the same export
+// again. The returned result covers the documents that were imported before any error.
+// The outcome for each document of a batch is also passed to recordBatch, so that it can be audited.
+func (vc *VaultCollection) importVault(vaultID string, reader *vaultexport.Reader,
+	recordBatch func([]audit.DocumentEntry)) (*models.ImportResult, error) {
+	result := &models.ImportResult{}
+
+	config, err := reader.ReadConfiguration()
+	if err != nil {
+		return result, err
+	}
+
+	config.ReferenceID = vaultID
+
+	err = vc.createDataVault(config)
+	if err != nil && err != edverrors.ErrDuplicateVault {
+		return result, err
+	}
+
+	result.VaultCreated = err == nil
+
+	for {
+		documents, readErr := readImportBatch(reader)
+
+		if len(documents) > 0 {
+			err = vc.importDocuments(vaultID, documents, result, recordBatch)
+			if err != nil {
+				return result, err
+			}
+		}
+
+		if readErr == io.EOF {
+			return result, nil
+		}
+
+		if readErr != nil {
+			return result, readErr
+		}
+	}
+}
+
+// readImportBatch reads the next documents to import. Along with the documents that were read, it returns io.EOF
+// once the whole export was read, or the error that stopped it from being read.
+func readImportBatch(reader *vaultexport.Reader) ([]models.EncryptedDocument, error) {
+	var documents []models.EncryptedDocument
+
+	for len(documents) < importBatchSize {
+		document, err := reader.ReadDocument()
+		if err != nil {
+			return documents, err
+		}
+
+		documents = append(documents, *document)
+	}
+
+	return documents, nil
+}
+
+func (vc *VaultCollection) importDocuments(vaultID string, documents []models.EncryptedDocument,
+	result *models.ImportResult, recordBatch func([]audit.DocumentEntry)) error {
+	operations := make([]models.BatchOperation, len(documents))
+
+	for i := range documents {
+		operations[i] = models.BatchOperation{Type: models.BatchOperationCreate, Document: &documents[i]}
+	}
+
+	batchResults, err := vc.applyBatch(vaultID, operations)
+	if err != nil {
+		return err
+	}
+
+	auditDocuments := make([]audit.DocumentEntry, len(batchResults))
+
+	for i, batchResult := range batchResults {
+		document := audit.DocumentEntry{ID: batchResult.ID, Operation: importDocumentOperation,
+			Result: audit.ResultFailure, StatusCode: batchResult.Status}
+
+		switch {
+		case batchResult.Status == http.StatusCreated:
+			result.Imported++
+			document.Result = audit.ResultSuccess
+		case batchResult.Status == http.StatusConflict && vc.documentAlreadyImported(vaultID, &documents[i]):
+			result.Skipped++
+			document.Result = audit.ResultSkipped
+		default:
+			result.Failed = append(result.Failed, batchResult)
+		}
+
+		auditDocuments[i] = document
+	}
+
+	recordBatch(auditDocuments)
+
+	return nil
+}
+
+// documentAlreadyImported returns whether the given document is already in the vault with the same contents.
+func (vc *VaultCollection) documentAlreadyImported(vaultID string, document *models.EncryptedDocument) bool {
+	documentBytes, err := vc.readDocument(vaultID, document.ID)
+	if err != nil {
+		return false
+	}
+
+	storedDocument := models.EncryptedDocument{}
+
+	err = json.Unmarshal(documentBytes, &storedDocument)
+	if err != nil {
+		return false
+	}
+
+	storedChecksum, err := vaultexport.DocumentChecksum(&storedDocument)
+	if err != nil {
+		return false
+	}
+
+	checksum, err := vaultexport.DocumentChecksum(document)
+
+	return err == nil && checksum == storedChecksum
+}
+
+func writeImportFailure(rw http.ResponseWriter, req *http.Request, vaultID string, result *models.ImportResult,
+	err error) {
+	logFailure(req, "import vault", vaultID, err)
+
+	switch {
+	case err == edverrors.ErrRequestBodyTooLarge:
+		rw.WriteHeader(http.StatusRequestEntityTooLarge)
+	case isClientError(err):
+		rw.WriteHeader(http.StatusBadRequest)
+	default:
+		rw.WriteHeader(http.StatusInternalServerError)
+	}
+
+	_, err = rw.Write([]byte(fmt.Sprintf("Failed to import vault after importing %d documents and skipping %d: %s",
+		result.Imported, result.Skipped, err)))
+	if err != nil {
+		requestlog.Logger(req).Errorf("Failed to write response for vault import failure: %s", err.Error())
+	}
+}
@@ -0,0 +1,186 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+	"github.com/trustbloc/edv/pkg/vaultexport"
+)
+
+func TestExportAndImport(t *testing.T) {
+	t.Run("Export a vault and import it into another server", func(t *testing.T) {
+		export := exportTestVault(t)
+
+		count, err := vaultexport.Verify(strings.NewReader(export))
+		require.NoError(t, err)
+		require.Equal(t, 2, count)
+
+		op := New(memedvprovider.NewProvider())
+
+		result := importTestVault(t, op, export)
+		require.Equal(t, &models.ImportResult{VaultCreated: true, Imported: 2}, result)
+
+		_, err = op.vaultCollection.readDocument(testVaultID, testDocID)
+		require.NoError(t, err)
+
+		_, err = op.vaultCollection.readDocument(testVaultID, testBatchDocID1)
+		require.NoError(t, err)
+
+		_, err = op.vaultCollection.readDocument(testVaultID, testBatchDocID2)
+		require.Equal(t, edverrors.ErrDocumentNotFound, err)
+
+		stats, err := op.vaultCollection.getVaultStats(testVaultID)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), stats.DocumentCount)
+		require.Equal(t, &models.VaultQuota{MaxDocuments: 10}, stats.Quota)
+	})
+	t.Run("Importing the same export again skips the documents that were already imported", func(t *testing.T) {
+		export := exportTestVault(t)
+
+		op := New(memedvprovider.NewProvider())
+
+		// Only the first document makes it in before the import is cut short.
+		lines := strings.SplitAfter(export, "\n")
+
+		rr := serveImportRequest(t, op, testVaultID, lines[0]+lines[1])
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "Failed to import vault after importing 1 documents and skipping 0")
+		require.Contains(t, rr.Body.String(), "the export ended before its end record")
+
+		result := importTestVault(t, op, export)
+		require.Equal(t, &models.ImportResult{Imported: 1, Skipped: 1}, result)
+	})
+	t.Run("Documents that are already in the vault with other contents fail", func(t *testing.T) {
+		export := exportTestVault(t)
+
+		op := New(memedvprovider.NewProvider())
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+
+		otherDocument := testBatchDocument(t, testDocID)
+		otherDocument.Sequence = 1
+
+		applyTestBatch(t, op, []models.BatchOperation{{Type: models.BatchOperationCreate, Document: otherDocument}})
+
+		result := importTestVault(t, op, export)
+		require.Equal(t, &models.ImportResult{Imported: 1, Failed: []models.BatchResult{
+			{ID: testDocID, Status: http.StatusConflict, Error: edverrors.ErrDuplicateDocument.Error()},
+		}}, result)
+	})
+	t.Run("Invalid exports", func(t *testing.T) {
+		export := exportTestVault(t)
+
+		for _, invalidExport := range []string{
+			"",
+			"{",
+			`{"type":"document"}`,
+			strings.Replace(export, `"ciphertext":"Cb-963`, `"ciphertext":"Db-963`, 1),
+			strings.Replace(export, `"documentCount":2`, `"documentCount":3`, 1),
+		} {
+			rr := serveImportRequest(t, New(memedvprovider.NewProvider()), testVaultID, invalidExport)
+			require.Equal(t, http.StatusBadRequest, rr.Code)
+			require.Contains(t, rr.Body.String(), vaultexport.ErrInvalidExport.Error())
+		}
+
+		rr := serveImportRequest(t, New(memedvprovider.NewProvider()), testVaultID,
+			`{"type":"vault","configuration":{"quota":{"maxDocuments":-1}}}`)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), edverrors.ErrInvalidVaultQuota.Error())
+
+		op := New(memedvprovider.NewProvider(), WithBodySizeLimits(BodySizeLimits{Import: 100}))
+
+		rr = serveImportRequest(t, op, testVaultID, export)
+		require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	})
+	t.Run("Import fails to write documents", func(t *testing.T) {
+		op := New(&mockEDVProvider{errStoreBulkWrite: errors.New("bulk write error"),
+			numTimesOpenStoreCalledBeforeErr: 3})
+
+		rr := serveImportRequest(t, op, testVaultID, exportTestVault(t))
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "bulk write error")
+	})
+	t.Run("Export vault not found", func(t *testing.T) {
+		rr := serveExportRequest(t, New(memedvprovider.NewProvider()), testVaultID)
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Equal(t, "Failed to export vault: "+edverrors.ErrVaultNotFound.Error(), rr.Body.String())
+	})
+	t.Run("Export provider errors", func(t *testing.T) {
+		rr := serveExportRequest(t, New(&mockEDVProvider{errOpenStore: errors.New("open store error")}),
+			testVaultID)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Equal(t, "Failed to export vault: open store error", rr.Body.String())
+
+		rr = serveExportRequest(t, New(&mockEDVProvider{errStoreChanges: errors.New("changes error"),
+			numTimesOpenStoreCalledBeforeErr: 1}), testVaultID)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Equal(t, "Failed to export vault: failed to read changes: changes error", rr.Body.String())
+	})
+}
+
+// exportTestVault exports a vault with a quota and two documents, along with one that was deleted.
+func exportTestVault(t *testing.T) string {
+	op := New(memedvprovider.NewProvider())
+
+	require.Equal(t, http.StatusCreated, createTestVault(t, op, `{"maxDocuments":10}`))
+	require.Equal(t, http.StatusCreated, createTestDocument(t, op, testDocID))
+
+	applyTestBatch(t, op, []models.BatchOperation{
+		{Type: models.BatchOperationCreate, Document: testBatchDocument(t, testBatchDocID1)},
+		{Type: models.BatchOperationCreate, Document: testBatchDocument(t, testBatchDocID2)},
+	})
+	applyTestBatch(t, op, []models.BatchOperation{{Type: models.BatchOperationDelete, ID: testBatchDocID2}})
+
+	rr := serveExportRequest(t, op, testVaultID)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, NDJSONContentType, rr.Header().Get("Content-Type"))
+
+	return rr.Body.String()
+}
+
+func importTestVault(t *testing.T, op *Operation, export string) *models.ImportResult {
+	rr := serveImportRequest(t, op, testVaultID, export)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	result := models.ImportResult{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+
+	return &result
+}
+
+func serveExportRequest(t *testing.T, op *Operation, vaultID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, exportEndpoint, nil)
+	req = mux.SetURLVars(req, map[string]string{vaultIDPathVariable: vaultID})
+
+	rr := httptest.NewRecorder()
+	getHandler(t, op, exportEndpoint).Handle().ServeHTTP(rr, req)
+
+	return rr
+}
+
+func serveImportRequest(t *testing.T, op *Operation, vaultID, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, importEndpoint, bytes.NewBufferString(body))
+	req = mux.SetURLVars(req, map[string]string{vaultIDPathVariable: vaultID})
+
+	rr := httptest.NewRecorder()
+	getHandler(t, op, importEndpoint).Handle().ServeHTTP(rr, req)
+
+	return rr
+}
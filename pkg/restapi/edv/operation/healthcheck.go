@@ -0,0 +1,115 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edv/pkg/requestlog"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+	"github.com/trustbloc/edv/pkg/version"
+)
+
+const (
+	healthCheckEndpoint = "/healthcheck"
+	readinessEndpoint   = "/readiness"
+
+	healthCheckStatusSuccess = "success"
+	readinessStatusReady     = "ready"
+	readinessStatusNotReady  = "not ready"
+	dependencyStatusUp       = "up"
+	dependencyStatusDown     = "down"
+
+	edvProviderDependencyName     = "edvProvider"
+	storageProviderDependencyName = "storageProvider"
+
+	// readinessProbeKey is looked up in the vault configuration store to check that the storage provider is reachable.
+	// Whether it's found doesn't matter.
+	readinessProbeKey = "_readiness"
+)
+
+// healthCheckHandler reports whether the EDV server process is alive. It doesn't check any dependencies.
+func (c *Operation) healthCheckHandler(rw http.ResponseWriter, req *http.Request) {
+	sendJSONResponse(rw, req, http.StatusOK, &models.HealthCheckResponse{
+		Status:      healthCheckStatusSuccess,
+		CurrentTime: time.Now(),
+		Version:     version.Version,
+	})
+}
+
+// readinessHandler reports whether the EDV server is able to serve requests by checking its dependencies.
+func (c *Operation) readinessHandler(rw http.ResponseWriter, req *http.Request) {
+	response := &models.ReadinessResponse{
+		Status:       readinessStatusReady,
+		Version:      version.Version,
+		Dependencies: make(map[string]models.DependencyStatus),
+	}
+
+	statusCode := http.StatusOK
+
+	for name, ping := range map[string]func() error{
+		edvProviderDependencyName:     c.vaultCollection.provider.Ping,
+		storageProviderDependencyName: c.vaultCollection.pingStorageProvider,
+	} {
+		dependencyStatus := models.DependencyStatus{Status: dependencyStatusUp}
+
+		if err := ping(); err != nil {
+			requestlog.Logger(req).Warnf("%s readiness check failed: %s", name, err.Error())
+
+			dependencyStatus = models.DependencyStatus{Status: dependencyStatusDown, Error: err.Error()}
+			response.Status = readinessStatusNotReady
+			statusCode = http.StatusServiceUnavailable
+		}
+
+		response.Dependencies[name] = dependencyStatus
+	}
+
+	sendJSONResponse(rw, req, statusCode, response)
+}
+
+// pingStorageProvider checks that the storage provider used for vault configurations can be reached.
+// The storage provider has no ping of its own, so a key is looked up in the vault configuration store instead.
+func (vc *VaultCollection) pingStorageProvider() error {
+	store, err := vc.configurationStore()
+	if err != nil {
+		return err
+	}
+
+	_, err = store.Get(readinessProbeKey)
+	if err != nil && !errors.Is(err, storage.ErrValueNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+func sendJSONResponse(rw http.ResponseWriter, req *http.Request, statusCode int, response interface{}) {
+	responseBytes, err := json.Marshal(response)
+	if err != nil {
+		rw.WriteHeader(http.StatusInternalServerError)
+
+		_, err = rw.Write([]byte(err.Error()))
+		if err != nil {
+			requestlog.Logger(req).Errorf("Failed to write response for JSON marshalling failure: %s", err.Error())
+		}
+
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(statusCode)
+
+	_, err = rw.Write(responseBytes)
+	if err != nil {
+		requestlog.Logger(req).Errorf("Failed to write JSON response: %s", err.Error())
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+	"github.com/trustbloc/edv/pkg/version"
+)
+
+func TestHealthCheckHandler(t *testing.T) {
+	op := New(memedvprovider.NewProvider())
+
+	rr := httptest.NewRecorder()
+
+	getHandler(t, op, healthCheckEndpoint).Handle().ServeHTTP(rr,
+		httptest.NewRequest(http.MethodGet, healthCheckEndpoint, nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	response := models.HealthCheckResponse{}
+
+	err := json.Unmarshal(rr.Body.Bytes(), &response)
+	require.NoError(t, err)
+	require.Equal(t, healthCheckStatusSuccess, response.Status)
+	require.Equal(t, version.Version, response.Version)
+	require.False(t, response.CurrentTime.IsZero())
+}
+
+func TestReadinessHandler(t *testing.T) {
+	t.Run("Ready", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		rr := httptest.NewRecorder()
+
+		getHandler(t, op, readinessEndpoint).Handle().ServeHTTP(rr,
+			httptest.NewRequest(http.MethodGet, readinessEndpoint, nil))
+
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		response := models.ReadinessResponse{}
+
+		err := json.Unmarshal(rr.Body.Bytes(), &response)
+		require.NoError(t, err)
+		require.Equal(t, readinessStatusReady, response.Status)
+		require.Equal(t, version.Version, response.Version)
+		require.Equal(t, models.DependencyStatus{Status: dependencyStatusUp},
+			response.Dependencies[edvProviderDependencyName])
+		require.Equal(t, models.DependencyStatus{Status: dependencyStatusUp},
+			response.Dependencies[storageProviderDependencyName])
+	})
+	t.Run("Not ready - EDV provider unreachable", func(t *testing.T) {
+		op := New(&mockEDVProvider{errPing: errors.New("connection refused")})
+
+		rr := httptest.NewRecorder()
+
+		getHandler(t, op, readinessEndpoint).Handle().ServeHTTP(rr,
+			httptest.NewRequest(http.MethodGet, readinessEndpoint, nil))
+
+		require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+		response := models.ReadinessResponse{}
+
+		err := json.Unmarshal(rr.Body.Bytes(), &response)
+		require.NoError(t, err)
+		require.Equal(t, readinessStatusNotReady, response.Status)
+		require.Equal(t, models.DependencyStatus{Status: dependencyStatusDown, Error: "connection refused"},
+			response.Dependencies[edvProviderDependencyName])
+		require.Equal(t, models.DependencyStatus{Status: dependencyStatusUp},
+			response.Dependencies[storageProviderDependencyName])
+	})
+	t.Run("Not ready - storage provider unreachable", func(t *testing.T) {
+		storageProvider := mockstore.NewMockStoreProvider()
+		storageProvider.Store.Store[readinessProbeKey] = []byte("{}")
+		storageProvider.Store.ErrGet = errors.New("connection refused")
+
+		op := New(memedvprovider.NewProvider(), WithStorageProvider(storageProvider))
+
+		rr := httptest.NewRecorder()
+
+		getHandler(t, op, readinessEndpoint).Handle().ServeHTTP(rr,
+			httptest.NewRequest(http.MethodGet, readinessEndpoint, nil))
+
+		require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+		response := models.ReadinessResponse{}
+
+		err := json.Unmarshal(rr.Body.Bytes(), &response)
+		require.NoError(t, err)
+		require.Equal(t, readinessStatusNotReady, response.Status)
+		require.Equal(t, models.DependencyStatus{Status: dependencyStatusUp},
+			response.Dependencies[edvProviderDependencyName])
+		require.Equal(t, models.DependencyStatus{Status: dependencyStatusDown, Error: "connection refused"},
+			response.Dependencies[storageProviderDependencyName])
+	})
+	t.Run("Not ready - storage provider can't create the vault configuration store", func(t *testing.T) {
+		storageProvider := mockstore.NewMockStoreProvider()
+		storageProvider.ErrCreateStore = errors.New("connection refused")
+
+		op := New(memedvprovider.NewProvider(), WithStorageProvider(storageProvider))
+
+		rr := httptest.NewRecorder()
+
+		getHandler(t, op, readinessEndpoint).Handle().ServeHTTP(rr,
+			httptest.NewRequest(http.MethodGet, readinessEndpoint, nil))
+
+		require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+		response := models.ReadinessResponse{}
+
+		err := json.Unmarshal(rr.Body.Bytes(), &response)
+		require.NoError(t, err)
+		require.Equal(t, models.DependencyStatus{Status: dependencyStatusDown,
+			Error: "failed to create vault configuration store: connection refused"},
+			response.Dependencies[storageProviderDependencyName])
+	})
+}
+
+func TestSendJSONResponse_MarshalFailure(t *testing.T) {
+	rr := httptest.NewRecorder()
+
+	sendJSONResponse(rr, nil, http.StatusOK, make(chan int))
+
+	require.Equal(t, http.StatusInternalServerError, rr.Code)
+	require.Contains(t, rr.Body.String(), "unsupported type")
+}
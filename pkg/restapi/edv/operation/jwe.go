@@ -0,0 +1,20 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"github.com/trustbloc/edv/pkg/jwe"
+)
+
+// WithJWEPolicy sets the policy that the JWEs of incoming documents must satisfy.
+// The JWE must always be well-formed. By default, its alg and enc values must be in jwe.DefaultAllowedAlgorithms
+// and jwe.DefaultAllowedEncryptions, which are also used for whichever list the given policy leaves empty.
+func WithJWEPolicy(policy jwe.Policy) Option {
+	return func(opts *Operation) {
+		opts.vaultCollection.jwePolicy = policy
+	}
+}
@@ -0,0 +1,47 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/jwe"
+)
+
+func TestCreateDocumentHandler_InvalidJWE(t *testing.T) {
+	t.Run("Malformed JWE", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		createDataVaultExpectSuccess(t, op)
+
+		rr := serveTestRequest(t, op, createDocumentEndpoint,
+			`{"id":"`+testDocID+`","jwe":{"protected":"eyJlbmMiOiJDMjBQIn0","ciphertext":"not base64!"}}`)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Equal(t, "invalid jwe: ciphertext isn't valid base64url", rr.Body.String())
+	})
+	t.Run("Algorithm not allowed by policy", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider(), WithJWEPolicy(jwe.Policy{AllowedAlgorithms: []string{"ECDH-ES+A256KW"}}))
+
+		createDataVaultExpectSuccess(t, op)
+
+		rr := serveTestRequest(t, op, createDocumentEndpoint, testEncryptedDocument)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.True(t, strings.HasPrefix(rr.Body.String(), "invalid jwe: "))
+
+		op = New(memedvprovider.NewProvider(), WithJWEPolicy(jwe.Policy{AllowedAlgorithms: []string{"A256KW"}}))
+
+		createDataVaultExpectSuccess(t, op)
+
+		rr = serveTestRequest(t, op, createDocumentEndpoint, testEncryptedDocument)
+		require.Equal(t, http.StatusCreated, rr.Code)
+	})
+}
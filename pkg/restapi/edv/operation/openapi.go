@@ -0,0 +1,26 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"net/http"
+
+	"github.com/trustbloc/edv/pkg/requestlog"
+	"github.com/trustbloc/edv/pkg/restapi/edv/openapi"
+)
+
+const openAPIEndpoint = "/openapi.json"
+
+// openAPIHandler serves the OpenAPI document describing the EDV REST API.
+func (c *Operation) openAPIHandler(rw http.ResponseWriter, req *http.Request) {
+	rw.Header().Set("Content-Type", "application/json")
+
+	_, err := rw.Write([]byte(openapi.Spec))
+	if err != nil {
+		requestlog.Logger(req).Errorf("Failed to write OpenAPI document response: %s", err.Error())
+	}
+}
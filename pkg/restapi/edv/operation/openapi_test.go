@@ -0,0 +1,35 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+)
+
+func TestOpenAPIHandler(t *testing.T) {
+	op := New(memedvprovider.NewProvider())
+
+	rr := httptest.NewRecorder()
+
+	getHandler(t, op, openAPIEndpoint).Handle().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, openAPIEndpoint, nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var spec map[string]interface{}
+
+	err := json.Unmarshal(rr.Body.Bytes(), &spec)
+	require.NoError(t, err)
+	require.Equal(t, "3.0.3", spec["openapi"])
+}
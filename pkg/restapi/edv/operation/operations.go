@@ -8,19 +8,27 @@ package operation
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"sync"
+	"time"
 
-	"github.com/btcsuite/btcutil/base58"
 	"github.com/gorilla/mux"
-	log "github.com/sirupsen/logrus"
 	"github.com/trustbloc/edge-core/pkg/storage"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
 
 	"github.com/trustbloc/edv/pkg/edvprovider"
 	"github.com/trustbloc/edv/pkg/internal/common/support"
+	"github.com/trustbloc/edv/pkg/jwe"
+	"github.com/trustbloc/edv/pkg/ratelimit"
+	"github.com/trustbloc/edv/pkg/replication"
+	"github.com/trustbloc/edv/pkg/requestlog"
 	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
 	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+	"github.com/trustbloc/edv/pkg/vaultexport"
+	"github.com/trustbloc/edv/pkg/webhook"
 )
 
 const (
@@ -42,13 +50,40 @@ type Handler interface {
 	Handle() http.HandlerFunc
 }
 
+// Option configures the EDV operations.
+type Option func(opts *Operation)
+
+// WithStorageProvider sets the storage provider used to keep vault configurations and usage.
+// If not set, then they're kept in memory.
+func WithStorageProvider(storageProvider storage.Provider) Option {
+	return func(opts *Operation) {
+		opts.vaultCollection.storageProvider = storageProvider
+	}
+}
+
 // New returns a new EDV operations instance.
 // If dbPrefix is blank, then no prefixing will be done to the vault IDs.
-func New(provider edvprovider.EDVProvider) *Operation {
+func New(provider edvprovider.EDVProvider, opts ...Option) *Operation {
 	svc := &Operation{
 		vaultCollection: VaultCollection{
-			provider: provider,
-		}}
+			provider:        provider,
+			storageProvider: memstore.NewProvider(),
+		},
+		bodySizeLimits: BodySizeLimits{
+			VaultConfiguration: DefaultMaxVaultConfigurationSize,
+			Query:              DefaultMaxQuerySize,
+			Document:           DefaultMaxDocumentSize,
+			Batch:              DefaultMaxBatchSize,
+			Import:             DefaultMaxImportSize,
+		},
+		eventPollInterval:      DefaultEventPollInterval,
+		eventHeartbeatInterval: eventStreamHeartbeatInterval,
+	}
+
+	for _, opt := range opts {
+		opt(svc)
+	}
+
 	svc.registerHandler()
 
 	return svc
@@ -56,43 +91,64 @@ func New(provider edvprovider.EDVProvider) *Operation {
 
 // Operation defines handlers for EDV service
 type Operation struct {
-	handlers        []Handler
-	vaultCollection VaultCollection
+	handlers               []Handler
+	vaultCollection        VaultCollection
+	auditRecorder          AuditRecorder
+	writeRateLimiter       *ratelimit.Limiter
+	queryRateLimiter       *ratelimit.Limiter
+	bodySizeLimits         BodySizeLimits
+	strictJSON             bool
+	eventPollInterval      time.Duration
+	eventHeartbeatInterval time.Duration
 }
 
 // VaultCollection represents EDV storage.
 type VaultCollection struct {
-	provider edvprovider.EDVProvider
+	provider        edvprovider.EDVProvider
+	storageProvider storage.Provider
+	configStore     storage.Store
+	configStoreMux  sync.Mutex
+	vaultLocks      sync.Map
+	jwePolicy       jwe.Policy
+	changeNotifier  changeNotifier
+	webhooks        *webhook.Dispatcher
+	replicator      *replication.Replicator
 }
 
 func (c *Operation) createDataVaultHandler(rw http.ResponseWriter, req *http.Request) {
 	config := models.DataVaultConfiguration{}
 
-	err := json.NewDecoder(req.Body).Decode(&config)
+	err := c.decodeRequestBody(rw, req, c.bodySizeLimits.VaultConfiguration, &config)
 
 	blankReferenceIDProvided := err == nil && config.ReferenceID == ""
 
 	if err != nil || blankReferenceIDProvided {
-		rw.WriteHeader(http.StatusBadRequest)
-
 		var errMsg string
 		if blankReferenceIDProvided {
+			rw.WriteHeader(http.StatusBadRequest)
+
 			errMsg = "referenceId can't be blank"
 		} else {
+			rw.WriteHeader(decodeFailureStatusCode(err))
+
 			errMsg = err.Error()
 		}
 
 		_, err = rw.Write([]byte(errMsg))
 		if err != nil {
-			log.Errorf("Failed to write response for data vault creation failure due to the provided"+
+			requestlog.Logger(req).Errorf("Failed to write response for data vault creation failure due to the provided"+
 				" data vault configuration: %s", err.Error())
 		}
 
 		return
 	}
 
-	err = c.vaultCollection.createDataVault(config.ReferenceID)
+	setAuditVaultID(req, config.ReferenceID)
+
+	err = c.vaultCollection.createDataVault(&config)
 	if err != nil {
+		logFailure(req, "create data vault", config.ReferenceID, err)
+
 		if err == edverrors.ErrDuplicateVault {
 			rw.WriteHeader(http.StatusConflict)
 		} else {
@@ -101,7 +157,7 @@ func (c *Operation) createDataVaultHandler(rw http.ResponseWriter, req *http.Req
 
 		_, err = rw.Write([]byte(fmt.Sprintf("Data vault creation failed: %s", err)))
 		if err != nil {
-			log.Errorf("Failed to write response for data vault creation failure: %s", err.Error())
+			requestlog.Logger(req).Errorf("Failed to write response for data vault creation failure: %s", err.Error())
 		}
 
 		return
@@ -116,30 +172,32 @@ func (c *Operation) createDataVaultHandler(rw http.ResponseWriter, req *http.Req
 func (c *Operation) queryVaultHandler(rw http.ResponseWriter, req *http.Request) {
 	incomingQuery := models.Query{}
 
-	err := json.NewDecoder(req.Body).Decode(&incomingQuery)
+	err := c.decodeRequestBody(rw, req, c.bodySizeLimits.Query, &incomingQuery)
 	if err != nil {
-		rw.WriteHeader(http.StatusBadRequest)
+		rw.WriteHeader(decodeFailureStatusCode(err))
 
 		_, err = rw.Write([]byte(err.Error()))
 		if err != nil {
-			log.Errorf(edverrors.QueryVaultFailureToWriteFailureResponseErrMsg, err.Error())
+			requestlog.Logger(req).Errorf(edverrors.QueryVaultFailureToWriteFailureResponseErrMsg, err.Error())
 		}
 
 		return
 	}
 
-	vaultID, success := unescapePathVar(vaultIDPathVariable, mux.Vars(req), rw)
+	vaultID, success := vaultIDFromPath(req, rw)
 	if !success {
 		return
 	}
 
 	matchingDocumentIDs, err := c.vaultCollection.queryVault(vaultID, &incomingQuery)
 	if err != nil {
+		logFailure(req, "query vault", vaultID, err)
+
 		rw.WriteHeader(http.StatusBadRequest)
 
 		_, err = rw.Write([]byte(err.Error()))
 		if err != nil {
-			log.Errorf(edverrors.QueryVaultFailureToWriteFailureResponseErrMsg, err.Error())
+			requestlog.Logger(req).Errorf(edverrors.QueryVaultFailureToWriteFailureResponseErrMsg, err.Error())
 		}
 
 		return
@@ -147,40 +205,40 @@ func (c *Operation) queryVaultHandler(rw http.ResponseWriter, req *http.Request)
 
 	fullDocumentURLs := convertToFullDocumentURLs(matchingDocumentIDs, vaultID, req)
 
-	sendQueryResponse(rw, fullDocumentURLs)
+	sendQueryResponse(rw, req, fullDocumentURLs)
 }
 
 func (c *Operation) createDocumentHandler(rw http.ResponseWriter, req *http.Request) {
 	incomingDocument := models.EncryptedDocument{}
 
-	err := json.NewDecoder(req.Body).Decode(&incomingDocument)
+	err := c.decodeRequestBody(rw, req, c.bodySizeLimits.Document, &incomingDocument)
 	if err != nil {
-		rw.WriteHeader(http.StatusBadRequest)
+		rw.WriteHeader(decodeFailureStatusCode(err))
 
 		_, err = rw.Write([]byte(err.Error()))
 		if err != nil {
-			log.Errorf("Failed to write response for document creation failure: %s", err.Error())
+			requestlog.Logger(req).Errorf("Failed to write response for document creation failure: %s", err.Error())
 		}
 
 		return
 	}
 
-	vaultID, success := unescapePathVar(vaultIDPathVariable, mux.Vars(req), rw)
+	vaultID, success := vaultIDFromPath(req, rw)
 	if !success {
 		return
 	}
 
+	setAuditDocumentID(req, incomingDocument.ID)
+
 	err = c.vaultCollection.createDocument(vaultID, incomingDocument)
 	if err != nil {
-		if err == edverrors.ErrDuplicateDocument {
-			rw.WriteHeader(http.StatusConflict)
-		} else {
-			rw.WriteHeader(http.StatusBadRequest)
-		}
+		logFailure(req, "create document", vaultID, err)
+
+		rw.WriteHeader(createDocumentFailureStatusCode(err))
 
 		_, err = rw.Write([]byte(err.Error()))
 		if err != nil {
-			log.Errorf(
+			requestlog.Logger(req).Errorf(
 				"Failed to write response for document creation failure: %s", err.Error())
 		}
 
@@ -193,18 +251,20 @@ func (c *Operation) createDocumentHandler(rw http.ResponseWriter, req *http.Requ
 }
 
 func (c *Operation) readDocumentHandler(rw http.ResponseWriter, req *http.Request) {
-	vaultID, success := unescapePathVar(vaultIDPathVariable, mux.Vars(req), rw)
+	vaultID, success := vaultIDFromPath(req, rw)
 	if !success {
 		return
 	}
 
-	docID, success := unescapePathVar(docIDPathVariable, mux.Vars(req), rw)
+	docID, success := unescapePathVar(docIDPathVariable, req, rw)
 	if !success {
 		return
 	}
 
 	documentBytes, err := c.vaultCollection.readDocument(vaultID, docID)
 	if err != nil {
+		logFailure(req, "read document", vaultID, err)
+
 		if err == edverrors.ErrDocumentNotFound || err == edverrors.ErrVaultNotFound {
 			rw.WriteHeader(http.StatusNotFound)
 		} else {
@@ -213,7 +273,7 @@ func (c *Operation) readDocumentHandler(rw http.ResponseWriter, req *http.Reques
 
 		_, err = rw.Write([]byte(err.Error()))
 		if err != nil {
-			log.Errorf("Failed to write response for document retrieval failure: %s", err.Error())
+			requestlog.Logger(req).Errorf("Failed to write response for document retrieval failure: %s", err.Error())
 		}
 
 		return
@@ -221,25 +281,41 @@ func (c *Operation) readDocumentHandler(rw http.ResponseWriter, req *http.Reques
 
 	_, err = rw.Write(documentBytes)
 	if err != nil {
-		log.Errorf("Failed to write response for document retrieval success: %s", err.Error())
+		requestlog.Logger(req).Errorf("Failed to write response for document retrieval success: %s", err.Error())
 	}
 }
 
-func (vc *VaultCollection) createDataVault(vaultID string) error {
+func (vc *VaultCollection) createDataVault(config *models.DataVaultConfiguration) error {
+	// With CouchDB, the EDV server's own stores sit next to the vaults, so a vault can't take one of their names.
+	if IsInternalStoreName(config.ReferenceID) {
+		return edverrors.ErrReservedVaultID
+	}
+
+	if err := checkVaultQuota(config.Quota); err != nil {
+		return err
+	}
+
+	if err := checkVaultPolicy(config); err != nil {
+		return err
+	}
+
+	vaultID := config.ReferenceID
+
+	// Creating the store is what claims the vault ID, since only one of several concurrent creates can succeed.
 	err := vc.provider.CreateStore(vaultID)
 	if err == storage.ErrDuplicateStore {
 		return edverrors.ErrDuplicateVault
 	}
 
-	store, err := vc.provider.OpenStore(vaultID)
 	if err != nil {
 		return err
 	}
 
-	err = store.CreateEDVIndex()
+	err = vc.setUpDataVault(config)
 	if err != nil {
-		if err == edvprovider.ErrIndexingNotSupported { // Allow the EDV to still operate without index support
-			return nil
+		// The store is removed so that the vault can be created again rather than being left half set up.
+		if deleteErr := vc.provider.DeleteStore(vaultID); deleteErr != nil {
+			return fmt.Errorf("%w (failed to remove the vault's store as well: %s)", err, deleteErr.Error())
 		}
 
 		return err
@@ -248,6 +324,21 @@ func (vc *VaultCollection) createDataVault(vaultID string) error {
 	return nil
 }
 
+// setUpDataVault indexes and configures a vault whose store has just been created.
+func (vc *VaultCollection) setUpDataVault(config *models.DataVaultConfiguration) error {
+	store, err := vc.provider.OpenStore(config.ReferenceID)
+	if err != nil {
+		return err
+	}
+
+	err = store.CreateEDVIndex()
+	if err != nil && err != edvprovider.ErrIndexingNotSupported { // Allow the EDV to still operate without index support
+		return err
+	}
+
+	return vc.storeVaultRecord(config.ReferenceID, &vaultRecord{Configuration: *config})
+}
+
 func (vc *VaultCollection) createDocument(vaultID string, document models.EncryptedDocument) error {
 	store, err := vc.provider.OpenStore(vaultID)
 	if err != nil {
@@ -258,23 +349,40 @@ func (vc *VaultCollection) createDocument(vaultID string, document models.Encryp
 		return err
 	}
 
-	if encodingErr := checkIfBase58Encoded128BitValue(document.ID); encodingErr != nil {
-		return encodingErr
+	// Documents are created one at a time per vault so that usage can be tracked and quotas enforced accurately.
+	unlock := vc.lockVault(vaultID)
+	defer unlock()
+
+	record, err := vc.getVaultRecord(vaultID)
+	if err != nil {
+		return err
 	}
 
-	// The Create Document API call should not overwrite an existing document.
-	// So we first check to make sure there is not already a document associated with the id.
-	// If there is, we send back an error.
-	_, err = store.Get(document.ID)
-	if err == nil {
-		return edverrors.ErrDuplicateDocument
+	documentSize, err := vc.checkDocumentAllowedInVault(record, document)
+	if err != nil {
+		return err
 	}
 
-	if err != storage.ErrValueNotFound {
+	// The Create Document API call should not overwrite an existing document,
+	// so the store fails with ErrDuplicateDocument if there's already a document with the same ID.
+	err = store.Create(document)
+	if err != nil {
 		return err
 	}
 
-	return store.Put(document)
+	vc.recordDocumentCreated(vaultID, record, documentSize)
+	vc.notifyChange(vaultID)
+
+	return nil
+}
+
+// notifyChange wakes up the event streams and the webhook dispatcher waiting for the given vault to change.
+func (vc *VaultCollection) notifyChange(vaultID string) {
+	vc.changeNotifier.notify(vaultID)
+
+	if vc.webhooks != nil {
+		vc.webhooks.Notify(vaultID)
+	}
 }
 
 func (vc *VaultCollection) readDocument(vaultID, docID string) ([]byte, error) {
@@ -312,28 +420,29 @@ func (vc *VaultCollection) queryVault(vaultID string, query *models.Query) ([]st
 	return store.Query(query)
 }
 
-// This function can't tell if the value before being encoded was precisely 128 bits long.
-// This is because the byte58.decode function returns an array of bytes, not just a string of bits.
-// So the closest I can do is see if the decoded byte array is 16 bytes long,
-// however this means that if the original value was 121 bits to 127 bits long it'll still be accepted.
-func checkIfBase58Encoded128BitValue(id string) error {
-	decodedBytes := base58.Decode(id)
-	if len(decodedBytes) == 0 {
-		return edverrors.ErrNotBase58Encoded
+// validateEncryptedDocument checks that the required fields of the given document are present and valid,
+// and that its JWE is well-formed and satisfies the JWE policy. The document ID must have the given format.
+func (vc *VaultCollection) validateEncryptedDocument(document *models.EncryptedDocument, idFormat string) error {
+	if document.ID == "" {
+		return edverrors.ErrMissingDocumentID
 	}
 
-	if len(decodedBytes) != 16 {
-		return edverrors.ErrNot128BitValue
+	if err := checkDocumentID(document.ID, idFormat); err != nil {
+		return err
 	}
 
-	return nil
+	if len(document.JWE) == 0 || string(document.JWE) == "null" {
+		return edverrors.ErrMissingJWE
+	}
+
+	return jwe.Validate(document.JWE, vc.jwePolicy)
 }
 
-func sendQueryResponse(rw http.ResponseWriter, matchingDocumentIDs []string) {
+func sendQueryResponse(rw http.ResponseWriter, req *http.Request, matchingDocumentIDs []string) {
 	if matchingDocumentIDs == nil {
 		_, err := rw.Write([]byte("no matching documents found"))
 		if err != nil {
-			log.Errorf(edverrors.QueryVaultFailureToWriteSuccessResponseErrMsg, err.Error())
+			requestlog.Logger(req).Errorf(edverrors.QueryVaultFailureToWriteSuccessResponseErrMsg, err.Error())
 		}
 
 		return
@@ -345,7 +454,7 @@ func sendQueryResponse(rw http.ResponseWriter, matchingDocumentIDs []string) {
 
 		_, err = rw.Write([]byte(err.Error()))
 		if err != nil {
-			log.Errorf(edverrors.QueryVaultFailureToWriteFailureResponseErrMsg, err.Error())
+			requestlog.Logger(req).Errorf(edverrors.QueryVaultFailureToWriteFailureResponseErrMsg, err.Error())
 		}
 
 		return
@@ -353,7 +462,7 @@ func sendQueryResponse(rw http.ResponseWriter, matchingDocumentIDs []string) {
 
 	_, err = rw.Write(matchingDocumentIDsBytes)
 	if err != nil {
-		log.Errorf(edverrors.QueryVaultFailureToWriteSuccessResponseErrMsg, err.Error())
+		requestlog.Logger(req).Errorf(edverrors.QueryVaultFailureToWriteSuccessResponseErrMsg, err.Error())
 	}
 }
 
@@ -361,10 +470,50 @@ func sendQueryResponse(rw http.ResponseWriter, matchingDocumentIDs []string) {
 func (c *Operation) registerHandler() {
 	// Add more protocol endpoints here to expose them as controller API endpoints
 	c.handlers = []Handler{
-		support.NewHTTPHandler(createVaultEndpoint, http.MethodPost, c.createDataVaultHandler),
-		support.NewHTTPHandler(queryVaultEndpoint, http.MethodPost, c.queryVaultHandler),
-		support.NewHTTPHandler(createDocumentEndpoint, http.MethodPost, c.createDocumentHandler),
-		support.NewHTTPHandler(readDocumentEndpoint, http.MethodGet, c.readDocumentHandler),
+		support.NewHTTPHandler(createVaultEndpoint, http.MethodPost,
+			c.audited(createVaultAction, rateLimited(c.writeRateLimiter, c.createDataVaultHandler))),
+		support.NewHTTPHandler(listVaultsEndpoint, http.MethodGet,
+			c.audited(listVaultsAction, rateLimited(c.queryRateLimiter, c.listVaultsHandler))),
+		support.NewHTTPHandler(queryVaultEndpoint, http.MethodPost,
+			c.audited(queryVaultAction, rateLimited(c.queryRateLimiter, c.queryVaultHandler))),
+		support.NewHTTPHandler(createDocumentEndpoint, http.MethodPost,
+			c.audited(createDocumentAction, rateLimited(c.writeRateLimiter, c.createDocumentHandler))),
+		support.NewHTTPHandler(readDocumentEndpoint, http.MethodGet,
+			c.audited(readDocumentAction, c.readDocumentHandler)),
+		support.NewHTTPHandler(listDocumentsEndpoint, http.MethodGet,
+			c.audited(listDocumentsAction, rateLimited(c.queryRateLimiter, c.listDocumentsHandler))),
+		support.NewHTTPHandler(batchEndpoint, http.MethodPost,
+			c.audited(batchDocumentsAction, rateLimited(c.writeRateLimiter, c.batchHandler))),
+		support.NewHTTPHandler(exportEndpoint, http.MethodGet,
+			c.audited(exportVaultAction, c.exportVaultHandler)),
+		support.NewHTTPHandler(importEndpoint, http.MethodPost,
+			c.audited(importVaultAction, rateLimited(c.writeRateLimiter, c.importVaultHandler))),
+		support.NewHTTPHandler(reindexEndpoint, http.MethodPost,
+			c.audited(reindexVaultAction, rateLimited(c.writeRateLimiter, c.reindexVaultHandler))),
+		support.NewHTTPHandler(vaultStatsEndpoint, http.MethodGet,
+			c.audited(readVaultStatsAction, c.vaultStatsHandler)),
+		support.NewHTTPHandler(changesEndpoint, http.MethodGet,
+			c.audited(readChangesAction, c.changesHandler)),
+		support.NewHTTPHandler(eventsEndpoint, http.MethodGet,
+			c.audited(streamEventsAction, c.eventsHandler)),
+		support.NewHTTPHandler(webhooksEndpoint, http.MethodPost,
+			c.audited(createWebhookAction, rateLimited(c.writeRateLimiter, c.createWebhookHandler))),
+		support.NewHTTPHandler(webhooksEndpoint, http.MethodGet,
+			c.audited(readWebhooksAction, c.readWebhooksHandler)),
+		support.NewHTTPHandler(webhookEndpoint, http.MethodGet,
+			c.audited(readWebhookAction, c.readWebhookHandler)),
+		support.NewHTTPHandler(webhookEndpoint, http.MethodDelete,
+			c.audited(deleteWebhookAction, c.deleteWebhookHandler)),
+		support.NewHTTPHandler(webhookDeliveriesEndpoint, http.MethodGet,
+			c.audited(readWebhookDeliveriesAction, c.readWebhookDeliveriesHandler)),
+		support.NewHTTPHandler(replicationEndpoint, http.MethodGet,
+			c.audited(readReplicationStatusAction, c.readReplicationStatusHandler)),
+		support.NewHTTPHandler(replicationConflictEndpoint, http.MethodPost,
+			c.audited(resolveReplicationConflictAction,
+				rateLimited(c.writeRateLimiter, c.resolveReplicationConflictHandler))),
+		support.NewHTTPHandler(healthCheckEndpoint, http.MethodGet, c.healthCheckHandler),
+		support.NewHTTPHandler(readinessEndpoint, http.MethodGet, c.readinessHandler),
+		support.NewHTTPHandler(openAPIEndpoint, http.MethodGet, c.openAPIHandler),
 	}
 }
 
@@ -373,16 +522,16 @@ func (c *Operation) GetRESTHandlers() []Handler {
 	return c.handlers
 }
 
-// Unescapes the given path variable from the vars map and writes a response if any failure occurs.
+// Unescapes the given path variable from the request and writes a response if any failure occurs.
 // Returns the unescaped version of the path variable and a bool indicating whether the unescaping was successful.
-func unescapePathVar(pathVar string, vars map[string]string, rw http.ResponseWriter) (string, bool) {
-	unescapedPathVar, err := url.PathUnescape(vars[pathVar])
+func unescapePathVar(pathVar string, req *http.Request, rw http.ResponseWriter) (string, bool) {
+	unescapedPathVar, err := url.PathUnescape(mux.Vars(req)[pathVar])
 	if err != nil {
 		rw.WriteHeader(http.StatusInternalServerError)
 
 		_, err = rw.Write([]byte(fmt.Sprintf("unable to escape %s path variable: %s", pathVar, err.Error())))
 		if err != nil {
-			log.Errorf("Failed to write response for %s unescaping failure: %s", pathVar, err.Error())
+			requestlog.Logger(req).Errorf("Failed to write response for %s unescaping failure: %s", pathVar, err.Error())
 		}
 
 		return "", false
@@ -391,6 +540,71 @@ func unescapePathVar(pathVar string, vars map[string]string, rw http.ResponseWri
 	return unescapedPathVar, true
 }
 
+// vaultIDFromPath returns the unescaped vault ID from the request's path. The EDV server's own stores aren't vaults,
+// so their names are treated like vaults that don't exist, and a not found response is written for them.
+// Returns whether the vault ID can be used.
+func vaultIDFromPath(req *http.Request, rw http.ResponseWriter) (string, bool) {
+	vaultID, success := unescapePathVar(vaultIDPathVariable, req, rw)
+	if !success {
+		return "", false
+	}
+
+	if IsInternalStoreName(vaultID) {
+		logFailure(req, "access vault", vaultID, edverrors.ErrVaultNotFound)
+
+		rw.WriteHeader(http.StatusNotFound)
+
+		_, err := rw.Write([]byte(edverrors.ErrVaultNotFound.Error()))
+		if err != nil {
+			requestlog.Logger(req).Errorf("Failed to write response for reserved vault ID: %s", err.Error())
+		}
+
+		return "", false
+	}
+
+	return vaultID, true
+}
+
+// logFailure logs a failed vault operation along with the ID of the request that triggered it.
+// Failures caused by the client (e.g. a missing vault) are logged at debug level, while anything else
+// (typically an error from the EDV provider) is logged as an error.
+func logFailure(req *http.Request, operation, vaultID string, err error) {
+	logger := requestlog.Logger(req).WithField("vaultID", vaultID)
+
+	if isClientError(err) {
+		logger.Debugf("Failed to %s: %s", operation, err.Error())
+
+		return
+	}
+
+	logger.Errorf("Failed to %s: %s", operation, err.Error())
+}
+
+func isClientError(err error) bool {
+	if errors.Is(err, jwe.ErrInvalidJWE) || errors.Is(err, vaultexport.ErrInvalidExport) {
+		return true
+	}
+
+	switch err {
+	case edverrors.ErrVaultNotFound, edverrors.ErrDocumentNotFound, edverrors.ErrDuplicateVault,
+		edverrors.ErrDuplicateDocument, edverrors.ErrNotBase58Encoded, edverrors.ErrNot128BitValue,
+		edverrors.ErrDocumentTooLarge, edverrors.ErrVaultQuotaExceeded, edverrors.ErrInvalidVaultQuota,
+		edverrors.ErrMissingDocumentID, edverrors.ErrMissingJWE, edverrors.ErrInvalidVaultPolicy,
+		edverrors.ErrKEKMismatch, edverrors.ErrHMACMismatch, edverrors.ErrInvalidDocumentIDFormat, edverrors.ErrNotUUID,
+		edverrors.ErrInvalidDocumentID, edverrors.ErrWebhookNotFound, edverrors.ErrInvalidWebhookURL,
+		edverrors.ErrWebhooksDisabled, edverrors.ErrReplicationDisabled, edverrors.ErrVaultNotReplicated,
+		edverrors.ErrConflictNotFound, edverrors.ErrInvalidConflictResolution, edverrors.ErrEmptyBatch,
+		edverrors.ErrBatchTooLarge, edverrors.ErrInvalidBatchOperation, edverrors.ErrMissingBatchDocument,
+		edverrors.ErrRepeatedBatchDocument, edverrors.ErrRequestBodyTooLarge, edverrors.ErrInvalidDocumentListLimit,
+		edverrors.ErrInvalidIncludeDocuments, edverrors.ErrReservedVaultID, edverrors.ErrMissingController,
+		edverrors.ErrUnauthenticatedController, edverrors.ErrControllerMismatch, edverrors.ErrWebhookURLNotAllowed,
+		edvprovider.ErrIndexNameAndValueAlreadyDeclaredUnique, edvprovider.ErrIndexNameAndValueCannotBeUnique:
+		return true
+	default:
+		return false
+	}
+}
+
 func convertToFullDocumentURLs(documentIDs []string, vaultID string, req *http.Request) []string {
 	fullDocumentURLs := make([]string, len(documentIDs))
 
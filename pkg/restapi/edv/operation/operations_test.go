@@ -14,6 +14,10 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -21,11 +25,16 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 	"github.com/trustbloc/edge-core/pkg/storage"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
 
+	"github.com/trustbloc/edv/pkg/audit"
 	"github.com/trustbloc/edv/pkg/edvprovider"
 	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/replication"
+	"github.com/trustbloc/edv/pkg/requestlog"
 	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
 	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+	"github.com/trustbloc/edv/pkg/webhook"
 )
 
 const (
@@ -248,17 +257,79 @@ func TestCreateDataVaultHandler_DuplicateDataVault(t *testing.T) {
 		rr.Body.String())
 }
 
+func TestCreateDataVaultHandler_ReservedVaultID(t *testing.T) {
+	provider := memedvprovider.NewProvider()
+	op := New(provider)
+
+	for _, vaultID := range []string{VaultConfigurationStoreName, audit.StoreName, webhook.StoreName,
+		replication.StoreName} {
+		config := strings.Replace(testDataVaultConfiguration, testVaultID, vaultID, 1)
+
+		rr := httptest.NewRecorder()
+		getHandler(t, op, createVaultEndpoint).Handle().ServeHTTP(rr,
+			httptest.NewRequest(http.MethodPost, createVaultEndpoint, bytes.NewBufferString(config)))
+
+		require.Equal(t, http.StatusBadRequest, rr.Code, vaultID)
+		require.Equal(t, "Data vault creation failed: "+edverrors.ErrReservedVaultID.Error(), rr.Body.String())
+
+		_, err := provider.OpenStore(vaultID)
+		require.Equal(t, storage.ErrStoreNotFound, err, vaultID)
+	}
+}
+
+func TestReservedVaultIDsAreNotVaults(t *testing.T) {
+	// With CouchDB, the EDV server's own stores are in the same namespace as the vaults.
+	provider := memedvprovider.NewProvider()
+	require.NoError(t, provider.CreateStore(webhook.StoreName))
+
+	store, err := provider.OpenStore(webhook.StoreName)
+	require.NoError(t, err)
+	require.NoError(t, store.Put(models.EncryptedDocument{ID: testDocID, JWE: []byte(`{"secret":"value"}`)}))
+
+	op := New(provider)
+
+	rr := serveListDocumentsRequest(t, op, webhook.StoreName, url.Values{})
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	require.Equal(t, edverrors.ErrVaultNotFound.Error(), rr.Body.String())
+
+	req := httptest.NewRequest(http.MethodGet, readDocumentEndpoint, nil)
+	req = mux.SetURLVars(req, map[string]string{vaultIDPathVariable: webhook.StoreName, docIDPathVariable: testDocID})
+
+	rr = httptest.NewRecorder()
+	getHandler(t, op, readDocumentEndpoint).Handle().ServeHTTP(rr, req)
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	require.Equal(t, edverrors.ErrVaultNotFound.Error(), rr.Body.String())
+
+	rr = serveListDocumentsRequest(t, op, VaultConfigurationStoreName, url.Values{})
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
+
 type mockEDVProvider struct {
 	errStoreCreateEDVIndex           error
 	errOpenStore                     error
 	numTimesOpenStoreCalled          int
 	numTimesOpenStoreCalledBeforeErr int
+	errPing                          error
+	errStoreChanges                  error
+	errStoreBulkWrite                error
+	errStoreReindex                  error
+	errStoreListDocuments            error
+	errStoreGet                      error
+	errStoreNames                    error
+	errDeleteStore                   error
+	numTimesDeleteStoreCalled        int
 }
 
 func (m *mockEDVProvider) CreateStore(name string) error {
 	return nil
 }
 
+func (m *mockEDVProvider) DeleteStore(name string) error {
+	m.numTimesDeleteStoreCalled++
+
+	return m.errDeleteStore
+}
+
 func (m *mockEDVProvider) OpenStore(name string) (edvprovider.EDVStore, error) {
 	if m.numTimesOpenStoreCalled == m.numTimesOpenStoreCalledBeforeErr {
 		return nil, m.errOpenStore
@@ -266,21 +337,44 @@ func (m *mockEDVProvider) OpenStore(name string) (edvprovider.EDVStore, error) {
 
 	m.numTimesOpenStoreCalled++
 
-	return &mockEDVStore{errCreateEDVIndex: m.errStoreCreateEDVIndex}, nil
+	return &mockEDVStore{errCreateEDVIndex: m.errStoreCreateEDVIndex, errChanges: m.errStoreChanges,
+		errBulkWrite: m.errStoreBulkWrite, errReindex: m.errStoreReindex, errListDocuments: m.errStoreListDocuments,
+		errGet: m.errStoreGet}, nil
+}
+
+func (m *mockEDVProvider) StoreNames() ([]string, error) {
+	return nil, m.errStoreNames
+}
+
+func (m *mockEDVProvider) Ping() error {
+	return m.errPing
+}
+
+func (m *mockEDVProvider) Close() error {
+	return nil
 }
 
 type mockEDVStore struct {
 	errCreateEDVIndex error
+	errChanges        error
+	errBulkWrite      error
+	errReindex        error
+	errListDocuments  error
+	errGet            error
 }
 
 func (m *mockEDVStore) Put(document models.EncryptedDocument) error {
 	panic("implement me")
 }
 
-func (m *mockEDVStore) Get(k string) ([]byte, error) {
+func (m *mockEDVStore) Create(document models.EncryptedDocument) error {
 	panic("implement me")
 }
 
+func (m *mockEDVStore) Get(k string) ([]byte, error) {
+	return nil, m.errGet
+}
+
 func (m *mockEDVStore) CreateEDVIndex() error {
 	return m.errCreateEDVIndex
 }
@@ -289,6 +383,44 @@ func (m *mockEDVStore) Query(query *models.Query) ([]string, error) {
 	return []string{"docID1", "docID2"}, nil
 }
 
+func (m *mockEDVStore) Reindex() (*models.ReindexResult, error) {
+	if m.errReindex != nil {
+		return nil, m.errReindex
+	}
+
+	return &models.ReindexResult{Documents: 2, MappingsCreated: 1, UniquenessViolations: []models.UniquenessViolation{
+		{Name: "name", Value: "value", DocumentIDs: []string{"docID1", "docID2"}},
+	}}, nil
+}
+
+func (m *mockEDVStore) ListDocuments(cursor string, limit int) (*models.DocumentList, error) {
+	if m.errListDocuments != nil {
+		return nil, m.errListDocuments
+	}
+
+	return &models.DocumentList{DocumentIDs: []string{"docID1"}, Cursor: "docID1"}, nil
+}
+
+func (m *mockEDVStore) Check(bool) (*models.ConsistencyReport, error) {
+	return &models.ConsistencyReport{Documents: 2}, nil
+}
+
+func (m *mockEDVStore) Changes(since string, limit int) (*models.ChangeFeed, error) {
+	if m.errChanges != nil {
+		return nil, m.errChanges
+	}
+
+	return &models.ChangeFeed{Changes: []models.DocumentChange{}, Cursor: since}, nil
+}
+
+func (m *mockEDVStore) BulkWrite(writes []edvprovider.DocumentWrite) ([]error, error) {
+	if m.errBulkWrite != nil {
+		return nil, m.errBulkWrite
+	}
+
+	return make([]error, len(writes)), nil
+}
+
 func TestCreateDataVaultHandler_FailToCreateEDVIndex(t *testing.T) {
 	errTest := errors.New("create EDV index error")
 	op := New(&mockEDVProvider{errStoreCreateEDVIndex: errTest, numTimesOpenStoreCalledBeforeErr: 1})
@@ -305,6 +437,40 @@ func TestCreateDataVaultHandler_FailToCreateEDVIndex(t *testing.T) {
 	require.Equal(t, http.StatusBadRequest, rr.Code)
 }
 
+func TestCreateDataVaultHandler_FailToSetUpVault(t *testing.T) {
+	t.Run("Store is removed so the vault can be created again", func(t *testing.T) {
+		provider := memedvprovider.NewProvider()
+		storageProvider := mockstore.NewMockStoreProvider()
+		storageProvider.Store.ErrPut = errors.New("put failure")
+
+		op := New(provider, WithStorageProvider(storageProvider))
+
+		require.Equal(t, http.StatusBadRequest, createTestVault(t, op, ""))
+
+		_, err := provider.OpenStore(testVaultID)
+		require.Equal(t, storage.ErrStoreNotFound, err)
+
+		storageProvider.Store.ErrPut = nil
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+	})
+	t.Run("Fail to remove the store", func(t *testing.T) {
+		provider := &mockEDVProvider{errStoreCreateEDVIndex: errors.New("create EDV index error"),
+			numTimesOpenStoreCalledBeforeErr: 1, errDeleteStore: errors.New("delete store error")}
+
+		op := New(provider)
+
+		rr := httptest.NewRecorder()
+		getHandler(t, op, createVaultEndpoint).Handle().ServeHTTP(rr,
+			httptest.NewRequest(http.MethodPost, createVaultEndpoint, bytes.NewBufferString(testDataVaultConfiguration)))
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Equal(t, "Data vault creation failed: create EDV index error "+
+			"(failed to remove the vault's store as well: delete store error)", rr.Body.String())
+		require.Equal(t, 1, provider.numTimesDeleteStoreCalled)
+	})
+}
+
 func TestQueryVaultHandler(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		op := New(&mockEDVProvider{numTimesOpenStoreCalledBeforeErr: 2})
@@ -467,7 +633,7 @@ func TestSendQueryResponse(t *testing.T) {
 	t.Run("No matching documents", func(t *testing.T) {
 		rr := httptest.NewRecorder()
 
-		sendQueryResponse(rr, nil)
+		sendQueryResponse(rr, nil, nil)
 
 		require.Equal(t, http.StatusOK, rr.Code)
 		require.Equal(t, "no matching documents found", rr.Body.String())
@@ -476,7 +642,7 @@ func TestSendQueryResponse(t *testing.T) {
 		var logContents bytes.Buffer
 		log.SetOutput(&logContents)
 
-		sendQueryResponse(failingResponseWriter{}, nil)
+		sendQueryResponse(failingResponseWriter{}, nil, nil)
 
 		require.Contains(t, logContents.String(), fmt.Sprintf(edverrors.QueryVaultFailureToWriteSuccessResponseErrMsg,
 			"failingResponseWriter always fails"))
@@ -485,7 +651,7 @@ func TestSendQueryResponse(t *testing.T) {
 		var logContents bytes.Buffer
 		log.SetOutput(&logContents)
 
-		sendQueryResponse(failingResponseWriter{}, []string{"docID1", "docID2"})
+		sendQueryResponse(failingResponseWriter{}, nil, []string{"docID1", "docID2"})
 
 		require.Contains(t, logContents.String(), fmt.Sprintf(edverrors.QueryVaultFailureToWriteSuccessResponseErrMsg,
 			"failingResponseWriter always fails"))
@@ -589,6 +755,53 @@ func TestCreateDocumentHandler_DuplicateDocuments(t *testing.T) {
 	require.Contains(t, rr.Body.String(), edverrors.ErrDuplicateDocument.Error())
 }
 
+func TestCreateDocumentHandler_ConcurrentDuplicateDocuments(t *testing.T) {
+	op := New(memedvprovider.NewProvider())
+
+	createDataVaultExpectSuccess(t, op)
+
+	const numRequests = 10
+
+	createDocumentEndpointHandler := getHandler(t, op, createDocumentEndpoint)
+	statusCodes := make(chan int, numRequests)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < numRequests; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			req := httptest.NewRequest(http.MethodPost, createDocumentEndpoint,
+				bytes.NewBufferString(testEncryptedDocument))
+			req = mux.SetURLVars(req, map[string]string{vaultIDPathVariable: testVaultID})
+
+			rr := httptest.NewRecorder()
+			createDocumentEndpointHandler.Handle().ServeHTTP(rr, req)
+
+			statusCodes <- rr.Code
+		}()
+	}
+
+	wg.Wait()
+	close(statusCodes)
+
+	numCreated := 0
+
+	for statusCode := range statusCodes {
+		if statusCode == http.StatusCreated {
+			numCreated++
+
+			continue
+		}
+
+		require.Equal(t, http.StatusConflict, statusCode)
+	}
+
+	require.Equal(t, 1, numCreated)
+}
+
 func TestCreateDocumentHandler_VaultDoesNotExist(t *testing.T) {
 	op := New(memedvprovider.NewProvider())
 	createDocumentEndpointHandler := getHandler(t, op, createDocumentEndpoint)
@@ -893,6 +1106,43 @@ func TestReadDocumentHandler_ResponseWriterFailsWhileWritingRetrievedDocument(t
 		" failingResponseWriter always fails")
 }
 
+func TestLogFailure(t *testing.T) {
+	var logContents bytes.Buffer
+
+	log.SetOutput(&logContents)
+
+	defer log.SetOutput(os.Stderr)
+
+	t.Run("Provider error is logged with the request ID", func(t *testing.T) {
+		logContents.Reset()
+
+		op := New(&mockEDVProvider{errOpenStore: errors.New("database unreachable")})
+
+		req, err := http.NewRequest(http.MethodGet, "", nil)
+		require.NoError(t, err)
+
+		req.Header.Set(requestlog.RequestIDHeader, "testRequestID")
+		req = mux.SetURLVars(req, getMapWithValidVaultIDAndDocID())
+
+		rr := httptest.NewRecorder()
+
+		requestlog.Handler(getHandler(t, op, readDocumentEndpoint).Handle()).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, logContents.String(), "level=error")
+		require.Contains(t, logContents.String(), "Failed to read document: database unreachable")
+		require.Contains(t, logContents.String(), "requestID=testRequestID")
+	})
+	t.Run("Client errors aren't logged as errors", func(t *testing.T) {
+		logContents.Reset()
+
+		logFailure(httptest.NewRequest(http.MethodGet, "/", nil), "read document", testVaultID,
+			edverrors.ErrDocumentNotFound)
+
+		require.NotContains(t, logContents.String(), "level=error")
+	})
+}
+
 func createDataVaultExpectSuccess(t *testing.T, op *Operation) {
 	req, err := http.NewRequest(http.MethodPost, "", bytes.NewBuffer([]byte(testDataVaultConfiguration)))
 	require.NoError(t, err)
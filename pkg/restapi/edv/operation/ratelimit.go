@@ -0,0 +1,87 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/trustbloc/edv/pkg/principal"
+	"github.com/trustbloc/edv/pkg/ratelimit"
+	"github.com/trustbloc/edv/pkg/requestlog"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+)
+
+// WithWriteRateLimiter limits the rate of vault and document creation requests with the given limiter.
+func WithWriteRateLimiter(limiter *ratelimit.Limiter) Option {
+	return func(opts *Operation) {
+		opts.writeRateLimiter = limiter
+	}
+}
+
+// WithQueryRateLimiter limits the rate of vault query requests with the given limiter.
+func WithQueryRateLimiter(limiter *ratelimit.Limiter) Option {
+	return func(opts *Operation) {
+		opts.queryRateLimiter = limiter
+	}
+}
+
+// rateLimited wraps the given handler so that requests are rejected with a 429 status code once either the client
+// or the vault they're for has used up its tokens in the given limiter. Clients are identified by their
+// authenticated identity if they have one, or their IP address otherwise.
+// If the limiter is nil, then the handler is returned unchanged.
+func rateLimited(limiter *ratelimit.Limiter, handle http.HandlerFunc) http.HandlerFunc {
+	if limiter == nil {
+		return handle
+	}
+
+	return func(rw http.ResponseWriter, req *http.Request) {
+		keys := []string{"client:" + clientKey(req)}
+
+		if vaultID, found := mux.Vars(req)[vaultIDPathVariable]; found {
+			keys = append(keys, "vault:"+vaultID)
+		}
+
+		allowed, retryAfter := limiter.Allow(keys...)
+		if allowed {
+			handle(rw, req)
+
+			return
+		}
+
+		retryAfterSeconds := int(math.Ceil(retryAfter.Seconds()))
+
+		requestlog.Logger(req).Debugf("Rejected request from %s: %s", keys[0], edverrors.ErrRateLimitExceeded)
+
+		rw.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds))
+		rw.WriteHeader(http.StatusTooManyRequests)
+
+		_, err := rw.Write([]byte(fmt.Sprintf("%s. Retry after %d seconds",
+			edverrors.ErrRateLimitExceeded, retryAfterSeconds)))
+		if err != nil {
+			requestlog.Logger(req).Errorf("Failed to write response for rate limited request: %s", err.Error())
+		}
+	}
+}
+
+func clientKey(req *http.Request) string {
+	if clientIdentity, found := principal.FromRequest(req); found {
+		return clientIdentity
+	}
+
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,78 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/principal"
+	"github.com/trustbloc/edv/pkg/ratelimit"
+)
+
+func TestRateLimited(t *testing.T) {
+	t.Run("Writes are limited per client", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider(), WithWriteRateLimiter(ratelimit.New(0.001, 1)))
+
+		createVault := func(remoteAddr, referenceID string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, createVaultEndpoint,
+				bytes.NewBufferString(`{"referenceId":"`+referenceID+`"}`))
+			req.RemoteAddr = remoteAddr
+
+			rr := httptest.NewRecorder()
+			getHandler(t, op, createVaultEndpoint).Handle().ServeHTTP(rr, req)
+
+			return rr
+		}
+
+		require.Equal(t, http.StatusCreated, createVault("192.0.2.1:1234", "vault1").Code)
+
+		rr := createVault("192.0.2.1:5678", "vault2")
+		require.Equal(t, http.StatusTooManyRequests, rr.Code)
+		require.NotEmpty(t, rr.Header().Get("Retry-After"))
+		require.Contains(t, rr.Body.String(), "rate limit exceeded")
+
+		require.Equal(t, http.StatusCreated, createVault("192.0.2.2:1234", "vault2").Code)
+	})
+	t.Run("Queries are limited per vault", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider(), WithQueryRateLimiter(ratelimit.New(0.001, 1)))
+
+		query := func(clientIdentity, vaultID string) int {
+			req := httptest.NewRequest(http.MethodPost, queryVaultEndpoint, bytes.NewBufferString(testQuery))
+			req = req.WithContext(principal.NewContext(req.Context(), clientIdentity))
+			req = mux.SetURLVars(req, map[string]string{vaultIDPathVariable: vaultID})
+
+			rr := httptest.NewRecorder()
+			getHandler(t, op, queryVaultEndpoint).Handle().ServeHTTP(rr, req)
+
+			return rr.Code
+		}
+
+		require.Equal(t, http.StatusBadRequest, query("did:example:1", "vault1"))
+		require.Equal(t, http.StatusTooManyRequests, query("did:example:2", "vault1"))
+		require.Equal(t, http.StatusBadRequest, query("did:example:2", "vault2"))
+	})
+}
+
+func TestClientKey(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	req.RemoteAddr = "192.0.2.1:1234"
+	require.Equal(t, "192.0.2.1", clientKey(req))
+
+	req.RemoteAddr = "not a host and port"
+	require.Equal(t, "not a host and port", clientKey(req))
+
+	req = req.WithContext(principal.NewContext(req.Context(), "did:example:123"))
+	require.Equal(t, "did:example:123", clientKey(req))
+}
@@ -0,0 +1,83 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edv/pkg/edvprovider"
+	"github.com/trustbloc/edv/pkg/requestlog"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+const (
+	reindexEndpoint = edvCommonEndpointPathRoot + "/{" + vaultIDPathVariable + "}/reindex"
+
+	reindexVaultAction = "reindexVault"
+)
+
+func (c *Operation) reindexVaultHandler(rw http.ResponseWriter, req *http.Request) {
+	vaultID, success := vaultIDFromPath(req, rw)
+	if !success {
+		return
+	}
+
+	result, err := c.vaultCollection.reindexVault(vaultID)
+	if err != nil {
+		writeReindexFailure(rw, req, vaultID, err)
+
+		return
+	}
+
+	if len(result.UniquenessViolations) > 0 {
+		requestlog.Logger(req).WithField("vaultID", vaultID).Warnf(
+			"Reindexing found %d index names and values that are declared unique but shared by several documents",
+			len(result.UniquenessViolations))
+	}
+
+	sendJSONResponse(rw, req, http.StatusOK, result)
+}
+
+// reindexVault rebuilds the vault's encrypted indexes from its documents. Other document creations
+// through this server wait until it's done.
+func (vc *VaultCollection) reindexVault(vaultID string) (*models.ReindexResult, error) {
+	store, err := vc.provider.OpenStore(vaultID)
+	if err != nil {
+		if err == storage.ErrStoreNotFound {
+			return nil, edverrors.ErrVaultNotFound
+		}
+
+		return nil, err
+	}
+
+	unlock := vc.lockVault(vaultID)
+	defer unlock()
+
+	return store.Reindex()
+}
+
+func writeReindexFailure(rw http.ResponseWriter, req *http.Request, vaultID string, err error) {
+	logFailure(req, "reindex vault", vaultID, err)
+
+	switch err {
+	case edverrors.ErrVaultNotFound:
+		rw.WriteHeader(http.StatusNotFound)
+	case edvprovider.ErrIndexingNotSupported:
+		rw.WriteHeader(http.StatusNotImplemented)
+	default:
+		rw.WriteHeader(http.StatusInternalServerError)
+	}
+
+	_, err = rw.Write([]byte(fmt.Sprintf("Failed to reindex vault: %s", err)))
+	if err != nil {
+		requestlog.Logger(req).Errorf("Failed to write response for vault reindex failure: %s", err.Error())
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edv/pkg/edvprovider"
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+func TestReindexVaultHandler(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		rr := serveReindexRequest(t, New(&mockEDVProvider{numTimesOpenStoreCalledBeforeErr: 1}), testVaultID)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		result := models.ReindexResult{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+		require.Equal(t, models.ReindexResult{Documents: 2, MappingsCreated: 1,
+			UniquenessViolations: []models.UniquenessViolation{
+				{Name: "name", Value: "value", DocumentIDs: []string{"docID1", "docID2"}},
+			}}, result)
+	})
+	t.Run("Vault not found", func(t *testing.T) {
+		rr := serveReindexRequest(t, New(memedvprovider.NewProvider()), testVaultID)
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Equal(t, "Failed to reindex vault: "+edverrors.ErrVaultNotFound.Error(), rr.Body.String())
+	})
+	t.Run("Provider doesn't support indexing", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+
+		rr := serveReindexRequest(t, op, testVaultID)
+		require.Equal(t, http.StatusNotImplemented, rr.Code)
+		require.Equal(t, "Failed to reindex vault: "+edvprovider.ErrIndexingNotSupported.Error(), rr.Body.String())
+	})
+	t.Run("Provider errors", func(t *testing.T) {
+		rr := serveReindexRequest(t, New(&mockEDVProvider{errOpenStore: errors.New("open store error")}),
+			testVaultID)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Equal(t, "Failed to reindex vault: open store error", rr.Body.String())
+
+		rr = serveReindexRequest(t, New(&mockEDVProvider{errStoreReindex: errors.New("reindex error"),
+			numTimesOpenStoreCalledBeforeErr: 1}), testVaultID)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Equal(t, "Failed to reindex vault: reindex error", rr.Body.String())
+	})
+}
+
+func serveReindexRequest(t *testing.T, op *Operation, vaultID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, reindexEndpoint, nil)
+	req = mux.SetURLVars(req, map[string]string{vaultIDPathVariable: vaultID})
+
+	rr := httptest.NewRecorder()
+	getHandler(t, op, reindexEndpoint).Handle().ServeHTTP(rr, req)
+
+	return rr
+}
@@ -0,0 +1,198 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edv/pkg/edvprovider"
+	"github.com/trustbloc/edv/pkg/replication"
+	"github.com/trustbloc/edv/pkg/requestlog"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+const (
+	replicationEndpoint         = edvCommonEndpointPathRoot + "/{" + vaultIDPathVariable + "}/replication"
+	replicationConflictEndpoint = replicationEndpoint + "/conflicts/{" + docIDPathVariable + "}"
+
+	// maxConflictResolutionSize is the maximum size, in bytes, of the request body when resolving a conflict.
+	maxConflictResolutionSize = 1024
+
+	readReplicationStatusAction      = "readReplicationStatus"
+	resolveReplicationConflictAction = "resolveReplicationConflict"
+)
+
+// WithReplicator sets the replicator that mirrors vaults from another EDV server, so that their replication status
+// can be read and their conflicts resolved. If not set, then the replication endpoints respond with a 501 status code.
+// The replicator writes the documents it replicates through the operations, so that they count towards the vaults'
+// usage, and so it should only be started once the operations have been created.
+func WithReplicator(replicator *replication.Replicator) Option {
+	return func(opts *Operation) {
+		opts.vaultCollection.replicator = replicator
+
+		if replicator != nil {
+			replicator.SetVaultWriter(&opts.vaultCollection)
+		}
+	}
+}
+
+func (c *Operation) readReplicationStatusHandler(rw http.ResponseWriter, req *http.Request) {
+	vaultID, success := vaultIDFromPath(req, rw)
+	if !success {
+		return
+	}
+
+	status, err := c.vaultCollection.readReplicationStatus(vaultID)
+	if err != nil {
+		writeReplicationFailure(rw, req, "read replication status", vaultID, err)
+
+		return
+	}
+
+	sendJSONResponse(rw, req, http.StatusOK, status)
+}
+
+func (c *Operation) resolveReplicationConflictHandler(rw http.ResponseWriter, req *http.Request) {
+	resolution := models.ConflictResolution{}
+
+	err := c.decodeRequestBody(rw, req, maxConflictResolutionSize, &resolution)
+	if err != nil {
+		rw.WriteHeader(decodeFailureStatusCode(err))
+
+		_, err = rw.Write([]byte(err.Error()))
+		if err != nil {
+			requestlog.Logger(req).Errorf("Failed to write response for conflict resolution failure: %s", err.Error())
+		}
+
+		return
+	}
+
+	vaultID, success := vaultIDFromPath(req, rw)
+	if !success {
+		return
+	}
+
+	docID, success := unescapePathVar(docIDPathVariable, req, rw)
+	if !success {
+		return
+	}
+
+	err = c.vaultCollection.resolveReplicationConflict(vaultID, docID, resolution.Keep)
+	if err != nil {
+		writeReplicationFailure(rw, req, "resolve replication conflict", vaultID, err)
+
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func (vc *VaultCollection) readReplicationStatus(vaultID string) (*models.ReplicationStatus, error) {
+	if vc.replicator == nil {
+		return nil, edverrors.ErrReplicationDisabled
+	}
+
+	return vc.replicator.Status(vaultID)
+}
+
+func (vc *VaultCollection) resolveReplicationConflict(vaultID, docID, keep string) error {
+	if vc.replicator == nil {
+		return edverrors.ErrReplicationDisabled
+	}
+
+	return vc.replicator.ResolveConflict(vaultID, docID, keep)
+}
+
+// WriteReplicatedDocument stores a document replicated from another EDV server in the given vault, replacing its
+// current version if it has one. Like a single-document request, it's written while holding the vault's lock and it
+// counts towards the vault's quota and usage. The document is written in bulk so that, when it's an update,
+// it isn't checked against its own unique indexed attributes.
+func (vc *VaultCollection) WriteReplicatedDocument(vaultID string, document models.EncryptedDocument) error {
+	store, err := vc.provider.OpenStore(vaultID)
+	if err != nil {
+		if err == storage.ErrStoreNotFound {
+			return edverrors.ErrVaultNotFound
+		}
+
+		return err
+	}
+
+	unlock := vc.lockVault(vaultID)
+	defer unlock()
+
+	record, err := vc.getVaultRecord(vaultID)
+	if err != nil {
+		return err
+	}
+
+	documentSize, err := encodedDocumentSize(document)
+	if err != nil {
+		return err
+	}
+
+	write := edvprovider.DocumentWrite{Type: edvprovider.WriteUpdate, Document: document}
+	addedDocuments := int64(0)
+
+	replacedSize, err := storedDocumentSize(store, document.ID)
+	if err == edverrors.ErrDocumentNotFound {
+		write.Type = edvprovider.WriteCreate
+		addedDocuments = 1
+	} else if err != nil {
+		return err
+	}
+
+	err = checkUsageFitsQuota(record.Configuration.Quota, documentSize,
+		record.DocumentCount+addedDocuments, record.TotalBytes+documentSize-replacedSize)
+	if err != nil {
+		return err
+	}
+
+	writeErrs, err := store.BulkWrite([]edvprovider.DocumentWrite{write})
+	if err != nil {
+		return err
+	}
+
+	if writeErrs[0] != nil {
+		return writeErrs[0]
+	}
+
+	record.DocumentCount += addedDocuments
+	record.TotalBytes += documentSize - replacedSize
+
+	vc.recordUsage(vaultID, record)
+	vc.notifyChange(vaultID)
+
+	return nil
+}
+
+func writeReplicationFailure(rw http.ResponseWriter, req *http.Request, operation, vaultID string, err error) {
+	logFailure(req, operation, vaultID, err)
+
+	rw.WriteHeader(replicationFailureStatusCode(err))
+
+	_, err = rw.Write([]byte(fmt.Sprintf("Failed to %s: %s", operation, err)))
+	if err != nil {
+		requestlog.Logger(req).Errorf("Failed to write response for %s failure: %s", operation, err.Error())
+	}
+}
+
+func replicationFailureStatusCode(err error) int {
+	switch err {
+	case edverrors.ErrVaultNotFound, edverrors.ErrVaultNotReplicated, edverrors.ErrConflictNotFound:
+		return http.StatusNotFound
+	case edverrors.ErrInvalidConflictResolution:
+		return http.StatusBadRequest
+	case edverrors.ErrReplicationDisabled:
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
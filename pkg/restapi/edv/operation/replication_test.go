@@ -0,0 +1,243 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+
+	"github.com/trustbloc/edv/pkg/edvprovider"
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/replication"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+func TestReplicationHandlers(t *testing.T) {
+	t.Run("Read status and resolve a conflict", func(t *testing.T) {
+		op, replicator, remoteStore := newTestReplicationOperation(t, memedvprovider.NewProvider())
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+
+		putReplicationTestDocument(t, remoteStore, "original")
+
+		_, err := replicator.Replicate(context.Background(), testVaultID)
+		require.NoError(t, err)
+
+		localStore, err := op.vaultCollection.provider.OpenStore(testVaultID)
+		require.NoError(t, err)
+
+		putReplicationTestDocument(t, localStore, "local edit")
+		putReplicationTestDocument(t, remoteStore, "remote edit")
+
+		_, err = replicator.Replicate(context.Background(), testVaultID)
+		require.NoError(t, err)
+
+		rr := serveReplicationRequest(t, op, http.MethodGet, replicationEndpoint, "", "")
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		status := models.ReplicationStatus{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &status))
+		require.Equal(t, testVaultID, status.VaultID)
+		require.NotEmpty(t, status.Cursor)
+		require.Len(t, status.Conflicts, 1)
+		require.Equal(t, testDocID, status.Conflicts[0].DocumentID)
+
+		rr = serveReplicationRequest(t, op, http.MethodPost, replicationConflictEndpoint, testDocID,
+			`{"keep":"remote"}`)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+
+		documentBytes, err := localStore.Get(testDocID)
+		require.NoError(t, err)
+		require.Contains(t, string(documentBytes), "remote edit")
+
+		rr = serveReplicationRequest(t, op, http.MethodGet, replicationEndpoint, "", "")
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Contains(t, rr.Body.String(), `"conflicts":[]`)
+	})
+	t.Run("Conflict not found", func(t *testing.T) {
+		op, _, _ := newTestReplicationOperation(t, memedvprovider.NewProvider())
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+
+		rr := serveReplicationRequest(t, op, http.MethodPost, replicationConflictEndpoint, testDocID,
+			`{"keep":"local"}`)
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Contains(t, rr.Body.String(), edverrors.ErrConflictNotFound.Error())
+	})
+	t.Run("Invalid resolution", func(t *testing.T) {
+		op, _, _ := newTestReplicationOperation(t, memedvprovider.NewProvider())
+
+		rr := serveReplicationRequest(t, op, http.MethodPost, replicationConflictEndpoint, testDocID,
+			`{"keep":"both"}`)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), edverrors.ErrInvalidConflictResolution.Error())
+
+		rr = serveReplicationRequest(t, op, http.MethodPost, replicationConflictEndpoint, testDocID, `{`)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+	t.Run("Vault not found", func(t *testing.T) {
+		op, _, _ := newTestReplicationOperation(t, memedvprovider.NewProvider())
+
+		rr := serveReplicationRequest(t, op, http.MethodGet, replicationEndpoint, "", "")
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Contains(t, rr.Body.String(), edverrors.ErrVaultNotFound.Error())
+	})
+	t.Run("Vault not replicated", func(t *testing.T) {
+		provider := memedvprovider.NewProvider()
+
+		replicator, err := replication.New(&storeSource{}, provider, memstore.NewProvider(), []string{"othervault"})
+		require.NoError(t, err)
+
+		op := New(provider, WithReplicator(replicator))
+
+		rr := serveReplicationRequest(t, op, http.MethodGet, replicationEndpoint, "", "")
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Contains(t, rr.Body.String(), edverrors.ErrVaultNotReplicated.Error())
+	})
+	t.Run("Replication disabled", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		rr := serveReplicationRequest(t, op, http.MethodGet, replicationEndpoint, "", "")
+		require.Equal(t, http.StatusNotImplemented, rr.Code)
+		require.Contains(t, rr.Body.String(), edverrors.ErrReplicationDisabled.Error())
+
+		rr = serveReplicationRequest(t, op, http.MethodPost, replicationConflictEndpoint, testDocID,
+			`{"keep":"local"}`)
+		require.Equal(t, http.StatusNotImplemented, rr.Code)
+	})
+	t.Run("Provider error", func(t *testing.T) {
+		op, _, _ := newTestReplicationOperation(t, &mockEDVProvider{errOpenStore: errors.New("open store error")})
+
+		rr := serveReplicationRequest(t, op, http.MethodGet, replicationEndpoint, "", "")
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "Failed to read replication status: open store error")
+	})
+}
+
+func TestWriteReplicatedDocument(t *testing.T) {
+	t.Run("Replicated documents count towards the vault's usage", func(t *testing.T) {
+		op, replicator, remoteStore := newTestReplicationOperation(t, memedvprovider.NewProvider())
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+
+		putReplicationTestDocument(t, remoteStore, "original")
+
+		_, err := replicator.Replicate(context.Background(), testVaultID)
+		require.NoError(t, err)
+
+		stats := readTestVaultStats(t, op)
+		require.Equal(t, int64(1), stats.DocumentCount)
+		require.Equal(t, replicationTestDocumentSize(t, "original"), stats.TotalBytes)
+
+		putReplicationTestDocument(t, remoteStore, "a longer remote edit")
+
+		_, err = replicator.Replicate(context.Background(), testVaultID)
+		require.NoError(t, err)
+
+		stats = readTestVaultStats(t, op)
+		require.Equal(t, int64(1), stats.DocumentCount)
+		require.Equal(t, replicationTestDocumentSize(t, "a longer remote edit"), stats.TotalBytes)
+	})
+	t.Run("Replicated documents must fit the vault's quota", func(t *testing.T) {
+		op, replicator, remoteStore := newTestReplicationOperation(t, memedvprovider.NewProvider())
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, `{"maxDocuments":1}`))
+
+		putReplicationTestDocument(t, remoteStore, "original")
+		require.NoError(t, remoteStore.Put(models.EncryptedDocument{ID: testBatchDocID1, JWE: []byte(`"other"`)}))
+
+		_, err := replicator.Replicate(context.Background(), testVaultID)
+		require.True(t, errors.Is(err, edverrors.ErrVaultQuotaExceeded))
+
+		stats := readTestVaultStats(t, op)
+		require.Equal(t, int64(1), stats.DocumentCount)
+	})
+	t.Run("Vault not found", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		err := op.vaultCollection.WriteReplicatedDocument(testVaultID, models.EncryptedDocument{ID: testDocID})
+		require.Equal(t, edverrors.ErrVaultNotFound, err)
+	})
+	t.Run("Provider error", func(t *testing.T) {
+		op := New(&mockEDVProvider{errOpenStore: errors.New("open store error")})
+
+		err := op.vaultCollection.WriteReplicatedDocument(testVaultID, models.EncryptedDocument{ID: testDocID})
+		require.EqualError(t, err, "open store error")
+	})
+}
+
+// storeSource serves the documents of an EDV store, standing in for a remote EDV server.
+type storeSource struct {
+	store edvprovider.EDVStore
+}
+
+func (s *storeSource) ReadChanges(_, since string, limit int) (*models.ChangeFeed, error) {
+	return s.store.Changes(since, limit)
+}
+
+func (s *storeSource) ReadDocument(_, docID string) (*models.EncryptedDocument, error) {
+	documentBytes, err := s.store.Get(docID)
+	if errors.Is(err, storage.ErrValueNotFound) {
+		return nil, edverrors.ErrDocumentNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	document := models.EncryptedDocument{}
+
+	return &document, json.Unmarshal(documentBytes, &document)
+}
+
+// newTestReplicationOperation returns an operation that replicates the test vault from a remote store,
+// along with its replicator and the remote store.
+func newTestReplicationOperation(t *testing.T,
+	provider edvprovider.EDVProvider) (*Operation, *replication.Replicator, edvprovider.EDVStore) {
+	remoteProvider := memedvprovider.NewProvider()
+	require.NoError(t, remoteProvider.CreateStore(testVaultID))
+
+	remoteStore, err := remoteProvider.OpenStore(testVaultID)
+	require.NoError(t, err)
+
+	replicator, err := replication.New(&storeSource{store: remoteStore}, provider, memstore.NewProvider(),
+		[]string{testVaultID})
+	require.NoError(t, err)
+
+	return New(provider, WithReplicator(replicator)), replicator, remoteStore
+}
+
+func putReplicationTestDocument(t *testing.T, store edvprovider.EDVStore, content string) {
+	require.NoError(t, store.Put(models.EncryptedDocument{ID: testDocID, JWE: []byte(`"` + content + `"`)}))
+}
+
+func replicationTestDocumentSize(t *testing.T, content string) int64 {
+	documentSize, err := encodedDocumentSize(models.EncryptedDocument{ID: testDocID, JWE: []byte(`"` + content + `"`)})
+	require.NoError(t, err)
+
+	return documentSize
+}
+
+func serveReplicationRequest(t *testing.T, op *Operation, method, endpoint, docID,
+	body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, endpoint, bytes.NewBufferString(body))
+	req = mux.SetURLVars(req, map[string]string{vaultIDPathVariable: testVaultID, docIDPathVariable: docID})
+
+	rr := httptest.NewRecorder()
+	getHandler(t, op, endpoint).Handle().ServeHTTP(rr, req)
+
+	return rr
+}
@@ -0,0 +1,270 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edv/pkg/audit"
+	"github.com/trustbloc/edv/pkg/replication"
+	"github.com/trustbloc/edv/pkg/requestlog"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+	"github.com/trustbloc/edv/pkg/webhook"
+)
+
+const (
+	// VaultConfigurationStoreName is the name of the store in the storage provider that holds vault configurations
+	// and usage.
+	VaultConfigurationStoreName = "vaultconfigurations"
+
+	vaultStatsEndpoint = edvCommonEndpointPathRoot + "/{" + vaultIDPathVariable + "}/stats"
+
+	readVaultStatsAction = "readVaultStats"
+)
+
+// IsInternalStoreName returns whether the given store name is one that the EDV server keeps its own data in,
+// rather than a vault. With CouchDB, these stores' databases sit next to the vaults' databases.
+func IsInternalStoreName(name string) bool {
+	switch name {
+	case VaultConfigurationStoreName, audit.StoreName, webhook.StoreName, replication.StoreName:
+		return true
+	default:
+		return false
+	}
+}
+
+// vaultRecord is what's kept in the vault configuration store for each vault.
+// Vaults created before configurations were stored don't have a record until a document is created in them,
+// so their usage only counts documents created since then.
+type vaultRecord struct {
+	Configuration models.DataVaultConfiguration `json:"configuration"`
+	DocumentCount int64                         `json:"documentCount"`
+	TotalBytes    int64                         `json:"totalBytes"`
+}
+
+func (c *Operation) vaultStatsHandler(rw http.ResponseWriter, req *http.Request) {
+	vaultID, success := vaultIDFromPath(req, rw)
+	if !success {
+		return
+	}
+
+	stats, err := c.vaultCollection.getVaultStats(vaultID)
+	if err != nil {
+		logFailure(req, "read vault stats", vaultID, err)
+
+		if err == edverrors.ErrVaultNotFound {
+			rw.WriteHeader(http.StatusNotFound)
+		} else {
+			rw.WriteHeader(http.StatusInternalServerError)
+		}
+
+		_, err = rw.Write([]byte(fmt.Sprintf("Failed to read vault stats: %s", err)))
+		if err != nil {
+			requestlog.Logger(req).Errorf("Failed to write response for vault stats failure: %s", err.Error())
+		}
+
+		return
+	}
+
+	sendJSONResponse(rw, req, http.StatusOK, stats)
+}
+
+func (vc *VaultCollection) getVaultStats(vaultID string) (*models.VaultStats, error) {
+	_, err := vc.provider.OpenStore(vaultID)
+	if err != nil {
+		if err == storage.ErrStoreNotFound {
+			return nil, edverrors.ErrVaultNotFound
+		}
+
+		return nil, err
+	}
+
+	record, err := vc.getVaultRecord(vaultID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.VaultStats{
+		DocumentCount: record.DocumentCount,
+		TotalBytes:    record.TotalBytes,
+		Quota:         record.Configuration.Quota,
+	}, nil
+}
+
+// configurationStore returns the vault configuration store, creating it the first time it's needed.
+func (vc *VaultCollection) configurationStore() (storage.Store, error) {
+	vc.configStoreMux.Lock()
+	defer vc.configStoreMux.Unlock()
+
+	if vc.configStore != nil {
+		return vc.configStore, nil
+	}
+
+	err := vc.storageProvider.CreateStore(VaultConfigurationStoreName)
+	if err != nil && err != storage.ErrDuplicateStore {
+		return nil, fmt.Errorf("failed to create vault configuration store: %w", err)
+	}
+
+	store, err := vc.storageProvider.OpenStore(VaultConfigurationStoreName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vault configuration store: %w", err)
+	}
+
+	vc.configStore = store
+
+	return store, nil
+}
+
+// getVaultRecord returns the stored record for the given vault, or an empty record if there isn't one.
+func (vc *VaultCollection) getVaultRecord(vaultID string) (*vaultRecord, error) {
+	store, err := vc.configurationStore()
+	if err != nil {
+		return nil, err
+	}
+
+	recordBytes, err := store.Get(vaultID)
+	if err == storage.ErrValueNotFound {
+		return &vaultRecord{Configuration: models.DataVaultConfiguration{ReferenceID: vaultID}}, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vault configuration: %w", err)
+	}
+
+	record := vaultRecord{}
+
+	err = json.Unmarshal(recordBytes, &record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse vault configuration: %w", err)
+	}
+
+	return &record, nil
+}
+
+func (vc *VaultCollection) storeVaultRecord(vaultID string, record *vaultRecord) error {
+	store, err := vc.configurationStore()
+	if err != nil {
+		return err
+	}
+
+	recordBytes, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal vault configuration: %w", err)
+	}
+
+	err = store.Put(vaultID, recordBytes)
+	if err != nil {
+		return fmt.Errorf("failed to store vault configuration: %w", err)
+	}
+
+	return nil
+}
+
+// lockVault serializes operations that update a vault's usage. It returns the function that releases the lock.
+// Note that this only works within a single EDV server instance.
+func (vc *VaultCollection) lockVault(vaultID string) func() {
+	vaultLock, _ := vc.vaultLocks.LoadOrStore(vaultID, &sync.Mutex{})
+
+	mutex := vaultLock.(*sync.Mutex) //nolint: errcheck
+	mutex.Lock()
+
+	return mutex.Unlock
+}
+
+// recordDocumentCreated adds the given document to the vault's usage.
+// The document has already been stored by now, so failing to update the usage is logged rather than returned.
+func (vc *VaultCollection) recordDocumentCreated(vaultID string, record *vaultRecord, documentSize int64) {
+	record.DocumentCount++
+	record.TotalBytes += documentSize
+
+	err := vc.storeVaultRecord(vaultID, record)
+	if err != nil {
+		log.WithField("vaultID", vaultID).Errorf("Failed to update vault usage: %s", err.Error())
+	}
+}
+
+func checkVaultQuota(quota *models.VaultQuota) error {
+	if quota == nil {
+		return nil
+	}
+
+	if quota.MaxDocuments < 0 || quota.MaxBytes < 0 || quota.MaxDocumentSize < 0 {
+		return edverrors.ErrInvalidVaultQuota
+	}
+
+	return nil
+}
+
+// checkDocumentFitsQuota checks whether the given document can be added to the vault without exceeding its quota.
+// The size of the document is returned so that it can be added to the usage.
+func checkDocumentFitsQuota(record *vaultRecord, document models.EncryptedDocument) (int64, error) {
+	documentSize, err := encodedDocumentSize(document)
+	if err != nil {
+		return 0, err
+	}
+
+	err = checkUsageFitsQuota(record.Configuration.Quota, documentSize,
+		record.DocumentCount+1, record.TotalBytes+documentSize)
+	if err != nil {
+		return 0, err
+	}
+
+	return documentSize, nil
+}
+
+// encodedDocumentSize returns the size of the given document's JSON serialization,
+// which is what counts towards a vault's usage.
+func encodedDocumentSize(document models.EncryptedDocument) (int64, error) {
+	documentBytes, err := json.Marshal(document)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(len(documentBytes)), nil
+}
+
+// checkUsageFitsQuota checks that a document of the given size is allowed by the quota,
+// and that the vault's usage once it's stored would still be within the quota.
+func checkUsageFitsQuota(quota *models.VaultQuota, documentSize, documentCount, totalBytes int64) error {
+	if quota == nil {
+		return nil
+	}
+
+	if quota.MaxDocumentSize > 0 && documentSize > quota.MaxDocumentSize {
+		return edverrors.ErrDocumentTooLarge
+	}
+
+	if quota.MaxDocuments > 0 && documentCount > quota.MaxDocuments {
+		return edverrors.ErrVaultQuotaExceeded
+	}
+
+	if quota.MaxBytes > 0 && totalBytes > quota.MaxBytes {
+		return edverrors.ErrVaultQuotaExceeded
+	}
+
+	return nil
+}
+
+func createDocumentFailureStatusCode(err error) int {
+	switch err {
+	case edverrors.ErrDuplicateDocument:
+		return http.StatusConflict
+	case edverrors.ErrDocumentTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case edverrors.ErrVaultQuotaExceeded:
+		return http.StatusInsufficientStorage
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -0,0 +1,241 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+const (
+	testDocID2 = "BiR14UiGZCa35S9BZMWPA"
+	testDocID3 = "Am1Ny7JUdakgzEf8Y4Szc"
+)
+
+func TestVaultQuota(t *testing.T) {
+	t.Run("Max documents", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, `{"maxDocuments":2}`))
+		require.Equal(t, http.StatusCreated, createTestDocument(t, op, testDocID))
+		require.Equal(t, http.StatusCreated, createTestDocument(t, op, testDocID2))
+		require.Equal(t, http.StatusInsufficientStorage, createTestDocument(t, op, testDocID3))
+
+		stats := readTestVaultStats(t, op)
+		require.Equal(t, int64(2), stats.DocumentCount)
+		require.Equal(t, int64(2), stats.Quota.MaxDocuments)
+	})
+	t.Run("Max bytes", func(t *testing.T) {
+		document := models.EncryptedDocument{}
+		require.NoError(t, json.Unmarshal([]byte(testEncryptedDocument), &document))
+
+		documentBytes, err := json.Marshal(document)
+		require.NoError(t, err)
+
+		// Enough room for one test document, but not two.
+		maxBytes := len(documentBytes) * 3 / 2
+
+		op := New(memedvprovider.NewProvider())
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, fmt.Sprintf(`{"maxBytes":%d}`, maxBytes)))
+		require.Equal(t, http.StatusCreated, createTestDocument(t, op, testDocID))
+		require.Equal(t, http.StatusInsufficientStorage, createTestDocument(t, op, testDocID2))
+
+		stats := readTestVaultStats(t, op)
+		require.Equal(t, int64(1), stats.DocumentCount)
+		require.Equal(t, int64(len(documentBytes)), stats.TotalBytes)
+	})
+	t.Run("Max document size", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, `{"maxDocumentSize":10}`))
+		require.Equal(t, http.StatusRequestEntityTooLarge, createTestDocument(t, op, testDocID))
+
+		stats := readTestVaultStats(t, op)
+		require.Equal(t, int64(0), stats.DocumentCount)
+		require.Equal(t, int64(0), stats.TotalBytes)
+	})
+	t.Run("No quota", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+		require.Equal(t, http.StatusCreated, createTestDocument(t, op, testDocID))
+		require.Equal(t, http.StatusConflict, createTestDocument(t, op, testDocID))
+
+		stats := readTestVaultStats(t, op)
+		require.Equal(t, int64(1), stats.DocumentCount)
+		require.Nil(t, stats.Quota)
+	})
+	t.Run("Negative limit", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		require.Equal(t, http.StatusBadRequest, createTestVault(t, op, `{"maxBytes":-1}`))
+	})
+}
+
+func TestVaultStatsHandler(t *testing.T) {
+	t.Run("Vault created without a stored configuration", func(t *testing.T) {
+		provider := memedvprovider.NewProvider()
+		require.NoError(t, provider.CreateStore(testVaultID))
+
+		op := New(provider)
+
+		stats := readTestVaultStats(t, op)
+		require.Equal(t, models.VaultStats{}, *stats)
+	})
+	t.Run("Vault doesn't exist", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		rr := serveVaultStatsRequest(t, op, testVaultID)
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Contains(t, rr.Body.String(), edverrors.ErrVaultNotFound.Error())
+	})
+	t.Run("Fail to unescape vault ID", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		rr := serveVaultStatsRequest(t, op, "%")
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+	t.Run("Fail to get vault configuration", func(t *testing.T) {
+		provider := memedvprovider.NewProvider()
+		require.NoError(t, provider.CreateStore(testVaultID))
+
+		storageProvider := mockstore.NewMockStoreProvider()
+		storageProvider.Store.Store[testVaultID] = []byte("{}")
+		storageProvider.Store.ErrGet = errors.New("get failure")
+
+		op := New(provider, WithStorageProvider(storageProvider))
+
+		rr := serveVaultStatsRequest(t, op, testVaultID)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to get vault configuration: get failure")
+	})
+}
+
+func TestVaultCollection_ConfigurationStore(t *testing.T) {
+	t.Run("Fail to create store", func(t *testing.T) {
+		storageProvider := mockstore.NewMockStoreProvider()
+		storageProvider.ErrCreateStore = errors.New("create failure")
+
+		op := New(memedvprovider.NewProvider(), WithStorageProvider(storageProvider))
+
+		_, err := op.vaultCollection.getVaultRecord(testVaultID)
+		require.EqualError(t, err, "failed to create vault configuration store: create failure")
+	})
+	t.Run("Fail to open store", func(t *testing.T) {
+		storageProvider := mockstore.NewMockStoreProvider()
+		storageProvider.FailNameSpace = VaultConfigurationStoreName
+
+		op := New(memedvprovider.NewProvider(), WithStorageProvider(storageProvider))
+
+		err := op.vaultCollection.storeVaultRecord(testVaultID, &vaultRecord{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to open vault configuration store")
+	})
+	t.Run("Store is reused", func(t *testing.T) {
+		storageProvider := memstore.NewProvider()
+
+		op := New(memedvprovider.NewProvider(), WithStorageProvider(storageProvider))
+
+		require.NoError(t, op.vaultCollection.storeVaultRecord(testVaultID, &vaultRecord{DocumentCount: 1}))
+
+		op = New(memedvprovider.NewProvider(), WithStorageProvider(storageProvider))
+
+		record, err := op.vaultCollection.getVaultRecord(testVaultID)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), record.DocumentCount)
+	})
+	t.Run("Unparsable record", func(t *testing.T) {
+		storageProvider := mockstore.NewMockStoreProvider()
+		storageProvider.Store.Store[testVaultID] = []byte("not json")
+
+		op := New(memedvprovider.NewProvider(), WithStorageProvider(storageProvider))
+
+		_, err := op.vaultCollection.getVaultRecord(testVaultID)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse vault configuration")
+	})
+	t.Run("Failure to update usage doesn't fail document creation", func(t *testing.T) {
+		storageProvider := mockstore.NewMockStoreProvider()
+
+		op := New(memedvprovider.NewProvider(), WithStorageProvider(storageProvider))
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+
+		storageProvider.Store.ErrPut = errors.New("put failure")
+
+		require.Equal(t, http.StatusCreated, createTestDocument(t, op, testDocID))
+	})
+}
+
+func TestIsInternalStoreName(t *testing.T) {
+	for _, name := range []string{VaultConfigurationStoreName, "auditlog", "webhooks", "replication"} {
+		require.True(t, IsInternalStoreName(name), name)
+	}
+
+	require.False(t, IsInternalStoreName(testVaultID))
+}
+
+func createTestVault(t *testing.T, op *Operation, quota string) int {
+	config := strings.Replace(testDataVaultConfiguration, `"sequence": 0,`, `"sequence": 0,"quota": `+quota+`,`, 1)
+	if quota == "" {
+		config = testDataVaultConfiguration
+	}
+
+	rr := httptest.NewRecorder()
+	getHandler(t, op, createVaultEndpoint).Handle().ServeHTTP(rr,
+		httptest.NewRequest(http.MethodPost, createVaultEndpoint, bytes.NewBufferString(config)))
+
+	return rr.Code
+}
+
+func createTestDocument(t *testing.T, op *Operation, docID string) int {
+	document := strings.Replace(testEncryptedDocument, testDocID, docID, 1)
+
+	req := httptest.NewRequest(http.MethodPost, createDocumentEndpoint, bytes.NewBufferString(document))
+	req = mux.SetURLVars(req, map[string]string{vaultIDPathVariable: testVaultID})
+
+	rr := httptest.NewRecorder()
+	getHandler(t, op, createDocumentEndpoint).Handle().ServeHTTP(rr, req)
+
+	return rr.Code
+}
+
+func serveVaultStatsRequest(t *testing.T, op *Operation, vaultID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, vaultStatsEndpoint, nil)
+	req = mux.SetURLVars(req, map[string]string{vaultIDPathVariable: vaultID})
+
+	rr := httptest.NewRecorder()
+	getHandler(t, op, vaultStatsEndpoint).Handle().ServeHTTP(rr, req)
+
+	return rr
+}
+
+func readTestVaultStats(t *testing.T, op *Operation) *models.VaultStats {
+	rr := serveVaultStatsRequest(t, op, testVaultID)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	stats := models.VaultStats{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &stats))
+
+	return &stats
+}
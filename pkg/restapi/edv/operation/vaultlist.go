@@ -0,0 +1,115 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/trustbloc/edv/pkg/principal"
+	"github.com/trustbloc/edv/pkg/requestlog"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+const (
+	listVaultsEndpoint = edvCommonEndpointPathRoot
+
+	listVaultsControllerQueryParameter = "controller"
+
+	listVaultsAction = "listVaults"
+)
+
+func (c *Operation) listVaultsHandler(rw http.ResponseWriter, req *http.Request) {
+	controller := req.URL.Query().Get(listVaultsControllerQueryParameter)
+
+	err := checkListVaultsAllowed(req, controller)
+	if err != nil {
+		writeListVaultsFailure(rw, req, err)
+
+		return
+	}
+
+	list, err := c.vaultCollection.listVaults(controller)
+	if err != nil {
+		writeListVaultsFailure(rw, req, err)
+
+		return
+	}
+
+	sendJSONResponse(rw, req, http.StatusOK, list)
+}
+
+// checkListVaultsAllowed checks that the client that sent the request authenticated itself as the given controller.
+// Knowing which vaults a controller has is enough to go after them, so nobody else can list them.
+func checkListVaultsAllowed(req *http.Request, controller string) error {
+	if controller == "" {
+		return edverrors.ErrMissingController
+	}
+
+	clientID, ok := principal.FromRequest(req)
+	if !ok {
+		return edverrors.ErrUnauthenticatedController
+	}
+
+	if clientID != controller {
+		return edverrors.ErrControllerMismatch
+	}
+
+	return nil
+}
+
+// listVaults returns the IDs of the vaults that the given controller controls, sorted.
+// A vault's controller is kept in its configuration, which is only written by whoever created the vault, so the list
+// is built from the configurations of every store in the EDV provider each time rather than kept separately,
+// where concurrent updates from several EDV server instances could overwrite each other. Vaults that were created
+// before configurations were stored have no known controller, so they're left out.
+func (vc *VaultCollection) listVaults(controller string) (*models.VaultList, error) {
+	storeNames, err := vc.provider.StoreNames()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vaults: %w", err)
+	}
+
+	vaultIDs := []string{}
+
+	for _, storeName := range storeNames {
+		if IsInternalStoreName(storeName) {
+			continue
+		}
+
+		record, err := vc.getVaultRecord(storeName)
+		if err != nil {
+			return nil, err
+		}
+
+		if record.Configuration.Controller == controller {
+			vaultIDs = append(vaultIDs, storeName)
+		}
+	}
+
+	return &models.VaultList{Controller: controller, VaultIDs: vaultIDs}, nil
+}
+
+func writeListVaultsFailure(rw http.ResponseWriter, req *http.Request, err error) {
+	logFailure(req, "list vaults", "", err)
+
+	switch err {
+	case edverrors.ErrMissingController:
+		rw.WriteHeader(http.StatusBadRequest)
+	case edverrors.ErrUnauthenticatedController:
+		rw.WriteHeader(http.StatusUnauthorized)
+	case edverrors.ErrControllerMismatch:
+		rw.WriteHeader(http.StatusForbidden)
+	default:
+		rw.WriteHeader(http.StatusInternalServerError)
+	}
+
+	_, err = rw.Write([]byte(fmt.Sprintf("Failed to list vaults: %s", err)))
+	if err != nil {
+		requestlog.Logger(req).Errorf("Failed to write response for vault list failure: %s", err.Error())
+	}
+}
@@ -0,0 +1,160 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/principal"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+const testController = "did:example:123456789"
+
+func TestListVaultsHandler(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		rr := serveListVaultsRequest(t, op, testController, testController)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+		require.Equal(t, &models.VaultList{Controller: testController, VaultIDs: []string{}}, parseVaultList(t, rr))
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+
+		require.NoError(t, op.vaultCollection.createDataVault(&models.DataVaultConfiguration{
+			ReferenceID: "anothervault", Controller: testController,
+		}))
+		require.NoError(t, op.vaultCollection.createDataVault(&models.DataVaultConfiguration{
+			ReferenceID: "someoneelsesvault", Controller: "did:example:987654321",
+		}))
+		require.NoError(t, op.vaultCollection.createDataVault(&models.DataVaultConfiguration{
+			ReferenceID: "uncontrolledvault",
+		}))
+
+		rr = serveListVaultsRequest(t, op, testController, testController)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, &models.VaultList{Controller: testController, VaultIDs: []string{"anothervault", testVaultID}},
+			parseVaultList(t, rr))
+	})
+	t.Run("Vaults are found from their configurations", func(t *testing.T) {
+		provider := memedvprovider.NewProvider()
+		storageProvider := memstore.NewProvider()
+
+		op := New(provider, WithStorageProvider(storageProvider))
+
+		require.NoError(t, op.vaultCollection.storeVaultRecord(testVaultID, &vaultRecord{
+			Configuration: models.DataVaultConfiguration{ReferenceID: testVaultID, Controller: testController},
+		}))
+		require.NoError(t, provider.CreateStore(testVaultID))
+		require.NoError(t, provider.CreateStore("vaultwithoutconfiguration"))
+		require.NoError(t, provider.CreateStore(VaultConfigurationStoreName))
+
+		rr := serveListVaultsRequest(t, op, testController, testController)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, []string{testVaultID}, parseVaultList(t, rr).VaultIDs)
+	})
+	t.Run("Vaults created by another EDV server instance are found", func(t *testing.T) {
+		provider := memedvprovider.NewProvider()
+		storageProvider := memstore.NewProvider()
+
+		op := New(provider, WithStorageProvider(storageProvider))
+		otherOp := New(provider, WithStorageProvider(storageProvider))
+
+		require.NoError(t, op.vaultCollection.createDataVault(&models.DataVaultConfiguration{
+			ReferenceID: "vault1", Controller: testController,
+		}))
+
+		rr := serveListVaultsRequest(t, otherOp, testController, testController)
+		require.Equal(t, []string{"vault1"}, parseVaultList(t, rr).VaultIDs)
+
+		require.NoError(t, otherOp.vaultCollection.createDataVault(&models.DataVaultConfiguration{
+			ReferenceID: "vault2", Controller: testController,
+		}))
+
+		rr = serveListVaultsRequest(t, op, testController, testController)
+		require.Equal(t, []string{"vault1", "vault2"}, parseVaultList(t, rr).VaultIDs)
+	})
+	t.Run("Not allowed", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		rr := serveListVaultsRequest(t, op, "", testController)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Equal(t, "Failed to list vaults: "+edverrors.ErrMissingController.Error(), rr.Body.String())
+
+		rr = serveListVaultsRequest(t, op, testController, "")
+		require.Equal(t, http.StatusUnauthorized, rr.Code)
+		require.Equal(t, "Failed to list vaults: "+edverrors.ErrUnauthenticatedController.Error(), rr.Body.String())
+
+		rr = serveListVaultsRequest(t, op, testController, "did:example:987654321")
+		require.Equal(t, http.StatusForbidden, rr.Code)
+		require.Equal(t, "Failed to list vaults: "+edverrors.ErrControllerMismatch.Error(), rr.Body.String())
+	})
+	t.Run("Fail to list stores", func(t *testing.T) {
+		op := New(&mockEDVProvider{errStoreNames: errors.New("store names error")})
+
+		rr := serveListVaultsRequest(t, op, testController, testController)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Equal(t, "Failed to list vaults: failed to list vaults: store names error", rr.Body.String())
+	})
+	t.Run("Fail to read vault configuration", func(t *testing.T) {
+		provider := memedvprovider.NewProvider()
+		require.NoError(t, provider.CreateStore(testVaultID))
+
+		storageProvider := mockstore.NewMockStoreProvider()
+		storageProvider.Store.Store[testVaultID] = []byte("not json")
+
+		op := New(provider, WithStorageProvider(storageProvider))
+
+		rr := serveListVaultsRequest(t, op, testController, testController)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to parse vault configuration")
+	})
+}
+
+func serveListVaultsRequest(t *testing.T, op *Operation, controller,
+	clientIdentity string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet,
+		listVaultsEndpoint+"?"+url.Values{listVaultsControllerQueryParameter: {controller}}.Encode(), nil)
+
+	if clientIdentity != "" {
+		req = req.WithContext(principal.NewContext(req.Context(), clientIdentity))
+	}
+
+	rr := httptest.NewRecorder()
+
+	// Listing and creating vaults share a path, so the handler is looked up by method as well.
+	for _, handler := range op.GetRESTHandlers() {
+		if handler.Path() == listVaultsEndpoint && handler.Method() == http.MethodGet {
+			handler.Handle().ServeHTTP(rr, req)
+
+			return rr
+		}
+	}
+
+	require.Fail(t, "unable to find handler")
+
+	return nil
+}
+
+func parseVaultList(t *testing.T, rr *httptest.ResponseRecorder) *models.VaultList {
+	list := models.VaultList{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &list))
+
+	return &list
+}
@@ -0,0 +1,90 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"github.com/trustbloc/edv/pkg/jwe"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+// checkVaultPolicy checks that the key IDs enforced by the vault's policy are set in its configuration,
+// and that its document ID format is supported.
+func checkVaultPolicy(config *models.DataVaultConfiguration) error {
+	policy := config.Policy
+	if policy == nil {
+		return nil
+	}
+
+	if (policy.EnforceKEK && config.KEK.ID == "") || (policy.EnforceHMAC && config.HMAC.ID == "") {
+		return edverrors.ErrInvalidVaultPolicy
+	}
+
+	return checkDocumentIDFormat(policy.DocumentIDFormat)
+}
+
+// documentIDFormat returns the format that the IDs of documents in the vault must have.
+func documentIDFormat(config *models.DataVaultConfiguration) string {
+	if config.Policy == nil {
+		return models.DocumentIDFormatBase58
+	}
+
+	return config.Policy.DocumentIDFormat
+}
+
+// checkDocumentAllowedInVault checks that the given document is valid and follows the vault's policy and quota.
+// The size of the document is returned so that it can be added to the vault's usage.
+func (vc *VaultCollection) checkDocumentAllowedInVault(record *vaultRecord,
+	document models.EncryptedDocument) (int64, error) {
+	err := vc.checkDocumentValidInVault(record, &document)
+	if err != nil {
+		return 0, err
+	}
+
+	return checkDocumentFitsQuota(record, document)
+}
+
+// checkDocumentValidInVault checks that the given document is valid and follows the vault's policy.
+func (vc *VaultCollection) checkDocumentValidInVault(record *vaultRecord, document *models.EncryptedDocument) error {
+	err := vc.validateEncryptedDocument(document, documentIDFormat(&record.Configuration))
+	if err != nil {
+		return err
+	}
+
+	return checkDocumentFollowsPolicy(&record.Configuration, document)
+}
+
+// checkDocumentFollowsPolicy checks that the given document uses the keys that the vault's policy enforces.
+func checkDocumentFollowsPolicy(config *models.DataVaultConfiguration, document *models.EncryptedDocument) error {
+	policy := config.Policy
+	if policy == nil {
+		return nil
+	}
+
+	if policy.EnforceKEK {
+		keyIDs, err := jwe.RecipientKeyIDs(document.JWE)
+		if err != nil {
+			return err
+		}
+
+		for _, keyID := range keyIDs {
+			if keyID != config.KEK.ID {
+				return edverrors.ErrKEKMismatch
+			}
+		}
+	}
+
+	if policy.EnforceHMAC {
+		for _, indexedAttributeCollection := range document.IndexedAttributeCollections {
+			if indexedAttributeCollection.HMAC.ID != config.HMAC.ID {
+				return edverrors.ErrHMACMismatch
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+)
+
+const (
+	testKEKID  = "https://example.com/kms/12345"
+	testHMACID = "https://example.com/kms/67891"
+)
+
+func TestVaultPolicy(t *testing.T) {
+	withPolicy := func(policy string) string {
+		return strings.Replace(testDataVaultConfiguration, `"sequence": 0,`, `"sequence": 0,"policy": `+policy+`,`, 1)
+	}
+
+	encryptedTo := func(kid string) string {
+		return strings.Replace(testEncryptedDocument, "https://example.com/kms/z7BgF536GaR", kid, 1)
+	}
+
+	indexedWith := func(hmacID string) string {
+		return strings.Replace(testEncryptedDocument, `"indexed":null`,
+			`"indexed":[{"sequence":0,"hmac":{"id":"`+hmacID+`","type":"Sha256HmacKey2019"},`+
+				`"attributes":[{"name":"CUQaxPtSLtd8L3WBAIkJ4DiVJeqoF6bdnhR7lSaPloZ","value":"RV58Va4904K"}]}]`, 1)
+	}
+
+	t.Run("Invalid policy", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		config := strings.Replace(withPolicy(`{"enforceKek":true}`), testKEKID, "", 1)
+
+		rr := serveTestRequest(t, op, createVaultEndpoint, config)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), edverrors.ErrInvalidVaultPolicy.Error())
+	})
+	t.Run("Enforce KEK", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		rr := serveTestRequest(t, op, createVaultEndpoint, withPolicy(`{"enforceKek":true}`))
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		rr = serveTestRequest(t, op, createDocumentEndpoint, testEncryptedDocument)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Equal(t, edverrors.ErrKEKMismatch.Error(), rr.Body.String())
+
+		rr = serveTestRequest(t, op, createDocumentEndpoint, indexedWith("https://example.com/kms/other"))
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+
+		rr = serveTestRequest(t, op, createDocumentEndpoint, encryptedTo(testKEKID))
+		require.Equal(t, http.StatusCreated, rr.Code)
+	})
+	t.Run("Enforce HMAC", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		rr := serveTestRequest(t, op, createVaultEndpoint, withPolicy(`{"enforceHmac":true}`))
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		rr = serveTestRequest(t, op, createDocumentEndpoint, indexedWith("https://example.com/kms/other"))
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Equal(t, edverrors.ErrHMACMismatch.Error(), rr.Body.String())
+
+		rr = serveTestRequest(t, op, createDocumentEndpoint, indexedWith(testHMACID))
+		require.Equal(t, http.StatusCreated, rr.Code)
+	})
+	t.Run("No policy", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		createDataVaultExpectSuccess(t, op)
+
+		rr := serveTestRequest(t, op, createDocumentEndpoint, indexedWith("https://example.com/kms/other"))
+		require.Equal(t, http.StatusCreated, rr.Code)
+	})
+}
@@ -0,0 +1,233 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edv/pkg/requestlog"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+	"github.com/trustbloc/edv/pkg/webhook"
+)
+
+const (
+	webhookIDPathVariable = "webhookID"
+
+	webhooksEndpoint          = edvCommonEndpointPathRoot + "/{" + vaultIDPathVariable + "}/webhooks"
+	webhookEndpoint           = webhooksEndpoint + "/{" + webhookIDPathVariable + "}"
+	webhookDeliveriesEndpoint = webhookEndpoint + "/deliveries"
+
+	// maxWebhookRequestSize is the maximum size, in bytes, of the request body when creating a webhook.
+	maxWebhookRequestSize = 8 * 1024
+
+	createWebhookAction         = "createWebhook"
+	readWebhooksAction          = "readWebhooks"
+	readWebhookAction           = "readWebhook"
+	deleteWebhookAction         = "deleteWebhook"
+	readWebhookDeliveriesAction = "readWebhookDeliveries"
+)
+
+// WithWebhookDispatcher sets the dispatcher that keeps track of webhooks and delivers vault events to them.
+// If not set, then the webhook endpoints respond with a 501 status code.
+func WithWebhookDispatcher(dispatcher *webhook.Dispatcher) Option {
+	return func(opts *Operation) {
+		opts.vaultCollection.webhooks = dispatcher
+	}
+}
+
+func (c *Operation) createWebhookHandler(rw http.ResponseWriter, req *http.Request) {
+	request := models.WebhookRequest{}
+
+	err := c.decodeRequestBody(rw, req, maxWebhookRequestSize, &request)
+	if err != nil {
+		rw.WriteHeader(decodeFailureStatusCode(err))
+
+		_, err = rw.Write([]byte(err.Error()))
+		if err != nil {
+			requestlog.Logger(req).Errorf("Failed to write response for webhook creation failure: %s", err.Error())
+		}
+
+		return
+	}
+
+	vaultID, success := vaultIDFromPath(req, rw)
+	if !success {
+		return
+	}
+
+	createdWebhook, err := c.vaultCollection.createWebhook(vaultID, request.URL)
+	if err != nil {
+		writeWebhookFailure(rw, req, "create webhook", vaultID, err)
+
+		return
+	}
+
+	rw.Header().Set("Location", req.Host+"/encrypted-data-vaults/"+
+		url.PathEscape(vaultID)+"/webhooks/"+url.PathEscape(createdWebhook.ID))
+	sendJSONResponse(rw, req, http.StatusCreated, createdWebhook)
+}
+
+func (c *Operation) readWebhooksHandler(rw http.ResponseWriter, req *http.Request) {
+	vaultID, success := vaultIDFromPath(req, rw)
+	if !success {
+		return
+	}
+
+	webhooks, err := c.vaultCollection.readWebhooks(vaultID)
+	if err != nil {
+		writeWebhookFailure(rw, req, "read webhooks", vaultID, err)
+
+		return
+	}
+
+	sendJSONResponse(rw, req, http.StatusOK, webhooks)
+}
+
+func (c *Operation) readWebhookHandler(rw http.ResponseWriter, req *http.Request) {
+	vaultID, webhookID, success := unescapeWebhookPathVars(req, rw)
+	if !success {
+		return
+	}
+
+	readWebhook, err := c.vaultCollection.readWebhook(vaultID, webhookID)
+	if err != nil {
+		writeWebhookFailure(rw, req, "read webhook", vaultID, err)
+
+		return
+	}
+
+	sendJSONResponse(rw, req, http.StatusOK, readWebhook)
+}
+
+func (c *Operation) deleteWebhookHandler(rw http.ResponseWriter, req *http.Request) {
+	vaultID, webhookID, success := unescapeWebhookPathVars(req, rw)
+	if !success {
+		return
+	}
+
+	err := c.vaultCollection.deleteWebhook(vaultID, webhookID)
+	if err != nil {
+		writeWebhookFailure(rw, req, "delete webhook", vaultID, err)
+
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func (c *Operation) readWebhookDeliveriesHandler(rw http.ResponseWriter, req *http.Request) {
+	vaultID, webhookID, success := unescapeWebhookPathVars(req, rw)
+	if !success {
+		return
+	}
+
+	deliveries, err := c.vaultCollection.readWebhookDeliveries(vaultID, webhookID)
+	if err != nil {
+		writeWebhookFailure(rw, req, "read webhook deliveries", vaultID, err)
+
+		return
+	}
+
+	sendJSONResponse(rw, req, http.StatusOK, deliveries)
+}
+
+func (vc *VaultCollection) createWebhook(vaultID, callbackURL string) (*models.Webhook, error) {
+	if err := vc.checkWebhooksAvailable(vaultID); err != nil {
+		return nil, err
+	}
+
+	return vc.webhooks.Subscribe(vaultID, callbackURL)
+}
+
+func (vc *VaultCollection) readWebhooks(vaultID string) ([]models.Webhook, error) {
+	if err := vc.checkWebhooksAvailable(vaultID); err != nil {
+		return nil, err
+	}
+
+	return vc.webhooks.Webhooks(vaultID)
+}
+
+func (vc *VaultCollection) readWebhook(vaultID, webhookID string) (*models.Webhook, error) {
+	if err := vc.checkWebhooksAvailable(vaultID); err != nil {
+		return nil, err
+	}
+
+	return vc.webhooks.Webhook(vaultID, webhookID)
+}
+
+func (vc *VaultCollection) deleteWebhook(vaultID, webhookID string) error {
+	if err := vc.checkWebhooksAvailable(vaultID); err != nil {
+		return err
+	}
+
+	return vc.webhooks.Unsubscribe(vaultID, webhookID)
+}
+
+func (vc *VaultCollection) readWebhookDeliveries(vaultID, webhookID string) ([]models.WebhookDelivery, error) {
+	if err := vc.checkWebhooksAvailable(vaultID); err != nil {
+		return nil, err
+	}
+
+	return vc.webhooks.Deliveries(vaultID, webhookID)
+}
+
+// checkWebhooksAvailable checks that webhooks are enabled and that the given vault exists.
+func (vc *VaultCollection) checkWebhooksAvailable(vaultID string) error {
+	if vc.webhooks == nil {
+		return edverrors.ErrWebhooksDisabled
+	}
+
+	_, err := vc.provider.OpenStore(vaultID)
+	if err == storage.ErrStoreNotFound {
+		return edverrors.ErrVaultNotFound
+	}
+
+	return err
+}
+
+func unescapeWebhookPathVars(req *http.Request, rw http.ResponseWriter) (string, string, bool) {
+	vaultID, success := vaultIDFromPath(req, rw)
+	if !success {
+		return "", "", false
+	}
+
+	webhookID, success := unescapePathVar(webhookIDPathVariable, req, rw)
+	if !success {
+		return "", "", false
+	}
+
+	return vaultID, webhookID, true
+}
+
+func writeWebhookFailure(rw http.ResponseWriter, req *http.Request, operation, vaultID string, err error) {
+	logFailure(req, operation, vaultID, err)
+
+	rw.WriteHeader(webhookFailureStatusCode(err))
+
+	_, err = rw.Write([]byte(fmt.Sprintf("Failed to %s: %s", operation, err)))
+	if err != nil {
+		requestlog.Logger(req).Errorf("Failed to write response for %s failure: %s", operation, err.Error())
+	}
+}
+
+func webhookFailureStatusCode(err error) int {
+	switch err {
+	case edverrors.ErrVaultNotFound, edverrors.ErrWebhookNotFound:
+		return http.StatusNotFound
+	case edverrors.ErrInvalidWebhookURL, edverrors.ErrWebhookURLNotAllowed:
+		return http.StatusBadRequest
+	case edverrors.ErrWebhooksDisabled:
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,227 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+
+	"github.com/trustbloc/edv/pkg/edvprovider"
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+	"github.com/trustbloc/edv/pkg/webhook"
+)
+
+const testWebhookURL = "https://example.com/webhook"
+
+func TestWebhookHandlers(t *testing.T) {
+	t.Run("Create, read and delete a webhook", func(t *testing.T) {
+		op, _ := newTestWebhookOperation(t, memedvprovider.NewProvider())
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+
+		rr := serveWebhookRequest(t, op, http.MethodPost, webhooksEndpoint, "",
+			`{"url":"`+testWebhookURL+`"}`)
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		created := models.Webhook{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+		require.Equal(t, testVaultID, created.VaultID)
+		require.Equal(t, testWebhookURL, created.URL)
+		require.NotEmpty(t, created.Secret)
+		require.Equal(t, "example.com/encrypted-data-vaults/"+url.PathEscape(testVaultID)+"/webhooks/"+created.ID,
+			rr.Header().Get("Location"))
+
+		rr = serveWebhookRequest(t, op, http.MethodGet, webhooksEndpoint, "", "")
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var webhooks []models.Webhook
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &webhooks))
+		require.Len(t, webhooks, 1)
+		require.Equal(t, created.ID, webhooks[0].ID)
+		require.Empty(t, webhooks[0].Secret)
+
+		rr = serveWebhookRequest(t, op, http.MethodGet, webhookEndpoint, created.ID, "")
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		readWebhook := models.Webhook{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &readWebhook))
+		require.Equal(t, created.ID, readWebhook.ID)
+		require.Empty(t, readWebhook.Secret)
+
+		rr = serveWebhookRequest(t, op, http.MethodGet, webhookDeliveriesEndpoint, created.ID, "")
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "[]", rr.Body.String())
+
+		rr = serveWebhookRequest(t, op, http.MethodDelete, webhookEndpoint, created.ID, "")
+		require.Equal(t, http.StatusNoContent, rr.Code)
+
+		rr = serveWebhookRequest(t, op, http.MethodGet, webhookEndpoint, created.ID, "")
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Contains(t, rr.Body.String(), edverrors.ErrWebhookNotFound.Error())
+
+		rr = serveWebhookRequest(t, op, http.MethodGet, webhooksEndpoint, "", "")
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "[]", rr.Body.String())
+	})
+	t.Run("Created documents are delivered to webhooks", func(t *testing.T) {
+		received := make(chan []byte, 1)
+
+		receiver := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			body, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+
+			received <- body
+		}))
+		defer receiver.Close()
+
+		op, dispatcher := newTestWebhookOperation(t, memedvprovider.NewProvider(),
+			webhook.WithHTTPClient(receiver.Client()), webhook.WithPollInterval(time.Hour))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go dispatcher.Run(ctx)
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+
+		rr := serveWebhookRequest(t, op, http.MethodPost, webhooksEndpoint, "", `{"url":"`+receiver.URL+`"}`)
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		created := models.Webhook{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+
+		require.Equal(t, http.StatusCreated, createTestDocument(t, op, testDocID))
+
+		select {
+		case body := <-received:
+			event := models.WebhookEvent{}
+			require.NoError(t, json.Unmarshal(body, &event))
+			require.Equal(t, created.ID, event.WebhookID)
+			require.Equal(t, testDocID, event.Change.ID)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "webhook wasn't called after a document was created")
+		}
+	})
+	t.Run("Invalid webhook URL", func(t *testing.T) {
+		op, _ := newTestWebhookOperation(t, memedvprovider.NewProvider())
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+
+		rr := serveWebhookRequest(t, op, http.MethodPost, webhooksEndpoint, "", `{"url":"http://example.com"}`)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), edverrors.ErrInvalidWebhookURL.Error())
+	})
+	t.Run("Webhook URL not allowed", func(t *testing.T) {
+		op, _ := newTestWebhookOperation(t, memedvprovider.NewProvider())
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+
+		rr := serveWebhookRequest(t, op, http.MethodPost, webhooksEndpoint, "",
+			`{"url":"https://internal.example.com/webhook"}`)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), edverrors.ErrWebhookURLNotAllowed.Error())
+	})
+	t.Run("Invalid request body", func(t *testing.T) {
+		op, _ := newTestWebhookOperation(t, memedvprovider.NewProvider())
+
+		rr := serveWebhookRequest(t, op, http.MethodPost, webhooksEndpoint, "", `{`)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+	t.Run("Vault not found", func(t *testing.T) {
+		op, _ := newTestWebhookOperation(t, memedvprovider.NewProvider())
+
+		rr := serveWebhookRequest(t, op, http.MethodPost, webhooksEndpoint, "", `{"url":"`+testWebhookURL+`"}`)
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Contains(t, rr.Body.String(), edverrors.ErrVaultNotFound.Error())
+
+		for _, endpoint := range []string{webhooksEndpoint, webhookEndpoint, webhookDeliveriesEndpoint} {
+			rr = serveWebhookRequest(t, op, http.MethodGet, endpoint, "webhookID", "")
+			require.Equal(t, http.StatusNotFound, rr.Code)
+			require.Contains(t, rr.Body.String(), edverrors.ErrVaultNotFound.Error())
+		}
+
+		rr = serveWebhookRequest(t, op, http.MethodDelete, webhookEndpoint, "webhookID", "")
+		require.Equal(t, http.StatusNotFound, rr.Code)
+	})
+	t.Run("Webhook not found", func(t *testing.T) {
+		op, _ := newTestWebhookOperation(t, memedvprovider.NewProvider())
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+
+		rr := serveWebhookRequest(t, op, http.MethodGet, webhookDeliveriesEndpoint, "webhookID", "")
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Contains(t, rr.Body.String(), edverrors.ErrWebhookNotFound.Error())
+
+		rr = serveWebhookRequest(t, op, http.MethodDelete, webhookEndpoint, "webhookID", "")
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Contains(t, rr.Body.String(), edverrors.ErrWebhookNotFound.Error())
+	})
+	t.Run("Webhooks disabled", func(t *testing.T) {
+		op := New(memedvprovider.NewProvider())
+
+		require.Equal(t, http.StatusCreated, createTestVault(t, op, ""))
+
+		rr := serveWebhookRequest(t, op, http.MethodPost, webhooksEndpoint, "", `{"url":"`+testWebhookURL+`"}`)
+		require.Equal(t, http.StatusNotImplemented, rr.Code)
+		require.Contains(t, rr.Body.String(), edverrors.ErrWebhooksDisabled.Error())
+
+		// Creating documents still works.
+		require.Equal(t, http.StatusCreated, createTestDocument(t, op, testDocID))
+	})
+	t.Run("Provider error", func(t *testing.T) {
+		op, _ := newTestWebhookOperation(t, &mockEDVProvider{errOpenStore: errors.New("open store error")})
+
+		rr := serveWebhookRequest(t, op, http.MethodGet, webhooksEndpoint, "", "")
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "Failed to read webhooks: open store error")
+	})
+}
+
+func newTestWebhookOperation(t *testing.T, provider edvprovider.EDVProvider,
+	opts ...webhook.Option) (*Operation, *webhook.Dispatcher) {
+	// The test hosts are allowed, so that they aren't looked up.
+	opts = append([]webhook.Option{webhook.WithAllowedHosts([]string{"example.com", "127.0.0.1"})}, opts...)
+
+	dispatcher, err := webhook.New(provider, memstore.NewProvider(), opts...)
+	require.NoError(t, err)
+
+	return New(provider, WithWebhookDispatcher(dispatcher)), dispatcher
+}
+
+func serveWebhookRequest(t *testing.T, op *Operation, method, endpoint, webhookID,
+	body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, endpoint, bytes.NewBufferString(body))
+	req = mux.SetURLVars(req, map[string]string{vaultIDPathVariable: testVaultID, webhookIDPathVariable: webhookID})
+
+	rr := httptest.NewRecorder()
+
+	for _, handler := range op.GetRESTHandlers() {
+		if handler.Path() == endpoint && handler.Method() == method {
+			handler.Handle().ServeHTTP(rr, req)
+
+			return rr
+		}
+	}
+
+	require.Fail(t, "unable to find handler")
+
+	return nil
+}
@@ -0,0 +1,215 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vaultexport
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+// ErrInvalidExport is returned when a vault export is malformed, incomplete or doesn't match its checksums.
+var ErrInvalidExport = errors.New("invalid vault export")
+
+// Writer writes a vault export as newline-delimited JSON.
+type Writer struct {
+	encoder       *json.Encoder
+	documentCount int
+	checksums     hash.Hash
+}
+
+// NewWriter returns a writer of a vault export to the given writer.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{encoder: json.NewEncoder(w), checksums: sha256.New()}
+}
+
+// WriteConfiguration writes the vault record holding the given configuration. It must be written first.
+func (w *Writer) WriteConfiguration(config *models.DataVaultConfiguration) error {
+	return w.encoder.Encode(models.ExportRecord{Type: models.ExportRecordVault, Configuration: config})
+}
+
+// WriteDocument writes a document record holding the given document along with its checksum.
+func (w *Writer) WriteDocument(document *models.EncryptedDocument) error {
+	checksum, err := DocumentChecksum(document)
+	if err != nil {
+		return err
+	}
+
+	err = w.encoder.Encode(models.ExportRecord{Type: models.ExportRecordDocument, Document: document,
+		Checksum: checksum})
+	if err != nil {
+		return err
+	}
+
+	w.documentCount++
+	_, _ = w.checksums.Write([]byte(checksum))
+
+	return nil
+}
+
+// Finish writes the end record, which completes the export.
+func (w *Writer) Finish() error {
+	return w.encoder.Encode(models.ExportRecord{
+		Type:          models.ExportRecordEnd,
+		Checksum:      hex.EncodeToString(w.checksums.Sum(nil)),
+		DocumentCount: w.documentCount,
+	})
+}
+
+// Reader reads a vault export, checking each document against its checksum and the whole export against
+// its end record.
+type Reader struct {
+	decoder       *json.Decoder
+	documentCount int
+	checksums     hash.Hash
+	finished      bool
+}
+
+// NewReader returns a reader of the vault export read from the given reader.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{decoder: json.NewDecoder(r), checksums: sha256.New()}
+}
+
+// ReadConfiguration reads the vault record at the start of the export and returns the vault's configuration.
+func (r *Reader) ReadConfiguration() (*models.DataVaultConfiguration, error) {
+	record, err := r.readRecord()
+	if err != nil {
+		return nil, err
+	}
+
+	if record.Type != models.ExportRecordVault || record.Configuration == nil {
+		return nil, fmt.Errorf("%w: the export doesn't start with a vault record", ErrInvalidExport)
+	}
+
+	return record.Configuration, nil
+}
+
+// ReadDocument reads the next document of the export. Once every document has been read, it checks the end record
+// and returns io.EOF.
+func (r *Reader) ReadDocument() (*models.EncryptedDocument, error) {
+	if r.finished {
+		return nil, io.EOF
+	}
+
+	record, err := r.readRecord()
+	if err != nil {
+		return nil, err
+	}
+
+	switch record.Type {
+	case models.ExportRecordDocument:
+		return r.checkDocument(record)
+	case models.ExportRecordEnd:
+		return nil, r.checkEnd(record)
+	default:
+		return nil, fmt.Errorf("%w: unexpected %q record", ErrInvalidExport, record.Type)
+	}
+}
+
+// DocumentCount returns the number of documents read so far.
+func (r *Reader) DocumentCount() int {
+	return r.documentCount
+}
+
+func (r *Reader) readRecord() (*models.ExportRecord, error) {
+	record := models.ExportRecord{}
+
+	err := r.decoder.Decode(&record)
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: the export ended before its end record", ErrInvalidExport)
+	}
+
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+
+	if err == io.ErrUnexpectedEOF || errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidExport, err)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+func (r *Reader) checkDocument(record *models.ExportRecord) (*models.EncryptedDocument, error) {
+	if record.Document == nil {
+		return nil, fmt.Errorf("%w: document record %d has no document", ErrInvalidExport, r.documentCount+1)
+	}
+
+	checksum, err := DocumentChecksum(record.Document)
+	if err != nil {
+		return nil, err
+	}
+
+	if checksum != record.Checksum {
+		return nil, fmt.Errorf("%w: document %s doesn't match its checksum", ErrInvalidExport, record.Document.ID)
+	}
+
+	r.documentCount++
+	_, _ = r.checksums.Write([]byte(checksum))
+
+	return record.Document, nil
+}
+
+func (r *Reader) checkEnd(record *models.ExportRecord) error {
+	if record.DocumentCount != r.documentCount {
+		return fmt.Errorf("%w: the export has %d documents, but its end record says it has %d",
+			ErrInvalidExport, r.documentCount, record.DocumentCount)
+	}
+
+	if record.Checksum != hex.EncodeToString(r.checksums.Sum(nil)) {
+		return fmt.Errorf("%w: the documents don't match the checksum in the end record", ErrInvalidExport)
+	}
+
+	r.finished = true
+
+	return io.EOF
+}
+
+// Verify reads the whole vault export from the given reader, checking all of its checksums,
+// and returns the number of documents in it.
+func Verify(r io.Reader) (int, error) {
+	reader := NewReader(r)
+
+	_, err := reader.ReadConfiguration()
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		_, err = reader.ReadDocument()
+		if err == io.EOF {
+			return reader.DocumentCount(), nil
+		}
+
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// DocumentChecksum returns the hex-encoded SHA-256 hash of the JSON serialization of the given document.
+func DocumentChecksum(document *models.EncryptedDocument) (string, error) {
+	documentBytes, err := json.Marshal(document)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal document %s: %w", document.ID, err)
+	}
+
+	checksum := sha256.Sum256(documentBytes)
+
+	return hex.EncodeToString(checksum[:]), nil
+}
@@ -0,0 +1,110 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vaultexport
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+func TestWriterAndReader(t *testing.T) {
+	export := writeTestExport(t)
+
+	lines := strings.Split(strings.TrimSpace(export), "\n")
+	require.Len(t, lines, 4)
+	require.Contains(t, lines[0], `"type":"vault"`)
+	require.Contains(t, lines[3], `"type":"end"`)
+	require.Contains(t, lines[3], `"documentCount":2`)
+
+	reader := NewReader(strings.NewReader(export))
+
+	config, err := reader.ReadConfiguration()
+	require.NoError(t, err)
+	require.Equal(t, "testvault", config.ReferenceID)
+
+	document, err := reader.ReadDocument()
+	require.NoError(t, err)
+	require.Equal(t, "doc1", document.ID)
+
+	document, err = reader.ReadDocument()
+	require.NoError(t, err)
+	require.Equal(t, "doc2", document.ID)
+
+	_, err = reader.ReadDocument()
+	require.Equal(t, io.EOF, err)
+
+	_, err = reader.ReadDocument()
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, 2, reader.DocumentCount())
+}
+
+func TestVerify(t *testing.T) {
+	export := writeTestExport(t)
+
+	t.Run("Success", func(t *testing.T) {
+		count, err := Verify(strings.NewReader(export))
+		require.NoError(t, err)
+		require.Equal(t, 2, count)
+	})
+	t.Run("Invalid exports", func(t *testing.T) {
+		lines := strings.SplitAfter(export, "\n")
+
+		for _, test := range []struct {
+			export string
+			err    string
+		}{
+			{export: "", err: "the export ended before its end record"},
+			{export: "[", err: "unexpected EOF"},
+			{export: `{"type":1}`, err: "cannot unmarshal number"},
+			{export: lines[1], err: "the export doesn't start with a vault record"},
+			{export: lines[0] + lines[1], err: "the export ended before its end record"},
+			{export: lines[0] + lines[0], err: `unexpected "vault" record`},
+			{export: lines[0] + `{"type":"document"}`, err: "document record 1 has no document"},
+			{export: strings.Replace(export, `"doc1"`, `"doc3"`, 1), err: "document doc3 doesn't match its checksum"},
+			{export: lines[0] + lines[1] + lines[3], err: "the export has 1 documents, but its end record says it has 2"},
+			{export: lines[0] + lines[2] + lines[1] + lines[3], err: "the documents don't match the checksum"},
+		} {
+			_, err := Verify(strings.NewReader(test.export))
+			require.True(t, errors.Is(err, ErrInvalidExport), test.export)
+			require.Contains(t, err.Error(), test.err)
+		}
+	})
+	t.Run("Read error", func(t *testing.T) {
+		errRead := errors.New("read error")
+
+		_, err := Verify(io.MultiReader(strings.NewReader(export[:10]), &failingReader{err: errRead}))
+		require.Equal(t, errRead, err)
+	})
+}
+
+func writeTestExport(t *testing.T) string {
+	var buffer bytes.Buffer
+
+	writer := NewWriter(&buffer)
+
+	require.NoError(t, writer.WriteConfiguration(&models.DataVaultConfiguration{ReferenceID: "testvault"}))
+	require.NoError(t, writer.WriteDocument(&models.EncryptedDocument{ID: "doc1", JWE: []byte(`{"a":1}`)}))
+	require.NoError(t, writer.WriteDocument(&models.EncryptedDocument{ID: "doc2", JWE: []byte(`{"b":2}`)}))
+	require.NoError(t, writer.Finish())
+
+	return buffer.String()
+}
+
+type failingReader struct {
+	err error
+}
+
+func (f *failingReader) Read([]byte) (int, error) {
+	return 0, f.err
+}
@@ -0,0 +1,12 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package version
+
+// Version is the build version of the EDV server.
+// It's expected to be overridden at build time using
+// -ldflags "-X github.com/trustbloc/edv/pkg/version.Version=<version>".
+var Version = "dev" //nolint: gochecknoglobals
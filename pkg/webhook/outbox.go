@@ -0,0 +1,499 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+const (
+	// DeliveryIDHeader is the header that webhook requests carry their delivery ID in.
+	DeliveryIDHeader = "X-EDV-Delivery-ID"
+
+	changeFeedBatchSize = 100
+	maxResponseBodySize = 4096
+)
+
+// Notify lets the dispatcher know that the given vault changed, so that its webhooks are called without waiting for
+// the next poll.
+func (d *Dispatcher) Notify(vaultID string) {
+	d.wakeUp()
+}
+
+func (d *Dispatcher) wakeUp() {
+	select {
+	case d.wake <- struct{}{}:
+	default: // A dispatch is already due.
+	}
+}
+
+// Run delivers events until the given context is done. It returns once the deliveries that are in progress have
+// been interrupted.
+func (d *Dispatcher) Run(ctx context.Context) {
+	defer d.workers.Wait()
+
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.collectChanges()
+		d.deliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// collectChanges reads the changes made to every vault with webhooks into the outbox.
+func (d *Dispatcher) collectChanges() {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	var vaultIDs []string
+
+	if _, err := d.getJSON(vaultsKey, &vaultIDs); err != nil {
+		log.Errorf("Failed to read the vaults with webhooks: %s", err.Error())
+
+		return
+	}
+
+	for _, vaultID := range vaultIDs {
+		vault, err := d.getVaultRecord(vaultID)
+		if err == nil && vault != nil {
+			err = d.collectVaultChanges(vaultID, vault)
+		}
+
+		if err != nil {
+			log.WithField("vaultID", vaultID).Errorf("Failed to queue webhook deliveries: %s", err.Error())
+		}
+	}
+}
+
+// collectVaultChanges adds a delivery to the outbox for each of the vault's webhooks and each change made to the vault
+// since its cursor, then moves the cursor past them. The caller must hold the lock.
+func (d *Dispatcher) collectVaultChanges(vaultID string, vault *vaultRecord) error {
+	store, err := d.provider.OpenStore(vaultID)
+	if err != nil {
+		return err
+	}
+
+	for {
+		feed, err := store.Changes(vault.Cursor, changeFeedBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to read the vault's change feed: %w", err)
+		}
+
+		if feed.Cursor == vault.Cursor {
+			return nil
+		}
+
+		for _, webhookID := range vault.WebhookIDs {
+			for _, change := range feed.Changes {
+				if err := d.createDelivery(webhookID, change); err != nil {
+					return err
+				}
+			}
+		}
+
+		vault.Cursor = feed.Cursor
+
+		if err := d.putJSON(vaultKey(vaultID), vault); err != nil {
+			return err
+		}
+
+		if !feed.HasMore {
+			return nil
+		}
+	}
+}
+
+// createDelivery adds a pending delivery of the given change to the given webhook to the outbox and to the webhook's
+// delivery history. The delivery ID is derived from the webhook and the change, so a change that's read again
+// (e.g. after a crash) doesn't result in a second delivery. The caller must hold the lock.
+func (d *Dispatcher) createDelivery(webhookID string, change models.DocumentChange) error {
+	deliveryIDHash := sha256.Sum256([]byte(webhookID + "\n" + change.Sequence))
+	deliveryID := hex.EncodeToString(deliveryIDHash[:16])
+
+	found, err := d.getJSON(deliveryKey(deliveryID), &models.WebhookDelivery{})
+	if err != nil || found {
+		return err
+	}
+
+	// The delivery is added to the outbox before it's stored, so that every stored delivery is in the outbox.
+	err = d.addToOutbox(deliveryID)
+	if err != nil {
+		return err
+	}
+
+	now := d.now().UTC()
+
+	err = d.putJSON(deliveryKey(deliveryID), models.WebhookDelivery{
+		ID:            deliveryID,
+		WebhookID:     webhookID,
+		Change:        change,
+		Status:        models.DeliveryStatusPending,
+		CreatedAt:     now,
+		NextAttemptAt: &now,
+	})
+	if err != nil {
+		return err
+	}
+
+	return d.addToHistory(webhookID, deliveryID)
+}
+
+// addToOutbox puts the given delivery in the next slot of the outbox. The caller must hold the lock.
+func (d *Dispatcher) addToOutbox(deliveryID string) error {
+	state := outboxState{}
+
+	if _, err := d.getJSON(outboxKey, &state); err != nil {
+		return err
+	}
+
+	if err := d.putJSON(outboxSlotKey(state.Tail), deliveryID); err != nil {
+		return err
+	}
+
+	state.Tail++
+
+	return d.putJSON(outboxKey, state)
+}
+
+// addToHistory adds the given delivery to the webhook's delivery history. Deliveries that drop out of the history
+// can't be looked up anymore, so they're pruned if they're done with, or once they are otherwise.
+// The caller must hold the lock.
+func (d *Dispatcher) addToHistory(webhookID, deliveryID string) error {
+	var history []string
+
+	if _, err := d.getJSON(webhookDeliveriesKey(webhookID), &history); err != nil {
+		return err
+	}
+
+	history = append(history, deliveryID)
+
+	if len(history) > MaxDeliveryHistory {
+		for _, droppedID := range history[:len(history)-MaxDeliveryHistory] {
+			if err := d.pruneDeliveryIfDone(droppedID); err != nil {
+				return err
+			}
+		}
+
+		history = history[len(history)-MaxDeliveryHistory:]
+	}
+
+	return d.putJSON(webhookDeliveriesKey(webhookID), history)
+}
+
+// pruneDeliveryIfDone replaces the given delivery with an empty value if it succeeded or was given up on.
+// Stores can't delete values, so this is as small as it gets. The caller must hold the lock.
+func (d *Dispatcher) pruneDeliveryIfDone(deliveryID string) error {
+	delivery := models.WebhookDelivery{}
+
+	found, err := d.getJSON(deliveryKey(deliveryID), &delivery)
+	if err != nil || !found || delivery.Status == models.DeliveryStatusPending {
+		return err
+	}
+
+	return d.putJSON(deliveryKey(deliveryID), struct{}{})
+}
+
+// outboxState is what's kept in the webhook store under outboxKey. Each delivery that's added to the outbox is put in
+// the next slot, which holds the delivery's ID until the delivery is done with. Head is the first slot that may still
+// hold a delivery, and Tail is the slot that the next delivery is put in.
+type outboxState struct {
+	Head int `json:"head"`
+	Tail int `json:"tail"`
+}
+
+// pendingDelivery is a delivery from the outbox along with the webhook it's for and the outbox slot it's in.
+type pendingDelivery struct {
+	delivery models.WebhookDelivery
+	webhook  webhookRecord
+	slot     int
+}
+
+// deliverDue hands the deliveries in the outbox that are due to workers, which attempt them and record the results.
+// At most maxConcurrentDeliveries are attempted at a time. Due deliveries that don't fit are left for when a worker is
+// done, instead of waiting for all the attempts in progress to finish.
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	due, err := d.dueDeliveries()
+	if err != nil {
+		log.Errorf("Failed to read the webhook outbox: %s", err.Error())
+
+		return
+	}
+
+	for i := range due {
+		d.workers.Add(1)
+
+		go func(pending *pendingDelivery) {
+			defer d.workers.Done()
+
+			d.attempt(ctx, pending)
+			d.finishAttempt(ctx, pending)
+		}(&due[i])
+	}
+}
+
+// dueDeliveries returns the deliveries in the outbox that are due and aren't being attempted already, up to the number
+// of workers that are free, and marks them as being attempted. Slots of deliveries that are done with are cleared
+// along the way, and the head of the outbox is moved past the cleared slots at its start.
+func (d *Dispatcher) dueDeliveries() ([]pendingDelivery, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	state := outboxState{}
+
+	if _, err := d.getJSON(outboxKey, &state); err != nil {
+		return nil, err
+	}
+
+	freeWorkers := d.maxConcurrentDeliveries - len(d.inFlight)
+	head := state.Head
+	now := d.now()
+	queued := make(map[string]bool)
+
+	var due []pendingDelivery
+
+	for slot := state.Head; slot < state.Tail && len(due) < freeWorkers; slot++ {
+		pending, err := d.readOutboxSlot(slot, queued)
+		if err != nil {
+			return nil, err
+		}
+
+		if pending == nil {
+			if slot == head {
+				head++
+			}
+
+			continue
+		}
+
+		delivery := &pending.delivery
+
+		if d.inFlight[delivery.ID] || delivery.NextAttemptAt == nil || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+
+		if _, err := d.getJSON(webhookKey(delivery.WebhookID), &pending.webhook); err != nil {
+			return nil, err
+		}
+
+		due = append(due, *pending)
+	}
+
+	if head != state.Head {
+		state.Head = head
+
+		if err := d.putJSON(outboxKey, state); err != nil {
+			return nil, err
+		}
+	}
+
+	// There may be more due deliveries than there were free workers, so the next free worker checks again.
+	d.poolWasFull = len(due) == freeWorkers
+
+	for i := range due {
+		d.inFlight[due[i].delivery.ID] = true
+	}
+
+	return due, nil
+}
+
+// readOutboxSlot returns the pending delivery in the given outbox slot, or nil if the slot is empty. If the delivery
+// is done with, or was already seen in an earlier slot, then the slot is cleared. The caller must hold the lock.
+func (d *Dispatcher) readOutboxSlot(slot int, queued map[string]bool) (*pendingDelivery, error) {
+	var deliveryID string
+
+	if _, err := d.getJSON(outboxSlotKey(slot), &deliveryID); err != nil || deliveryID == "" {
+		return nil, err
+	}
+
+	pending := pendingDelivery{slot: slot}
+
+	found, err := d.getJSON(deliveryKey(deliveryID), &pending.delivery)
+	if err != nil {
+		return nil, err
+	}
+
+	if !found || pending.delivery.Status != models.DeliveryStatusPending || queued[deliveryID] {
+		return nil, d.putJSON(outboxSlotKey(slot), "")
+	}
+
+	queued[deliveryID] = true
+
+	return &pending, nil
+}
+
+// finishAttempt records the result of the given delivery attempt and frees its worker.
+func (d *Dispatcher) finishAttempt(ctx context.Context, attempted *pendingDelivery) {
+	d.mux.Lock()
+
+	// Deliveries interrupted by the context being done are attempted again on the next run.
+	if ctx.Err() == nil {
+		if err := d.recordAttempt(attempted); err != nil {
+			log.Errorf("Failed to record webhook delivery attempt: %s", err.Error())
+		}
+	}
+
+	delete(d.inFlight, attempted.delivery.ID)
+
+	wake := d.poolWasFull
+	d.poolWasFull = false
+
+	d.mux.Unlock()
+
+	if wake {
+		d.wakeUp()
+	}
+}
+
+// attempt sends the pending delivery to its webhook and updates its status with the result.
+func (d *Dispatcher) attempt(ctx context.Context, pending *pendingDelivery) {
+	delivery := &pending.delivery
+
+	if pending.webhook.ID == "" || pending.webhook.Deleted {
+		delivery.Status = models.DeliveryStatusFailed
+		delivery.NextAttemptAt = nil
+		delivery.LastError = "webhook was deleted"
+
+		return
+	}
+
+	now := d.now().UTC()
+
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+
+	statusCode, err := d.send(ctx, &pending.webhook, delivery)
+
+	delivery.LastStatusCode = statusCode
+
+	switch {
+	case err == nil:
+		delivery.Status = models.DeliveryStatusDelivered
+		delivery.NextAttemptAt = nil
+		delivery.LastError = ""
+	case delivery.Attempts >= d.maxAttempts:
+		delivery.Status = models.DeliveryStatusFailed
+		delivery.NextAttemptAt = nil
+		delivery.LastError = err.Error()
+	default:
+		nextAttemptAt := now.Add(d.backoff(delivery.Attempts))
+
+		delivery.NextAttemptAt = &nextAttemptAt
+		delivery.LastError = err.Error()
+	}
+}
+
+// send posts the delivery's event to the webhook and returns the receiver's status code.
+// Any status code other than 2xx is an error.
+func (d *Dispatcher) send(ctx context.Context, webhook *webhookRecord, delivery *models.WebhookDelivery) (int, error) {
+	payload, err := json.Marshal(models.WebhookEvent{
+		DeliveryID: delivery.ID,
+		WebhookID:  webhook.ID,
+		VaultID:    webhook.VaultID,
+		Change:     delivery.Change,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal webhook event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(DeliveryIDHeader, delivery.ID)
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, d.now(), payload))
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send webhook request: %w", err)
+	}
+
+	defer func() {
+		// Reading the rest of the body lets the connection be reused.
+		_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxResponseBodySize)) //nolint: errcheck
+
+		if err := resp.Body.Close(); err != nil {
+			log.Warnf("Failed to close webhook response body: %s", err.Error())
+		}
+	}()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return resp.StatusCode, fmt.Errorf("webhook receiver returned status code %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// recordAttempt stores the result of the given delivery attempt. Deliveries that succeeded or were given up on are
+// removed from the outbox, and pruned if they've dropped out of their webhook's delivery history already.
+// The caller must hold the lock.
+func (d *Dispatcher) recordAttempt(attempted *pendingDelivery) error {
+	delivery := attempted.delivery
+
+	if delivery.Status == models.DeliveryStatusPending {
+		return d.putJSON(deliveryKey(delivery.ID), delivery)
+	}
+
+	var history []string
+
+	if _, err := d.getJSON(webhookDeliveriesKey(delivery.WebhookID), &history); err != nil {
+		return err
+	}
+
+	var record interface{} = struct{}{}
+
+	for _, deliveryID := range history {
+		if deliveryID == delivery.ID {
+			record = delivery
+
+			break
+		}
+	}
+
+	if err := d.putJSON(deliveryKey(delivery.ID), record); err != nil {
+		return err
+	}
+
+	return d.putJSON(outboxSlotKey(attempted.slot), "")
+}
+
+// backoff returns how long to wait after the given number of failed attempts.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	backoff := d.initialBackoff
+
+	for i := 1; i < attempts && backoff < d.maxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > d.maxBackoff {
+		return d.maxBackoff
+	}
+
+	return backoff
+}
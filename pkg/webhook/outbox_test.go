@@ -0,0 +1,626 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+const (
+	testDocumentID  = "VJYHHJx4C8J9Fsgz7rZqSp"
+	testDocumentID2 = "AJYHHJx4C8J9Fsgz7rZqSp"
+)
+
+func TestDispatcher_Deliver(t *testing.T) {
+	t.Run("Signed event is delivered", func(t *testing.T) {
+		receiver := startTestReceiver()
+		defer receiver.Close()
+
+		d, store := newTestDispatcher(t, withTestReceiver(receiver))
+
+		// Changes made before the webhook was created aren't delivered.
+		createTestDocument(t, store, testDocumentID)
+
+		webhook, err := d.Subscribe(testVaultID, receiver.URL)
+		require.NoError(t, err)
+
+		createTestDocument(t, store, testDocumentID2)
+
+		dispatch(d)
+
+		requests := receiver.receivedRequests()
+		require.Len(t, requests, 1)
+
+		require.Equal(t, "application/json", requests[0].header.Get("Content-Type"))
+		require.NoError(t, VerifySignature(webhook.Secret, requests[0].header.Get(SignatureHeader),
+			requests[0].body, time.Minute))
+
+		event := models.WebhookEvent{}
+		require.NoError(t, json.Unmarshal(requests[0].body, &event))
+		require.Equal(t, models.WebhookEvent{
+			DeliveryID: requests[0].header.Get(DeliveryIDHeader),
+			WebhookID:  webhook.ID,
+			VaultID:    testVaultID,
+			Change:     models.DocumentChange{ID: testDocumentID2, Sequence: "2", Type: models.ChangeTypeCreated},
+		}, event)
+
+		deliveries, err := d.Deliveries(testVaultID, webhook.ID)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+		require.Equal(t, event.DeliveryID, deliveries[0].ID)
+		require.Equal(t, models.DeliveryStatusDelivered, deliveries[0].Status)
+		require.Equal(t, 1, deliveries[0].Attempts)
+		require.Equal(t, http.StatusOK, deliveries[0].LastStatusCode)
+		require.NotNil(t, deliveries[0].LastAttemptAt)
+		require.Nil(t, deliveries[0].NextAttemptAt)
+
+		// Delivered events aren't sent again.
+		dispatch(d)
+		require.Len(t, receiver.receivedRequests(), 1)
+	})
+	t.Run("Failed deliveries are retried with exponential backoff", func(t *testing.T) {
+		receiver := startTestReceiver(http.StatusInternalServerError, http.StatusServiceUnavailable)
+		defer receiver.Close()
+
+		d, store := newTestDispatcher(t, withTestReceiver(receiver), WithBackoff(time.Second, time.Minute))
+
+		now := time.Now()
+		d.now = func() time.Time { return now }
+
+		webhook, err := d.Subscribe(testVaultID, receiver.URL)
+		require.NoError(t, err)
+
+		createTestDocument(t, store, testDocumentID)
+
+		dispatch(d)
+
+		delivery := readOnlyDelivery(t, d, webhook.ID)
+		require.Equal(t, models.DeliveryStatusPending, delivery.Status)
+		require.Equal(t, 1, delivery.Attempts)
+		require.Equal(t, http.StatusInternalServerError, delivery.LastStatusCode)
+		require.Equal(t, "webhook receiver returned status code 500", delivery.LastError)
+		require.True(t, now.Add(time.Second).Equal(*delivery.NextAttemptAt))
+
+		// The retry isn't due yet.
+		dispatch(d)
+		require.Len(t, receiver.receivedRequests(), 1)
+
+		now = now.Add(time.Second)
+
+		dispatch(d)
+
+		delivery = readOnlyDelivery(t, d, webhook.ID)
+		require.Equal(t, models.DeliveryStatusPending, delivery.Status)
+		require.Equal(t, 2, delivery.Attempts)
+		require.Equal(t, http.StatusServiceUnavailable, delivery.LastStatusCode)
+		require.True(t, now.Add(2*time.Second).Equal(*delivery.NextAttemptAt))
+
+		now = now.Add(2 * time.Second)
+
+		dispatch(d)
+
+		delivery = readOnlyDelivery(t, d, webhook.ID)
+		require.Equal(t, models.DeliveryStatusDelivered, delivery.Status)
+		require.Equal(t, 3, delivery.Attempts)
+		require.Empty(t, delivery.LastError)
+
+		// Every attempt has the same delivery ID.
+		requests := receiver.receivedRequests()
+		require.Len(t, requests, 3)
+
+		for _, request := range requests {
+			require.Equal(t, delivery.ID, request.header.Get(DeliveryIDHeader))
+		}
+	})
+	t.Run("Deliveries are given up on after the maximum number of attempts", func(t *testing.T) {
+		receiver := startTestReceiver(http.StatusInternalServerError, http.StatusInternalServerError)
+		defer receiver.Close()
+
+		d, store := newTestDispatcher(t, withTestReceiver(receiver), WithMaxAttempts(2),
+			WithBackoff(time.Nanosecond, time.Nanosecond))
+
+		webhook, err := d.Subscribe(testVaultID, receiver.URL)
+		require.NoError(t, err)
+
+		createTestDocument(t, store, testDocumentID)
+
+		dispatch(d)
+		time.Sleep(time.Millisecond)
+		dispatch(d)
+		time.Sleep(time.Millisecond)
+		dispatch(d)
+
+		require.Len(t, receiver.receivedRequests(), 2)
+
+		delivery := readOnlyDelivery(t, d, webhook.ID)
+		require.Equal(t, models.DeliveryStatusFailed, delivery.Status)
+		require.Equal(t, 2, delivery.Attempts)
+		require.Nil(t, delivery.NextAttemptAt)
+		require.Equal(t, "webhook receiver returned status code 500", delivery.LastError)
+	})
+	t.Run("Receiver unreachable", func(t *testing.T) {
+		receiver := startTestReceiver()
+		receiver.Close()
+
+		d, store := newTestDispatcher(t, WithMaxAttempts(1), WithAllowedHosts([]string{"127.0.0.1"}))
+
+		webhook, err := d.Subscribe(testVaultID, receiver.URL)
+		require.NoError(t, err)
+
+		createTestDocument(t, store, testDocumentID)
+
+		dispatch(d)
+
+		delivery := readOnlyDelivery(t, d, webhook.ID)
+		require.Equal(t, models.DeliveryStatusFailed, delivery.Status)
+		require.Zero(t, delivery.LastStatusCode)
+		require.Contains(t, delivery.LastError, "failed to send webhook request")
+	})
+	t.Run("Pending deliveries to deleted webhooks are given up on", func(t *testing.T) {
+		receiver := startTestReceiver()
+		defer receiver.Close()
+
+		d, store := newTestDispatcher(t, withTestReceiver(receiver))
+
+		webhook, err := d.Subscribe(testVaultID, receiver.URL)
+		require.NoError(t, err)
+
+		createTestDocument(t, store, testDocumentID)
+
+		d.collectChanges()
+
+		require.NoError(t, d.Unsubscribe(testVaultID, webhook.ID))
+
+		d.deliverDue(context.Background())
+		d.workers.Wait()
+
+		require.Empty(t, receiver.receivedRequests())
+
+		require.Empty(t, readOutbox(t, d))
+
+		deliveryIDs := readIDs(t, d, webhookDeliveriesKey(webhook.ID))
+		require.Len(t, deliveryIDs, 1)
+
+		delivery := models.WebhookDelivery{}
+
+		found, err := d.getJSON(deliveryKey(deliveryIDs[0]), &delivery)
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, models.DeliveryStatusFailed, delivery.Status)
+		require.Equal(t, "webhook was deleted", delivery.LastError)
+	})
+	t.Run("Each webhook gets its own delivery", func(t *testing.T) {
+		receiver := startTestReceiver()
+		defer receiver.Close()
+
+		d, store := newTestDispatcher(t, withTestReceiver(receiver))
+
+		webhook, err := d.Subscribe(testVaultID, receiver.URL)
+		require.NoError(t, err)
+
+		otherWebhook, err := d.Subscribe(testVaultID, receiver.URL)
+		require.NoError(t, err)
+
+		createTestDocument(t, store, testDocumentID)
+
+		dispatch(d)
+
+		require.Len(t, receiver.receivedRequests(), 2)
+		require.NotEqual(t, readOnlyDelivery(t, d, webhook.ID).ID, readOnlyDelivery(t, d, otherWebhook.ID).ID)
+	})
+}
+
+func TestDispatcher_WorkerPool(t *testing.T) {
+	var (
+		mux                        sync.Mutex
+		received                   int
+		inProgress, mostInProgress int
+	)
+
+	release := make(chan struct{})
+
+	receiver := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		mux.Lock()
+		received++
+		inProgress++
+
+		if inProgress > mostInProgress {
+			mostInProgress = inProgress
+		}
+		mux.Unlock()
+
+		<-release
+
+		mux.Lock()
+		inProgress--
+		mux.Unlock()
+	}))
+	defer receiver.Close()
+
+	d, store := newTestDispatcher(t, WithHTTPClient(receiver.Client()), WithAllowedHosts([]string{"127.0.0.1"}),
+		WithMaxConcurrentDeliveries(2))
+
+	_, err := d.Subscribe(testVaultID, receiver.URL)
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		createTestDocument(t, store, strconv.Itoa(i))
+	}
+
+	d.collectChanges()
+
+	// Delivering doesn't wait for the receiver.
+	d.deliverDue(context.Background())
+
+	require.Eventually(t, func() bool {
+		mux.Lock()
+		defer mux.Unlock()
+
+		return received == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Every worker is busy, so nothing else is attempted.
+	d.deliverDue(context.Background())
+	require.Len(t, d.inFlight, 2)
+
+	close(release)
+
+	// Once a worker is free, the dispatcher is woken up to attempt the deliveries that were left.
+	select {
+	case <-d.wake:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "dispatcher wasn't woken up after a worker was done")
+	}
+
+	for i := 0; i < 5 && len(readOutbox(t, d)) > 0; i++ {
+		dispatch(d)
+	}
+
+	require.Empty(t, readOutbox(t, d))
+
+	mux.Lock()
+	defer mux.Unlock()
+
+	require.Equal(t, 5, received)
+	require.Equal(t, 2, mostInProgress)
+}
+
+func TestDispatcher_DeliveriesAreMadeOnce(t *testing.T) {
+	receiver := startTestReceiver()
+	defer receiver.Close()
+
+	d, store := newTestDispatcher(t, withTestReceiver(receiver))
+
+	webhook, err := d.Subscribe(testVaultID, receiver.URL)
+	require.NoError(t, err)
+
+	createTestDocument(t, store, testDocumentID)
+
+	d.collectChanges()
+
+	deliveryIDs := readOutbox(t, d)
+	require.Len(t, deliveryIDs, 1)
+
+	// The change is read again, e.g. after a crash.
+	require.NoError(t, d.createDelivery(webhook.ID,
+		models.DocumentChange{ID: testDocumentID, Sequence: "1", Type: models.ChangeTypeCreated}))
+	require.Equal(t, deliveryIDs, readOutbox(t, d))
+
+	// The delivery ended up in the outbox twice, e.g. after a crash before it was stored.
+	require.NoError(t, d.addToOutbox(deliveryIDs[0]))
+
+	dispatch(d)
+
+	require.Len(t, receiver.receivedRequests(), 1)
+	require.Empty(t, readOutbox(t, d))
+	require.Len(t, readIDs(t, d, webhookDeliveriesKey(webhook.ID)), 1)
+}
+
+func TestDispatcher_DeliveriesArePruned(t *testing.T) {
+	receiver := startTestReceiver()
+	defer receiver.Close()
+
+	d, store := newTestDispatcher(t, withTestReceiver(receiver))
+
+	webhook, err := d.Subscribe(testVaultID, receiver.URL)
+	require.NoError(t, err)
+
+	createTestDocument(t, store, "0")
+	dispatch(d)
+
+	createTestDocument(t, store, "1")
+	d.collectChanges()
+
+	history := readIDs(t, d, webhookDeliveriesKey(webhook.ID))
+	require.Len(t, history, 2)
+
+	for i := 2; i < MaxDeliveryHistory+2; i++ {
+		createTestDocument(t, store, strconv.Itoa(i))
+	}
+
+	d.collectChanges()
+
+	// The first delivery was done with when it dropped out of the history, so it's pruned right away.
+	// The second is pruned once it's done with.
+	requirePruned(t, d, history[0])
+
+	delivery := models.WebhookDelivery{}
+
+	_, err = d.getJSON(deliveryKey(history[1]), &delivery)
+	require.NoError(t, err)
+	require.Equal(t, models.DeliveryStatusPending, delivery.Status)
+
+	for len(readOutbox(t, d)) > 0 {
+		dispatch(d)
+	}
+
+	requirePruned(t, d, history[1])
+
+	deliveries, err := d.Deliveries(testVaultID, webhook.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, MaxDeliveryHistory)
+
+	for i := range deliveries {
+		require.Equal(t, models.DeliveryStatusDelivered, deliveries[i].Status)
+	}
+}
+
+func TestDispatcher_ReceiverAddressIsCheckedWhenConnecting(t *testing.T) {
+	t.Run("Host that resolves to an internal address", func(t *testing.T) {
+		d, store := newTestDispatcher(t, WithMaxAttempts(1))
+
+		webhook, err := d.Subscribe(testVaultID, testWebhookURL)
+		require.NoError(t, err)
+
+		// The host is pointed at the EDV server's network after the webhook was created.
+		d.lookupIPAddr = func(context.Context, string) ([]net.IPAddr, error) {
+			return []net.IPAddr{{IP: net.IPv4(127, 0, 0, 1)}}, nil
+		}
+
+		createTestDocument(t, store, testDocumentID)
+
+		dispatch(d)
+
+		delivery := readOnlyDelivery(t, d, webhook.ID)
+		require.Equal(t, models.DeliveryStatusFailed, delivery.Status)
+		require.Contains(t, delivery.LastError, edverrors.ErrWebhookURLNotAllowed.Error())
+	})
+	t.Run("Allowed host", func(t *testing.T) {
+		receiver := startTestReceiver()
+		defer receiver.Close()
+
+		d, store := newTestDispatcher(t, WithAllowedHosts([]string{"127.0.0.1"}))
+
+		// The default client is used, trusting the test receiver's certificate.
+		transport := d.httpClient.Transport.(*http.Transport)                                     //nolint: errcheck
+		transport.TLSClientConfig = receiver.Client().Transport.(*http.Transport).TLSClientConfig //nolint: errcheck
+
+		webhook, err := d.Subscribe(testVaultID, receiver.URL)
+		require.NoError(t, err)
+
+		createTestDocument(t, store, testDocumentID)
+
+		dispatch(d)
+
+		require.Len(t, receiver.receivedRequests(), 1)
+		require.Equal(t, models.DeliveryStatusDelivered, readOnlyDelivery(t, d, webhook.ID).Status)
+	})
+}
+
+func TestDispatcher_OutboxPersistence(t *testing.T) {
+	receiver := startTestReceiver()
+	defer receiver.Close()
+
+	provider := newTestEDVProvider(t)
+	storageProvider := memstore.NewProvider()
+
+	d, err := New(provider, storageProvider, withTestReceiver(receiver))
+	require.NoError(t, err)
+
+	webhook, err := d.Subscribe(testVaultID, receiver.URL)
+	require.NoError(t, err)
+
+	store, err := provider.OpenStore(testVaultID)
+	require.NoError(t, err)
+
+	createTestDocument(t, store, testDocumentID)
+
+	// The change is queued, but the server stops before delivering it.
+	d.collectChanges()
+
+	restarted, err := New(provider, storageProvider, withTestReceiver(receiver))
+	require.NoError(t, err)
+
+	dispatch(restarted)
+
+	require.Len(t, receiver.receivedRequests(), 1)
+	require.Equal(t, models.DeliveryStatusDelivered, readOnlyDelivery(t, restarted, webhook.ID).Status)
+	require.Empty(t, readOutbox(t, restarted))
+
+	// The outbox's slots were cleared, and its head moved past them.
+	var deliveryID string
+
+	_, err = restarted.getJSON(outboxSlotKey(0), &deliveryID)
+	require.NoError(t, err)
+	require.Empty(t, deliveryID)
+
+	restarted.deliverDue(context.Background())
+
+	state := outboxState{}
+
+	_, err = restarted.getJSON(outboxKey, &state)
+	require.NoError(t, err)
+	require.Equal(t, outboxState{Head: 1, Tail: 1}, state)
+}
+
+func TestDispatcher_Run(t *testing.T) {
+	receiver := startTestReceiver()
+	defer receiver.Close()
+
+	d, store := newTestDispatcher(t, withTestReceiver(receiver), WithPollInterval(time.Hour))
+
+	_, err := d.Subscribe(testVaultID, receiver.URL)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+
+	createTestDocument(t, store, testDocumentID)
+	d.Notify(testVaultID)
+
+	require.Eventually(t, func() bool {
+		return len(receiver.receivedRequests()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "dispatcher didn't stop after its context was done")
+	}
+}
+
+func TestDispatcher_Backoff(t *testing.T) {
+	d, _ := newTestDispatcher(t, WithBackoff(time.Second, 5*time.Second))
+
+	require.Equal(t, time.Second, d.backoff(1))
+	require.Equal(t, 2*time.Second, d.backoff(2))
+	require.Equal(t, 4*time.Second, d.backoff(3))
+	require.Equal(t, 5*time.Second, d.backoff(4))
+	require.Equal(t, 5*time.Second, d.backoff(100))
+}
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// testReceiver is a webhook receiver that responds with the given status codes in order, then with 200.
+type testReceiver struct {
+	*httptest.Server
+	mux         sync.Mutex
+	requests    []receivedRequest
+	statusCodes []int
+}
+
+func startTestReceiver(statusCodes ...int) *testReceiver {
+	receiver := &testReceiver{statusCodes: statusCodes}
+	receiver.Server = httptest.NewTLSServer(http.HandlerFunc(receiver.serveHTTP))
+
+	return receiver
+}
+
+func (r *testReceiver) serveHTTP(rw http.ResponseWriter, req *http.Request) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		panic(err)
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.requests = append(r.requests, receivedRequest{header: req.Header, body: body})
+
+	statusCode := http.StatusOK
+
+	if len(r.statusCodes) > 0 {
+		statusCode, r.statusCodes = r.statusCodes[0], r.statusCodes[1:]
+	}
+
+	rw.WriteHeader(statusCode)
+}
+
+func (r *testReceiver) receivedRequests() []receivedRequest {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	return append([]receivedRequest(nil), r.requests...)
+}
+
+// withTestReceiver sets the HTTP client that trusts the test receiver, and allows its host.
+func withTestReceiver(receiver *testReceiver) Option {
+	return func(opts *Dispatcher) {
+		WithHTTPClient(receiver.Client())(opts)
+		WithAllowedHosts([]string{"127.0.0.1"})(opts)
+	}
+}
+
+// dispatch queues the changes made to vaults and waits for the deliveries that are due to be attempted.
+func dispatch(d *Dispatcher) {
+	d.collectChanges()
+	d.deliverDue(context.Background())
+	d.workers.Wait()
+}
+
+func readOnlyDelivery(t *testing.T, d *Dispatcher, webhookID string) models.WebhookDelivery {
+	deliveries, err := d.Deliveries(testVaultID, webhookID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+
+	return deliveries[0]
+}
+
+func requirePruned(t *testing.T, d *Dispatcher, deliveryID string) {
+	record, err := d.store.Get(deliveryKey(deliveryID))
+	require.NoError(t, err)
+	require.Equal(t, "{}", string(record))
+}
+
+// readOutbox returns the IDs of the deliveries in the outbox.
+func readOutbox(t *testing.T, d *Dispatcher) []string {
+	state := outboxState{}
+
+	_, err := d.getJSON(outboxKey, &state)
+	require.NoError(t, err)
+
+	var deliveryIDs []string
+
+	for slot := state.Head; slot < state.Tail; slot++ {
+		var deliveryID string
+
+		_, err := d.getJSON(outboxSlotKey(slot), &deliveryID)
+		require.NoError(t, err)
+
+		if deliveryID != "" {
+			deliveryIDs = append(deliveryIDs, deliveryID)
+		}
+	}
+
+	return deliveryIDs
+}
+
+// readIDs returns the list of IDs stored under the given key.
+func readIDs(t *testing.T, d *Dispatcher, key string) []string {
+	var ids []string
+
+	_, err := d.getJSON(key, &ids)
+	require.NoError(t, err)
+
+	return ids
+}
@@ -0,0 +1,83 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader is the header that webhook requests carry their signature in.
+const SignatureHeader = "X-EDV-Signature"
+
+// ErrInvalidSignature is returned by VerifySignature when a signature is malformed or doesn't match the payload.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// ErrSignatureExpired is returned by VerifySignature when a signature was made too long ago or too far in the future.
+var ErrSignatureExpired = errors.New("webhook signature timestamp is outside the tolerance")
+
+// Sign returns the signature header value for the given payload sent at the given time.
+// It has the form t=<unix timestamp>,v1=<signature>, where the signature is the hex-encoded HMAC-SHA256 of the
+// timestamp, a period and the payload, keyed with the webhook's secret. Including the timestamp lets receivers
+// reject replayed requests.
+func Sign(secret string, timestamp time.Time, payload []byte) string {
+	unixTimestamp := strconv.FormatInt(timestamp.Unix(), 10)
+
+	return fmt.Sprintf("t=%s,v1=%s", unixTimestamp, computeSignature(secret, unixTimestamp, payload))
+}
+
+// VerifySignature checks that the given signature header value was made with the webhook's secret for the given
+// payload. If tolerance isn't 0, then the signature's timestamp must also be within tolerance of the current time.
+func VerifySignature(secret, header string, payload []byte, tolerance time.Duration) error {
+	var unixTimestamp, signature string
+
+	for _, part := range strings.Split(header, ",") {
+		keyValue := strings.SplitN(part, "=", 2)
+		if len(keyValue) != 2 { //nolint: gomnd
+			return ErrInvalidSignature
+		}
+
+		switch keyValue[0] {
+		case "t":
+			unixTimestamp = keyValue[1]
+		case "v1":
+			signature = keyValue[1]
+		}
+	}
+
+	seconds, err := strconv.ParseInt(unixTimestamp, 10, 64)
+	if err != nil || signature == "" {
+		return ErrInvalidSignature
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(computeSignature(secret, unixTimestamp, payload))) {
+		return ErrInvalidSignature
+	}
+
+	age := time.Since(time.Unix(seconds, 0))
+	if tolerance != 0 && (age > tolerance || age < -tolerance) {
+		return ErrSignatureExpired
+	}
+
+	return nil
+}
+
+func computeSignature(secret, unixTimestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+
+	// Writing to a hash never fails.
+	_, _ = mac.Write([]byte(unixTimestamp + "."))
+	_, _ = mac.Write(payload)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
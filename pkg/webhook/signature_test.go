@@ -0,0 +1,69 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package webhook
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "testsecret"
+
+func TestSign(t *testing.T) {
+	payload := []byte(`{"deliveryId":"1"}`)
+
+	signature := Sign(testSecret, time.Unix(1600000000, 0), payload)
+	require.True(t, strings.HasPrefix(signature, "t=1600000000,v1="))
+	require.Equal(t, signature, Sign(testSecret, time.Unix(1600000000, 0), payload))
+	require.NotEqual(t, signature, Sign("othersecret", time.Unix(1600000000, 0), payload))
+	require.NotEqual(t, signature, Sign(testSecret, time.Unix(1600000001, 0), payload))
+}
+
+func TestVerifySignature(t *testing.T) {
+	payload := []byte(`{"deliveryId":"1"}`)
+
+	t.Run("Success", func(t *testing.T) {
+		signature := Sign(testSecret, time.Now(), payload)
+
+		require.NoError(t, VerifySignature(testSecret, signature, payload, time.Minute))
+	})
+	t.Run("Success: no tolerance", func(t *testing.T) {
+		signature := Sign(testSecret, time.Unix(1600000000, 0), payload)
+
+		require.NoError(t, VerifySignature(testSecret, signature, payload, 0))
+	})
+	t.Run("Wrong secret", func(t *testing.T) {
+		signature := Sign("othersecret", time.Now(), payload)
+
+		require.Equal(t, ErrInvalidSignature, VerifySignature(testSecret, signature, payload, time.Minute))
+	})
+	t.Run("Modified payload", func(t *testing.T) {
+		signature := Sign(testSecret, time.Now(), payload)
+
+		require.Equal(t, ErrInvalidSignature,
+			VerifySignature(testSecret, signature, []byte(`{"deliveryId":"2"}`), time.Minute))
+	})
+	t.Run("Modified timestamp", func(t *testing.T) {
+		signature := Sign(testSecret, time.Unix(1600000000, 0), payload)
+		signature = strings.Replace(signature, "t=1600000000", "t=1600000001", 1)
+
+		require.Equal(t, ErrInvalidSignature, VerifySignature(testSecret, signature, payload, 0))
+	})
+	t.Run("Expired", func(t *testing.T) {
+		signature := Sign(testSecret, time.Now().Add(-time.Hour), payload)
+
+		require.Equal(t, ErrSignatureExpired, VerifySignature(testSecret, signature, payload, time.Minute))
+	})
+	t.Run("Malformed", func(t *testing.T) {
+		for _, signature := range []string{"", "t=1600000000", "v1=abc", "t=notANumber,v1=abc", "t1600000000,v1abc"} {
+			require.Equal(t, ErrInvalidSignature, VerifySignature(testSecret, signature, payload, 0), signature)
+		}
+	})
+}
@@ -0,0 +1,630 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package webhook delivers the changes made to vaults to the callback URLs subscribed to them.
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edv/pkg/edvprovider"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+const (
+	// StoreName is the name of the store in the storage provider that holds webhooks and their outbox.
+	StoreName = "webhooks"
+
+	// DefaultMaxAttempts is how many times a delivery is attempted before it's given up on,
+	// if WithMaxAttempts isn't used.
+	DefaultMaxAttempts = 10
+	// DefaultInitialBackoff is how long to wait before retrying a failed delivery for the first time,
+	// if WithBackoff isn't used. The wait doubles after every failed attempt.
+	DefaultInitialBackoff = time.Second
+	// DefaultMaxBackoff is the longest to wait between attempts of a delivery, if WithBackoff isn't used.
+	DefaultMaxBackoff = time.Hour
+	// DefaultPollInterval is how often the change feeds of vaults with webhooks and the outbox are checked,
+	// if WithPollInterval isn't used.
+	DefaultPollInterval = 2 * time.Second
+	// DefaultTimeout is how long to wait for a webhook receiver to respond, if WithHTTPClient isn't used.
+	DefaultTimeout = 10 * time.Second
+	// DefaultMaxConcurrentDeliveries is how many deliveries are attempted at the same time,
+	// if WithMaxConcurrentDeliveries isn't used.
+	DefaultMaxConcurrentDeliveries = 10
+	// MaxDeliveryHistory is the number of most recent deliveries whose status is kept for each webhook.
+	MaxDeliveryHistory = 100
+
+	secretSize = 32
+
+	vaultsKey = "vaults"
+	outboxKey = "outbox"
+)
+
+// Option configures a Dispatcher.
+type Option func(opts *Dispatcher)
+
+// WithHTTPClient sets the HTTP client used to send events to webhook receivers.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(opts *Dispatcher) {
+		opts.httpClient = httpClient
+	}
+}
+
+// WithMaxAttempts sets how many times a delivery is attempted before it's given up on.
+func WithMaxAttempts(maxAttempts int) Option {
+	return func(opts *Dispatcher) {
+		if maxAttempts > 0 {
+			opts.maxAttempts = maxAttempts
+		}
+	}
+}
+
+// WithBackoff sets how long to wait before retrying a failed delivery for the first time, and the longest to wait
+// between attempts. The wait doubles after every failed attempt.
+func WithBackoff(initial, max time.Duration) Option {
+	return func(opts *Dispatcher) {
+		if initial > 0 && max >= initial {
+			opts.initialBackoff = initial
+			opts.maxBackoff = max
+		}
+	}
+}
+
+// WithPollInterval sets how often the change feeds of vaults with webhooks and the outbox are checked.
+// Changes made through the same EDV server instance are picked up right away if Notify is called.
+func WithPollInterval(interval time.Duration) Option {
+	return func(opts *Dispatcher) {
+		if interval > 0 {
+			opts.pollInterval = interval
+		}
+	}
+}
+
+// WithMaxConcurrentDeliveries sets how many deliveries are attempted at the same time. Deliveries that are due while
+// that many are in progress wait for one of them to finish, so slow receivers don't hold up the others for longer
+// than necessary.
+func WithMaxConcurrentDeliveries(maxConcurrentDeliveries int) Option {
+	return func(opts *Dispatcher) {
+		if maxConcurrentDeliveries > 0 {
+			opts.maxConcurrentDeliveries = maxConcurrentDeliveries
+		}
+	}
+}
+
+// WithAllowedHosts restricts the hosts of callback URLs to the given ones. Since they're trusted, they may resolve to
+// loopback, private or link-local addresses, which callback URLs are otherwise not allowed to.
+func WithAllowedHosts(hosts []string) Option {
+	return func(opts *Dispatcher) {
+		opts.allowedHosts = make(map[string]bool, len(hosts))
+
+		for _, host := range hosts {
+			opts.allowedHosts[strings.ToLower(host)] = true
+		}
+	}
+}
+
+// Dispatcher keeps track of the webhooks subscribed to vaults and delivers the changes made to them.
+// Changes are read from each vault's change feed into a persistent outbox, so deliveries survive restarts.
+// Delivery is at least once, so receivers should use the delivery ID to ignore duplicates.
+type Dispatcher struct {
+	provider       edvprovider.EDVProvider
+	store          storage.Store
+	httpClient     *http.Client
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	pollInterval   time.Duration
+	allowedHosts   map[string]bool
+	lookupIPAddr   func(ctx context.Context, host string) ([]net.IPAddr, error)
+	now            func() time.Time
+	wake           chan struct{}
+	// maxConcurrentDeliveries is the size of the pool of workers that attempt deliveries.
+	maxConcurrentDeliveries int
+	workers                 sync.WaitGroup
+	// mux serializes updates to the webhook store. Note that this only works within a single EDV server instance.
+	// It also guards inFlight and poolWasFull.
+	mux sync.Mutex
+	// inFlight holds the IDs of the deliveries that are being attempted.
+	inFlight map[string]bool
+	// poolWasFull is set when due deliveries were left for later because every worker was busy.
+	poolWasFull bool
+}
+
+// webhookRecord is what's kept in the webhook store for each webhook. Stores can't delete values,
+// so deleted webhooks are marked as such.
+type webhookRecord struct {
+	models.Webhook
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// vaultRecord is what's kept in the webhook store for each vault with webhooks.
+// Cursor is how far the vault's change feed has been read into the outbox.
+type vaultRecord struct {
+	Cursor     string   `json:"cursor"`
+	WebhookIDs []string `json:"webhookIds"`
+}
+
+// New returns a new Dispatcher that reads changes from the given EDV provider and keeps webhooks and their outbox
+// in the given storage provider, creating the webhook store if needed. Run must be called for events to be delivered.
+func New(provider edvprovider.EDVProvider, storageProvider storage.Provider, opts ...Option) (*Dispatcher, error) {
+	err := storageProvider.CreateStore(StoreName)
+	if err != nil && err != storage.ErrDuplicateStore {
+		return nil, fmt.Errorf("failed to create webhook store: %w", err)
+	}
+
+	store, err := storageProvider.OpenStore(StoreName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open webhook store: %w", err)
+	}
+
+	d := &Dispatcher{
+		provider:                provider,
+		store:                   store,
+		maxAttempts:             DefaultMaxAttempts,
+		initialBackoff:          DefaultInitialBackoff,
+		maxBackoff:              DefaultMaxBackoff,
+		pollInterval:            DefaultPollInterval,
+		lookupIPAddr:            net.DefaultResolver.LookupIPAddr,
+		now:                     time.Now,
+		wake:                    make(chan struct{}, 1),
+		maxConcurrentDeliveries: DefaultMaxConcurrentDeliveries,
+		inFlight:                make(map[string]bool),
+	}
+
+	d.httpClient = &http.Client{Timeout: DefaultTimeout, Transport: d.newTransport()}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d, nil
+}
+
+// Subscribe creates a webhook that the changes made to the given vault from now on are sent to.
+// The returned webhook includes the secret that event payloads are signed with.
+func (d *Dispatcher) Subscribe(vaultID, callbackURL string) (*models.Webhook, error) {
+	if err := d.checkCallbackURL(callbackURL); err != nil {
+		return nil, err
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	vault, err := d.getVaultRecord(vaultID)
+	if err != nil {
+		return nil, err
+	}
+
+	if vault == nil {
+		vault, err = d.startTrackingVault(vaultID)
+	} else {
+		// Queue the changes made so far for the existing webhooks, so that the new one only gets later changes.
+		err = d.collectVaultChanges(vaultID, vault)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := models.Webhook{
+		ID:        uuid.New().String(),
+		VaultID:   vaultID,
+		URL:       callbackURL,
+		Secret:    secret,
+		CreatedAt: d.now().UTC(),
+	}
+
+	err = d.putJSON(webhookKey(webhook.ID), webhookRecord{Webhook: webhook})
+	if err != nil {
+		return nil, err
+	}
+
+	vault.WebhookIDs = append(vault.WebhookIDs, webhook.ID)
+
+	err = d.putJSON(vaultKey(vaultID), vault)
+	if err != nil {
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+// Webhooks returns the webhooks subscribed to the given vault, without their secrets.
+func (d *Dispatcher) Webhooks(vaultID string) ([]models.Webhook, error) {
+	vault, err := d.getVaultRecord(vaultID)
+	if err != nil {
+		return nil, err
+	}
+
+	if vault == nil {
+		return []models.Webhook{}, nil
+	}
+
+	webhooks := make([]models.Webhook, 0, len(vault.WebhookIDs))
+
+	for _, webhookID := range vault.WebhookIDs {
+		webhook, err := d.Webhook(vaultID, webhookID)
+		if err != nil {
+			return nil, err
+		}
+
+		webhooks = append(webhooks, *webhook)
+	}
+
+	return webhooks, nil
+}
+
+// Webhook returns the given webhook of the given vault, without its secret.
+// If there's no such webhook, then edverrors.ErrWebhookNotFound is returned.
+func (d *Dispatcher) Webhook(vaultID, webhookID string) (*models.Webhook, error) {
+	record, err := d.getWebhookRecord(vaultID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := record.Webhook
+	webhook.Secret = ""
+
+	return &webhook, nil
+}
+
+// Unsubscribe deletes the given webhook of the given vault. Its pending deliveries are given up on.
+// If there's no such webhook, then edverrors.ErrWebhookNotFound is returned.
+func (d *Dispatcher) Unsubscribe(vaultID, webhookID string) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	record, err := d.getWebhookRecord(vaultID, webhookID)
+	if err != nil {
+		return err
+	}
+
+	record.Deleted = true
+
+	err = d.putJSON(webhookKey(webhookID), record)
+	if err != nil {
+		return err
+	}
+
+	vault, err := d.getVaultRecord(vaultID)
+	if err != nil || vault == nil {
+		return err
+	}
+
+	vault.WebhookIDs = removeID(vault.WebhookIDs, webhookID)
+
+	return d.putJSON(vaultKey(vaultID), vault)
+}
+
+// Deliveries returns the status of the most recent deliveries to the given webhook of the given vault, oldest first.
+// If there's no such webhook, then edverrors.ErrWebhookNotFound is returned.
+func (d *Dispatcher) Deliveries(vaultID, webhookID string) ([]models.WebhookDelivery, error) {
+	if _, err := d.getWebhookRecord(vaultID, webhookID); err != nil {
+		return nil, err
+	}
+
+	var deliveryIDs []string
+
+	if _, err := d.getJSON(webhookDeliveriesKey(webhookID), &deliveryIDs); err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]models.WebhookDelivery, 0, len(deliveryIDs))
+
+	for _, deliveryID := range deliveryIDs {
+		delivery := models.WebhookDelivery{}
+
+		found, err := d.getJSON(deliveryKey(deliveryID), &delivery)
+		if err != nil {
+			return nil, err
+		}
+
+		if found {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+
+	return deliveries, nil
+}
+
+// startTrackingVault starts reading the given vault's change feed into the outbox from its current end.
+func (d *Dispatcher) startTrackingVault(vaultID string) (*vaultRecord, error) {
+	store, err := d.provider.OpenStore(vaultID)
+	if err != nil {
+		if err == storage.ErrStoreNotFound {
+			return nil, edverrors.ErrVaultNotFound
+		}
+
+		return nil, err
+	}
+
+	feed, err := store.Changes(edvprovider.ChangesNow, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the vault's change feed: %w", err)
+	}
+
+	var vaultIDs []string
+
+	if _, err = d.getJSON(vaultsKey, &vaultIDs); err != nil {
+		return nil, err
+	}
+
+	err = d.putJSON(vaultsKey, append(vaultIDs, vaultID))
+	if err != nil {
+		return nil, err
+	}
+
+	return &vaultRecord{Cursor: feed.Cursor}, nil
+}
+
+func (d *Dispatcher) getWebhookRecord(vaultID, webhookID string) (*webhookRecord, error) {
+	record := webhookRecord{}
+
+	found, err := d.getJSON(webhookKey(webhookID), &record)
+	if err != nil {
+		return nil, err
+	}
+
+	if !found || record.Deleted || record.VaultID != vaultID {
+		return nil, edverrors.ErrWebhookNotFound
+	}
+
+	return &record, nil
+}
+
+// getVaultRecord returns the record of the given vault, or nil if it never had webhooks.
+func (d *Dispatcher) getVaultRecord(vaultID string) (*vaultRecord, error) {
+	vault := vaultRecord{}
+
+	found, err := d.getJSON(vaultKey(vaultID), &vault)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return &vault, nil
+}
+
+// getJSON unmarshals the value stored under the given key into v. It returns false if there's no such value.
+func (d *Dispatcher) getJSON(key string, v interface{}) (bool, error) {
+	valueBytes, err := d.store.Get(key)
+	if err == storage.ErrValueNotFound {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("failed to get %s from webhook store: %w", key, err)
+	}
+
+	err = json.Unmarshal(valueBytes, v)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse %s from webhook store: %w", key, err)
+	}
+
+	return true, nil
+}
+
+func (d *Dispatcher) putJSON(key string, v interface{}) error {
+	valueBytes, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", key, err)
+	}
+
+	err = d.store.Put(key, valueBytes)
+	if err != nil {
+		return fmt.Errorf("failed to store %s in webhook store: %w", key, err)
+	}
+
+	return nil
+}
+
+func (d *Dispatcher) checkCallbackURL(callbackURL string) error {
+	parsedURL, err := url.Parse(callbackURL)
+	if err != nil || parsedURL.Scheme != "https" || parsedURL.Hostname() == "" {
+		return edverrors.ErrInvalidWebhookURL
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+
+	_, err = d.resolveCallbackHost(ctx, strings.ToLower(parsedURL.Hostname()))
+
+	return err
+}
+
+// resolveCallbackHost returns the addresses that the given callback URL host resolves to, or
+// edverrors.ErrWebhookURLNotAllowed if it isn't allowed. Hosts that were allowed with WithAllowedHosts aren't
+// resolved, so no addresses are returned for them.
+func (d *Dispatcher) resolveCallbackHost(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if len(d.allowedHosts) > 0 {
+		if !d.allowedHosts[host] {
+			return nil, edverrors.ErrWebhookURLNotAllowed
+		}
+
+		return nil, nil
+	}
+
+	addrs, err := d.lookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return nil, edverrors.ErrWebhookURLNotAllowed
+	}
+
+	for _, addr := range addrs {
+		if !isPublicAddress(addr.IP) {
+			return nil, edverrors.ErrWebhookURLNotAllowed
+		}
+	}
+
+	return addrs, nil
+}
+
+// newTransport returns the transport of the default HTTP client. Callback URL hosts are resolved again when
+// connecting, and the addresses that were checked are the ones connected to, so a host can't pass the check
+// when subscribing and then be pointed at an internal address. Proxies aren't used, since the addresses of
+// receivers couldn't be checked through them.
+func (d *Dispatcher) newTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint: errcheck
+	transport.Proxy = nil
+
+	dialer := &net.Dialer{Timeout: DefaultTimeout, KeepAlive: DefaultTimeout}
+
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+
+		addrs, err := d.resolveCallbackHost(ctx, strings.ToLower(host))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", host, err)
+		}
+
+		if addrs == nil {
+			return dialer.DialContext(ctx, network, address)
+		}
+
+		var conn net.Conn
+
+		for _, addr := range addrs {
+			conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(addr.IP.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+		}
+
+		return nil, err
+	}
+
+	return transport
+}
+
+// nonPublicNetworks are the address ranges that aren't globally reachable, from the IANA IPv4 and IPv6
+// special-purpose address registries, plus the multicast and reserved ranges.
+var nonPublicNetworks = parseNetworks( //nolint: gochecknoglobals
+	// IPv4
+	"0.0.0.0/8",       // "This network"
+	"10.0.0.0/8",      // Private use
+	"100.64.0.0/10",   // Shared address space (carrier-grade NAT)
+	"127.0.0.0/8",     // Loopback
+	"169.254.0.0/16",  // Link local
+	"172.16.0.0/12",   // Private use
+	"192.0.0.0/24",    // IETF protocol assignments
+	"192.0.2.0/24",    // Documentation (TEST-NET-1)
+	"192.88.99.0/24",  // 6to4 relay anycast
+	"192.168.0.0/16",  // Private use
+	"198.18.0.0/15",   // Benchmarking
+	"198.51.100.0/24", // Documentation (TEST-NET-2)
+	"203.0.113.0/24",  // Documentation (TEST-NET-3)
+	"224.0.0.0/4",     // Multicast
+	"240.0.0.0/4",     // Reserved, including the limited broadcast address
+	// IPv6
+	"::/128",         // Unspecified
+	"::1/128",        // Loopback
+	"64:ff9b::/96",   // IPv4/IPv6 translation, which can reach any IPv4 address
+	"64:ff9b:1::/48", // Local-use IPv4/IPv6 translation
+	"100::/64",       // Discard-only
+	"2001::/23",      // IETF protocol assignments, including Teredo
+	"2001:db8::/32",  // Documentation
+	"2002::/16",      // 6to4, which can reach any IPv4 address
+	"fc00::/7",       // Unique local
+	"fe80::/10",      // Link local
+	"fec0::/10",      // Site local (deprecated)
+	"ff00::/8",       // Multicast
+)
+
+// isPublicAddress returns whether the given address can be reached from outside of the EDV server's network.
+// IPv4-mapped IPv6 addresses are checked as the IPv4 addresses they map to.
+func isPublicAddress(ip net.IP) bool {
+	if ipv4 := ip.To4(); ipv4 != nil {
+		ip = ipv4
+	}
+
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseNetworks parses the given CIDR notation networks, which must be valid.
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+
+		networks[i] = network
+	}
+
+	return networks
+}
+
+func generateSecret() (string, error) {
+	secret := make([]byte, secretSize)
+
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	return hex.EncodeToString(secret), nil
+}
+
+func removeID(ids []string, idToRemove string) []string {
+	remaining := make([]string, 0, len(ids))
+
+	for _, id := range ids {
+		if id != idToRemove {
+			remaining = append(remaining, id)
+		}
+	}
+
+	return remaining
+}
+
+func vaultKey(vaultID string) string {
+	return "vault_" + vaultID
+}
+
+func webhookKey(webhookID string) string {
+	return "webhook_" + webhookID
+}
+
+func webhookDeliveriesKey(webhookID string) string {
+	return "deliveries_" + webhookID
+}
+
+func deliveryKey(deliveryID string) string {
+	return "delivery_" + deliveryID
+}
+
+func outboxSlotKey(slot int) string {
+	return "outbox_" + strconv.Itoa(slot)
+}
@@ -0,0 +1,280 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+
+	"github.com/trustbloc/edv/pkg/edvprovider"
+	"github.com/trustbloc/edv/pkg/edvprovider/memedvprovider"
+	"github.com/trustbloc/edv/pkg/restapi/edv/edverrors"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+const (
+	testVaultID    = "testvault"
+	testWebhookURL = "https://example.com/webhook"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		storageProvider := memstore.NewProvider()
+
+		_, err := New(memedvprovider.NewProvider(), storageProvider)
+		require.NoError(t, err)
+
+		// The webhook store already exists the second time.
+		_, err = New(memedvprovider.NewProvider(), storageProvider)
+		require.NoError(t, err)
+	})
+	t.Run("Failed to create store", func(t *testing.T) {
+		storageProvider := mockstore.NewMockStoreProvider()
+		storageProvider.ErrCreateStore = errors.New("create store error")
+
+		d, err := New(memedvprovider.NewProvider(), storageProvider)
+		require.EqualError(t, err, "failed to create webhook store: create store error")
+		require.Nil(t, d)
+	})
+	t.Run("Failed to open store", func(t *testing.T) {
+		storageProvider := mockstore.NewMockStoreProvider()
+		storageProvider.ErrOpenStoreHandle = errors.New("open store error")
+
+		d, err := New(memedvprovider.NewProvider(), storageProvider)
+		require.EqualError(t, err, "failed to open webhook store: open store error")
+		require.Nil(t, d)
+	})
+}
+
+func TestDispatcher_Subscribe(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		d, _ := newTestDispatcher(t)
+
+		webhook, err := d.Subscribe(testVaultID, testWebhookURL)
+		require.NoError(t, err)
+		require.NotEmpty(t, webhook.ID)
+		require.Equal(t, testVaultID, webhook.VaultID)
+		require.Equal(t, testWebhookURL, webhook.URL)
+		require.Len(t, webhook.Secret, 2*secretSize)
+		require.False(t, webhook.CreatedAt.IsZero())
+
+		secondWebhook, err := d.Subscribe(testVaultID, testWebhookURL)
+		require.NoError(t, err)
+		require.NotEqual(t, webhook.ID, secondWebhook.ID)
+		require.NotEqual(t, webhook.Secret, secondWebhook.Secret)
+
+		webhooks, err := d.Webhooks(testVaultID)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 2)
+		require.Equal(t, webhook.ID, webhooks[0].ID)
+		require.Empty(t, webhooks[0].Secret)
+		require.Equal(t, secondWebhook.ID, webhooks[1].ID)
+	})
+	t.Run("Invalid URL", func(t *testing.T) {
+		d, _ := newTestDispatcher(t)
+
+		for _, callbackURL := range []string{"", "http://example.com/webhook", "https://", "example.com", "https://%"} {
+			webhook, err := d.Subscribe(testVaultID, callbackURL)
+			require.Equal(t, edverrors.ErrInvalidWebhookURL, err, callbackURL)
+			require.Nil(t, webhook)
+		}
+	})
+	t.Run("Host not allowed", func(t *testing.T) {
+		d, _ := newTestDispatcher(t)
+
+		for _, callbackURL := range []string{
+			"https://loopback.example.com/webhook", "https://private.example.com/webhook",
+			"https://linklocal.example.com/webhook", "https://partlyprivate.example.com/webhook",
+			"https://unknown.example.com/webhook", "https://127.0.0.1:8080/webhook", "https://[::1]/webhook",
+			"https://169.254.169.254/latest/meta-data",
+		} {
+			webhook, err := d.Subscribe(testVaultID, callbackURL)
+			require.Equal(t, edverrors.ErrWebhookURLNotAllowed, err, callbackURL)
+			require.Nil(t, webhook)
+		}
+	})
+	t.Run("Allowed hosts", func(t *testing.T) {
+		d, _ := newTestDispatcher(t, WithAllowedHosts([]string{"Internal.Example.com"}))
+
+		// Allowed hosts aren't resolved.
+		d.lookupIPAddr = func(context.Context, string) ([]net.IPAddr, error) {
+			return nil, errors.New("hosts shouldn't be resolved")
+		}
+
+		webhook, err := d.Subscribe(testVaultID, "https://internal.example.com:8443/webhook")
+		require.NoError(t, err)
+		require.NotNil(t, webhook)
+
+		// Other hosts aren't allowed, public or not.
+		webhook, err = d.Subscribe(testVaultID, testWebhookURL)
+		require.Equal(t, edverrors.ErrWebhookURLNotAllowed, err)
+		require.Nil(t, webhook)
+	})
+	t.Run("Vault not found", func(t *testing.T) {
+		d, _ := newTestDispatcher(t)
+
+		webhook, err := d.Subscribe("othervault", testWebhookURL)
+		require.Equal(t, edverrors.ErrVaultNotFound, err)
+		require.Nil(t, webhook)
+	})
+	t.Run("Storage error", func(t *testing.T) {
+		storageProvider := mockstore.NewMockStoreProvider()
+
+		d, err := New(newTestEDVProvider(t), storageProvider)
+		require.NoError(t, err)
+
+		d.lookupIPAddr = lookupTestIPAddr
+
+		storageProvider.Store.ErrPut = errors.New("put error")
+
+		webhook, err := d.Subscribe(testVaultID, testWebhookURL)
+		require.EqualError(t, err, "failed to store vaults in webhook store: put error")
+		require.Nil(t, webhook)
+	})
+}
+
+func TestDispatcher_Webhooks(t *testing.T) {
+	d, _ := newTestDispatcher(t)
+
+	webhooks, err := d.Webhooks(testVaultID)
+	require.NoError(t, err)
+	require.NotNil(t, webhooks)
+	require.Empty(t, webhooks)
+}
+
+func TestDispatcher_Webhook(t *testing.T) {
+	d, _ := newTestDispatcher(t)
+
+	created, err := d.Subscribe(testVaultID, testWebhookURL)
+	require.NoError(t, err)
+
+	webhook, err := d.Webhook(testVaultID, created.ID)
+	require.NoError(t, err)
+	require.Equal(t, created.ID, webhook.ID)
+	require.Empty(t, webhook.Secret)
+
+	webhook, err = d.Webhook("othervault", created.ID)
+	require.Equal(t, edverrors.ErrWebhookNotFound, err)
+	require.Nil(t, webhook)
+
+	webhook, err = d.Webhook(testVaultID, "notAWebhook")
+	require.Equal(t, edverrors.ErrWebhookNotFound, err)
+	require.Nil(t, webhook)
+}
+
+func TestDispatcher_Unsubscribe(t *testing.T) {
+	d, _ := newTestDispatcher(t)
+
+	webhook, err := d.Subscribe(testVaultID, testWebhookURL)
+	require.NoError(t, err)
+
+	otherWebhook, err := d.Subscribe(testVaultID, testWebhookURL)
+	require.NoError(t, err)
+
+	require.NoError(t, d.Unsubscribe(testVaultID, webhook.ID))
+
+	_, err = d.Webhook(testVaultID, webhook.ID)
+	require.Equal(t, edverrors.ErrWebhookNotFound, err)
+
+	webhooks, err := d.Webhooks(testVaultID)
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+	require.Equal(t, otherWebhook.ID, webhooks[0].ID)
+
+	require.Equal(t, edverrors.ErrWebhookNotFound, d.Unsubscribe(testVaultID, webhook.ID))
+}
+
+func TestDispatcher_Deliveries(t *testing.T) {
+	d, _ := newTestDispatcher(t)
+
+	webhook, err := d.Subscribe(testVaultID, testWebhookURL)
+	require.NoError(t, err)
+
+	deliveries, err := d.Deliveries(testVaultID, webhook.ID)
+	require.NoError(t, err)
+	require.NotNil(t, deliveries)
+	require.Empty(t, deliveries)
+
+	deliveries, err = d.Deliveries(testVaultID, "notAWebhook")
+	require.Equal(t, edverrors.ErrWebhookNotFound, err)
+	require.Nil(t, deliveries)
+}
+
+func TestIsPublicAddress(t *testing.T) {
+	for _, address := range []string{"93.184.216.34", "2606:2800:220:1:248:1893:25c8:1946", "100.128.0.1",
+		"198.20.0.1"} {
+		require.True(t, isPublicAddress(net.ParseIP(address)), address)
+	}
+
+	for _, address := range []string{
+		"127.0.0.1", "::1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "fd00::1", "169.254.169.254", "fe80::1",
+		"0.0.0.0", "::", "224.0.0.1", "ff02::1", "::ffff:127.0.0.1", "100.64.0.1", "100.127.255.254",
+		"192.0.0.170", "198.18.0.1", "255.255.255.255", "240.0.0.1", "64:ff9b::a00:1", "2002:a00:1::1",
+		"2001:db8::1", "fec0::1", "::ffff:10.0.0.1",
+	} {
+		require.False(t, isPublicAddress(net.ParseIP(address)), address)
+	}
+}
+
+// newTestDispatcher returns a dispatcher for a provider that has the test vault, along with the test vault's store.
+// Hosts are resolved with lookupTestIPAddr.
+func newTestDispatcher(t *testing.T, opts ...Option) (*Dispatcher, edvprovider.EDVStore) {
+	provider := newTestEDVProvider(t)
+
+	d, err := New(provider, memstore.NewProvider(), opts...)
+	require.NoError(t, err)
+
+	d.lookupIPAddr = lookupTestIPAddr
+
+	store, err := provider.OpenStore(testVaultID)
+	require.NoError(t, err)
+
+	return d, store
+}
+
+func newTestEDVProvider(t *testing.T) *memedvprovider.MemEDVProvider {
+	provider := memedvprovider.NewProvider()
+	require.NoError(t, provider.CreateStore(testVaultID))
+
+	return provider
+}
+
+func createTestDocument(t *testing.T, store edvprovider.EDVStore, documentID string) {
+	require.NoError(t, store.Create(models.EncryptedDocument{ID: documentID}))
+}
+
+// lookupTestIPAddr stands in for DNS in tests. IP addresses resolve to themselves.
+func lookupTestIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IPAddr{{IP: ip}}, nil
+	}
+
+	addresses, ok := map[string][]string{
+		"example.com":               {"93.184.216.34"},
+		"loopback.example.com":      {"127.0.0.1"},
+		"private.example.com":       {"10.1.2.3"},
+		"linklocal.example.com":     {"169.254.169.254"},
+		"partlyprivate.example.com": {"93.184.216.34", "192.168.1.1"},
+	}[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	addrs := make([]net.IPAddr, len(addresses))
+
+	for i, address := range addresses {
+		addrs[i] = net.IPAddr{IP: net.ParseIP(address)}
+	}
+
+	return addrs, nil
+}